@@ -1,7 +1,9 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // FrappeBenchSpec defines the desired state of FrappeBench
@@ -10,6 +12,14 @@ type FrappeBenchSpec struct {
 	// +kubebuilder:validation:Required
 	FrappeVersion string `json:"frappeVersion"`
 
+	// FrappeVersionChannel follows a channel (e.g. "stable", "edge") that the operator config
+	// key frappeVersionChannels maps to an image tag. The tag replaces frappeVersion as the
+	// image tag; an explicit imageConfig.tag still wins. The resolved image is recorded in
+	// status.resolvedImage and components only roll when it changes.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`
+	// +optional
+	FrappeVersionChannel string `json:"frappeVersionChannel,omitempty"`
+
 	// Apps to install with their sources
 	// Supports FPM packages, Git repositories, and pre-built images
 	// +optional
@@ -32,6 +42,13 @@ type FrappeBenchSpec struct {
 	// +optional
 	ComponentResources *ComponentResources `json:"componentResources,omitempty"`
 
+	// ResourceProfile selects the preset the component resources default to: "default"
+	// (DefaultComponentResources) or "production" (ProductionComponentResources).
+	// Components set in componentResources override the profile.
+	// +kubebuilder:validation:Enum=default;production
+	// +optional
+	ResourceProfile string `json:"resourceProfile,omitempty"`
+
 	// RedisConfig defines Redis/Dragonfly configuration
 	// +optional
 	RedisConfig *RedisConfig `json:"redisConfig,omitempty"`
@@ -40,11 +57,43 @@ type FrappeBenchSpec struct {
 	// +optional
 	StorageClassName string `json:"storageClassName,omitempty"`
 
-	// StorageSize for the bench PVC (e.g., "10Gi")
+	// ServiceAccountName is the ServiceAccount of the gunicorn, nginx, socketio, scheduler
+	// and worker pods. When unset they run as the namespace's default ServiceAccount.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// JobServiceAccountName is the ServiceAccount of the bench's Jobs and of the Jobs run for
+	// its sites (init, delete, backup, restore, migrate, ...). Defaults to serviceAccountName.
+	// +optional
+	JobServiceAccountName string `json:"jobServiceAccountName,omitempty"`
+
+	// Storage configures the StorageClass and access modes of the bench sites PVC
+	// +optional
+	Storage *BenchStorageConfig `json:"storage,omitempty"`
+
+	// StorageSize for the bench PVC (e.g., "10Gi"). Increasing it expands the PVC when
+	// its StorageClass allows volume expansion; the PVC never shrinks.
 	// +optional
 	// +kubebuilder:default="10Gi"
 	StorageSize string `json:"storageSize,omitempty"`
 
+	// SharedAppsPVC names an existing ReadOnlyMany PersistentVolumeClaim in the bench
+	// namespace holding the built assets of the bench image. It is mounted read-only at
+	// sites/assets in every bench pod, and the init jobs no longer copy the image's
+	// assets_cache into the sites PVC.
+	// +optional
+	SharedAppsPVC string `json:"sharedAppsPVC,omitempty"`
+
+	// ExtraVolumes are added to the gunicorn, socketio, scheduler and worker pods and to the
+	// bench-init, migrate and site-init jobs, e.g. a PVC to keep the bench logs
+	// +optional
+	ExtraVolumes []corev1.Volume `json:"extraVolumes,omitempty"`
+
+	// ExtraVolumeMounts mount extraVolumes into the main container of those pods. A mount
+	// may not overlap the managed sites mount at /home/frappe/frappe-bench/sites.
+	// +optional
+	ExtraVolumeMounts []corev1.VolumeMount `json:"extraVolumeMounts,omitempty"`
+
 	// DBConfig defines default database configuration for all sites in this bench
 	// +optional
 	DBConfig *DatabaseConfig `json:"dbConfig,omitempty"`
@@ -53,6 +102,19 @@ type FrappeBenchSpec struct {
 	// +optional
 	DomainConfig *DomainConfig `json:"domainConfig,omitempty"`
 
+	// CommonSiteConfig sets extra keys in the bench's common_site_config.json, shared by
+	// every site. Values that parse as JSON (numbers, booleans, objects) are written as
+	// such, anything else as a string. Operator-managed keys (redis_*, socketio_port) are
+	// ignored.
+	// +optional
+	CommonSiteConfig map[string]string `json:"commonSiteConfig,omitempty"`
+
+	// CommonSiteConfigSecretRef names a Secret whose keys are merged into
+	// common_site_config.json like commonSiteConfig, for sensitive values such as mail
+	// credentials. Secret keys win over commonSiteConfig.
+	// +optional
+	CommonSiteConfigSecretRef *corev1.LocalObjectReference `json:"commonSiteConfigSecretRef,omitempty"`
+
 	// FPMConfig for FPM repository configuration
 	// Merged with operator-level FPM configuration
 	// +optional
@@ -69,6 +131,20 @@ type FrappeBenchSpec struct {
 	// +optional
 	WorkerAutoscaling *WorkerAutoscalingConfig `json:"workerAutoscaling,omitempty"`
 
+	// Workers defines named worker pools, one Deployment per pool running
+	// `bench worker --queue <queue>`. When empty the bench runs the built-in
+	// default, long and short workers.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Workers []WorkerPool `json:"workers,omitempty"`
+
+	// GunicornAutoscaling scales the gunicorn Deployment with a HorizontalPodAutoscaler
+	// on CPU and/or memory utilization. Without it (or when disabled) gunicorn runs
+	// componentReplicas.gunicorn replicas.
+	// +optional
+	GunicornAutoscaling *GunicornAutoscaling `json:"gunicornAutoscaling,omitempty"`
+
 	// Security defines security context settings for all pods in this bench
 	// +optional
 	Security *SecurityConfig `json:"security,omitempty"`
@@ -82,6 +158,267 @@ type FrappeBenchSpec struct {
 	// PodConfig defines advanced pod configuration for all bench components
 	// +optional
 	PodConfig *PodConfig `json:"podConfig,omitempty"`
+
+	// JobPlacement sets the nodeSelector, affinity, tolerations and geoTag of the one-shot
+	// jobs running against the bench (init, site init, delete, backup, restore), e.g. to keep
+	// them in the zone of a ReadWriteOnce sites PVC. Defaults to podConfig; a site's own
+	// podConfig placement wins for that site's jobs.
+	// +optional
+	JobPlacement *PodConfig `json:"jobPlacement,omitempty"`
+
+	// CombinedWebService exposes gunicorn and socketio through a single `<bench>-web`
+	// Service with named ports instead of separate services (useful for service meshes
+	// with per-service overhead). Defaults to separate services.
+	// +optional
+	CombinedWebService bool `json:"combinedWebService,omitempty"`
+
+	// ComponentPodAnnotations adds pod template annotations per component
+	// (e.g. `sidecar.istio.io/inject` to opt individual components into a service mesh)
+	// +optional
+	ComponentPodAnnotations *ComponentPodAnnotations `json:"componentPodAnnotations,omitempty"`
+
+	// ExcludeJobsFromMesh annotates bench-init, site-init, site-delete, health-check,
+	// backup and restore job pods so Istio and Linkerd skip sidecar injection (an
+	// injected sidecar keeps the job pod running after the main container exits)
+	// +optional
+	ExcludeJobsFromMesh bool `json:"excludeJobsFromMesh,omitempty"`
+
+	// JobTTLSecondsAfterFinished is how long finished bench-init, site-init and site-delete
+	// Jobs are kept before Kubernetes deletes them. Defaults to jobTTLSecondsAfterFinished
+	// in the operator ConfigMap, or one hour.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	JobTTLSecondsAfterFinished *int32 `json:"jobTTLSecondsAfterFinished,omitempty"`
+
+	// RetryFailedInit deletes a failed bench-init Job after a backoff (30s, doubling up to
+	// 10m) so the next reconcile recreates it and transient failures heal by themselves.
+	// By default the failed Job is kept for inspection until it is deleted or expires.
+	// +optional
+	RetryFailedInit bool `json:"retryFailedInit,omitempty"`
+
+	// MigrationSnapshot takes a VolumeSnapshot of the sites PVC before each bench migrate
+	// job, as a filesystem-level rollback point. Requires the snapshot.storage.k8s.io CRDs.
+	// +optional
+	MigrationSnapshot *VolumeSnapshotConfig `json:"migrationSnapshot,omitempty"`
+
+	// Components switches optional bench components off, e.g. socketio and the
+	// scheduler for API-only benches
+	// +optional
+	Components *BenchComponents `json:"components,omitempty"`
+
+	// Probes tunes the readiness and liveness probes of gunicorn, nginx and socketio
+	// +optional
+	Probes *BenchProbes `json:"probes,omitempty"`
+
+	// UpdateStrategy controls how the gunicorn and nginx Deployments roll out new pods,
+	// e.g. without surge pods on nodes short of memory
+	// +optional
+	UpdateStrategy *BenchUpdateStrategy `json:"updateStrategy,omitempty"`
+
+	// GracefulShutdown controls how gunicorn and nginx pods stop during rollouts
+	// +optional
+	GracefulShutdown *GracefulShutdownConfig `json:"gracefulShutdown,omitempty"`
+
+	// NetworkPolicy isolates the bench's pods from other tenants with NetworkPolicies
+	// +optional
+	NetworkPolicy *BenchNetworkPolicy `json:"networkPolicy,omitempty"`
+
+	// NginxTLS makes nginx also serve HTTPS on port 8443 of `<bench>-nginx`, which
+	// passthrough and reencrypt OpenShift Routes connect to
+	// +optional
+	NginxTLS *NginxTLSConfig `json:"nginxTLS,omitempty"`
+
+	// Nginx replaces the frappe server block of the bench's nginx with one rendered by the
+	// operator, to raise the upload size or read timeout or add directives
+	// +optional
+	Nginx *NginxConfig `json:"nginx,omitempty"`
+
+	// Paused stops the operator from reconciling the bench and its owned resources,
+	// e.g. during incident response. Deletion still proceeds while paused.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Suspended scales every Deployment and the redis StatefulSets of the bench to zero
+	// while keeping its data, e.g. to park an idle bench overnight. Setting it back to
+	// false restores the replica counts the bench had before.
+	// +optional
+	Suspended bool `json:"suspended,omitempty"`
+}
+
+// BenchComponents toggles the optional components of a bench
+type BenchComponents struct {
+	// Nginx: when disabled the `<bench>-nginx` Service forwards straight to gunicorn,
+	// so static assets are served by gunicorn and socketio must be disabled too
+	// +optional
+	Nginx *ComponentToggle `json:"nginx,omitempty"`
+
+	// SocketIO: when disabled realtime updates are unavailable
+	// +optional
+	SocketIO *ComponentToggle `json:"socketio,omitempty"`
+
+	// Scheduler: when disabled scheduled jobs no longer run
+	// +optional
+	Scheduler *ComponentToggle `json:"scheduler,omitempty"`
+}
+
+// ComponentToggle enables or disables a bench component
+type ComponentToggle struct {
+	// Enabled defaults to true; disabling deletes the component's Deployment
+	// +kubebuilder:default=true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// BenchProbes configures the health probes of the web-facing bench components
+type BenchProbes struct {
+	// SiteName is the site the gunicorn (`/api/method/ping`) and nginx (`/`) HTTP probes
+	// request. Frappe only answers for sites it knows, so without it both components
+	// are probed on their TCP port instead
+	// +optional
+	SiteName string `json:"siteName,omitempty"`
+
+	// Gunicorn overrides the gunicorn probe timing (default 10s delay, 10s period)
+	// +optional
+	Gunicorn *ProbeTiming `json:"gunicorn,omitempty"`
+
+	// Nginx overrides the nginx probe timing (default 5s delay, 10s period)
+	// +optional
+	Nginx *ProbeTiming `json:"nginx,omitempty"`
+
+	// SocketIO overrides the socketio probe timing (default 5s delay, 10s period)
+	// +optional
+	SocketIO *ProbeTiming `json:"socketio,omitempty"`
+}
+
+// BenchNetworkPolicy configures the NetworkPolicies that isolate a bench. Bench pods only
+// accept traffic from each other, the bench's jobs, on the web-facing port the ingress
+// controller and, on redis-queue, KEDA; egress is not restricted.
+type BenchNetworkPolicy struct {
+	// Enabled creates the NetworkPolicies; disabling deletes them again
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// IngressNamespaceSelector selects the namespaces of the ingress controller. Defaults
+	// to the namespace of domainConfig.ingressControllerRef, the router namespaces on
+	// OpenShift, or the ingress-nginx namespace.
+	// +optional
+	IngressNamespaceSelector *metav1.LabelSelector `json:"ingressNamespaceSelector,omitempty"`
+}
+
+// NginxTLSConfig configures the HTTPS listener of the bench's nginx
+type NginxTLSConfig struct {
+	// SecretName is a kubernetes.io/tls Secret in the bench namespace. Its ca.crt, if
+	// present, becomes the destination CA of reencrypt Routes.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+// NginxConfig tunes the frappe server block of the bench's nginx
+type NginxConfig struct {
+	// ClientMaxBodySize limits request bodies such as file uploads, e.g. 100m (default 50m)
+	// +kubebuilder:validation:Pattern=`^[0-9]+[kKmMgG]?$`
+	// +optional
+	ClientMaxBodySize string `json:"clientMaxBodySize,omitempty"`
+
+	// ProxyReadTimeout is how long nginx waits for gunicorn to respond, e.g. 300s or 5m
+	// (default 120s)
+	// +kubebuilder:validation:Pattern=`^[0-9]+(ms|s|m|h)?$`
+	// +optional
+	ProxyReadTimeout string `json:"proxyReadTimeout,omitempty"`
+
+	// ExtraConfig is appended verbatim to the server block, e.g. extra locations or headers
+	// +optional
+	ExtraConfig string `json:"extraConfig,omitempty"`
+}
+
+// ProbeTiming overrides when a component's probes start and how often they run.
+// Liveness probes start 20 seconds after readiness probes.
+type ProbeTiming struct {
+	// InitialDelaySeconds before the first readiness probe
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+
+	// PeriodSeconds between probes
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+}
+
+// BenchUpdateStrategy sets the rollout strategy of the web Deployments
+type BenchUpdateStrategy struct {
+	// Gunicorn strategy; the Kubernetes default (RollingUpdate, 25% surge and unavailable) when unset
+	// +optional
+	Gunicorn *ComponentUpdateStrategy `json:"gunicorn,omitempty"`
+
+	// Nginx strategy; the Kubernetes default (RollingUpdate, 25% surge and unavailable) when unset
+	// +optional
+	Nginx *ComponentUpdateStrategy `json:"nginx,omitempty"`
+}
+
+// ComponentUpdateStrategy is the rollout strategy of a component Deployment
+type ComponentUpdateStrategy struct {
+	// Type is RollingUpdate, replacing pods a few at a time, or Recreate, stopping every
+	// old pod before starting new ones
+	// +kubebuilder:validation:Enum=RollingUpdate;Recreate
+	// +kubebuilder:default=RollingUpdate
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// MaxSurge is how many pods, or what percentage of the replicas, may run above the
+	// desired count during a RollingUpdate; defaults to 25%
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+
+	// MaxUnavailable is how many pods, or what percentage of the replicas, may be
+	// unavailable during a RollingUpdate; defaults to 25%
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// Update strategy types of ComponentUpdateStrategy
+const (
+	UpdateStrategyRollingUpdate = "RollingUpdate"
+	UpdateStrategyRecreate      = "Recreate"
+)
+
+// WithDefaults returns a copy of s with the Kubernetes defaults filled in: RollingUpdate,
+// with 25% surge and unavailable. A nil s yields the defaults.
+func (s *ComponentUpdateStrategy) WithDefaults() *ComponentUpdateStrategy {
+	result := &ComponentUpdateStrategy{}
+	if s != nil {
+		result = s.DeepCopy()
+	}
+	if result.Type == "" {
+		result.Type = UpdateStrategyRollingUpdate
+	}
+	if result.Type == UpdateStrategyRollingUpdate {
+		defaultPercent := intstr.FromString("25%")
+		if result.MaxSurge == nil {
+			result.MaxSurge = &defaultPercent
+		}
+		if result.MaxUnavailable == nil {
+			unavailable := defaultPercent
+			result.MaxUnavailable = &unavailable
+		}
+	}
+	return result
+}
+
+// GracefulShutdownConfig drains gunicorn and nginx pods before they stop: a preStop hook
+// sleeps while the pod is removed from Service endpoints and ingress backends, then the
+// container is signalled and has the rest of the grace period to finish open requests.
+type GracefulShutdownConfig struct {
+	// Seconds is the terminationGracePeriodSeconds of the pods (default 30)
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Seconds *int64 `json:"seconds,omitempty"`
+
+	// PreStopSleepSeconds is how long the preStop hook waits before the container is
+	// signalled (default 5); 0 removes the hook
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PreStopSleepSeconds *int64 `json:"preStopSleepSeconds,omitempty"`
 }
 
 // WorkerScalingStatus reports the scaling status of a worker
@@ -99,6 +436,20 @@ type WorkerScalingStatus struct {
 	KEDAManaged bool `json:"kedaManaged"`
 }
 
+// BenchStorageConfig configures the bench sites PVC. Both fields are immutable once the
+// PVC exists.
+type BenchStorageConfig struct {
+	// ClassName is the StorageClass of the sites PVC. It takes precedence over
+	// spec.storageClassName; when neither is set the cluster's default StorageClass is used.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+
+	// AccessModes of the sites PVC. When unset, ReadWriteMany is requested from StorageClasses
+	// known to support it and ReadWriteOnce otherwise.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+}
+
 // FrappeBenchStatus defines the observed state of FrappeBench
 type FrappeBenchStatus struct {
 	// Phase represents the current phase of the bench
@@ -128,11 +479,73 @@ type FrappeBenchStatus struct {
 	// WorkerScaling reports scaling mode per worker type
 	// +optional
 	WorkerScaling map[string]WorkerScalingStatus `json:"workerScaling,omitempty"`
+
+	// ImageDigest is the last resolved digest of the bench image (set when rolloutOnDigestChange is enabled)
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// ResolvedImage is the bench image spec.frappeVersionChannel last resolved to
+	// +optional
+	ResolvedImage string `json:"resolvedImage,omitempty"`
+
+	// MigratedImage is the bench image the sites were last migrated to with bench migrate
+	// +optional
+	MigratedImage string `json:"migratedImage,omitempty"`
+
+	// MigrationSnapshot is the name of the VolumeSnapshot taken before the last migration, if
+	// any. It is not owned by the bench and can be restored from manually.
+	// +optional
+	MigrationSnapshot string `json:"migrationSnapshot,omitempty"`
+
+	// SyncedRedisConfig is the redis_cache/redis_queue pair last synced into common_site_config.json
+	// +optional
+	SyncedRedisConfig string `json:"syncedRedisConfig,omitempty"`
+
+	// CommonSiteConfigKeys lists the spec.commonSiteConfig and commonSiteConfigSecretRef keys
+	// currently written to common_site_config.json
+	// +optional
+	CommonSiteConfigKeys []string `json:"commonSiteConfigKeys,omitempty"`
+
+	// CommonSiteConfigHash identifies the common site config values last written to
+	// common_site_config.json
+	// +optional
+	CommonSiteConfigHash string `json:"commonSiteConfigHash,omitempty"`
+
+	// InitFailureLog holds the last lines of the log of the failed bench-init pod
+	// +optional
+	InitFailureLog string `json:"initFailureLog,omitempty"`
+
+	// InitFailureSummary is a one-line reason for the bench-init failure, taken from
+	// known Frappe errors in the pod log when possible
+	// +optional
+	InitFailureSummary string `json:"initFailureSummary,omitempty"`
+
+	// InitRetries counts the failed bench-init Jobs recreated by retryFailedInit
+	// +optional
+	InitRetries int32 `json:"initRetries,omitempty"`
+
+	// RedisCacheSize is the redis-cache size computed by redisConfig.autoSizePerSite
+	// +optional
+	RedisCacheSize *RedisAutoSizeStatus `json:"redisCacheSize,omitempty"`
+
+	// AppVersions maps each installed app to its version, as reported by bench version
+	// +optional
+	AppVersions map[string]string `json:"appVersions,omitempty"`
+
+	// AppVersionsSource is the image and app set AppVersions was read from
+	// +optional
+	AppVersionsSource string `json:"appVersionsSource,omitempty"`
+
+	// Endpoints maps gunicorn, nginx, socketio, redis-cache and redis-queue to their
+	// in-cluster host:port; redis shows the external instance when one is configured
+	// +optional
+	Endpoints map[string]string `json:"endpoints,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 //+kubebuilder:printcolumn:name="Apps",type=string,JSONPath=`.status.installedApps`
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -19,8 +19,14 @@ package v1alpha1
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -30,36 +36,77 @@ import (
 // log is for logging in this package.
 var frappebenchlog = logf.Log.WithName("frappebench-resource")
 
+// benchAPIMapper resolves optional APIs such as KEDA for admission warnings. It is set by
+// SetupWebhookWithManager; without it the APIs are assumed to be installed.
+var benchAPIMapper meta.RESTMapper
+
 func (r *FrappeBench) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	benchAPIMapper = mgr.GetRESTMapper()
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
+		WithDefaulter(r).
+		WithValidator(r).
 		Complete()
 }
 
+//+kubebuilder:webhook:path=/mutate-vyogo-tech-v1alpha1-frappebench,mutating=true,failurePolicy=fail,sideEffects=None,groups=vyogo.tech,resources=frappebenches,verbs=create;update,versions=v1alpha1,name=mfrappebench.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomDefaulter = &FrappeBench{}
+
+// Default implements webhook.CustomDefaulter: every configured worker autoscaling block gets
+// the missing fields of its worker type, the same defaults the controller applies at runtime
+func (r *FrappeBench) Default(ctx context.Context, obj runtime.Object) error {
+	bench, ok := obj.(*FrappeBench)
+	if !ok {
+		return fmt.Errorf("expected a FrappeBench but got a %T", obj)
+	}
+	frappebenchlog.Info("default", "name", bench.Name)
+
+	for _, worker := range bench.configuredWorkerScaling() {
+		*worker.config = *worker.config.WithDefaults(worker.workerType)
+	}
+	if strategies := bench.Spec.UpdateStrategy; strategies != nil {
+		for _, strategy := range []*ComponentUpdateStrategy{strategies.Gunicorn, strategies.Nginx} {
+			if strategy != nil {
+				*strategy = *strategy.WithDefaults()
+			}
+		}
+	}
+	return nil
+}
+
 //+kubebuilder:webhook:path=/validate-vyogo-tech-v1alpha1-frappebench,mutating=false,failurePolicy=fail,sideEffects=None,groups=vyogo.tech,resources=frappebenches,verbs=create;update,versions=v1alpha1,name=vfrappebench.kb.io,admissionReviewVersions=v1
 
 var _ webhook.CustomValidator = &FrappeBench{}
 
 // ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
 func (r *FrappeBench) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
-	frappebenchlog.Info("validate create", "name", r.Name)
+	bench, ok := obj.(*FrappeBench)
+	if !ok {
+		return nil, fmt.Errorf("expected a FrappeBench but got a %T", obj)
+	}
+	frappebenchlog.Info("validate create", "name", bench.Name)
 
-	if err := r.validateBench(); err != nil {
+	if err := bench.validateBench(); err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return bench.benchWarnings(), nil
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
 func (r *FrappeBench) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
-	frappebenchlog.Info("validate update", "name", r.Name)
+	bench, ok := newObj.(*FrappeBench)
+	if !ok {
+		return nil, fmt.Errorf("expected a FrappeBench but got a %T", newObj)
+	}
+	frappebenchlog.Info("validate update", "name", bench.Name)
 
-	if err := r.validateBench(); err != nil {
+	if err := bench.validateBench(); err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return bench.benchWarnings(), nil
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
@@ -68,6 +115,59 @@ func (r *FrappeBench) ValidateDelete(ctx context.Context, obj runtime.Object) (a
 	return nil, nil
 }
 
+// workerScaling is one worker autoscaling block set in the bench spec
+type workerScaling struct {
+	path       string
+	workerType string
+	config     *WorkerAutoscaling
+}
+
+// configuredWorkerScaling returns the worker autoscaling blocks set in the spec, in a
+// stable order so validation errors are deterministic
+func (r *FrappeBench) configuredWorkerScaling() []workerScaling {
+	var workers []workerScaling
+	if wa := r.Spec.WorkerAutoscaling; wa != nil {
+		for _, w := range []workerScaling{
+			{"workerAutoscaling.default", "default", wa.Default},
+			{"workerAutoscaling.long", "long", wa.Long},
+			{"workerAutoscaling.short", "short", wa.Short},
+		} {
+			if w.config != nil {
+				workers = append(workers, w)
+			}
+		}
+	}
+	for _, pool := range r.Spec.Workers {
+		if pool.Autoscaling != nil {
+			workers = append(workers, workerScaling{fmt.Sprintf("workers[%s].autoscaling", pool.Name), pool.Name, pool.Autoscaling})
+		}
+	}
+	return workers
+}
+
+// benchWarnings flags settings the controller accepts but cannot fully honour
+func (r *FrappeBench) benchWarnings() admission.Warnings {
+	var warnings admission.Warnings
+	if kedaInstalled() {
+		return warnings
+	}
+	for _, worker := range r.configuredWorkerScaling() {
+		if enabled := worker.config.WithDefaults(worker.workerType).Enabled; enabled != nil && *enabled {
+			warnings = append(warnings, fmt.Sprintf("%s.enabled is true but KEDA is not installed; the worker runs staticReplicas until it is", worker.path))
+		}
+	}
+	return warnings
+}
+
+// kedaInstalled reports whether the KEDA ScaledObject API is served
+func kedaInstalled() bool {
+	if benchAPIMapper == nil {
+		return true
+	}
+	_, err := benchAPIMapper.RESTMapping(schema.GroupKind{Group: "keda.sh", Kind: "ScaledObject"}, "v1alpha1")
+	return !meta.IsNoMatchError(err)
+}
+
 func (r *FrappeBench) validateBench() error {
 	// Validate FrappeVersion
 	if r.Spec.FrappeVersion == "" {
@@ -89,5 +189,201 @@ func (r *FrappeBench) validateBench() error {
 		}
 	}
 
+	// Component images replace the bench repository and tag separately
+	if r.Spec.ImageConfig != nil && r.Spec.ImageConfig.Components != nil {
+		components := r.Spec.ImageConfig.Components
+		for _, image := range []struct {
+			name  string
+			image *ComponentImage
+		}{
+			{"gunicorn", components.Gunicorn},
+			{"worker", components.Worker},
+			{"socketio", components.SocketIO},
+		} {
+			if err := validateComponentImage("imageConfig.components."+image.name, image.image); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The external redis Secret is copied into the bench's namespace, so it may only come
+	// from there; another namespace would expose any Secret with host and port keys
+	if r.Spec.RedisConfig != nil && r.Spec.RedisConfig.ConnectionSecretRef != nil {
+		if ns := r.Spec.RedisConfig.ConnectionSecretRef.Namespace; ns != "" && ns != r.Namespace {
+			return fmt.Errorf("redisConfig.connectionSecretRef.namespace must be empty or %q, the bench's namespace; got %q", r.Namespace, ns)
+		}
+	}
+
+	// Sites inherit the bench's dbConfig and read its Secret from their own namespace,
+	// which is the bench's
+	if err := ValidateDBSecretNamespace(r.Spec.DBConfig, r.Namespace); err != nil {
+		return err
+	}
+
+	// Extra volumes must not shadow the volumes the operator manages
+	if err := ValidateExtraVolumes(r.Spec.ExtraVolumes, r.Spec.ExtraVolumeMounts); err != nil {
+		return err
+	}
+
+	// Validate worker lifecycle: a drain with no grace period is killed immediately,
+	// which defeats the point on scale-down (including KEDA scale-to-zero)
+	if r.Spec.WorkerAutoscaling != nil {
+		workers := map[string]*WorkerAutoscaling{
+			"default": r.Spec.WorkerAutoscaling.Default,
+			"long":    r.Spec.WorkerAutoscaling.Long,
+			"short":   r.Spec.WorkerAutoscaling.Short,
+		}
+		for name, w := range workers {
+			if err := validateWorkerLifecycle("workerAutoscaling."+name, w); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate replica bounds with the defaults the controller fills in, so a ScaledObject
+	// is never generated with minReplicaCount above maxReplicaCount
+	for _, worker := range r.configuredWorkerScaling() {
+		if err := validateWorkerReplicas(worker.path, worker.config.WithDefaults(worker.workerType)); err != nil {
+			return err
+		}
+	}
+
+	// The preStop sleep counts against the grace period, so it must end before the
+	// kubelet kills the container (grace period defaults to 30s, the sleep to 5s)
+	if gs := r.Spec.GracefulShutdown; gs != nil {
+		seconds, sleep := int64(30), int64(5)
+		if gs.Seconds != nil {
+			seconds = *gs.Seconds
+		}
+		if gs.PreStopSleepSeconds != nil {
+			sleep = *gs.PreStopSleepSeconds
+		}
+		if sleep >= seconds {
+			return fmt.Errorf("gracefulShutdown.preStopSleepSeconds (%d) must be shorter than gracefulShutdown.seconds (%d)", sleep, seconds)
+		}
+	}
+
+	// Rolling update bounds are only meaningful for RollingUpdate, and a rollout
+	// needs room to either surge or take a pod down
+	if strategies := r.Spec.UpdateStrategy; strategies != nil {
+		for _, component := range []struct {
+			name     string
+			strategy *ComponentUpdateStrategy
+		}{
+			{"gunicorn", strategies.Gunicorn},
+			{"nginx", strategies.Nginx},
+		} {
+			if err := validateUpdateStrategy("updateStrategy."+component.name, component.strategy); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate worker pools: one Deployment per name, and a fixed replica count
+	// is shorthand for static autoscaling, so the two can't be combined
+	seen := make(map[string]bool, len(r.Spec.Workers))
+	for _, pool := range r.Spec.Workers {
+		if pool.Name == "" {
+			return fmt.Errorf("workers[].name is required")
+		}
+		if seen[pool.Name] {
+			return fmt.Errorf("workers: duplicate pool name %q", pool.Name)
+		}
+		seen[pool.Name] = true
+		if pool.Replicas != nil && pool.Autoscaling != nil {
+			return fmt.Errorf("workers[%s]: replicas and autoscaling are mutually exclusive", pool.Name)
+		}
+		if err := validateWorkerLifecycle(fmt.Sprintf("workers[%s].autoscaling", pool.Name), pool.Autoscaling); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateWorkerLifecycle checks the drain settings of one worker's scaling config
+func validateWorkerLifecycle(path string, w *WorkerAutoscaling) error {
+	if w == nil {
+		return nil
+	}
+	if w.TerminationGracePeriodSeconds != nil && *w.TerminationGracePeriodSeconds < 0 {
+		return fmt.Errorf("%s.terminationGracePeriodSeconds must be non-negative", path)
+	}
+	if w.DrainOnStop != nil && *w.DrainOnStop && w.TerminationGracePeriodSeconds != nil && *w.TerminationGracePeriodSeconds == 0 {
+		return fmt.Errorf("%s.drainOnStop requires a non-zero terminationGracePeriodSeconds", path)
+	}
+	return nil
+}
+
+// validateUpdateStrategy checks one component's updateStrategy
+func validateUpdateStrategy(path string, s *ComponentUpdateStrategy) error {
+	if s == nil {
+		return nil
+	}
+	if s.Type == UpdateStrategyRecreate {
+		if s.MaxSurge != nil || s.MaxUnavailable != nil {
+			return fmt.Errorf("%s: maxSurge and maxUnavailable only apply to type RollingUpdate", path)
+		}
+		return nil
+	}
+	defaulted := s.WithDefaults()
+	surge, err := validateRolloutBound(path+".maxSurge", *defaulted.MaxSurge)
+	if err != nil {
+		return err
+	}
+	unavailable, err := validateRolloutBound(path+".maxUnavailable", *defaulted.MaxUnavailable)
+	if err != nil {
+		return err
+	}
+	if surge == 0 && unavailable == 0 {
+		return fmt.Errorf("%s: maxSurge and maxUnavailable must not both be zero", path)
+	}
+	return nil
+}
+
+// validateRolloutBound checks a maxSurge or maxUnavailable value, a non-negative count or
+// percentage, and returns it as a number
+func validateRolloutBound(path string, value intstr.IntOrString) (int, error) {
+	if value.Type == intstr.Int {
+		if value.IntVal < 0 {
+			return 0, fmt.Errorf("%s must be non-negative", path)
+		}
+		return int(value.IntVal), nil
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(value.StrVal, "%"))
+	if err != nil || !strings.HasSuffix(value.StrVal, "%") || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("%s %q must be a count or a percentage between 0%% and 100%%", path, value.StrVal)
+	}
+	return percent, nil
+}
+
+// validateWorkerReplicas checks the replica bounds of one worker's defaulted scaling config
+func validateWorkerReplicas(path string, w *WorkerAutoscaling) error {
+	enabled := w.Enabled != nil && *w.Enabled
+	if enabled && (w.MaxReplicas == nil || *w.MaxReplicas < 1) {
+		return fmt.Errorf("%s.maxReplicas must be at least 1 when autoscaling is enabled", path)
+	}
+	if w.MinReplicas != nil && w.MaxReplicas != nil && *w.MinReplicas > *w.MaxReplicas {
+		return fmt.Errorf("%s.minReplicas (%d) must not exceed maxReplicas (%d)", path, *w.MinReplicas, *w.MaxReplicas)
+	}
+	return nil
+}
+
+// imageTagPattern matches a valid image tag
+var imageTagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// validateComponentImage checks one imageConfig.components override: a valid tag, and a
+// repository without a tag or digest of its own
+func validateComponentImage(path string, image *ComponentImage) error {
+	if image == nil {
+		return nil
+	}
+	if image.Tag != "" && !imageTagPattern.MatchString(image.Tag) {
+		return fmt.Errorf("%s.tag %q is not a valid image tag", path, image.Tag)
+	}
+	repository := image.Repository
+	if strings.Contains(repository, "@") || strings.Contains(repository[strings.LastIndex(repository, "/")+1:], ":") {
+		return fmt.Errorf("%s.repository %q must not include a tag or digest, set tag instead", path, repository)
+	}
 	return nil
 }
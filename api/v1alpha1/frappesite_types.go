@@ -49,11 +49,19 @@ type FrappeSiteSpec struct {
 	// +optional
 	Domain string `json:"domain,omitempty"`
 
+	// Aliases are additional hostnames the site answers on, e.g. the www or apex variant
+	// or vanity domains. Each gets an Ingress rule or Route and is listed in
+	// site_config.json as domains; the resolved domain stays host_name.
+	// +kubebuilder:validation:items:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// +optional
+	Aliases []string `json:"aliases,omitempty"`
+
 	// TLS configuration
 	// +optional
 	TLS TLSConfig `json:"tls,omitempty"`
 
-	// IngressClassName specifies the ingress class
+	// IngressClassName specifies the ingress class; ingress.className takes precedence.
+	// Defaults to the operator's defaultIngressClass, else nginx.
 	// +optional
 	IngressClassName string `json:"ingressClassName,omitempty"`
 
@@ -65,16 +73,91 @@ type FrappeSiteSpec struct {
 	// +optional
 	RouteConfig *RouteConfig `json:"routeConfig,omitempty"`
 
+	// DNS publishes the site's hostname through external-dns
+	// +optional
+	DNS *DNSConfig `json:"dns,omitempty"`
+
 	// Apps to install on this site
 	// These apps are checked against the actual container filesystem during installation
 	// Apps not available in the container will be gracefully skipped with warnings
-	// Note: Apps can only be installed during initial site creation and are immutable thereafter
+	// Note: Apps are only installed during initial site creation; removing an app uninstalls it
 	// +optional
 	Apps []string `json:"apps,omitempty"`
 
 	// PodConfig defines advanced pod configuration for site-specific jobs (init, backup, etc.)
 	// +optional
 	PodConfig *PodConfig `json:"podConfig,omitempty"`
+
+	// InitScriptPreamble references a ConfigMap key holding a shell snippet that the
+	// site-init job runs before `bench new-site` (e.g. installing OS deps).
+	// The snippet is mounted read-only into the job and executed in a child shell;
+	// it is never inlined into the generated script.
+	// +optional
+	InitScriptPreamble *corev1.ConfigMapKeySelector `json:"initScriptPreamble,omitempty"`
+
+	// InitJob sets the retry limit and deadline of the site's init job
+	// +optional
+	InitJob *InitJobConfig `json:"initJob,omitempty"`
+
+	// Env sets extra environment variables on the site's init job, e.g. for apps that read
+	// configuration during bench new-site. The credentials the operator passes to the job
+	// stay in mounted files, and the variable names the init script uses are reserved.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Env []SiteEnvVar `json:"env,omitempty"`
+
+	// PublishWhenHealthy delays creating the public Ingress/Route until a one-shot
+	// job can reach the site through the bench's in-cluster nginx. The site stays in
+	// Provisioning (condition PublishGated) until the check passes or times out.
+	// +optional
+	PublishWhenHealthy bool `json:"publishWhenHealthy,omitempty"`
+
+	// RunMigrateOnInit runs bench --site <site> migrate in a one-shot job after the site
+	// is initialized and only marks the site Ready once it succeeds, reported by the
+	// Migrated condition. Enabling it on an existing site migrates the site once.
+	// +optional
+	RunMigrateOnInit bool `json:"runMigrateOnInit,omitempty"`
+
+	// MaintenanceMode puts the site into Frappe maintenance mode (bench set-maintenance-mode),
+	// so users see the maintenance page instead of errors during upgrades. The applied
+	// state is reported by the MaintenanceMode condition.
+	// +optional
+	MaintenanceMode bool `json:"maintenanceMode,omitempty"`
+
+	// CORS allows browser frontends on other origins to call the site's API
+	// +optional
+	CORS *CORSConfig `json:"cors,omitempty"`
+
+	// SiteConfig sets extra keys in the site's site_config.json on every reconcile. Values
+	// that parse as JSON (numbers, booleans, objects) are written as such, anything else as
+	// a string. Operator-managed keys (host_name, db_*, redis_*, allow_cors, encryption_key,
+	// maintenance_mode) are ignored.
+	// +optional
+	SiteConfig map[string]string `json:"siteConfig,omitempty"`
+
+	// SiteConfigSecretRef names a Secret whose keys are merged into site_config.json like
+	// siteConfig, for sensitive values such as mail or OAuth credentials. Secret keys win
+	// over siteConfig.
+	// +optional
+	SiteConfigSecretRef *corev1.LocalObjectReference `json:"siteConfigSecretRef,omitempty"`
+
+	// SizeHint sizes the site's init, migrate, backup, restore and site jobs from a preset
+	// (small, medium or large). Jobs run without resource requirements when unset.
+	// +kubebuilder:validation:Enum=small;medium;large
+	// +optional
+	SizeHint string `json:"sizeHint,omitempty"`
+
+	// Paused stops the operator from reconciling the site and its jobs, Ingress and
+	// Route, e.g. during incident response. Deletion still proceeds while paused.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// BackupBeforeDelete backs the site up (bench backup --with-files) to
+	// sites/pre-delete-backups/<siteName> on the bench's sites volume when the FrappeSite is
+	// deleted, and only drops the site once the backup has completed
+	// +optional
+	BackupBeforeDelete bool `json:"backupBeforeDelete,omitempty"`
 }
 
 // FrappeSitePhase represents the current phase
@@ -109,6 +192,16 @@ type FrappeSiteStatus struct {
 	// +optional
 	DatabaseCredentialsSecret string `json:"databaseCredentialsSecret,omitempty"`
 
+	// DatabaseCredentialsSecretNamespace is the namespace of DatabaseCredentialsSecret when
+	// it isn't the site's, e.g. CloudNativePG role Secrets live in the Cluster's namespace
+	// +optional
+	DatabaseCredentialsSecretNamespace string `json:"databaseCredentialsSecretNamespace,omitempty"`
+
+	// DatabaseCredentialsVersion is the resourceVersion of DatabaseCredentialsSecret whose
+	// credentials are written to site_config.json
+	// +optional
+	DatabaseCredentialsVersion string `json:"databaseCredentialsVersion,omitempty"`
+
 	// SiteURL is the accessible URL
 	// +optional
 	SiteURL string `json:"siteURL,omitempty"`
@@ -126,15 +219,19 @@ type FrappeSiteStatus struct {
 	ResolvedDomain string `json:"resolvedDomain,omitempty"`
 
 	// DomainSource indicates how domain was determined
-	// Values: explicit, bench-suffix, auto-detected, sitename-default
+	// Values: explicit, bench-suffix, operator-default, auto-detected, sitename-default
 	// +optional
 	DomainSource string `json:"domainSource,omitempty"`
 
-	// InstalledApps lists the apps that were requested for installation on this site.
-	// Some requested apps may have been skipped or failed; see FailedApps and AppInstallationStatus.
+	// InstalledApps lists the requested apps installed on this site, as reported by the init job.
+	// Sites initialized by an init job that reported nothing list every requested app.
 	// +optional
 	InstalledApps []string `json:"installedApps,omitempty"`
 
+	// SkippedApps lists the requested apps the init job skipped because the bench does not have them
+	// +optional
+	SkippedApps []string `json:"skippedApps,omitempty"`
+
 	// AppInstallationStatus provides detailed status of app installation
 	// +optional
 	AppInstallationStatus string `json:"appInstallationStatus,omitempty"`
@@ -143,13 +240,64 @@ type FrappeSiteStatus struct {
 	// +optional
 	FailedApps map[string]string `json:"failedApps,omitempty"`
 
+	// InitFailureLog holds the last lines of the log of the failed site init pod
+	// +optional
+	InitFailureLog string `json:"initFailureLog,omitempty"`
+
+	// InitFailureSummary is a one-line reason for the site init failure, taken from
+	// known Frappe errors in the pod log when possible
+	// +optional
+	InitFailureSummary string `json:"initFailureSummary,omitempty"`
+
 	// ObservedGeneration reflects the generation of the most recently observed FrappeSite spec
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastReconcileTime is when the controller last completed a full reconcile of the site,
+	// ending with the site Ready at ObservedGeneration
+	// +optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// CORSOrigins lists the origins currently written to allow_cors in site_config.json
+	// +optional
+	CORSOrigins []string `json:"corsOrigins,omitempty"`
+
+	// SiteConfigKeys lists the spec.siteConfig and siteConfigSecretRef keys currently written
+	// to site_config.json
+	// +optional
+	SiteConfigKeys []string `json:"siteConfigKeys,omitempty"`
+
+	// SiteConfigHash identifies the site config values last written to site_config.json
+	// +optional
+	SiteConfigHash string `json:"siteConfigHash,omitempty"`
+
+	// ProvisioningWaitReason is what the site is waiting for (BenchNotReady or
+	// DatabaseProvisioning); empty once provisioning has moved past both
+	// +optional
+	ProvisioningWaitReason string `json:"provisioningWaitReason,omitempty"`
+
+	// ProvisioningAttempts is the backoff step reached since ProvisioningWaitSince while
+	// waiting for ProvisioningWaitReason
+	// +optional
+	ProvisioningAttempts int32 `json:"provisioningAttempts,omitempty"`
+
+	// ProvisioningWaitSince is when the site started waiting for ProvisioningWaitReason
+	// +optional
+	ProvisioningWaitSince *metav1.Time `json:"provisioningWaitSince,omitempty"`
+
+	// PreDeleteBackupPath is the directory, relative to the bench root, holding the backup
+	// taken by spec.backupBeforeDelete; usable as a SiteRestore localPath
+	// +optional
+	PreDeleteBackupPath string `json:"preDeleteBackupPath,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.status.resolvedDomain`
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.siteURL`,priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // FrappeSite is the Schema for the frappesites API
 type FrappeSite struct {
@@ -19,8 +19,11 @@ package v1alpha1
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -33,6 +36,7 @@ var frappesitelog = logf.Log.WithName("frappesite-resource")
 func (r *FrappeSite) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
+		WithValidator(r).
 		Complete()
 }
 
@@ -42,24 +46,42 @@ var _ webhook.CustomValidator = &FrappeSite{}
 
 // ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
 func (r *FrappeSite) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
-	frappesitelog.Info("validate create", "name", r.Name)
+	site, ok := obj.(*FrappeSite)
+	if !ok {
+		return nil, fmt.Errorf("expected a FrappeSite but got a %T", obj)
+	}
+	frappesitelog.Info("validate create", "name", site.Name)
 
-	if err := r.validateSite(); err != nil {
+	if err := site.validateSite(); err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return site.siteWarnings(), nil
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
 func (r *FrappeSite) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
-	frappesitelog.Info("validate update", "name", r.Name)
+	site, ok := newObj.(*FrappeSite)
+	if !ok {
+		return nil, fmt.Errorf("expected a FrappeSite but got a %T", newObj)
+	}
+	oldSite, ok := oldObj.(*FrappeSite)
+	if !ok {
+		return nil, fmt.Errorf("expected a FrappeSite but got a %T", oldObj)
+	}
+	frappesitelog.Info("validate update", "name", site.Name)
+
+	// Finalizer removal and metadata edits of a site admitted under older rules, or one
+	// being deleted, must not be held up by rules its spec predates
+	if site.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldSite.Spec, site.Spec) {
+		return nil, nil
+	}
 
-	if err := r.validateSite(); err != nil {
+	if err := site.validateSite(); err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return site.siteWarnings(), nil
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
@@ -68,11 +90,42 @@ func (r *FrappeSite) ValidateDelete(ctx context.Context, obj runtime.Object) (ad
 	return nil, nil
 }
 
+// siteWarnings flags settings the controller accepts but cannot fully honour
+func (r *FrappeSite) siteWarnings() admission.Warnings {
+	var warnings admission.Warnings
+	if route := r.Spec.RouteConfig; route != nil && route.WildcardPolicy == "subdomain" && route.TLSTermination == "passthrough" {
+		warnings = append(warnings, "routeConfig.wildcardPolicy subdomain is not supported with passthrough termination; the Route is created for the site's domain only")
+	}
+	if ingress := r.Spec.Ingress; ingress != nil && ingress.Enabled != nil && !*ingress.Enabled &&
+		!strings.Contains(ingress.InternalURLTemplate, "{domain}") {
+		warnings = append(warnings, "status.siteURL will be the bench nginx Service address; nginx selects the site from the Host header, so clients must send the site's domain as Host (or set ingress.internalURLTemplate to an address using {domain})")
+	}
+	return warnings
+}
+
 func (r *FrappeSite) validateSite() error {
 	// Validate site name
 	if r.Spec.SiteName == "" {
 		return fmt.Errorf("siteName cannot be empty")
 	}
+	if errs := validation.IsDNS1123Subdomain(r.Spec.SiteName); len(errs) > 0 {
+		return fmt.Errorf("siteName %q is not a valid DNS name: %s", r.Spec.SiteName, errs[0])
+	}
+	if r.Spec.Domain != "" {
+		if errs := validation.IsDNS1123Subdomain(r.Spec.Domain); len(errs) > 0 {
+			return fmt.Errorf("domain %q is not a valid DNS name: %s", r.Spec.Domain, errs[0])
+		}
+	}
+	seen := map[string]bool{r.Spec.SiteName: true, r.Spec.Domain: true}
+	for _, alias := range r.Spec.Aliases {
+		if errs := validation.IsDNS1123Subdomain(alias); len(errs) > 0 {
+			return fmt.Errorf("aliases[%s] is not a valid DNS name: %s", alias, errs[0])
+		}
+		if seen[alias] {
+			return fmt.Errorf("aliases[%s] duplicates the site's name, domain or another alias", alias)
+		}
+		seen[alias] = true
+	}
 
 	// Validate bench reference
 	if r.Spec.BenchRef == nil {
@@ -81,6 +134,9 @@ func (r *FrappeSite) validateSite() error {
 	if r.Spec.BenchRef.Name == "" {
 		return fmt.Errorf("benchRef.name cannot be empty")
 	}
+	if err := r.ValidateBenchNamespace(); err != nil {
+		return err
+	}
 
 	// Validate database mode (empty DBConfig is valid; defaults to shared)
 	if r.Spec.DBConfig.Mode != "" {
@@ -99,5 +155,55 @@ func (r *FrappeSite) validateSite() error {
 		}
 	}
 
+	// External databases are reached through the connection secret only
+	if r.Spec.DBConfig.Provider == "external" {
+		if r.Spec.DBConfig.ConnectionSecretRef == nil || r.Spec.DBConfig.ConnectionSecretRef.Name == "" {
+			return fmt.Errorf("dbConfig.connectionSecretRef must be specified for the external provider")
+		}
+	}
+	if err := ValidateDBSecretNamespace(&r.Spec.DBConfig, r.Namespace); err != nil {
+		return err
+	}
+
+	for _, app := range r.Spec.Apps {
+		if !IsValidAppName(app) {
+			return fmt.Errorf("apps[%s]: app names may only contain letters, digits, underscores and dashes", app)
+		}
+	}
+
+	if err := ValidateSiteEnv(r.Spec.Env); err != nil {
+		return err
+	}
+
+	// Validate init script preamble reference
+	if r.Spec.InitScriptPreamble != nil {
+		if r.Spec.InitScriptPreamble.Name == "" || r.Spec.InitScriptPreamble.Key == "" {
+			return fmt.Errorf("initScriptPreamble must reference a ConfigMap name and key")
+		}
+	}
+
 	return nil
 }
+
+// ValidateDBSecretNamespace rejects a dbConfig.connectionSecretRef in another namespace
+// than namespace. The Secret is always read from the site's namespace, so a foreign
+// namespace would either be ignored or hand a site another tenant's credentials.
+func ValidateDBSecretNamespace(db *DatabaseConfig, namespace string) error {
+	if db == nil || db.ConnectionSecretRef == nil || namespace == "" {
+		return nil
+	}
+	if ns := db.ConnectionSecretRef.Namespace; ns != "" && ns != namespace {
+		return fmt.Errorf("dbConfig.connectionSecretRef.namespace must be empty or %q, the site's namespace; got %q", namespace, ns)
+	}
+	return nil
+}
+
+// ValidateBenchNamespace rejects a benchRef to a bench in another namespace. The site's
+// jobs mount the bench's sites PVC, and a PVC can only be mounted in its own namespace.
+func (r *FrappeSite) ValidateBenchNamespace() error {
+	if r.Spec.BenchRef == nil || r.Spec.BenchRef.Namespace == "" || r.Namespace == "" || r.Spec.BenchRef.Namespace == r.Namespace {
+		return nil
+	}
+	return fmt.Errorf("benchRef.namespace %s differs from the site's namespace %s: the site's jobs mount the bench's sites PVC, which can only be mounted in namespace %s; create the site there",
+		r.Spec.BenchRef.Namespace, r.Namespace, r.Spec.BenchRef.Namespace)
+}
@@ -1,8 +1,15 @@
 package v1alpha1
 
 import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 // SecurityConfig defines security context settings for pods and containers
@@ -82,7 +89,8 @@ type DatabaseConfig struct {
 	// +optional
 	MariaDBRef *NamespacedName `json:"mariadbRef,omitempty"`
 
-	// PostgresRef references an existing PostgreSQL cluster (future)
+	// PostgresRef references an existing CloudNativePG Cluster for the postgres provider
+	// (defaults to frappe-postgres in the site namespace)
 	// +optional
 	PostgresRef *NamespacedName `json:"postgresRef,omitempty"`
 
@@ -108,8 +116,14 @@ type DatabaseConfig struct {
 
 	// ConnectionSecretRef references a Secret containing database credentials
 	// Required for 'external' provider. Secret should contain: username, password, database (optional, defaults to siteName)
+	// The Secret is read from the site's namespace; namespace must be empty or match it.
 	// +optional
 	ConnectionSecretRef *corev1.SecretReference `json:"connectionSecretRef,omitempty"`
+
+	// SkipDropOnDelete leaves the site database in place when the site is deleted; only the
+	// site directory is archived. For external databases whose credentials can't drop it.
+	// +optional
+	SkipDropOnDelete bool `json:"skipDropOnDelete,omitempty"`
 }
 
 // IngressConfig defines Ingress configuration
@@ -129,6 +143,14 @@ type IngressConfig struct {
 	// TLS configuration
 	// +optional
 	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// InternalURLTemplate sets status.siteURL when Ingress is disabled. Placeholders:
+	// {bench}, {namespace} (the bench's), {port} and {domain}.
+	// Defaults to "http://{bench}-nginx.{namespace}.svc:{port}". nginx selects the site from
+	// the Host header, so clients of an address without {domain} must send the site's domain.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	InternalURLTemplate string `json:"internalURLTemplate,omitempty"`
 }
 
 // TLSConfig defines TLS/SSL configuration
@@ -144,6 +166,178 @@ type TLSConfig struct {
 	// Issuer for cert-manager integration
 	// +optional
 	Issuer string `json:"issuer,omitempty"`
+
+	// IssuerKind is the kind of the cert-manager issuer: ClusterIssuer (default) or a
+	// namespaced Issuer in the site's namespace
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	IssuerKind string `json:"issuerKind,omitempty"`
+}
+
+// CORSConfig defines cross-origin access to a site's API
+type CORSConfig struct {
+	// AllowOrigins lists the origins (scheme://host[:port]) allowed to call the site's API
+	// from a browser. Written to allow_cors in site_config.json; use a single "*" to allow
+	// any origin.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Pattern=`^(\*|https?://[A-Za-z0-9.-]+(:[0-9]+)?)$`
+	AllowOrigins []string `json:"allowOrigins"`
+}
+
+// InitJobConfig tunes the Job that runs bench new-site for a site
+type InitJobConfig struct {
+	// BackoffLimit is how many times a failed init job is retried before the site is
+	// marked Failed
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=2
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+
+	// ActiveDeadlineSeconds stops the init job, e.g. when bench new-site hangs on an
+	// unreachable database, and marks the site Failed with reason InitTimeout. The job
+	// runs without a deadline when unset.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+
+	// DatabaseWaitSeconds is how long an init container waits for the database host and
+	// port to accept connections before bench new-site runs. The init pod fails, and is
+	// retried within backoffLimit, when the database is still unreachable; 0 disables
+	// the wait.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=300
+	// +optional
+	DatabaseWaitSeconds *int32 `json:"databaseWaitSeconds,omitempty"`
+}
+
+// SiteEnvVar is an environment variable for a site's init job, set from a literal value
+// or a Secret key in the site's namespace
+type SiteEnvVar struct {
+	// Name of the environment variable
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+
+	// Value of the environment variable
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// SecretKeyRef reads the value from a Secret key instead
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// reservedSiteEnvNames are set by the site init script from its mounted secret files,
+// so spec.env cannot override them
+var reservedSiteEnvNames = map[string]bool{
+	"ADMIN_PASSWORD":  true,
+	"APPS_TO_INSTALL": true,
+	"BENCH_NAME":      true,
+	"DB_HOST":         true,
+	"DB_NAME":         true,
+	"DB_PASSWORD":     true,
+	"DB_PORT":         true,
+	"DB_PROVIDER":     true,
+	"DB_USER":         true,
+	"DOMAIN":          true,
+	"LOGNAME":         true,
+	"REDIS_CACHE":     true,
+	"REDIS_QUEUE":     true,
+	"SHARED_ASSETS":   true,
+	"SITE_NAME":       true,
+	"USER":            true,
+}
+
+// siteEnvNamePattern matches the names a shell can export
+var siteEnvNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateSiteEnv checks that every variable has a valid, unreserved name and exactly one
+// of value and secretKeyRef
+func ValidateSiteEnv(env []SiteEnvVar) error {
+	seen := make(map[string]bool, len(env))
+	for _, e := range env {
+		if !siteEnvNamePattern.MatchString(e.Name) {
+			return fmt.Errorf("env[%s]: name must consist of letters, digits and underscores and not start with a digit", e.Name)
+		}
+		if reservedSiteEnvNames[e.Name] {
+			return fmt.Errorf("env[%s]: name is reserved for the site init script", e.Name)
+		}
+		if seen[e.Name] {
+			return fmt.Errorf("env[%s]: duplicate name", e.Name)
+		}
+		seen[e.Name] = true
+		if e.SecretKeyRef != nil {
+			if e.Value != "" {
+				return fmt.Errorf("env[%s]: value and secretKeyRef are mutually exclusive", e.Name)
+			}
+			if e.SecretKeyRef.Name == "" || e.SecretKeyRef.Key == "" {
+				return fmt.Errorf("env[%s]: secretKeyRef must set name and key", e.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// benchSitesMountPath is where the operator mounts the sites PVC in bench pods
+const benchSitesMountPath = "/home/frappe/frappe-bench/sites"
+
+// reservedBenchVolumes are the pod volumes the operator manages in bench pods and jobs
+var reservedBenchVolumes = map[string]bool{
+	"sites":         true,
+	"shared-assets": true,
+	"site-secrets":  true,
+	"site-preamble": true,
+}
+
+// ValidateExtraVolumes checks that extra volumes have unique, unreserved names and that
+// every mount references one of them at an absolute path clear of the sites mount
+func ValidateExtraVolumes(volumes []corev1.Volume, mounts []corev1.VolumeMount) error {
+	names := make(map[string]bool, len(volumes))
+	for _, v := range volumes {
+		if v.Name == "" {
+			return fmt.Errorf("extraVolumes[].name is required")
+		}
+		if reservedBenchVolumes[v.Name] {
+			return fmt.Errorf("extraVolumes[%s]: name is reserved for a volume the operator manages", v.Name)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("extraVolumes[%s]: duplicate name", v.Name)
+		}
+		names[v.Name] = true
+	}
+	paths := make(map[string]bool, len(mounts))
+	for _, m := range mounts {
+		if !names[m.Name] {
+			return fmt.Errorf("extraVolumeMounts[%s]: no extra volume with this name", m.Name)
+		}
+		if !path.IsAbs(m.MountPath) {
+			return fmt.Errorf("extraVolumeMounts[%s]: mountPath %q must be absolute", m.Name, m.MountPath)
+		}
+		mountPath := path.Clean(m.MountPath)
+		if mountPath == benchSitesMountPath || strings.HasPrefix(mountPath, benchSitesMountPath+"/") ||
+			strings.HasPrefix(benchSitesMountPath, strings.TrimSuffix(mountPath, "/")+"/") {
+			return fmt.Errorf("extraVolumeMounts[%s]: mountPath %q overlaps the sites mount at %s", m.Name, m.MountPath, benchSitesMountPath)
+		}
+		if paths[mountPath] {
+			return fmt.Errorf("extraVolumeMounts[%s]: duplicate mountPath %q", m.Name, m.MountPath)
+		}
+		paths[mountPath] = true
+	}
+	return nil
+}
+
+// IsValidAppName reports whether an app name is safe to pass to bench: letters, digits,
+// underscores and dashes only
+func IsValidAppName(app string) bool {
+	if app == "" {
+		return false
+	}
+	for _, char := range app {
+		if !((char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
+			(char >= '0' && char <= '9') || char == '_' || char == '-') {
+			return false
+		}
+	}
+	return true
 }
 
 // DomainConfig defines domain resolution behavior
@@ -188,9 +382,47 @@ type ImageConfig struct {
 	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
 	PullPolicy corev1.PullPolicy `json:"pullPolicy,omitempty"`
 
-	// PullSecrets for private registries
+	// PullSecrets for private registries, set on every pod the operator runs for the bench
 	// +optional
 	PullSecrets []corev1.LocalObjectReference `json:"pullSecrets,omitempty"`
+
+	// RolloutOnDigestChange periodically resolves the image tag to its registry digest and
+	// restarts the bench deployments when it changes, so mutable tags stay current
+	// +optional
+	RolloutOnDigestChange bool `json:"rolloutOnDigestChange,omitempty"`
+
+	// Components overrides the image of individual components, e.g. a slim image for the
+	// workers and a full one for gunicorn. Everything else (nginx, scheduler, jobs) keeps
+	// the bench image.
+	// +optional
+	Components *ComponentImages `json:"components,omitempty"`
+}
+
+// ComponentImages holds per-component image overrides of a bench
+type ComponentImages struct {
+	// Gunicorn overrides the image of the gunicorn Deployment
+	// +optional
+	Gunicorn *ComponentImage `json:"gunicorn,omitempty"`
+
+	// Worker overrides the image of every worker Deployment
+	// +optional
+	Worker *ComponentImage `json:"worker,omitempty"`
+
+	// SocketIO overrides the image of the Socket.IO Deployment
+	// +optional
+	SocketIO *ComponentImage `json:"socketio,omitempty"`
+}
+
+// ComponentImage overrides the repository and/or tag of the bench image for one component
+type ComponentImage struct {
+	// Repository replaces the bench image repository
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// Tag replaces the bench image tag
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`
+	// +optional
+	Tag string `json:"tag,omitempty"`
 }
 
 // ComponentReplicas defines replica counts for bench components
@@ -266,6 +498,37 @@ type ComponentResources struct {
 	WorkerShort *ResourceRequirements `json:"workerShort,omitempty"`
 }
 
+// ComponentPodAnnotations defines extra pod template annotations for bench components
+type ComponentPodAnnotations struct {
+	// Gunicorn pod annotations
+	// +optional
+	Gunicorn map[string]string `json:"gunicorn,omitempty"`
+
+	// Nginx pod annotations
+	// +optional
+	Nginx map[string]string `json:"nginx,omitempty"`
+
+	// Scheduler pod annotations
+	// +optional
+	Scheduler map[string]string `json:"scheduler,omitempty"`
+
+	// Socketio pod annotations
+	// +optional
+	Socketio map[string]string `json:"socketio,omitempty"`
+
+	// WorkerDefault pod annotations
+	// +optional
+	WorkerDefault map[string]string `json:"workerDefault,omitempty"`
+
+	// WorkerLong pod annotations
+	// +optional
+	WorkerLong map[string]string `json:"workerLong,omitempty"`
+
+	// WorkerShort pod annotations
+	// +optional
+	WorkerShort map[string]string `json:"workerShort,omitempty"`
+}
+
 // DefaultComponentResources returns sensible default resource requirements for Frappe components
 // These defaults are suitable for small to medium workloads and should be adjusted for production
 func DefaultComponentResources() ComponentResources {
@@ -419,6 +682,12 @@ func ProductionComponentResources() ComponentResources {
 	}
 }
 
+// Values of FrappeBenchSpec.ResourceProfile
+const (
+	ResourceProfileDefault    = "default"
+	ResourceProfileProduction = "production"
+)
+
 // MergeResources merges user-provided resources with defaults, user values take precedence
 func (c ComponentResources) MergeWithDefaults(defaults ComponentResources) ComponentResources {
 	result := defaults
@@ -469,9 +738,40 @@ type RedisConfig struct {
 	// +optional
 	StorageSize *resource.Quantity `json:"storageSize,omitempty"`
 
-	// ConnectionSecretRef for external Redis
+	// ConnectionSecretRef points at a Secret with the host, port and optional password of an
+	// external Redis, used for both cache and queue instead of the operator's StatefulSets.
+	// The Secret is always read in the bench's namespace.
 	// +optional
 	ConnectionSecretRef *corev1.SecretReference `json:"connectionSecretRef,omitempty"`
+
+	// AutoSizePerSite sizes the redis-cache memory from the number of Ready sites on the bench,
+	// overriding the memory in Resources
+	// +optional
+	AutoSizePerSite *RedisAutoSize `json:"autoSizePerSite,omitempty"`
+}
+
+// RedisAutoSize scales the redis-cache memory with the number of Ready sites on a bench
+type RedisAutoSize struct {
+	// MemoryPerSite is the cache memory budgeted for each Ready site
+	// +kubebuilder:validation:Required
+	MemoryPerSite resource.Quantity `json:"memoryPerSite"`
+
+	// MinMemory is the smallest cache size, also used while no site is Ready (default 512Mi)
+	// +optional
+	MinMemory *resource.Quantity `json:"minMemory,omitempty"`
+
+	// MaxMemory caps the cache size however many sites there are (default 8Gi)
+	// +optional
+	MaxMemory *resource.Quantity `json:"maxMemory,omitempty"`
+}
+
+// RedisAutoSizeStatus reports the redis-cache size computed from a bench's site count
+type RedisAutoSizeStatus struct {
+	// ReadySites is the number of Ready sites the size was computed for
+	ReadySites int32 `json:"readySites"`
+
+	// Memory is the memory request and limit applied to the redis-cache container
+	Memory resource.Quantity `json:"memory"`
 }
 
 // AppSource defines where an app comes from and how to install it
@@ -552,6 +852,38 @@ type GitConfig struct {
 	Enabled *bool `json:"enabled,omitempty"`
 }
 
+// GunicornAutoscaling defines HorizontalPodAutoscaler settings for gunicorn
+type GunicornAutoscaling struct {
+	// Enabled controls whether the HPA is created; disabling deletes it
+	// +optional
+	// +kubebuilder:default=true
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// MinReplicas is the lower bound the HPA scales gunicorn down to
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper bound the HPA scales gunicorn up to
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// TargetCPUUtilizationPercentage is the average CPU utilization, relative to the
+	// gunicorn CPU request, the HPA aims for. Defaults to 80 when no target is set.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// TargetMemoryUtilizationPercentage is the average memory utilization, relative to
+	// the gunicorn memory request, the HPA aims for
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+}
+
 // WorkerAutoscaling defines scaling configuration for a worker type
 // Supports both KEDA-based autoscaling and static replica counts
 type WorkerAutoscaling struct {
@@ -599,6 +931,97 @@ type WorkerAutoscaling struct {
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:default=30
 	PollingInterval *int32 `json:"pollingInterval,omitempty"`
+
+	// DrainOnStop adds a preStop hook that asks the worker for a warm shutdown, so it
+	// finishes the job in flight before the pod is killed on scale-down.
+	// Defaults to true for default and long workers, false for short workers.
+	// +optional
+	DrainOnStop *bool `json:"drainOnStop,omitempty"`
+
+	// TerminationGracePeriodSeconds bounds how long a stopping worker may take to drain.
+	// Defaults to 300 for default workers, 1500 for long workers and 30 for short workers.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+}
+
+// DefaultWorkerAutoscaling returns the scaling defaults of a worker type. Short and long
+// workers scale to zero with KEDA; default workers and custom pools keep one replica.
+func DefaultWorkerAutoscaling(workerType string) *WorkerAutoscaling {
+	switch workerType {
+	case "short":
+		// Short jobs: scale-to-zero with aggressive scaling, exit fast rather than drain
+		return &WorkerAutoscaling{
+			Enabled:                       ptr.To(true),
+			MinReplicas:                   ptr.To[int32](0),
+			MaxReplicas:                   ptr.To[int32](10),
+			QueueLength:                   ptr.To[int32](5),
+			CooldownPeriod:                ptr.To[int32](60),
+			PollingInterval:               ptr.To[int32](15),
+			DrainOnStop:                   ptr.To(false),
+			TerminationGracePeriodSeconds: ptr.To[int64](30),
+		}
+	case "long":
+		// Long jobs: scale-to-zero with conservative scaling, drain for up to the
+		// long queue's default job timeout
+		return &WorkerAutoscaling{
+			Enabled:                       ptr.To(true),
+			MinReplicas:                   ptr.To[int32](0),
+			MaxReplicas:                   ptr.To[int32](5),
+			QueueLength:                   ptr.To[int32](2),
+			CooldownPeriod:                ptr.To[int32](300),
+			PollingInterval:               ptr.To[int32](30),
+			DrainOnStop:                   ptr.To(true),
+			TerminationGracePeriodSeconds: ptr.To[int64](1500),
+		}
+	default:
+		// Default and custom pools: always one replica
+		return &WorkerAutoscaling{
+			Enabled:                       ptr.To(false),
+			StaticReplicas:                ptr.To[int32](1),
+			DrainOnStop:                   ptr.To(true),
+			TerminationGracePeriodSeconds: ptr.To[int64](300),
+		}
+	}
+}
+
+// WithDefaults returns a copy of w with its missing fields taken from the defaults of
+// the worker type. A nil w yields the defaults.
+func (w *WorkerAutoscaling) WithDefaults(workerType string) *WorkerAutoscaling {
+	defaults := DefaultWorkerAutoscaling(workerType)
+	if w == nil {
+		return defaults
+	}
+
+	result := w.DeepCopy()
+	if result.Enabled == nil {
+		result.Enabled = defaults.Enabled
+	}
+	if result.MinReplicas == nil {
+		result.MinReplicas = defaults.MinReplicas
+	}
+	if result.MaxReplicas == nil {
+		result.MaxReplicas = defaults.MaxReplicas
+	}
+	if result.StaticReplicas == nil {
+		result.StaticReplicas = defaults.StaticReplicas
+	}
+	if result.QueueLength == nil {
+		result.QueueLength = defaults.QueueLength
+	}
+	if result.CooldownPeriod == nil {
+		result.CooldownPeriod = defaults.CooldownPeriod
+	}
+	if result.PollingInterval == nil {
+		result.PollingInterval = defaults.PollingInterval
+	}
+	if result.DrainOnStop == nil {
+		result.DrainOnStop = defaults.DrainOnStop
+	}
+	if result.TerminationGracePeriodSeconds == nil {
+		result.TerminationGracePeriodSeconds = defaults.TerminationGracePeriodSeconds
+	}
+	return result
 }
 
 // WorkerAutoscalingConfig defines scaling per worker type
@@ -616,9 +1039,40 @@ type WorkerAutoscalingConfig struct {
 	Default *WorkerAutoscaling `json:"default,omitempty"`
 }
 
+// WorkerPool is a named worker Deployment consuming one RQ queue
+type WorkerPool struct {
+	// Name of the pool; the Deployment is named `<bench>-worker-<name>`
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Queue the pool's workers consume. Defaults to the pool name.
+	// +optional
+	Queue string `json:"queue,omitempty"`
+
+	// Replicas runs a fixed number of workers, shorthand for autoscaling with
+	// enabled=false and staticReplicas. Mutually exclusive with Autoscaling.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Autoscaling configures KEDA or static scaling for the pool. Pools named
+	// default, long or short fall back to workerAutoscaling and their built-in
+	// defaults; other pools default to one static replica.
+	// +optional
+	Autoscaling *WorkerAutoscaling `json:"autoscaling,omitempty"`
+
+	// Resources for the pool's workers. Pools named long or short default to
+	// componentResources.workerLong/workerShort, all others to workerDefault.
+	// +optional
+	Resources *ResourceRequirements `json:"resources,omitempty"`
+}
+
 // RouteConfig defines OpenShift Route configuration for a site
 type RouteConfig struct {
-	// Enabled controls whether Route should be created (defaults to true on OpenShift)
+	// Enabled controls whether Route should be created (defaults to true on OpenShift,
+	// false when the operator config sets preferIngressOnOpenShift)
 	// +optional
 	Enabled *bool `json:"enabled,omitempty"`
 
@@ -643,6 +1097,18 @@ type RouteConfig struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
+// DNSConfig opts a site into DNS record management by external-dns
+type DNSConfig struct {
+	// ExternalDNS annotates the site's Ingress or Route with
+	// external-dns.alpha.kubernetes.io/hostname so external-dns creates the DNS record
+	// +optional
+	ExternalDNS bool `json:"externalDNS,omitempty"`
+
+	// ExternalDNSHostname is the hostname external-dns publishes; defaults to the resolved domain
+	// +optional
+	ExternalDNSHostname string `json:"externalDNSHostname,omitempty"`
+}
+
 // MustParseQuantity parses a resource quantity string and panics on error
 // This is a convenience function for tests and static initialization
 func MustParseQuantity(s string) resource.Quantity {
@@ -676,3 +1142,28 @@ type S3Config struct {
 	// +kubebuilder:default=true
 	UseSSL bool `json:"useSSL,omitempty"`
 }
+
+// OperationProgress reports how far a running backup or restore job has got
+type OperationProgress struct {
+	// Stage is the step the job is in (e.g. "Backing up", "Downloading database.sql.gz", "Importing")
+	// +optional
+	Stage string `json:"stage,omitempty"`
+
+	// Percent is BytesProcessed relative to BytesTotal
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Percent int32 `json:"percent,omitempty"`
+
+	// BytesProcessed is the number of bytes written or downloaded so far in this stage
+	// +optional
+	BytesProcessed int64 `json:"bytesProcessed,omitempty"`
+
+	// BytesTotal is the expected number of bytes for this stage (0 when unknown)
+	// +optional
+	BytesTotal int64 `json:"bytesTotal,omitempty"`
+
+	// LastUpdated is when the progress was last read from the job
+	// +optional
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
@@ -34,6 +34,38 @@ type SiteBackupSpec struct {
 	// +optional
 	Schedule string `json:"schedule,omitempty"`
 
+	// ConcurrencyPolicy of the scheduled backup CronJob: Allow, Forbid or Replace.
+	// Defaults to Forbid, so a run is skipped while the previous one is still going.
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	// +optional
+	ConcurrencyPolicy string `json:"concurrencyPolicy,omitempty"`
+
+	// SuccessfulJobsHistoryLimit is how many completed backup Jobs the CronJob keeps
+	// (Kubernetes default 3)
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit,omitempty"`
+
+	// FailedJobsHistoryLimit is how many failed backup Jobs the CronJob keeps
+	// (Kubernetes default 1)
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+
+	// StartingDeadlineSeconds skips a scheduled run that could not start within this
+	// many seconds of its scheduled time
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// Mode selects what the backup contains: full (database and files), db-only or
+	// files-only, e.g. to schedule frequent db-only backups next to rare full ones.
+	// bench backup always dumps the database, so files-only discards the dump written
+	// by the run. When empty, withFiles decides whether the files are included.
+	// +kubebuilder:validation:Enum=full;db-only;files-only
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
 	// WithFiles includes private and public files in the backup
 	// +optional
 	// +kubebuilder:default=false
@@ -44,10 +76,30 @@ type SiteBackupSpec struct {
 	// +kubebuilder:default=false
 	Compress bool `json:"compress,omitempty"`
 
-	// Storage configures where to store the backup
+	// Storage configures where to store the backup. With storage.s3 set, the files
+	// written by each run are uploaded to the bucket under <site>/<job name>/.
 	// +optional
 	Storage *BackupStorageConfig `json:"storage,omitempty"`
 
+	// ExecutionNamespace runs the backup Job (or CronJob) in another namespace, e.g. a
+	// dedicated backup namespace with its own quota. The bench's sites volume must be
+	// ReadWriteMany and backed by CSI or NFS so it can be bound a second time there.
+	// The namespace must be listed in the operator config's backupExecutionNamespaces.
+	// Defaults to the SiteBackup's namespace.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +optional
+	ExecutionNamespace string `json:"executionNamespace,omitempty"`
+
+	// RedactConfig replaces credentials in the backed-up site_config.json (db_password,
+	// encryption_key and any key ending in password, secret, token or _key) with a
+	// placeholder, for backups shared with less-trusted systems. The real values are kept
+	// in the <name>-redacted-config Secret, which SiteRestore's redactedConfigSecretRef
+	// re-injects them from when restoring the config.
+	// +optional
+	// +kubebuilder:default=false
+	RedactConfig bool `json:"redactConfig,omitempty"`
+
 	// BackupPath specifies the path to save the backup files
 	// If empty, uses the default site backup location
 	// +optional
@@ -102,19 +154,72 @@ type SiteBackupStatus struct {
 	// +optional
 	LastBackupJob string `json:"lastBackupJob,omitempty"`
 
+	// Mode is the backup mode of the last backup job or cronjob
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
 	// Message provides additional information about the backup status
 	// +optional
 	Message string `json:"message,omitempty"`
+
+	// LogTail holds the last lines of the log of a failed backup pod
+	// +optional
+	LogTail string `json:"logTail,omitempty"`
+
+	// FailureSummary is a one-line reason for a failed backup, taken from known Frappe
+	// errors in the pod log when possible
+	// +optional
+	FailureSummary string `json:"failureSummary,omitempty"`
+
+	// Progress reports how far a running one-time backup has got
+	// +optional
+	Progress *OperationProgress `json:"progress,omitempty"`
+
+	// S3 is where the last one-time backup was uploaded, when storage.s3 is set
+	// +optional
+	S3 *BackupS3Location `json:"s3,omitempty"`
+
+	// BackupFiles are the files the last successful backup wrote to the sites volume
+	// +optional
+	BackupFiles []BackupFile `json:"backupFiles,omitempty"`
+
+	// TotalBytes is the combined size of BackupFiles
+	// +optional
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+
+	// Conditions represent the latest available observations of the backup's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// BackupFile is a file written by a backup
+type BackupFile struct {
+	// Path of the file in the backup pod, e.g. /home/frappe/frappe-bench/sites/<site>/private/backups/...
+	Path string `json:"path"`
+
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+}
+
+// BackupS3Location identifies an uploaded backup
+type BackupS3Location struct {
+	// Bucket the backup was uploaded to
+	Bucket string `json:"bucket"`
+
+	// Key of the database backup, or of the public files archive of a files-only
+	// backup; the other files of the same run are stored next to it
+	Key string `json:"key"`
 }
 
 // BackupStorageConfig defines storage backend for backups
 type BackupStorageConfig struct {
-	// Type of storage: s3 or pvc
+	// Type of storage: s3 or pvc. With s3, storage.s3 is required and the backup
+	// files are removed from the sites volume once uploaded.
 	// +kubebuilder:validation:Enum=s3;pvc
 	// +kubebuilder:default=pvc
 	Type string `json:"type,omitempty"`
 
-	// S3 configuration
+	// S3 configuration; the backup is uploaded whenever it is set
 	// +optional
 	S3 *S3Config `json:"s3,omitempty"`
 
@@ -125,6 +230,11 @@ type BackupStorageConfig struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.site`
+//+kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.status.mode`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Last Backup",type="date",JSONPath=`.status.lastBackup`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // SiteBackup is the Schema for the sitebackups API
 type SiteBackup struct {
@@ -20,26 +20,152 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
-
-// SiteDashboardChartSpec defines the desired state of SiteDashboardChart
+// SiteDashboardChartSpec defines the desired state of SiteDashboardChart: a Frappe
+// Dashboard Chart, declared as code
 type SiteDashboardChartSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Site is the name of the Frappe site to configure (spec.siteName of a FrappeSite in
+	// the SiteDashboardChart's namespace)
+	Site string `json:"site"`
+
+	// ChartName is the name of the Dashboard Chart document; an existing chart of that name
+	// is replaced
+	// +kubebuilder:validation:MinLength=1
+	ChartName string `json:"chartName"`
+
+	// ChartType selects how the source documents are aggregated
+	// +kubebuilder:validation:Enum=Count;Sum;Average;Group By
+	// +kubebuilder:default=Count
+	// +optional
+	ChartType string `json:"chartType,omitempty"`
+
+	// Type is how the chart is drawn
+	// +kubebuilder:validation:Enum=Line;Bar;Percentage;Pie;Donut;Heatmap
+	// +kubebuilder:default=Line
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// DocumentType is the source DocType, e.g. Sales Invoice
+	// +kubebuilder:validation:MinLength=1
+	DocumentType string `json:"documentType"`
+
+	// ValueBasedOn is the numeric field summed or averaged; required for Sum and Average
+	// +optional
+	ValueBasedOn string `json:"valueBasedOn,omitempty"`
+
+	// TimeSeries plots the documents over time; required unless chartType is Group By
+	// +optional
+	TimeSeries *DashboardChartTimeSeries `json:"timeSeries,omitempty"`
+
+	// GroupBy groups the documents by a field; required for chartType Group By
+	// +optional
+	GroupBy *DashboardChartGroupBy `json:"groupBy,omitempty"`
 
-	// Foo is an example field of SiteDashboardChart. Edit sitedashboardchart_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	// Filters restrict the source documents; all must match
+	// +optional
+	Filters []DashboardChartFilter `json:"filters,omitempty"`
+
+	// Color of the chart, e.g. #449CF0
+	// +optional
+	Color string `json:"color,omitempty"`
+
+	// Module the chart belongs to, e.g. Accounts
+	// +optional
+	Module string `json:"module,omitempty"`
+}
+
+// DashboardChartTimeSeries places documents on a time axis by a date field
+type DashboardChartTimeSeries struct {
+	// BasedOn is the date field, e.g. posting_date
+	BasedOn string `json:"basedOn"`
+
+	// Timespan is the period shown
+	// +kubebuilder:validation:Enum=Last Year;Last Quarter;Last Month;Last Week
+	// +kubebuilder:default=Last Year
+	// +optional
+	Timespan string `json:"timespan,omitempty"`
+
+	// TimeInterval is the width of each point
+	// +kubebuilder:validation:Enum=Yearly;Quarterly;Monthly;Weekly;Daily
+	// +kubebuilder:default=Monthly
+	// +optional
+	TimeInterval string `json:"timeInterval,omitempty"`
+}
+
+// DashboardChartGroupBy groups documents by the values of a field
+type DashboardChartGroupBy struct {
+	// Field whose values form the groups, e.g. customer
+	Field string `json:"field"`
+
+	// Type of the per-group value
+	// +kubebuilder:validation:Enum=Count;Sum;Average
+	// +kubebuilder:default=Count
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// AggregateField is the numeric field summed or averaged; required for Sum and Average
+	// +optional
+	AggregateField string `json:"aggregateField,omitempty"`
+
+	// NumberOfGroups limits the chart to the largest groups; 0 shows all
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	NumberOfGroups int32 `json:"numberOfGroups,omitempty"`
+}
+
+// DashboardChartFilter is a condition on a field of the source documents
+type DashboardChartFilter struct {
+	// Field of the source DocType
+	Field string `json:"field"`
+
+	// Operator compares the field with the value
+	// +kubebuilder:validation:Enum="=";"!=";">";"<";">=";"<=";like;not like;in;not in;is
+	// +kubebuilder:default="="
+	// +optional
+	Operator string `json:"operator,omitempty"`
+
+	// Value to compare with; a comma-separated list for in and not in, set or not set for is
+	Value string `json:"value"`
 }
 
 // SiteDashboardChartStatus defines the observed state of SiteDashboardChart
 type SiteDashboardChartStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Phase is Pending, Applying, Applied or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// ChartName is the name of the Dashboard Chart last applied on the site; it is removed
+	// from the site when the chart is renamed or the SiteDashboardChart is deleted
+	// +optional
+	ChartName string `json:"chartName,omitempty"`
+
+	// Hash identifies the chart definition last applied or attempted; the definition is
+	// applied again only when it changes
+	// +optional
+	Hash string `json:"hash,omitempty"`
+
+	// JobName is the name of the Job applying or removing the chart
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Message provides additional information about the phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastAppliedTime is when the chart was last applied successfully
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// Conditions holds the Applied condition
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.site`
+//+kubebuilder:printcolumn:name="Chart",type=string,JSONPath=`.spec.chartName`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // SiteDashboardChart is the Schema for the sitedashboardcharts API
 type SiteDashboardChart struct {
@@ -23,23 +23,138 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// SiteJobCommand is a bench command a SiteJob may run
+// +kubebuilder:validation:Enum=clear-cache;migrate;build;set-config;execute
+type SiteJobCommand string
+
+const (
+	// SiteJobClearCache runs `bench --site <site> clear-cache`
+	SiteJobClearCache SiteJobCommand = "clear-cache"
+	// SiteJobMigrate runs `bench --site <site> migrate`
+	SiteJobMigrate SiteJobCommand = "migrate"
+	// SiteJobBuild runs `bench build` for the bench's assets
+	SiteJobBuild SiteJobCommand = "build"
+	// SiteJobSetConfig runs `bench --site <site> set-config <key> <value>`
+	SiteJobSetConfig SiteJobCommand = "set-config"
+	// SiteJobExecute runs `bench --site <site> execute <method>`
+	SiteJobExecute SiteJobCommand = "execute"
+)
+
 // SiteJobSpec defines the desired state of SiteJob
 type SiteJobSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Site is the name of the Frappe site to run the command on (spec.siteName of a
+	// FrappeSite in the SiteJob's namespace)
+	// +optional
+	Site string `json:"site,omitempty"`
+
+	// Command is the bench command to run. Its arguments come from the field of the
+	// same name and are passed to bench as separate arguments, never through a shell.
+	// Without a command the SiteJob only tracks a Job named <sitejob>-job created by hand.
+	// +optional
+	Command SiteJobCommand `json:"command,omitempty"`
+
+	// Migrate holds the arguments of the migrate command
+	// +optional
+	Migrate *SiteJobMigrateArgs `json:"migrate,omitempty"`
+
+	// Build holds the arguments of the build command
+	// +optional
+	Build *SiteJobBuildArgs `json:"build,omitempty"`
+
+	// SetConfig holds the arguments of the set-config command
+	// +optional
+	SetConfig *SiteJobSetConfigArgs `json:"setConfig,omitempty"`
+
+	// Execute holds the arguments of the execute command
+	// +optional
+	Execute *SiteJobExecuteArgs `json:"execute,omitempty"`
+
+	// TimeoutSeconds bounds how long the job may run. It is applied as the Job's
+	// activeDeadlineSeconds; a job that exceeds it fails with a JobTimedOut event.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+}
+
+// SiteJobMigrateArgs are the arguments of `bench migrate`
+type SiteJobMigrateArgs struct {
+	// SkipFailing continues past patches that fail
+	// +optional
+	SkipFailing bool `json:"skipFailing,omitempty"`
+}
+
+// SiteJobBuildArgs are the arguments of `bench build`
+type SiteJobBuildArgs struct {
+	// App builds the assets of this app only
+	// +optional
+	App string `json:"app,omitempty"`
+}
+
+// SiteJobSetConfigArgs are the arguments of `bench set-config`
+type SiteJobSetConfigArgs struct {
+	// Key is the site_config.json key to set. Keys managed by the operator are rejected.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Key string `json:"key"`
+
+	// Value is the value to set
+	Value string `json:"value"`
+
+	// Parse stores the value as JSON, e.g. a number or list, instead of a string
+	// +optional
+	Parse bool `json:"parse,omitempty"`
+}
+
+// SiteJobExecuteArgs are the arguments of `bench execute`
+type SiteJobExecuteArgs struct {
+	// Method is the dotted path of the Python function to call, e.g. frappe.utils.scheduler.enable_scheduler
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)+$`
+	Method string `json:"method"`
+
+	// Args are the positional arguments of the function
+	// +optional
+	Args []string `json:"args,omitempty"`
 
-	// Foo is an example field of SiteJob. Edit sitejob_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	// Kwargs are the keyword arguments of the function
+	// +optional
+	Kwargs map[string]string `json:"kwargs,omitempty"`
 }
 
 // SiteJobStatus defines the observed state of SiteJob
 type SiteJobStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Phase is Pending, Running, Succeeded or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// JobName is the name of the Job running the command
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Message provides additional information about the job status
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LogTail holds the last lines of the failed pod's log
+	// +optional
+	LogTail string `json:"logTail,omitempty"`
+
+	// FailureSummary is a one-line reason for the failure, taken from known Frappe
+	// errors in the pod log when possible
+	// +optional
+	FailureSummary string `json:"failureSummary,omitempty"`
+
+	// ExitCode is the exit status of the command's container
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// CompletionTime is the timestamp when the job finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // SiteJob is the Schema for the sitejobs API
 type SiteJob struct {
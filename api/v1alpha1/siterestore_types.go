@@ -64,6 +64,18 @@ type SiteRestoreSpec struct {
 	// +optional
 	PrivateFilesSource *BackupSource `json:"privateFilesSource,omitempty"`
 
+	// ConfigBackupSource specifies where to get the site_config.json backup from.
+	// Database and redis connection settings always come from the live site. Values
+	// redacted by SiteBackup's redactConfig come from RedactedConfigSecretRef.
+	// +optional
+	ConfigBackupSource *BackupSource `json:"configBackupSource,omitempty"`
+
+	// RedactedConfigSecretRef names the <sitebackup>-redacted-config Secret a SiteBackup
+	// with redactConfig keeps the redacted values in. Required to restore a redacted
+	// config backup whose encryption_key is redacted.
+	// +optional
+	RedactedConfigSecretRef *corev1.LocalObjectReference `json:"redactedConfigSecretRef,omitempty"`
+
 	// AdminPasswordSecretRef references a secret key containing the new admin password
 	// +optional
 	AdminPasswordSecretRef *corev1.SecretKeySelector `json:"adminPasswordSecretRef,omitempty"`
@@ -72,6 +84,19 @@ type SiteRestoreSpec struct {
 	// +optional
 	// +kubebuilder:default=false
 	Force bool `json:"force,omitempty"`
+
+	// VolumeSnapshot takes a VolumeSnapshot of the bench's sites PVC before the restore
+	// job runs, as a filesystem-level rollback point. Requires the snapshot.storage.k8s.io CRDs.
+	// +optional
+	VolumeSnapshot *VolumeSnapshotConfig `json:"volumeSnapshot,omitempty"`
+}
+
+// VolumeSnapshotConfig configures a VolumeSnapshot of the sites PVC
+type VolumeSnapshotConfig struct {
+	// VolumeSnapshotClassName is the VolumeSnapshotClass used for the snapshot
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName"`
 }
 
 // SiteRestoreStatus defines the observed state of SiteRestore
@@ -91,12 +116,22 @@ type SiteRestoreStatus struct {
 	// Message provides additional information about the restore status
 	// +optional
 	Message string `json:"message,omitempty"`
+
+	// Progress reports how far a running restore has got
+	// +optional
+	Progress *OperationProgress `json:"progress,omitempty"`
+
+	// VolumeSnapshot is the name of the VolumeSnapshot taken before the restore, if any.
+	// It is not owned by the SiteRestore and can be restored from manually.
+	// +optional
+	VolumeSnapshot string `json:"volumeSnapshot,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 //+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.site`
+//+kubebuilder:printcolumn:name="Progress",type=integer,JSONPath=`.status.progress.percent`
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // SiteRestore is the Schema for the siterestores API
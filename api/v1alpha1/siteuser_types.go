@@ -17,29 +17,83 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
-
-// SiteUserSpec defines the desired state of SiteUser
+// SiteUserSpec defines the desired state of SiteUser: a Frappe user whose password and
+// enabled flag the operator keeps in line with the spec
 type SiteUserSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Site is the name of the Frappe site to configure (spec.siteName of a FrappeSite in
+	// the SiteUser's namespace)
+	Site string `json:"site"`
+
+	// Email is the user's login and the name of the User document; a missing user is created
+	// +kubebuilder:validation:MinLength=3
+	Email string `json:"email"`
+
+	// FirstName of the user; defaults to the part of the email before the @ when the user is created
+	// +optional
+	FirstName string `json:"firstName,omitempty"`
 
-	// Foo is an example field of SiteUser. Edit siteuser_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	// LastName of the user
+	// +optional
+	LastName string `json:"lastName,omitempty"`
+
+	// PasswordSecretRef selects the key of a Secret in the SiteUser's namespace holding the
+	// user's password. The password is set again whenever the Secret changes.
+	// +optional
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+
+	// Enabled controls whether the user can log in; a disabled user is kept, not deleted
+	// +kubebuilder:default=true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
 }
 
 // SiteUserStatus defines the observed state of SiteUser
 type SiteUserStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Phase is Pending, Applying, Applied or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Hash identifies the user state last applied or attempted; the user is updated again
+	// only when it changes
+	// +optional
+	Hash string `json:"hash,omitempty"`
+
+	// JobName is the name of the Job updating the user
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Message provides additional information about the phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Enabled is the enabled flag last applied to the user
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// PasswordSecretVersion is the resourceVersion of the password Secret last applied
+	// +optional
+	PasswordSecretVersion string `json:"passwordSecretVersion,omitempty"`
+
+	// LastAppliedTime is when the user was last updated successfully
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// Conditions holds the MissingSecret condition
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.site`
+//+kubebuilder:printcolumn:name="Email",type=string,JSONPath=`.spec.email`
+//+kubebuilder:printcolumn:name="Enabled",type=boolean,JSONPath=`.status.enabled`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // SiteUser is the Schema for the siteusers API
 type SiteUser struct {
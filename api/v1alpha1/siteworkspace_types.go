@@ -20,26 +20,124 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
-
-// SiteWorkspaceSpec defines the desired state of SiteWorkspace
+// SiteWorkspaceSpec defines the desired state of SiteWorkspace: a Frappe Workspace, the
+// sidebar page grouping shortcuts and link cards, declared as code
 type SiteWorkspaceSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Site is the name of the Frappe site to configure (spec.siteName of a FrappeSite in
+	// the SiteWorkspace's namespace)
+	Site string `json:"site"`
+
+	// Label is the name of the Workspace document; an existing workspace of that name is replaced
+	// +kubebuilder:validation:MinLength=1
+	Label string `json:"label"`
+
+	// Title shown in the sidebar; defaults to the label
+	// +optional
+	Title string `json:"title,omitempty"`
+
+	// Module the workspace belongs to, e.g. Selling
+	// +optional
+	Module string `json:"module,omitempty"`
+
+	// Icon is the name of a Frappe icon, e.g. sell
+	// +optional
+	Icon string `json:"icon,omitempty"`
+
+	// ParentPage nests the workspace under another workspace in the sidebar
+	// +optional
+	ParentPage string `json:"parentPage,omitempty"`
+
+	// SequenceID orders the workspace in the sidebar
+	// +optional
+	SequenceID *int32 `json:"sequenceId,omitempty"`
+
+	// Shortcuts are the tiles at the top of the workspace, in order
+	// +optional
+	Shortcuts []WorkspaceShortcut `json:"shortcuts,omitempty"`
+
+	// Cards are the link cards below the shortcuts, in order
+	// +optional
+	Cards []WorkspaceCard `json:"cards,omitempty"`
+}
+
+// WorkspaceShortcut is a tile linking to a DocType, Report, Page, Dashboard or URL
+type WorkspaceShortcut struct {
+	// Label of the tile
+	Label string `json:"label"`
+
+	// Type of the target
+	// +kubebuilder:validation:Enum=DocType;Report;Page;Dashboard;URL
+	Type string `json:"type"`
+
+	// LinkTo names the target; required unless type is URL
+	// +optional
+	LinkTo string `json:"linkTo,omitempty"`
 
-	// Foo is an example field of SiteWorkspace. Edit siteworkspace_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	// URL is the target of a URL shortcut
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Color of the tile, e.g. Blue
+	// +optional
+	Color string `json:"color,omitempty"`
+}
+
+// WorkspaceCard is a titled card of links
+type WorkspaceCard struct {
+	// Label is the card title
+	Label string `json:"label"`
+
+	// Links of the card, in order
+	// +optional
+	Links []WorkspaceLink `json:"links,omitempty"`
+}
+
+// WorkspaceLink is a link on a card
+type WorkspaceLink struct {
+	// Label of the link
+	Label string `json:"label"`
+
+	// Type of the target
+	// +kubebuilder:validation:Enum=DocType;Report;Page
+	Type string `json:"type"`
+
+	// LinkTo names the target
+	LinkTo string `json:"linkTo"`
+
+	// Onboard marks the link as an onboarding step
+	// +optional
+	Onboard bool `json:"onboard,omitempty"`
 }
 
 // SiteWorkspaceStatus defines the observed state of SiteWorkspace
 type SiteWorkspaceStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Phase is Applying, Applied or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Hash identifies the workspace definition last applied or attempted; the definition
+	// is applied again only when it changes
+	// +optional
+	Hash string `json:"hash,omitempty"`
+
+	// JobName is the name of the Job applying the workspace
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Message provides additional information about the phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastAppliedTime is when the workspace was last applied successfully
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.site`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // SiteWorkspace is the Schema for the siteworkspaces API
 type SiteWorkspace struct {
@@ -18,12 +18,20 @@ package v1alpha1
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestFrappeBenchValidateCreate(t *testing.T) {
+	drain := true
+	noGrace, grace := int64(0), int64(600)
+	sleep, longSleep := int64(10), int64(45)
+	replicas := int32(2)
 	tests := []struct {
 		name    string
 		bench   *FrappeBench
@@ -71,6 +79,174 @@ func TestFrappeBenchValidateCreate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "worker drain without grace period",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					WorkerAutoscaling: &WorkerAutoscalingConfig{
+						Long: &WorkerAutoscaling{DrainOnStop: &drain, TerminationGracePeriodSeconds: &noGrace},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "worker drain with grace period",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					WorkerAutoscaling: &WorkerAutoscalingConfig{
+						Long: &WorkerAutoscaling{DrainOnStop: &drain, TerminationGracePeriodSeconds: &grace},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "custom worker pools",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					Workers: []WorkerPool{
+						{Name: "default"},
+						{Name: "integrations", Replicas: &replicas},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate worker pool",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					Workers:       []WorkerPool{{Name: "integrations"}, {Name: "integrations"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "worker pool with replicas and autoscaling",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					Workers: []WorkerPool{
+						{Name: "integrations", Replicas: &replicas, Autoscaling: &WorkerAutoscaling{}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "worker pool drain without grace period",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					Workers: []WorkerPool{
+						{Name: "integrations", Autoscaling: &WorkerAutoscaling{DrainOnStop: &drain, TerminationGracePeriodSeconds: &noGrace}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "graceful shutdown",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion:    "version-15",
+					AppsJSON:         `["frappe"]`,
+					GracefulShutdown: &GracefulShutdownConfig{Seconds: &grace, PreStopSleepSeconds: &sleep},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "preStop sleep outlasting the default grace period",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion:    "version-15",
+					AppsJSON:         `["frappe"]`,
+					GracefulShutdown: &GracefulShutdownConfig{PreStopSleepSeconds: &longSleep},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "per-component images",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					ImageConfig: &ImageConfig{Components: &ComponentImages{
+						Gunicorn: &ComponentImage{Repository: "registry.example.com:5000/erp/web"},
+						Worker:   &ComponentImage{Tag: "v15.2.0-slim"},
+					}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "component image with an invalid tag",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					ImageConfig:   &ImageConfig{Components: &ComponentImages{Worker: &ComponentImage{Tag: "-slim"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "component image repository with a tag",
+			bench: &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-bench",
+				},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					AppsJSON:      `["frappe"]`,
+					ImageConfig:   &ImageConfig{Components: &ComponentImages{SocketIO: &ComponentImage{Repository: "erp/socketio:v15"}}},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,6 +362,277 @@ func TestFrappeSiteValidateCreate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "init script preamble without key",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					InitScriptPreamble: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "preamble"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid env",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					Env: []SiteEnvVar{
+						{Name: "FRAPPE_REDIS_CACHE", Value: "redis://cache:6379"},
+						{Name: "API_TOKEN", SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "tokens"},
+							Key:                  "api",
+						}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "reserved env name",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					Env: []SiteEnvVar{
+						{Name: "DB_PASSWORD", Value: "override"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "env with value and secretKeyRef",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					Env: []SiteEnvVar{
+						{Name: "API_TOKEN", Value: "x", SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "tokens"},
+							Key:                  "api",
+						}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid env name",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					Env: []SiteEnvVar{
+						{Name: "1TOKEN", Value: "x"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "siteName not a DNS name",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "Test_Site.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "external provider without connection secret",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					DBConfig: DatabaseConfig{Provider: "external"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "external provider with connection secret",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					DBConfig: DatabaseConfig{
+						Provider:            "external",
+						ConnectionSecretRef: &corev1.SecretReference{Name: "db-conn"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "external provider with connection secret in the site's namespace",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-site",
+					Namespace: "erp",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					DBConfig: DatabaseConfig{
+						Provider:            "external",
+						ConnectionSecretRef: &corev1.SecretReference{Name: "db-conn", Namespace: "erp"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "external provider with connection secret in another namespace",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-site",
+					Namespace: "erp",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					DBConfig: DatabaseConfig{
+						Provider:            "external",
+						ConnectionSecretRef: &corev1.SecretReference{Name: "db-conn", Namespace: "other-tenant"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsafe app name",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					Apps: []string{"erpnext", "hrms; rm -rf /"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "aliases",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					Domain:   "shop.example.com",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					Aliases: []string{"www.shop.example.com", "shop.example.org"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "alias repeats the domain",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-site",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					Domain:   "shop.example.com",
+					BenchRef: &NamespacedName{
+						Name: "test-bench",
+					},
+					Aliases: []string{"shop.example.com"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "bench in the site's namespace",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-site",
+					Namespace: "erp",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name:      "test-bench",
+						Namespace: "erp",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "bench in another namespace",
+			site: &FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-site",
+					Namespace: "erp",
+				},
+				Spec: FrappeSiteSpec{
+					SiteName: "test.local",
+					BenchRef: &NamespacedName{
+						Name:      "test-bench",
+						Namespace: "shared-benches",
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -231,6 +678,303 @@ func TestFrappeBenchValidateDelete(t *testing.T) {
 	}
 }
 
+func TestFrappeBenchValidateWorkerReplicas(t *testing.T) {
+	int32p := func(v int32) *int32 { return &v }
+	boolp := func(v bool) *bool { return &v }
+	tests := []struct {
+		name        string
+		autoscaling *WorkerAutoscalingConfig
+		workers     []WorkerPool
+		wantErr     string
+	}{
+		{
+			name:        "min below max",
+			autoscaling: &WorkerAutoscalingConfig{Short: &WorkerAutoscaling{MinReplicas: int32p(1), MaxReplicas: int32p(4)}},
+		},
+		{
+			name:        "min above max",
+			autoscaling: &WorkerAutoscalingConfig{Short: &WorkerAutoscaling{MinReplicas: int32p(5), MaxReplicas: int32p(2)}},
+			wantErr:     "workerAutoscaling.short.minReplicas (5) must not exceed maxReplicas (2)",
+		},
+		{
+			name:        "min above the worker type's default max",
+			autoscaling: &WorkerAutoscalingConfig{Long: &WorkerAutoscaling{MinReplicas: int32p(6)}},
+			wantErr:     "workerAutoscaling.long.minReplicas (6) must not exceed maxReplicas (5)",
+		},
+		{
+			name:        "enabled with zero max",
+			autoscaling: &WorkerAutoscalingConfig{Short: &WorkerAutoscaling{MinReplicas: int32p(0), MaxReplicas: int32p(0)}},
+			wantErr:     "workerAutoscaling.short.maxReplicas must be at least 1",
+		},
+		{
+			name:        "enabled without max on a static worker type",
+			autoscaling: &WorkerAutoscalingConfig{Default: &WorkerAutoscaling{Enabled: boolp(true), StaticReplicas: int32p(2)}},
+			wantErr:     "workerAutoscaling.default.maxReplicas must be at least 1",
+		},
+		{
+			name:        "disabled with static replicas only",
+			autoscaling: &WorkerAutoscalingConfig{Default: &WorkerAutoscaling{Enabled: boolp(false), StaticReplicas: int32p(2)}},
+		},
+		{
+			name:    "pool min above max",
+			workers: []WorkerPool{{Name: "reports", Autoscaling: &WorkerAutoscaling{Enabled: boolp(true), MinReplicas: int32p(3), MaxReplicas: int32p(1)}}},
+			wantErr: "workers[reports].autoscaling.minReplicas (3) must not exceed maxReplicas (1)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bench := &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-bench"},
+				Spec: FrappeBenchSpec{
+					FrappeVersion:     "version-15",
+					Apps:              []AppSource{{Name: "frappe", Source: "image"}},
+					WorkerAutoscaling: tt.autoscaling,
+					Workers:           tt.workers,
+				},
+			}
+			_, err := (&FrappeBench{}).ValidateCreate(context.TODO(), bench)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateCreate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateCreate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFrappeBenchValidateExtraVolumes(t *testing.T) {
+	logs := corev1.Volume{Name: "logs", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	tests := []struct {
+		name    string
+		volumes []corev1.Volume
+		mounts  []corev1.VolumeMount
+		wantErr string
+	}{
+		{
+			name:    "logs mount next to sites",
+			volumes: []corev1.Volume{logs},
+			mounts:  []corev1.VolumeMount{{Name: "logs", MountPath: "/home/frappe/frappe-bench/logs"}},
+		},
+		{
+			name:    "reserved name",
+			volumes: []corev1.Volume{{Name: "sites"}},
+			wantErr: "extraVolumes[sites]: name is reserved",
+		},
+		{
+			name:    "mount without volume",
+			mounts:  []corev1.VolumeMount{{Name: "logs", MountPath: "/logs"}},
+			wantErr: "extraVolumeMounts[logs]: no extra volume with this name",
+		},
+		{
+			name:    "mount on the sites path",
+			volumes: []corev1.Volume{logs},
+			mounts:  []corev1.VolumeMount{{Name: "logs", MountPath: "/home/frappe/frappe-bench/sites/"}},
+			wantErr: "overlaps the sites mount",
+		},
+		{
+			name:    "mount inside sites",
+			volumes: []corev1.Volume{logs},
+			mounts:  []corev1.VolumeMount{{Name: "logs", MountPath: "/home/frappe/frappe-bench/sites/logs"}},
+			wantErr: "overlaps the sites mount",
+		},
+		{
+			name:    "mount above sites",
+			volumes: []corev1.Volume{logs},
+			mounts:  []corev1.VolumeMount{{Name: "logs", MountPath: "/home/frappe"}},
+			wantErr: "overlaps the sites mount",
+		},
+		{
+			name:    "relative path",
+			volumes: []corev1.Volume{logs},
+			mounts:  []corev1.VolumeMount{{Name: "logs", MountPath: "logs"}},
+			wantErr: "must be absolute",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bench := &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-bench"},
+				Spec: FrappeBenchSpec{
+					FrappeVersion:     "version-15",
+					Apps:              []AppSource{{Name: "frappe", Source: "image"}},
+					ExtraVolumes:      tt.volumes,
+					ExtraVolumeMounts: tt.mounts,
+				},
+			}
+			_, err := (&FrappeBench{}).ValidateCreate(context.TODO(), bench)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateCreate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateCreate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFrappeBenchValidateRedisSecretNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		wantErr   bool
+	}{
+		{name: "no namespace"},
+		{name: "bench namespace", namespace: "erp"},
+		{name: "other namespace", namespace: "other-tenant", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bench := &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-bench", Namespace: "erp"},
+				Spec: FrappeBenchSpec{
+					FrappeVersion: "version-15",
+					Apps:          []AppSource{{Name: "frappe", Source: "image"}},
+					RedisConfig: &RedisConfig{
+						ConnectionSecretRef: &corev1.SecretReference{Name: "elasticache", Namespace: tt.namespace},
+					},
+				},
+			}
+			_, err := (&FrappeBench{}).ValidateCreate(context.TODO(), bench)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "redisConfig.connectionSecretRef.namespace") {
+				t.Errorf("expected the error to name the field, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFrappeBenchValidateUpdateStrategy(t *testing.T) {
+	zero, one := intstr.FromInt32(0), intstr.FromInt32(1)
+	zeroPercent, tooMuch := intstr.FromString("0%"), intstr.FromString("150%")
+	tests := []struct {
+		name     string
+		strategy *ComponentUpdateStrategy
+		wantErr  string
+	}{
+		{name: "no surge", strategy: &ComponentUpdateStrategy{MaxSurge: &zero, MaxUnavailable: &one}},
+		{name: "defaults", strategy: &ComponentUpdateStrategy{}},
+		{name: "recreate", strategy: &ComponentUpdateStrategy{Type: UpdateStrategyRecreate}},
+		{
+			name:     "recreate with bounds",
+			strategy: &ComponentUpdateStrategy{Type: UpdateStrategyRecreate, MaxSurge: &zero},
+			wantErr:  "updateStrategy.gunicorn: maxSurge and maxUnavailable only apply to type RollingUpdate",
+		},
+		{
+			name:     "no room to roll",
+			strategy: &ComponentUpdateStrategy{MaxSurge: &zero, MaxUnavailable: &zeroPercent},
+			wantErr:  "must not both be zero",
+		},
+		{
+			name:     "percentage above 100",
+			strategy: &ComponentUpdateStrategy{MaxUnavailable: &tooMuch},
+			wantErr:  "updateStrategy.gunicorn.maxUnavailable \"150%\" must be a count or a percentage",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bench := &FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-bench"},
+				Spec: FrappeBenchSpec{
+					FrappeVersion:  "version-15",
+					Apps:           []AppSource{{Name: "frappe", Source: "image"}},
+					UpdateStrategy: &BenchUpdateStrategy{Gunicorn: tt.strategy},
+				},
+			}
+			_, err := (&FrappeBench{}).ValidateCreate(context.TODO(), bench)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateCreate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateCreate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFrappeBenchDefaultUpdateStrategy(t *testing.T) {
+	zero := intstr.FromInt32(0)
+	bench := &FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-bench"},
+		Spec: FrappeBenchSpec{
+			UpdateStrategy: &BenchUpdateStrategy{Gunicorn: &ComponentUpdateStrategy{MaxSurge: &zero}},
+		},
+	}
+	if err := (&FrappeBench{}).Default(context.TODO(), bench); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	gunicorn := bench.Spec.UpdateStrategy.Gunicorn
+	if gunicorn.Type != UpdateStrategyRollingUpdate || gunicorn.MaxSurge.IntValue() != 0 || gunicorn.MaxUnavailable == nil || gunicorn.MaxUnavailable.StrVal != "25%" {
+		t.Errorf("expected RollingUpdate with maxSurge 0 and maxUnavailable 25%%, got %+v", gunicorn)
+	}
+	if bench.Spec.UpdateStrategy.Nginx != nil {
+		t.Error("expected the unset nginx strategy to stay unset")
+	}
+}
+
+func TestFrappeBenchDefaultWorkerAutoscaling(t *testing.T) {
+	maxReplicas := int32(3)
+	bench := &FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-bench"},
+		Spec: FrappeBenchSpec{
+			WorkerAutoscaling: &WorkerAutoscalingConfig{Long: &WorkerAutoscaling{MaxReplicas: &maxReplicas}},
+		},
+	}
+	if err := (&FrappeBench{}).Default(context.TODO(), bench); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	long := bench.Spec.WorkerAutoscaling.Long
+	if long.Enabled == nil || !*long.Enabled || long.MinReplicas == nil || *long.MinReplicas != 0 || *long.MaxReplicas != 3 {
+		t.Errorf("expected long worker defaults with maxReplicas 3, got %+v", long)
+	}
+	if long.TerminationGracePeriodSeconds == nil || *long.TerminationGracePeriodSeconds != 1500 {
+		t.Errorf("expected the long worker grace period, got %v", long.TerminationGracePeriodSeconds)
+	}
+	if bench.Spec.WorkerAutoscaling.Short != nil {
+		t.Error("expected unset workers to stay unset")
+	}
+}
+
+func TestFrappeBenchValidateKEDAWarning(t *testing.T) {
+	defer func() { benchAPIMapper = nil }()
+	benchAPIMapper = meta.NewDefaultRESTMapper(nil)
+	enabled, disabled := true, false
+	bench := &FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-bench"},
+		Spec: FrappeBenchSpec{
+			FrappeVersion: "version-15",
+			Apps:          []AppSource{{Name: "frappe", Source: "image"}},
+			WorkerAutoscaling: &WorkerAutoscalingConfig{
+				Short:   &WorkerAutoscaling{Enabled: &enabled},
+				Default: &WorkerAutoscaling{Enabled: &disabled},
+			},
+		},
+	}
+	warnings, err := bench.ValidateCreate(context.TODO(), bench)
+	if err != nil {
+		t.Fatalf("ValidateCreate() error = %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "workerAutoscaling.short.enabled") {
+		t.Errorf("expected a KEDA warning for the short worker, got %v", warnings)
+	}
+
+	benchAPIMapper = nil
+	if warnings, _ := bench.ValidateUpdate(context.TODO(), bench, bench); len(warnings) != 0 {
+		t.Errorf("expected no warnings when KEDA can't be checked, got %v", warnings)
+	}
+}
+
 func TestFrappeSiteValidateUpdate(t *testing.T) {
 	validSite := &FrappeSite{
 		ObjectMeta: metav1.ObjectMeta{Name: "test-site"},
@@ -248,10 +992,91 @@ func TestFrappeSiteValidateUpdate(t *testing.T) {
 	if err != nil {
 		t.Errorf("ValidateUpdate(valid) error = %v", err)
 	}
-	_, err = invalidSite.ValidateUpdate(context.TODO(), invalidSite, invalidSite)
+	_, err = invalidSite.ValidateUpdate(context.TODO(), validSite, invalidSite)
 	if err == nil {
 		t.Error("ValidateUpdate(invalid) expected error")
 	}
+
+	// Updates that leave the spec alone, e.g. a finalizer removal, are not re-validated
+	relabeled := invalidSite.DeepCopy()
+	relabeled.Labels = map[string]string{"team": "erp"}
+	if _, err := relabeled.ValidateUpdate(context.TODO(), invalidSite, relabeled); err != nil {
+		t.Errorf("ValidateUpdate(unchanged spec) error = %v", err)
+	}
+	deleting := invalidSite.DeepCopy()
+	deleting.Spec.SiteName = "changed.local"
+	deleting.DeletionTimestamp = &metav1.Time{}
+	if _, err := deleting.ValidateUpdate(context.TODO(), invalidSite, deleting); err != nil {
+		t.Errorf("ValidateUpdate(deleting) error = %v", err)
+	}
+}
+
+func TestFrappeSiteValidateCreateUsesAdmittedObject(t *testing.T) {
+	// The webhook registers an empty FrappeSite as validator; the admitted object is obj
+	validator := &FrappeSite{}
+	site := &FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-site"},
+		Spec: FrappeSiteSpec{
+			SiteName: "test.local",
+			BenchRef: &NamespacedName{Name: "test-bench"},
+		},
+	}
+	if _, err := validator.ValidateCreate(context.TODO(), site); err != nil {
+		t.Errorf("ValidateCreate(valid) error = %v", err)
+	}
+	if _, err := validator.ValidateUpdate(context.TODO(), site, &FrappeSite{}); err == nil {
+		t.Error("ValidateUpdate(invalid newObj) expected error")
+	}
+	if _, err := validator.ValidateCreate(context.TODO(), &FrappeBench{}); err == nil {
+		t.Error("ValidateCreate(FrappeBench) expected error")
+	}
+}
+
+func TestFrappeSiteValidateWildcardPassthroughWarning(t *testing.T) {
+	site := &FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-site"},
+		Spec: FrappeSiteSpec{
+			SiteName:    "acme.example.com",
+			BenchRef:    &NamespacedName{Name: "test-bench"},
+			RouteConfig: &RouteConfig{WildcardPolicy: "subdomain", TLSTermination: "passthrough"},
+		},
+	}
+	warnings, err := site.ValidateCreate(context.TODO(), site)
+	if err != nil {
+		t.Fatalf("ValidateCreate() error = %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a wildcard/passthrough warning, got %v", warnings)
+	}
+
+	site.Spec.RouteConfig.TLSTermination = "edge"
+	if warnings, _ := site.ValidateUpdate(context.TODO(), site, site); len(warnings) != 0 {
+		t.Errorf("expected no warnings for an edge wildcard Route, got %v", warnings)
+	}
+}
+
+func TestFrappeSiteValidateInternalURLHostWarning(t *testing.T) {
+	disabled := false
+	site := &FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-site"},
+		Spec: FrappeSiteSpec{
+			SiteName: "acme.example.com",
+			BenchRef: &NamespacedName{Name: "test-bench"},
+			Ingress:  &IngressConfig{Enabled: &disabled},
+		},
+	}
+	warnings, err := site.ValidateCreate(context.TODO(), site)
+	if err != nil {
+		t.Fatalf("ValidateCreate() error = %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Host") {
+		t.Errorf("expected a Host header warning for the default internal URL, got %v", warnings)
+	}
+
+	site.Spec.Ingress.InternalURLTemplate = "http://{domain}:{port}"
+	if warnings, _ := site.ValidateUpdate(context.TODO(), site, site); len(warnings) != 0 {
+		t.Errorf("expected no warnings for a template naming the domain, got %v", warnings)
+	}
 }
 
 func TestFrappeSiteValidateDelete(t *testing.T) {
@@ -21,9 +21,10 @@ limitations under the License.
 package v1alpha1
 
 import (
-	corev1 "k8s.io/api/core/v1"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -41,13 +42,43 @@ func (in *AppSource) DeepCopy() *AppSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupFile) DeepCopyInto(out *BackupFile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupFile.
+func (in *BackupFile) DeepCopy() *BackupFile {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupS3Location) DeepCopyInto(out *BackupS3Location) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupS3Location.
+func (in *BackupS3Location) DeepCopy() *BackupS3Location {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupS3Location)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BackupSource) DeepCopyInto(out *BackupSource) {
 	*out = *in
 	if in.S3 != nil {
 		in, out := &in.S3, &out.S3
 		*out = new(S3DownloadConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -81,6 +112,260 @@ func (in *BackupStorageConfig) DeepCopy() *BackupStorageConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BenchComponents) DeepCopyInto(out *BenchComponents) {
+	*out = *in
+	if in.Nginx != nil {
+		in, out := &in.Nginx, &out.Nginx
+		*out = new(ComponentToggle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SocketIO != nil {
+		in, out := &in.SocketIO, &out.SocketIO
+		*out = new(ComponentToggle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Scheduler != nil {
+		in, out := &in.Scheduler, &out.Scheduler
+		*out = new(ComponentToggle)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BenchComponents.
+func (in *BenchComponents) DeepCopy() *BenchComponents {
+	if in == nil {
+		return nil
+	}
+	out := new(BenchComponents)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BenchNetworkPolicy) DeepCopyInto(out *BenchNetworkPolicy) {
+	*out = *in
+	if in.IngressNamespaceSelector != nil {
+		in, out := &in.IngressNamespaceSelector, &out.IngressNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BenchNetworkPolicy.
+func (in *BenchNetworkPolicy) DeepCopy() *BenchNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(BenchNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BenchProbes) DeepCopyInto(out *BenchProbes) {
+	*out = *in
+	if in.Gunicorn != nil {
+		in, out := &in.Gunicorn, &out.Gunicorn
+		*out = new(ProbeTiming)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Nginx != nil {
+		in, out := &in.Nginx, &out.Nginx
+		*out = new(ProbeTiming)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SocketIO != nil {
+		in, out := &in.SocketIO, &out.SocketIO
+		*out = new(ProbeTiming)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BenchProbes.
+func (in *BenchProbes) DeepCopy() *BenchProbes {
+	if in == nil {
+		return nil
+	}
+	out := new(BenchProbes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BenchStorageConfig) DeepCopyInto(out *BenchStorageConfig) {
+	*out = *in
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BenchStorageConfig.
+func (in *BenchStorageConfig) DeepCopy() *BenchStorageConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BenchStorageConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BenchUpdateStrategy) DeepCopyInto(out *BenchUpdateStrategy) {
+	*out = *in
+	if in.Gunicorn != nil {
+		in, out := &in.Gunicorn, &out.Gunicorn
+		*out = new(ComponentUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Nginx != nil {
+		in, out := &in.Nginx, &out.Nginx
+		*out = new(ComponentUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BenchUpdateStrategy.
+func (in *BenchUpdateStrategy) DeepCopy() *BenchUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(BenchUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CORSConfig) DeepCopyInto(out *CORSConfig) {
+	*out = *in
+	if in.AllowOrigins != nil {
+		in, out := &in.AllowOrigins, &out.AllowOrigins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CORSConfig.
+func (in *CORSConfig) DeepCopy() *CORSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CORSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentImage) DeepCopyInto(out *ComponentImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentImage.
+func (in *ComponentImage) DeepCopy() *ComponentImage {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentImages) DeepCopyInto(out *ComponentImages) {
+	*out = *in
+	if in.Gunicorn != nil {
+		in, out := &in.Gunicorn, &out.Gunicorn
+		*out = new(ComponentImage)
+		**out = **in
+	}
+	if in.Worker != nil {
+		in, out := &in.Worker, &out.Worker
+		*out = new(ComponentImage)
+		**out = **in
+	}
+	if in.SocketIO != nil {
+		in, out := &in.SocketIO, &out.SocketIO
+		*out = new(ComponentImage)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentImages.
+func (in *ComponentImages) DeepCopy() *ComponentImages {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentImages)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentPodAnnotations) DeepCopyInto(out *ComponentPodAnnotations) {
+	*out = *in
+	if in.Gunicorn != nil {
+		in, out := &in.Gunicorn, &out.Gunicorn
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Nginx != nil {
+		in, out := &in.Nginx, &out.Nginx
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Scheduler != nil {
+		in, out := &in.Scheduler, &out.Scheduler
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Socketio != nil {
+		in, out := &in.Socketio, &out.Socketio
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.WorkerDefault != nil {
+		in, out := &in.WorkerDefault, &out.WorkerDefault
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.WorkerLong != nil {
+		in, out := &in.WorkerLong, &out.WorkerLong
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.WorkerShort != nil {
+		in, out := &in.WorkerShort, &out.WorkerShort
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPodAnnotations.
+func (in *ComponentPodAnnotations) DeepCopy() *ComponentPodAnnotations {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentPodAnnotations)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentReplicas) DeepCopyInto(out *ComponentReplicas) {
 	*out = *in
@@ -146,6 +431,111 @@ func (in *ComponentResources) DeepCopy() *ComponentResources {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentToggle) DeepCopyInto(out *ComponentToggle) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentToggle.
+func (in *ComponentToggle) DeepCopy() *ComponentToggle {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentToggle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentUpdateStrategy) DeepCopyInto(out *ComponentUpdateStrategy) {
+	*out = *in
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentUpdateStrategy.
+func (in *ComponentUpdateStrategy) DeepCopy() *ComponentUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSConfig) DeepCopyInto(out *DNSConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSConfig.
+func (in *DNSConfig) DeepCopy() *DNSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DashboardChartFilter) DeepCopyInto(out *DashboardChartFilter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DashboardChartFilter.
+func (in *DashboardChartFilter) DeepCopy() *DashboardChartFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(DashboardChartFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DashboardChartGroupBy) DeepCopyInto(out *DashboardChartGroupBy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DashboardChartGroupBy.
+func (in *DashboardChartGroupBy) DeepCopy() *DashboardChartGroupBy {
+	if in == nil {
+		return nil
+	}
+	out := new(DashboardChartGroupBy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DashboardChartTimeSeries) DeepCopyInto(out *DashboardChartTimeSeries) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DashboardChartTimeSeries.
+func (in *DashboardChartTimeSeries) DeepCopy() *DashboardChartTimeSeries {
+	if in == nil {
+		return nil
+	}
+	out := new(DashboardChartTimeSeries)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseConfig) DeepCopyInto(out *DatabaseConfig) {
 	*out = *in
@@ -171,7 +561,7 @@ func (in *DatabaseConfig) DeepCopyInto(out *DatabaseConfig) {
 	}
 	if in.ConnectionSecretRef != nil {
 		in, out := &in.ConnectionSecretRef, &out.ConnectionSecretRef
-		*out = new(corev1.SecretReference)
+		*out = new(v1.SecretReference)
 		**out = **in
 	}
 }
@@ -238,7 +628,7 @@ func (in *FPMRepository) DeepCopyInto(out *FPMRepository) {
 	*out = *in
 	if in.AuthSecretRef != nil {
 		in, out := &in.AuthSecretRef, &out.AuthSecretRef
-		*out = new(corev1.SecretReference)
+		*out = new(v1.SecretReference)
 		**out = **in
 	}
 }
@@ -340,6 +730,25 @@ func (in *FrappeBenchSpec) DeepCopyInto(out *FrappeBenchSpec) {
 		*out = new(RedisConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(BenchStorageConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtraVolumes != nil {
+		in, out := &in.ExtraVolumes, &out.ExtraVolumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraVolumeMounts != nil {
+		in, out := &in.ExtraVolumeMounts, &out.ExtraVolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.DBConfig != nil {
 		in, out := &in.DBConfig, &out.DBConfig
 		*out = new(DatabaseConfig)
@@ -350,6 +759,18 @@ func (in *FrappeBenchSpec) DeepCopyInto(out *FrappeBenchSpec) {
 		*out = new(DomainConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CommonSiteConfig != nil {
+		in, out := &in.CommonSiteConfig, &out.CommonSiteConfig
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CommonSiteConfigSecretRef != nil {
+		in, out := &in.CommonSiteConfigSecretRef, &out.CommonSiteConfigSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
 	if in.FPMConfig != nil {
 		in, out := &in.FPMConfig, &out.FPMConfig
 		*out = new(FPMConfig)
@@ -365,11 +786,88 @@ func (in *FrappeBenchSpec) DeepCopyInto(out *FrappeBenchSpec) {
 		*out = new(WorkerAutoscalingConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Workers != nil {
+		in, out := &in.Workers, &out.Workers
+		*out = make([]WorkerPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GunicornAutoscaling != nil {
+		in, out := &in.GunicornAutoscaling, &out.GunicornAutoscaling
+		*out = new(GunicornAutoscaling)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Security != nil {
 		in, out := &in.Security, &out.Security
 		*out = new(SecurityConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SiteReconcileConcurrency != nil {
+		in, out := &in.SiteReconcileConcurrency, &out.SiteReconcileConcurrency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PodConfig != nil {
+		in, out := &in.PodConfig, &out.PodConfig
+		*out = new(PodConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JobPlacement != nil {
+		in, out := &in.JobPlacement, &out.JobPlacement
+		*out = new(PodConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ComponentPodAnnotations != nil {
+		in, out := &in.ComponentPodAnnotations, &out.ComponentPodAnnotations
+		*out = new(ComponentPodAnnotations)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JobTTLSecondsAfterFinished != nil {
+		in, out := &in.JobTTLSecondsAfterFinished, &out.JobTTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MigrationSnapshot != nil {
+		in, out := &in.MigrationSnapshot, &out.MigrationSnapshot
+		*out = new(VolumeSnapshotConfig)
+		**out = **in
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = new(BenchComponents)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Probes != nil {
+		in, out := &in.Probes, &out.Probes
+		*out = new(BenchProbes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(BenchUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GracefulShutdown != nil {
+		in, out := &in.GracefulShutdown, &out.GracefulShutdown
+		*out = new(GracefulShutdownConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(BenchNetworkPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NginxTLS != nil {
+		in, out := &in.NginxTLS, &out.NginxTLS
+		*out = new(NginxTLSConfig)
+		**out = **in
+	}
+	if in.Nginx != nil {
+		in, out := &in.Nginx, &out.Nginx
+		*out = new(NginxConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrappeBenchSpec.
@@ -387,7 +885,7 @@ func (in *FrappeBenchStatus) DeepCopyInto(out *FrappeBenchStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -409,6 +907,30 @@ func (in *FrappeBenchStatus) DeepCopyInto(out *FrappeBenchStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.CommonSiteConfigKeys != nil {
+		in, out := &in.CommonSiteConfigKeys, &out.CommonSiteConfigKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RedisCacheSize != nil {
+		in, out := &in.RedisCacheSize, &out.RedisCacheSize
+		*out = new(RedisAutoSizeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppVersions != nil {
+		in, out := &in.AppVersions, &out.AppVersions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrappeBenchStatus.
@@ -490,10 +1012,15 @@ func (in *FrappeSiteSpec) DeepCopyInto(out *FrappeSiteSpec) {
 	}
 	if in.AdminPasswordSecretRef != nil {
 		in, out := &in.AdminPasswordSecretRef, &out.AdminPasswordSecretRef
-		*out = new(corev1.SecretReference)
+		*out = new(v1.SecretReference)
 		**out = **in
 	}
 	in.DBConfig.DeepCopyInto(&out.DBConfig)
+	if in.Aliases != nil {
+		in, out := &in.Aliases, &out.Aliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	out.TLS = in.TLS
 	if in.Ingress != nil {
 		in, out := &in.Ingress, &out.Ingress
@@ -505,6 +1032,55 @@ func (in *FrappeSiteSpec) DeepCopyInto(out *FrappeSiteSpec) {
 		*out = new(RouteConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DNSConfig)
+		**out = **in
+	}
+	if in.Apps != nil {
+		in, out := &in.Apps, &out.Apps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodConfig != nil {
+		in, out := &in.PodConfig, &out.PodConfig
+		*out = new(PodConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InitScriptPreamble != nil {
+		in, out := &in.InitScriptPreamble, &out.InitScriptPreamble
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InitJob != nil {
+		in, out := &in.InitJob, &out.InitJob
+		*out = new(InitJobConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]SiteEnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CORS != nil {
+		in, out := &in.CORS, &out.CORS
+		*out = new(CORSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SiteConfig != nil {
+		in, out := &in.SiteConfig, &out.SiteConfig
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SiteConfigSecretRef != nil {
+		in, out := &in.SiteConfigSecretRef, &out.SiteConfigSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrappeSiteSpec.
@@ -522,11 +1098,46 @@ func (in *FrappeSiteStatus) DeepCopyInto(out *FrappeSiteStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.InstalledApps != nil {
+		in, out := &in.InstalledApps, &out.InstalledApps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkippedApps != nil {
+		in, out := &in.SkippedApps, &out.SkippedApps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailedApps != nil {
+		in, out := &in.FailedApps, &out.FailedApps
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastReconcileTime != nil {
+		in, out := &in.LastReconcileTime, &out.LastReconcileTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CORSOrigins != nil {
+		in, out := &in.CORSOrigins, &out.CORSOrigins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SiteConfigKeys != nil {
+		in, out := &in.SiteConfigKeys, &out.SiteConfigKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProvisioningWaitSince != nil {
+		in, out := &in.ProvisioningWaitSince, &out.ProvisioningWaitSince
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrappeSiteStatus.
@@ -628,6 +1239,21 @@ func (in *FrappeWorkpaceStatus) DeepCopy() *FrappeWorkpaceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GeoTagConfig) DeepCopyInto(out *GeoTagConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeoTagConfig.
+func (in *GeoTagConfig) DeepCopy() *GeoTagConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GeoTagConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitConfig) DeepCopyInto(out *GitConfig) {
 	*out = *in
@@ -648,14 +1274,84 @@ func (in *GitConfig) DeepCopy() *GitConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GracefulShutdownConfig) DeepCopyInto(out *GracefulShutdownConfig) {
+	*out = *in
+	if in.Seconds != nil {
+		in, out := &in.Seconds, &out.Seconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PreStopSleepSeconds != nil {
+		in, out := &in.PreStopSleepSeconds, &out.PreStopSleepSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GracefulShutdownConfig.
+func (in *GracefulShutdownConfig) DeepCopy() *GracefulShutdownConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GracefulShutdownConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GunicornAutoscaling) DeepCopyInto(out *GunicornAutoscaling) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetCPUUtilizationPercentage != nil {
+		in, out := &in.TargetCPUUtilizationPercentage, &out.TargetCPUUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetMemoryUtilizationPercentage != nil {
+		in, out := &in.TargetMemoryUtilizationPercentage, &out.TargetMemoryUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GunicornAutoscaling.
+func (in *GunicornAutoscaling) DeepCopy() *GunicornAutoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(GunicornAutoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageConfig) DeepCopyInto(out *ImageConfig) {
 	*out = *in
 	if in.PullSecrets != nil {
 		in, out := &in.PullSecrets, &out.PullSecrets
-		*out = make([]corev1.LocalObjectReference, len(*in))
+		*out = make([]v1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = new(ComponentImages)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageConfig.
@@ -700,6 +1396,36 @@ func (in *IngressConfig) DeepCopy() *IngressConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InitJobConfig) DeepCopyInto(out *InitJobConfig) {
+	*out = *in
+	if in.BackoffLimit != nil {
+		in, out := &in.BackoffLimit, &out.BackoffLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ActiveDeadlineSeconds != nil {
+		in, out := &in.ActiveDeadlineSeconds, &out.ActiveDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DatabaseWaitSeconds != nil {
+		in, out := &in.DatabaseWaitSeconds, &out.DatabaseWaitSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InitJobConfig.
+func (in *InitJobConfig) DeepCopy() *InitJobConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(InitJobConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NamespacedName) DeepCopyInto(out *NamespacedName) {
 	*out = *in
@@ -715,6 +1441,165 @@ func (in *NamespacedName) DeepCopy() *NamespacedName {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NginxConfig) DeepCopyInto(out *NginxConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NginxConfig.
+func (in *NginxConfig) DeepCopy() *NginxConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NginxConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NginxTLSConfig) DeepCopyInto(out *NginxTLSConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NginxTLSConfig.
+func (in *NginxTLSConfig) DeepCopy() *NginxTLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NginxTLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationProgress) DeepCopyInto(out *OperationProgress) {
+	*out = *in
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationProgress.
+func (in *OperationProgress) DeepCopy() *OperationProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodConfig) DeepCopyInto(out *PodConfig) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GeoTag != nil {
+		in, out := &in.GeoTag, &out.GeoTag
+		*out = new(GeoTagConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodConfig.
+func (in *PodConfig) DeepCopy() *PodConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PodConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeTiming) DeepCopyInto(out *ProbeTiming) {
+	*out = *in
+	if in.InitialDelaySeconds != nil {
+		in, out := &in.InitialDelaySeconds, &out.InitialDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PeriodSeconds != nil {
+		in, out := &in.PeriodSeconds, &out.PeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeTiming.
+func (in *ProbeTiming) DeepCopy() *ProbeTiming {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeTiming)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisAutoSize) DeepCopyInto(out *RedisAutoSize) {
+	*out = *in
+	out.MemoryPerSite = in.MemoryPerSite.DeepCopy()
+	if in.MinMemory != nil {
+		in, out := &in.MinMemory, &out.MinMemory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxMemory != nil {
+		in, out := &in.MaxMemory, &out.MaxMemory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisAutoSize.
+func (in *RedisAutoSize) DeepCopy() *RedisAutoSize {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisAutoSize)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisAutoSizeStatus) DeepCopyInto(out *RedisAutoSizeStatus) {
+	*out = *in
+	out.Memory = in.Memory.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisAutoSizeStatus.
+func (in *RedisAutoSizeStatus) DeepCopy() *RedisAutoSizeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisAutoSizeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisConfig) DeepCopyInto(out *RedisConfig) {
 	*out = *in
@@ -735,9 +1620,14 @@ func (in *RedisConfig) DeepCopyInto(out *RedisConfig) {
 	}
 	if in.ConnectionSecretRef != nil {
 		in, out := &in.ConnectionSecretRef, &out.ConnectionSecretRef
-		*out = new(corev1.SecretReference)
+		*out = new(v1.SecretReference)
 		**out = **in
 	}
+	if in.AutoSizePerSite != nil {
+		in, out := &in.AutoSizePerSite, &out.AutoSizePerSite
+		*out = new(RedisAutoSize)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisConfig.
@@ -755,14 +1645,14 @@ func (in *ResourceRequirements) DeepCopyInto(out *ResourceRequirements) {
 	*out = *in
 	if in.Requests != nil {
 		in, out := &in.Requests, &out.Requests
-		*out = make(corev1.ResourceList, len(*in))
+		*out = make(v1.ResourceList, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
 	if in.Limits != nil {
 		in, out := &in.Limits, &out.Limits
-		*out = make(corev1.ResourceList, len(*in))
+		*out = make(v1.ResourceList, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val.DeepCopy()
 		}
@@ -826,7 +1716,7 @@ func (in *S3Config) DeepCopy() *S3Config {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3DownloadConfig) DeepCopyInto(out *S3DownloadConfig) {
 	*out = *in
-	out.S3Config = in.S3Config
+	in.S3Config.DeepCopyInto(&out.S3Config)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3DownloadConfig.
@@ -844,12 +1734,12 @@ func (in *SecurityConfig) DeepCopyInto(out *SecurityConfig) {
 	*out = *in
 	if in.PodSecurityContext != nil {
 		in, out := &in.PodSecurityContext, &out.PodSecurityContext
-		*out = new(corev1.PodSecurityContext)
+		*out = new(v1.PodSecurityContext)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.SecurityContext != nil {
 		in, out := &in.SecurityContext, &out.SecurityContext
-		*out = new(corev1.SecurityContext)
+		*out = new(v1.SecurityContext)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -920,12 +1810,32 @@ func (in *SiteBackupList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
-	return nil
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SiteBackupSpec) DeepCopyInto(out *SiteBackupSpec) {
-	*out = *in
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteBackupSpec) DeepCopyInto(out *SiteBackupSpec) {
+	*out = *in
+	if in.SuccessfulJobsHistoryLimit != nil {
+		in, out := &in.SuccessfulJobsHistoryLimit, &out.SuccessfulJobsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailedJobsHistoryLimit != nil {
+		in, out := &in.FailedJobsHistoryLimit, &out.FailedJobsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.StartingDeadlineSeconds != nil {
+		in, out := &in.StartingDeadlineSeconds, &out.StartingDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(BackupStorageConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Exclude != nil {
 		in, out := &in.Exclude, &out.Exclude
 		*out = make([]string, len(*in))
@@ -936,11 +1846,6 @@ func (in *SiteBackupSpec) DeepCopyInto(out *SiteBackupSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.Storage != nil {
-		in, out := &in.Storage, &out.Storage
-		*out = new(BackupStorageConfig)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteBackupSpec.
@@ -957,6 +1862,28 @@ func (in *SiteBackupSpec) DeepCopy() *SiteBackupSpec {
 func (in *SiteBackupStatus) DeepCopyInto(out *SiteBackupStatus) {
 	*out = *in
 	in.LastBackup.DeepCopyInto(&out.LastBackup)
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(OperationProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(BackupS3Location)
+		**out = **in
+	}
+	if in.BackupFiles != nil {
+		in, out := &in.BackupFiles, &out.BackupFiles
+		*out = make([]BackupFile, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteBackupStatus.
@@ -1001,8 +1928,8 @@ func (in *SiteDashboardChart) DeepCopyInto(out *SiteDashboardChart) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteDashboardChart.
@@ -1058,6 +1985,21 @@ func (in *SiteDashboardChartList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteDashboardChartSpec) DeepCopyInto(out *SiteDashboardChartSpec) {
 	*out = *in
+	if in.TimeSeries != nil {
+		in, out := &in.TimeSeries, &out.TimeSeries
+		*out = new(DashboardChartTimeSeries)
+		**out = **in
+	}
+	if in.GroupBy != nil {
+		in, out := &in.GroupBy, &out.GroupBy
+		*out = new(DashboardChartGroupBy)
+		**out = **in
+	}
+	if in.Filters != nil {
+		in, out := &in.Filters, &out.Filters
+		*out = make([]DashboardChartFilter, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteDashboardChartSpec.
@@ -1073,6 +2015,17 @@ func (in *SiteDashboardChartSpec) DeepCopy() *SiteDashboardChartSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteDashboardChartStatus) DeepCopyInto(out *SiteDashboardChartStatus) {
 	*out = *in
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteDashboardChartStatus.
@@ -1147,13 +2100,33 @@ func (in *SiteDashboardStatus) DeepCopy() *SiteDashboardStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteEnvVar) DeepCopyInto(out *SiteEnvVar) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteEnvVar.
+func (in *SiteEnvVar) DeepCopy() *SiteEnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteEnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteJob) DeepCopyInto(out *SiteJob) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteJob.
@@ -1174,6 +2147,48 @@ func (in *SiteJob) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteJobBuildArgs) DeepCopyInto(out *SiteJobBuildArgs) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteJobBuildArgs.
+func (in *SiteJobBuildArgs) DeepCopy() *SiteJobBuildArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteJobBuildArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteJobExecuteArgs) DeepCopyInto(out *SiteJobExecuteArgs) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Kwargs != nil {
+		in, out := &in.Kwargs, &out.Kwargs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteJobExecuteArgs.
+func (in *SiteJobExecuteArgs) DeepCopy() *SiteJobExecuteArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteJobExecuteArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteJobList) DeepCopyInto(out *SiteJobList) {
 	*out = *in
@@ -1206,9 +2221,64 @@ func (in *SiteJobList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteJobMigrateArgs) DeepCopyInto(out *SiteJobMigrateArgs) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteJobMigrateArgs.
+func (in *SiteJobMigrateArgs) DeepCopy() *SiteJobMigrateArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteJobMigrateArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteJobSetConfigArgs) DeepCopyInto(out *SiteJobSetConfigArgs) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteJobSetConfigArgs.
+func (in *SiteJobSetConfigArgs) DeepCopy() *SiteJobSetConfigArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteJobSetConfigArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteJobSpec) DeepCopyInto(out *SiteJobSpec) {
 	*out = *in
+	if in.Migrate != nil {
+		in, out := &in.Migrate, &out.Migrate
+		*out = new(SiteJobMigrateArgs)
+		**out = **in
+	}
+	if in.Build != nil {
+		in, out := &in.Build, &out.Build
+		*out = new(SiteJobBuildArgs)
+		**out = **in
+	}
+	if in.SetConfig != nil {
+		in, out := &in.SetConfig, &out.SetConfig
+		*out = new(SiteJobSetConfigArgs)
+		**out = **in
+	}
+	if in.Execute != nil {
+		in, out := &in.Execute, &out.Execute
+		*out = new(SiteJobExecuteArgs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteJobSpec.
@@ -1224,6 +2294,15 @@ func (in *SiteJobSpec) DeepCopy() *SiteJobSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteJobStatus) DeepCopyInto(out *SiteJobStatus) {
 	*out = *in
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteJobStatus.
@@ -1310,11 +2389,26 @@ func (in *SiteRestoreSpec) DeepCopyInto(out *SiteRestoreSpec) {
 		*out = new(BackupSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ConfigBackupSource != nil {
+		in, out := &in.ConfigBackupSource, &out.ConfigBackupSource
+		*out = new(BackupSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RedactedConfigSecretRef != nil {
+		in, out := &in.RedactedConfigSecretRef, &out.RedactedConfigSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
 	if in.AdminPasswordSecretRef != nil {
 		in, out := &in.AdminPasswordSecretRef, &out.AdminPasswordSecretRef
-		*out = new(corev1.SecretKeySelector)
+		*out = new(v1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.VolumeSnapshot != nil {
+		in, out := &in.VolumeSnapshot, &out.VolumeSnapshot
+		*out = new(VolumeSnapshotConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteRestoreSpec.
@@ -1334,6 +2428,11 @@ func (in *SiteRestoreStatus) DeepCopyInto(out *SiteRestoreStatus) {
 		in, out := &in.CompletionTime, &out.CompletionTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(OperationProgress)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteRestoreStatus.
@@ -1351,8 +2450,8 @@ func (in *SiteUser) DeepCopyInto(out *SiteUser) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteUser.
@@ -1408,6 +2507,16 @@ func (in *SiteUserList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteUserSpec) DeepCopyInto(out *SiteUserSpec) {
 	*out = *in
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteUserSpec.
@@ -1423,6 +2532,22 @@ func (in *SiteUserSpec) DeepCopy() *SiteUserSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteUserStatus) DeepCopyInto(out *SiteUserStatus) {
 	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteUserStatus.
@@ -1440,8 +2565,8 @@ func (in *SiteWorkspace) DeepCopyInto(out *SiteWorkspace) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteWorkspace.
@@ -1497,6 +2622,23 @@ func (in *SiteWorkspaceList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteWorkspaceSpec) DeepCopyInto(out *SiteWorkspaceSpec) {
 	*out = *in
+	if in.SequenceID != nil {
+		in, out := &in.SequenceID, &out.SequenceID
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Shortcuts != nil {
+		in, out := &in.Shortcuts, &out.Shortcuts
+		*out = make([]WorkspaceShortcut, len(*in))
+		copy(*out, *in)
+	}
+	if in.Cards != nil {
+		in, out := &in.Cards, &out.Cards
+		*out = make([]WorkspaceCard, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteWorkspaceSpec.
@@ -1512,6 +2654,10 @@ func (in *SiteWorkspaceSpec) DeepCopy() *SiteWorkspaceSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteWorkspaceStatus) DeepCopyInto(out *SiteWorkspaceStatus) {
 	*out = *in
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteWorkspaceStatus.
@@ -1539,6 +2685,21 @@ func (in *TLSConfig) DeepCopy() *TLSConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeSnapshotConfig) DeepCopyInto(out *VolumeSnapshotConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeSnapshotConfig.
+func (in *VolumeSnapshotConfig) DeepCopy() *VolumeSnapshotConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSnapshotConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkerAutoscaling) DeepCopyInto(out *WorkerAutoscaling) {
 	*out = *in
@@ -1577,6 +2738,16 @@ func (in *WorkerAutoscaling) DeepCopyInto(out *WorkerAutoscaling) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.DrainOnStop != nil {
+		in, out := &in.DrainOnStop, &out.DrainOnStop
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerAutoscaling.
@@ -1619,6 +2790,36 @@ func (in *WorkerAutoscalingConfig) DeepCopy() *WorkerAutoscalingConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPool) DeepCopyInto(out *WorkerPool) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(WorkerAutoscaling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPool.
+func (in *WorkerPool) DeepCopy() *WorkerPool {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkerScalingStatus) DeepCopyInto(out *WorkerScalingStatus) {
 	*out = *in
@@ -1633,3 +2834,53 @@ func (in *WorkerScalingStatus) DeepCopy() *WorkerScalingStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceCard) DeepCopyInto(out *WorkspaceCard) {
+	*out = *in
+	if in.Links != nil {
+		in, out := &in.Links, &out.Links
+		*out = make([]WorkspaceLink, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceCard.
+func (in *WorkspaceCard) DeepCopy() *WorkspaceCard {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceCard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceLink) DeepCopyInto(out *WorkspaceLink) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceLink.
+func (in *WorkspaceLink) DeepCopy() *WorkspaceLink {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceLink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceShortcut) DeepCopyInto(out *WorkspaceShortcut) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceShortcut.
+func (in *WorkspaceShortcut) DeepCopy() *WorkspaceShortcut {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceShortcut)
+	in.DeepCopyInto(out)
+	return out
+}
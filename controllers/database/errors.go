@@ -0,0 +1,86 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// ErrorClass tells callers why a provider call failed, so they can decide whether retrying helps
+type ErrorClass string
+
+const (
+	// ErrorClassNotFound means the database server or its custom resource definitions don't
+	// exist (yet), e.g. no MariaDB CR or CloudNativePG Cluster
+	ErrorClassNotFound ErrorClass = "NotFound"
+	// ErrorClassPermissionDenied means the operator isn't allowed to read or create the
+	// provider's resources
+	ErrorClassPermissionDenied ErrorClass = "PermissionDenied"
+	// ErrorClassTransient means the call may succeed when retried
+	ErrorClassTransient ErrorClass = "Transient"
+	// ErrorClassMisconfigured means dbConfig or a referenced Secret is wrong; retrying
+	// without changing them won't help
+	ErrorClassMisconfigured ErrorClass = "Misconfigured"
+)
+
+// ProviderError is an error returned by a Provider with its class
+type ProviderError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// notFoundError returns a NotFound ProviderError
+func notFoundError(format string, args ...interface{}) error {
+	return &ProviderError{Class: ErrorClassNotFound, Err: fmt.Errorf(format, args...)}
+}
+
+// misconfiguredError returns a Misconfigured ProviderError
+func misconfiguredError(format string, args ...interface{}) error {
+	return &ProviderError{Class: ErrorClassMisconfigured, Err: fmt.Errorf(format, args...)}
+}
+
+// ClassifyError returns the class of an error returned by a Provider. Errors that aren't
+// ProviderErrors are classified by their API status; anything else, including an open
+// circuit breaker, is Transient.
+func ClassifyError(err error) ErrorClass {
+	var providerErr *ProviderError
+	switch {
+	case errors.As(err, &providerErr):
+		return providerErr.Class
+	case apierrors.IsNotFound(err), meta.IsNoMatchError(err):
+		// A missing kind means the database operator isn't installed
+		return ErrorClassNotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return ErrorClassPermissionDenied
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return ErrorClassMisconfigured
+	default:
+		return ErrorClassTransient
+	}
+}
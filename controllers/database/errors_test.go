@@ -0,0 +1,85 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/circuitbreaker"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClassifyError(t *testing.T) {
+	gr := schema.GroupResource{Group: "k8s.mariadb.com", Resource: "databases"}
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"not found", notFoundError("shared MariaDB instance not found"), ErrorClassNotFound},
+		{"misconfigured", misconfiguredError("unsupported database mode: replica"), ErrorClassMisconfigured},
+		{"wrapped provider error", fmt.Errorf("failed to ensure Database CR: %w", misconfiguredError("bad")), ErrorClassMisconfigured},
+		{"API not found", fmt.Errorf("failed to get database secret: %w", apierrors.NewNotFound(gr, "site-db")), ErrorClassNotFound},
+		{"kind not installed", &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "k8s.mariadb.com", Kind: "Database"}}, ErrorClassNotFound},
+		{"forbidden", fmt.Errorf("failed to ensure User CR: %w", apierrors.NewForbidden(gr, "site-user", errors.New("rbac"))), ErrorClassPermissionDenied},
+		{"unauthorized", apierrors.NewUnauthorized("token expired"), ErrorClassPermissionDenied},
+		{"invalid", apierrors.NewInvalid(schema.GroupKind{Group: "k8s.mariadb.com", Kind: "Database"}, "site-db", nil), ErrorClassMisconfigured},
+		{"circuit open", circuitbreaker.ErrCircuitOpen, ErrorClassTransient},
+		{"server timeout", apierrors.NewServerTimeout(gr, "create", 1), ErrorClassTransient},
+		{"unknown", errors.New("connection reset by peer"), ErrorClassTransient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderErrorClasses(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := vyogotechv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	if _, err := NewProvider(vyogotechv1alpha1.DatabaseConfig{Provider: "oracle"}, c, scheme); ClassifyError(err) != ErrorClassMisconfigured {
+		t.Errorf("expected an unsupported provider to be Misconfigured, got %v", err)
+	}
+
+	site := &vyogotechv1alpha1.FrappeSite{ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"}}
+	site.Spec.DBConfig.Mode = "dedicated"
+	if _, _, err := PostgresClusterRef(site); ClassifyError(err) != ErrorClassMisconfigured {
+		t.Errorf("expected a dedicated PostgreSQL cluster to be Misconfigured, got %v", err)
+	}
+
+	site.Spec.DBConfig.Mode = "shared"
+	_, err := NewMariaDBProvider(c, scheme).EnsureDatabase(context.Background(), site)
+	if ClassifyError(err) != ErrorClassNotFound {
+		t.Errorf("expected a missing shared MariaDB to be NotFound, got %v", err)
+	}
+}
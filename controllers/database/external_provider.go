@@ -38,7 +38,19 @@ func NewExternalProvider(client client.Client) Provider {
 	}
 }
 
-// EnsureDatabase retrieves connection info from the site spec or secret
+// connectionSecret fetches the Secret named by spec.dbConfig.connectionSecretRef. It is
+// always read from the site's namespace so a site can't borrow another tenant's credentials.
+func (p *ExternalProvider) connectionSecret(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (*corev1.Secret, error) {
+	ref := site.Spec.DBConfig.ConnectionSecretRef
+	secret := &corev1.Secret{}
+	if err := p.client.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: site.Namespace}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get database secret: %w", err)
+	}
+	return secret, nil
+}
+
+// EnsureDatabase retrieves connection info from the site spec or secret. The database
+// itself is managed outside the operator and must already exist.
 func (p *ExternalProvider) EnsureDatabase(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (*DatabaseInfo, error) {
 	host := site.Spec.DBConfig.Host
 	port := site.Spec.DBConfig.Port
@@ -46,33 +58,33 @@ func (p *ExternalProvider) EnsureDatabase(ctx context.Context, site *vyogotechv1
 
 	dbType := "mariadb" // Default
 	if site.Spec.DBConfig.ConnectionSecretRef != nil {
-		secret := &corev1.Secret{}
-		err := p.client.Get(ctx, types.NamespacedName{
-			Name:      site.Spec.DBConfig.ConnectionSecretRef.Name,
-			Namespace: site.Namespace,
-		}, secret)
-		if err == nil {
-			if h, ok := secret.Data["host"]; ok {
-				host = string(h)
-			}
-			if pt, ok := secret.Data["port"]; ok {
-				port = string(pt)
-			}
-			if dn, ok := secret.Data["database"]; ok {
-				dbName = string(dn)
-			}
-			if configType, ok := secret.Data["type"]; ok {
-				dbType = string(configType)
-			}
+		secret, err := p.connectionSecret(ctx, site)
+		if err != nil {
+			return nil, err
+		}
+		if h, ok := secret.Data["host"]; ok {
+			host = string(h)
+		}
+		if pt, ok := secret.Data["port"]; ok {
+			port = string(pt)
+		}
+		if dn, ok := secret.Data["database"]; ok {
+			dbName = string(dn)
+		}
+		if configType, ok := secret.Data["type"]; ok {
+			dbType = string(configType)
 		}
 	}
 
 	if host == "" {
-		return nil, fmt.Errorf("database host is required (either in spec or secret)")
+		return nil, misconfiguredError("database host is required (either in spec or secret)")
 	}
 
 	if port == "" {
 		port = "3306" // Default for MariaDB/MySQL
+		if dbType == "postgres" {
+			port = "5432"
+		}
 	}
 
 	if dbType == "mariadb" && site.Spec.DBConfig.Provider != "external" && site.Spec.DBConfig.Provider != "" {
@@ -87,7 +99,8 @@ func (p *ExternalProvider) EnsureDatabase(ctx context.Context, site *vyogotechv1
 	}, nil
 }
 
-// IsReady checks if the connection secret exists
+// IsReady checks that the connection details are complete. There is no database CR to
+// wait for, so the server itself is assumed to be reachable.
 func (p *ExternalProvider) IsReady(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (bool, error) {
 	if site.Spec.DBConfig.ConnectionSecretRef == nil {
 		// If no secret ref, we assume it's "ready" if host is provided in spec,
@@ -95,54 +108,50 @@ func (p *ExternalProvider) IsReady(ctx context.Context, site *vyogotechv1alpha1.
 		if site.Spec.DBConfig.Host != "" {
 			return true, nil
 		}
-		return false, fmt.Errorf("connectionSecretRef or host is required for external database provider")
+		return false, misconfiguredError("connectionSecretRef or host is required for external database provider")
 	}
 
-	secret := &corev1.Secret{}
-	err := p.client.Get(ctx, types.NamespacedName{
-		Name:      site.Spec.DBConfig.ConnectionSecretRef.Name,
-		Namespace: site.Namespace,
-	}, secret)
-	if err != nil {
-		return false, fmt.Errorf("failed to get database secret: %w", err)
+	if _, err := p.GetCredentials(ctx, site); err != nil {
+		return false, err
+	}
+	if _, err := p.EnsureDatabase(ctx, site); err != nil {
+		return false, err
 	}
-
 	return true, nil
 }
 
 // GetCredentials retrieves credentials from the secret
 func (p *ExternalProvider) GetCredentials(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (*DatabaseCredentials, error) {
 	if site.Spec.DBConfig.ConnectionSecretRef == nil {
-		return nil, fmt.Errorf("connectionSecretRef is required for external database provider to retrieve credentials")
+		return nil, misconfiguredError("connectionSecretRef is required for external database provider to retrieve credentials")
 	}
 
-	secret := &corev1.Secret{}
-	err := p.client.Get(ctx, types.NamespacedName{
-		Name:      site.Spec.DBConfig.ConnectionSecretRef.Name,
-		Namespace: site.Namespace,
-	}, secret)
+	secret, err := p.connectionSecret(ctx, site)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get database secret: %w", err)
+		return nil, err
 	}
 
 	username, ok := secret.Data["username"]
 	if !ok {
-		return nil, fmt.Errorf("username not found in database secret '%s'", secret.Name)
+		return nil, misconfiguredError("username not found in database secret '%s'", secret.Name)
 	}
 
 	password, ok := secret.Data["password"]
 	if !ok {
-		return nil, fmt.Errorf("password not found in database secret '%s'", secret.Name)
+		return nil, misconfiguredError("password not found in database secret '%s'", secret.Name)
 	}
 
 	return &DatabaseCredentials{
-		Username:   string(username),
-		Password:   string(password),
-		SecretName: secret.Name,
+		Username:        string(username),
+		Password:        string(password),
+		SecretName:      secret.Name,
+		SecretNamespace: secret.Namespace,
+		Version:         secret.ResourceVersion,
 	}, nil
 }
 
-// Cleanup does nothing for external databases
+// Cleanup does nothing for external databases; the site controller's drop-site job
+// removes the site database with the connection secret's credentials
 func (p *ExternalProvider) Cleanup(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) error {
 	return nil
 }
@@ -252,3 +252,77 @@ func TestCircuitBreakerProvider_Cleanup_WhenClosed(t *testing.T) {
 	err := wrapped.Cleanup(ctx, site)
 	assert.NoError(t, err)
 }
+
+func TestExternalProvider_SecretOnly(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = vyogotechv1alpha1.AddToScheme(scheme)
+	ctx := context.Background()
+	// The connection secret is read from the site's namespace, whatever the ref says
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "rds", Namespace: "test-ns"},
+		Data: map[string][]byte{
+			"host":     []byte("db.abc.eu-west-1.rds.amazonaws.com"),
+			"database": []byte("site_db"),
+			"username": []byte("site_user"),
+			"password": []byte("s3cret"),
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+	provider := NewExternalProvider(client)
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "mysite",
+			DBConfig: vyogotechv1alpha1.DatabaseConfig{
+				Provider:            "external",
+				ConnectionSecretRef: &corev1.SecretReference{Name: "rds", Namespace: "db-creds"},
+			},
+		},
+	}
+
+	ready, err := provider.IsReady(ctx, site)
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	info, err := provider.EnsureDatabase(ctx, site)
+	require.NoError(t, err)
+	assert.Equal(t, "db.abc.eu-west-1.rds.amazonaws.com", info.Host)
+	assert.Equal(t, "3306", info.Port)
+	assert.Equal(t, "site_db", info.Name)
+	assert.Equal(t, "mariadb", info.Provider)
+
+	creds, err := provider.GetCredentials(ctx, site)
+	require.NoError(t, err)
+	assert.Equal(t, "site_user", creds.Username)
+	assert.Equal(t, "test-ns", creds.SecretNamespace)
+}
+
+func TestExternalProvider_IsReady_IncompleteSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = vyogotechv1alpha1.AddToScheme(scheme)
+	ctx := context.Background()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "rds", Namespace: "test-ns"},
+		Data: map[string][]byte{
+			"username": []byte("site_user"),
+			"password": []byte("s3cret"),
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+	provider := NewExternalProvider(client)
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			DBConfig: vyogotechv1alpha1.DatabaseConfig{
+				Provider:            "external",
+				ConnectionSecretRef: &corev1.SecretReference{Name: "rds"},
+			},
+		},
+	}
+
+	ready, err := provider.IsReady(ctx, site)
+	assert.False(t, ready)
+	assert.ErrorContains(t, err, "host is required")
+}
@@ -233,13 +233,15 @@ func (p *MariaDBProviderUnstructured) GetCredentials(ctx context.Context, site *
 
 	password, ok := secret.Data[passwordSecretKey]
 	if !ok {
-		return nil, fmt.Errorf("password key '%s' not found in secret", passwordSecretKey)
+		return nil, misconfiguredError("password key '%s' not found in secret", passwordSecretKey)
 	}
 
 	return &DatabaseCredentials{
-		Username:   dbUser,
-		Password:   string(password),
-		SecretName: secret.Name,
+		Username:        dbUser,
+		Password:        string(password),
+		SecretName:      secret.Name,
+		SecretNamespace: secret.Namespace,
+		Version:         secret.ResourceVersion,
 	}, nil
 }
 
@@ -273,7 +275,7 @@ func (p *MariaDBProviderUnstructured) getMariaDBInstance(ctx context.Context, si
 	case "dedicated":
 		return p.createDedicatedMariaDB(ctx, site)
 	default:
-		return "", "", fmt.Errorf("unsupported database mode: %s", mode)
+		return "", "", misconfiguredError("unsupported database mode: %s", mode)
 	}
 }
 
@@ -295,7 +297,7 @@ func (p *MariaDBProviderUnstructured) findOrCreateSharedMariaDB(ctx context.Cont
 		return "", "", err
 	}
 
-	return "", "", fmt.Errorf("shared MariaDB instance '%s' not found in namespace '%s'. Please create a MariaDB CR or specify dbConfig.mariadbRef", mariadbName, site.Namespace)
+	return "", "", notFoundError("shared MariaDB instance '%s' not found in namespace '%s'. Please create a MariaDB CR or specify dbConfig.mariadbRef", mariadbName, site.Namespace)
 }
 
 func (p *MariaDBProviderUnstructured) createDedicatedMariaDB(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (string, string, error) {
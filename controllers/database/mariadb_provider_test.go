@@ -137,6 +137,39 @@ func TestMariaDBProvider_GetCredentials(t *testing.T) {
 	assert.Equal(t, secretName, creds.SecretName)
 }
 
+func TestMariaDBProvider_GetCredentials_RotatedSecret(t *testing.T) {
+	ns := "default"
+	userObj := &unstructured.Unstructured{}
+	userObj.SetGroupVersionKind(UserGVK)
+	userObj.SetName("mysite-user")
+	userObj.SetNamespace(ns)
+	require.NoError(t, unstructured.SetNestedField(userObj.Object, "dbuser123", "spec", "name"))
+	require.NoError(t, unstructured.SetNestedField(userObj.Object, "mysite-db-password", "spec", "passwordSecretKeyRef", "name"))
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysite-db-password", Namespace: ns},
+		Data:       map[string][]byte{"password": []byte("oldpass")},
+	}
+	client := fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(userObj, secret).Build()
+	p := NewMariaDBProvider(client, testScheme)
+	ctx := context.Background()
+	site := &vyogotechv1alpha1.FrappeSite{ObjectMeta: metav1.ObjectMeta{Name: "mysite", Namespace: ns}}
+
+	before, err := p.GetCredentials(ctx, site)
+	require.NoError(t, err)
+	assert.NotEmpty(t, before.Version)
+
+	// The MariaDB operator rewrites the password Secret
+	require.NoError(t, client.Get(ctx, types.NamespacedName{Name: "mysite-db-password", Namespace: ns}, secret))
+	secret.Data["password"] = []byte("newpass")
+	require.NoError(t, client.Update(ctx, secret))
+
+	after, err := p.GetCredentials(ctx, site)
+	require.NoError(t, err)
+	assert.Equal(t, "newpass", after.Password)
+	assert.NotEqual(t, before.Version, after.Version)
+}
+
 func TestMariaDBProvider_GetCredentials_UserNotFound(t *testing.T) {
 	client := fake.NewClientBuilder().WithScheme(testScheme).Build()
 	p := NewMariaDBProvider(client, testScheme).(*MariaDBProviderUnstructured)
@@ -1,5 +1,17 @@
 /*
 Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
 */
 
 package database
@@ -8,43 +20,112 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
-func TestPostgresProvider_NotImplemented(t *testing.T) {
-	scheme := runtime.NewScheme()
-	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
-	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
-	client := fake.NewClientBuilder().WithScheme(scheme).Build()
-	p := NewPostgresProvider(client, scheme)
+func newPostgresTestSite() *vyogotechv1alpha1.FrappeSite {
+	return &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysite", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "MySite.local",
+			DBConfig: vyogotechv1alpha1.DatabaseConfig{Provider: "postgres"},
+		},
+	}
+}
+
+func newPostgresTestCluster(name, namespace string) *unstructured.Unstructured {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(PostgresClusterGVK)
+	cluster.SetName(name)
+	cluster.SetNamespace(namespace)
+	return cluster
+}
+
+func TestPostgresProvider_EnsureDatabase_NoCluster(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(testScheme).Build()
+	p := NewPostgresProvider(client, testScheme)
+
+	_, err := p.EnsureDatabase(context.Background(), newPostgresTestSite())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), SharedPostgresCluster)
+}
+
+func TestPostgresProvider_DedicatedUnsupported(t *testing.T) {
+	site := newPostgresTestSite()
+	site.Spec.DBConfig.Mode = "dedicated"
+	_, _, err := PostgresClusterRef(site)
+	assert.Error(t, err)
+
+	site.Spec.DBConfig.PostgresRef = &vyogotechv1alpha1.NamespacedName{Name: "pg", Namespace: "databases"}
+	name, namespace, err := PostgresClusterRef(site)
+	require.NoError(t, err)
+	assert.Equal(t, "pg", name)
+	assert.Equal(t, "databases", namespace)
+}
+
+func TestPostgresProvider_Lifecycle(t *testing.T) {
 	ctx := context.Background()
-	site := &vyogotechv1alpha1.FrappeSite{ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"}}
+	site := newPostgresTestSite()
+	client := fake.NewClientBuilder().WithScheme(testScheme).
+		WithRuntimeObjects(newPostgresTestCluster(SharedPostgresCluster, "default")).Build()
+	p := NewPostgresProvider(client, testScheme).(*PostgresProvider)
+	dbName := p.generateDBName(site)
+	assert.Equal(t, dbName, p.generateDBName(site))
+	assert.NotContains(t, dbName, "M", "PostgreSQL identifiers should be lowercase")
 
-	_, err := p.EnsureDatabase(ctx, site)
-	if err == nil {
-		t.Fatal("EnsureDatabase expected error")
-	}
-	if err != nil && err.Error() == "" {
-		t.Error("expected non-empty error")
-	}
+	info, err := p.EnsureDatabase(ctx, site)
+	require.NoError(t, err)
+	assert.Equal(t, "frappe-postgres-rw.default.svc.cluster.local", info.Host)
+	assert.Equal(t, "5432", info.Port)
+	assert.Equal(t, "postgres", info.Provider)
 
-	_, err = p.IsReady(ctx, site)
-	if err == nil {
-		t.Fatal("IsReady expected error")
-	}
+	// A second pass must not add the role twice
+	_, err = p.EnsureDatabase(ctx, site)
+	require.NoError(t, err)
 
-	_, err = p.GetCredentials(ctx, site)
-	if err == nil {
-		t.Fatal("GetCredentials expected error")
-	}
+	cluster := newPostgresTestCluster(SharedPostgresCluster, "default")
+	require.NoError(t, client.Get(ctx, types.NamespacedName{Name: SharedPostgresCluster, Namespace: "default"}, cluster))
+	roles, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "managed", "roles")
+	require.Len(t, roles, 1)
+	role := roles[0].(map[string]interface{})
+	assert.Equal(t, dbName, role["name"])
+	assert.Equal(t, "present", role["ensure"])
 
-	err = p.Cleanup(ctx, site)
-	if err == nil {
-		t.Fatal("Cleanup expected error")
-	}
+	database := &unstructured.Unstructured{}
+	database.SetGroupVersionKind(PostgresDatabaseGVK)
+	require.NoError(t, client.Get(ctx, types.NamespacedName{Name: "mysite-db", Namespace: "default"}, database))
+	owner, _, _ := unstructured.NestedString(database.Object, "spec", "owner")
+	assert.Equal(t, dbName, owner)
+
+	// Not ready until CloudNativePG applied the database and reconciled the role
+	ready, err := p.IsReady(ctx, site)
+	require.NoError(t, err)
+	assert.False(t, ready)
+	require.NoError(t, unstructured.SetNestedField(database.Object, true, "status", "applied"))
+	require.NoError(t, client.Update(ctx, database))
+	require.NoError(t, unstructured.SetNestedStringSlice(cluster.Object, []string{dbName}, "status", "managedRolesStatus", "byStatus", "reconciled"))
+	require.NoError(t, client.Update(ctx, cluster))
+	ready, err = p.IsReady(ctx, site)
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	creds, err := p.GetCredentials(ctx, site)
+	require.NoError(t, err)
+	assert.Equal(t, dbName, creds.Username)
+	assert.NotEmpty(t, creds.Password)
+	secret := &corev1.Secret{}
+	require.NoError(t, client.Get(ctx, types.NamespacedName{Name: creds.SecretName, Namespace: "default"}, secret))
+	assert.Equal(t, corev1.SecretTypeBasicAuth, secret.Type)
+
+	require.NoError(t, p.Cleanup(ctx, site))
+	require.NoError(t, client.Get(ctx, types.NamespacedName{Name: SharedPostgresCluster, Namespace: "default"}, cluster))
+	roles, _, _ = unstructured.NestedSlice(cluster.Object, "spec", "managed", "roles")
+	assert.Equal(t, "absent", roles[0].(map[string]interface{})["ensure"])
 }
@@ -18,7 +18,6 @@ package database
 
 import (
 	"context"
-	"fmt"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	"github.com/vyogotech/frappe-operator/pkg/circuitbreaker"
@@ -54,6 +53,11 @@ type DatabaseCredentials struct {
 	Username   string
 	Password   string
 	SecretName string
+	// SecretNamespace is the namespace of SecretName, which isn't always the site's
+	SecretNamespace string
+	// Version is the resourceVersion of SecretName; it changes when the credentials are
+	// rotated, e.g. when the MariaDB operator recreates the user's password Secret
+	Version string
 }
 
 // NewProvider returns the appropriate provider based on config
@@ -71,7 +75,7 @@ func NewProvider(config vyogotechv1alpha1.DatabaseConfig, client client.Client,
 	case "mariadb":
 		return NewMariaDBProvider(client, scheme), nil
 	case "postgres":
-		return nil, fmt.Errorf("PostgreSQL provider not yet implemented - planned for v1.1.0")
+		return NewPostgresProvider(client, scheme), nil
 	case "sqlite":
 		return NewSQLiteProvider(client, scheme), nil
 	case "external":
@@ -79,6 +83,6 @@ func NewProvider(config vyogotechv1alpha1.DatabaseConfig, client client.Client,
 		cb := circuitbreaker.New(circuitbreaker.DefaultConfig("external-db"))
 		return NewCircuitBreakerProvider(inner, cb), nil
 	default:
-		return nil, fmt.Errorf("unsupported database provider: %s (supported: mariadb, postgres, sqlite, external)", providerType)
+		return nil, misconfiguredError("unsupported database provider: %s (supported: mariadb, postgres, sqlite, external)", providerType)
 	}
 }
@@ -75,17 +75,14 @@ func TestNewProvider(t *testing.T) {
 		_ = p
 	})
 
-	t.Run("postgres returns error", func(t *testing.T) {
+	t.Run("postgres", func(t *testing.T) {
 		config := vyogotechv1alpha1.DatabaseConfig{Provider: "postgres"}
 		p, err := NewProvider(config, client, scheme)
-		if err == nil {
-			t.Fatal("NewProvider(postgres) expected error")
-		}
-		if p != nil {
-			t.Error("NewProvider(postgres) should return nil provider")
+		if err != nil {
+			t.Fatalf("NewProvider(postgres) error: %v", err)
 		}
-		if err != nil && err.Error() == "" {
-			t.Error("expected non-empty error message")
+		if _, ok := p.(*PostgresProvider); !ok {
+			t.Errorf("NewProvider(postgres) returned %T", p)
 		}
 	})
 
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// eventDedupKey identifies a warning by object and event reason
+type eventDedupKey struct {
+	uid    types.UID
+	reason string
+}
+
+// eventDeduper records a Warning event only when its message differs from the last one
+// recorded for the object and reason, for problems that have no condition of their own
+// and are checked on every reconcile. It is kept in memory, so after a restart each
+// warning is recorded once more.
+type eventDeduper struct {
+	mu   sync.Mutex
+	last map[eventDedupKey]string
+}
+
+// warn records the Warning event unless the same message was the last one for obj and reason
+func (d *eventDeduper) warn(recorder record.EventRecorder, obj client.Object, reason, message string) {
+	key := eventDedupKey{uid: obj.GetUID(), reason: reason}
+	d.mu.Lock()
+	if d.last[key] == message {
+		d.mu.Unlock()
+		return
+	}
+	if d.last == nil {
+		d.last = make(map[eventDedupKey]string)
+	}
+	d.last[key] = message
+	d.mu.Unlock()
+	recorder.Event(obj, corev1.EventTypeWarning, reason, message)
+}
+
+// resolve forgets the warning of obj for reason, so it is recorded again if it comes back
+func (d *eventDeduper) resolve(obj client.Object, reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.last, eventDedupKey{uid: obj.GetUID(), reason: reason})
+}
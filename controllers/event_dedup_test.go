@@ -0,0 +1,47 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEventDeduper(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	bench := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "bench", UID: "bench-uid"}}
+	other := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "other", UID: "other-uid"}}
+	var d eventDeduper
+
+	d.warn(recorder, bench, "Reason", "first")
+	d.warn(recorder, bench, "Reason", "first")
+	d.warn(recorder, other, "Reason", "first")
+	d.warn(recorder, bench, "Reason", "second")
+	if got := len(recorder.Events); got != 3 {
+		t.Fatalf("expected 3 events, got %d", got)
+	}
+
+	// A resolved warning that comes back is reported again
+	d.resolve(bench, "Reason")
+	d.warn(recorder, bench, "Reason", "second")
+	if got := len(recorder.Events); got != 4 {
+		t.Errorf("expected the returning warning to be recorded, got %d events", got)
+	}
+}
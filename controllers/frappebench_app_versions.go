@@ -0,0 +1,208 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// appDriftCondition is True while the installed app versions differ from the bench spec
+	appDriftCondition = "AppDrift"
+
+	// appVersionsAnnotation records on the app versions job which image and apps it reads
+	appVersionsAnnotation = "frappe.tech/app-versions"
+
+	// appVersionsFailedReason is the AppDrift reason and event of a failed app versions job
+	appVersionsFailedReason = "AppVersionsFailed"
+)
+
+// appNamePattern matches a Python package name as bench lists apps
+var appNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// appVersionsFingerprint identifies the image and requested apps the recorded app
+// versions were read for; fpm versions are included so a new pin is checked again
+func appVersionsFingerprint(image string, bench *vyogotechv1alpha1.FrappeBench) string {
+	apps := make([]string, 0, len(bench.Spec.Apps))
+	for _, app := range bench.Spec.Apps {
+		if app.Version != "" {
+			apps = append(apps, app.Name+"@"+app.Version)
+		} else {
+			apps = append(apps, app.Name)
+		}
+	}
+	return migrationFingerprint(image, apps)
+}
+
+// parseAppVersions reads the app versions from the app versions job, one `<app> <version>`
+// line per app. bench version --format json doesn't fit the 4KB termination message
+// once a bench has a few apps, so the job keeps just these two columns.
+func parseAppVersions(output string) (map[string]string, error) {
+	versions := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		// bench may print warnings ahead of the versions
+		if len(fields) != 2 || !appNamePattern.MatchString(fields[0]) {
+			continue
+		}
+		versions[fields[0]] = fields[1]
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no app versions in bench version output %q", output)
+	}
+	return versions, nil
+}
+
+// appDrift lists how the installed app versions differ from the bench spec: apps that
+// are missing, and fpm apps whose installed version is not the pinned one
+func appDrift(bench *vyogotechv1alpha1.FrappeBench, versions map[string]string) []string {
+	var drift []string
+	for _, app := range bench.Spec.Apps {
+		installed, ok := versions[app.Name]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("%s is not installed", app.Name))
+		case app.Source == "fpm" && app.Version != "" && installed != app.Version:
+			drift = append(drift, fmt.Sprintf("%s is %s, want %s", app.Name, installed, app.Version))
+		}
+	}
+	sort.Strings(drift)
+	return drift
+}
+
+// setAppDriftCondition compares status.appVersions with the bench spec, emitting an
+// AppDrift event when the versions start to diverge
+func (r *FrappeBenchReconciler) setAppDriftCondition(bench *vyogotechv1alpha1.FrappeBench) {
+	drift := appDrift(bench, bench.Status.AppVersions)
+	if len(drift) == 0 {
+		r.setCondition(bench, metav1.Condition{
+			Type:    appDriftCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "VersionsMatch",
+			Message: "Installed app versions match the bench spec",
+		})
+		return
+	}
+	message := fmt.Sprintf("Installed apps differ from the bench spec: %s", strings.Join(drift, "; "))
+	if cond := meta.FindStatusCondition(bench.Status.Conditions, appDriftCondition); cond == nil || cond.Message != message {
+		r.Recorder.Event(bench, corev1.EventTypeWarning, appDriftCondition, message)
+	}
+	r.setCondition(bench, metav1.Condition{
+		Type:    appDriftCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "VersionMismatch",
+		Message: message,
+	})
+}
+
+// ensureAppVersions records the versions of the installed apps in status.appVersions by
+// running bench version in a `<bench>-app-versions` job whenever the bench image or
+// requested apps change, and keeps the AppDrift condition current.
+func (r *FrappeBenchReconciler) ensureAppVersions(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	image := r.getBenchImage(ctx, bench)
+	desired := appVersionsFingerprint(image, bench)
+	if bench.Status.AppVersionsSource == desired {
+		r.setAppDriftCondition(bench)
+		return nil
+	}
+
+	jobName := fmt.Sprintf("%s-app-versions", bench.Name)
+	job, err := runSiteJob(ctx, r.Client, bench, jobName, siteJobKey{annotation: appVersionsAnnotation, value: desired}, func() (*batchv1.Job, error) {
+		return r.buildAppVersionsJob(ctx, bench, jobName, image)
+	})
+	if errors.Is(err, errJobFailed) {
+		// Not retried on its own; the job stays around until it is deleted
+		r.failAppVersions(bench, fmt.Sprintf("App versions job %s failed, delete it to retry", jobName))
+		return nil
+	}
+	if job == nil || err != nil {
+		return err
+	}
+
+	output, err := jobTerminationMessage(ctx, r, job)
+	if err != nil {
+		return err
+	}
+	versions, err := parseAppVersions(output)
+	if err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("Recorded app versions", "versions", versions)
+	bench.Status.AppVersions = versions
+	bench.Status.AppVersionsSource = desired
+	r.setAppDriftCondition(bench)
+	return nil
+}
+
+// failAppVersions reports a failed app versions job once, as AppDrift=Unknown and a
+// Warning event
+func (r *FrappeBenchReconciler) failAppVersions(bench *vyogotechv1alpha1.FrappeBench, message string) {
+	cond := meta.FindStatusCondition(bench.Status.Conditions, appDriftCondition)
+	if cond != nil && cond.Reason == appVersionsFailedReason && cond.Message == message {
+		return
+	}
+	r.setCondition(bench, metav1.Condition{
+		Type:    appDriftCondition,
+		Status:  metav1.ConditionUnknown,
+		Reason:  appVersionsFailedReason,
+		Message: message,
+	})
+	r.Recorder.Event(bench, corev1.EventTypeWarning, appVersionsFailedReason, message)
+}
+
+// buildAppVersionsJob returns the job that runs bench version in image
+func (r *FrappeBenchReconciler) buildAppVersionsJob(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, jobName, image string) (*batchv1.Job, error) {
+	container := resources.NewContainerBuilder("app-versions", image).
+		WithCommand("bash", "-c").
+		WithArgs("cd /home/frappe/frappe-bench && bench version --format plain | awk 'NF >= 2 {print $1, $2}' > /dev/termination-log").
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		WithEnv("USER", "frappe").
+		Build()
+
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(benchJobPodConfig(bench), ownedLabels(bench, r.benchLabels(bench)))
+
+	return resources.NewJobBuilder(jobName, bench.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationAppVersions, bench.Name, "")).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(2).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(bench, r.Scheme).
+		Build()
+}
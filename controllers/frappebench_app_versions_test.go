@@ -0,0 +1,177 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+const benchVersionOutput = `Checking for updates...
+frappe 15.40.0
+erpnext 15.31.0`
+
+func TestParseAppVersions(t *testing.T) {
+	versions, err := parseAppVersions(benchVersionOutput)
+	if err != nil {
+		t.Fatalf("parseAppVersions: %v", err)
+	}
+	if len(versions) != 2 || versions["frappe"] != "15.40.0" || versions["erpnext"] != "15.31.0" {
+		t.Errorf("unexpected versions %v", versions)
+	}
+	if _, err := parseAppVersions("Checking for updates..."); err == nil {
+		t.Error("expected an error for output without versions")
+	}
+}
+
+func TestAppDrift(t *testing.T) {
+	bench := &vyogotechv1alpha1.FrappeBench{
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			Apps: []vyogotechv1alpha1.AppSource{
+				{Name: "erpnext", Source: "fpm", Org: "frappe", Version: "15.31.0"},
+				{Name: "hrms", Source: "image"},
+			},
+		},
+	}
+	drift := appDrift(bench, map[string]string{"frappe": "15.40.0", "erpnext": "15.30.0"})
+	want := []string{"erpnext is 15.30.0, want 15.31.0", "hrms is not installed"}
+	if strings.Join(drift, "|") != strings.Join(want, "|") {
+		t.Errorf("appDrift() = %v, want %v", drift, want)
+	}
+	if drift := appDrift(bench, map[string]string{"erpnext": "15.31.0", "hrms": "15.2.0"}); len(drift) != 0 {
+		t.Errorf("expected no drift, got %v", drift)
+	}
+}
+
+func TestEnsureAppVersions(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Spec.Apps = []vyogotechv1alpha1.AppSource{{Name: "erpnext", Source: "fpm", Org: "frappe", Version: "15.31.0"}}
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	recorder := record.NewFakeRecorder(20)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: recorder}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-app-versions", Namespace: "default"}
+
+	if err := r.ensureAppVersions(ctx, bench); err != nil {
+		t.Fatalf("ensureAppVersions: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationAppVersions {
+		t.Errorf("expected operation label %q, got %v", jobOperationAppVersions, job.Labels)
+	}
+	if !strings.Contains(job.Spec.Template.Spec.Containers[0].Args[0], "bench version --format plain") {
+		t.Errorf("expected the job to run bench version, got %v", job.Spec.Template.Spec.Containers[0].Args)
+	}
+
+	// The succeeded pod reports the versions in its termination message
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-app-versions-x", Namespace: "default", Labels: map[string]string{"job-name": job.Name}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: benchVersionOutput}},
+			}},
+		},
+	}
+	if err := c.Create(ctx, pod); err != nil {
+		t.Fatalf("Create Pod: %v", err)
+	}
+	if err := r.ensureAppVersions(ctx, bench); err != nil {
+		t.Fatalf("ensureAppVersions: %v", err)
+	}
+	if bench.Status.AppVersions["erpnext"] != "15.31.0" || bench.Status.AppVersionsSource == "" {
+		t.Errorf("expected the versions to be recorded, got %v (%q)", bench.Status.AppVersions, bench.Status.AppVersionsSource)
+	}
+	if cond := meta.FindStatusCondition(bench.Status.Conditions, appDriftCondition); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Errorf("expected AppDrift=False, got %+v", cond)
+	}
+
+	// A failed job is reported once and left for the user to delete
+	bench.Status.AppVersionsSource = ""
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	for range 2 {
+		if err := r.ensureAppVersions(ctx, bench); err != nil {
+			t.Fatalf("ensureAppVersions: %v", err)
+		}
+	}
+	if cond := meta.FindStatusCondition(bench.Status.Conditions, appDriftCondition); cond == nil || cond.Reason != appVersionsFailedReason {
+		t.Errorf("expected AppDrift reason %s, got %+v", appVersionsFailedReason, cond)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, appVersionsFailedReason) {
+		t.Errorf("expected an %s event, got %q", appVersionsFailedReason, event)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected the failure to be reported once, got %q", <-recorder.Events)
+	}
+	if err := c.Get(ctx, key, &batchv1.Job{}); err != nil {
+		t.Errorf("expected the failed job to be kept, got %v", err)
+	}
+	if err := c.Delete(ctx, job); err != nil {
+		t.Fatalf("Delete Job: %v", err)
+	}
+	if err := r.ensureAppVersions(ctx, bench); err != nil {
+		t.Fatalf("ensureAppVersions: %v", err)
+	}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("expected the deleted job to be recreated: %v", err)
+	}
+	bench.Status.AppVersionsSource = appVersionsFingerprint(r.getBenchImage(ctx, bench), bench)
+
+	// Installed versions are re-read when the image changes; until then a stale job is replaced
+	bench.Spec.FrappeVersion = "16"
+	if err := r.ensureAppVersions(ctx, bench); err != nil {
+		t.Fatalf("ensureAppVersions: %v", err)
+	}
+	if err := c.Get(ctx, key, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the stale app versions job to be deleted, got %v", err)
+	}
+
+	// A pin the installed version doesn't satisfy is reported as drift
+	bench.Spec.FrappeVersion = "15"
+	bench.Spec.Apps[0].Version = "15.32.0"
+	bench.Status.AppVersionsSource = appVersionsFingerprint(r.getBenchImage(ctx, bench), bench)
+	if err := r.ensureAppVersions(ctx, bench); err != nil {
+		t.Fatalf("ensureAppVersions: %v", err)
+	}
+	cond := meta.FindStatusCondition(bench.Status.Conditions, appDriftCondition)
+	if cond == nil || cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, "erpnext is 15.31.0, want 15.32.0") {
+		t.Errorf("expected AppDrift=True for erpnext, got %+v", cond)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, appDriftCondition) {
+		t.Errorf("expected an AppDrift event, got %q", event)
+	}
+}
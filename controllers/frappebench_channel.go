@@ -0,0 +1,91 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// frappeVersionChannels reads the channel to image tag map from the operator ConfigMap
+// key frappeVersionChannels, a JSON object such as {"stable": "v15.40.1"}
+func frappeVersionChannels(operatorConfig *corev1.ConfigMap) (map[string]string, error) {
+	if operatorConfig == nil || strings.TrimSpace(operatorConfig.Data["frappeVersionChannels"]) == "" {
+		return nil, nil
+	}
+	channels := map[string]string{}
+	if err := json.Unmarshal([]byte(operatorConfig.Data["frappeVersionChannels"]), &channels); err != nil {
+		return nil, fmt.Errorf("invalid frappeVersionChannels in operator config: %w", err)
+	}
+	return channels, nil
+}
+
+// channelImage returns the image spec.frappeVersionChannel was last resolved to, empty when
+// the bench follows no channel or the bench controller hasn't resolved it yet. Controllers
+// other than the bench controller use it as is so they never resolve a newer tag first.
+func channelImage(bench *vyogotechv1alpha1.FrappeBench) string {
+	if bench.Spec.FrappeVersionChannel == "" {
+		return ""
+	}
+	return bench.Status.ResolvedImage
+}
+
+// resolveFrappeVersionChannel resolves spec.frappeVersionChannel to an image and records it
+// in status.resolvedImage. The components and the migration job pick the image up from
+// there, so they only change when the channel's tag does. A channel missing from the
+// operator config keeps the last resolved image; without one the bench uses frappeVersion.
+func (r *FrappeBenchReconciler) resolveFrappeVersionChannel(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, operatorConfig *corev1.ConfigMap) {
+	channel := bench.Spec.FrappeVersionChannel
+	if channel == "" {
+		bench.Status.ResolvedImage = ""
+		return
+	}
+	logger := log.FromContext(ctx)
+
+	channels, err := frappeVersionChannels(operatorConfig)
+	tag, ok := channels[channel]
+	if err != nil || !ok || tag == "" {
+		message := fmt.Sprintf("Channel %q is not defined in the operator config frappeVersionChannels", channel)
+		if err != nil {
+			message = err.Error()
+		}
+		r.warnings.warn(r.Recorder, bench, "UnknownFrappeVersionChannel", message)
+		if bench.Status.ResolvedImage != "" {
+			return
+		}
+		tag = bench.Spec.FrappeVersion
+	} else {
+		r.warnings.resolve(bench, "UnknownFrappeVersionChannel")
+	}
+
+	image := r.benchImageForVersion(ctx, bench, tag)
+	if image == bench.Status.ResolvedImage {
+		return
+	}
+	if bench.Status.ResolvedImage != "" {
+		logger.Info("Frappe version channel moved, rolling components", "channel", channel, "from", bench.Status.ResolvedImage, "to", image)
+		r.Recorder.Event(bench, corev1.EventTypeNormal, "FrappeVersionChannelUpdated",
+			fmt.Sprintf("Channel %s moved from %s to %s", channel, bench.Status.ResolvedImage, image))
+	}
+	bench.Status.ResolvedImage = image
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// channelConfig returns an operator ConfigMap mapping the given channels to tags
+func channelConfig(channels string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "frappe-operator-config", Namespace: "frappe-operator-system"},
+		Data:       map[string]string{"frappeVersionChannels": channels},
+	}
+}
+
+func TestFrappeVersionChannels(t *testing.T) {
+	channels, err := frappeVersionChannels(channelConfig(`{"stable": "v15.40.1", "edge": "version-15"}`))
+	if err != nil {
+		t.Fatalf("frappeVersionChannels: %v", err)
+	}
+	if channels["stable"] != "v15.40.1" || channels["edge"] != "version-15" {
+		t.Errorf("unexpected channels %v", channels)
+	}
+	if channels, err := frappeVersionChannels(nil); err != nil || channels != nil {
+		t.Errorf("expected no channels without a ConfigMap, got %v, %v", channels, err)
+	}
+	if _, err := frappeVersionChannels(channelConfig("stable: v15")); err == nil {
+		t.Error("expected an error for a value that isn't a JSON object")
+	}
+}
+
+func TestResolveFrappeVersionChannel(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	bench.Spec.FrappeVersionChannel = "stable"
+	siteReconciler, _ := newInitJobTestReconciler()
+	recorder := record.NewFakeRecorder(10)
+	r := &FrappeBenchReconciler{Client: siteReconciler.Client, Scheme: siteReconciler.Scheme, Recorder: recorder}
+	ctx := context.Background()
+
+	r.resolveFrappeVersionChannel(ctx, bench, channelConfig(`{"stable": "v15.40.1"}`))
+	if bench.Status.ResolvedImage != "docker.io/frappe/erpnext:v15.40.1" {
+		t.Fatalf("expected the channel tag to be resolved, got %q", bench.Status.ResolvedImage)
+	}
+	if image := r.getBenchImage(ctx, bench); image != bench.Status.ResolvedImage {
+		t.Errorf("expected the bench to run the resolved image, got %q", image)
+	}
+	if image := (&SiteJobReconciler{}).getBenchImage(bench); image != bench.Status.ResolvedImage {
+		t.Errorf("expected site jobs to run the resolved image, got %q", image)
+	}
+
+	// Resolving the same tag again is a no-op
+	r.resolveFrappeVersionChannel(ctx, bench, channelConfig(`{"stable": "v15.40.1"}`))
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event while the channel is unchanged, got %q", <-recorder.Events)
+	}
+
+	// A channel missing from the config keeps the last resolved image
+	r.resolveFrappeVersionChannel(ctx, bench, channelConfig(`{"edge": "version-15"}`))
+	if bench.Status.ResolvedImage != "docker.io/frappe/erpnext:v15.40.1" {
+		t.Errorf("expected the last resolved image to stay, got %q", bench.Status.ResolvedImage)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "UnknownFrappeVersionChannel") {
+		t.Errorf("expected an UnknownFrappeVersionChannel event, got %q", event)
+	}
+	r.resolveFrappeVersionChannel(ctx, bench, channelConfig(`{"edge": "version-15"}`))
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected the unknown channel to be reported once, got %q", <-recorder.Events)
+	}
+
+	r.resolveFrappeVersionChannel(ctx, bench, channelConfig(`{"stable": "v15.41.0"}`))
+	if bench.Status.ResolvedImage != "docker.io/frappe/erpnext:v15.41.0" {
+		t.Errorf("expected the channel bump to be resolved, got %q", bench.Status.ResolvedImage)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "FrappeVersionChannelUpdated") {
+		t.Errorf("expected a FrappeVersionChannelUpdated event, got %q", event)
+	}
+
+	// Leaving the channel goes back to frappeVersion
+	bench.Spec.FrappeVersionChannel = ""
+	r.resolveFrappeVersionChannel(ctx, bench, channelConfig(`{"stable": "v15.41.0"}`))
+	if image := r.getBenchImage(ctx, bench); bench.Status.ResolvedImage != "" || image != "docker.io/frappe/erpnext:15" {
+		t.Errorf("expected the frappeVersion image without a channel, got %q (resolved %q)", image, bench.Status.ResolvedImage)
+	}
+}
+
+func TestEnsureBenchMigrated_channelBump(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	bench.Spec.FrappeVersionChannel = "stable"
+	bench.Status.ResolvedImage = "docker.io/frappe/erpnext:v15.40.1"
+	bench.Status.MigratedImage = "docker.io/frappe/erpnext:v15.40.1"
+	siteReconciler, c := newInitJobTestReconciler(bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-migrate", Namespace: "default"}
+
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	if err := c.Get(ctx, key, &batchv1.Job{}); err == nil {
+		t.Fatal("expected no migration job while the channel is unchanged")
+	}
+
+	r.resolveFrappeVersionChannel(ctx, bench, channelConfig(`{"stable": "v15.41.0"}`))
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("expected a migration job after the channel bump: %v", err)
+	}
+	if image := job.Spec.Template.Spec.Containers[0].Image; image != "docker.io/frappe/erpnext:v15.41.0" {
+		t.Errorf("expected the job to run the new channel image, got %q", image)
+	}
+}
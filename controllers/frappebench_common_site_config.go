@@ -0,0 +1,224 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// commonSiteConfigAnnotation records on the init and common site config jobs which
+	// spec.commonSiteConfig values they write
+	commonSiteConfigAnnotation = "frappe.tech/common-site-config"
+
+	// commonSiteConfigSecretMountPath is where the jobs mount spec.commonSiteConfigSecretRef
+	commonSiteConfigSecretMountPath = "/tmp/common-site-config"
+)
+
+// operatorManagedCommonSiteConfigKey reports whether the operator owns a
+// common_site_config.json key. spec.commonSiteConfig can't override these, the bench
+// would lose its redis.
+func operatorManagedCommonSiteConfigKey(key string) bool {
+	return key == "socketio_port" || strings.HasPrefix(key, "redis_")
+}
+
+// desiredCommonSiteConfig works out the patch for spec.commonSiteConfig and
+// spec.commonSiteConfigSecretRef, the sorted keys it writes and a hash of their values
+// ("" when nothing is wanted). Secret values only go into the hash, never into the patch.
+func (r *FrappeBenchReconciler) desiredCommonSiteConfig(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (siteConfigPatch, []string, string, error) {
+	patch := siteConfigPatch{Set: map[string]string{}}
+	values := map[string]string{}
+	var ignored []string
+	for key, value := range bench.Spec.CommonSiteConfig {
+		if operatorManagedCommonSiteConfigKey(key) {
+			ignored = append(ignored, fmt.Sprintf("spec.commonSiteConfig key %q", key))
+			continue
+		}
+		patch.Set[key] = value
+		values[key] = value
+	}
+	if ref := bench.Spec.CommonSiteConfigSecretRef; ref != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: bench.Namespace}, secret); err != nil {
+			return siteConfigPatch{}, nil, "", fmt.Errorf("failed to get common site config secret %s: %w", ref.Name, err)
+		}
+		for key, value := range secret.Data {
+			if operatorManagedCommonSiteConfigKey(key) {
+				ignored = append(ignored, fmt.Sprintf("commonSiteConfigSecretRef key %q", key))
+				continue
+			}
+			// Secret keys win over commonSiteConfig
+			delete(patch.Set, key)
+			patch.SecretKeys = append(patch.SecretKeys, key)
+			values[key] = strings.TrimRight(string(value), "\r\n")
+		}
+		sort.Strings(patch.SecretKeys)
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		r.warnings.warn(r.Recorder, bench, "CommonSiteConfigKeyIgnored",
+			fmt.Sprintf("Ignoring keys managed by the operator: %s", strings.Join(ignored, ", ")))
+	} else {
+		r.warnings.resolve(bench, "CommonSiteConfigKeyIgnored")
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range bench.Status.CommonSiteConfigKeys {
+		if _, ok := values[key]; !ok {
+			patch.Remove = append(patch.Remove, key)
+		}
+	}
+	if len(values) == 0 {
+		return patch, nil, "", nil
+	}
+
+	// encoding/json sorts map keys, so the hash is stable
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return siteConfigPatch{}, nil, "", err
+	}
+	return patch, keys, fmt.Sprintf("%x", sha256.Sum256(encoded))[:16], nil
+}
+
+// commonSiteConfigKey is the commonSiteConfigAnnotation value of a job writing patch
+func commonSiteConfigKey(patch siteConfigPatch, hash string) string {
+	return hash + ";" + strings.Join(patch.Remove, ",")
+}
+
+// withCommonSiteConfig hands the spec.commonSiteConfig patch to a job container: the keys
+// in COMMON_SITE_CONFIG_PATCH and, when there are secret keys, the Secret mounted at
+// commonSiteConfigSecretMountPath
+func withCommonSiteConfig(job *batchv1.Job, bench *vyogotechv1alpha1.FrappeBench, patch siteConfigPatch, hash string) error {
+	encodedPatch, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	job.Annotations[commonSiteConfigAnnotation] = commonSiteConfigKey(patch, hash)
+
+	podSpec := &job.Spec.Template.Spec
+	container := &podSpec.Containers[0]
+	container.Env = append(container.Env, corev1.EnvVar{Name: "COMMON_SITE_CONFIG_PATCH", Value: string(encodedPatch)})
+	if len(patch.SecretKeys) > 0 {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "common-site-config",
+			MountPath: commonSiteConfigSecretMountPath,
+			ReadOnly:  true,
+		})
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "common-site-config",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: bench.Spec.CommonSiteConfigSecretRef.Name},
+			},
+		})
+	}
+	return nil
+}
+
+// ensureCommonSiteConfig merges spec.commonSiteConfig and spec.commonSiteConfigSecretRef
+// into common_site_config.json and removes keys dropped from them. The init job writes the
+// keys on first init; later changes are applied by a small config job. Operator-managed
+// redis keys are never touched.
+func (r *FrappeBenchReconciler) ensureCommonSiteConfig(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	patch, keys, hash, err := r.desiredCommonSiteConfig(ctx, bench)
+	if err != nil {
+		return err
+	}
+	if hash == bench.Status.CommonSiteConfigHash && len(patch.Remove) == 0 {
+		return nil
+	}
+	desiredKey := commonSiteConfigKey(patch, hash)
+
+	// The init job already wrote these values; no need for a second job
+	initJob := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-init", bench.Name), Namespace: bench.Namespace}, initJob)
+	if err == nil && initJob.Status.Succeeded > 0 && initJob.Annotations[commonSiteConfigAnnotation] == desiredKey {
+		bench.Status.CommonSiteConfigKeys = slices.Clone(keys)
+		bench.Status.CommonSiteConfigHash = hash
+		return nil
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	jobName := fmt.Sprintf("%s-common-site-config", bench.Name)
+	job, err := runSiteJob(ctx, r.Client, bench, jobName, siteJobKey{annotation: commonSiteConfigAnnotation, value: desiredKey}, func() (*batchv1.Job, error) {
+		return r.buildCommonSiteConfigJob(ctx, bench, jobName, patch, hash)
+	})
+	if job == nil || err != nil {
+		return err
+	}
+
+	bench.Status.CommonSiteConfigKeys = slices.Clone(keys)
+	bench.Status.CommonSiteConfigHash = hash
+	r.Recorder.Event(bench, corev1.EventTypeNormal, "CommonSiteConfigApplied",
+		fmt.Sprintf("common_site_config.json keys set: [%s], removed: [%s]", strings.Join(keys, ", "), strings.Join(patch.Remove, ", ")))
+	return nil
+}
+
+// buildCommonSiteConfigJob returns the job that merges patch into common_site_config.json
+func (r *FrappeBenchReconciler) buildCommonSiteConfigJob(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, jobName string, patch siteConfigPatch, hash string) (*batchv1.Job, error) {
+	mergeScript, err := scripts.GetScript(scripts.CommonSiteConfigMerge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load common site config script: %w", err)
+	}
+
+	job := resources.NewJobBuilder(jobName, bench.Namespace).
+		WithLabels(jobLabels(jobOperationCommonSiteConfig, bench.Name, "")).
+		WithBackoffLimit(2).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(resources.NewContainerBuilder("common-site-config", r.getBenchImage(ctx, bench)).
+			WithCommand("bash", "-c").
+			WithArgs(mergeScript).
+			WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+			WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+			Build()).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(bench, r.Scheme).
+		MustBuild()
+	if err := withCommonSiteConfig(job, bench, patch, hash); err != nil {
+		return nil, err
+	}
+
+	// The job runs the bench image, so it belongs on the same nodes as the deployments
+	syncPodPlacement(&job.Spec.Template.Spec, benchJobPodConfig(bench))
+	applyDefaultJobTTL(&job.Spec)
+	return job, nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// commonSiteConfigPatch decodes the COMMON_SITE_CONFIG_PATCH of a job
+func commonSiteConfigPatch(t *testing.T, job *batchv1.Job) siteConfigPatch {
+	t.Helper()
+	var patch siteConfigPatch
+	if err := json.Unmarshal([]byte(jobEnv(job, "COMMON_SITE_CONFIG_PATCH")), &patch); err != nil {
+		t.Fatalf("COMMON_SITE_CONFIG_PATCH is not JSON: %v", err)
+	}
+	return patch
+}
+
+func TestOperatorManagedCommonSiteConfigKey(t *testing.T) {
+	for _, key := range []string{"redis_cache", "redis_queue", "redis_socketio", "socketio_port"} {
+		if !operatorManagedCommonSiteConfigKey(key) {
+			t.Errorf("expected %q to be operator-managed", key)
+		}
+	}
+	for _, key := range []string{"mail_server", "developer_mode", "db_host", "max_file_size"} {
+		if operatorManagedCommonSiteConfigKey(key) {
+			t.Errorf("expected %q to be user-settable", key)
+		}
+	}
+}
+
+func TestEnsureBenchInitialized_commonSiteConfig(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Spec.CommonSiteConfig = map[string]string{"developer_mode": "1", "redis_cache": "redis://elsewhere:6379"}
+	bench.Spec.CommonSiteConfigSecretRef = &corev1.LocalObjectReference{Name: "bench-extra"}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-extra", Namespace: "default"},
+		Data:       map[string][]byte{"mail_password": []byte("s3cret")},
+	}
+	siteReconciler, c := newInitJobTestReconciler(site, bench, secret)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+		t.Fatalf("ensureBenchInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get init Job: %v", err)
+	}
+	patch := commonSiteConfigPatch(t, job)
+	if len(patch.Set) != 1 || patch.Set["developer_mode"] != "1" {
+		t.Errorf("expected only developer_mode to be set, got %v", patch.Set)
+	}
+	if !slices.Equal(patch.SecretKeys, []string{"mail_password"}) {
+		t.Errorf("expected mail_password from the secret, got %v", patch.SecretKeys)
+	}
+	mounted := false
+	for _, mount := range job.Spec.Template.Spec.Containers[0].VolumeMounts {
+		mounted = mounted || mount.MountPath == commonSiteConfigSecretMountPath
+	}
+	if !mounted {
+		t.Error("expected the commonSiteConfigSecretRef Secret to be mounted in the init job")
+	}
+
+	// The init job already wrote the values, so no config job is needed afterwards
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if err := r.ensureCommonSiteConfig(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfig: %v", err)
+	}
+	if !slices.Equal(bench.Status.CommonSiteConfigKeys, []string{"developer_mode", "mail_password"}) || bench.Status.CommonSiteConfigHash == "" {
+		t.Errorf("expected the init job's keys recorded, got %v (hash %q)", bench.Status.CommonSiteConfigKeys, bench.Status.CommonSiteConfigHash)
+	}
+	err := c.Get(ctx, types.NamespacedName{Name: "bench-common-site-config", Namespace: "default"}, &batchv1.Job{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected no common site config job after init, got %v", err)
+	}
+}
+
+func TestEnsureCommonSiteConfig(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Spec.CommonSiteConfig = map[string]string{"developer_mode": "1"}
+	bench.Status.CommonSiteConfigKeys = []string{"developer_mode", "mail_server"}
+	bench.Status.CommonSiteConfigHash = "previous"
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "bench-common-site-config", Namespace: "default"}
+
+	if err := r.ensureCommonSiteConfig(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfig: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationCommonSiteConfig {
+		t.Errorf("expected operation label %q, got %q", jobOperationCommonSiteConfig, job.Labels[jobOperationLabel])
+	}
+	assertSitesMount(t, "common site config job", job.Spec.Template.Spec)
+	patch := commonSiteConfigPatch(t, job)
+	if patch.Set["developer_mode"] != "1" || !slices.Equal(patch.Remove, []string{"mail_server"}) {
+		t.Errorf("expected developer_mode set and mail_server removed, got %+v", patch)
+	}
+
+	// Nothing is recorded until the job succeeds
+	if err := r.ensureCommonSiteConfig(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfig: %v", err)
+	}
+	if bench.Status.CommonSiteConfigHash != "previous" {
+		t.Errorf("expected the hash to wait for the job, got %q", bench.Status.CommonSiteConfigHash)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if err := r.ensureCommonSiteConfig(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfig: %v", err)
+	}
+	if !slices.Equal(bench.Status.CommonSiteConfigKeys, []string{"developer_mode"}) || bench.Status.CommonSiteConfigHash == "previous" {
+		t.Errorf("expected developer_mode recorded with a new hash, got %v (hash %q)", bench.Status.CommonSiteConfigKeys, bench.Status.CommonSiteConfigHash)
+	}
+
+	// A later change replaces the finished job
+	bench.Spec.CommonSiteConfig["developer_mode"] = "0"
+	if err := r.ensureCommonSiteConfig(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfig: %v", err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the stale job to be deleted, got %v", err)
+	}
+}
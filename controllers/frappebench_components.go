@@ -0,0 +1,137 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// benchDeploymentComponents lists the Deployments a bench can own, by component name:
+// the web components, the scheduler and one worker per pool. Disabled components stay
+// in the list: their Deployments may still be terminating when the bench is deleted,
+// and handleFinalizer has to wait for them too.
+func (r *FrappeBenchReconciler) benchDeploymentComponents(bench *vyogotechv1alpha1.FrappeBench) []string {
+	components := []string{"gunicorn", "nginx", "socketio", "scheduler"}
+	for _, worker := range r.benchWorkers(bench) {
+		components = append(components, "worker-"+worker.name)
+	}
+	return components
+}
+
+// componentDisabledReasons holds the event reason emitted when an optional
+// component's Deployment is removed because spec.components turned it off
+var componentDisabledReasons = map[string]string{
+	"nginx":     "NginxDisabled",
+	"socketio":  "SocketIODisabled",
+	"scheduler": "SchedulerDisabled",
+}
+
+// componentEnabled reports whether an optional bench component (nginx, socketio or
+// scheduler) is enabled; components are enabled unless spec.components turns them off
+func componentEnabled(bench *vyogotechv1alpha1.FrappeBench, component string) bool {
+	components := bench.Spec.Components
+	if components == nil {
+		return true
+	}
+	var toggle *vyogotechv1alpha1.ComponentToggle
+	switch component {
+	case "nginx":
+		toggle = components.Nginx
+	case "socketio":
+		toggle = components.SocketIO
+	case "scheduler":
+		toggle = components.Scheduler
+	}
+	return toggle == nil || toggle.Enabled == nil || *toggle.Enabled
+}
+
+// validateComponents rejects component combinations that leave the bench unreachable.
+// Without nginx, site Ingresses and Routes reach gunicorn through the `<bench>-nginx`
+// Service and nothing routes /socket.io, so socketio has to be disabled as well.
+func validateComponents(bench *vyogotechv1alpha1.FrappeBench) error {
+	if !componentEnabled(bench, "nginx") && componentEnabled(bench, "socketio") {
+		return fmt.Errorf("components.socketio must be disabled when components.nginx is disabled: without nginx nothing routes /socket.io to Socket.IO")
+	}
+	if !componentEnabled(bench, "nginx") && bench.Spec.NginxTLS != nil {
+		return fmt.Errorf("nginxTLS requires components.nginx to be enabled")
+	}
+	return nil
+}
+
+// deleteComponentDeployment removes the Deployment of a disabled component
+func (r *FrappeBenchReconciler) deleteComponentDeployment(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, component string) error {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", bench.Name, component),
+			Namespace: bench.Namespace,
+		},
+	}
+	if err := r.Delete(ctx, deploy, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	log.FromContext(ctx).Info("Deleted Deployment of disabled component", "deployment", deploy.Name)
+	if reason, ok := componentDisabledReasons[component]; ok {
+		r.Recorder.Event(bench, corev1.EventTypeNormal, reason, fmt.Sprintf("Deleted Deployment %s: spec.components.%s.enabled is false", deploy.Name, component))
+	}
+	return nil
+}
+
+// componentsNotReadyCondition is True while a bench web or scheduler Deployment has
+// fewer ready replicas than desired
+const componentsNotReadyCondition = "ComponentsNotReady"
+
+// degradedComponents returns the gunicorn, nginx and scheduler Deployments with fewer
+// ready replicas than desired, as "<component> (<ready>/<desired> ready)". Disabled
+// components are skipped; a missing Deployment counts as degraded.
+func (r *FrappeBenchReconciler) degradedComponents(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) ([]string, error) {
+	var degraded []string
+	for _, component := range []string{"gunicorn", "nginx", "scheduler"} {
+		if component != "gunicorn" && !componentEnabled(bench, component) {
+			continue
+		}
+		deploy := &appsv1.Deployment{}
+		err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%s", bench.Name, component), Namespace: bench.Namespace}, deploy)
+		if errors.IsNotFound(err) {
+			degraded = append(degraded, component+" (not created)")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		desired := int32(1)
+		if deploy.Spec.Replicas != nil {
+			desired = *deploy.Spec.Replicas
+		}
+		if deploy.Status.ReadyReplicas < desired {
+			degraded = append(degraded, fmt.Sprintf("%s (%d/%d ready)", component, deploy.Status.ReadyReplicas, desired))
+		}
+	}
+	return degraded, nil
+}
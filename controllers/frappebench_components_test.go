@@ -0,0 +1,197 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateComponents(t *testing.T) {
+	disabled := func() *vyogotechv1alpha1.ComponentToggle {
+		enabled := false
+		return &vyogotechv1alpha1.ComponentToggle{Enabled: &enabled}
+	}
+
+	tests := []struct {
+		name       string
+		components *vyogotechv1alpha1.BenchComponents
+		wantErr    bool
+	}{
+		{name: "defaults"},
+		{name: "no scheduler", components: &vyogotechv1alpha1.BenchComponents{Scheduler: disabled()}},
+		{name: "api only", components: &vyogotechv1alpha1.BenchComponents{Nginx: disabled(), SocketIO: disabled()}},
+		{name: "no nginx with socketio", components: &vyogotechv1alpha1.BenchComponents{Nginx: disabled()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, bench := newInitJobTestObjects()
+			bench.Spec.Components = tt.components
+			if err := validateComponents(bench); (err != nil) != tt.wantErr {
+				t.Errorf("validateComponents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDisabledComponentsAreRemoved(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(bench)
+	recorder := record.NewFakeRecorder(20)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: recorder}
+	ctx := context.Background()
+
+	for _, ensure := range []func(context.Context, *vyogotechv1alpha1.FrappeBench) error{r.ensureNginx, r.ensureSocketIO, r.ensureScheduler} {
+		if err := ensure(ctx, bench); err != nil {
+			t.Fatalf("ensure: %v", err)
+		}
+	}
+
+	enabled := false
+	bench.Spec.Components = &vyogotechv1alpha1.BenchComponents{
+		Nginx:     &vyogotechv1alpha1.ComponentToggle{Enabled: &enabled},
+		SocketIO:  &vyogotechv1alpha1.ComponentToggle{Enabled: &enabled},
+		Scheduler: &vyogotechv1alpha1.ComponentToggle{Enabled: &enabled},
+	}
+	for _, ensure := range []func(context.Context, *vyogotechv1alpha1.FrappeBench) error{r.ensureNginx, r.ensureSocketIO, r.ensureScheduler} {
+		if err := ensure(ctx, bench); err != nil {
+			t.Fatalf("ensure with components disabled: %v", err)
+		}
+	}
+
+	for _, name := range []string{"bench-nginx", "bench-socketio", "bench-scheduler"} {
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &appsv1.Deployment{}); err == nil {
+			t.Errorf("expected Deployment %s to be deleted", name)
+		}
+	}
+
+	events := map[string]bool{}
+	for len(recorder.Events) > 0 {
+		events[strings.Fields(<-recorder.Events)[1]] = true
+	}
+	for _, reason := range []string{"NginxDisabled", "SocketIODisabled", "SchedulerDisabled"} {
+		if !events[reason] {
+			t.Errorf("expected %s event, got %v", reason, events)
+		}
+	}
+
+	// A second pass finds nothing to delete and stays quiet
+	if err := r.ensureScheduler(ctx, bench); err != nil {
+		t.Fatalf("ensureScheduler: %v", err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event once the scheduler Deployment is gone, got %q", <-recorder.Events)
+	}
+
+	// Sites keep routing through <bench>-nginx, which now points at gunicorn
+	svc := &corev1.Service{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-nginx", Namespace: "default"}, svc); err != nil {
+		t.Fatalf("expected nginx Service to remain: %v", err)
+	}
+	if svc.Spec.Selector["component"] != "gunicorn" || svc.Spec.Ports[0].Port != 8080 || svc.Spec.Ports[0].TargetPort.IntVal != 8000 {
+		t.Errorf("expected nginx Service to forward 8080 to gunicorn:8000, got selector %v ports %+v", svc.Spec.Selector, svc.Spec.Ports)
+	}
+
+	// Re-enabling nginx points the Service back at nginx
+	bench.Spec.Components.Nginx = nil
+	if err := r.ensureNginx(ctx, bench); err != nil {
+		t.Fatalf("ensureNginx: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-nginx", Namespace: "default"}, svc); err != nil {
+		t.Fatalf("Get nginx Service: %v", err)
+	}
+	if svc.Spec.Selector["component"] != "nginx" || svc.Spec.Ports[0].TargetPort.IntVal != 8080 {
+		t.Errorf("expected nginx Service to target nginx again, got selector %v ports %+v", svc.Spec.Selector, svc.Spec.Ports)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-nginx", Namespace: "default"}, &appsv1.Deployment{}); err != nil {
+		t.Errorf("expected nginx Deployment to be recreated: %v", err)
+	}
+}
+
+func TestUpdateBenchStatus_componentsNotReady(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	siteReconciler, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).WithObjects(bench).WithStatusSubresource(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	initJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "bench-init", Namespace: "default"}}
+	if err := c.Create(ctx, initJob); err != nil {
+		t.Fatalf("Create init Job: %v", err)
+	}
+	initJob.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, initJob); err != nil {
+		t.Fatalf("Update init Job status: %v", err)
+	}
+	for _, ensure := range []func(context.Context, *vyogotechv1alpha1.FrappeBench) error{r.ensureGunicornDeployment, r.ensureNginxDeployment, r.ensureScheduler} {
+		if err := ensure(ctx, bench); err != nil {
+			t.Fatalf("ensure: %v", err)
+		}
+	}
+
+	// Deployments start out with no ready replicas
+	if err := r.updateBenchStatus(ctx, bench, false, nil); err != nil {
+		t.Fatalf("updateBenchStatus: %v", err)
+	}
+	if bench.Status.Phase == "Ready" {
+		t.Error("expected the bench not to be Ready with 0 ready replicas")
+	}
+	ready := meta.FindStatusCondition(bench.Status.Conditions, "Ready")
+	if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != componentsNotReadyCondition {
+		t.Fatalf("expected Ready=False with reason %s, got %+v", componentsNotReadyCondition, ready)
+	}
+	notReady := meta.FindStatusCondition(bench.Status.Conditions, componentsNotReadyCondition)
+	if notReady == nil || notReady.Status != metav1.ConditionTrue {
+		t.Fatalf("expected %s=True, got %+v", componentsNotReadyCondition, notReady)
+	}
+	for _, component := range []string{"gunicorn (0/1 ready)", "nginx (0/1 ready)", "scheduler (0/1 ready)"} {
+		if !strings.Contains(notReady.Message, component) {
+			t.Errorf("expected %q in %q", component, notReady.Message)
+		}
+	}
+
+	for _, component := range []string{"gunicorn", "nginx", "scheduler"} {
+		deploy := &appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-" + component, Namespace: "default"}, deploy); err != nil {
+			t.Fatalf("Get %s Deployment: %v", component, err)
+		}
+		deploy.Status.ReadyReplicas = *deploy.Spec.Replicas
+		if err := c.Status().Update(ctx, deploy); err != nil {
+			t.Fatalf("Update %s Deployment status: %v", component, err)
+		}
+	}
+	if err := r.updateBenchStatus(ctx, bench, false, nil); err != nil {
+		t.Fatalf("updateBenchStatus: %v", err)
+	}
+	if bench.Status.Phase != "Ready" {
+		t.Errorf("expected the bench to be Ready once every component is, got phase %q", bench.Status.Phase)
+	}
+	if meta.IsStatusConditionTrue(bench.Status.Conditions, componentsNotReadyCondition) {
+		t.Errorf("expected %s to clear", componentsNotReadyCondition)
+	}
+}
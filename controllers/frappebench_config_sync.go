@@ -0,0 +1,219 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// redisConfigAnnotation records on the config sync job which redis URLs it writes
+	redisConfigAnnotation = "frappe.tech/redis-config"
+
+	// restartedAtAnnotation is stamped on pod templates to restart deployments (same key as kubectl rollout restart)
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+	// configSyncChanged is the termination message of a config sync job that rewrote the file
+	configSyncChanged = "changed"
+)
+
+// desiredRedisConfig returns the redis_cache and redis_queue URLs for the bench's current services
+func desiredRedisConfig(bench *vyogotechv1alpha1.FrappeBench) (string, string) {
+	return fmt.Sprintf("redis://%s-redis-cache:6379", bench.Name),
+		fmt.Sprintf("redis://%s-redis-queue:6379", bench.Name)
+}
+
+// ensureCommonSiteConfigSynced keeps the redis URLs in common_site_config.json pointed at the
+// bench's redis services, or the external redis. When the last synced pair differs, a job
+// rewrites the file and, if it actually changed anything, every deployment reading the file
+// is restarted in one batch.
+func (r *FrappeBenchReconciler) ensureCommonSiteConfigSynced(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	redisCache, redisQueue, err := resolveRedisURLs(ctx, r.Client, bench)
+	if err != nil {
+		return err
+	}
+	desired := redisConfigFingerprint(redisCache, redisQueue)
+	// Passwords stay in the <bench>-redis-urls Secret; everything else sees them masked
+	redisCache, redisQueue = redactRedisURL(redisCache), redactRedisURL(redisQueue)
+	if bench.Status.SyncedRedisConfig == desired {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	jobName := fmt.Sprintf("%s-config-sync", bench.Name)
+	job, err := runSiteJob(ctx, r.Client, bench, jobName, siteJobKey{annotation: redisConfigAnnotation, value: desired}, func() (*batchv1.Job, error) {
+		return r.buildConfigSyncJob(ctx, bench, jobName, redisCache, redisQueue)
+	})
+	if stderrors.Is(err, errJobFailed) {
+		// Remove the failed job so the retry of the returned error runs it again
+		failed := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: jobName, Namespace: bench.Namespace}}
+		if err := r.Delete(ctx, failed, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if job == nil || err != nil {
+		return err
+	}
+
+	result, err := jobTerminationMessage(ctx, r, job)
+	if err != nil {
+		return err
+	}
+	if result == configSyncChanged {
+		logger.Info("common_site_config.json redis URLs changed, restarting deployments", "redisCache", redisCache, "redisQueue", redisQueue)
+		if err := r.restartConfigConsumers(ctx, bench); err != nil {
+			return err
+		}
+		r.Recorder.Event(bench, corev1.EventTypeNormal, "RedisConfigSynced",
+			fmt.Sprintf("Pointed common_site_config.json at %s and %s, restarting deployments", redisCache, redisQueue))
+	}
+	bench.Status.SyncedRedisConfig = desired
+	return nil
+}
+
+// buildConfigSyncJob returns the job that points common_site_config.json at the redis URLs
+func (r *FrappeBenchReconciler) buildConfigSyncJob(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, jobName, redisCache, redisQueue string) (*batchv1.Job, error) {
+	syncScript, err := scripts.RenderScript(scripts.SyncCommonSiteConfig, scripts.SyncCommonSiteConfigData{
+		RedisCache: redisCache,
+		RedisQueue: redisQueue,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render config sync script: %w", err)
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: bench.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: int32Ptr(2),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: jobPodAnnotations(bench),
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					SecurityContext:    r.getPodSecurityContext(ctx, bench),
+					ImagePullSecrets:   imagePullSecrets(bench),
+					ServiceAccountName: jobServiceAccount(bench),
+					Containers: []corev1.Container{
+						{
+							Name:    "config-sync",
+							Image:   r.getBenchImage(ctx, bench),
+							Command: []string{"bash", "-c"},
+							Args:    []string{syncScript},
+							Env:     redisURLEnv(bench),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "sites",
+									MountPath: sitesMountPath,
+									SubPath:   sitesVolumeSubPath,
+								},
+							},
+							SecurityContext: r.getContainerSecurityContext(ctx, bench),
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "sites",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: fmt.Sprintf("%s-sites", bench.Name),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// The job runs the bench image, so it belongs on the same nodes as the deployments
+	syncPodPlacement(&job.Spec.Template.Spec, benchJobPodConfig(bench))
+	labelJob(job, jobLabels(jobOperationConfigSync, bench.Name, ""))
+	applyDefaultJobTTL(&job.Spec)
+
+	if err := controllerutil.SetControllerReference(bench, job, r.Scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// jobTerminationMessage returns the termination message of the succeeded pod of a job
+func jobTerminationMessage(ctx context.Context, c client.Reader, job *batchv1.Job) (string, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil {
+				return strings.TrimSpace(status.State.Terminated.Message), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no succeeded pod found for job %s", job.Name)
+}
+
+// restartConfigConsumers restarts every bench deployment that reads common_site_config.json,
+// stamping them all with the same timestamp so the restart happens as a single batch
+func (r *FrappeBenchReconciler) restartConfigConsumers(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(bench.Namespace), client.MatchingLabels(r.benchLabels(bench))); err != nil {
+		return err
+	}
+
+	restartedAt := time.Now().Format(time.RFC3339)
+	for i := range deployments.Items {
+		deploy := &deployments.Items[i]
+		// nginx only serves static files and proxies, it never talks to redis
+		if deploy.Spec.Selector != nil && deploy.Spec.Selector.MatchLabels["component"] == "nginx" {
+			continue
+		}
+
+		patch := client.MergeFrom(deploy.DeepCopy())
+		if deploy.Spec.Template.Annotations == nil {
+			deploy.Spec.Template.Annotations = make(map[string]string)
+		}
+		deploy.Spec.Template.Annotations[restartedAtAnnotation] = restartedAt
+		if err := r.Patch(ctx, deploy, patch); err != nil {
+			return fmt.Errorf("failed to restart deployment %s: %w", deploy.Name, err)
+		}
+	}
+
+	return nil
+}
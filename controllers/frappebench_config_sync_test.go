@@ -0,0 +1,124 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEnsureCommonSiteConfigSynced(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := r.ensureNginxDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureNginxDeployment: %v", err)
+	}
+
+	// A bench that previously synced under another name must be re-synced
+	bench.Status.SyncedRedisConfig = "redis://old-redis-cache:6379,redis://old-redis-queue:6379"
+	if err := r.ensureCommonSiteConfigSynced(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfigSynced: %v", err)
+	}
+
+	key := types.NamespacedName{Name: "bench-config-sync", Namespace: "default"}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	script := strings.Join(job.Spec.Template.Spec.Containers[0].Args, " ")
+	if !strings.Contains(script, "redis://bench-redis-cache:6379") || !strings.Contains(script, "redis://bench-redis-queue:6379") {
+		t.Errorf("expected sync script to write the bench redis URLs, got %q", script)
+	}
+	assertSitesMount(t, "config sync job", job.Spec.Template.Spec)
+
+	// A failed job is deleted so the retry runs it again
+	job.Status.Failed = 1
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if err := r.ensureCommonSiteConfigSynced(ctx, bench); err == nil {
+		t.Fatal("expected error after the config sync job failed")
+	}
+	if err := c.Get(ctx, key, job); err == nil {
+		t.Fatal("expected the failed config sync job to be deleted")
+	}
+	if err := r.ensureCommonSiteConfigSynced(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfigSynced: %v", err)
+	}
+	job = &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("expected recreated config sync job: %v", err)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-config-sync-abcde", Namespace: "default", Labels: map[string]string{"job-name": job.Name}},
+	}
+	if err := c.Create(ctx, pod); err != nil {
+		t.Fatalf("Create Pod: %v", err)
+	}
+	pod.Status = corev1.PodStatus{
+		Phase: corev1.PodSucceeded,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "config-sync",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "changed"}},
+		}},
+	}
+	if err := c.Status().Update(ctx, pod); err != nil {
+		t.Fatalf("Update Pod status: %v", err)
+	}
+
+	if err := r.ensureCommonSiteConfigSynced(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfigSynced: %v", err)
+	}
+	if bench.Status.SyncedRedisConfig != "redis://bench-redis-cache:6379,redis://bench-redis-queue:6379" {
+		t.Errorf("expected synced redis config to be recorded, got %q", bench.Status.SyncedRedisConfig)
+	}
+
+	gunicorn := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, gunicorn); err != nil {
+		t.Fatalf("Get gunicorn Deployment: %v", err)
+	}
+	if gunicorn.Spec.Template.Annotations[restartedAtAnnotation] == "" {
+		t.Error("expected gunicorn to be restarted after the config changed")
+	}
+	nginx := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-nginx", Namespace: "default"}, nginx); err != nil {
+		t.Fatalf("Get nginx Deployment: %v", err)
+	}
+	if _, ok := nginx.Spec.Template.Annotations[restartedAtAnnotation]; ok {
+		t.Error("nginx does not read redis URLs and should not be restarted")
+	}
+}
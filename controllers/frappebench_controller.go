@@ -25,10 +25,14 @@ import (
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	"github.com/vyogotech/frappe-operator/pkg/constants"
+	"github.com/vyogotech/frappe-operator/pkg/progress"
+	"github.com/vyogotech/frappe-operator/pkg/registry"
 	"github.com/vyogotech/frappe-operator/pkg/scripts"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -38,8 +42,11 @@ import (
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // FrappeBenchReconciler reconciles a FrappeBench object
@@ -48,6 +55,22 @@ type FrappeBenchReconciler struct {
 	Scheme      *runtime.Scheme
 	Recorder    record.EventRecorder
 	IsOpenShift bool
+	// DigestResolver resolves image tags to digests; defaults to the registry HTTP API
+	DigestResolver registry.DigestResolver
+	// SequentialEnsure reconciles bench components one at a time instead of concurrently (for debugging)
+	SequentialEnsure bool
+	// LookupTimeout bounds calls against optional operator APIs (KEDA); defaults to 10s
+	LookupTimeout time.Duration
+	// MaxConcurrentReconciles is how many benches are reconciled at once; the operator sets
+	// it from FRAPPE_MAX_CONCURRENT_BENCH_RECONCILES (default 3), zero leaves one at a time
+	MaxConcurrentReconciles int
+	// LogReader tails the log of a failed bench-init pod into status.initFailureLog; skipped when nil
+	LogReader progress.LogReader
+
+	// benchInits caps bench-init jobs running at once (operator config maxConcurrentBenchInits)
+	benchInits benchInitGate
+	// warnings keeps warnings re-checked on every reconcile from repeating their events
+	warnings eventDeduper
 }
 
 const frappeBenchFinalizer = "vyogo.tech/bench-finalizer"
@@ -57,7 +80,10 @@ const frappeBenchFinalizer = "vyogo.tech/bench-finalizer"
 //+kubebuilder:rbac:groups=vyogo.tech,resources=frappebenches/finalizers,verbs=update
 //+kubebuilder:rbac:groups=vyogo.tech,resources=frappesites,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 //+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch
@@ -65,9 +91,13 @@ const frappeBenchFinalizer = "vyogo.tech/bench-finalizer"
 //+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=keda.sh,resources=scaledobjects,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=keda.sh,resources=scaledobjects/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=keda.sh,resources=scaledobjects/finalizers,verbs=update
+//+kubebuilder:rbac:groups=keda.sh,resources=triggerauthentications,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop
@@ -79,6 +109,7 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	bench := &vyogotechv1alpha1.FrappeBench{}
 	if err := r.Get(ctx, req.NamespacedName, bench); err != nil {
 		if errors.IsNotFound(err) {
+			BenchReady.DeleteLabelValues(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		logger.Error(err, "Failed to get FrappeBench")
@@ -86,6 +117,8 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	observeBenchReady(bench.Namespace, bench.Name, bench.Status.Phase)
+
 	logger.Info("Reconciling FrappeBench", "name", bench.Name, "namespace", bench.Namespace)
 	r.Recorder.Event(bench, corev1.EventTypeNormal, "Reconciling", "Starting FrappeBench reconciliation")
 
@@ -96,6 +129,73 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return result, nil
 	}
 
+	// A paused bench keeps its owned resources exactly as they are
+	if setPausedCondition(&bench.Status.Conditions, bench.Spec.Paused, bench.Generation) {
+		if bench.Spec.Paused {
+			r.Recorder.Event(bench, corev1.EventTypeNormal, "Paused", "Reconciliation paused by spec.paused")
+		}
+		if err := r.updateStatus(ctx, bench); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if bench.Spec.Paused {
+		logger.Info("FrappeBench is paused, skipping reconciliation")
+		return ctrl.Result{}, nil
+	}
+
+	// A suspended bench keeps its data but runs no pods
+	if bench.Spec.Suspended {
+		logger.Info("FrappeBench is suspended, scaling to zero")
+		return r.suspendBench(ctx, bench)
+	}
+	if err := r.resumeBench(ctx, bench); err != nil {
+		logger.Error(err, "Failed to resume bench")
+		return ctrl.Result{}, err
+	}
+
+	// Get operator configuration
+	operatorConfig, err := r.getOperatorConfig(ctx, bench.Namespace)
+	if err != nil {
+		logger.Error(err, "Failed to get operator config")
+		// Continue with defaults
+	}
+
+	// Enforce the operator's required-label policy
+	policy := labelPolicyFromConfig(operatorConfig)
+	missingLabels, policyChanged := policy.evaluate(bench, &bench.Status.Conditions)
+	if policyChanged && len(missingLabels) > 0 {
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "PolicyViolation", fmt.Sprintf("Missing required labels: %s", strings.Join(missingLabels, ", ")))
+	}
+	if policy.blocks(missingLabels, bench.Status.Phase == "Ready") {
+		logger.Info("Required labels missing, holding provisioning", "missing", missingLabels)
+		r.setCondition(bench, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "PolicyViolation",
+			Message: fmt.Sprintf("Provisioning is blocked until the required labels are set: %s", strings.Join(missingLabels, ", ")),
+		})
+		return ctrl.Result{}, r.updateStatus(ctx, bench)
+	}
+
+	// Fail fast on referenced Secrets that don't exist instead of failing deep inside a job
+	missingSecret, err := findMissingSecret(ctx, r.Client, benchSecretRefs(bench))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if setMissingSecretCondition(&bench.Status.Conditions, missingSecret, bench.Generation) && missingSecret != "" {
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "MissingSecret", missingSecret)
+	}
+	if missingSecret != "" && bench.Status.Phase != "Ready" {
+		logger.Info("Referenced Secret missing, holding provisioning", "reason", missingSecret)
+		r.setCondition(bench, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "MissingSecret",
+			Message: missingSecret,
+		})
+		return ctrl.Result{RequeueAfter: missingSecretRequeue}, r.updateStatus(ctx, bench)
+	}
+
 	// Set progressing condition at start
 	r.setCondition(bench, metav1.Condition{
 		Type:    "Progressing",
@@ -109,13 +209,6 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// Get operator configuration
-	operatorConfig, err := r.getOperatorConfig(ctx, bench.Namespace)
-	if err != nil {
-		logger.Error(err, "Failed to get operator config")
-		// Continue with defaults
-	}
-
 	// Determine Git enabled status
 	gitEnabled := r.isGitEnabled(operatorConfig, bench)
 	logger.Info("Git configuration", "enabled", gitEnabled)
@@ -127,6 +220,52 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	logger.Info("FPM repositories configured", "count", len(fpmRepos))
 
+	// The webhook rejects these too, but it may not be deployed
+	if err := vyogotechv1alpha1.ValidateExtraVolumes(bench.Spec.ExtraVolumes, bench.Spec.ExtraVolumeMounts); err != nil {
+		logger.Error(err, "Invalid extra volumes")
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "InvalidExtraVolumes", err.Error())
+		r.setCondition(bench, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidExtraVolumes",
+			Message: err.Error(),
+		})
+		return ctrl.Result{}, r.updateStatus(ctx, bench)
+	}
+
+	// Reject component toggles that would leave the bench unreachable
+	if err := validateComponents(bench); err != nil {
+		logger.Error(err, "Invalid component configuration")
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "InvalidComponents", err.Error())
+		r.setCondition(bench, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidComponents",
+			Message: err.Error(),
+		})
+		return ctrl.Result{}, r.updateStatus(ctx, bench)
+	}
+
+	// Resolve the version channel before anything picks the bench image
+	r.resolveFrappeVersionChannel(ctx, bench, operatorConfig)
+
+	// The shared assets PVC is mounted by every pod, so it must be shareable read-only
+	problem, err := r.sharedAppsPVCProblem(ctx, bench)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if problem != "" {
+		logger.Info("Shared apps PVC is invalid, requeueing", "reason", problem)
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "SharedAppsPVCInvalid", problem)
+		r.setCondition(bench, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "SharedAppsPVCInvalid",
+			Message: problem,
+		})
+		return ctrl.Result{RequeueAfter: missingSecretRequeue}, r.updateStatus(ctx, bench)
+	}
+
 	// Ensure storage
 	if err := r.ensureBenchStorage(ctx, bench); err != nil {
 		logger.Error(err, "Failed to ensure storage")
@@ -141,8 +280,39 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	r.Recorder.Event(bench, corev1.EventTypeNormal, "StorageReady", "Storage provisioned successfully")
 
+	// Resolve an external redis before anything writes its URLs
+	if err := r.ensureExternalRedis(ctx, bench); err != nil {
+		if isRedisConfigInvalid(err) {
+			logger.Info("External redis connection secret is invalid, requeueing", "reason", err.Error())
+			r.Recorder.Event(bench, corev1.EventTypeWarning, "RedisConfigInvalid", err.Error())
+			r.setCondition(bench, metav1.Condition{
+				Type:    "Ready",
+				Status:  metav1.ConditionFalse,
+				Reason:  "RedisConfigInvalid",
+				Message: err.Error(),
+			})
+			return ctrl.Result{RequeueAfter: redisConfigInvalidRequeue}, r.updateStatus(ctx, bench)
+		}
+		logger.Error(err, "Failed to resolve external redis")
+		return ctrl.Result{}, err
+	}
+
 	// Ensure bench initialization
-	ready, err := r.ensureBenchInitialized(ctx, bench, gitEnabled, fpmRepos)
+	ready, err := r.ensureBenchInitialized(ctx, bench, gitEnabled, fpmRepos, maxConcurrentBenchInits(operatorConfig))
+	if isBenchInitQueued(err) {
+		logger.Info("Waiting for a free bench-init slot, requeueing", "reason", err.Error())
+		r.setCondition(bench, metav1.Condition{
+			Type:    "Progressing",
+			Status:  metav1.ConditionTrue,
+			Reason:  "InitQueued",
+			Message: fmt.Sprintf("Waiting for a free bench-init slot (%v)", err),
+		})
+		return ctrl.Result{RequeueAfter: benchInitQueuedRequeue}, r.updateStatus(ctx, bench)
+	}
+	if failed, ok := asBenchInitFailed(err); ok {
+		logger.Info("Bench init job failed", "job", failed.job, "retryAfter", failed.retryAfter)
+		return ctrl.Result{RequeueAfter: failed.retryAfter}, r.updateStatus(ctx, bench)
+	}
 	if err != nil {
 		logger.Error(err, "Failed to ensure bench initialized")
 		r.Recorder.Event(bench, corev1.EventTypeWarning, "InitializationFailed", fmt.Sprintf("Failed to initialize bench: %v", err))
@@ -167,37 +337,10 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	r.Recorder.Event(bench, corev1.EventTypeNormal, "Initialized", "Bench initialization completed")
 
-	// Ensure Redis
-	if err := r.ensureRedis(ctx, bench); err != nil {
-		logger.Error(err, "Failed to ensure Redis")
-		r.Recorder.Event(bench, corev1.EventTypeWarning, "RedisFailed", fmt.Sprintf("Failed to ensure Redis: %v", err))
+	// Redis, Gunicorn, NGINX and Socket.IO only need the initialized bench
+	if err := r.runEnsureSteps(ctx, bench, r.independentEnsureSteps()); err != nil {
 		return ctrl.Result{}, err
 	}
-	r.Recorder.Event(bench, corev1.EventTypeNormal, "RedisReady", "Redis service created")
-
-	// Ensure Gunicorn
-	if err := r.ensureGunicorn(ctx, bench); err != nil {
-		logger.Error(err, "Failed to ensure Gunicorn")
-		r.Recorder.Event(bench, corev1.EventTypeWarning, "GunicornFailed", fmt.Sprintf("Failed to ensure Gunicorn: %v", err))
-		return ctrl.Result{}, err
-	}
-	r.Recorder.Event(bench, corev1.EventTypeNormal, "GunicornReady", "Gunicorn deployment created")
-
-	// Ensure NGINX
-	if err := r.ensureNginx(ctx, bench); err != nil {
-		logger.Error(err, "Failed to ensure NGINX")
-		r.Recorder.Event(bench, corev1.EventTypeWarning, "NginxFailed", fmt.Sprintf("Failed to ensure NGINX: %v", err))
-		return ctrl.Result{}, err
-	}
-	r.Recorder.Event(bench, corev1.EventTypeNormal, "NginxReady", "NGINX deployment created")
-
-	// Ensure Socket.IO
-	if err := r.ensureSocketIO(ctx, bench); err != nil {
-		logger.Error(err, "Failed to ensure Socket.IO")
-		r.Recorder.Event(bench, corev1.EventTypeWarning, "SocketIOFailed", fmt.Sprintf("Failed to ensure Socket.IO: %v", err))
-		return ctrl.Result{}, err
-	}
-	r.Recorder.Event(bench, corev1.EventTypeNormal, "SocketIOReady", "Socket.IO deployment created")
 
 	// Ensure Scheduler
 	if err := r.ensureScheduler(ctx, bench); err != nil {
@@ -209,12 +352,57 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// Ensure Workers
 	if err := r.ensureWorkers(ctx, bench); err != nil {
+		if isLookupTimeout(err) {
+			logger.Info("KEDA API did not respond, requeueing", "reason", err.Error())
+			r.setCondition(bench, metav1.Condition{
+				Type:    "Progressing",
+				Status:  metav1.ConditionTrue,
+				Reason:  "LookupTimeout",
+				Message: err.Error(),
+			})
+			return ctrl.Result{RequeueAfter: lookupTimeoutRequeue}, r.updateStatus(ctx, bench)
+		}
 		logger.Error(err, "Failed to ensure Workers")
 		r.Recorder.Event(bench, corev1.EventTypeWarning, "WorkersFailed", fmt.Sprintf("Failed to ensure Workers: %v", err))
 		return ctrl.Result{}, err
 	}
 	r.Recorder.Event(bench, corev1.EventTypeNormal, "WorkersReady", "Worker deployments created")
 
+	// Point common_site_config.json at this bench's redis services
+	if err := r.ensureCommonSiteConfigSynced(ctx, bench); err != nil {
+		logger.Error(err, "Failed to sync common_site_config.json")
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "RedisConfigSyncFailed", fmt.Sprintf("Failed to sync redis URLs in common_site_config.json: %v", err))
+		// Don't fail the reconciliation; the sync is retried on the next reconcile
+	}
+
+	// Merge spec.commonSiteConfig into common_site_config.json
+	if err := r.ensureCommonSiteConfig(ctx, bench); err != nil {
+		logger.Error(err, "Failed to apply common site config")
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "CommonSiteConfigFailed", fmt.Sprintf("Failed to apply spec.commonSiteConfig: %v", err))
+		// Don't fail the reconciliation; the config is applied again on the next reconcile
+	}
+
+	// Migrate the sites when the bench image or apps changed
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		logger.Error(err, "Failed to ensure sites are migrated")
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "MigrationCheckFailed", fmt.Sprintf("Failed to check site migration: %v", err))
+		// Don't fail the reconciliation; the migration is retried on the next reconcile
+	}
+
+	// Record the installed app versions and flag drift from the spec
+	if err := r.ensureAppVersions(ctx, bench); err != nil {
+		logger.Error(err, "Failed to record app versions")
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "AppVersionsFailed", fmt.Sprintf("Failed to read app versions: %v", err))
+		// Don't fail the reconciliation; the versions are read again on the next reconcile
+	}
+
+	// Roll deployments if the image tag now points at a new digest
+	if err := r.ensureImageDigestRollout(ctx, bench); err != nil {
+		logger.Error(err, "Failed to check image digest")
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "ImageDigestCheckFailed", fmt.Sprintf("Failed to check image digest: %v", err))
+		// Don't fail the reconciliation; the digest is re-checked on the next poll
+	}
+
 	// Update worker scaling status
 	if err := r.updateWorkerScalingStatus(ctx, bench); err != nil {
 		logger.Error(err, "Failed to update worker scaling status")
@@ -235,6 +423,15 @@ func (r *FrappeBenchReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Record successful reconciliation duration
 	ReconciliationDuration.WithLabelValues("frappebench", "success").Observe(time.Since(startTime).Seconds())
 
+	// Pre-migration VolumeSnapshots aren't owned by the bench, so their progress isn't watched
+	if bench.Spec.MigrationSnapshot != nil && benchMigrationPending(bench) {
+		return ctrl.Result{RequeueAfter: volumeSnapshotPollInterval}, nil
+	}
+
+	// Neither a new digest nor a channel bump in the operator config triggers a reconcile
+	if rolloutOnDigestChange(bench) || bench.Spec.FrappeVersionChannel != "" {
+		return ctrl.Result{RequeueAfter: imageDigestPollInterval}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -290,67 +487,15 @@ func (r *FrappeBenchReconciler) handleFinalizer(ctx context.Context, bench *vyog
 			}
 
 			// 2. Scale down all deployments and statefulsets to 0
-			deploymentComponents := []string{"gunicorn", "nginx", "socketio", "scheduler", "worker-default", "worker-long", "worker-short"}
-			for _, component := range deploymentComponents {
-				deployName := fmt.Sprintf("%s-%s", bench.Name, component)
-				deploy := &appsv1.Deployment{}
-				if err := r.Get(ctx, types.NamespacedName{Name: deployName, Namespace: bench.Namespace}, deploy); err == nil {
-					if deploy.Spec.Replicas != nil && *deploy.Spec.Replicas > 0 {
-						logger.Info("Scaling down deployment", "deployment", deployName)
-						zero := int32(0)
-						deploy.Spec.Replicas = &zero
-						if err := r.Update(ctx, deploy); err != nil {
-							logger.Error(err, "Failed to scale down deployment", "deployment", deployName)
-							r.Recorder.Event(bench, corev1.EventTypeWarning, "ScaleDownFailed", fmt.Sprintf("Failed to scale down %s: %v", deployName, err))
-						} else {
-							r.Recorder.Event(bench, corev1.EventTypeNormal, "ScaledDown", fmt.Sprintf("Scaled down deployment %s", deployName))
-						}
-					}
-				}
-			}
-
-			redisComponents := []string{"redis-cache", "redis-queue"}
-			for _, component := range redisComponents {
-				stsName := fmt.Sprintf("%s-%s", bench.Name, component)
-				sts := &appsv1.StatefulSet{}
-				if err := r.Get(ctx, types.NamespacedName{Name: stsName, Namespace: bench.Namespace}, sts); err == nil {
-					if sts.Spec.Replicas != nil && *sts.Spec.Replicas > 0 {
-						logger.Info("Scaling down statefulset", "statefulset", stsName)
-						zero := int32(0)
-						sts.Spec.Replicas = &zero
-						if err := r.Update(ctx, sts); err != nil {
-							logger.Error(err, "Failed to scale down statefulset", "statefulset", stsName)
-							r.Recorder.Event(bench, corev1.EventTypeWarning, "ScaleDownFailed", fmt.Sprintf("Failed to scale down %s: %v", stsName, err))
-						} else {
-							r.Recorder.Event(bench, corev1.EventTypeNormal, "ScaledDown", fmt.Sprintf("Scaled down statefulset %s", stsName))
-						}
-					}
-				}
+			if err := r.scaleDownBench(ctx, bench); err != nil {
+				return ctrl.Result{}, err
 			}
 
 			// 3. Wait for pods to terminate (check if any pods are still running)
-			allTerminated := true
-			for _, component := range deploymentComponents {
-				deployName := fmt.Sprintf("%s-%s", bench.Name, component)
-				deploy := &appsv1.Deployment{}
-				if err := r.Get(ctx, types.NamespacedName{Name: deployName, Namespace: bench.Namespace}, deploy); err == nil {
-					if deploy.Status.Replicas > 0 || deploy.Status.ReadyReplicas > 0 {
-						allTerminated = false
-						logger.Info("Waiting for pods to terminate", "deployment", deployName, "replicas", deploy.Status.Replicas)
-					}
-				}
-			}
-			for _, component := range redisComponents {
-				stsName := fmt.Sprintf("%s-%s", bench.Name, component)
-				sts := &appsv1.StatefulSet{}
-				if err := r.Get(ctx, types.NamespacedName{Name: stsName, Namespace: bench.Namespace}, sts); err == nil {
-					if sts.Status.Replicas > 0 || sts.Status.ReadyReplicas > 0 {
-						allTerminated = false
-						logger.Info("Waiting for pods to terminate", "statefulset", stsName, "replicas", sts.Status.Replicas)
-					}
-				}
+			allTerminated, err := r.benchPodsTerminated(ctx, bench)
+			if err != nil {
+				return ctrl.Result{}, err
 			}
-
 			if !allTerminated {
 				logger.Info("Pods still terminating, requeuing")
 				return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
@@ -376,6 +521,7 @@ func (r *FrappeBenchReconciler) handleFinalizer(ctx context.Context, bench *vyog
 			if err := r.Update(ctx, bench); err != nil {
 				return ctrl.Result{}, err
 			}
+			BenchReady.DeleteLabelValues(bench.Namespace, bench.Name)
 		}
 		return ctrl.Result{}, nil
 	}
@@ -397,8 +543,14 @@ func (r *FrappeBenchReconciler) setCondition(bench *vyogotechv1alpha1.FrappeBenc
 	meta.SetStatusCondition(&bench.Status.Conditions, condition)
 }
 
-// updateStatus updates the FrappeBench status with proper error handling and conflict retry
+// updateStatus updates the FrappeBench status with proper error handling and conflict retry.
+// A bench without a phase yet is written as Provisioning, so the Phase and Ready columns
+// are never blank.
 func (r *FrappeBenchReconciler) updateStatus(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	if bench.Status.Phase == "" {
+		bench.Status.Phase = "Provisioning"
+	}
+	syncReadyCondition(&bench.Status.Conditions, "FrappeBench", bench.Status.Phase, bench.Generation)
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		latest := &vyogotechv1alpha1.FrappeBench{}
 		if err := r.Get(ctx, types.NamespacedName{Name: bench.Name, Namespace: bench.Namespace}, latest); err != nil {
@@ -410,6 +562,7 @@ func (r *FrappeBenchReconciler) updateStatus(ctx context.Context, bench *vyogote
 		}
 		// Sync back resource version to avoid conflict in subsequent updates in same reconcile loop
 		bench.SetResourceVersion(latest.GetResourceVersion())
+		observeBenchReady(bench.Namespace, bench.Name, bench.Status.Phase)
 		return nil
 	})
 }
@@ -467,8 +620,9 @@ func (r *FrappeBenchReconciler) mergeFPMRepositories(operatorConfig *corev1.Conf
 	return repos, nil
 }
 
-// ensureBenchInitialized creates a job to initialize the Frappe bench
-func (r *FrappeBenchReconciler) ensureBenchInitialized(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, gitEnabled bool, fpmRepos []vyogotechv1alpha1.FPMRepository) (bool, error) {
+// ensureBenchInitialized creates a job to initialize the Frappe bench. At most maxInits
+// bench-init jobs run at once across the cluster (unlimited when zero).
+func (r *FrappeBenchReconciler) ensureBenchInitialized(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, gitEnabled bool, fpmRepos []vyogotechv1alpha1.FPMRepository, maxInits int) (bool, error) {
 	logger := log.FromContext(ctx)
 
 	jobName := fmt.Sprintf("%s-init", bench.Name)
@@ -476,15 +630,26 @@ func (r *FrappeBenchReconciler) ensureBenchInitialized(ctx context.Context, benc
 
 	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: bench.Namespace}, job)
 	if err == nil {
+		// Every change of the job reconciles the bench, including the one finishing it
+		if err := r.benchInits.observe(ctx, r.Client); err != nil {
+			return false, err
+		}
 		// Job exists, check status
 		if job.Status.Succeeded > 0 {
 			return true, nil
 		}
+		if jobFailed(job) {
+			return false, r.handleFailedBenchInit(ctx, bench, job)
+		}
 		return false, nil
 	}
 	if !errors.IsNotFound(err) {
 		return false, err
 	}
+	// A succeeded init job is removed after its TTL; the Initialized condition outlives it
+	if meta.IsStatusConditionTrue(bench.Status.Conditions, "Initialized") {
+		return true, nil
+	}
 
 	// Create init job
 	logger.Info("Creating bench init job", "job", jobName)
@@ -500,18 +665,30 @@ func (r *FrappeBenchReconciler) ensureBenchInitialized(ctx context.Context, benc
 		skipBuild = "1"
 	}
 
+	// spec.commonSiteConfig is written along with the operator-managed keys
+	commonSiteConfig, _, commonSiteConfigHash, err := r.desiredCommonSiteConfig(ctx, bench)
+	if err != nil {
+		return false, err
+	}
+
 	// Create the job
 	pvcName := fmt.Sprintf("%s-sites", bench.Name)
 	job = &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      jobName,
 			Namespace: bench.Namespace,
+			Labels:    ownedLabels(bench, r.componentLabels(bench, benchInitJobComponent)),
 		},
 		Spec: batchv1.JobSpec{
 			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: jobPodAnnotations(bench),
+				},
 				Spec: corev1.PodSpec{
-					RestartPolicy:   corev1.RestartPolicyNever,
-					SecurityContext: r.getPodSecurityContext(ctx, bench),
+					RestartPolicy:      corev1.RestartPolicyNever,
+					SecurityContext:    r.getPodSecurityContext(ctx, bench),
+					ImagePullSecrets:   imagePullSecrets(bench),
+					ServiceAccountName: jobServiceAccount(bench),
 					Containers: []corev1.Container{
 						{
 							Name:    "bench-init",
@@ -521,20 +698,25 @@ func (r *FrappeBenchReconciler) ensureBenchInitialized(ctx context.Context, benc
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "sites",
-									MountPath: "/home/frappe/frappe-bench/sites",
+									MountPath: sitesMountPath,
+									SubPath:   sitesVolumeSubPath,
 								},
 							},
 							SecurityContext: r.getContainerSecurityContext(ctx, bench),
-							Env: []corev1.EnvVar{
+							Env: append([]corev1.EnvVar{
 								{
 									Name:  "SKIP_BENCH_BUILD",
 									Value: skipBuild,
 								},
+								{
+									Name:  "SHARED_ASSETS",
+									Value: sharedAssetsEnvValue(bench),
+								},
 								{
 									Name:  "USER",
 									Value: "frappe",
 								},
-							},
+							}, redisURLEnv(bench)...),
 						},
 					},
 					Volumes: []corev1.Volume{
@@ -552,26 +734,45 @@ func (r *FrappeBenchReconciler) ensureBenchInitialized(ctx context.Context, benc
 		},
 	}
 
-	applyDefaultJobTTL(&job.Spec)
+	if err := withCommonSiteConfig(job, bench, commonSiteConfig, commonSiteConfigHash); err != nil {
+		return false, err
+	}
+
+	// The job mounts the sites PVC, so it follows the bench's job placement
+	syncPodPlacement(&job.Spec.Template.Spec, benchJobPodConfig(bench))
+	labelJob(job, jobLabels(jobOperationInit, bench.Name, ""))
+	syncExtraVolumes(&job.Spec.Template, bench)
+	// A missing ConfigMap means the default TTL
+	operatorConfig, _ := r.getOperatorConfig(ctx, bench.Namespace)
+	job.Spec.TTLSecondsAfterFinished = int32Ptr(lifecycleJobTTL(bench, operatorConfig))
 
 	if err := controllerutil.SetControllerReference(bench, job, r.Scheme); err != nil {
 		return false, err
 	}
 
-	return false, r.Create(ctx, job)
+	return false, r.benchInits.create(ctx, r.Client, job, maxInits)
 }
 
-// getBenchImage returns the image to use for the bench
-// Priority: 1. bench.spec.imageConfig, 2. operator ConfigMap defaults, 3. hardcoded constants
+// getBenchImage returns the image to use for the bench: the image its frappeVersionChannel
+// resolved to, otherwise the image for spec.frappeVersion
 func (r *FrappeBenchReconciler) getBenchImage(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) string {
+	if image := channelImage(bench); image != "" {
+		return image
+	}
+	return r.benchImageForVersion(ctx, bench, bench.Spec.FrappeVersion)
+}
+
+// benchImageForVersion returns the bench image tagged with version
+// Priority: 1. bench.spec.imageConfig, 2. operator ConfigMap defaults, 3. hardcoded constants
+func (r *FrappeBenchReconciler) benchImageForVersion(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, version string) string {
 	// Priority 1: Check bench-level ImageConfig override
 	if bench.Spec.ImageConfig != nil && bench.Spec.ImageConfig.Repository != "" {
 		image := bench.Spec.ImageConfig.Repository
 		if bench.Spec.ImageConfig.Tag != "" {
 			image = fmt.Sprintf("%s:%s", image, bench.Spec.ImageConfig.Tag)
-		} else if bench.Spec.FrappeVersion != "" {
+		} else if version != "" {
 			// If tag not specified but version is, use version as tag
-			image = fmt.Sprintf("%s:%s", image, bench.Spec.FrappeVersion)
+			image = fmt.Sprintf("%s:%s", image, version)
 		}
 		return image
 	}
@@ -581,11 +782,11 @@ func (r *FrappeBenchReconciler) getBenchImage(ctx context.Context, bench *vyogot
 	if err == nil && operatorConfig != nil {
 		if defaultImage, ok := operatorConfig.Data["defaultFrappeImage"]; ok && defaultImage != "" {
 			// If version is specified, replace tag in default image
-			if bench.Spec.FrappeVersion != "" && bench.Spec.FrappeVersion != "latest" {
+			if version != "" && version != "latest" {
 				// Extract repository from default image and append version tag
 				parts := strings.Split(defaultImage, ":")
 				if len(parts) == 2 {
-					return fmt.Sprintf("%s:%s", parts[0], bench.Spec.FrappeVersion)
+					return fmt.Sprintf("%s:%s", parts[0], version)
 				}
 			}
 			return defaultImage
@@ -593,8 +794,8 @@ func (r *FrappeBenchReconciler) getBenchImage(ctx context.Context, bench *vyogot
 	}
 
 	// Priority 3: Fall back to constants with version
-	if bench.Spec.FrappeVersion != "" && bench.Spec.FrappeVersion != "latest" {
-		return fmt.Sprintf("docker.io/frappe/erpnext:%s", bench.Spec.FrappeVersion)
+	if version != "" && version != "latest" {
+		return fmt.Sprintf("docker.io/frappe/erpnext:%s", version)
 	}
 	return constants.DefaultFrappeImage
 }
@@ -626,10 +827,25 @@ func (r *FrappeBenchReconciler) updateWorkerScalingStatus(ctx context.Context, b
 		bench.Status.WorkerScaling = make(map[string]vyogotechv1alpha1.WorkerScalingStatus)
 	}
 
-	kedaAvailable := r.isKEDAAvailable(ctx)
-	workerTypes := []string{"default", "long", "short"}
+	kedaAvailable, err := r.isKEDAAvailable(ctx)
+	if err != nil {
+		return err
+	}
+	workers := r.benchWorkers(bench)
+
+	// Drop pools that were removed from spec.workers
+	wanted := make(map[string]bool, len(workers))
+	for _, worker := range workers {
+		wanted[worker.name] = true
+	}
+	for workerType := range bench.Status.WorkerScaling {
+		if !wanted[workerType] {
+			delete(bench.Status.WorkerScaling, workerType)
+		}
+	}
 
-	for _, workerType := range workerTypes {
+	for _, worker := range workers {
+		workerType := worker.name
 		// Get the deployment
 		deployName := fmt.Sprintf("%s-worker-%s", bench.Name, workerType)
 		deploy := &appsv1.Deployment{}
@@ -642,9 +858,7 @@ func (r *FrappeBenchReconciler) updateWorkerScalingStatus(ctx context.Context, b
 			continue
 		}
 
-		// Get autoscaling config
-		config := r.getWorkerAutoscalingConfig(bench, workerType)
-		config = r.fillAutoscalingDefaults(config, workerType)
+		config := worker.config
 
 		// Determine mode and replicas
 		mode := "static"
@@ -680,10 +894,7 @@ func (r *FrappeBenchReconciler) updateBenchStatus(ctx context.Context, bench *vy
 	logger := log.FromContext(ctx)
 
 	// Collect installed app names
-	installedApps := make([]string, 0, len(bench.Spec.Apps))
-	for _, app := range bench.Spec.Apps {
-		installedApps = append(installedApps, app.Name)
-	}
+	installedApps := benchAppNames(bench)
 
 	// Collect FPM repository names
 	repoNames := make([]string, 0, len(fpmRepos))
@@ -706,8 +917,11 @@ func (r *FrappeBenchReconciler) updateBenchStatus(ctx context.Context, bench *vy
 	// Check if init job is succeeded
 	jobName := fmt.Sprintf("%s-init", bench.Name)
 	job := &batchv1.Job{}
-	if err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: bench.Namespace}, job); err == nil {
-		if job.Status.Succeeded > 0 {
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: bench.Namespace}, job)
+	// The succeeded init job is removed after its TTL; the Initialized condition outlives it
+	initRemoved := errors.IsNotFound(err) && meta.IsStatusConditionTrue(bench.Status.Conditions, "Initialized")
+	if err == nil || initRemoved {
+		if initRemoved || job.Status.Succeeded > 0 {
 			bench.Status.Phase = "Ready"
 			isReady = true
 			r.setCondition(bench, metav1.Condition{
@@ -722,29 +936,87 @@ func (r *FrappeBenchReconciler) updateBenchStatus(ctx context.Context, bench *vy
 				Reason:  "JobCompleted",
 				Message: "Initialization job completed successfully",
 			})
-		} else if job.Status.Failed > 0 {
+			bench.Status.InitFailureLog = ""
+			bench.Status.InitFailureSummary = ""
+			// A failed init job that was retried no longer degrades the bench
+			if degraded := meta.FindStatusCondition(bench.Status.Conditions, "Degraded"); degraded != nil && degraded.Status == metav1.ConditionTrue && degraded.Reason == "JobFailed" {
+				r.setCondition(bench, metav1.Condition{
+					Type:    "Degraded",
+					Status:  metav1.ConditionFalse,
+					Reason:  "Initialized",
+					Message: "Initialization job completed successfully",
+				})
+			}
+		}
+	}
+
+	// Sites running the previous image aren't served correctly until bench migrate succeeds
+	if isReady && benchMigrationPending(bench) {
+		isReady = false
+		cond := meta.FindStatusCondition(bench.Status.Conditions, migratingCondition)
+		if cond.Reason == migrationFailedReason {
 			bench.Status.Phase = "Failed"
+		} else {
+			bench.Status.Phase = "Migrating"
+		}
+		r.setCondition(bench, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  cond.Reason,
+			Message: cond.Message,
+		})
+	}
+
+	// An initialized bench isn't Ready while its web or scheduler pods aren't, so sites
+	// don't init against a half-up bench
+	if isReady {
+		degraded, err := r.degradedComponents(ctx, bench)
+		if err != nil {
+			return err
+		}
+		if len(degraded) > 0 {
+			isReady = false
+			bench.Status.Phase = "Provisioning"
+			message := "Components not ready: " + strings.Join(degraded, ", ")
 			r.setCondition(bench, metav1.Condition{
 				Type:    "Ready",
 				Status:  metav1.ConditionFalse,
-				Reason:  "InitializationFailed",
-				Message: "Initialization job failed",
+				Reason:  componentsNotReadyCondition,
+				Message: message,
 			})
 			r.setCondition(bench, metav1.Condition{
-				Type:    "Degraded",
+				Type:    componentsNotReadyCondition,
 				Status:  metav1.ConditionTrue,
-				Reason:  "JobFailed",
-				Message: "Initialization job failed",
+				Reason:  "ReplicasNotReady",
+				Message: message,
+			})
+		} else if meta.FindStatusCondition(bench.Status.Conditions, componentsNotReadyCondition) != nil {
+			r.setCondition(bench, metav1.Condition{
+				Type:    componentsNotReadyCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ReplicasReady",
+				Message: "All components have their desired ready replicas",
 			})
 		}
 	}
 
 	// Update status fields
 	bench.Status.GitEnabled = gitEnabled
-	bench.Status.InstalledApps = installedApps
+	if !benchMigrationPending(bench) {
+		// While migrating, installedApps keeps the apps the sites were last migrated with
+		bench.Status.InstalledApps = installedApps
+	}
 	bench.Status.FPMRepositories = repoNames
 	bench.Status.ObservedGeneration = bench.Generation
 
+	var redisCache, redisQueue string
+	if isExternalRedis(bench) {
+		if redisCache, redisQueue, err = resolveRedisURLs(ctx, r.Client, bench); err != nil {
+			logger.Info("External redis is not resolvable, leaving it out of the endpoints", "reason", err.Error())
+		}
+	}
+	bench.Status.Endpoints = r.benchEndpoints(bench, redisCache, redisQueue)
+
 	// Update status with proper error handling
 	if err := r.updateStatus(ctx, bench); err != nil {
 		logger.Error(err, "Failed to update bench status")
@@ -757,16 +1029,39 @@ func (r *FrappeBenchReconciler) updateBenchStatus(ctx context.Context, bench *vy
 	return nil
 }
 
+// autoSizedBenchForSite maps a FrappeSite to its bench when that bench sizes redis from its site count
+func (r *FrappeBenchReconciler) autoSizedBenchForSite(ctx context.Context, obj client.Object) []reconcile.Request {
+	site, ok := obj.(*vyogotechv1alpha1.FrappeSite)
+	if !ok || site.Spec.BenchRef == nil {
+		return nil
+	}
+	key := types.NamespacedName{Name: site.Spec.BenchRef.Name, Namespace: site.Namespace}
+	bench := &vyogotechv1alpha1.FrappeBench{}
+	if err := r.Get(ctx, key, bench); err != nil || redisAutoSize(bench) == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: key}}
+}
+
 // SetupWithManager sets up the controller with the Manager
 func (r *FrappeBenchReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	opts := controller.Options{}
+	if r.MaxConcurrentReconciles > 0 {
+		opts.MaxConcurrentReconciles = r.MaxConcurrentReconciles
+	}
 	builder := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(opts).
 		For(&vyogotechv1alpha1.FrappeBench{}).
 		Owns(&corev1.Service{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.PersistentVolumeClaim{}).
 		Owns(&batchv1.Job{}).
 		Owns(&appsv1.Deployment{}).
-		Owns(&appsv1.StatefulSet{})
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		// Resize auto-sized redis caches as sites become (or stop being) Ready
+		Watches(&vyogotechv1alpha1.FrappeSite{}, handler.EnqueueRequestsFromMapFunc(r.autoSizedBenchForSite))
 
 	// Detect platform
 	// r.IsOpenShift is already set by main.go, no need to re-detect
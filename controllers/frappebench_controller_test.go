@@ -30,7 +30,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -350,7 +352,7 @@ var _ = Describe("FrappeBench Controller", func() {
 			bench.Spec.FrappeVersion = "15"
 			Expect(fakeClient.Create(ctx, bench)).To(Succeed())
 
-			_, err := reconciler.ensureBenchInitialized(ctx, bench, false, nil)
+			_, err := reconciler.ensureBenchInitialized(ctx, bench, false, nil, 0)
 			Expect(err).NotTo(HaveOccurred())
 
 			job := &batchv1.Job{}
@@ -360,4 +362,22 @@ var _ = Describe("FrappeBench Controller", func() {
 			Expect(*job.Spec.TTLSecondsAfterFinished).To(Equal(resources.DefaultJobTTL))
 		})
 	})
+
+	Describe("SetupWithManager", func() {
+		It("succeeds when MaxConcurrentReconciles is set", func() {
+			if skipControllerTests {
+				Skip("envtest not available")
+			}
+			mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: clientgoscheme.Scheme})
+			Expect(err).NotTo(HaveOccurred())
+			r := &FrappeBenchReconciler{
+				Client:                  mgr.GetClient(),
+				Scheme:                  mgr.GetScheme(),
+				Recorder:                mgr.GetEventRecorderFor("frappebench-controller"),
+				IsOpenShift:             false,
+				MaxConcurrentReconciles: 5,
+			}
+			Expect(r.SetupWithManager(mgr)).To(Succeed())
+		})
+	})
 })
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"maps"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	"github.com/vyogotech/frappe-operator/pkg/resources"
@@ -31,10 +32,88 @@ import (
 
 // ensureGunicorn ensures the Gunicorn Deployment and Service exist
 func (r *FrappeBenchReconciler) ensureGunicorn(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
-	if err := r.ensureGunicornService(ctx, bench); err != nil {
+	if bench.Spec.CombinedWebService {
+		if err := r.ensureWebService(ctx, bench); err != nil {
+			return err
+		}
+	} else if err := r.ensureGunicornService(ctx, bench); err != nil {
+		return err
+	}
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
 		return err
 	}
-	return r.ensureGunicornDeployment(ctx, bench)
+	return r.ensureGunicornHPA(ctx, bench)
+}
+
+// ensureWebService ensures the combined gunicorn/socketio Service exists and the
+// per-component services it replaces are removed
+func (r *FrappeBenchReconciler) ensureWebService(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	logger := log.FromContext(ctx)
+
+	for _, component := range []string{"gunicorn", "socketio"} {
+		old := &corev1.Service{}
+		name := fmt.Sprintf("%s-%s", bench.Name, component)
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: bench.Namespace}, old); err == nil {
+			logger.Info("Deleting per-component Service replaced by combined web Service", "service", name)
+			if err := r.Delete(ctx, old); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	svcName := fmt.Sprintf("%s-web", bench.Name)
+	svc := &corev1.Service{}
+
+	err := r.Get(ctx, types.NamespacedName{Name: svcName, Namespace: bench.Namespace}, svc)
+	if err == nil {
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		if !syncOwnedLabels(&svc.ObjectMeta, nil, extraLabels) {
+			return nil
+		}
+		logger.Info("Updating combined web Service labels", "service", svcName)
+		return r.Update(ctx, svc)
+	}
+
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	logger.Info("Creating combined web Service", "service", svcName)
+
+	// Apply Pod Config (Labels only for Service)
+	_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+
+	// Named target ports make each port resolve only to the pods that declare it
+	svc, err = resources.NewServiceBuilder(svcName, bench.Namespace).
+		WithLabels(extraLabels).
+		WithSelector(r.webBackendLabels(bench)).
+		WithNamedTargetPort("http", 8000, "http").
+		WithNamedTargetPort("socketio", 9000, "socketio").
+		WithOwner(bench, r.Scheme).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	return r.Create(ctx, svc)
+}
+
+// deleteWebServiceIfExists removes the combined web Service when switching back to separate services
+func (r *FrappeBenchReconciler) deleteWebServiceIfExists(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	svc := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-web", bench.Name), Namespace: bench.Namespace}, svc)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := r.Delete(ctx, svc); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
 }
 
 func (r *FrappeBenchReconciler) ensureGunicornService(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
@@ -45,7 +124,12 @@ func (r *FrappeBenchReconciler) ensureGunicornService(ctx context.Context, bench
 
 	err := r.Get(ctx, types.NamespacedName{Name: svcName, Namespace: bench.Namespace}, svc)
 	if err == nil {
-		return nil
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		if !syncOwnedLabels(&svc.ObjectMeta, nil, extraLabels) {
+			return nil
+		}
+		logger.Info("Updating Gunicorn Service labels", "service", svcName)
+		return r.Update(ctx, svc)
 	}
 
 	if !errors.IsNotFound(err) {
@@ -55,7 +139,7 @@ func (r *FrappeBenchReconciler) ensureGunicornService(ctx context.Context, bench
 	logger.Info("Creating Gunicorn Service", "service", svcName)
 
 	// Apply Pod Config (Labels only for Service)
-	_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, r.benchLabels(bench))
+	_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
 
 	svc, err = resources.NewServiceBuilder(svcName, bench.Namespace).
 		WithLabels(extraLabels).
@@ -79,10 +163,70 @@ func (r *FrappeBenchReconciler) ensureGunicornDeployment(ctx context.Context, be
 	err := r.Get(ctx, types.NamespacedName{Name: deployName, Namespace: bench.Namespace}, deploy)
 	if err == nil {
 		// Update existing deployment if image has changed
-		image := r.getBenchImage(ctx, bench)
+		image := r.getComponentImage(ctx, bench, "gunicorn")
+		changed := false
 		if deploy.Spec.Template.Spec.Containers[0].Image != image {
 			logger.Info("Updating Gunicorn Deployment image", "deployment", deployName, "oldImage", deploy.Spec.Template.Spec.Containers[0].Image, "newImage", image)
 			deploy.Spec.Template.Spec.Containers[0].Image = image
+			changed = true
+		}
+		// Pods created before combinedWebService was enabled need the web backend label
+		if bench.Spec.CombinedWebService && deploy.Spec.Template.Labels[webBackendLabel] != "true" {
+			if deploy.Spec.Template.Labels == nil {
+				deploy.Spec.Template.Labels = make(map[string]string)
+			}
+			deploy.Spec.Template.Labels[webBackendLabel] = "true"
+			changed = true
+		}
+		if syncPodPlacement(&deploy.Spec.Template.Spec, bench.Spec.PodConfig) {
+			logger.Info("Updating pod placement", "deployment", deployName)
+			changed = true
+		}
+		if syncImagePullSecrets(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating image pull secrets", "deployment", deployName)
+			changed = true
+		}
+		if syncServiceAccount(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating service account", "deployment", deployName)
+			changed = true
+		}
+		if syncSharedAssets(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating shared assets volume", "deployment", deployName)
+			changed = true
+		}
+		if syncExtraVolumes(&deploy.Spec.Template, bench) {
+			logger.Info("Updating extra volumes", "deployment", deployName)
+			changed = true
+		}
+		if syncComponentProbes(&deploy.Spec.Template.Spec.Containers[0], bench, "gunicorn") {
+			logger.Info("Updating Gunicorn probes", "deployment", deployName)
+			changed = true
+		}
+		if syncComponentPodAnnotations(&deploy.Spec.Template, bench, "gunicorn") {
+			logger.Info("Updating Gunicorn pod annotations", "deployment", deployName)
+			changed = true
+		}
+		if syncGracefulShutdown(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating Gunicorn graceful shutdown", "deployment", deployName)
+			changed = true
+		}
+		if syncDeploymentStrategy(deploy, bench, "gunicorn") {
+			logger.Info("Updating Gunicorn update strategy", "deployment", deployName)
+			changed = true
+		}
+		// Only update replicas if NOT managed by the HPA (the HPA controls replicas)
+		if replicas := r.getGunicornReplicas(bench); !gunicornAutoscalingEnabled(bench) &&
+			(deploy.Spec.Replicas == nil || *deploy.Spec.Replicas != replicas) {
+			logger.Info("Updating Gunicorn replicas", "deployment", deployName, "replicas", replicas)
+			deploy.Spec.Replicas = &replicas
+			changed = true
+		}
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		if syncOwnedLabels(&deploy.ObjectMeta, &deploy.Spec.Template, extraLabels) {
+			logger.Info("Updating Gunicorn labels", "deployment", deployName)
+			changed = true
+		}
+		if changed {
 			return r.Update(ctx, deploy)
 		}
 		return nil
@@ -95,29 +239,40 @@ func (r *FrappeBenchReconciler) ensureGunicornDeployment(ctx context.Context, be
 	logger.Info("Creating Gunicorn Deployment", "deployment", deployName)
 
 	replicas := r.getGunicornReplicas(bench)
-	image := r.getBenchImage(ctx, bench)
+	if gunicornAutoscalingEnabled(bench) {
+		replicas, _ = gunicornHPAReplicas(bench)
+	}
+	image := r.getComponentImage(ctx, bench, "gunicorn")
 	pvcName := fmt.Sprintf("%s-sites", bench.Name)
 
+	readiness, liveness := componentProbes(bench, "gunicorn")
 	container := resources.NewContainerBuilder("gunicorn", image).
 		WithPort("http", 8000).
-		WithVolumeMountSubPath("sites", "/home/frappe/frappe-bench/sites", "frappe-sites").
+		WithReadinessProbe(readiness).
+		WithLivenessProbe(liveness).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
 		WithResources(r.getGunicornResources(bench)).
 		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
 		WithEnv("USER", "frappe").
 		Build()
 
 	// Apply Pod Config
-	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, r.benchLabels(bench))
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
 
 	deploy, err = resources.NewDeploymentBuilder(deployName, bench.Namespace).
 		WithLabels(extraLabels).
 		WithExtraPodLabels(extraLabels).
+		WithExtraPodLabels(map[string]string{webBackendLabel: "true"}).
 		WithSelector(r.componentLabels(bench, "gunicorn")).
+		WithPodAnnotations(componentPodTemplateAnnotations(bench, "gunicorn")).
 		WithReplicas(replicas).
+		WithStrategy(deploymentStrategy(bench, "gunicorn")).
 		WithNodeSelector(nodeSelector).
 		WithAffinity(affinity).
 		WithTolerations(tolerations).
 		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(componentServiceAccount(bench)).
 		WithContainer(container).
 		WithPVCVolume("sites", pvcName).
 		WithOwner(bench, r.Scheme).
@@ -125,6 +280,9 @@ func (r *FrappeBenchReconciler) ensureGunicornDeployment(ctx context.Context, be
 	if err != nil {
 		return err
 	}
+	syncGracefulShutdown(&deploy.Spec.Template.Spec, bench)
+	syncSharedAssets(&deploy.Spec.Template.Spec, bench)
+	syncExtraVolumes(&deploy.Spec.Template, bench)
 
 	return r.Create(ctx, deploy)
 }
@@ -134,18 +292,51 @@ func (r *FrappeBenchReconciler) ensureNginx(ctx context.Context, bench *vyogotec
 	if err := r.ensureNginxService(ctx, bench); err != nil {
 		return err
 	}
+	if err := r.ensureNginxTLSConfigMap(ctx, bench); err != nil {
+		return err
+	}
+	if err := r.ensureNginxConfigMap(ctx, bench); err != nil {
+		return err
+	}
+	if !componentEnabled(bench, "nginx") {
+		return r.deleteComponentDeployment(ctx, bench, "nginx")
+	}
 	return r.ensureNginxDeployment(ctx, bench)
 }
 
+// nginxServiceTarget returns the pods and port behind `<bench>-nginx`. With nginx disabled
+// the Service forwards to gunicorn, so site Ingresses, Routes and health checks keep working.
+func (r *FrappeBenchReconciler) nginxServiceTarget(bench *vyogotechv1alpha1.FrappeBench) (map[string]string, int32) {
+	if !componentEnabled(bench, "nginx") {
+		return r.componentLabels(bench, "gunicorn"), 8000
+	}
+	return r.componentLabels(bench, "nginx"), 8080
+}
+
 func (r *FrappeBenchReconciler) ensureNginxService(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
 	logger := log.FromContext(ctx)
 
 	svcName := fmt.Sprintf("%s-nginx", bench.Name)
 	svc := &corev1.Service{}
+	selector, targetPort := r.nginxServiceTarget(bench)
+	ports := nginxServicePorts(bench, targetPort)
 
 	err := r.Get(ctx, types.NamespacedName{Name: svcName, Namespace: bench.Namespace}, svc)
 	if err == nil {
-		return nil
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		labelsChanged := syncOwnedLabels(&svc.ObjectMeta, nil, extraLabels)
+		// Follow spec.components.nginx and spec.nginxTLS being toggled
+		if maps.Equal(svc.Spec.Selector, selector) && servicePortsMatch(svc.Spec.Ports, ports) {
+			if !labelsChanged {
+				return nil
+			}
+			logger.Info("Updating NGINX Service labels", "service", svcName)
+			return r.Update(ctx, svc)
+		}
+		logger.Info("Retargeting NGINX Service", "service", svcName, "targetPort", targetPort)
+		svc.Spec.Selector = selector
+		svc.Spec.Ports = ports
+		return r.Update(ctx, svc)
 	}
 
 	if !errors.IsNotFound(err) {
@@ -155,17 +346,17 @@ func (r *FrappeBenchReconciler) ensureNginxService(ctx context.Context, bench *v
 	logger.Info("Creating NGINX Service", "service", svcName)
 
 	// Apply Pod Config (Labels only for Service)
-	_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, r.benchLabels(bench))
+	_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
 
 	svc, err = resources.NewServiceBuilder(svcName, bench.Namespace).
 		WithLabels(extraLabels).
-		WithSelector(r.componentLabels(bench, "nginx")).
-		WithPort("http", 8080, 8080).
+		WithSelector(selector).
 		WithOwner(bench, r.Scheme).
 		Build()
 	if err != nil {
 		return err
 	}
+	svc.Spec.Ports = ports
 
 	return r.Create(ctx, svc)
 }
@@ -180,9 +371,75 @@ func (r *FrappeBenchReconciler) ensureNginxDeployment(ctx context.Context, bench
 	if err == nil {
 		// Update existing deployment if image has changed
 		image := r.getBenchImage(ctx, bench)
-		if deploy.Spec.Template.Spec.Containers[0].Image != image {
-			logger.Info("Updating NGINX Deployment image", "deployment", deployName, "oldImage", deploy.Spec.Template.Spec.Containers[0].Image, "newImage", image)
-			deploy.Spec.Template.Spec.Containers[0].Image = image
+		changed := false
+		container := &deploy.Spec.Template.Spec.Containers[0]
+		if container.Image != image {
+			logger.Info("Updating NGINX Deployment image", "deployment", deployName, "oldImage", container.Image, "newImage", image)
+			container.Image = image
+			changed = true
+		}
+		// Keep upstreams in sync with the combined/separate service layout
+		upstreams := map[string]string{
+			"BACKEND":  r.getGunicornUpstream(bench),
+			"SOCKETIO": r.getSocketIOUpstream(bench),
+		}
+		for i := range container.Env {
+			if want, ok := upstreams[container.Env[i].Name]; ok && container.Env[i].Value != want {
+				logger.Info("Updating NGINX upstream", "deployment", deployName, "env", container.Env[i].Name, "value", want)
+				container.Env[i].Value = want
+				changed = true
+			}
+		}
+		if syncComponentProbes(container, bench, "nginx") {
+			logger.Info("Updating NGINX probes", "deployment", deployName)
+			changed = true
+		}
+		if syncPodPlacement(&deploy.Spec.Template.Spec, bench.Spec.PodConfig) {
+			logger.Info("Updating pod placement", "deployment", deployName)
+			changed = true
+		}
+		if syncImagePullSecrets(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating image pull secrets", "deployment", deployName)
+			changed = true
+		}
+		if syncServiceAccount(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating service account", "deployment", deployName)
+			changed = true
+		}
+		if syncSharedAssets(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating shared assets volume", "deployment", deployName)
+			changed = true
+		}
+		if syncNginxTLS(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating NGINX TLS listener", "deployment", deployName)
+			changed = true
+		}
+		configChanged, err := syncNginxConfig(&deploy.Spec.Template, bench)
+		if err != nil {
+			return err
+		}
+		if configChanged {
+			logger.Info("Updating NGINX config", "deployment", deployName)
+			changed = true
+		}
+		if syncComponentPodAnnotations(&deploy.Spec.Template, bench, "nginx") {
+			logger.Info("Updating NGINX pod annotations", "deployment", deployName)
+			changed = true
+		}
+		if syncGracefulShutdown(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating NGINX graceful shutdown", "deployment", deployName)
+			changed = true
+		}
+		if syncDeploymentStrategy(deploy, bench, "nginx") {
+			logger.Info("Updating NGINX update strategy", "deployment", deployName)
+			changed = true
+		}
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		if syncOwnedLabels(&deploy.ObjectMeta, &deploy.Spec.Template, extraLabels) {
+			logger.Info("Updating NGINX labels", "deployment", deployName)
+			changed = true
+		}
+		if changed {
 			return r.Update(ctx, deploy)
 		}
 		return nil
@@ -197,34 +454,40 @@ func (r *FrappeBenchReconciler) ensureNginxDeployment(ctx context.Context, bench
 	replicas := r.getNginxReplicas(bench)
 	image := r.getBenchImage(ctx, bench)
 	pvcName := fmt.Sprintf("%s-sites", bench.Name)
-	gunicornSvc := fmt.Sprintf("%s-gunicorn", bench.Name)
 
+	readiness, liveness := componentProbes(bench, "nginx")
 	container := resources.NewContainerBuilder("nginx", image).
 		WithArgs("nginx-entrypoint.sh").
 		WithPort("http", 8080).
-		WithEnv("BACKEND", fmt.Sprintf("%s:8000", gunicornSvc)).
-		WithEnv("SOCKETIO", fmt.Sprintf("%s-socketio:9000", bench.Name)).
+		WithReadinessProbe(readiness).
+		WithLivenessProbe(liveness).
+		WithEnv("BACKEND", r.getGunicornUpstream(bench)).
+		WithEnv("SOCKETIO", r.getSocketIOUpstream(bench)).
 		WithEnv("UPSTREAM_REAL_IP_ADDRESS", "127.0.0.1").
 		WithEnv("UPSTREAM_REAL_IP_RECURSIVE", "off").
 		WithEnv("UPSTREAM_REAL_IP_HEADER", "X-Forwarded-For").
 		WithEnv("FRAPPE_SITE_NAME_HEADER", "$host").
-		WithVolumeMountSubPath("sites", "/home/frappe/frappe-bench/sites", "frappe-sites").
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
 		WithResources(r.getNginxResources(bench)).
 		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
 		Build()
 
 	// Apply Pod Config
-	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, r.benchLabels(bench))
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
 
 	deploy, err = resources.NewDeploymentBuilder(deployName, bench.Namespace).
 		WithLabels(extraLabels).
 		WithExtraPodLabels(extraLabels).
 		WithSelector(r.componentLabels(bench, "nginx")).
+		WithPodAnnotations(componentPodTemplateAnnotations(bench, "nginx")).
 		WithReplicas(replicas).
+		WithStrategy(deploymentStrategy(bench, "nginx")).
 		WithNodeSelector(nodeSelector).
 		WithAffinity(affinity).
 		WithTolerations(tolerations).
 		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(componentServiceAccount(bench)).
 		WithContainer(container).
 		WithPVCVolume("sites", pvcName).
 		WithOwner(bench, r.Scheme).
@@ -232,14 +495,30 @@ func (r *FrappeBenchReconciler) ensureNginxDeployment(ctx context.Context, bench
 	if err != nil {
 		return err
 	}
+	syncNginxTLS(&deploy.Spec.Template.Spec, bench)
+	syncGracefulShutdown(&deploy.Spec.Template.Spec, bench)
+	syncSharedAssets(&deploy.Spec.Template.Spec, bench)
+	if _, err := syncNginxConfig(&deploy.Spec.Template, bench); err != nil {
+		return err
+	}
 
 	return r.Create(ctx, deploy)
 }
 
 // ensureSocketIO ensures the Socket.IO Deployment and Service exist
 func (r *FrappeBenchReconciler) ensureSocketIO(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
-	if err := r.ensureSocketIOService(ctx, bench); err != nil {
-		return err
+	// The combined web Service is managed by ensureGunicorn
+	if !bench.Spec.CombinedWebService {
+		if err := r.ensureSocketIOService(ctx, bench); err != nil {
+			return err
+		}
+		if err := r.deleteWebServiceIfExists(ctx, bench); err != nil {
+			return err
+		}
+	}
+	// The Service stays so nginx can still resolve its /socket.io upstream
+	if !componentEnabled(bench, "socketio") {
+		return r.deleteComponentDeployment(ctx, bench, "socketio")
 	}
 	return r.ensureSocketIODeployment(ctx, bench)
 }
@@ -252,7 +531,12 @@ func (r *FrappeBenchReconciler) ensureSocketIOService(ctx context.Context, bench
 
 	err := r.Get(ctx, types.NamespacedName{Name: svcName, Namespace: bench.Namespace}, svc)
 	if err == nil {
-		return nil
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		if !syncOwnedLabels(&svc.ObjectMeta, nil, extraLabels) {
+			return nil
+		}
+		logger.Info("Updating Socket.IO Service labels", "service", svcName)
+		return r.Update(ctx, svc)
 	}
 
 	if !errors.IsNotFound(err) {
@@ -262,7 +546,7 @@ func (r *FrappeBenchReconciler) ensureSocketIOService(ctx context.Context, bench
 	logger.Info("Creating Socket.IO Service", "service", svcName)
 
 	// Apply Pod Config (Labels only for Service)
-	_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, r.benchLabels(bench))
+	_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
 
 	svc, err = resources.NewServiceBuilder(svcName, bench.Namespace).
 		WithLabels(extraLabels).
@@ -286,10 +570,55 @@ func (r *FrappeBenchReconciler) ensureSocketIODeployment(ctx context.Context, be
 	err := r.Get(ctx, types.NamespacedName{Name: deployName, Namespace: bench.Namespace}, deploy)
 	if err == nil {
 		// Update existing deployment if image has changed
-		image := r.getBenchImage(ctx, bench)
+		image := r.getComponentImage(ctx, bench, "socketio")
+		changed := false
 		if deploy.Spec.Template.Spec.Containers[0].Image != image {
 			logger.Info("Updating Socket.IO Deployment image", "deployment", deployName, "oldImage", deploy.Spec.Template.Spec.Containers[0].Image, "newImage", image)
 			deploy.Spec.Template.Spec.Containers[0].Image = image
+			changed = true
+		}
+		if syncComponentProbes(&deploy.Spec.Template.Spec.Containers[0], bench, "socketio") {
+			logger.Info("Updating Socket.IO probes", "deployment", deployName)
+			changed = true
+		}
+		// Pods created before combinedWebService was enabled need the web backend label
+		if bench.Spec.CombinedWebService && deploy.Spec.Template.Labels[webBackendLabel] != "true" {
+			if deploy.Spec.Template.Labels == nil {
+				deploy.Spec.Template.Labels = make(map[string]string)
+			}
+			deploy.Spec.Template.Labels[webBackendLabel] = "true"
+			changed = true
+		}
+		if syncPodPlacement(&deploy.Spec.Template.Spec, bench.Spec.PodConfig) {
+			logger.Info("Updating pod placement", "deployment", deployName)
+			changed = true
+		}
+		if syncImagePullSecrets(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating image pull secrets", "deployment", deployName)
+			changed = true
+		}
+		if syncServiceAccount(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating service account", "deployment", deployName)
+			changed = true
+		}
+		if syncSharedAssets(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating shared assets volume", "deployment", deployName)
+			changed = true
+		}
+		if syncExtraVolumes(&deploy.Spec.Template, bench) {
+			logger.Info("Updating extra volumes", "deployment", deployName)
+			changed = true
+		}
+		if syncComponentPodAnnotations(&deploy.Spec.Template, bench, "socketio") {
+			logger.Info("Updating Socket.IO pod annotations", "deployment", deployName)
+			changed = true
+		}
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		if syncOwnedLabels(&deploy.ObjectMeta, &deploy.Spec.Template, extraLabels) {
+			logger.Info("Updating Socket.IO labels", "deployment", deployName)
+			changed = true
+		}
+		if changed {
 			return r.Update(ctx, deploy)
 		}
 		return nil
@@ -302,30 +631,37 @@ func (r *FrappeBenchReconciler) ensureSocketIODeployment(ctx context.Context, be
 	logger.Info("Creating Socket.IO Deployment", "deployment", deployName)
 
 	replicas := r.getSocketIOReplicas(bench)
-	image := r.getBenchImage(ctx, bench)
+	image := r.getComponentImage(ctx, bench, "socketio")
 	pvcName := fmt.Sprintf("%s-sites", bench.Name)
 
+	readiness, liveness := componentProbes(bench, "socketio")
 	container := resources.NewContainerBuilder("socketio", image).
 		WithArgs("node", "/home/frappe/frappe-bench/apps/frappe/socketio.js").
 		WithPort("socketio", 9000).
-		WithVolumeMountSubPath("sites", "/home/frappe/frappe-bench/sites", "frappe-sites").
+		WithReadinessProbe(readiness).
+		WithLivenessProbe(liveness).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
 		WithResources(r.getSocketIOResources(bench)).
 		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
 		WithEnv("USER", "frappe").
 		Build()
 
 	// Apply Pod Config
-	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, r.benchLabels(bench))
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
 
 	deploy, err = resources.NewDeploymentBuilder(deployName, bench.Namespace).
 		WithLabels(extraLabels).
 		WithExtraPodLabels(extraLabels).
+		WithExtraPodLabels(map[string]string{webBackendLabel: "true"}).
 		WithSelector(r.componentLabels(bench, "socketio")).
+		WithPodAnnotations(componentPodTemplateAnnotations(bench, "socketio")).
 		WithReplicas(replicas).
 		WithNodeSelector(nodeSelector).
 		WithAffinity(affinity).
 		WithTolerations(tolerations).
 		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(componentServiceAccount(bench)).
 		WithContainer(container).
 		WithPVCVolume("sites", pvcName).
 		WithOwner(bench, r.Scheme).
@@ -333,12 +669,17 @@ func (r *FrappeBenchReconciler) ensureSocketIODeployment(ctx context.Context, be
 	if err != nil {
 		return err
 	}
+	syncSharedAssets(&deploy.Spec.Template.Spec, bench)
+	syncExtraVolumes(&deploy.Spec.Template, bench)
 
 	return r.Create(ctx, deploy)
 }
 
 // ensureScheduler ensures the Scheduler Deployment exists
 func (r *FrappeBenchReconciler) ensureScheduler(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	if !componentEnabled(bench, "scheduler") {
+		return r.deleteComponentDeployment(ctx, bench, "scheduler")
+	}
 	logger := log.FromContext(ctx)
 
 	deployName := fmt.Sprintf("%s-scheduler", bench.Name)
@@ -348,9 +689,42 @@ func (r *FrappeBenchReconciler) ensureScheduler(ctx context.Context, bench *vyog
 	if err == nil {
 		// Update existing deployment if image has changed
 		image := r.getBenchImage(ctx, bench)
+		changed := false
 		if deploy.Spec.Template.Spec.Containers[0].Image != image {
 			logger.Info("Updating Scheduler Deployment image", "deployment", deployName, "oldImage", deploy.Spec.Template.Spec.Containers[0].Image, "newImage", image)
 			deploy.Spec.Template.Spec.Containers[0].Image = image
+			changed = true
+		}
+		if syncPodPlacement(&deploy.Spec.Template.Spec, bench.Spec.PodConfig) {
+			logger.Info("Updating pod placement", "deployment", deployName)
+			changed = true
+		}
+		if syncImagePullSecrets(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating image pull secrets", "deployment", deployName)
+			changed = true
+		}
+		if syncServiceAccount(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating service account", "deployment", deployName)
+			changed = true
+		}
+		if syncSharedAssets(&deploy.Spec.Template.Spec, bench) {
+			logger.Info("Updating shared assets volume", "deployment", deployName)
+			changed = true
+		}
+		if syncExtraVolumes(&deploy.Spec.Template, bench) {
+			logger.Info("Updating extra volumes", "deployment", deployName)
+			changed = true
+		}
+		if syncComponentPodAnnotations(&deploy.Spec.Template, bench, "scheduler") {
+			logger.Info("Updating Scheduler pod annotations", "deployment", deployName)
+			changed = true
+		}
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		if syncOwnedLabels(&deploy.ObjectMeta, &deploy.Spec.Template, extraLabels) {
+			logger.Info("Updating Scheduler labels", "deployment", deployName)
+			changed = true
+		}
+		if changed {
 			return r.Update(ctx, deploy)
 		}
 		return nil
@@ -368,24 +742,27 @@ func (r *FrappeBenchReconciler) ensureScheduler(ctx context.Context, bench *vyog
 
 	container := resources.NewContainerBuilder("scheduler", image).
 		WithArgs("bench", "schedule").
-		WithVolumeMountSubPath("sites", "/home/frappe/frappe-bench/sites", "frappe-sites").
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
 		WithResources(r.getSchedulerResources(bench)).
 		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
 		WithEnv("USER", "frappe").
 		Build()
 
 	// Apply Pod Config
-	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, r.benchLabels(bench))
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
 
 	deploy, err = resources.NewDeploymentBuilder(deployName, bench.Namespace).
 		WithLabels(extraLabels).
 		WithExtraPodLabels(extraLabels).
 		WithSelector(r.componentLabels(bench, "scheduler")).
+		WithPodAnnotations(componentPodTemplateAnnotations(bench, "scheduler")).
 		WithReplicas(replicas).
 		WithNodeSelector(nodeSelector).
 		WithAffinity(affinity).
 		WithTolerations(tolerations).
 		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(componentServiceAccount(bench)).
 		WithContainer(container).
 		WithPVCVolume("sites", pvcName).
 		WithOwner(bench, r.Scheme).
@@ -393,6 +770,8 @@ func (r *FrappeBenchReconciler) ensureScheduler(ctx context.Context, bench *vyog
 	if err != nil {
 		return err
 	}
+	syncSharedAssets(&deploy.Spec.Template.Spec, bench)
+	syncExtraVolumes(&deploy.Spec.Template, bench)
 
 	return r.Create(ctx, deploy)
 }
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+)
+
+// serviceAddress is the in-cluster host:port of a bench Service
+func serviceAddress(bench *vyogotechv1alpha1.FrappeBench, component string, port int32) string {
+	return fmt.Sprintf("%s-%s.%s.svc:%d", bench.Name, component, bench.Namespace, port)
+}
+
+// upstreamAddress qualifies an in-namespace upstream such as "bench-web:8000" with the
+// bench's namespace
+func upstreamAddress(bench *vyogotechv1alpha1.FrappeBench, upstream string) string {
+	host, port, err := net.SplitHostPort(upstream)
+	if err != nil {
+		return upstream
+	}
+	return net.JoinHostPort(fmt.Sprintf("%s.%s.svc", host, bench.Namespace), port)
+}
+
+// redisAddress returns the host:port of a redis URL, without the password
+func redisAddress(redisURL string) string {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// benchEndpoints lists the addresses of the bench's services for status.endpoints.
+// gunicorn and socketio follow the Services nginx proxies to, so with combinedWebService
+// both point at <bench>-web. <bench>-nginx always exists, forwarding to gunicorn when nginx
+// is disabled; a disabled socketio has no pods behind its Service and is left out. With
+// redisConfig.connectionSecretRef, redis points at the resolved external URLs and is left
+// out while they can't be resolved.
+func (r *FrappeBenchReconciler) benchEndpoints(bench *vyogotechv1alpha1.FrappeBench, redisCache, redisQueue string) map[string]string {
+	endpoints := map[string]string{
+		"gunicorn": upstreamAddress(bench, r.getGunicornUpstream(bench)),
+		"nginx":    serviceAddress(bench, "nginx", 8080),
+	}
+	if componentEnabled(bench, "socketio") {
+		endpoints["socketio"] = upstreamAddress(bench, r.getSocketIOUpstream(bench))
+	}
+	if !isExternalRedis(bench) {
+		endpoints["redis-cache"] = serviceAddress(bench, "redis-cache", 6379)
+		endpoints["redis-queue"] = serviceAddress(bench, "redis-queue", 6379)
+		return endpoints
+	}
+	if addr := redisAddress(redisCache); addr != "" {
+		endpoints["redis-cache"] = addr
+	}
+	if addr := redisAddress(redisQueue); addr != "" {
+		endpoints["redis-queue"] = addr
+	}
+	return endpoints
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"maps"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBenchEndpoints(t *testing.T) {
+	bench := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "prod"}}
+	r := &FrappeBenchReconciler{}
+	want := map[string]string{
+		"gunicorn":    "bench-gunicorn.prod.svc:8000",
+		"nginx":       "bench-nginx.prod.svc:8080",
+		"socketio":    "bench-socketio.prod.svc:9000",
+		"redis-cache": "bench-redis-cache.prod.svc:6379",
+		"redis-queue": "bench-redis-queue.prod.svc:6379",
+	}
+	if got := r.benchEndpoints(bench, "", ""); !maps.Equal(got, want) {
+		t.Errorf("benchEndpoints() = %v, want %v", got, want)
+	}
+
+	// A combined web Service serves both gunicorn and socketio
+	bench.Spec.CombinedWebService = true
+	got := r.benchEndpoints(bench, "", "")
+	if got["gunicorn"] != "bench-web.prod.svc:8000" || got["socketio"] != "bench-web.prod.svc:9000" {
+		t.Errorf("expected the combined web Service, got %v", got)
+	}
+	bench.Spec.CombinedWebService = false
+
+	// The nginx Service stays when nginx is disabled; socketio has nothing behind it
+	disabled := false
+	bench.Spec.Components = &vyogotechv1alpha1.BenchComponents{
+		Nginx:    &vyogotechv1alpha1.ComponentToggle{Enabled: &disabled},
+		SocketIO: &vyogotechv1alpha1.ComponentToggle{Enabled: &disabled},
+	}
+	got = r.benchEndpoints(bench, "", "")
+	if got["nginx"] != "bench-nginx.prod.svc:8080" {
+		t.Errorf("expected the nginx Service to be listed, got %v", got)
+	}
+	if _, ok := got["socketio"]; ok {
+		t.Errorf("expected no socketio endpoint, got %v", got)
+	}
+
+	// External redis shows its host:port without the password
+	bench.Spec.RedisConfig = &vyogotechv1alpha1.RedisConfig{
+		ConnectionSecretRef: &corev1.SecretReference{Name: "redis"},
+	}
+	got = r.benchEndpoints(bench, "redis://:secret@redis.example.com:6380", "redis://:secret@redis.example.com:6380")
+	if got["redis-cache"] != "redis.example.com:6380" || got["redis-queue"] != "redis.example.com:6380" {
+		t.Errorf("expected the external redis address, got %v", got)
+	}
+	got = r.benchEndpoints(bench, "", "")
+	if _, ok := got["redis-cache"]; ok {
+		t.Errorf("expected no redis endpoint while the external redis is unresolved, got %v", got)
+	}
+}
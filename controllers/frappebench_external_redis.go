@@ -0,0 +1,202 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Keys read from redisConfig.connectionSecretRef; password is optional
+const (
+	redisSecretHostKey     = "host"
+	redisSecretPortKey     = "port"
+	redisSecretPasswordKey = "password"
+)
+
+// Keys of the <bench>-redis-urls Secret, read by bench jobs as REDIS_CACHE and REDIS_QUEUE.
+// The Secret also holds the password alone under redisPasswordKey for KEDA.
+const (
+	redisCacheURLKey = "redis_cache"
+	redisQueueURLKey = "redis_queue"
+)
+
+// redisConfigInvalidRequeue is how often a bench with an unusable redis connection Secret
+// checks it again
+const redisConfigInvalidRequeue = 30 * time.Second
+
+// errRedisConfigInvalid marks a connection Secret that exists but can't be used
+var errRedisConfigInvalid = stderrors.New("invalid external redis configuration")
+
+// isRedisConfigInvalid reports whether err comes from an unusable connection Secret
+func isRedisConfigInvalid(err error) bool {
+	return stderrors.Is(err, errRedisConfigInvalid)
+}
+
+// isExternalRedis reports whether the bench uses an existing Redis instead of its own
+// StatefulSets
+func isExternalRedis(bench *vyogotechv1alpha1.FrappeBench) bool {
+	return bench.Spec.RedisConfig != nil && bench.Spec.RedisConfig.ConnectionSecretRef != nil
+}
+
+// redisURLSecretName is the Secret holding the resolved external redis URLs
+func redisURLSecretName(bench *vyogotechv1alpha1.FrappeBench) string {
+	return fmt.Sprintf("%s-redis-urls", bench.Name)
+}
+
+// resolveRedisURLs returns the redis_cache and redis_queue URLs for the bench: its own
+// services, or the endpoint in redisConfig.connectionSecretRef (shared by cache and
+// queue, password included). The Secret is always read in the bench's namespace: its
+// contents are copied into <bench>-redis-urls, so honouring another namespace would let
+// a bench author read any Secret with host and port keys.
+func resolveRedisURLs(ctx context.Context, c client.Client, bench *vyogotechv1alpha1.FrappeBench) (string, string, error) {
+	if !isExternalRedis(bench) {
+		cache, queue := desiredRedisConfig(bench)
+		return cache, queue, nil
+	}
+
+	ref := bench.Spec.RedisConfig.ConnectionSecretRef
+	namespace := bench.Namespace
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret); err != nil {
+		return "", "", fmt.Errorf("failed to get redis connection secret %s/%s: %w", namespace, ref.Name, err)
+	}
+
+	host := string(secret.Data[redisSecretHostKey])
+	port := string(secret.Data[redisSecretPortKey])
+	if host == "" || port == "" {
+		return "", "", fmt.Errorf("%w: secret %s/%s must set %q and %q", errRedisConfigInvalid, namespace, ref.Name, redisSecretHostKey, redisSecretPortKey)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", "", fmt.Errorf("%w: secret %s/%s has invalid port %q", errRedisConfigInvalid, namespace, ref.Name, port)
+	}
+
+	u := url.URL{Scheme: "redis", Host: net.JoinHostPort(host, port)}
+	if password := string(secret.Data[redisSecretPasswordKey]); password != "" {
+		u.User = url.UserPassword("", password)
+	}
+	return u.String(), u.String(), nil
+}
+
+// redisConfigFingerprint identifies a redis_cache/redis_queue pair without exposing
+// passwords, for status and annotations
+func redisConfigFingerprint(redisCache, redisQueue string) string {
+	fingerprint := redactRedisURL(redisCache) + "," + redactRedisURL(redisQueue)
+	if fingerprint != redisCache+","+redisQueue {
+		sum := sha256.Sum256([]byte(redisCache + "," + redisQueue))
+		fingerprint += ",sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return fingerprint
+}
+
+// redactRedisURL masks the password of a redis URL
+func redactRedisURL(redisURL string) string {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return redisURL
+	}
+	return u.Redacted()
+}
+
+// ensureExternalRedis validates redisConfig.connectionSecretRef and writes the resolved
+// URLs to the <bench>-redis-urls Secret, which bench jobs read instead of the defaults
+func (r *FrappeBenchReconciler) ensureExternalRedis(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	if !isExternalRedis(bench) {
+		return nil
+	}
+
+	redisCache, redisQueue, err := resolveRedisURLs(ctx, r.Client, bench)
+	if err != nil {
+		return err
+	}
+	data := map[string][]byte{
+		redisCacheURLKey: []byte(redisCache),
+		redisQueueURLKey: []byte(redisQueue),
+	}
+	if password := redisURLPassword(redisQueue); password != "" {
+		data[redisPasswordKey] = []byte(password)
+	}
+
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: redisURLSecretName(bench), Namespace: bench.Namespace}, secret)
+	if err == nil {
+		if reflect.DeepEqual(secret.Data, data) {
+			return nil
+		}
+		secret.Data = data
+		return r.Update(ctx, secret)
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	log.FromContext(ctx).Info("Creating external redis URL secret", "secret", redisURLSecretName(bench), "redisCache", redactRedisURL(redisCache))
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisURLSecretName(bench),
+			Namespace: bench.Namespace,
+			Labels:    r.benchLabels(bench),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	if err := controllerutil.SetControllerReference(bench, secret, r.Scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, secret)
+}
+
+// redisURLEnv passes the external redis URLs to a bench job container; nil for benches
+// running their own redis
+func redisURLEnv(bench *vyogotechv1alpha1.FrappeBench) []corev1.EnvVar {
+	if !isExternalRedis(bench) {
+		return nil
+	}
+	var env []corev1.EnvVar
+	for _, v := range []struct{ name, key string }{
+		{"REDIS_CACHE", redisCacheURLKey},
+		{"REDIS_QUEUE", redisQueueURLKey},
+	} {
+		env = append(env, corev1.EnvVar{
+			Name: v.name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: redisURLSecretName(bench)},
+					Key:                  v.key,
+				},
+			},
+		})
+	}
+	return env
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newExternalRedisTestObjects(data map[string][]byte) (*vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench, *corev1.Secret) {
+	site, bench := newInitJobTestObjects()
+	bench.Spec.RedisConfig = &vyogotechv1alpha1.RedisConfig{
+		Type:                "redis",
+		ConnectionSecretRef: &corev1.SecretReference{Name: "elasticache"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "elasticache", Namespace: "default"},
+		Data:       data,
+	}
+	return site, bench, secret
+}
+
+func TestEnsureExternalRedis_invalidSecret(t *testing.T) {
+	_, bench, secret := newExternalRedisTestObjects(map[string][]byte{"host": []byte("redis.example.com")})
+	siteReconciler, _ := newInitJobTestReconciler(bench, secret)
+	r := &FrappeBenchReconciler{Client: siteReconciler.Client, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+
+	err := r.ensureExternalRedis(context.Background(), bench)
+	if !isRedisConfigInvalid(err) || !strings.Contains(err.Error(), `"port"`) {
+		t.Fatalf("expected an invalid redis config error naming the port key, got %v", err)
+	}
+}
+
+func TestResolveRedisURLs_ignoresSecretNamespace(t *testing.T) {
+	_, bench, secret := newExternalRedisTestObjects(map[string][]byte{"host": []byte("redis.example.com"), "port": []byte("6379")})
+	bench.Spec.RedisConfig.ConnectionSecretRef.Namespace = "other-tenant"
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "elasticache", Namespace: "other-tenant"},
+		Data:       map[string][]byte{"host": []byte("foreign.example.com"), "port": []byte("6379"), "password": []byte("theirs")},
+	}
+	siteReconciler, _ := newInitJobTestReconciler(bench, secret, foreign)
+
+	cache, _, err := resolveRedisURLs(context.Background(), siteReconciler.Client, bench)
+	if err != nil {
+		t.Fatalf("resolveRedisURLs: %v", err)
+	}
+	if cache != "redis://redis.example.com:6379" {
+		t.Errorf("expected the Secret in the bench's namespace to be used, got %q", cache)
+	}
+}
+
+func TestExternalRedis(t *testing.T) {
+	site, bench, secret := newExternalRedisTestObjects(map[string][]byte{
+		"host":     []byte("redis.example.com"),
+		"port":     []byte("6380"),
+		"password": []byte("s3cret"),
+	})
+	siteReconciler, c := newInitJobTestReconciler(site, bench, secret)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	wantURL := "redis://:s3cret@redis.example.com:6380"
+
+	if err := r.ensureExternalRedis(ctx, bench); err != nil {
+		t.Fatalf("ensureExternalRedis: %v", err)
+	}
+	urls := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-urls", Namespace: "default"}, urls); err != nil {
+		t.Fatalf("Get redis URL Secret: %v", err)
+	}
+	if string(urls.Data[redisCacheURLKey]) != wantURL || string(urls.Data[redisQueueURLKey]) != wantURL {
+		t.Errorf("expected both URLs to be %s, got %v", wantURL, urls.Data)
+	}
+
+	// No redis of our own
+	if err := r.ensureRedis(ctx, bench); err != nil {
+		t.Fatalf("ensureRedis: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-cache", Namespace: "default"}, &appsv1.StatefulSet{}); err == nil {
+		t.Error("expected no redis-cache StatefulSet with an external redis")
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-queue", Namespace: "default"}, &corev1.Service{}); err == nil {
+		t.Error("expected no redis-queue Service with an external redis")
+	}
+
+	// The config sync job reads the URLs from the Secret and the password stays out of the spec
+	if err := r.ensureCommonSiteConfigSynced(ctx, bench); err != nil {
+		t.Fatalf("ensureCommonSiteConfigSynced: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-config-sync", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get config sync Job: %v", err)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if strings.Contains(strings.Join(container.Args, " "), "s3cret") || strings.Contains(job.Annotations[redisConfigAnnotation], "s3cret") {
+		t.Error("the redis password must not appear in the config sync job")
+	}
+	var fromSecret bool
+	for _, env := range container.Env {
+		if env.Name == "REDIS_CACHE" && env.ValueFrom != nil && env.ValueFrom.SecretKeyRef.Name == "bench-redis-urls" {
+			fromSecret = true
+		}
+	}
+	if !fromSecret {
+		t.Errorf("expected REDIS_CACHE from the bench-redis-urls Secret, got %+v", container.Env)
+	}
+
+	// Sites get the external URLs in their init secret
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	if _, err := siteReconciler.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	initSecret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init-secrets", Namespace: "default"}, initSecret); err != nil {
+		t.Fatalf("Get init Secret: %v", err)
+	}
+	if string(initSecret.Data["redis_cache"]) != wantURL || string(initSecret.Data["redis_queue"]) != wantURL {
+		t.Errorf("expected the init secret to carry %s, got %v", wantURL, initSecret.Data)
+	}
+}
+
+func TestEnsureScaledObject_redisAuthentication(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantAuth bool
+	}{
+		{name: "password", password: "s3cret", wantAuth: true},
+		{name: "no password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string][]byte{"host": []byte("redis.example.com"), "port": []byte("6380")}
+			if tt.password != "" {
+				data["password"] = []byte(tt.password)
+			}
+			_, bench, secret := newExternalRedisTestObjects(data)
+			scheme := runtime.NewScheme()
+			utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+			utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+			for _, kind := range []string{"ScaledObject", "TriggerAuthentication"} {
+				gvk := schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: kind}
+				scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+				scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(kind+"List"), &unstructured.UnstructuredList{})
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, secret).Build()
+			r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+			ctx := context.Background()
+
+			if err := r.ensureExternalRedis(ctx, bench); err != nil {
+				t.Fatalf("ensureExternalRedis: %v", err)
+			}
+			if err := r.ensureScaledObject(ctx, bench, "short", "short", r.fillAutoscalingDefaults(nil, "short")); err != nil {
+				t.Fatalf("ensureScaledObject: %v", err)
+			}
+
+			scaledObject := &unstructured.Unstructured{}
+			scaledObject.SetGroupVersionKind(schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: "ScaledObject"})
+			if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-short", Namespace: "default"}, scaledObject); err != nil {
+				t.Fatalf("Get ScaledObject: %v", err)
+			}
+			triggers, _, _ := unstructured.NestedSlice(scaledObject.Object, "spec", "triggers")
+			trigger := triggers[0].(map[string]interface{})
+			if address, _, _ := unstructured.NestedString(trigger, "metadata", "address"); address != "redis.example.com:6380" {
+				t.Errorf("expected the external redis address, got %q", address)
+			}
+			authRef, found, _ := unstructured.NestedString(trigger, "authenticationRef", "name")
+			auth := &unstructured.Unstructured{}
+			auth.SetGroupVersionKind(triggerAuthenticationGVK)
+			authErr := c.Get(ctx, types.NamespacedName{Name: "bench-redis-auth", Namespace: "default"}, auth)
+			if !tt.wantAuth {
+				if found {
+					t.Errorf("expected no authenticationRef without a password, got %q", authRef)
+				}
+				if authErr == nil {
+					t.Error("expected no TriggerAuthentication without a password")
+				}
+				return
+			}
+			if authRef != "bench-redis-auth" {
+				t.Errorf("expected authenticationRef bench-redis-auth, got %q", authRef)
+			}
+			if authErr != nil {
+				t.Fatalf("Get TriggerAuthentication: %v", authErr)
+			}
+			refs, _, _ := unstructured.NestedSlice(auth.Object, "spec", "secretTargetRef")
+			ref := refs[0].(map[string]interface{})
+			if ref["parameter"] != "password" || ref["name"] != "bench-redis-urls" || ref["key"] != redisPasswordKey {
+				t.Errorf("unexpected secretTargetRef %v", ref)
+			}
+			urls := &corev1.Secret{}
+			if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-urls", Namespace: "default"}, urls); err != nil {
+				t.Fatalf("Get redis URL Secret: %v", err)
+			}
+			if string(urls.Data[redisPasswordKey]) != tt.password {
+				t.Errorf("expected the password under %s, got %v", redisPasswordKey, urls.Data)
+			}
+		})
+	}
+}
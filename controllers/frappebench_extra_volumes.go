@@ -0,0 +1,93 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// extraVolumesAnnotation lists the spec.extraVolumes a pod template carries, so they
+	// can be removed again once dropped from the spec
+	extraVolumesAnnotation = "frappe.tech/extra-volumes"
+	// extraVolumesHashAnnotation fingerprints the extra volumes and mounts last applied;
+	// the API server defaults volume fields, so the volumes can't be compared directly
+	extraVolumesHashAnnotation = "frappe.tech/extra-volumes-hash"
+)
+
+// extraVolumesHash fingerprints spec.extraVolumes and spec.extraVolumeMounts, empty when
+// the bench has neither
+func extraVolumesHash(bench *vyogotechv1alpha1.FrappeBench) string {
+	if len(bench.Spec.ExtraVolumes) == 0 && len(bench.Spec.ExtraVolumeMounts) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(struct {
+		Volumes []corev1.Volume      `json:"volumes"`
+		Mounts  []corev1.VolumeMount `json:"mounts"`
+	}{bench.Spec.ExtraVolumes, bench.Spec.ExtraVolumeMounts})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// syncExtraVolumes adds spec.extraVolumes to a bench pod template and spec.extraVolumeMounts
+// to its main container, replacing the ones applied before, and reports whether anything
+// changed
+func syncExtraVolumes(template *corev1.PodTemplateSpec, bench *vyogotechv1alpha1.FrappeBench) bool {
+	if len(template.Spec.Containers) == 0 {
+		return false
+	}
+	hash := extraVolumesHash(bench)
+	if template.Annotations[extraVolumesHashAnnotation] == hash {
+		return false
+	}
+
+	previous := strings.Split(template.Annotations[extraVolumesAnnotation], ",")
+	container := &template.Spec.Containers[0]
+	template.Spec.Volumes = slices.DeleteFunc(template.Spec.Volumes, func(v corev1.Volume) bool {
+		return slices.Contains(previous, v.Name)
+	})
+	container.VolumeMounts = slices.DeleteFunc(container.VolumeMounts, func(m corev1.VolumeMount) bool {
+		return slices.Contains(previous, m.Name)
+	})
+
+	if hash == "" {
+		delete(template.Annotations, extraVolumesAnnotation)
+		delete(template.Annotations, extraVolumesHashAnnotation)
+		return true
+	}
+	names := make([]string, 0, len(bench.Spec.ExtraVolumes))
+	for _, v := range bench.Spec.ExtraVolumes {
+		template.Spec.Volumes = append(template.Spec.Volumes, *v.DeepCopy())
+		names = append(names, v.Name)
+	}
+	for _, m := range bench.Spec.ExtraVolumeMounts {
+		container.VolumeMounts = append(container.VolumeMounts, *m.DeepCopy())
+	}
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations[extraVolumesAnnotation] = strings.Join(names, ",")
+	template.Annotations[extraVolumesHashAnnotation] = hash
+	return true
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEnsureGunicornDeployment_extraVolumes(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Spec.ExtraVolumes = []corev1.Volume{{
+		Name: "logs",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "bench-logs"},
+		},
+	}}
+	bench.Spec.ExtraVolumeMounts = []corev1.VolumeMount{{Name: "logs", MountPath: "/home/frappe/frappe-bench/logs"}}
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}
+
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if v := findVolume(deploy.Spec.Template.Spec.Volumes, "logs"); v == nil || v.PersistentVolumeClaim == nil || v.PersistentVolumeClaim.ClaimName != "bench-logs" {
+		t.Errorf("expected the logs PVC volume, got %v", deploy.Spec.Template.Spec.Volumes)
+	}
+	if m := findVolumeMount(deploy.Spec.Template.Spec.Containers[0].VolumeMounts, "logs"); m == nil || m.MountPath != "/home/frappe/frappe-bench/logs" {
+		t.Errorf("expected the logs mount, got %v", deploy.Spec.Template.Spec.Containers[0].VolumeMounts)
+	}
+	assertSitesMount(t, "gunicorn", deploy.Spec.Template.Spec)
+
+	// Dropping the extra volumes from the spec removes them from the deployment again
+	bench.Spec.ExtraVolumes = nil
+	bench.Spec.ExtraVolumeMounts = nil
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if findVolume(deploy.Spec.Template.Spec.Volumes, "logs") != nil || findVolumeMount(deploy.Spec.Template.Spec.Containers[0].VolumeMounts, "logs") != nil {
+		t.Errorf("expected the logs volume to be removed, got %v", deploy.Spec.Template.Spec.Volumes)
+	}
+	if _, ok := deploy.Spec.Template.Annotations[extraVolumesHashAnnotation]; ok {
+		t.Errorf("expected the extra volumes annotation to be removed, got %v", deploy.Spec.Template.Annotations)
+	}
+	assertSitesMount(t, "gunicorn", deploy.Spec.Template.Spec)
+}
+
+func findVolume(volumes []corev1.Volume, name string) *corev1.Volume {
+	for i := range volumes {
+		if volumes[i].Name == name {
+			return &volumes[i]
+		}
+	}
+	return nil
+}
+
+func findVolumeMount(mounts []corev1.VolumeMount, name string) *corev1.VolumeMount {
+	for i := range mounts {
+		if mounts[i].Name == name {
+			return &mounts[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"strconv"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Defaults of spec.gracefulShutdown. The grace period matches the Kubernetes default;
+// the sleep covers the usual delay before endpoint removal reaches kube-proxy and
+// ingress controllers.
+const (
+	defaultShutdownGracePeriodSeconds int64 = 30
+	defaultPreStopSleepSeconds        int64 = 5
+)
+
+// webShutdown returns the preStop hook and grace period of the gunicorn and nginx pods
+func webShutdown(bench *vyogotechv1alpha1.FrappeBench) (*corev1.Lifecycle, *int64) {
+	seconds, sleep := defaultShutdownGracePeriodSeconds, defaultPreStopSleepSeconds
+	if gs := bench.Spec.GracefulShutdown; gs != nil {
+		if gs.Seconds != nil {
+			seconds = *gs.Seconds
+		}
+		if gs.PreStopSleepSeconds != nil {
+			sleep = *gs.PreStopSleepSeconds
+		}
+	}
+	if sleep == 0 {
+		return nil, &seconds
+	}
+	return &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: []string{"sleep", strconv.FormatInt(sleep, 10)}},
+		},
+	}, &seconds
+}
+
+// syncGracefulShutdown sets the preStop hook and grace period of a gunicorn or nginx pod
+// from spec.gracefulShutdown, reporting whether anything changed
+func syncGracefulShutdown(podSpec *corev1.PodSpec, bench *vyogotechv1alpha1.FrappeBench) bool {
+	if len(podSpec.Containers) == 0 {
+		return false
+	}
+	lifecycle, gracePeriod := webShutdown(bench)
+	container := &podSpec.Containers[0]
+	if reflect.DeepEqual(container.Lifecycle, lifecycle) && reflect.DeepEqual(podSpec.TerminationGracePeriodSeconds, gracePeriod) {
+		return false
+	}
+	container.Lifecycle = lifecycle
+	podSpec.TerminationGracePeriodSeconds = gracePeriod
+	return true
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	deployments := map[string]func() error{
+		"bench-gunicorn": func() error { return r.ensureGunicornDeployment(ctx, bench) },
+		"bench-nginx":    func() error { return r.ensureNginxDeployment(ctx, bench) },
+	}
+	check := func(name string, wantSleep string, wantGrace int64) {
+		t.Helper()
+		deploy := &appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, deploy); err != nil {
+			t.Fatalf("Get %s: %v", name, err)
+		}
+		podSpec := deploy.Spec.Template.Spec
+		if podSpec.TerminationGracePeriodSeconds == nil || *podSpec.TerminationGracePeriodSeconds != wantGrace {
+			t.Errorf("%s: expected grace period %d, got %v", name, wantGrace, podSpec.TerminationGracePeriodSeconds)
+		}
+		lifecycle := podSpec.Containers[0].Lifecycle
+		if wantSleep == "" {
+			if lifecycle != nil {
+				t.Errorf("%s: expected no preStop hook, got %+v", name, lifecycle)
+			}
+			return
+		}
+		if lifecycle == nil || lifecycle.PreStop == nil || lifecycle.PreStop.Exec == nil ||
+			!slices.Equal(lifecycle.PreStop.Exec.Command, []string{"sleep", wantSleep}) {
+			t.Errorf("%s: expected preStop sleep %s, got %+v", name, wantSleep, lifecycle)
+		}
+	}
+
+	// New deployments get the default drain
+	for name, ensure := range deployments {
+		if err := ensure(); err != nil {
+			t.Fatalf("ensure %s: %v", name, err)
+		}
+		check(name, "5", 30)
+	}
+
+	// Changing spec.gracefulShutdown updates the existing deployments
+	seconds, sleep := int64(60), int64(15)
+	bench.Spec.GracefulShutdown = &vyogotechv1alpha1.GracefulShutdownConfig{Seconds: &seconds, PreStopSleepSeconds: &sleep}
+	for name, ensure := range deployments {
+		if err := ensure(); err != nil {
+			t.Fatalf("ensure %s: %v", name, err)
+		}
+		check(name, "15", 60)
+	}
+
+	// A zero sleep removes the hook
+	sleep = 0
+	for name, ensure := range deployments {
+		if err := ensure(); err != nil {
+			t.Fatalf("ensure %s: %v", name, err)
+		}
+		check(name, "", 60)
+	}
+}
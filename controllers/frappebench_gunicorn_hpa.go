@@ -0,0 +1,150 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// defaultGunicornTargetCPU is the CPU utilization the gunicorn HPA aims for when no target is set
+const defaultGunicornTargetCPU int32 = 80
+
+// gunicornAutoscalingEnabled reports whether gunicorn replicas are managed by an HPA
+func gunicornAutoscalingEnabled(bench *vyogotechv1alpha1.FrappeBench) bool {
+	config := bench.Spec.GunicornAutoscaling
+	return config != nil && (config.Enabled == nil || *config.Enabled)
+}
+
+// gunicornHPAReplicas returns the min and max replicas of the gunicorn HPA
+func gunicornHPAReplicas(bench *vyogotechv1alpha1.FrappeBench) (int32, int32) {
+	minReplicas, maxReplicas := int32(1), int32(10)
+	if config := bench.Spec.GunicornAutoscaling; config != nil {
+		if config.MinReplicas != nil {
+			minReplicas = *config.MinReplicas
+		}
+		if config.MaxReplicas != nil {
+			maxReplicas = *config.MaxReplicas
+		}
+	}
+	return minReplicas, maxReplicas
+}
+
+// gunicornHPASpec builds the HPA spec for spec.gunicornAutoscaling
+func gunicornHPASpec(bench *vyogotechv1alpha1.FrappeBench) (autoscalingv2.HorizontalPodAutoscalerSpec, error) {
+	config := bench.Spec.GunicornAutoscaling
+	minReplicas, maxReplicas := gunicornHPAReplicas(bench)
+	if minReplicas > maxReplicas {
+		return autoscalingv2.HorizontalPodAutoscalerSpec{}, fmt.Errorf("gunicornAutoscaling.minReplicas (%d) is greater than maxReplicas (%d)", minReplicas, maxReplicas)
+	}
+
+	utilization := func(resource corev1.ResourceName, target int32) autoscalingv2.MetricSpec {
+		return autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: resource,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: &target,
+				},
+			},
+		}
+	}
+	var metrics []autoscalingv2.MetricSpec
+	if config.TargetCPUUtilizationPercentage != nil {
+		metrics = append(metrics, utilization(corev1.ResourceCPU, *config.TargetCPUUtilizationPercentage))
+	}
+	if config.TargetMemoryUtilizationPercentage != nil {
+		metrics = append(metrics, utilization(corev1.ResourceMemory, *config.TargetMemoryUtilizationPercentage))
+	}
+	if len(metrics) == 0 {
+		metrics = append(metrics, utilization(corev1.ResourceCPU, defaultGunicornTargetCPU))
+	}
+
+	return autoscalingv2.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       fmt.Sprintf("%s-gunicorn", bench.Name),
+		},
+		MinReplicas: &minReplicas,
+		MaxReplicas: maxReplicas,
+		Metrics:     metrics,
+	}, nil
+}
+
+// ensureGunicornHPA creates or updates the gunicorn HPA when spec.gunicornAutoscaling is
+// enabled and deletes it otherwise, handing the replicas back to componentReplicas.gunicorn
+func (r *FrappeBenchReconciler) ensureGunicornHPA(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	logger := log.FromContext(ctx)
+
+	hpaName := fmt.Sprintf("%s-gunicorn", bench.Name)
+	existing := &autoscalingv2.HorizontalPodAutoscaler{}
+	err := r.Get(ctx, types.NamespacedName{Name: hpaName, Namespace: bench.Namespace}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if !gunicornAutoscalingEnabled(bench) {
+		if !found {
+			return nil
+		}
+		logger.Info("Deleting gunicorn HorizontalPodAutoscaler", "hpa", hpaName)
+		if err := r.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	spec, err := gunicornHPASpec(bench)
+	if err != nil {
+		return err
+	}
+	if found {
+		if equality.Semantic.DeepEqual(existing.Spec, spec) {
+			return nil
+		}
+		logger.Info("Updating gunicorn HorizontalPodAutoscaler", "hpa", hpaName, "minReplicas", *spec.MinReplicas, "maxReplicas", spec.MaxReplicas)
+		existing.Spec = spec
+		return r.Update(ctx, existing)
+	}
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hpaName,
+			Namespace: bench.Namespace,
+			Labels:    r.componentLabels(bench, "gunicorn"),
+		},
+		Spec: spec,
+	}
+	if err := controllerutil.SetControllerReference(bench, hpa, r.Scheme); err != nil {
+		return err
+	}
+	logger.Info("Creating gunicorn HorizontalPodAutoscaler", "hpa", hpaName, "minReplicas", *spec.MinReplicas, "maxReplicas", spec.MaxReplicas)
+	return r.Create(ctx, hpa)
+}
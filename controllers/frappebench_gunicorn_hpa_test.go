@@ -0,0 +1,178 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGunicornHPASpec(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			GunicornAutoscaling: &vyogotechv1alpha1.GunicornAutoscaling{
+				MinReplicas:                       int32Ptr(2),
+				MaxReplicas:                       int32Ptr(8),
+				TargetCPUUtilizationPercentage:    int32Ptr(70),
+				TargetMemoryUtilizationPercentage: int32Ptr(85),
+			},
+		},
+	}
+
+	spec, err := gunicornHPASpec(bench)
+	if err != nil {
+		t.Fatalf("gunicornHPASpec: %v", err)
+	}
+	if spec.ScaleTargetRef.Kind != "Deployment" || spec.ScaleTargetRef.Name != "bench-gunicorn" {
+		t.Errorf("expected the HPA to target bench-gunicorn, got %+v", spec.ScaleTargetRef)
+	}
+	if *spec.MinReplicas != 2 || spec.MaxReplicas != 8 {
+		t.Errorf("expected 2-8 replicas, got %d-%d", *spec.MinReplicas, spec.MaxReplicas)
+	}
+	want := map[corev1.ResourceName]int32{corev1.ResourceCPU: 70, corev1.ResourceMemory: 85}
+	if len(spec.Metrics) != len(want) {
+		t.Fatalf("expected %d metrics, got %+v", len(want), spec.Metrics)
+	}
+	for _, metric := range spec.Metrics {
+		if metric.Type != autoscalingv2.ResourceMetricSourceType || metric.Resource.Target.Type != autoscalingv2.UtilizationMetricType {
+			t.Errorf("expected resource utilization metrics, got %+v", metric)
+			continue
+		}
+		if *metric.Resource.Target.AverageUtilization != want[metric.Resource.Name] {
+			t.Errorf("expected %s target %d, got %d", metric.Resource.Name, want[metric.Resource.Name], *metric.Resource.Target.AverageUtilization)
+		}
+	}
+
+	// No target set: scale on CPU
+	bench.Spec.GunicornAutoscaling = &vyogotechv1alpha1.GunicornAutoscaling{}
+	spec, err = gunicornHPASpec(bench)
+	if err != nil {
+		t.Fatalf("gunicornHPASpec: %v", err)
+	}
+	if *spec.MinReplicas != 1 || spec.MaxReplicas != 10 || len(spec.Metrics) != 1 ||
+		spec.Metrics[0].Resource.Name != corev1.ResourceCPU || *spec.Metrics[0].Resource.Target.AverageUtilization != defaultGunicornTargetCPU {
+		t.Errorf("unexpected default spec %+v", spec)
+	}
+
+	bench.Spec.GunicornAutoscaling = &vyogotechv1alpha1.GunicornAutoscaling{MinReplicas: int32Ptr(5), MaxReplicas: int32Ptr(3)}
+	if _, err := gunicornHPASpec(bench); err == nil {
+		t.Errorf("expected an error for minReplicas > maxReplicas")
+	}
+}
+
+func TestEnsureGunicornHPA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	minReplicas, maxReplicas := int32(3), int32(6)
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default", UID: "bench-uid"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			FrappeVersion:       "v15",
+			ComponentReplicas:   &vyogotechv1alpha1.ComponentReplicas{Gunicorn: 2},
+			GunicornAutoscaling: &vyogotechv1alpha1.GunicornAutoscaling{MinReplicas: &minReplicas, MaxReplicas: &maxReplicas},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}
+
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := r.ensureGunicornHPA(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornHPA: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if *deploy.Spec.Replicas != minReplicas {
+		t.Errorf("expected an autoscaled deployment to start at minReplicas %d, got %d", minReplicas, *deploy.Spec.Replicas)
+	}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	if err := c.Get(ctx, key, hpa); err != nil {
+		t.Fatalf("Get HPA: %v", err)
+	}
+	if *hpa.Spec.MinReplicas != 3 || hpa.Spec.MaxReplicas != 6 {
+		t.Errorf("expected 3-6 replicas, got %d-%d", *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+	}
+	if len(hpa.OwnerReferences) != 1 || hpa.OwnerReferences[0].Name != "bench" {
+		t.Errorf("expected the HPA to be owned by the bench, got %+v", hpa.OwnerReferences)
+	}
+
+	// The HPA owns the replicas while autoscaling is on
+	scaled := int32(5)
+	deploy.Spec.Replicas = &scaled
+	if err := c.Update(ctx, deploy); err != nil {
+		t.Fatalf("Update Deployment: %v", err)
+	}
+	maxReplicas = 12
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := r.ensureGunicornHPA(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornHPA: %v", err)
+	}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if *deploy.Spec.Replicas != scaled {
+		t.Errorf("expected the HPA's replica count to be kept, got %d", *deploy.Spec.Replicas)
+	}
+	if err := c.Get(ctx, key, hpa); err != nil {
+		t.Fatalf("Get HPA: %v", err)
+	}
+	if hpa.Spec.MaxReplicas != 12 {
+		t.Errorf("expected the HPA to be updated to maxReplicas 12, got %d", hpa.Spec.MaxReplicas)
+	}
+
+	// Disabling removes the HPA and falls back to componentReplicas.gunicorn
+	disabled := false
+	bench.Spec.GunicornAutoscaling.Enabled = &disabled
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := r.ensureGunicornHPA(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornHPA: %v", err)
+	}
+	if err := c.Get(ctx, key, &autoscalingv2.HorizontalPodAutoscaler{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the HPA to be deleted, got %v", err)
+	}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if *deploy.Spec.Replicas != 2 {
+		t.Errorf("expected componentReplicas.gunicorn (2) after disabling autoscaling, got %d", *deploy.Spec.Replicas)
+	}
+}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
@@ -39,13 +40,42 @@ func (r *FrappeBenchReconciler) componentLabels(bench *vyogotechv1alpha1.FrappeB
 	return labels
 }
 
+// webBackendLabel marks gunicorn and socketio pods so the combined web service can select both
+const webBackendLabel = "frappe.tech/web-backend"
+
+// webBackendLabels returns the selector of the combined web service
+func (r *FrappeBenchReconciler) webBackendLabels(bench *vyogotechv1alpha1.FrappeBench) map[string]string {
+	labels := r.benchLabels(bench)
+	labels[webBackendLabel] = "true"
+	return labels
+}
+
+// getGunicornUpstream returns the in-cluster address nginx proxies HTTP traffic to
+func (r *FrappeBenchReconciler) getGunicornUpstream(bench *vyogotechv1alpha1.FrappeBench) string {
+	if bench.Spec.CombinedWebService {
+		return fmt.Sprintf("%s-web:8000", bench.Name)
+	}
+	return fmt.Sprintf("%s-gunicorn:8000", bench.Name)
+}
+
+// getSocketIOUpstream returns the in-cluster address nginx proxies websocket traffic to
+func (r *FrappeBenchReconciler) getSocketIOUpstream(bench *vyogotechv1alpha1.FrappeBench) string {
+	if bench.Spec.CombinedWebService {
+		return fmt.Sprintf("%s-web:9000", bench.Name)
+	}
+	return fmt.Sprintf("%s-socketio:9000", bench.Name)
+}
+
 // Image getters
 
 func (r *FrappeBenchReconciler) getRedisImage(bench *vyogotechv1alpha1.FrappeBench) string {
 	if bench.Spec.RedisConfig != nil && bench.Spec.RedisConfig.Image != "" {
 		return bench.Spec.RedisConfig.Image
 	}
-	return "redis:7-alpine"
+	if isDragonfly(bench) {
+		return defaultDragonflyImage
+	}
+	return defaultRedisImage
 }
 
 // Replica getters
@@ -113,11 +143,28 @@ func (r *FrappeBenchReconciler) getRedisResources(bench *vyogotechv1alpha1.Frapp
 	}
 }
 
+// benchComponentResources returns spec.componentResources on top of the selected
+// spec.resourceProfile. Without a profile, components missing from componentResources
+// keep the built-in values of their getters.
+func benchComponentResources(bench *vyogotechv1alpha1.FrappeBench) vyogotechv1alpha1.ComponentResources {
+	var overrides vyogotechv1alpha1.ComponentResources
+	if bench.Spec.ComponentResources != nil {
+		overrides = *bench.Spec.ComponentResources
+	}
+	switch bench.Spec.ResourceProfile {
+	case vyogotechv1alpha1.ResourceProfileDefault:
+		return overrides.MergeWithDefaults(vyogotechv1alpha1.DefaultComponentResources())
+	case vyogotechv1alpha1.ResourceProfileProduction:
+		return overrides.MergeWithDefaults(vyogotechv1alpha1.ProductionComponentResources())
+	}
+	return overrides
+}
+
 func (r *FrappeBenchReconciler) getGunicornResources(bench *vyogotechv1alpha1.FrappeBench) corev1.ResourceRequirements {
-	if bench.Spec.ComponentResources != nil && bench.Spec.ComponentResources.Gunicorn != nil {
+	if res := benchComponentResources(bench).Gunicorn; res != nil {
 		return corev1.ResourceRequirements{
-			Requests: bench.Spec.ComponentResources.Gunicorn.Requests,
-			Limits:   bench.Spec.ComponentResources.Gunicorn.Limits,
+			Requests: res.Requests,
+			Limits:   res.Limits,
 		}
 	}
 	return corev1.ResourceRequirements{
@@ -133,10 +180,10 @@ func (r *FrappeBenchReconciler) getGunicornResources(bench *vyogotechv1alpha1.Fr
 }
 
 func (r *FrappeBenchReconciler) getNginxResources(bench *vyogotechv1alpha1.FrappeBench) corev1.ResourceRequirements {
-	if bench.Spec.ComponentResources != nil && bench.Spec.ComponentResources.Nginx != nil {
+	if res := benchComponentResources(bench).Nginx; res != nil {
 		return corev1.ResourceRequirements{
-			Requests: bench.Spec.ComponentResources.Nginx.Requests,
-			Limits:   bench.Spec.ComponentResources.Nginx.Limits,
+			Requests: res.Requests,
+			Limits:   res.Limits,
 		}
 	}
 	return corev1.ResourceRequirements{
@@ -152,10 +199,10 @@ func (r *FrappeBenchReconciler) getNginxResources(bench *vyogotechv1alpha1.Frapp
 }
 
 func (r *FrappeBenchReconciler) getSocketIOResources(bench *vyogotechv1alpha1.FrappeBench) corev1.ResourceRequirements {
-	if bench.Spec.ComponentResources != nil && bench.Spec.ComponentResources.Socketio != nil {
+	if res := benchComponentResources(bench).Socketio; res != nil {
 		return corev1.ResourceRequirements{
-			Requests: bench.Spec.ComponentResources.Socketio.Requests,
-			Limits:   bench.Spec.ComponentResources.Socketio.Limits,
+			Requests: res.Requests,
+			Limits:   res.Limits,
 		}
 	}
 	return corev1.ResourceRequirements{
@@ -171,10 +218,10 @@ func (r *FrappeBenchReconciler) getSocketIOResources(bench *vyogotechv1alpha1.Fr
 }
 
 func (r *FrappeBenchReconciler) getSchedulerResources(bench *vyogotechv1alpha1.FrappeBench) corev1.ResourceRequirements {
-	if bench.Spec.ComponentResources != nil && bench.Spec.ComponentResources.Scheduler != nil {
+	if res := benchComponentResources(bench).Scheduler; res != nil {
 		return corev1.ResourceRequirements{
-			Requests: bench.Spec.ComponentResources.Scheduler.Requests,
-			Limits:   bench.Spec.ComponentResources.Scheduler.Limits,
+			Requests: res.Requests,
+			Limits:   res.Limits,
 		}
 	}
 	return corev1.ResourceRequirements{
@@ -190,10 +237,10 @@ func (r *FrappeBenchReconciler) getSchedulerResources(bench *vyogotechv1alpha1.F
 }
 
 func (r *FrappeBenchReconciler) getWorkerDefaultResources(bench *vyogotechv1alpha1.FrappeBench) corev1.ResourceRequirements {
-	if bench.Spec.ComponentResources != nil && bench.Spec.ComponentResources.WorkerDefault != nil {
+	if res := benchComponentResources(bench).WorkerDefault; res != nil {
 		return corev1.ResourceRequirements{
-			Requests: bench.Spec.ComponentResources.WorkerDefault.Requests,
-			Limits:   bench.Spec.ComponentResources.WorkerDefault.Limits,
+			Requests: res.Requests,
+			Limits:   res.Limits,
 		}
 	}
 	return corev1.ResourceRequirements{
@@ -209,10 +256,10 @@ func (r *FrappeBenchReconciler) getWorkerDefaultResources(bench *vyogotechv1alph
 }
 
 func (r *FrappeBenchReconciler) getWorkerLongResources(bench *vyogotechv1alpha1.FrappeBench) corev1.ResourceRequirements {
-	if bench.Spec.ComponentResources != nil && bench.Spec.ComponentResources.WorkerLong != nil {
+	if res := benchComponentResources(bench).WorkerLong; res != nil {
 		return corev1.ResourceRequirements{
-			Requests: bench.Spec.ComponentResources.WorkerLong.Requests,
-			Limits:   bench.Spec.ComponentResources.WorkerLong.Limits,
+			Requests: res.Requests,
+			Limits:   res.Limits,
 		}
 	}
 	return corev1.ResourceRequirements{
@@ -228,10 +275,10 @@ func (r *FrappeBenchReconciler) getWorkerLongResources(bench *vyogotechv1alpha1.
 }
 
 func (r *FrappeBenchReconciler) getWorkerShortResources(bench *vyogotechv1alpha1.FrappeBench) corev1.ResourceRequirements {
-	if bench.Spec.ComponentResources != nil && bench.Spec.ComponentResources.WorkerShort != nil {
+	if res := benchComponentResources(bench).WorkerShort; res != nil {
 		return corev1.ResourceRequirements{
-			Requests: bench.Spec.ComponentResources.WorkerShort.Requests,
-			Limits:   bench.Spec.ComponentResources.WorkerShort.Limits,
+			Requests: res.Requests,
+			Limits:   res.Limits,
 		}
 	}
 	return corev1.ResourceRequirements{
@@ -303,71 +350,30 @@ func (r *FrappeBenchReconciler) getWorkerAutoscalingConfig(bench *vyogotechv1alp
 
 // getDefaultAutoscalingConfig returns opinionated defaults for each worker type
 func (r *FrappeBenchReconciler) getDefaultAutoscalingConfig(workerType string) *vyogotechv1alpha1.WorkerAutoscaling {
-	switch workerType {
-	case "short":
-		// Short jobs: scale-to-zero with aggressive scaling
-		return &vyogotechv1alpha1.WorkerAutoscaling{
-			Enabled:         boolPtr(true),
-			MinReplicas:     int32Ptr(0),
-			MaxReplicas:     int32Ptr(10),
-			QueueLength:     int32Ptr(5),
-			CooldownPeriod:  int32Ptr(60),
-			PollingInterval: int32Ptr(15),
-		}
-	case "long":
-		// Long jobs: scale-to-zero with conservative scaling
-		return &vyogotechv1alpha1.WorkerAutoscaling{
-			Enabled:         boolPtr(true),
-			MinReplicas:     int32Ptr(0),
-			MaxReplicas:     int32Ptr(5),
-			QueueLength:     int32Ptr(2),
-			CooldownPeriod:  int32Ptr(300),
-			PollingInterval: int32Ptr(30),
-		}
-	case "default":
-		// Default/scheduler: always one replica (scheduler must run)
-		return &vyogotechv1alpha1.WorkerAutoscaling{
-			Enabled:        boolPtr(false),
-			StaticReplicas: int32Ptr(1),
-		}
-	}
-	return nil
+	return vyogotechv1alpha1.DefaultWorkerAutoscaling(workerType)
 }
 
 // fillAutoscalingDefaults fills in missing fields with defaults
 func (r *FrappeBenchReconciler) fillAutoscalingDefaults(config *vyogotechv1alpha1.WorkerAutoscaling, workerType string) *vyogotechv1alpha1.WorkerAutoscaling {
-	if config == nil {
-		return r.getDefaultAutoscalingConfig(workerType)
-	}
+	return config.WithDefaults(workerType)
+}
 
-	result := &vyogotechv1alpha1.WorkerAutoscaling{}
-	*result = *config
+// workerDrainCommand asks the RQ worker for a warm shutdown and waits for the container
+// to exit, so the job in flight finishes before the kubelet signals the container. bench
+// worker runs as PID 1 and finishes its job on SIGTERM; the work-horse child running the
+// job must not be signalled, which is why this is not kill -1.
+var workerDrainCommand = []string{"/bin/sh", "-c", "kill -TERM 1; while kill -0 1 2>/dev/null; do sleep 1; done"}
 
-	// Fill in defaults
-	defaults := r.getDefaultAutoscalingConfig(workerType)
-	if result.Enabled == nil {
-		result.Enabled = defaults.Enabled
+// getWorkerLifecycle returns the preStop drain hook for a worker, or nil when draining is disabled
+func (r *FrappeBenchReconciler) getWorkerLifecycle(config *vyogotechv1alpha1.WorkerAutoscaling) *corev1.Lifecycle {
+	if config.DrainOnStop == nil || !*config.DrainOnStop {
+		return nil
 	}
-	if result.MinReplicas == nil {
-		result.MinReplicas = defaults.MinReplicas
-	}
-	if result.MaxReplicas == nil {
-		result.MaxReplicas = defaults.MaxReplicas
-	}
-	if result.StaticReplicas == nil {
-		result.StaticReplicas = defaults.StaticReplicas
-	}
-	if result.QueueLength == nil {
-		result.QueueLength = defaults.QueueLength
-	}
-	if result.CooldownPeriod == nil {
-		result.CooldownPeriod = defaults.CooldownPeriod
-	}
-	if result.PollingInterval == nil {
-		result.PollingInterval = defaults.PollingInterval
+	return &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: workerDrainCommand},
+		},
 	}
-
-	return result
 }
 
 // getWorkerReplicaCount determines the replica count based on scaling mode
@@ -0,0 +1,289 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/registry"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// imageDigestAnnotation is stamped on bench pod templates to force a rollout when the image digest changes
+	imageDigestAnnotation = "frappe.tech/image-digest"
+
+	// imageDigestPollInterval is how often the bench image digest is re-resolved
+	imageDigestPollInterval = 5 * time.Minute
+
+	// imagePullSecretMissingCondition is set while a Secret in imageConfig.pullSecrets
+	// doesn't exist, so the digest is resolved without it
+	imagePullSecretMissingCondition = "ImagePullSecretMissing"
+)
+
+// rolloutOnDigestChange reports whether digest-driven rollouts are enabled for the bench
+func rolloutOnDigestChange(bench *vyogotechv1alpha1.FrappeBench) bool {
+	return bench.Spec.ImageConfig != nil && bench.Spec.ImageConfig.RolloutOnDigestChange
+}
+
+// componentImageOverride returns the imageConfig.components override of a bench
+// component (gunicorn, worker or socketio), nil when it runs the bench image
+func componentImageOverride(bench *vyogotechv1alpha1.FrappeBench, component string) *vyogotechv1alpha1.ComponentImage {
+	if bench.Spec.ImageConfig == nil || bench.Spec.ImageConfig.Components == nil {
+		return nil
+	}
+	components := bench.Spec.ImageConfig.Components
+	switch component {
+	case "gunicorn":
+		return components.Gunicorn
+	case "worker":
+		return components.Worker
+	case "socketio":
+		return components.SocketIO
+	}
+	return nil
+}
+
+// splitImage splits an image reference into repository and tag ("" without a tag); a
+// registry port is not mistaken for a tag
+func splitImage(image string) (string, string) {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, ""
+}
+
+// getComponentImage returns the image of a bench component: the bench image with the
+// repository and tag of its imageConfig.components override, if any
+func (r *FrappeBenchReconciler) getComponentImage(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, component string) string {
+	image := r.getBenchImage(ctx, bench)
+	override := componentImageOverride(bench, component)
+	if override == nil {
+		return image
+	}
+	repository, tag := splitImage(image)
+	if override.Repository != "" {
+		repository = override.Repository
+	}
+	if override.Tag != "" {
+		tag = override.Tag
+	}
+	if tag == "" {
+		return repository
+	}
+	return repository + ":" + tag
+}
+
+// imagePullSecrets returns the bench's image pull secrets for every pod the operator runs for it
+func imagePullSecrets(bench *vyogotechv1alpha1.FrappeBench) []corev1.LocalObjectReference {
+	if bench.Spec.ImageConfig == nil {
+		return nil
+	}
+	return bench.Spec.ImageConfig.PullSecrets
+}
+
+// syncImagePullSecrets brings the image pull secrets of an existing pod template in line
+// with the bench's imageConfig, reporting whether anything changed
+func syncImagePullSecrets(spec *corev1.PodSpec, bench *vyogotechv1alpha1.FrappeBench) bool {
+	secrets := imagePullSecrets(bench)
+	// nil and empty are the same to the API server
+	if len(spec.ImagePullSecrets) == 0 && len(secrets) == 0 {
+		return false
+	}
+	if reflect.DeepEqual(spec.ImagePullSecrets, secrets) {
+		return false
+	}
+	spec.ImagePullSecrets = secrets
+	return true
+}
+
+// ensureImageDigestRollout resolves the bench image digest and, when it differs from the
+// last recorded one, annotates every bench deployment's pod template to trigger a rollout
+func (r *FrappeBenchReconciler) ensureImageDigestRollout(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	if !rolloutOnDigestChange(bench) {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	image := r.getBenchImage(ctx, bench)
+	ref := registry.ParseReference(image)
+	resolver := r.DigestResolver
+	if resolver == nil {
+		resolver = registry.NewHTTPDigestResolver()
+	}
+
+	creds, missing, err := r.getRegistryCredentials(ctx, bench, ref.Registry)
+	if err != nil {
+		logger.Error(err, "Failed to read image pull secrets, resolving anonymously")
+	}
+	r.setImagePullSecretCondition(bench, missing)
+
+	digest, err := resolver.Resolve(ctx, image, creds)
+	if err != nil {
+		return fmt.Errorf("failed to resolve digest for %s: %w", image, err)
+	}
+
+	if digest == bench.Status.ImageDigest {
+		return nil
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(bench.Namespace), client.MatchingLabels(r.benchLabels(bench))); err != nil {
+		return err
+	}
+
+	for i := range deployments.Items {
+		deploy := &deployments.Items[i]
+		if deploy.Spec.Template.Annotations[imageDigestAnnotation] == digest {
+			continue
+		}
+
+		patch := client.MergeFrom(deploy.DeepCopy())
+		if deploy.Spec.Template.Annotations == nil {
+			deploy.Spec.Template.Annotations = make(map[string]string)
+		}
+		deploy.Spec.Template.Annotations[imageDigestAnnotation] = digest
+		// The tag is unchanged, so nodes must re-pull to actually pick up the new digest. An
+		// image pinned by digest always pulls what it names.
+		for j := range deploy.Spec.Template.Spec.Containers {
+			if ref.Digest == "" && deploy.Spec.Template.Spec.Containers[j].Image == image {
+				deploy.Spec.Template.Spec.Containers[j].ImagePullPolicy = corev1.PullAlways
+			}
+		}
+		if err := r.Patch(ctx, deploy, patch); err != nil {
+			return fmt.Errorf("failed to annotate deployment %s: %w", deploy.Name, err)
+		}
+	}
+
+	// The first resolution only records a baseline; later changes are real image updates
+	if bench.Status.ImageDigest != "" {
+		logger.Info("Bench image digest changed, rolling deployments", "image", image, "oldDigest", bench.Status.ImageDigest, "newDigest", digest)
+		r.Recorder.Event(bench, corev1.EventTypeNormal, "ImageDigestChanged",
+			fmt.Sprintf("Image %s now resolves to %s, restarting deployments", image, digest))
+	}
+	bench.Status.ImageDigest = digest
+
+	return nil
+}
+
+// setImagePullSecretCondition records the pull secrets that don't exist on the
+// ImagePullSecretMissing condition, with a Warning event when they change. Benches never
+// missing one get no condition at all.
+func (r *FrappeBenchReconciler) setImagePullSecretCondition(bench *vyogotechv1alpha1.FrappeBench, missing []string) {
+	cond := meta.FindStatusCondition(bench.Status.Conditions, imagePullSecretMissingCondition)
+	if len(missing) == 0 {
+		if cond != nil && cond.Status != metav1.ConditionFalse {
+			r.setCondition(bench, metav1.Condition{
+				Type:    imagePullSecretMissingCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  "SecretsFound",
+				Message: "All image pull Secrets are present",
+			})
+		}
+		return
+	}
+	message := fmt.Sprintf("Image pull Secrets not found: %s; resolving the image digest without them", strings.Join(missing, ", "))
+	if cond != nil && cond.Status == metav1.ConditionTrue && cond.Message == message {
+		return
+	}
+	r.Recorder.Event(bench, corev1.EventTypeWarning, imagePullSecretMissingCondition, message)
+	r.setCondition(bench, metav1.Condition{
+		Type:    imagePullSecretMissingCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "SecretNotFound",
+		Message: message,
+	})
+}
+
+// getRegistryCredentials looks up basic-auth credentials for registryHost in the bench's
+// image pull secrets, the first match winning. Secrets that don't exist are skipped and
+// returned in missing.
+func (r *FrappeBenchReconciler) getRegistryCredentials(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, registryHost string) (creds *registry.Credentials, missing []string, err error) {
+	if bench.Spec.ImageConfig == nil {
+		return nil, nil, nil
+	}
+
+	for _, ref := range bench.Spec.ImageConfig.PullSecrets {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: bench.Namespace}, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				missing = append(missing, ref.Name)
+				continue
+			}
+			return nil, missing, err
+		}
+		data, ok := secret.Data[corev1.DockerConfigJsonKey]
+		if !ok || creds != nil {
+			continue
+		}
+
+		var config struct {
+			Auths map[string]struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+				Auth     string `json:"auth"`
+			} `json:"auths"`
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, missing, fmt.Errorf("invalid %s in secret %s: %w", corev1.DockerConfigJsonKey, ref.Name, err)
+		}
+
+		for server, auth := range config.Auths {
+			if !registryHostMatches(server, registryHost) {
+				continue
+			}
+			if auth.Username != "" {
+				creds = &registry.Credentials{Username: auth.Username, Password: auth.Password}
+				break
+			}
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				continue
+			}
+			if user, pass, found := strings.Cut(string(decoded), ":"); found {
+				creds = &registry.Credentials{Username: user, Password: pass}
+				break
+			}
+		}
+	}
+
+	return creds, missing, nil
+}
+
+// registryHostMatches compares a dockerconfig server key (which may be a URL) with a registry host
+func registryHostMatches(server, registryHost string) bool {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	server = strings.SplitN(server, "/", 2)[0]
+	if registryHost == "docker.io" {
+		return server == "docker.io" || server == "index.docker.io" || server == "registry-1.docker.io"
+	}
+	return server == registryHost
+}
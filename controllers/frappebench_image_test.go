@@ -0,0 +1,318 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/registry"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeDigestResolver struct {
+	digest string
+	creds  *registry.Credentials
+}
+
+func (f *fakeDigestResolver) Resolve(_ context.Context, _ string, creds *registry.Credentials) (string, error) {
+	f.creds = creds
+	return f.digest, nil
+}
+
+func TestEnsureImageDigestRollout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			FrappeVersion: "15",
+			ImageConfig: &vyogotechv1alpha1.ImageConfig{
+				Repository:            "registry.example.com/frappe",
+				Tag:                   "version-15",
+				RolloutOnDigestChange: true,
+				PullSecrets:           []corev1.LocalObjectReference{{Name: "pull"}},
+			},
+		},
+	}
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"username":"u","password":"p"}}}`),
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, pullSecret).Build()
+	resolver := &fakeDigestResolver{digest: "sha256:one"}
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10), DigestResolver: resolver}
+	ctx := context.Background()
+
+	if err := r.ensureGunicorn(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicorn: %v", err)
+	}
+
+	// First resolution records the baseline digest
+	if err := r.ensureImageDigestRollout(ctx, bench); err != nil {
+		t.Fatalf("ensureImageDigestRollout: %v", err)
+	}
+	if bench.Status.ImageDigest != "sha256:one" {
+		t.Errorf("expected status digest sha256:one, got %q", bench.Status.ImageDigest)
+	}
+	if resolver.creds == nil || resolver.creds.Username != "u" {
+		t.Errorf("expected pull secret credentials to be used, got %+v", resolver.creds)
+	}
+
+	// A new digest is stamped on the pod template
+	resolver.digest = "sha256:two"
+	if err := r.ensureImageDigestRollout(ctx, bench); err != nil {
+		t.Fatalf("ensureImageDigestRollout: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if got := deploy.Spec.Template.Annotations[imageDigestAnnotation]; got != "sha256:two" {
+		t.Errorf("expected pod template annotation sha256:two, got %q", got)
+	}
+	if deploy.Spec.Template.Spec.Containers[0].ImagePullPolicy != corev1.PullAlways {
+		t.Errorf("expected PullAlways, got %q", deploy.Spec.Template.Spec.Containers[0].ImagePullPolicy)
+	}
+	if bench.Status.ImageDigest != "sha256:two" {
+		t.Errorf("expected status digest sha256:two, got %q", bench.Status.ImageDigest)
+	}
+}
+
+func TestEnsureImageDigestRollout_MissingPullSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			ImageConfig: &vyogotechv1alpha1.ImageConfig{
+				Repository:            "registry.example.com/frappe@sha256:pinned",
+				RolloutOnDigestChange: true,
+				PullSecrets:           []corev1.LocalObjectReference{{Name: "gone"}, {Name: "pull"}},
+			},
+		},
+	}
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"username":"u","password":"p"}}}`),
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, pullSecret).Build()
+	resolver := &fakeDigestResolver{digest: "sha256:pinned"}
+	recorder := record.NewFakeRecorder(10)
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: recorder, DigestResolver: resolver}
+	ctx := context.Background()
+
+	if err := r.ensureGunicorn(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicorn: %v", err)
+	}
+
+	// The missing Secret is reported and the next one still supplies the credentials
+	for i := 0; i < 2; i++ {
+		if err := r.ensureImageDigestRollout(ctx, bench); err != nil {
+			t.Fatalf("ensureImageDigestRollout: %v", err)
+		}
+	}
+	if resolver.creds == nil || resolver.creds.Username != "u" {
+		t.Errorf("expected the remaining pull secret to be used, got %+v", resolver.creds)
+	}
+	cond := meta.FindStatusCondition(bench.Status.Conditions, imagePullSecretMissingCondition)
+	if cond == nil || cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, "gone") {
+		t.Errorf("expected ImagePullSecretMissing=True naming the Secret, got %+v", cond)
+	}
+	if n := len(recorder.Events); n != 1 {
+		t.Errorf("expected one warning for the missing Secret, got %d", n)
+	}
+
+	// An image pinned by digest keeps its pull policy
+	resolver.digest = "sha256:other"
+	if err := r.ensureImageDigestRollout(ctx, bench); err != nil {
+		t.Fatalf("ensureImageDigestRollout: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if deploy.Spec.Template.Spec.Containers[0].ImagePullPolicy == corev1.PullAlways {
+		t.Error("expected no PullAlways for an image pinned by digest")
+	}
+
+	// Creating the Secret clears the condition
+	gone := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "gone", Namespace: "default"}}
+	if err := c.Create(ctx, gone); err != nil {
+		t.Fatalf("Create Secret: %v", err)
+	}
+	if err := r.ensureImageDigestRollout(ctx, bench); err != nil {
+		t.Fatalf("ensureImageDigestRollout: %v", err)
+	}
+	if cond := meta.FindStatusCondition(bench.Status.Conditions, imagePullSecretMissingCondition); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Errorf("expected ImagePullSecretMissing=False, got %+v", cond)
+	}
+}
+
+func TestEnsureImageDigestRollout_Disabled(t *testing.T) {
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	resolver := &fakeDigestResolver{digest: "sha256:one"}
+	r := &FrappeBenchReconciler{DigestResolver: resolver}
+
+	if err := r.ensureImageDigestRollout(context.Background(), bench); err != nil {
+		t.Fatalf("ensureImageDigestRollout: %v", err)
+	}
+	if bench.Status.ImageDigest != "" {
+		t.Errorf("digest should not be resolved when rolloutOnDigestChange is off, got %q", bench.Status.ImageDigest)
+	}
+}
+
+func TestImagePullSecrets_Deployment(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			FrappeVersion: "15",
+			ImageConfig: &vyogotechv1alpha1.ImageConfig{
+				Repository:  "registry.example.com/frappe",
+				PullSecrets: []corev1.LocalObjectReference{{Name: "pull"}},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+
+	if err := r.ensureGunicorn(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicorn: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if got := deploy.Spec.Template.Spec.ImagePullSecrets; len(got) != 1 || got[0].Name != "pull" {
+		t.Fatalf("expected pull secret on the pod template, got %v", got)
+	}
+
+	// Changing the bench's pull secrets updates the existing deployment
+	bench.Spec.ImageConfig.PullSecrets = []corev1.LocalObjectReference{{Name: "pull-new"}}
+	if err := r.ensureGunicorn(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicorn: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if got := deploy.Spec.Template.Spec.ImagePullSecrets; len(got) != 1 || got[0].Name != "pull-new" {
+		t.Errorf("expected updated pull secret on the pod template, got %v", got)
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	for image, want := range map[string][2]string{
+		"frappe/erpnext:v15":                      {"frappe/erpnext", "v15"},
+		"frappe/erpnext":                          {"frappe/erpnext", ""},
+		"registry.example.com:5000/erp/web":       {"registry.example.com:5000/erp/web", ""},
+		"registry.example.com:5000/erp/web:v15.1": {"registry.example.com:5000/erp/web", "v15.1"},
+	} {
+		if repository, tag := splitImage(image); repository != want[0] || tag != want[1] {
+			t.Errorf("splitImage(%q) = %q, %q, want %q, %q", image, repository, tag, want[0], want[1])
+		}
+	}
+}
+
+func TestComponentImages(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default", UID: "bench-uid"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			FrappeVersion: "15",
+			ImageConfig: &vyogotechv1alpha1.ImageConfig{
+				Repository: "registry.example.com/erp",
+				Tag:        "v15.2.0",
+				Components: &vyogotechv1alpha1.ComponentImages{
+					Gunicorn: &vyogotechv1alpha1.ComponentImage{Repository: "registry.example.com/erp-web"},
+					Worker:   &vyogotechv1alpha1.ComponentImage{Tag: "v15.2.0-slim"},
+				},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	for _, ensure := range []func(context.Context, *vyogotechv1alpha1.FrappeBench) error{r.ensureGunicornDeployment, r.ensureSocketIODeployment, r.ensureScheduler, r.ensureWorkers} {
+		if err := ensure(ctx, bench); err != nil {
+			t.Fatalf("ensure: %v", err)
+		}
+	}
+	images := func() map[string]string {
+		images := map[string]string{}
+		for _, name := range []string{"gunicorn", "socketio", "scheduler", "worker-default", "worker-long"} {
+			deploy := &appsv1.Deployment{}
+			if err := c.Get(ctx, types.NamespacedName{Name: "bench-" + name, Namespace: "default"}, deploy); err != nil {
+				t.Fatalf("Get %s Deployment: %v", name, err)
+			}
+			images[name] = deploy.Spec.Template.Spec.Containers[0].Image
+		}
+		return images
+	}
+	want := map[string]string{
+		"gunicorn":       "registry.example.com/erp-web:v15.2.0",
+		"socketio":       "registry.example.com/erp:v15.2.0",
+		"scheduler":      "registry.example.com/erp:v15.2.0",
+		"worker-default": "registry.example.com/erp:v15.2.0-slim",
+		"worker-long":    "registry.example.com/erp:v15.2.0-slim",
+	}
+	if got := images(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected component images %v, got %v", want, got)
+	}
+
+	// Dropping an override moves the component back to the bench image
+	bench.Spec.ImageConfig.Components.Worker = nil
+	if err := r.ensureWorkers(ctx, bench); err != nil {
+		t.Fatalf("ensureWorkers: %v", err)
+	}
+	if got := images()["worker-default"]; got != "registry.example.com/erp:v15.2.0" {
+		t.Errorf("expected the worker back on the bench image, got %q", got)
+	}
+}
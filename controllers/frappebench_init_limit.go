@@ -0,0 +1,136 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// benchInitJobComponent labels bench-init Jobs so they can be counted across namespaces
+const benchInitJobComponent = "bench-init"
+
+// benchInitQueuedRequeue is how long a bench waits for a free bench-init slot
+const benchInitQueuedRequeue = 15 * time.Second
+
+// errBenchInitQueued marks a bench whose init Job waits for a free slot
+var errBenchInitQueued = errors.New("bench-init limit reached")
+
+// isBenchInitQueued reports whether err comes from a bench-init Job held back by the limit
+func isBenchInitQueued(err error) bool {
+	return errors.Is(err, errBenchInitQueued)
+}
+
+// maxConcurrentBenchInits reads maxConcurrentBenchInits from the operator ConfigMap;
+// zero (the default) means bench-init Jobs are not limited
+func maxConcurrentBenchInits(operatorConfig *corev1.ConfigMap) int {
+	if operatorConfig == nil {
+		return 0
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(operatorConfig.Data["maxConcurrentBenchInits"]))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// benchInitGate serializes bench-init Job creation so concurrent reconciles can't
+// overshoot the cluster-wide limit between counting running Jobs and creating one
+type benchInitGate struct {
+	mu sync.Mutex
+	// created holds Jobs this process created that the cache may not list yet
+	created map[types.NamespacedName]struct{}
+}
+
+// create creates the bench-init Job unless limit Jobs are already running, in which
+// case it returns an error wrapping errBenchInitQueued. A Job another reconcile
+// already created counts as created.
+func (g *benchInitGate) create(ctx context.Context, c client.Client, job *batchv1.Job, limit int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	running, err := g.running(ctx, c)
+	if err != nil {
+		return err
+	}
+	BenchInitJobsRunning.Set(float64(running))
+	if limit > 0 && running >= limit {
+		return fmt.Errorf("%w: %d of %d bench-init jobs running", errBenchInitQueued, running, limit)
+	}
+
+	if err := c.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	if g.created == nil {
+		g.created = make(map[types.NamespacedName]struct{})
+	}
+	g.created[client.ObjectKeyFromObject(job)] = struct{}{}
+	BenchInitJobsRunning.Set(float64(running + 1))
+	return nil
+}
+
+// observe recounts the running bench-init Jobs into BenchInitJobsRunning, so the gauge
+// drops when a Job finishes instead of only moving when another Job is created
+func (g *benchInitGate) observe(ctx context.Context, c client.Client) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	running, err := g.running(ctx, c)
+	if err != nil {
+		return err
+	}
+	BenchInitJobsRunning.Set(float64(running))
+	return nil
+}
+
+// running counts bench-init Jobs that have neither succeeded nor failed, including
+// Jobs created by this process that haven't reached the cache yet
+func (g *benchInitGate) running(ctx context.Context, c client.Client) (int, error) {
+	jobs := &batchv1.JobList{}
+	if err := c.List(ctx, jobs, client.MatchingLabels{"component": benchInitJobComponent}); err != nil {
+		return 0, err
+	}
+
+	listed := make(map[types.NamespacedName]struct{}, len(jobs.Items))
+	running := 0
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		listed[client.ObjectKeyFromObject(job)] = struct{}{}
+		if job.Status.Succeeded == 0 && job.Status.Failed == 0 {
+			running++
+		}
+	}
+	for key := range g.created {
+		if _, ok := listed[key]; ok {
+			delete(g.created, key)
+			continue
+		}
+		running++
+	}
+	return running, nil
+}
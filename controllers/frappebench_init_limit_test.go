@@ -0,0 +1,166 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMaxConcurrentBenchInits(t *testing.T) {
+	for value, want := range map[string]int{"": 0, "3": 3, " 2 ": 2, "-1": 0, "many": 0} {
+		cm := &corev1.ConfigMap{Data: map[string]string{"maxConcurrentBenchInits": value}}
+		if got := maxConcurrentBenchInits(cm); got != want {
+			t.Errorf("maxConcurrentBenchInits(%q) = %d, want %d", value, got, want)
+		}
+	}
+	if got := maxConcurrentBenchInits(nil); got != 0 {
+		t.Errorf("expected no limit without operator config, got %d", got)
+	}
+}
+
+func TestEnsureBenchInitialized_concurrencyLimit(t *testing.T) {
+	benches := make([]*vyogotechv1alpha1.FrappeBench, 5)
+	objs := make([]client.Object, len(benches))
+	for i := range benches {
+		benches[i] = &vyogotechv1alpha1.FrappeBench{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("bench-%d", i), Namespace: fmt.Sprintf("ns-%d", i)},
+			Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+		}
+		objs[i] = benches[i]
+	}
+	siteReconciler, c := newInitJobTestReconciler(objs...)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	// Concurrent reconciles must not overshoot the limit
+	var wg sync.WaitGroup
+	queued := make([]bool, len(benches))
+	for i, bench := range benches {
+		wg.Add(1)
+		go func(i int, bench *vyogotechv1alpha1.FrappeBench) {
+			defer wg.Done()
+			_, err := r.ensureBenchInitialized(ctx, bench, false, nil, 2)
+			if err != nil && !isBenchInitQueued(err) {
+				t.Errorf("ensureBenchInitialized(%s): %v", bench.Name, err)
+			}
+			queued[i] = isBenchInitQueued(err)
+		}(i, bench)
+	}
+	wg.Wait()
+
+	jobs := &batchv1.JobList{}
+	if err := c.List(ctx, jobs, client.MatchingLabels{"component": benchInitJobComponent}); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(jobs.Items) != 2 {
+		t.Fatalf("expected 2 bench-init jobs at the limit, got %d", len(jobs.Items))
+	}
+	if got := testutil.ToFloat64(BenchInitJobsRunning); got != 2 {
+		t.Errorf("expected running gauge 2, got %v", got)
+	}
+
+	// A finished init job frees its slot for a queued bench
+	finished := &jobs.Items[0]
+	finished.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, finished); err != nil {
+		t.Fatalf("Update job status: %v", err)
+	}
+	for _, bench := range benches {
+		if bench.Name+"-init" != finished.Name {
+			continue
+		}
+		if ready, err := r.ensureBenchInitialized(ctx, bench, false, nil, 2); err != nil || !ready {
+			t.Fatalf("expected %s to be initialized, got ready=%v err=%v", bench.Name, ready, err)
+		}
+	}
+	if got := testutil.ToFloat64(BenchInitJobsRunning); got != 1 {
+		t.Errorf("expected running gauge to drop to 1 once a job finished, got %v", got)
+	}
+	for i, bench := range benches {
+		if !queued[i] {
+			continue
+		}
+		if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 2); err != nil {
+			t.Fatalf("expected queued bench %s to get the free slot: %v", bench.Name, err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: bench.Name + "-init", Namespace: bench.Namespace}, &batchv1.Job{}); err != nil {
+			t.Fatalf("expected init job for %s: %v", bench.Name, err)
+		}
+		break
+	}
+
+	// Without a limit the remaining benches start right away
+	for _, bench := range benches {
+		if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+			t.Fatalf("ensureBenchInitialized(%s) without limit: %v", bench.Name, err)
+		}
+	}
+	if err := c.List(ctx, jobs, client.MatchingLabels{"component": benchInitJobComponent}); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(jobs.Items) != len(benches) {
+		t.Errorf("expected %d bench-init jobs without a limit, got %d", len(benches), len(jobs.Items))
+	}
+}
+
+func TestEnsureBenchInitialized_removedJob(t *testing.T) {
+	ttl := int32(60)
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15", JobTTLSecondsAfterFinished: &ttl},
+	}
+	siteReconciler, c := newInitJobTestReconciler(bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-init", Namespace: "default"}
+
+	if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+		t.Fatalf("ensureBenchInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Spec.TTLSecondsAfterFinished == nil || *job.Spec.TTLSecondsAfterFinished != ttl {
+		t.Errorf("expected TTL %d, got %v", ttl, job.Spec.TTLSecondsAfterFinished)
+	}
+
+	// Once the bench is Initialized, a garbage collected init job is not recreated
+	if err := c.Delete(ctx, job); err != nil {
+		t.Fatalf("Delete Job: %v", err)
+	}
+	bench.Status.Conditions = []metav1.Condition{{Type: "Initialized", Status: metav1.ConditionTrue, Reason: "InitJobSucceeded"}}
+	done, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0)
+	if err != nil || !done {
+		t.Fatalf("expected the bench to stay initialized, got %v, %v", done, err)
+	}
+	if err := c.Get(ctx, key, &batchv1.Job{}); err == nil {
+		t.Error("expected no new bench init Job")
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/backoff"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// benchInitLogTailLines is how much of a failed bench-init pod's log is kept in status.initFailureLog
+const benchInitLogTailLines = 50
+
+// Backoff before spec.retryFailedInit recreates a failed bench-init Job
+const (
+	benchInitRetryBase = 30 * time.Second
+	benchInitRetryMax  = 10 * time.Minute
+)
+
+// benchInitFailedError is returned by ensureBenchInitialized when the bench-init Job failed.
+// retryAfter is when the bench should be reconciled again, zero when the failed Job is kept.
+type benchInitFailedError struct {
+	job        string
+	retryAfter time.Duration
+}
+
+func (e *benchInitFailedError) Error() string {
+	return fmt.Sprintf("bench-init job %s failed", e.job)
+}
+
+// asBenchInitFailed returns the benchInitFailedError in err's chain, if any
+func asBenchInitFailed(err error) (*benchInitFailedError, bool) {
+	var failed *benchInitFailedError
+	ok := errors.As(err, &failed)
+	return failed, ok
+}
+
+// jobFailedAt returns when a failed job was marked failed
+func jobFailedAt(job *batchv1.Job) time.Time {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return cond.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+// handleFailedBenchInit records a failed bench-init Job in the bench status: the tail of the
+// failed pod's log, Initialized=False and Degraded=True. With spec.retryFailedInit the Job is
+// deleted once the backoff has passed, so the next reconcile creates a new one.
+func (r *FrappeBenchReconciler) handleFailedBenchInit(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, job *batchv1.Job) error {
+	logger := log.FromContext(ctx)
+
+	// Degraded stays JobFailed while the failed job exists, so the failure is counted once
+	if degraded := meta.FindStatusCondition(bench.Status.Conditions, "Degraded"); degraded == nil || degraded.Reason != "JobFailed" {
+		JobFailures.WithLabelValues(jobFailureBenchInit).Inc()
+		r.Recorder.Event(bench, corev1.EventTypeWarning, "InitJobFailed", fmt.Sprintf("Bench init job %s failed", job.Name))
+		failure, err := readJobFailure(ctx, r.Client, r.LogReader, job, "", benchInitLogTailLines)
+		if err != nil {
+			// The log is best effort; the failure itself is still recorded
+			logger.Error(err, "Failed to read bench init job log", "job", job.Name)
+		}
+		bench.Status.InitFailureLog = failure.LogTail
+		bench.Status.InitFailureSummary = failure.Summary
+	}
+
+	message := fmt.Sprintf("Initialization job %s failed", job.Name)
+	if bench.Status.InitFailureSummary != "" {
+		message += ": " + bench.Status.InitFailureSummary
+	}
+	if !bench.Spec.RetryFailedInit {
+		message += "; delete it to retry"
+	}
+	bench.Status.Phase = "Failed"
+	r.setCondition(bench, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  "InitializationFailed",
+		Message: message,
+	})
+	r.setCondition(bench, metav1.Condition{
+		Type:    "Initialized",
+		Status:  metav1.ConditionFalse,
+		Reason:  "JobFailed",
+		Message: message,
+	})
+	r.setCondition(bench, metav1.Condition{
+		Type:    "Degraded",
+		Status:  metav1.ConditionTrue,
+		Reason:  "JobFailed",
+		Message: message,
+	})
+
+	failed := &benchInitFailedError{job: job.Name}
+	if !bench.Spec.RetryFailedInit {
+		return failed
+	}
+
+	wait := backoff.ExponentialBackoff(benchInitRetryBase, int(bench.Status.InitRetries), benchInitRetryMax)
+	if remaining := wait - time.Since(jobFailedAt(job)); remaining > 0 {
+		failed.retryAfter = remaining
+		return failed
+	}
+
+	logger.Info("Deleting failed bench init job to retry", "job", job.Name, "retries", bench.Status.InitRetries)
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete failed bench init job: %w", err)
+	}
+	bench.Status.InitRetries++
+	r.Recorder.Event(bench, corev1.EventTypeNormal, "InitJobRetried",
+		fmt.Sprintf("Deleted failed bench init job %s to retry (attempt %d)", job.Name, bench.Status.InitRetries+1))
+	r.setCondition(bench, metav1.Condition{
+		Type:    "Degraded",
+		Status:  metav1.ConditionFalse,
+		Reason:  "InitRetrying",
+		Message: fmt.Sprintf("Retrying bench initialization after job %s failed", job.Name),
+	})
+	failed.retryAfter = time.Second
+	return failed
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// newFailedBenchInitObjects returns a bench with a bench-init Job that failed at failedAt, and its failed pod
+func newFailedBenchInitObjects(failedAt time.Time) (*vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench, *batchv1.Job, *corev1.Pod) {
+	site, bench := newInitJobTestObjects()
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-init", Namespace: "default"},
+		Status: batchv1.JobStatus{
+			Failed: 1,
+			Conditions: []batchv1.JobCondition{{
+				Type:               batchv1.JobFailed,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(failedAt),
+			}},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-init-abc", Namespace: "default", Labels: map[string]string{"job-name": "bench-init"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodFailed},
+	}
+	return site, bench, job, pod
+}
+
+func TestEnsureBenchInitialized_failedJob(t *testing.T) {
+	site, bench, job, pod := newFailedBenchInitObjects(time.Now())
+	siteReconciler, c := newInitJobTestReconciler(site, bench, job, pod)
+	recorder := record.NewFakeRecorder(10)
+	r := &FrappeBenchReconciler{
+		Client:    c,
+		Scheme:    siteReconciler.Scheme,
+		Recorder:  recorder,
+		LogReader: &fakeLogReader{logs: "Installing frappe\nERROR: could not fetch app\n"},
+	}
+	ctx := context.Background()
+
+	ready, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0)
+	failed, ok := asBenchInitFailed(err)
+	if ready || !ok {
+		t.Fatalf("expected a bench init failure, got ready=%v err=%v", ready, err)
+	}
+	if failed.retryAfter != 0 {
+		t.Errorf("expected no requeue without retryFailedInit, got %s", failed.retryAfter)
+	}
+	if !strings.Contains(bench.Status.InitFailureLog, "could not fetch app") {
+		t.Errorf("expected the pod log tail in status, got %q", bench.Status.InitFailureLog)
+	}
+	if bench.Status.Phase != "Failed" {
+		t.Errorf("expected phase Failed, got %q", bench.Status.Phase)
+	}
+	for conditionType, want := range map[string]metav1.ConditionStatus{"Initialized": metav1.ConditionFalse, "Degraded": metav1.ConditionTrue, "Ready": metav1.ConditionFalse} {
+		if cond := meta.FindStatusCondition(bench.Status.Conditions, conditionType); cond == nil || cond.Status != want {
+			t.Errorf("expected %s=%s, got %+v", conditionType, want, cond)
+		}
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "InitJobFailed") {
+		t.Errorf("expected an InitJobFailed event, got %q", event)
+	}
+
+	// The failure is reported once; the failed job is kept for inspection
+	if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0); err == nil {
+		t.Fatal("expected the failure to be reported again")
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no further events, got %d", len(recorder.Events))
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-init", Namespace: "default"}, &batchv1.Job{}); err != nil {
+		t.Errorf("expected the failed job to be kept: %v", err)
+	}
+}
+
+func TestEnsureBenchInitialized_retryFailedInit(t *testing.T) {
+	site, bench, job, pod := newFailedBenchInitObjects(time.Now().Add(-10 * time.Second))
+	bench.Spec.RetryFailedInit = true
+	siteReconciler, c := newInitJobTestReconciler(site, bench, job, pod)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "bench-init", Namespace: "default"}
+
+	// Within the backoff the job is kept and the bench requeued for when it ends
+	_, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0)
+	failed, ok := asBenchInitFailed(err)
+	if !ok || failed.retryAfter <= 0 || failed.retryAfter > benchInitRetryBase {
+		t.Fatalf("expected a requeue within the backoff, got %v", err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); err != nil {
+		t.Fatalf("expected the job to be kept during the backoff: %v", err)
+	}
+
+	// Once the backoff has passed the job is deleted and the retry counted
+	existing := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, existing); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	existing.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-time.Minute))
+	if err := c.Status().Update(ctx, existing); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0); err == nil {
+		t.Fatal("expected the failure to requeue the bench")
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Fatalf("expected the failed job to be deleted, got %v", err)
+	}
+	if bench.Status.InitRetries != 1 {
+		t.Errorf("expected one retry, got %d", bench.Status.InitRetries)
+	}
+
+	// The next reconcile creates a new init job
+	if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+		t.Fatalf("ensureBenchInitialized: %v", err)
+	}
+	recreated := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, recreated); err != nil || jobFailed(recreated) {
+		t.Errorf("expected a new init job, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// redisPasswordKey is the key of the <bench>-redis-urls Secret holding the external redis
+// password on its own, for the KEDA TriggerAuthentication
+const redisPasswordKey = "redis_password"
+
+// triggerAuthenticationGVK is the KEDA TriggerAuthentication kind
+var triggerAuthenticationGVK = schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: "TriggerAuthentication"}
+
+// redisTriggerAuthName is the TriggerAuthentication the worker ScaledObjects use for a
+// password-protected external redis
+func redisTriggerAuthName(bench *vyogotechv1alpha1.FrappeBench) string {
+	return fmt.Sprintf("%s-redis-auth", bench.Name)
+}
+
+// redisURLPassword returns the password of a redis URL, empty when it has none
+func redisURLPassword(redisURL string) string {
+	u, err := url.Parse(redisURL)
+	if err != nil || u.User == nil {
+		return ""
+	}
+	password, _ := u.User.Password()
+	return password
+}
+
+// kedaRedisTarget returns the address the worker ScaledObjects read queue depth from and
+// the TriggerAuthentication they authenticate with; the name is empty when the redis has
+// no password
+func (r *FrappeBenchReconciler) kedaRedisTarget(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (string, string, error) {
+	if !isExternalRedis(bench) {
+		return r.getRedisAddress(bench), "", nil
+	}
+	_, redisQueue, err := resolveRedisURLs(ctx, r.Client, bench)
+	if err != nil {
+		return "", "", err
+	}
+	u, err := url.Parse(redisQueue)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid external redis URL: %w", err)
+	}
+	if redisURLPassword(redisQueue) == "" {
+		return u.Host, "", nil
+	}
+	return u.Host, redisTriggerAuthName(bench), nil
+}
+
+// ensureRedisTriggerAuthentication keeps the KEDA TriggerAuthentication for the external
+// redis password in step with redisConfig.connectionSecretRef. It points KEDA at the
+// password in the <bench>-redis-urls Secret, which lives in the bench namespace even when
+// the connection Secret doesn't, and is deleted when there is no password.
+func (r *FrappeBenchReconciler) ensureRedisTriggerAuthentication(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, authName string) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(triggerAuthenticationGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: redisTriggerAuthName(bench), Namespace: bench.Namespace}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if authName == "" {
+		if found {
+			log.FromContext(ctx).Info("Deleting redis TriggerAuthentication", "name", existing.GetName())
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	spec := map[string]interface{}{
+		"secretTargetRef": []interface{}{
+			map[string]interface{}{
+				"parameter": "password",
+				"name":      redisURLSecretName(bench),
+				"key":       redisPasswordKey,
+			},
+		},
+	}
+	if found {
+		current, _, _ := unstructured.NestedFieldNoCopy(existing.Object, "spec")
+		if reflect.DeepEqual(current, spec) {
+			return nil
+		}
+		if err := unstructured.SetNestedField(existing.Object, spec, "spec"); err != nil {
+			return err
+		}
+		return r.Update(ctx, existing)
+	}
+
+	auth := &unstructured.Unstructured{}
+	auth.SetGroupVersionKind(triggerAuthenticationGVK)
+	auth.SetName(authName)
+	auth.SetNamespace(bench.Namespace)
+	auth.SetLabels(r.benchLabels(bench))
+	if err := unstructured.SetNestedField(auth.Object, spec, "spec"); err != nil {
+		return fmt.Errorf("failed to set TriggerAuthentication spec: %w", err)
+	}
+	if err := controllerutil.SetControllerReference(bench, auth, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+	log.FromContext(ctx).Info("Creating redis TriggerAuthentication", "name", authName)
+	return r.Create(ctx, auth)
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// migratingCondition is True while a migration job runs bench migrate on every site
+	migratingCondition = "Migrating"
+
+	// migrationAnnotation records on the migration job which image and apps it migrates to
+	migrationAnnotation = "frappe.tech/migration"
+
+	// migrationFailedReason is the Migrating reason of a failed migration job
+	migrationFailedReason = "MigrationFailed"
+)
+
+// benchAppNames returns the names of the apps in the bench spec
+func benchAppNames(bench *vyogotechv1alpha1.FrappeBench) []string {
+	apps := make([]string, 0, len(bench.Spec.Apps))
+	for _, app := range bench.Spec.Apps {
+		apps = append(apps, app.Name)
+	}
+	return apps
+}
+
+// migrationFingerprint identifies the image and app set a migration brings the sites to
+func migrationFingerprint(image string, apps []string) string {
+	sorted := append([]string(nil), apps...)
+	sort.Strings(sorted)
+	return image + ";" + strings.Join(sorted, ",")
+}
+
+// benchMigrationPending reports whether the sites have not been migrated to the bench's
+// current image and apps yet, either because the job is running or because it failed
+func benchMigrationPending(bench *vyogotechv1alpha1.FrappeBench) bool {
+	cond := meta.FindStatusCondition(bench.Status.Conditions, migratingCondition)
+	return cond != nil && (cond.Status == metav1.ConditionTrue || cond.Reason == migrationFailedReason)
+}
+
+// ensureBenchMigrated runs bench --site all migrate when the bench image or app list
+// differs from what the sites were last migrated to. The first reconcile after
+// initialization only records the current image, there is nothing to migrate yet.
+func (r *FrappeBenchReconciler) ensureBenchMigrated(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	image := r.getBenchImage(ctx, bench)
+	apps := benchAppNames(bench)
+	if bench.Status.MigratedImage == "" {
+		bench.Status.MigratedImage = image
+		return nil
+	}
+	desired := migrationFingerprint(image, apps)
+	if migrationFingerprint(bench.Status.MigratedImage, bench.Status.InstalledApps) == desired {
+		if benchMigrationPending(bench) {
+			// The spec went back to what the sites already run
+			r.setCondition(bench, metav1.Condition{
+				Type:    migratingCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  "UpToDate",
+				Message: fmt.Sprintf("Sites are migrated to %s", image),
+			})
+		}
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	jobName := fmt.Sprintf("%s-migrate", bench.Name)
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: bench.Namespace}, job)
+	if err == nil {
+		if job.Annotations[migrationAnnotation] != desired {
+			// Left over from an earlier upgrade; remove it so the next reconcile migrates to the current image
+			logger.Info("Replacing stale migration job", "job", jobName)
+			r.markMigrationPending(bench, image, fmt.Sprintf("Replacing stale migration job %s", jobName))
+			return client.IgnoreNotFound(r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+		}
+		if job.Status.Failed > 0 {
+			r.failMigration(bench, fmt.Sprintf("Migration job %s failed, delete it to retry", jobName))
+			return nil
+		}
+		if job.Status.Succeeded == 0 {
+			return nil
+		}
+
+		logger.Info("Sites migrated", "image", image, "apps", apps)
+		bench.Status.MigratedImage = image
+		bench.Status.InstalledApps = apps
+		r.setCondition(bench, metav1.Condition{
+			Type:    migratingCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "MigrationSucceeded",
+			Message: fmt.Sprintf("Sites are migrated to %s", image),
+		})
+		r.Recorder.Event(bench, corev1.EventTypeNormal, "MigrationSucceeded", fmt.Sprintf("Migrated all sites to %s", image))
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	// Take the rollback point before bench migrate touches the sites
+	if bench.Spec.MigrationSnapshot != nil {
+		cut, err := r.ensureMigrationSnapshot(ctx, bench, desired)
+		if err != nil || !cut {
+			r.markMigrationPending(bench, image, "Waiting for the pre-migration snapshot")
+			return err
+		}
+	}
+
+	sizeHint, err := r.benchSitesSizeHint(ctx, bench)
+	if err != nil {
+		return err
+	}
+
+	container := resources.NewContainerBuilder("migrate", image).
+		WithCommand("bash", "-c").
+		WithArgs("cd /home/frappe/frappe-bench && bench --site all migrate").
+		WithResources(siteJobResources(sizeHint)).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		WithEnv("USER", "frappe").
+		Build()
+
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(benchJobPodConfig(bench), ownedLabels(bench, r.benchLabels(bench)))
+
+	job = resources.NewJobBuilder(jobName, bench.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationMigrate, bench.Name, "")).
+		WithAnnotations(map[string]string{migrationAnnotation: desired}).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(0).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(bench, r.Scheme).
+		MustBuild()
+	syncExtraVolumes(&job.Spec.Template, bench)
+
+	logger.Info("Creating migration job", "job", jobName, "from", bench.Status.MigratedImage, "to", image)
+	if err := r.Create(ctx, job); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Migrating all sites from %s to %s", bench.Status.MigratedImage, image)
+	r.setCondition(bench, metav1.Condition{
+		Type:    migratingCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "MigrationRunning",
+		Message: message,
+	})
+	r.Recorder.Event(bench, corev1.EventTypeNormal, "MigrationStarted", message)
+	return nil
+}
+
+// markMigrationPending sets Migrating before the migration job exists, so updateBenchStatus
+// keeps installedApps at what the sites were last migrated with and a later reconcile
+// still sees the app change. A failed migration keeps its condition until it is retried.
+func (r *FrappeBenchReconciler) markMigrationPending(bench *vyogotechv1alpha1.FrappeBench, image, message string) {
+	if benchMigrationPending(bench) {
+		return
+	}
+	r.setCondition(bench, metav1.Condition{
+		Type:    migratingCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "MigrationPending",
+		Message: fmt.Sprintf("%s before migrating all sites to %s", message, image),
+	})
+}
+
+// benchSitesSizeHint returns the largest sizeHint of the bench's sites, so the job
+// migrating all of them gets the resources its biggest site needs
+func (r *FrappeBenchReconciler) benchSitesSizeHint(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (string, error) {
+	siteList := &vyogotechv1alpha1.FrappeSiteList{}
+	if err := r.List(ctx, siteList, client.InNamespace(bench.Namespace)); err != nil {
+		return "", err
+	}
+
+	sizeHint := ""
+	for _, site := range siteList.Items {
+		if site.Spec.BenchRef != nil && site.Spec.BenchRef.Name == bench.Name {
+			sizeHint = largerSizeHint(sizeHint, site.Spec.SizeHint)
+		}
+	}
+	return sizeHint, nil
+}
+
+// migrationSnapshotName names the VolumeSnapshot taken before migrating to desired, so each
+// upgrade gets its own rollback point
+func migrationSnapshotName(bench *vyogotechv1alpha1.FrappeBench, desired string) string {
+	sum := sha256.Sum256([]byte(desired))
+	return fmt.Sprintf("%s-pre-migrate-%x", bench.Name, sum[:4])
+}
+
+// ensureMigrationSnapshot snapshots the bench's sites PVC before the migration job runs and
+// reports whether the snapshot has been cut. While it is cut the sites stay Migrating; when
+// it can't be taken the migration is failed rather than run without a rollback point. The
+// snapshot isn't owned by the bench so it outlives it.
+func (r *FrappeBenchReconciler) ensureMigrationSnapshot(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, desired string) (bool, error) {
+	available, err := isVolumeSnapshotAvailable(ctx, r.Client, r.LookupTimeout)
+	if err != nil {
+		return false, err
+	}
+	if !available {
+		r.failMigration(bench, "migrationSnapshot is set but "+volumeSnapshotCRDMissing)
+		return false, nil
+	}
+
+	key := client.ObjectKey{Name: migrationSnapshotName(bench, desired), Namespace: bench.Namespace}
+	snapshot, created, err := ensureSitesVolumeSnapshot(ctx, r.Client, bench, key, bench.Spec.MigrationSnapshot, map[string]string{
+		"app":   "frappe",
+		"bench": bench.Name,
+	})
+	if err != nil {
+		return false, err
+	}
+	if created {
+		log.FromContext(ctx).Info("Created pre-migration VolumeSnapshot", "volumeSnapshot", key.Name)
+		r.Recorder.Event(bench, corev1.EventTypeNormal, "VolumeSnapshotCreated", fmt.Sprintf("Created VolumeSnapshot %s of PVC %s-sites", key.Name, bench.Name))
+		bench.Status.MigrationSnapshot = key.Name
+	}
+
+	cut, failure := volumeSnapshotCut(snapshot)
+	if failure != "" {
+		r.failMigration(bench, fmt.Sprintf("%s, delete it to retry", failure))
+		return false, nil
+	}
+	if !cut {
+		r.setCondition(bench, metav1.Condition{
+			Type:    migratingCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "WaitingForVolumeSnapshot",
+			Message: fmt.Sprintf("Waiting for VolumeSnapshot %s before migrating the sites", key.Name),
+		})
+	}
+	return cut, nil
+}
+
+// failMigration marks the migration failed, emitting the event only when it newly fails
+func (r *FrappeBenchReconciler) failMigration(bench *vyogotechv1alpha1.FrappeBench, message string) {
+	cond := meta.FindStatusCondition(bench.Status.Conditions, migratingCondition)
+	if cond != nil && cond.Reason == migrationFailedReason && cond.Message == message {
+		return
+	}
+	r.setCondition(bench, metav1.Condition{
+		Type:    migratingCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  migrationFailedReason,
+		Message: message,
+	})
+	r.Recorder.Event(bench, corev1.EventTypeWarning, migrationFailedReason, message)
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestEnsureBenchMigrated(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	recorder := record.NewFakeRecorder(20)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: recorder}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-migrate", Namespace: "default"}
+
+	// The first pass only records what the freshly initialized sites run
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	if bench.Status.MigratedImage != "docker.io/frappe/erpnext:15" {
+		t.Errorf("expected the current image to be recorded, got %q", bench.Status.MigratedImage)
+	}
+	if err := c.Get(ctx, key, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Fatalf("expected no migration job for a fresh bench, got %v", err)
+	}
+
+	bench.Spec.FrappeVersion = "16"
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if script := strings.Join(job.Spec.Template.Spec.Containers[0].Args, " "); !strings.Contains(script, "bench --site all migrate") {
+		t.Errorf("expected the job to migrate all sites, got %q", script)
+	}
+	if image := job.Spec.Template.Spec.Containers[0].Image; image != "docker.io/frappe/erpnext:16" {
+		t.Errorf("expected the job to run the new image, got %q", image)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationMigrate {
+		t.Errorf("expected operation label %q, got %q", jobOperationMigrate, job.Labels[jobOperationLabel])
+	}
+	assertSitesMount(t, "migration job", job.Spec.Template.Spec)
+	if !meta.IsStatusConditionTrue(bench.Status.Conditions, migratingCondition) {
+		t.Errorf("expected Migrating condition to be True")
+	}
+	if !benchMigrationPending(bench) {
+		t.Errorf("expected migration to be pending while the job runs")
+	}
+	if bench.Status.MigratedImage != "docker.io/frappe/erpnext:15" {
+		t.Errorf("expected the migrated image to stay until the job succeeds, got %q", bench.Status.MigratedImage)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "MigrationStarted") {
+		t.Errorf("expected MigrationStarted event, got %q", event)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	if bench.Status.MigratedImage != "docker.io/frappe/erpnext:16" {
+		t.Errorf("expected the new image to be recorded, got %q", bench.Status.MigratedImage)
+	}
+	if benchMigrationPending(bench) {
+		t.Errorf("expected no pending migration after the job succeeded")
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "MigrationSucceeded") {
+		t.Errorf("expected MigrationSucceeded event, got %q", event)
+	}
+}
+
+func TestEnsureBenchMigrated_AppsChangedAndFailed(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Status.MigratedImage = "docker.io/frappe/erpnext:15"
+	bench.Status.InstalledApps = []string{"erpnext"}
+	bench.Spec.Apps = []vyogotechv1alpha1.AppSource{{Name: "erpnext"}, {Name: "hrms"}}
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	recorder := record.NewFakeRecorder(20)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: recorder}
+	ctx := context.Background()
+
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-migrate", Namespace: "default"}, job); err != nil {
+		t.Fatalf("expected a migration job for the new app, got %v", err)
+	}
+	<-recorder.Events
+
+	job.Status.Failed = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	cond := meta.FindStatusCondition(bench.Status.Conditions, migratingCondition)
+	if cond == nil || cond.Reason != migrationFailedReason {
+		t.Fatalf("expected Migrating reason %s, got %+v", migrationFailedReason, cond)
+	}
+	if !benchMigrationPending(bench) {
+		t.Errorf("expected a failed migration to stay pending")
+	}
+	if event := <-recorder.Events; !strings.Contains(event, migrationFailedReason) {
+		t.Errorf("expected MigrationFailed event, got %q", event)
+	}
+
+	// Going back to the apps the sites run clears the failure
+	bench.Spec.Apps = bench.Spec.Apps[:1]
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	if benchMigrationPending(bench) {
+		t.Errorf("expected no pending migration after reverting the apps")
+	}
+}
+
+func TestEnsureBenchMigrated_StaleJobKeepsAppChangePending(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Status.MigratedImage = "docker.io/frappe/erpnext:15"
+	bench.Status.InstalledApps = []string{"erpnext"}
+	bench.Spec.Apps = []vyogotechv1alpha1.AppSource{{Name: "erpnext"}, {Name: "hrms"}}
+	stale := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bench-migrate",
+			Namespace:   "default",
+			Annotations: map[string]string{migrationAnnotation: "docker.io/frappe/erpnext:14;erpnext"},
+		},
+	}
+	siteReconciler, c := newInitJobTestReconciler(site, bench, stale)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(stale), &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Fatalf("expected the stale job to be deleted, got %v", err)
+	}
+	// Without a pending migration updateBenchStatus would record hrms as installed and
+	// the next reconcile would find nothing to migrate
+	if !benchMigrationPending(bench) {
+		t.Fatalf("expected the migration to be pending while the stale job is replaced")
+	}
+
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(stale), job); err != nil {
+		t.Fatalf("expected a migration job for the app change: %v", err)
+	}
+	if job.Annotations[migrationAnnotation] != "docker.io/frappe/erpnext:15;erpnext,hrms" {
+		t.Errorf("expected the job to migrate to the new app set, got %q", job.Annotations[migrationAnnotation])
+	}
+}
+
+func TestEnsureBenchMigrated_VolumeSnapshot(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	bench.Status.MigratedImage = "docker.io/frappe/erpnext:15"
+	bench.Spec.FrappeVersion = "16"
+	bench.Spec.MigrationSnapshot = &vyogotechv1alpha1.VolumeSnapshotConfig{VolumeSnapshotClassName: "csi-snapclass"}
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	scheme.AddKnownTypeWithName(volumeSnapshotGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(volumeSnapshotGVK.GroupVersion().WithKind("VolumeSnapshotList"), &unstructured.UnstructuredList{})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "bench-migrate", Namespace: "default"}
+
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Fatalf("expected no migration job before the snapshot is cut, got %v", err)
+	}
+	snapshotName := bench.Status.MigrationSnapshot
+	if !strings.HasPrefix(snapshotName, "bench-pre-migrate-") {
+		t.Fatalf("expected status.migrationSnapshot to be recorded, got %q", snapshotName)
+	}
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	if err := c.Get(ctx, types.NamespacedName{Name: snapshotName, Namespace: "default"}, snapshot); err != nil {
+		t.Fatalf("expected VolumeSnapshot: %v", err)
+	}
+	if pvc, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName"); pvc != "bench-sites" {
+		t.Errorf("expected snapshot of bench-sites, got %q", pvc)
+	}
+	if cond := meta.FindStatusCondition(bench.Status.Conditions, migratingCondition); cond == nil || cond.Reason != "WaitingForVolumeSnapshot" {
+		t.Errorf("expected Migrating reason WaitingForVolumeSnapshot, got %+v", cond)
+	}
+	if !benchMigrationPending(bench) {
+		t.Error("expected the migration to be pending while the snapshot is cut")
+	}
+
+	utilruntime.Must(unstructured.SetNestedField(snapshot.Object, "2024-01-01T00:00:00Z", "status", "creationTime"))
+	if err := c.Update(ctx, snapshot); err != nil {
+		t.Fatalf("Update snapshot: %v", err)
+	}
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); err != nil {
+		t.Fatalf("expected the migration job once the snapshot is cut: %v", err)
+	}
+	if bench.Status.MigrationSnapshot != snapshotName {
+		t.Errorf("expected the snapshot to stay in status, got %q", bench.Status.MigrationSnapshot)
+	}
+}
+
+func TestEnsureBenchMigrated_VolumeSnapshotCRDMissing(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Status.MigratedImage = "docker.io/frappe/erpnext:15"
+	bench.Spec.FrappeVersion = "16"
+	bench.Spec.MigrationSnapshot = &vyogotechv1alpha1.VolumeSnapshotConfig{VolumeSnapshotClassName: "csi-snapclass"}
+	siteReconciler, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).WithObjects(site, bench).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return &meta.NoKindMatchError{GroupKind: volumeSnapshotGVK.GroupKind(), SearchedVersions: []string{"v1"}}
+		},
+	}).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	cond := meta.FindStatusCondition(bench.Status.Conditions, migratingCondition)
+	if cond == nil || cond.Reason != migrationFailedReason || !strings.Contains(cond.Message, "not installed") {
+		t.Fatalf("expected the migration to fail without the VolumeSnapshot CRD, got %+v", cond)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-migrate", Namespace: "default"}, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("expected no migration job without a snapshot, got %v", err)
+	}
+}
+
+func TestFrappeSiteReconciler_WaitsForBenchMigration(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Status.Phase = "Ready"
+	meta.SetStatusCondition(&bench.Status.Conditions, metav1.Condition{Type: migratingCondition, Status: metav1.ConditionTrue, Reason: "MigrationRunning"})
+	r, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+	r.Client = c
+	ctx := context.Background()
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "site", Namespace: "default"}})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if result.RequeueAfter != progressPollInterval {
+		t.Errorf("expected requeue after %v, got %v", progressPollInterval, result.RequeueAfter)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site", Namespace: "default"}, site); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	cond := meta.FindStatusCondition(site.Status.Conditions, "BenchReady")
+	if cond == nil || cond.Reason != "BenchMigrating" {
+		t.Errorf("expected BenchReady reason BenchMigrating, got %+v", cond)
+	}
+}
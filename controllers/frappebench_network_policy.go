@@ -0,0 +1,192 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Namespace labels the ingress controller is matched by when no selector is configured
+const (
+	namespaceNameLabel          = "kubernetes.io/metadata.name"
+	defaultIngressNamespace     = "ingress-nginx"
+	openShiftIngressPolicyGroup = "network.openshift.io/policy-group"
+	defaultKEDANamespace        = "keda"
+)
+
+// benchNetworkPolicySuffixes names the NetworkPolicies a bench owns, `<bench>-<suffix>`
+var benchNetworkPolicySuffixes = []string{"default-deny", "allow-bench", "allow-ingress", "allow-keda"}
+
+// networkPolicyEnabled reports whether spec.networkPolicy asks for NetworkPolicies
+func networkPolicyEnabled(bench *vyogotechv1alpha1.FrappeBench) bool {
+	return bench.Spec.NetworkPolicy != nil && bench.Spec.NetworkPolicy.Enabled
+}
+
+// ingressNamespaceSelector returns the namespaces allowed to reach the bench's web port
+func (r *FrappeBenchReconciler) ingressNamespaceSelector(bench *vyogotechv1alpha1.FrappeBench) *metav1.LabelSelector {
+	if selector := bench.Spec.NetworkPolicy.IngressNamespaceSelector; selector != nil {
+		return selector.DeepCopy()
+	}
+	if ref := bench.Spec.DomainConfig; ref != nil && ref.IngressControllerRef != nil && ref.IngressControllerRef.Namespace != "" {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: ref.IngressControllerRef.Namespace}}
+	}
+	if r.IsOpenShift {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{openShiftIngressPolicyGroup: "ingress"}}
+	}
+	return &metav1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: defaultIngressNamespace}}
+}
+
+// kedaNamespace returns the namespace KEDA's operator runs in, from the operator config's
+// kedaNamespace
+func kedaNamespace(operatorConfig *corev1.ConfigMap) string {
+	if operatorConfig != nil {
+		if ns := strings.TrimSpace(operatorConfig.Data["kedaNamespace"]); ns != "" {
+			return ns
+		}
+	}
+	return defaultKEDANamespace
+}
+
+// benchNetworkPolicies returns the NetworkPolicy specs of a bench by name suffix: every
+// bench pod denies ingress by default, accepts traffic from the bench's own pods and
+// jobs, the pods behind `<bench>-nginx` accept the ingress controller on its ports and
+// redis-queue accepts KEDA, whose redis scaler reads the queue lengths of autoscaled
+// workers. Other cross-namespace clients, e.g. a Prometheus scraper, stay blocked.
+func (r *FrappeBenchReconciler) benchNetworkPolicies(bench *vyogotechv1alpha1.FrappeBench, operatorConfig *corev1.ConfigMap) map[string]networkingv1.NetworkPolicySpec {
+	benchPods := metav1.LabelSelector{MatchLabels: r.benchLabels(bench)}
+	ingressOnly := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	webPods, webPort := r.nginxServiceTarget(bench)
+	protocol := corev1.ProtocolTCP
+	port := intstr.FromInt32(webPort)
+	webPorts := []networkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: &port}}
+	if nginxTLSEnabled(bench) {
+		httpsPort := intstr.FromInt32(nginxHTTPSPort)
+		webPorts = append(webPorts, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &httpsPort})
+	}
+	redisPort := intstr.FromInt32(6379)
+
+	return map[string]networkingv1.NetworkPolicySpec{
+		"default-deny": {
+			PodSelector: benchPods,
+			PolicyTypes: ingressOnly,
+		},
+		"allow-bench": {
+			PodSelector: benchPods,
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{
+					{PodSelector: benchPods.DeepCopy()},
+					{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{jobBenchLabel: jobLabelValue(bench.Name)}}},
+				},
+			}},
+			PolicyTypes: ingressOnly,
+		},
+		"allow-ingress": {
+			PodSelector: metav1.LabelSelector{MatchLabels: webPods},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From:  []networkingv1.NetworkPolicyPeer{{NamespaceSelector: r.ingressNamespaceSelector(bench)}},
+				Ports: webPorts,
+			}},
+			PolicyTypes: ingressOnly,
+		},
+		"allow-keda": {
+			PodSelector: metav1.LabelSelector{MatchLabels: r.componentLabels(bench, "redis-queue")},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{namespaceNameLabel: kedaNamespace(operatorConfig)},
+				}}},
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: &redisPort}},
+			}},
+			PolicyTypes: ingressOnly,
+		},
+	}
+}
+
+// ensureNetworkPolicies creates or updates the bench's NetworkPolicies when
+// spec.networkPolicy is enabled and deletes them otherwise
+func (r *FrappeBenchReconciler) ensureNetworkPolicies(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	logger := log.FromContext(ctx)
+	enabled := networkPolicyEnabled(bench)
+
+	var specs map[string]networkingv1.NetworkPolicySpec
+	if enabled {
+		operatorConfig, _ := r.getOperatorConfig(ctx, bench.Namespace)
+		specs = r.benchNetworkPolicies(bench, operatorConfig)
+	}
+	for _, suffix := range benchNetworkPolicySuffixes {
+		name := fmt.Sprintf("%s-%s", bench.Name, suffix)
+		existing := &networkingv1.NetworkPolicy{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: bench.Namespace}, existing)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		found := err == nil
+
+		if !enabled {
+			if !found {
+				continue
+			}
+			logger.Info("Deleting NetworkPolicy", "networkPolicy", name)
+			if err := r.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			continue
+		}
+
+		spec := specs[suffix]
+		if found {
+			if equality.Semantic.DeepEqual(existing.Spec, spec) {
+				continue
+			}
+			logger.Info("Updating NetworkPolicy", "networkPolicy", name)
+			existing.Spec = spec
+			if err := r.Update(ctx, existing); err != nil {
+				return err
+			}
+			continue
+		}
+
+		policy := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: bench.Namespace,
+				Labels:    r.benchLabels(bench),
+			},
+			Spec: spec,
+		}
+		if err := controllerutil.SetControllerReference(bench, policy, r.Scheme); err != nil {
+			return err
+		}
+		logger.Info("Creating NetworkPolicy", "networkPolicy", name)
+		if err := r.Create(ctx, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureNetworkPolicies(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "tenant-a", UID: "bench-uid"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			FrappeVersion: "15",
+			NetworkPolicy: &vyogotechv1alpha1.BenchNetworkPolicy{Enabled: true},
+			DomainConfig: &vyogotechv1alpha1.DomainConfig{
+				IngressControllerRef: &vyogotechv1alpha1.NamespacedName{Name: "traefik", Namespace: "traefik"},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	get := func(suffix string) (*networkingv1.NetworkPolicy, error) {
+		policy := &networkingv1.NetworkPolicy{}
+		err := c.Get(ctx, types.NamespacedName{Name: "bench-" + suffix, Namespace: "tenant-a"}, policy)
+		return policy, err
+	}
+
+	if err := r.ensureNetworkPolicies(ctx, bench); err != nil {
+		t.Fatalf("ensureNetworkPolicies: %v", err)
+	}
+	for _, suffix := range benchNetworkPolicySuffixes {
+		policy, err := get(suffix)
+		if err != nil {
+			t.Fatalf("expected NetworkPolicy bench-%s: %v", suffix, err)
+		}
+		if len(policy.OwnerReferences) != 1 || policy.OwnerReferences[0].Name != "bench" {
+			t.Errorf("expected bench-%s to be owned by the bench, got %+v", suffix, policy.OwnerReferences)
+		}
+	}
+
+	deny, _ := get("default-deny")
+	if deny.Spec.PodSelector.MatchLabels["bench"] != "bench" || len(deny.Spec.Ingress) != 0 {
+		t.Errorf("expected default-deny to select every bench pod without ingress rules, got %+v", deny.Spec)
+	}
+	allowBench, _ := get("allow-bench")
+	if peers := allowBench.Spec.Ingress[0].From; len(peers) != 2 || peers[1].PodSelector.MatchLabels[jobBenchLabel] != "bench" {
+		t.Errorf("expected allow-bench to admit bench pods and jobs, got %+v", peers)
+	}
+	ingress, _ := get("allow-ingress")
+	rule := ingress.Spec.Ingress[0]
+	if ingress.Spec.PodSelector.MatchLabels["component"] != "nginx" || rule.Ports[0].Port.IntVal != 8080 {
+		t.Errorf("expected allow-ingress to open nginx on 8080, got %+v", ingress.Spec)
+	}
+	if rule.From[0].NamespaceSelector.MatchLabels[namespaceNameLabel] != "traefik" {
+		t.Errorf("expected the ingress controller namespace from domainConfig, got %+v", rule.From[0].NamespaceSelector)
+	}
+	keda, _ := get("allow-keda")
+	rule = keda.Spec.Ingress[0]
+	if keda.Spec.PodSelector.MatchLabels["component"] != "redis-queue" || rule.Ports[0].Port.IntVal != 6379 {
+		t.Errorf("expected allow-keda to open redis-queue on 6379, got %+v", keda.Spec)
+	}
+	if rule.From[0].NamespaceSelector.MatchLabels[namespaceNameLabel] != defaultKEDANamespace {
+		t.Errorf("expected KEDA's namespace to default to keda, got %+v", rule.From[0].NamespaceSelector)
+	}
+
+	// The KEDA namespace comes from the operator config
+	operatorConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "frappe-operator-config", Namespace: "frappe-operator-system"},
+		Data:       map[string]string{"kedaNamespace": "openshift-keda"},
+	}
+	if err := c.Create(ctx, operatorConfig); err != nil {
+		t.Fatalf("create operator config: %v", err)
+	}
+	if err := r.ensureNetworkPolicies(ctx, bench); err != nil {
+		t.Fatalf("ensureNetworkPolicies: %v", err)
+	}
+	keda, _ = get("allow-keda")
+	if got := keda.Spec.Ingress[0].From[0].NamespaceSelector.MatchLabels[namespaceNameLabel]; got != "openshift-keda" {
+		t.Errorf("expected the configured KEDA namespace, got %q", got)
+	}
+
+	// Without nginx the web port moves to gunicorn
+	disabled := false
+	bench.Spec.Components = &vyogotechv1alpha1.BenchComponents{
+		Nginx:    &vyogotechv1alpha1.ComponentToggle{Enabled: &disabled},
+		SocketIO: &vyogotechv1alpha1.ComponentToggle{Enabled: &disabled},
+	}
+	if err := r.ensureNetworkPolicies(ctx, bench); err != nil {
+		t.Fatalf("ensureNetworkPolicies: %v", err)
+	}
+	ingress, _ = get("allow-ingress")
+	if ingress.Spec.PodSelector.MatchLabels["component"] != "gunicorn" || ingress.Spec.Ingress[0].Ports[0].Port.IntVal != 8000 {
+		t.Errorf("expected allow-ingress to follow nginx being disabled, got %+v", ingress.Spec)
+	}
+
+	// Disabling removes the policies
+	bench.Spec.NetworkPolicy.Enabled = false
+	if err := r.ensureNetworkPolicies(ctx, bench); err != nil {
+		t.Fatalf("ensureNetworkPolicies: %v", err)
+	}
+	for _, suffix := range benchNetworkPolicySuffixes {
+		if _, err := get(suffix); !errors.IsNotFound(err) {
+			t.Errorf("expected bench-%s to be deleted, got %v", suffix, err)
+		}
+	}
+}
+
+func TestIngressNamespaceSelector(t *testing.T) {
+	bench := &vyogotechv1alpha1.FrappeBench{
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{NetworkPolicy: &vyogotechv1alpha1.BenchNetworkPolicy{Enabled: true}},
+	}
+	if got := (&FrappeBenchReconciler{}).ingressNamespaceSelector(bench); got.MatchLabels[namespaceNameLabel] != defaultIngressNamespace {
+		t.Errorf("expected the ingress-nginx namespace by default, got %+v", got)
+	}
+	if got := (&FrappeBenchReconciler{IsOpenShift: true}).ingressNamespaceSelector(bench); got.MatchLabels[openShiftIngressPolicyGroup] != "ingress" {
+		t.Errorf("expected the OpenShift router policy group, got %+v", got)
+	}
+	custom := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "edge"}}
+	bench.Spec.NetworkPolicy.IngressNamespaceSelector = custom
+	if got := (&FrappeBenchReconciler{IsOpenShift: true}).ingressNamespaceSelector(bench); got.MatchLabels["team"] != "edge" {
+		t.Errorf("expected the configured selector, got %+v", got)
+	}
+}
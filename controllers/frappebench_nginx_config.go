@@ -0,0 +1,204 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Frappe server block of the bench nginx, rendered from spec.nginx
+const (
+	nginxConfigVolume = "nginx-config"
+	nginxConfigKey    = "frappe.conf.template"
+	// nginxConfigMountPath is the template nginx-entrypoint.sh renders /etc/nginx/conf.d/frappe.conf from
+	nginxConfigMountPath = "/templates/nginx/" + nginxConfigKey
+	// nginxConfigChecksumAnnotation rolls the nginx pods when the rendered config changes;
+	// subPath mounts don't pick up ConfigMap updates
+	nginxConfigChecksumAnnotation = "frappe.tech/nginx-config-checksum"
+
+	defaultNginxClientMaxBodySize = "50m"
+	defaultNginxProxyReadTimeout  = "120s"
+)
+
+// nginxConfigData returns spec.nginx with defaults filled in
+func nginxConfigData(bench *vyogotechv1alpha1.FrappeBench) scripts.NginxFrappeConfData {
+	data := scripts.NginxFrappeConfData{
+		ClientMaxBodySize: defaultNginxClientMaxBodySize,
+		ProxyReadTimeout:  defaultNginxProxyReadTimeout,
+	}
+	if cfg := bench.Spec.Nginx; cfg != nil {
+		if cfg.ClientMaxBodySize != "" {
+			data.ClientMaxBodySize = cfg.ClientMaxBodySize
+		}
+		if cfg.ProxyReadTimeout != "" {
+			data.ProxyReadTimeout = cfg.ProxyReadTimeout
+		}
+		data.ExtraConfig = cfg.ExtraConfig
+	}
+	return data
+}
+
+// nginxConfigEnabled reports whether the bench's nginx runs the operator-rendered server block
+func nginxConfigEnabled(bench *vyogotechv1alpha1.FrappeBench) bool {
+	return bench.Spec.Nginx != nil && componentEnabled(bench, "nginx")
+}
+
+// renderNginxConfig renders the frappe server block template for spec.nginx
+func renderNginxConfig(bench *vyogotechv1alpha1.FrappeBench) (string, error) {
+	config, err := scripts.RenderScript(scripts.NginxFrappeConf, nginxConfigData(bench))
+	if err != nil {
+		return "", fmt.Errorf("failed to render nginx config: %w", err)
+	}
+	return config, nil
+}
+
+// nginxConfigChecksum identifies a rendered nginx config in the pod template annotation
+func nginxConfigChecksum(config string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(config)))[:16]
+}
+
+// ensureNginxConfigMap keeps `<bench>-nginx-config` holding the rendered frappe server
+// block while spec.nginx is set and deletes it otherwise
+func (r *FrappeBenchReconciler) ensureNginxConfigMap(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	logger := log.FromContext(ctx)
+	name := fmt.Sprintf("%s-nginx-config", bench.Name)
+
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: bench.Namespace}, cm)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if !nginxConfigEnabled(bench) {
+		if !found {
+			return nil
+		}
+		logger.Info("Deleting NGINX config ConfigMap", "configMap", name)
+		if err := r.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	config, err := renderNginxConfig(bench)
+	if err != nil {
+		return err
+	}
+	if found {
+		if cm.Data[nginxConfigKey] == config {
+			return nil
+		}
+		logger.Info("Updating NGINX config ConfigMap", "configMap", name)
+		cm.Data = map[string]string{nginxConfigKey: config}
+		return r.Update(ctx, cm)
+	}
+
+	cm = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: bench.Namespace,
+			Labels:    r.benchLabels(bench),
+		},
+		Data: map[string]string{nginxConfigKey: config},
+	}
+	if err := controllerutil.SetControllerReference(bench, cm, r.Scheme); err != nil {
+		return err
+	}
+	logger.Info("Creating NGINX config ConfigMap", "configMap", name)
+	return r.Create(ctx, cm)
+}
+
+// syncNginxConfig mounts the rendered server block over the image's nginx template and
+// keeps the checksum annotation current, or removes both when spec.nginx is unset.
+// Returns true if the pod template changed.
+func syncNginxConfig(template *corev1.PodTemplateSpec, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	podSpec := &template.Spec
+	if len(podSpec.Containers) == 0 {
+		return false, nil
+	}
+	container := &podSpec.Containers[0]
+
+	checksum := ""
+	if nginxConfigEnabled(bench) {
+		config, err := renderNginxConfig(bench)
+		if err != nil {
+			return false, err
+		}
+		checksum = nginxConfigChecksum(config)
+	}
+
+	mounted := false
+	for _, m := range container.VolumeMounts {
+		if m.Name == nginxConfigVolume {
+			mounted = true
+		}
+	}
+	if template.Annotations[nginxConfigChecksumAnnotation] == checksum && mounted == (checksum != "") {
+		return false, nil
+	}
+
+	volumes := podSpec.Volumes[:0:0]
+	for _, v := range podSpec.Volumes {
+		if v.Name != nginxConfigVolume {
+			volumes = append(volumes, v)
+		}
+	}
+	volumeMounts := container.VolumeMounts[:0:0]
+	for _, m := range container.VolumeMounts {
+		if m.Name != nginxConfigVolume {
+			volumeMounts = append(volumeMounts, m)
+		}
+	}
+	delete(template.Annotations, nginxConfigChecksumAnnotation)
+
+	if checksum != "" {
+		volumes = append(volumes, corev1.Volume{
+			Name: nginxConfigVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: fmt.Sprintf("%s-nginx-config", bench.Name)},
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      nginxConfigVolume,
+			MountPath: nginxConfigMountPath,
+			SubPath:   nginxConfigKey,
+			ReadOnly:  true,
+		})
+		if template.Annotations == nil {
+			template.Annotations = make(map[string]string)
+		}
+		template.Annotations[nginxConfigChecksumAnnotation] = checksum
+	}
+
+	podSpec.Volumes = volumes
+	container.VolumeMounts = volumeMounts
+	return true, nil
+}
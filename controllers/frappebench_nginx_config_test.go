@@ -0,0 +1,137 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureNginxConfig(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default", UID: "bench-uid"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			FrappeVersion: "15",
+			Nginx: &vyogotechv1alpha1.NginxConfig{
+				ClientMaxBodySize: "100m",
+				ExtraConfig:       "    location /healthz { return 200; }",
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-nginx", Namespace: "default"}
+	cmKey := types.NamespacedName{Name: "bench-nginx-config", Namespace: "default"}
+
+	if err := r.ensureNginx(ctx, bench); err != nil {
+		t.Fatalf("ensureNginx: %v", err)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, cmKey, cm); err != nil {
+		t.Fatalf("Get ConfigMap: %v", err)
+	}
+	conf := cm.Data[nginxConfigKey]
+	for _, want := range []string{"client_max_body_size 100m;", "proxy_read_timeout 120s;", "location /healthz { return 200; }"} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("expected %q in the rendered config, got:\n%s", want, conf)
+		}
+	}
+
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	var mount *corev1.VolumeMount
+	for i, m := range deploy.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if m.Name == nginxConfigVolume {
+			mount = &deploy.Spec.Template.Spec.Containers[0].VolumeMounts[i]
+		}
+	}
+	if mount == nil || mount.MountPath != nginxConfigMountPath || mount.SubPath != nginxConfigKey {
+		t.Errorf("expected the config to be mounted over the nginx template, got %+v", mount)
+	}
+	checksum := deploy.Spec.Template.Annotations[nginxConfigChecksumAnnotation]
+	if checksum != nginxConfigChecksum(conf) {
+		t.Errorf("expected checksum %q, got %q", nginxConfigChecksum(conf), checksum)
+	}
+
+	// A spec change re-renders the ConfigMap and rolls the pods
+	bench.Spec.Nginx.ProxyReadTimeout = "600s"
+	if err := r.ensureNginx(ctx, bench); err != nil {
+		t.Fatalf("ensureNginx: %v", err)
+	}
+	if err := c.Get(ctx, cmKey, cm); err != nil {
+		t.Fatalf("Get ConfigMap: %v", err)
+	}
+	if !strings.Contains(cm.Data[nginxConfigKey], "proxy_read_timeout 600s;") {
+		t.Errorf("expected the ConfigMap to follow spec.nginx, got:\n%s", cm.Data[nginxConfigKey])
+	}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if deploy.Spec.Template.Annotations[nginxConfigChecksumAnnotation] == checksum {
+		t.Error("expected the checksum annotation to change with the config")
+	}
+
+	// Clearing spec.nginx goes back to the image's own config
+	bench.Spec.Nginx = nil
+	if err := r.ensureNginx(ctx, bench); err != nil {
+		t.Fatalf("ensureNginx: %v", err)
+	}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	for _, v := range deploy.Spec.Template.Spec.Volumes {
+		if v.Name == nginxConfigVolume {
+			t.Error("expected the nginx config volume to be removed")
+		}
+	}
+	if _, ok := deploy.Spec.Template.Annotations[nginxConfigChecksumAnnotation]; ok {
+		t.Error("expected the checksum annotation to be removed")
+	}
+	if err := c.Get(ctx, cmKey, &corev1.ConfigMap{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the config ConfigMap to be deleted, got %v", err)
+	}
+}
+
+func TestNginxTLSConfFollowsSpec(t *testing.T) {
+	bench := &vyogotechv1alpha1.FrappeBench{
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			Nginx: &vyogotechv1alpha1.NginxConfig{ClientMaxBodySize: "1g", ProxyReadTimeout: "300s"},
+		},
+	}
+	conf := nginxTLSConf(bench)
+	if !strings.Contains(conf, "client_max_body_size 1g;") || !strings.Contains(conf, "proxy_read_timeout 300s;") {
+		t.Errorf("expected the HTTPS listener to use spec.nginx limits, got:\n%s", conf)
+	}
+}
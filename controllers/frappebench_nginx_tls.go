@@ -0,0 +1,233 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// HTTPS listener of the bench nginx, enabled by spec.nginxTLS
+const (
+	nginxHTTPSPort        int32 = 8443
+	nginxTLSVolume              = "nginx-tls"
+	nginxTLSConfVolume          = "nginx-tls-conf"
+	nginxTLSCertDir             = "/etc/nginx/tls"
+	nginxTLSConfKey             = "frappe-tls.conf"
+	nginxTLSConfMountPath       = "/etc/nginx/conf.d/" + nginxTLSConfKey
+)
+
+// nginxTLSConf terminates TLS on 8443 and hands requests to the plain frappe server
+// block on 8080, which already trusts X-Forwarded-For from 127.0.0.1. Body size and read
+// timeout follow spec.nginx so both listeners accept the same requests.
+func nginxTLSConf(bench *vyogotechv1alpha1.FrappeBench) string {
+	data := nginxConfigData(bench)
+	return fmt.Sprintf(`server {
+    listen %d ssl;
+    ssl_certificate %s/tls.crt;
+    ssl_certificate_key %s/tls.key;
+    client_max_body_size %s;
+
+    location / {
+        proxy_pass http://127.0.0.1:8080;
+        proxy_http_version 1.1;
+        proxy_set_header Host $host;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto https;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection "upgrade";
+        proxy_read_timeout %s;
+    }
+}
+`, nginxHTTPSPort, nginxTLSCertDir, nginxTLSCertDir, data.ClientMaxBodySize, data.ProxyReadTimeout)
+}
+
+// nginxTLSEnabled reports whether the bench's nginx serves HTTPS
+func nginxTLSEnabled(bench *vyogotechv1alpha1.FrappeBench) bool {
+	return bench.Spec.NginxTLS != nil && bench.Spec.NginxTLS.SecretName != "" && componentEnabled(bench, "nginx")
+}
+
+// ensureNginxTLSConfigMap keeps `<bench>-nginx-tls` holding the HTTPS server block
+// while spec.nginxTLS is set and deletes it otherwise
+func (r *FrappeBenchReconciler) ensureNginxTLSConfigMap(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	logger := log.FromContext(ctx)
+	name := fmt.Sprintf("%s-nginx-tls", bench.Name)
+
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: bench.Namespace}, cm)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if !nginxTLSEnabled(bench) {
+		if !found {
+			return nil
+		}
+		logger.Info("Deleting NGINX TLS ConfigMap", "configMap", name)
+		if err := r.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	conf := nginxTLSConf(bench)
+	if found {
+		if cm.Data[nginxTLSConfKey] == conf {
+			return nil
+		}
+		cm.Data = map[string]string{nginxTLSConfKey: conf}
+		return r.Update(ctx, cm)
+	}
+
+	cm = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: bench.Namespace,
+			Labels:    r.benchLabels(bench),
+		},
+		Data: map[string]string{nginxTLSConfKey: conf},
+	}
+	if err := controllerutil.SetControllerReference(bench, cm, r.Scheme); err != nil {
+		return err
+	}
+	logger.Info("Creating NGINX TLS ConfigMap", "configMap", name)
+	return r.Create(ctx, cm)
+}
+
+// nginxServicePorts returns the ports of `<bench>-nginx`: http always, https while nginx serves TLS
+func nginxServicePorts(bench *vyogotechv1alpha1.FrappeBench, targetPort int32) []corev1.ServicePort {
+	ports := []corev1.ServicePort{{
+		Name:       "http",
+		Port:       8080,
+		TargetPort: intstr.FromInt32(targetPort),
+		Protocol:   corev1.ProtocolTCP,
+	}}
+	if nginxTLSEnabled(bench) {
+		ports = append(ports, corev1.ServicePort{
+			Name:       "https",
+			Port:       nginxHTTPSPort,
+			TargetPort: intstr.FromInt32(nginxHTTPSPort),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+	return ports
+}
+
+// servicePortsMatch compares the ports the operator sets, ignoring API server defaults
+func servicePortsMatch(existing, desired []corev1.ServicePort) bool {
+	if len(existing) != len(desired) {
+		return false
+	}
+	for i := range desired {
+		if existing[i].Name != desired[i].Name || existing[i].Port != desired[i].Port || existing[i].TargetPort != desired[i].TargetPort {
+			return false
+		}
+	}
+	return true
+}
+
+// syncNginxTLS adds or removes the HTTPS port, certificate and server block of the nginx
+// container to match spec.nginxTLS. Returns true if the pod spec changed.
+func syncNginxTLS(podSpec *corev1.PodSpec, bench *vyogotechv1alpha1.FrappeBench) bool {
+	if len(podSpec.Containers) == 0 {
+		return false
+	}
+	container := &podSpec.Containers[0]
+	enabled := nginxTLSEnabled(bench)
+
+	// Compare only what the operator sets; the API server defaults modes and protocols
+	secretName := ""
+	for _, v := range podSpec.Volumes {
+		if v.Name == nginxTLSVolume && v.Secret != nil {
+			secretName = v.Secret.SecretName
+		}
+	}
+	hasPort := false
+	for _, p := range container.Ports {
+		if p.Name == "https" {
+			hasPort = true
+		}
+	}
+	mounts := 0
+	for _, m := range container.VolumeMounts {
+		if m.Name == nginxTLSVolume || m.Name == nginxTLSConfVolume {
+			mounts++
+		}
+	}
+	if enabled && secretName == bench.Spec.NginxTLS.SecretName && hasPort && mounts == 2 {
+		return false
+	}
+	if !enabled && secretName == "" && !hasPort && mounts == 0 {
+		return false
+	}
+
+	volumes := podSpec.Volumes[:0:0]
+	for _, v := range podSpec.Volumes {
+		if v.Name != nginxTLSVolume && v.Name != nginxTLSConfVolume {
+			volumes = append(volumes, v)
+		}
+	}
+	volumeMounts := container.VolumeMounts[:0:0]
+	for _, m := range container.VolumeMounts {
+		if m.Name != nginxTLSVolume && m.Name != nginxTLSConfVolume {
+			volumeMounts = append(volumeMounts, m)
+		}
+	}
+	ports := container.Ports[:0:0]
+	for _, p := range container.Ports {
+		if p.Name != "https" {
+			ports = append(ports, p)
+		}
+	}
+
+	if enabled {
+		volumes = append(volumes,
+			corev1.Volume{
+				Name: nginxTLSVolume,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: bench.Spec.NginxTLS.SecretName},
+				},
+			},
+			corev1.Volume{
+				Name: nginxTLSConfVolume,
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: fmt.Sprintf("%s-nginx-tls", bench.Name)},
+					},
+				},
+			})
+		volumeMounts = append(volumeMounts,
+			corev1.VolumeMount{Name: nginxTLSVolume, MountPath: nginxTLSCertDir, ReadOnly: true},
+			corev1.VolumeMount{Name: nginxTLSConfVolume, MountPath: nginxTLSConfMountPath, SubPath: nginxTLSConfKey, ReadOnly: true})
+		ports = append(ports, corev1.ContainerPort{Name: "https", ContainerPort: nginxHTTPSPort, Protocol: corev1.ProtocolTCP})
+	}
+
+	podSpec.Volumes = volumes
+	container.VolumeMounts = volumeMounts
+	container.Ports = ports
+	return true
+}
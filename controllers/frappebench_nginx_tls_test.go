@@ -0,0 +1,129 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureNginxTLS(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default", UID: "bench-uid"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			FrappeVersion: "15",
+			NginxTLS:      &vyogotechv1alpha1.NginxTLSConfig{SecretName: "bench-nginx-cert"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-nginx", Namespace: "default"}
+
+	if err := r.ensureNginx(ctx, bench); err != nil {
+		t.Fatalf("ensureNginx: %v", err)
+	}
+	svc := &corev1.Service{}
+	if err := c.Get(ctx, key, svc); err != nil {
+		t.Fatalf("Get Service: %v", err)
+	}
+	if len(svc.Spec.Ports) != 2 || svc.Spec.Ports[1].Name != "https" || svc.Spec.Ports[1].Port != nginxHTTPSPort {
+		t.Errorf("expected http and https Service ports, got %+v", svc.Spec.Ports)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-nginx-tls", Namespace: "default"}, cm); err != nil {
+		t.Fatalf("Get ConfigMap: %v", err)
+	}
+	if cm.Data[nginxTLSConfKey] != nginxTLSConf(bench) {
+		t.Errorf("expected the HTTPS server block in the ConfigMap, got %q", cm.Data[nginxTLSConfKey])
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	if syncNginxTLS(&deploy.Spec.Template.Spec, bench) {
+		t.Error("expected the new Deployment to already serve HTTPS")
+	}
+	var certSecret string
+	for _, v := range deploy.Spec.Template.Spec.Volumes {
+		if v.Name == nginxTLSVolume {
+			certSecret = v.Secret.SecretName
+		}
+	}
+	if certSecret != "bench-nginx-cert" {
+		t.Errorf("expected the certificate Secret to be mounted, got %q", certSecret)
+	}
+
+	// Turning TLS off removes the listener again
+	bench.Spec.NginxTLS = nil
+	if err := r.ensureNginx(ctx, bench); err != nil {
+		t.Fatalf("ensureNginx: %v", err)
+	}
+	if err := c.Get(ctx, key, svc); err != nil {
+		t.Fatalf("Get Service: %v", err)
+	}
+	if len(svc.Spec.Ports) != 1 {
+		t.Errorf("expected only the http Service port, got %+v", svc.Spec.Ports)
+	}
+	if err := c.Get(ctx, key, deploy); err != nil {
+		t.Fatalf("Get Deployment: %v", err)
+	}
+	for _, p := range deploy.Spec.Template.Spec.Containers[0].Ports {
+		if p.Name == "https" {
+			t.Error("expected the https container port to be removed")
+		}
+	}
+	for _, v := range deploy.Spec.Template.Spec.Volumes {
+		if v.Name == nginxTLSVolume || v.Name == nginxTLSConfVolume {
+			t.Errorf("expected volume %s to be removed", v.Name)
+		}
+	}
+	err := c.Get(ctx, types.NamespacedName{Name: "bench-nginx-tls", Namespace: "default"}, &corev1.ConfigMap{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the TLS ConfigMap to be deleted, got %v", err)
+	}
+}
+
+func TestValidateComponentsNginxTLS(t *testing.T) {
+	disabled := false
+	bench := &vyogotechv1alpha1.FrappeBench{
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			NginxTLS: &vyogotechv1alpha1.NginxTLSConfig{SecretName: "cert"},
+			Components: &vyogotechv1alpha1.BenchComponents{
+				Nginx:    &vyogotechv1alpha1.ComponentToggle{Enabled: &disabled},
+				SocketIO: &vyogotechv1alpha1.ComponentToggle{Enabled: &disabled},
+			},
+		},
+	}
+	if err := validateComponents(bench); err == nil {
+		t.Error("expected nginxTLS without nginx to be rejected")
+	}
+}
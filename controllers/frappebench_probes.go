@@ -0,0 +1,115 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// livenessProbeGraceSeconds delays liveness probes past readiness probes so a slow
+// start is reported as not ready instead of being restarted
+const livenessProbeGraceSeconds = 20
+
+// componentProbeDefaults are the port, HTTP path and default timing of each probed component.
+// An empty path means the component is always probed on its TCP port.
+var componentProbeDefaults = map[string]struct {
+	port         int32
+	path         string
+	initialDelay int32
+	period       int32
+}{
+	"gunicorn": {port: 8000, path: "/api/method/ping", initialDelay: 10, period: 10},
+	"nginx":    {port: 8080, path: "/", initialDelay: 5, period: 10},
+	"socketio": {port: 9000, initialDelay: 5, period: 10},
+}
+
+// probeTiming returns the spec.probes override for a component
+func probeTiming(bench *vyogotechv1alpha1.FrappeBench, component string) *vyogotechv1alpha1.ProbeTiming {
+	probes := bench.Spec.Probes
+	if probes == nil {
+		return nil
+	}
+	switch component {
+	case "gunicorn":
+		return probes.Gunicorn
+	case "nginx":
+		return probes.Nginx
+	case "socketio":
+		return probes.SocketIO
+	}
+	return nil
+}
+
+// componentProbes returns the readiness and liveness probes for a bench component.
+// Every field the API server would default is set, so the probes compare equal to
+// the ones read back from an existing Deployment.
+func componentProbes(bench *vyogotechv1alpha1.FrappeBench, component string) (readiness, liveness *corev1.Probe) {
+	defaults := componentProbeDefaults[component]
+	initialDelay, period := defaults.initialDelay, defaults.period
+	if timing := probeTiming(bench, component); timing != nil {
+		if timing.InitialDelaySeconds != nil {
+			initialDelay = *timing.InitialDelaySeconds
+		}
+		if timing.PeriodSeconds != nil {
+			period = *timing.PeriodSeconds
+		}
+	}
+
+	handler := corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(defaults.port)},
+	}
+	// Frappe resolves the site from the Host header and answers 404 for unknown hosts,
+	// so HTTP probes are only usable with a site to ask for
+	if defaults.path != "" && bench.Spec.Probes != nil && bench.Spec.Probes.SiteName != "" {
+		handler = corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:        defaults.path,
+				Port:        intstr.FromInt32(defaults.port),
+				Scheme:      corev1.URISchemeHTTP,
+				HTTPHeaders: []corev1.HTTPHeader{{Name: "Host", Value: bench.Spec.Probes.SiteName}},
+			},
+		}
+	}
+
+	newProbe := func(delay int32) *corev1.Probe {
+		return &corev1.Probe{
+			ProbeHandler:        *handler.DeepCopy(),
+			InitialDelaySeconds: delay,
+			PeriodSeconds:       period,
+			TimeoutSeconds:      1,
+			SuccessThreshold:    1,
+			FailureThreshold:    3,
+		}
+	}
+	return newProbe(initialDelay), newProbe(initialDelay + livenessProbeGraceSeconds)
+}
+
+// syncComponentProbes brings the probes of an existing component container in line
+// with the bench spec, reporting whether anything changed
+func syncComponentProbes(container *corev1.Container, bench *vyogotechv1alpha1.FrappeBench, component string) bool {
+	readiness, liveness := componentProbes(bench, component)
+	if reflect.DeepEqual(container.ReadinessProbe, readiness) && reflect.DeepEqual(container.LivenessProbe, liveness) {
+		return false
+	}
+	container.ReadinessProbe = readiness
+	container.LivenessProbe = liveness
+	return true
+}
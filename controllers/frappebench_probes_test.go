@@ -0,0 +1,104 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestComponentProbes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+
+	getDeployment := func(name string) *appsv1.Deployment {
+		deploy := &appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, deploy); err != nil {
+			t.Fatalf("Get Deployment %s: %v", name, err)
+		}
+		return deploy
+	}
+
+	if err := r.ensureGunicorn(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicorn: %v", err)
+	}
+	if err := r.ensureSocketIO(ctx, bench); err != nil {
+		t.Fatalf("ensureSocketIO: %v", err)
+	}
+
+	// Without a site to ask for, gunicorn is probed on its TCP port
+	gunicorn := getDeployment("bench-gunicorn").Spec.Template.Spec.Containers[0]
+	if gunicorn.ReadinessProbe == nil || gunicorn.ReadinessProbe.TCPSocket == nil || gunicorn.ReadinessProbe.TCPSocket.Port.IntVal != 8000 {
+		t.Fatalf("expected a TCP readiness probe on 8000, got %+v", gunicorn.ReadinessProbe)
+	}
+	if gunicorn.LivenessProbe == nil || gunicorn.LivenessProbe.InitialDelaySeconds != 10+livenessProbeGraceSeconds {
+		t.Errorf("expected the liveness probe to start after the readiness probe, got %+v", gunicorn.LivenessProbe)
+	}
+	socketio := getDeployment("bench-socketio").Spec.Template.Spec.Containers[0]
+	if socketio.ReadinessProbe == nil || socketio.ReadinessProbe.TCPSocket == nil || socketio.ReadinessProbe.TCPSocket.Port.IntVal != 9000 {
+		t.Errorf("expected a TCP readiness probe on 9000, got %+v", socketio.ReadinessProbe)
+	}
+
+	// spec.probes switches gunicorn to the ping endpoint and overrides the timing
+	delay, period := int32(30), int32(5)
+	bench.Spec.Probes = &vyogotechv1alpha1.BenchProbes{
+		SiteName: "site.local",
+		Gunicorn: &vyogotechv1alpha1.ProbeTiming{InitialDelaySeconds: &delay, PeriodSeconds: &period},
+	}
+	if err := r.ensureGunicorn(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicorn: %v", err)
+	}
+	gunicorn = getDeployment("bench-gunicorn").Spec.Template.Spec.Containers[0]
+	probe := gunicorn.ReadinessProbe
+	if probe == nil || probe.HTTPGet == nil || probe.HTTPGet.Path != "/api/method/ping" || probe.HTTPGet.Port.IntVal != 8000 {
+		t.Fatalf("expected an HTTP ping readiness probe, got %+v", probe)
+	}
+	if len(probe.HTTPGet.HTTPHeaders) != 1 || probe.HTTPGet.HTTPHeaders[0].Value != "site.local" {
+		t.Errorf("expected the probe to ask for site.local, got %v", probe.HTTPGet.HTTPHeaders)
+	}
+	if probe.InitialDelaySeconds != 30 || probe.PeriodSeconds != 5 {
+		t.Errorf("expected the spec.probes timing, got delay=%d period=%d", probe.InitialDelaySeconds, probe.PeriodSeconds)
+	}
+
+	// Matching probes leave the deployment alone
+	before := getDeployment("bench-gunicorn").ResourceVersion
+	if err := r.ensureGunicorn(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicorn: %v", err)
+	}
+	if after := getDeployment("bench-gunicorn").ResourceVersion; after != before {
+		t.Errorf("expected no update when probes match, resourceVersion %s -> %s", before, after)
+	}
+}
@@ -19,18 +19,46 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	"github.com/vyogotech/frappe-operator/pkg/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-// ensureRedis ensures the Redis StatefulSet and Service exist
+var (
+	// Bounds for redisConfig.autoSizePerSite when minMemory/maxMemory are not set
+	defaultRedisAutoSizeMin = resource.MustParse("512Mi")
+	defaultRedisAutoSizeMax = resource.MustParse("8Gi")
+)
+
+const (
+	redisTypeDragonfly = "dragonfly"
+
+	defaultRedisImage     = "redis:7-alpine"
+	defaultDragonflyImage = "docker.dragonflydb.io/dragonflydb/dragonfly:v1.24.0"
+)
+
+// redisMaxMemoryPercent of an auto-sized cache container is handed to redis as maxmemory,
+// leaving headroom for fragmentation and client buffers so redis evicts instead of being OOM-killed
+const redisMaxMemoryPercent = 80
+
+// ensureRedis ensures the Redis StatefulSet and Service exist, unless the bench uses an
+// external redis
 func (r *FrappeBenchReconciler) ensureRedis(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	if redisAutoSize(bench) == nil || isExternalRedis(bench) {
+		bench.Status.RedisCacheSize = nil
+	}
+	if isExternalRedis(bench) {
+		return nil
+	}
+
 	// Create redis-cache and redis-queue services (socketio not needed for v15+)
 	if err := r.ensureRedisService(ctx, bench, "redis-cache"); err != nil {
 		return err
@@ -62,7 +90,7 @@ func (r *FrappeBenchReconciler) ensureRedisService(ctx context.Context, bench *v
 	logger.Info("Creating Redis Service", "service", svcName, "type", serviceType)
 
 	svc, err = resources.NewServiceBuilder(svcName, bench.Namespace).
-		WithLabels(r.benchLabels(bench)).
+		WithLabels(ownedLabels(bench, r.benchLabels(bench))).
 		WithSelector(r.componentLabels(bench, fmt.Sprintf("redis-%s", serviceType))).
 		WithPort("redis", 6379, 6379).
 		WithOwner(bench, r.Scheme).
@@ -91,22 +119,40 @@ func (r *FrappeBenchReconciler) ensureRedisStatefulSet(ctx context.Context, benc
 	}
 
 	replicas := int32(1)
-	redisImage := r.getRedisImage(bench)
+	redisResources := r.getRedisResources(bench)
 
-	container := resources.NewContainerBuilder("redis", redisImage).
-		WithCommand("redis-server").
-		WithArgs("--save", "", "--appendonly", "no", "--stop-writes-on-bgsave-error", "no").
-		WithPort("redis", 6379).
-		WithResources(r.getRedisResources(bench)).
-		WithSecurityContext(r.getRedisContainerSecurityContext(bench)).
-		Build()
+	// maxMemory only applies to the cache; evicting from the queue would drop jobs
+	var maxMemory int64
+	if role == "redis-cache" {
+		if redisAutoSize(bench) != nil {
+			memory, err := r.redisCacheAutoSize(ctx, bench)
+			if err != nil {
+				return err
+			}
+			redisResources = *redisResources.DeepCopy()
+			if redisResources.Requests == nil {
+				redisResources.Requests = corev1.ResourceList{}
+			}
+			if redisResources.Limits == nil {
+				redisResources.Limits = corev1.ResourceList{}
+			}
+			redisResources.Requests[corev1.ResourceMemory] = memory
+			redisResources.Limits[corev1.ResourceMemory] = memory
+			maxMemory = memory.Value() * redisMaxMemoryPercent / 100
+		} else if bench.Spec.RedisConfig != nil && bench.Spec.RedisConfig.MaxMemory != nil {
+			maxMemory = bench.Spec.RedisConfig.MaxMemory.Value()
+		}
+	}
+
+	container := r.redisContainer(bench, redisResources, maxMemory)
 
 	newSts, err := resources.NewStatefulSetBuilder(stsName, bench.Namespace).
-		WithLabels(r.benchLabels(bench)).
+		WithLabels(ownedLabels(bench, r.benchLabels(bench))).
 		WithSelector(r.componentLabels(bench, fmt.Sprintf("redis-%s", role))).
 		WithServiceName(stsName).
 		WithReplicas(replicas).
 		WithPodSecurityContext(r.getRedisPodSecurityContext(bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
 		WithContainer(container).
 		WithOwner(bench, r.Scheme).
 		Build()
@@ -126,6 +172,102 @@ func (r *FrappeBenchReconciler) ensureRedisStatefulSet(ctx context.Context, benc
 	return r.Update(ctx, sts)
 }
 
+// redisContainer builds the redis-server or Dragonfly container for a redis StatefulSet.
+// Both listen on 6379 without persistence, so the Services and the common_site_config
+// URLs are the same for either type. A non-zero maxMemory (bytes) makes the server
+// evict keys once full.
+func (r *FrappeBenchReconciler) redisContainer(bench *vyogotechv1alpha1.FrappeBench, redisResources corev1.ResourceRequirements, maxMemory int64) corev1.Container {
+	builder := resources.NewContainerBuilder("redis", r.getRedisImage(bench)).
+		WithPort("redis", 6379).
+		WithResources(redisResources).
+		WithSecurityContext(r.getRedisContainerSecurityContext(bench))
+
+	if isDragonfly(bench) {
+		args := []string{"--logtostderr", "--port=6379", "--dbfilename="}
+		if maxMemory > 0 {
+			args = append(args, "--maxmemory="+strconv.FormatInt(maxMemory, 10), "--cache_mode=true")
+		}
+		return builder.
+			WithCommand("dragonfly").
+			WithArgs(args...).
+			WithTCPReadinessProbe(6379, 5, 10).
+			Build()
+	}
+
+	args := []string{"--save", "", "--appendonly", "no", "--stop-writes-on-bgsave-error", "no"}
+	if maxMemory > 0 {
+		args = append(args,
+			"--maxmemory", strconv.FormatInt(maxMemory, 10),
+			"--maxmemory-policy", "allkeys-lru")
+	}
+	return builder.
+		WithCommand("redis-server").
+		WithArgs(args...).
+		Build()
+}
+
+// isDragonfly reports whether the bench runs Dragonfly instead of Redis
+func isDragonfly(bench *vyogotechv1alpha1.FrappeBench) bool {
+	return bench.Spec.RedisConfig != nil && bench.Spec.RedisConfig.Type == redisTypeDragonfly
+}
+
 func (r *FrappeBenchReconciler) getRedisAddress(bench *vyogotechv1alpha1.FrappeBench) string {
 	return fmt.Sprintf("%s-redis-cache:6379", bench.Name)
 }
+
+// redisAutoSize returns the bench's redisConfig.autoSizePerSite, or nil when disabled
+func redisAutoSize(bench *vyogotechv1alpha1.FrappeBench) *vyogotechv1alpha1.RedisAutoSize {
+	if bench.Spec.RedisConfig == nil {
+		return nil
+	}
+	return bench.Spec.RedisConfig.AutoSizePerSite
+}
+
+// computeRedisCacheMemory returns memoryPerSite times readySites, clamped to the configured bounds
+func computeRedisCacheMemory(autoSize *vyogotechv1alpha1.RedisAutoSize, readySites int32) resource.Quantity {
+	minMemory, maxMemory := defaultRedisAutoSizeMin, defaultRedisAutoSizeMax
+	if autoSize.MinMemory != nil {
+		minMemory = *autoSize.MinMemory
+	}
+	if autoSize.MaxMemory != nil {
+		maxMemory = *autoSize.MaxMemory
+	}
+
+	bytes := autoSize.MemoryPerSite.Value() * int64(readySites)
+	bytes = max(bytes, minMemory.Value())
+	bytes = min(bytes, maxMemory.Value())
+	return *resource.NewQuantity(bytes, resource.BinarySI)
+}
+
+// redisCacheAutoSize sizes the redis-cache from the bench's Ready sites and records the
+// result in status.redisCacheSize
+func (r *FrappeBenchReconciler) redisCacheAutoSize(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (resource.Quantity, error) {
+	readySites, err := r.countReadySites(ctx, bench)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("failed to count ready sites: %w", err)
+	}
+	memory := computeRedisCacheMemory(redisAutoSize(bench), readySites)
+
+	if previous := bench.Status.RedisCacheSize; previous == nil || previous.Memory.Cmp(memory) != 0 {
+		log.FromContext(ctx).Info("Sizing redis-cache from ready sites", "readySites", readySites, "memory", memory.String())
+	}
+	bench.Status.RedisCacheSize = &vyogotechv1alpha1.RedisAutoSizeStatus{ReadySites: readySites, Memory: memory}
+	return memory, nil
+}
+
+// countReadySites returns the number of Ready FrappeSites that reference the bench
+func (r *FrappeBenchReconciler) countReadySites(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (int32, error) {
+	siteList := &vyogotechv1alpha1.FrappeSiteList{}
+	if err := r.List(ctx, siteList, client.InNamespace(bench.Namespace)); err != nil {
+		return 0, err
+	}
+
+	var ready int32
+	for _, site := range siteList.Items {
+		if site.Spec.BenchRef != nil && site.Spec.BenchRef.Name == bench.Name &&
+			site.Status.Phase == vyogotechv1alpha1.FrappeSitePhaseReady {
+			ready++
+		}
+	}
+	return ready, nil
+}
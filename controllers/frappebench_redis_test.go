@@ -0,0 +1,179 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestComputeRedisCacheMemory(t *testing.T) {
+	maxMemory := resource.MustParse("1Gi")
+	autoSize := &vyogotechv1alpha1.RedisAutoSize{MemoryPerSite: resource.MustParse("256Mi"), MaxMemory: &maxMemory}
+
+	tests := []struct {
+		readySites int32
+		want       string
+	}{
+		{readySites: 0, want: "512Mi"},
+		{readySites: 3, want: "768Mi"},
+		{readySites: 10, want: "1Gi"},
+	}
+	for _, tt := range tests {
+		got := computeRedisCacheMemory(autoSize, tt.readySites)
+		if got.Cmp(resource.MustParse(tt.want)) != 0 {
+			t.Errorf("%d ready sites: expected %s, got %s", tt.readySites, tt.want, got.String())
+		}
+	}
+}
+
+func TestRedisCacheAutoSizedFromReadySites(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	bench.Spec.RedisConfig = &vyogotechv1alpha1.RedisConfig{
+		Type:            "redis",
+		AutoSizePerSite: &vyogotechv1alpha1.RedisAutoSize{MemoryPerSite: resource.MustParse("256Mi")},
+	}
+	objs := []client.Object{bench}
+	for i, phase := range []vyogotechv1alpha1.FrappeSitePhase{
+		vyogotechv1alpha1.FrappeSitePhaseReady,
+		vyogotechv1alpha1.FrappeSitePhaseReady,
+		vyogotechv1alpha1.FrappeSitePhaseReady,
+		vyogotechv1alpha1.FrappeSitePhaseProvisioning,
+	} {
+		objs = append(objs, &vyogotechv1alpha1.FrappeSite{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("site-%d", i), Namespace: "default"},
+			Spec:       vyogotechv1alpha1.FrappeSiteSpec{SiteName: fmt.Sprintf("site-%d.local", i), BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"}},
+			Status:     vyogotechv1alpha1.FrappeSiteStatus{Phase: phase},
+		})
+	}
+	siteReconciler, c := newInitJobTestReconciler(objs...)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureRedis(ctx, bench); err != nil {
+		t.Fatalf("ensureRedis: %v", err)
+	}
+
+	cache := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-cache", Namespace: "default"}, cache); err != nil {
+		t.Fatalf("Get redis-cache: %v", err)
+	}
+	container := cache.Spec.Template.Spec.Containers[0]
+	want := resource.MustParse("768Mi")
+	if got := container.Resources.Limits[corev1.ResourceMemory]; got.Cmp(want) != 0 {
+		t.Errorf("expected memory limit 768Mi for 3 ready sites, got %s", got.String())
+	}
+	if got := container.Resources.Requests[corev1.ResourceMemory]; got.Cmp(want) != 0 {
+		t.Errorf("expected memory request 768Mi for 3 ready sites, got %s", got.String())
+	}
+	if !slices.Contains(container.Args, "--maxmemory") || !slices.Contains(container.Args, "allkeys-lru") {
+		t.Errorf("expected redis maxmemory eviction settings, got %v", container.Args)
+	}
+
+	size := bench.Status.RedisCacheSize
+	if size == nil || size.ReadySites != 3 || size.Memory.Cmp(want) != 0 {
+		t.Errorf("expected status to report 3 ready sites and 768Mi, got %+v", size)
+	}
+
+	queue := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-queue", Namespace: "default"}, queue); err != nil {
+		t.Fatalf("Get redis-queue: %v", err)
+	}
+	if got := queue.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory]; got.Cmp(resource.MustParse("2Gi")) != 0 {
+		t.Errorf("expected redis-queue to keep its default size, got %s", got.String())
+	}
+
+	if reqs := r.autoSizedBenchForSite(ctx, objs[1]); len(reqs) != 1 || reqs[0].Name != "bench" {
+		t.Errorf("expected site changes to enqueue the auto-sized bench, got %v", reqs)
+	}
+}
+
+func TestRedisStatefulSetForType(t *testing.T) {
+	maxMemory := resource.MustParse("1Gi")
+	containers := map[string]corev1.Container{}
+	for _, redisType := range []string{"redis", "dragonfly"} {
+		_, bench := newInitJobTestObjects()
+		bench.Spec.RedisConfig = &vyogotechv1alpha1.RedisConfig{Type: redisType, MaxMemory: &maxMemory}
+		siteReconciler, c := newInitJobTestReconciler(bench)
+		r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+		ctx := context.Background()
+
+		if err := r.ensureRedis(ctx, bench); err != nil {
+			t.Fatalf("%s: ensureRedis: %v", redisType, err)
+		}
+		sts := &appsv1.StatefulSet{}
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-cache", Namespace: "default"}, sts); err != nil {
+			t.Fatalf("%s: Get redis-cache StatefulSet: %v", redisType, err)
+		}
+		container := sts.Spec.Template.Spec.Containers[0]
+		if len(container.Ports) != 1 || container.Ports[0].ContainerPort != 6379 {
+			t.Errorf("%s: expected port 6379, got %+v", redisType, container.Ports)
+		}
+		containers[redisType] = container
+	}
+
+	redis, dragonfly := containers["redis"], containers["dragonfly"]
+	if redis.Image != "redis:7-alpine" || dragonfly.Image != "docker.dragonflydb.io/dragonflydb/dragonfly:v1.24.0" {
+		t.Errorf("expected default images, got %q and %q", redis.Image, dragonfly.Image)
+	}
+	if !slices.Equal(redis.Command, []string{"redis-server"}) || !slices.Equal(dragonfly.Command, []string{"dragonfly"}) {
+		t.Errorf("expected redis-server and dragonfly commands, got %v and %v", redis.Command, dragonfly.Command)
+	}
+	if !slices.Contains(redis.Args, "--maxmemory") || !slices.Contains(redis.Args, "1073741824") {
+		t.Errorf("expected redis to get --maxmemory from maxMemory, got %v", redis.Args)
+	}
+	if !slices.Contains(dragonfly.Args, "--maxmemory=1073741824") || !slices.Contains(dragonfly.Args, "--port=6379") {
+		t.Errorf("expected dragonfly --maxmemory and --port flags, got %v", dragonfly.Args)
+	}
+	if dragonfly.ReadinessProbe == nil || dragonfly.ReadinessProbe.TCPSocket == nil {
+		t.Errorf("expected a TCP readiness probe on dragonfly, got %+v", dragonfly.ReadinessProbe)
+	}
+}
+
+func TestRedisQueueIgnoresMaxMemory(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	maxMemory := resource.MustParse("1Gi")
+	bench.Spec.RedisConfig = &vyogotechv1alpha1.RedisConfig{Type: "dragonfly", MaxMemory: &maxMemory}
+	siteReconciler, c := newInitJobTestReconciler(bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureRedis(ctx, bench); err != nil {
+		t.Fatalf("ensureRedis: %v", err)
+	}
+	sts := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-queue", Namespace: "default"}, sts); err != nil {
+		t.Fatalf("Get redis-queue StatefulSet: %v", err)
+	}
+	for _, arg := range sts.Spec.Template.Spec.Containers[0].Args {
+		if arg == "--cache_mode=true" || strings.HasPrefix(arg, "--maxmemory") {
+			t.Errorf("redis-queue must not evict keys, got arg %q", arg)
+		}
+	}
+}
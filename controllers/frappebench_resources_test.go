@@ -195,6 +195,89 @@ func TestFrappeBenchReconciler_Resources(t *testing.T) {
 			t.Error("Worker default deployment not created")
 		}
 	})
+
+	t.Run("combinedWebService", func(t *testing.T) {
+		combined := bench.DeepCopy()
+		combined.Spec.CombinedWebService = true
+		client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(combined).Build()
+		r := &FrappeBenchReconciler{Client: client, Scheme: scheme}
+		ctx := context.TODO()
+
+		if err := r.ensureGunicorn(ctx, combined); err != nil {
+			t.Fatalf("ensureGunicorn failed: %v", err)
+		}
+		if err := r.ensureNginx(ctx, combined); err != nil {
+			t.Fatalf("ensureNginx failed: %v", err)
+		}
+		if err := r.ensureSocketIO(ctx, combined); err != nil {
+			t.Fatalf("ensureSocketIO failed: %v", err)
+		}
+
+		svc := &corev1.Service{}
+		if err := client.Get(ctx, types.NamespacedName{Name: benchName + "-web", Namespace: namespace}, svc); err != nil {
+			t.Fatalf("Combined web service not created: %v", err)
+		}
+		ports := map[string]string{}
+		for _, p := range svc.Spec.Ports {
+			ports[p.Name] = p.TargetPort.String()
+		}
+		if ports["http"] != "http" || ports["socketio"] != "socketio" {
+			t.Errorf("Expected named target ports http and socketio, got %v", ports)
+		}
+		for _, name := range []string{"-gunicorn", "-socketio"} {
+			if err := client.Get(ctx, types.NamespacedName{Name: benchName + name, Namespace: namespace}, &corev1.Service{}); err == nil {
+				t.Errorf("Separate service %s should not exist in combined mode", benchName+name)
+			}
+		}
+
+		for _, name := range []string{"-gunicorn", "-socketio"} {
+			deploy := &appsv1.Deployment{}
+			if err := client.Get(ctx, types.NamespacedName{Name: benchName + name, Namespace: namespace}, deploy); err != nil {
+				t.Fatalf("Deployment %s not created: %v", name, err)
+			}
+			if deploy.Spec.Template.Labels[webBackendLabel] != "true" {
+				t.Errorf("Deployment %s pods missing %s label", name, webBackendLabel)
+			}
+		}
+
+		nginx := &appsv1.Deployment{}
+		if err := client.Get(ctx, types.NamespacedName{Name: benchName + "-nginx", Namespace: namespace}, nginx); err != nil {
+			t.Fatalf("NGINX deployment not created: %v", err)
+		}
+		env := map[string]string{}
+		for _, e := range nginx.Spec.Template.Spec.Containers[0].Env {
+			env[e.Name] = e.Value
+		}
+		if env["BACKEND"] != benchName+"-web:8000" || env["SOCKETIO"] != benchName+"-web:9000" {
+			t.Errorf("NGINX upstreams not pointed at combined service: %v", env)
+		}
+		if nginx.Spec.Template.Labels[webBackendLabel] != "" {
+			t.Error("NGINX pods must not be selected by the combined web service")
+		}
+
+		// Switching back restores separate services and upstreams
+		combined.Spec.CombinedWebService = false
+		if err := r.ensureGunicorn(ctx, combined); err != nil {
+			t.Fatalf("ensureGunicorn failed: %v", err)
+		}
+		if err := r.ensureNginx(ctx, combined); err != nil {
+			t.Fatalf("ensureNginx failed: %v", err)
+		}
+		if err := r.ensureSocketIO(ctx, combined); err != nil {
+			t.Fatalf("ensureSocketIO failed: %v", err)
+		}
+		if err := client.Get(ctx, types.NamespacedName{Name: benchName + "-web", Namespace: namespace}, &corev1.Service{}); err == nil {
+			t.Error("Combined web service should be removed in separate mode")
+		}
+		if err := client.Get(ctx, types.NamespacedName{Name: benchName + "-nginx", Namespace: namespace}, nginx); err != nil {
+			t.Fatalf("Get NGINX deployment: %v", err)
+		}
+		for _, e := range nginx.Spec.Template.Spec.Containers[0].Env {
+			if e.Name == "BACKEND" && e.Value != benchName+"-gunicorn:8000" {
+				t.Errorf("Expected BACKEND %s-gunicorn:8000, got %s", benchName, e.Value)
+			}
+		}
+	})
 }
 
 func TestFrappeBenchReconciler_Helpers(t *testing.T) {
@@ -490,6 +573,53 @@ func TestFrappeBenchReconciler_Helpers(t *testing.T) {
 		}
 	})
 
+	t.Run("resourceProfile", func(t *testing.T) {
+		r := &FrappeBenchReconciler{Scheme: scheme}
+		for profile, want := range map[string]vyogotechv1alpha1.ComponentResources{
+			vyogotechv1alpha1.ResourceProfileDefault:    vyogotechv1alpha1.DefaultComponentResources(),
+			vyogotechv1alpha1.ResourceProfileProduction: vyogotechv1alpha1.ProductionComponentResources(),
+		} {
+			bench := &vyogotechv1alpha1.FrappeBench{Spec: vyogotechv1alpha1.FrappeBenchSpec{ResourceProfile: profile}}
+			for name, got := range map[string]struct {
+				res  corev1.ResourceRequirements
+				want *vyogotechv1alpha1.ResourceRequirements
+			}{
+				"gunicorn":  {r.getGunicornResources(bench), want.Gunicorn},
+				"nginx":     {r.getNginxResources(bench), want.Nginx},
+				"scheduler": {r.getSchedulerResources(bench), want.Scheduler},
+				"socketio":  {r.getSocketIOResources(bench), want.Socketio},
+				"worker":    {r.getWorkerDefaultResources(bench), want.WorkerDefault},
+				"long":      {r.getWorkerLongResources(bench), want.WorkerLong},
+				"short":     {r.getWorkerShortResources(bench), want.WorkerShort},
+			} {
+				if got.res.Requests.Cpu().Cmp(*got.want.Requests.Cpu()) != 0 || got.res.Limits.Memory().Cmp(*got.want.Limits.Memory()) != 0 {
+					t.Errorf("%s profile %s: expected %v, got %v", profile, name, got.want, got.res)
+				}
+			}
+		}
+	})
+
+	t.Run("componentResources override the profile", func(t *testing.T) {
+		bench := &vyogotechv1alpha1.FrappeBench{
+			Spec: vyogotechv1alpha1.FrappeBenchSpec{
+				ResourceProfile: vyogotechv1alpha1.ResourceProfileProduction,
+				ComponentResources: &vyogotechv1alpha1.ComponentResources{
+					Gunicorn: &vyogotechv1alpha1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")},
+					},
+				},
+			},
+		}
+		r := &FrappeBenchReconciler{Scheme: scheme}
+		if res := r.getGunicornResources(bench); res.Requests.Cpu().Cmp(resource.MustParse("3")) != 0 {
+			t.Errorf("expected the componentResources gunicorn request 3, got %s", res.Requests.Cpu())
+		}
+		production := vyogotechv1alpha1.ProductionComponentResources()
+		if res := r.getNginxResources(bench); res.Requests.Cpu().Cmp(*production.Nginx.Requests.Cpu()) != 0 {
+			t.Errorf("expected the production nginx request %s, got %s", production.Nginx.Requests.Cpu(), res.Requests.Cpu())
+		}
+	})
+
 	t.Run("getRedisResources default", func(t *testing.T) {
 		bench := &vyogotechv1alpha1.FrappeBench{
 			ObjectMeta: metav1.ObjectMeta{Name: benchName, Namespace: namespace},
@@ -573,3 +703,52 @@ func TestEnsureRedisStatefulSet_UpdateImmutability(t *testing.T) {
 		t.Errorf("Expected bench label to be updated, got %v. Metadata: %+v", updatedSts.Labels, updatedSts.ObjectMeta)
 	}
 }
+
+func TestBenchDeployments_PodConfigAppliedToExisting(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-bench", Namespace: "test-ns"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "v15"},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: client, Scheme: scheme}
+	ctx := context.TODO()
+
+	ensure := map[string]func() error{
+		"test-bench-gunicorn":  func() error { return r.ensureGunicornDeployment(ctx, bench) },
+		"test-bench-nginx":     func() error { return r.ensureNginxDeployment(ctx, bench) },
+		"test-bench-socketio":  func() error { return r.ensureSocketIODeployment(ctx, bench) },
+		"test-bench-scheduler": func() error { return r.ensureScheduler(ctx, bench) },
+	}
+	for name, fn := range ensure {
+		if err := fn(); err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+	}
+
+	// PodConfig added after the deployments exist
+	bench.Spec.PodConfig = &vyogotechv1alpha1.PodConfig{
+		NodeSelector: map[string]string{"kubernetes.io/arch": "amd64"},
+		Tolerations:  []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+		GeoTag:       &vyogotechv1alpha1.GeoTagConfig{Zone: "us-east-1a"},
+	}
+	for name, fn := range ensure {
+		if err := fn(); err != nil {
+			t.Fatalf("updating %s: %v", name, err)
+		}
+		deploy := &appsv1.Deployment{}
+		if err := client.Get(ctx, types.NamespacedName{Name: name, Namespace: "test-ns"}, deploy); err != nil {
+			t.Fatalf("Get %s: %v", name, err)
+		}
+		spec := deploy.Spec.Template.Spec
+		if spec.NodeSelector["kubernetes.io/arch"] != "amd64" || spec.NodeSelector["topology.kubernetes.io/zone"] != "us-east-1a" {
+			t.Errorf("%s: expected nodeSelector from pod config, got %v", name, spec.NodeSelector)
+		}
+		if len(spec.Tolerations) != 1 {
+			t.Errorf("%s: expected tolerations from pod config, got %v", name, spec.Tolerations)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// sharedAssetsVolume is the pod volume of spec.sharedAppsPVC
+	sharedAssetsVolume = "shared-assets"
+	// sharedAssetsMountPath is where spec.sharedAppsPVC is mounted in the bench pods
+	sharedAssetsMountPath = sitesMountPath + "/assets"
+)
+
+// sharedAssetsEnvValue is the SHARED_ASSETS value of the init jobs; "1" skips copying
+// the image's assets_cache into the sites PVC
+func sharedAssetsEnvValue(bench *vyogotechv1alpha1.FrappeBench) string {
+	if bench.Spec.SharedAppsPVC != "" {
+		return "1"
+	}
+	return "0"
+}
+
+// sharedAppsPVCProblem explains why spec.sharedAppsPVC can't be mounted, or returns ""
+// when it is unset or names a ReadOnlyMany PVC
+func (r *FrappeBenchReconciler) sharedAppsPVCProblem(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (string, error) {
+	name := bench.Spec.SharedAppsPVC
+	if name == "" {
+		return "", nil
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: bench.Namespace}, pvc); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("sharedAppsPVC %s does not exist", name), nil
+		}
+		return "", err
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadOnlyMany {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("sharedAppsPVC %s must have the ReadOnlyMany access mode, it has %v", name, pvc.Spec.AccessModes), nil
+}
+
+// syncSharedAssets mounts spec.sharedAppsPVC read-only at sites/assets in a bench pod,
+// or removes the mount once the field is cleared, reporting whether anything changed
+func syncSharedAssets(podSpec *corev1.PodSpec, bench *vyogotechv1alpha1.FrappeBench) bool {
+	if len(podSpec.Containers) == 0 {
+		return false
+	}
+	container := &podSpec.Containers[0]
+	claimName := bench.Spec.SharedAppsPVC
+
+	// Compare only what the operator sets; the API server defaults the rest
+	current := ""
+	for _, v := range podSpec.Volumes {
+		if v.Name == sharedAssetsVolume && v.PersistentVolumeClaim != nil {
+			current = v.PersistentVolumeClaim.ClaimName
+		}
+	}
+	mounted := false
+	for _, m := range container.VolumeMounts {
+		if m.Name == sharedAssetsVolume {
+			mounted = true
+		}
+	}
+	if current == claimName && mounted == (claimName != "") {
+		return false
+	}
+
+	volumes := podSpec.Volumes[:0:0]
+	for _, v := range podSpec.Volumes {
+		if v.Name != sharedAssetsVolume {
+			volumes = append(volumes, v)
+		}
+	}
+	volumeMounts := container.VolumeMounts[:0:0]
+	for _, m := range container.VolumeMounts {
+		if m.Name != sharedAssetsVolume {
+			volumeMounts = append(volumeMounts, m)
+		}
+	}
+	if claimName != "" {
+		volumes = append(volumes, corev1.Volume{
+			Name: sharedAssetsVolume,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: claimName,
+					ReadOnly:  true,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      sharedAssetsVolume,
+			MountPath: sharedAssetsMountPath,
+			ReadOnly:  true,
+		})
+	}
+	podSpec.Volumes = volumes
+	container.VolumeMounts = volumeMounts
+	return true
+}
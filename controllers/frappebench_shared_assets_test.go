@@ -0,0 +1,129 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSharedAppsPVCProblem(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	pvc := func(name string, mode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{mode}},
+		}
+	}
+	siteReconciler, c := newInitJobTestReconciler(site, bench, pvc("assets", corev1.ReadOnlyMany), pvc("rwo", corev1.ReadWriteOnce))
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		claim string
+		want  string
+	}{
+		{"", ""},
+		{"assets", ""},
+		{"rwo", "must have the ReadOnlyMany access mode"},
+		{"missing", "does not exist"},
+	} {
+		bench.Spec.SharedAppsPVC = tc.claim
+		problem, err := r.sharedAppsPVCProblem(ctx, bench)
+		if err != nil {
+			t.Fatalf("%q: sharedAppsPVCProblem: %v", tc.claim, err)
+		}
+		if (tc.want == "") != (problem == "") || !strings.Contains(problem, tc.want) {
+			t.Errorf("%q: expected problem %q, got %q", tc.claim, tc.want, problem)
+		}
+	}
+}
+
+func TestSyncSharedAssets(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	deployments := map[string]func() error{
+		"bench-gunicorn":  func() error { return r.ensureGunicornDeployment(ctx, bench) },
+		"bench-nginx":     func() error { return r.ensureNginxDeployment(ctx, bench) },
+		"bench-scheduler": func() error { return r.ensureScheduler(ctx, bench) },
+	}
+	check := func(name, wantClaim string) {
+		t.Helper()
+		deploy := &appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, deploy); err != nil {
+			t.Fatalf("Get %s: %v", name, err)
+		}
+		podSpec := deploy.Spec.Template.Spec
+		claim := ""
+		for _, v := range podSpec.Volumes {
+			if v.Name == sharedAssetsVolume {
+				claim = v.PersistentVolumeClaim.ClaimName
+				if !v.PersistentVolumeClaim.ReadOnly {
+					t.Errorf("%s: expected a read-only claim", name)
+				}
+			}
+		}
+		if claim != wantClaim {
+			t.Errorf("%s: expected shared assets claim %q, got %q", name, wantClaim, claim)
+		}
+		mounted := false
+		for _, m := range podSpec.Containers[0].VolumeMounts {
+			if m.Name == sharedAssetsVolume {
+				mounted = m.MountPath == sharedAssetsMountPath && m.ReadOnly
+			}
+		}
+		if mounted != (wantClaim != "") {
+			t.Errorf("%s: expected read-only mount at %s: %v", name, sharedAssetsMountPath, wantClaim != "")
+		}
+	}
+
+	// New deployments mount the shared assets
+	bench.Spec.SharedAppsPVC = "assets"
+	for name, ensure := range deployments {
+		if err := ensure(); err != nil {
+			t.Fatalf("ensure %s: %v", name, err)
+		}
+		check(name, "assets")
+	}
+
+	// Clearing the field removes the mount from existing deployments
+	bench.Spec.SharedAppsPVC = ""
+	for name, ensure := range deployments {
+		if err := ensure(); err != nil {
+			t.Fatalf("ensure %s: %v", name, err)
+		}
+		check(name, "")
+	}
+
+	if sharedAssetsEnvValue(bench) != "0" {
+		t.Error("expected init jobs to sync assets without a shared assets PVC")
+	}
+	bench.Spec.SharedAppsPVC = "assets"
+	if sharedAssetsEnvValue(bench) != "1" {
+		t.Error("expected init jobs to skip the asset sync with a shared assets PVC")
+	}
+}
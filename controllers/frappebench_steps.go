@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ensureStep is one bench component reconciled by the bench controller
+type ensureStep struct {
+	// name is used in logs, events and errors (e.g. "Redis")
+	name string
+	// event reasons are "<reason>Ready" and "<reason>Failed"
+	reason       string
+	readyMessage string
+	ensure       func(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error
+}
+
+// independentEnsureSteps returns the components that only depend on the bench being
+// initialized and can therefore be reconciled concurrently
+func (r *FrappeBenchReconciler) independentEnsureSteps() []ensureStep {
+	return []ensureStep{
+		{name: "Redis", reason: "Redis", readyMessage: "Redis service created", ensure: r.ensureRedis},
+		{name: "Gunicorn", reason: "Gunicorn", readyMessage: "Gunicorn deployment created", ensure: r.ensureGunicorn},
+		{name: "NGINX", reason: "Nginx", readyMessage: "NGINX deployment created", ensure: r.ensureNginx},
+		{name: "Socket.IO", reason: "SocketIO", readyMessage: "Socket.IO deployment created", ensure: r.ensureSocketIO},
+		{name: "NetworkPolicies", reason: "NetworkPolicy", readyMessage: "NetworkPolicies reconciled", ensure: r.ensureNetworkPolicies},
+	}
+}
+
+// runEnsureSteps runs the given steps concurrently and returns the errors of every failed
+// step joined together. With SequentialEnsure set the steps run one after another in order
+// and the first failure stops the rest, matching the original reconcile behaviour.
+func (r *FrappeBenchReconciler) runEnsureSteps(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, steps []ensureStep) error {
+	if r.SequentialEnsure {
+		for _, step := range steps {
+			if err := r.runEnsureStep(ctx, bench, step); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// A plain group (no shared context) so one failure doesn't cancel the other steps
+	// and every error can be reported
+	errs := make([]error, len(steps))
+	var g errgroup.Group
+	for i, step := range steps {
+		g.Go(func() error {
+			errs[i] = r.runEnsureStep(ctx, bench, step)
+			return errs[i]
+		})
+	}
+	_ = g.Wait()
+
+	return errors.Join(errs...)
+}
+
+// runEnsureStep runs a single step and records its outcome as a log line and event
+func (r *FrappeBenchReconciler) runEnsureStep(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, step ensureStep) error {
+	if err := step.ensure(ctx, bench); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to ensure "+step.name)
+		r.Recorder.Event(bench, corev1.EventTypeWarning, step.reason+"Failed", fmt.Sprintf("Failed to ensure %s: %v", step.name, err))
+		return fmt.Errorf("failed to ensure %s: %w", step.name, err)
+	}
+	r.Recorder.Event(bench, corev1.EventTypeNormal, step.reason+"Ready", step.readyMessage)
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRunEnsureSteps(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	errRedis := errors.New("redis down")
+	errNginx := errors.New("nginx down")
+
+	var ran atomic.Int32
+	steps := []ensureStep{
+		{name: "Redis", reason: "Redis", ensure: func(context.Context, *vyogotechv1alpha1.FrappeBench) error { ran.Add(1); return errRedis }},
+		{name: "Gunicorn", reason: "Gunicorn", ensure: func(context.Context, *vyogotechv1alpha1.FrappeBench) error { ran.Add(1); return nil }},
+		{name: "NGINX", reason: "Nginx", ensure: func(context.Context, *vyogotechv1alpha1.FrappeBench) error { ran.Add(1); return errNginx }},
+	}
+
+	r := &FrappeBenchReconciler{Recorder: record.NewFakeRecorder(20)}
+	err := r.runEnsureSteps(context.Background(), bench, steps)
+	if !errors.Is(err, errRedis) || !errors.Is(err, errNginx) {
+		t.Errorf("expected both step errors to be aggregated, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "failed to ensure NGINX") {
+		t.Errorf("expected the failing step to be named, got %v", err)
+	}
+	if ran.Load() != 3 {
+		t.Errorf("expected every step to run despite failures, ran %d", ran.Load())
+	}
+
+	// Sequential mode stops at the first failure
+	ran.Store(0)
+	r.SequentialEnsure = true
+	err = r.runEnsureSteps(context.Background(), bench, steps)
+	if !errors.Is(err, errRedis) || errors.Is(err, errNginx) {
+		t.Errorf("expected only the first error in sequential mode, got %v", err)
+	}
+	if ran.Load() != 1 {
+		t.Errorf("expected sequential mode to stop after the first step, ran %d", ran.Load())
+	}
+}
+
+func TestIndependentEnsureStepsCreateComponents(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.runEnsureSteps(ctx, bench, r.independentEnsureSteps()); err != nil {
+		t.Fatalf("runEnsureSteps: %v", err)
+	}
+	for _, name := range []string{"bench-gunicorn", "bench-nginx", "bench-socketio"} {
+		deploy := &appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, deploy); err != nil {
+			t.Errorf("expected Deployment %s to be created concurrently: %v", name, err)
+		}
+	}
+	redis := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-cache", Namespace: "default"}, redis); err != nil {
+		t.Errorf("expected redis cache StatefulSet: %v", err)
+	}
+}
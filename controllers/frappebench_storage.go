@@ -26,12 +26,26 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+const (
+	// sitesMountPath is where the bench sites PVC is mounted in every bench pod and job
+	sitesMountPath = "/home/frappe/frappe-bench/sites"
+	// sitesVolumeSubPath is the directory inside the sites PVC that holds the bench sites.
+	// All deployments and jobs must mount the same subPath so they see the same
+	// apps.txt, common_site_config.json and site directories.
+	sitesVolumeSubPath = "frappe-sites"
+
+	// storageResizingCondition tracks an expansion of the sites PVC
+	storageResizingCondition = "StorageResizing"
+)
+
 // ensureBenchStorage ensures the PVC for the bench exists
 func (r *FrappeBenchReconciler) ensureBenchStorage(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
 	logger := log.FromContext(ctx)
@@ -42,7 +56,8 @@ func (r *FrappeBenchReconciler) ensureBenchStorage(ctx context.Context, bench *v
 	err := r.Get(ctx, types.NamespacedName{Name: pvcName, Namespace: bench.Namespace}, pvc)
 	if err == nil {
 		logger.V(1).Info("PVC already exists", "pvc", pvcName)
-		return nil
+		r.checkBenchStorageClass(bench, pvc)
+		return r.ensureBenchStorageSize(ctx, bench, pvc)
 	}
 
 	if !errors.IsNotFound(err) {
@@ -54,30 +69,193 @@ func (r *FrappeBenchReconciler) ensureBenchStorage(ctx context.Context, bench *v
 		return err
 	}
 
-	accessMode, err := r.determineAccessMode(ctx, bench, sc)
+	accessModes := benchStorageAccessModes(bench)
+	if len(accessModes) == 0 {
+		accessMode, err := r.determineAccessMode(ctx, bench, sc)
+		if err != nil {
+			return err
+		}
+		accessModes = []corev1.PersistentVolumeAccessMode{accessMode}
+	}
+
+	return r.createBenchPVC(ctx, bench, accessModes, sc)
+}
+
+// benchStorageClassName returns the StorageClass requested for the sites PVC, empty to
+// use the cluster default
+func benchStorageClassName(bench *vyogotechv1alpha1.FrappeBench) string {
+	if bench.Spec.Storage != nil && bench.Spec.Storage.ClassName != "" {
+		return bench.Spec.Storage.ClassName
+	}
+	return bench.Spec.StorageClassName
+}
+
+// benchStorageAccessModes returns the access modes set in spec.storage, nil to detect them
+func benchStorageAccessModes(bench *vyogotechv1alpha1.FrappeBench) []corev1.PersistentVolumeAccessMode {
+	if bench.Spec.Storage == nil {
+		return nil
+	}
+	return bench.Spec.Storage.AccessModes
+}
+
+// checkBenchStorageClass warns when the requested StorageClass differs from the one the
+// sites PVC was created with. A PVC's storageClassName is immutable, so the PVC is left as is.
+func (r *FrappeBenchReconciler) checkBenchStorageClass(bench *vyogotechv1alpha1.FrappeBench, pvc *corev1.PersistentVolumeClaim) {
+	desired := benchStorageClassName(bench)
+	if desired == "" {
+		r.warnings.resolve(bench, "StorageClassChangeRejected")
+		return
+	}
+	current := ""
+	if pvc.Spec.StorageClassName != nil {
+		current = *pvc.Spec.StorageClassName
+	}
+	if current == desired {
+		r.warnings.resolve(bench, "StorageClassChangeRejected")
+		return
+	}
+	r.warnings.warn(r.Recorder, bench, "StorageClassChangeRejected",
+		fmt.Sprintf("PVC %s was created with StorageClass %q and can't be moved to %q; recreate the bench to change it", pvc.Name, current, desired))
+}
+
+// benchStorageSize returns the requested size of the sites PVC
+func benchStorageSize(bench *vyogotechv1alpha1.FrappeBench) (resource.Quantity, error) {
+	sizeStr := bench.Spec.StorageSize
+	if sizeStr == "" {
+		sizeStr = "10Gi"
+	}
+	return resource.ParseQuantity(sizeStr)
+}
+
+// ensureBenchStorageSize grows the sites PVC when spec.storageSize increases. Shrinking
+// is not possible and is rejected with a warning; so is growing a PVC whose StorageClass
+// does not allow volume expansion. The StorageResizing condition tracks an expansion
+// until the volume reports the new capacity.
+func (r *FrappeBenchReconciler) ensureBenchStorageSize(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, pvc *corev1.PersistentVolumeClaim) error {
+	logger := log.FromContext(ctx)
+
+	desired, err := benchStorageSize(bench)
 	if err != nil {
+		return fmt.Errorf("invalid storageSize %q: %w", bench.Spec.StorageSize, err)
+	}
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+
+	switch desired.Cmp(requested) {
+	case -1:
+		r.rejectStorageChange(bench, "StorageShrinkRejected", "ShrinkRejected",
+			fmt.Sprintf("storageSize %s is smaller than the %s requested by PVC %s; volumes can't shrink", desired.String(), requested.String(), pvc.Name))
+		return nil
+	case 0:
+		r.updateStorageResizingCondition(bench, pvc, desired)
+		return nil
+	}
+
+	scName := ""
+	if pvc.Spec.StorageClassName != nil {
+		scName = *pvc.Spec.StorageClassName
+	}
+	if scName == "" {
+		return r.rejectStorageExpansion(bench, pvc, "PVC %s has no StorageClass, it can't be expanded", pvc.Name)
+	}
+	sc := &storagev1.StorageClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: scName}, sc); err != nil {
+		return fmt.Errorf("failed to get storage class %q: %w", scName, err)
+	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return r.rejectStorageExpansion(bench, pvc, "StorageClass %s does not allow volume expansion; PVC %s stays at %s", scName, pvc.Name, requested.String())
+	}
+
+	logger.Info("Expanding sites PVC", "pvc", pvc.Name, "from", requested.String(), "to", desired.String())
+	patch := client.MergeFrom(pvc.DeepCopy())
+	if pvc.Spec.Resources.Requests == nil {
+		pvc.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = desired
+	if err := r.Patch(ctx, pvc, patch); err != nil {
 		return err
 	}
+	r.Recorder.Event(bench, corev1.EventTypeNormal, "StorageResizing",
+		fmt.Sprintf("Expanding PVC %s from %s to %s", pvc.Name, requested.String(), desired.String()))
+	r.setCondition(bench, metav1.Condition{
+		Type:    storageResizingCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Resizing",
+		Message: fmt.Sprintf("Expanding PVC %s to %s", pvc.Name, desired.String()),
+	})
+	return nil
+}
+
+// rejectStorageExpansion records that the sites PVC can't grow to the requested size
+func (r *FrappeBenchReconciler) rejectStorageExpansion(bench *vyogotechv1alpha1.FrappeBench, pvc *corev1.PersistentVolumeClaim, format string, args ...interface{}) error {
+	r.rejectStorageChange(bench, "StorageExpansionUnsupported", "ExpansionNotSupported", fmt.Sprintf(format, args...))
+	return nil
+}
+
+// rejectStorageChange sets StorageResizing=False with reason, recording the Warning event
+// only when the condition changes
+func (r *FrappeBenchReconciler) rejectStorageChange(bench *vyogotechv1alpha1.FrappeBench, event, reason, message string) {
+	cond := meta.FindStatusCondition(bench.Status.Conditions, storageResizingCondition)
+	if cond != nil && cond.Reason == reason && cond.Message == message {
+		return
+	}
+	r.Recorder.Event(bench, corev1.EventTypeWarning, event, message)
+	r.setCondition(bench, metav1.Condition{
+		Type:    storageResizingCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
 
-	return r.createBenchPVC(ctx, bench, accessMode, sc)
+// updateStorageResizingCondition clears StorageResizing once the volume reports the
+// requested capacity. File systems are grown by the kubelet, which may wait for the
+// next pod start (FileSystemResizePending).
+func (r *FrappeBenchReconciler) updateStorageResizingCondition(bench *vyogotechv1alpha1.FrappeBench, pvc *corev1.PersistentVolumeClaim, desired resource.Quantity) {
+	if !meta.IsStatusConditionTrue(bench.Status.Conditions, storageResizingCondition) {
+		return
+	}
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	if capacity.Cmp(desired) < 0 {
+		message := fmt.Sprintf("Expanding PVC %s to %s (capacity %s)", pvc.Name, desired.String(), capacity.String())
+		for _, cond := range pvc.Status.Conditions {
+			if cond.Type == corev1.PersistentVolumeClaimFileSystemResizePending && cond.Status == corev1.ConditionTrue {
+				message = fmt.Sprintf("PVC %s waits for a pod restart to grow its file system to %s", pvc.Name, desired.String())
+			}
+		}
+		r.setCondition(bench, metav1.Condition{
+			Type:    storageResizingCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Resizing",
+			Message: message,
+		})
+		return
+	}
+	r.Recorder.Event(bench, corev1.EventTypeNormal, "StorageResized", fmt.Sprintf("PVC %s expanded to %s", pvc.Name, capacity.String()))
+	r.setCondition(bench, metav1.Condition{
+		Type:    storageResizingCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Resized",
+		Message: fmt.Sprintf("PVC %s has %s", pvc.Name, capacity.String()),
+	})
 }
 
-func (r *FrappeBenchReconciler) createBenchPVC(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, accessMode corev1.PersistentVolumeAccessMode, sc *storagev1.StorageClass) error {
+func (r *FrappeBenchReconciler) createBenchPVC(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, accessModes []corev1.PersistentVolumeAccessMode, sc *storagev1.StorageClass) error {
 	logger := log.FromContext(ctx)
 	pvcName := fmt.Sprintf("%s-sites", bench.Name)
-	sizeStr := bench.Spec.StorageSize
-	if sizeStr == "" {
-		sizeStr = "10Gi"
+	storageSize, err := benchStorageSize(bench)
+	if err != nil {
+		return fmt.Errorf("invalid storageSize %q: %w", bench.Spec.StorageSize, err)
 	}
-	storageSize := resource.MustParse(sizeStr)
 
 	builder := resources.NewPVCBuilder(pvcName, bench.Namespace).
 		WithLabels(r.benchLabels(bench)).
 		WithAnnotations(map[string]string{
-			"frappe.tech/requested-access": string(accessMode),
+			"frappe.tech/requested-access": string(accessModes[0]),
 		}).
-		WithAccessMode(accessMode).
 		WithStorageRequest(storageSize)
+	for _, mode := range accessModes {
+		builder.WithAccessMode(mode)
+	}
 
 	if sc != nil {
 		builder.WithStorageClass(sc.Name).
@@ -87,7 +265,8 @@ func (r *FrappeBenchReconciler) createBenchPVC(ctx context.Context, bench *vyogo
 			})
 	}
 
-	if accessMode == corev1.ReadWriteOnce {
+	// Explicit access modes are not a fallback from ReadWriteMany
+	if len(benchStorageAccessModes(bench)) == 0 && accessModes[0] == corev1.ReadWriteOnce {
 		builder.WithAnnotations(map[string]string{"frappe.tech/fallback": "true"})
 	}
 
@@ -96,25 +275,25 @@ func (r *FrappeBenchReconciler) createBenchPVC(ctx context.Context, bench *vyogo
 		return err
 	}
 
-	logger.Info("Creating PVC for bench", "pvc", pvcName, "accessMode", accessMode)
+	logger.Info("Creating PVC for bench", "pvc", pvcName, "accessModes", accessModes)
 	return r.Create(ctx, pvc)
 }
 
 func (r *FrappeBenchReconciler) chooseStorageClass(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (*storagev1.StorageClass, error) {
 	logger := log.FromContext(ctx)
 
-	if bench.Spec.StorageClassName != "" {
+	if scName := benchStorageClassName(bench); scName != "" {
 		sc := &storagev1.StorageClass{}
-		if err := r.Get(ctx, types.NamespacedName{Name: bench.Spec.StorageClassName}, sc); err != nil {
+		if err := r.Get(ctx, types.NamespacedName{Name: scName}, sc); err != nil {
 			if errors.IsNotFound(err) {
-				return nil, fmt.Errorf("specified storage class '%s' not found in cluster. Available storage classes can be listed with 'kubectl get storageclass'", bench.Spec.StorageClassName)
+				return nil, fmt.Errorf("specified storage class '%s' not found in cluster. Available storage classes can be listed with 'kubectl get storageclass'", scName)
 			}
-			return nil, fmt.Errorf("failed to get storage class '%s': %w", bench.Spec.StorageClassName, err)
+			return nil, fmt.Errorf("failed to get storage class '%s': %w", scName, err)
 		}
 
 		// Validate that the storage class is ready for use
 		if sc.Provisioner == "" {
-			return nil, fmt.Errorf("storage class '%s' has no provisioner configured", bench.Spec.StorageClassName)
+			return nil, fmt.Errorf("storage class '%s' has no provisioner configured", scName)
 		}
 
 		logger.Info("Using specified storage class", "storageClass", sc.Name, "provisioner", sc.Provisioner)
@@ -0,0 +1,208 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newStorageTestObjects(allowExpansion bool) (*vyogotechv1alpha1.FrappeBench, *corev1.PersistentVolumeClaim, *storagev1.StorageClass) {
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15", StorageSize: "20Gi"},
+	}
+	scName := "standard"
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-sites", Namespace: "default"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &scName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+		},
+	}
+	sc := &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: scName},
+		Provisioner:          "ebs.csi.aws.com",
+		AllowVolumeExpansion: &allowExpansion,
+	}
+	return bench, pvc, sc
+}
+
+func newStorageTestReconciler(bench *vyogotechv1alpha1.FrappeBench, pvc *corev1.PersistentVolumeClaim, sc *storagev1.StorageClass) (*FrappeBenchReconciler, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, pvc, sc).
+		WithStatusSubresource(&corev1.PersistentVolumeClaim{}).Build()
+	recorder := record.NewFakeRecorder(10)
+	return &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: recorder}, recorder
+}
+
+func TestEnsureBenchStorage_Expands(t *testing.T) {
+	bench, pvc, sc := newStorageTestObjects(true)
+	r, _ := newStorageTestReconciler(bench, pvc, sc)
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench-sites", Namespace: "default"}
+
+	if err := r.ensureBenchStorage(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchStorage: %v", err)
+	}
+	updated := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get PVC: %v", err)
+	}
+	if got := updated.Spec.Resources.Requests[corev1.ResourceStorage]; got.String() != "20Gi" {
+		t.Errorf("expected PVC request 20Gi, got %s", got.String())
+	}
+	cond := meta.FindStatusCondition(bench.Status.Conditions, storageResizingCondition)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "Resizing" {
+		t.Fatalf("expected StorageResizing=True, got %+v", cond)
+	}
+
+	// Still at the old capacity: keeps resizing
+	if err := r.ensureBenchStorage(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchStorage: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(bench.Status.Conditions, storageResizingCondition) {
+		t.Error("expected StorageResizing to stay true until capacity grows")
+	}
+
+	updated.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")}
+	if err := r.Status().Update(ctx, updated); err != nil {
+		t.Fatalf("update PVC status: %v", err)
+	}
+	if err := r.ensureBenchStorage(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchStorage: %v", err)
+	}
+	cond = meta.FindStatusCondition(bench.Status.Conditions, storageResizingCondition)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "Resized" {
+		t.Errorf("expected StorageResizing=False/Resized, got %+v", cond)
+	}
+}
+
+func TestEnsureBenchStorage_RejectsExpansion(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowExpansion bool
+		size           string
+		wantEvent      string
+	}{
+		{name: "storage class without expansion", allowExpansion: false, size: "20Gi", wantEvent: "StorageExpansionUnsupported"},
+		{name: "shrink", allowExpansion: true, size: "5Gi", wantEvent: "StorageShrinkRejected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bench, pvc, sc := newStorageTestObjects(tt.allowExpansion)
+			bench.Spec.StorageSize = tt.size
+			r, recorder := newStorageTestReconciler(bench, pvc, sc)
+			ctx := context.Background()
+
+			if err := r.ensureBenchStorage(ctx, bench); err != nil {
+				t.Fatalf("ensureBenchStorage: %v", err)
+			}
+			updated := &corev1.PersistentVolumeClaim{}
+			if err := r.Get(ctx, types.NamespacedName{Name: "bench-sites", Namespace: "default"}, updated); err != nil {
+				t.Fatalf("Get PVC: %v", err)
+			}
+			if got := updated.Spec.Resources.Requests[corev1.ResourceStorage]; got.String() != "10Gi" {
+				t.Errorf("expected PVC request to stay 10Gi, got %s", got.String())
+			}
+			if event := <-recorder.Events; !strings.Contains(event, tt.wantEvent) {
+				t.Errorf("expected %s event, got %q", tt.wantEvent, event)
+			}
+			if err := r.ensureBenchStorage(ctx, bench); err != nil {
+				t.Fatalf("ensureBenchStorage: %v", err)
+			}
+			if len(recorder.Events) != 0 {
+				t.Errorf("expected the rejection to be reported once, got %q", <-recorder.Events)
+			}
+		})
+	}
+}
+
+func TestEnsureBenchStorage_StorageClass(t *testing.T) {
+	bench, _, sc := newStorageTestObjects(true)
+	bench.Spec.Storage = &vyogotechv1alpha1.BenchStorageConfig{
+		ClassName:   "fast",
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOncePod},
+	}
+	fast := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}, Provisioner: "pd.csi.storage.gke.io"}
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	recorder := record.NewFakeRecorder(10)
+	r := &FrappeBenchReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, sc, fast).Build(),
+		Scheme:   scheme,
+		Recorder: recorder,
+	}
+	ctx := context.Background()
+
+	if err := r.ensureBenchStorage(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchStorage: %v", err)
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "bench-sites", Namespace: "default"}, pvc); err != nil {
+		t.Fatalf("Get PVC: %v", err)
+	}
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != "fast" {
+		t.Errorf("expected storage class fast, got %v", pvc.Spec.StorageClassName)
+	}
+	if len(pvc.Spec.AccessModes) != 1 || pvc.Spec.AccessModes[0] != corev1.ReadWriteOncePod {
+		t.Errorf("expected access modes [ReadWriteOncePod], got %v", pvc.Spec.AccessModes)
+	}
+
+	// The class of an existing PVC can't change
+	bench.Spec.Storage.ClassName = "standard"
+	if err := r.ensureBenchStorage(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchStorage: %v", err)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "StorageClassChangeRejected") {
+		t.Errorf("expected StorageClassChangeRejected event, got %q", event)
+	}
+	if err := r.ensureBenchStorage(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchStorage: %v", err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected the rejection to be reported once, got %q", <-recorder.Events)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: "bench-sites", Namespace: "default"}, pvc); err != nil {
+		t.Fatalf("Get PVC: %v", err)
+	}
+	if *pvc.Spec.StorageClassName != "fast" {
+		t.Errorf("expected the PVC to keep storage class fast, got %s", *pvc.Spec.StorageClassName)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// suspendedCondition is set on benches with spec.suspended
+	suspendedCondition = "Suspended"
+	// suspendPollInterval is how often a suspending bench checks that its pods are gone
+	suspendPollInterval = 5 * time.Second
+	// suspendedReplicasAnnotation records the replica count a Deployment or StatefulSet
+	// had before the bench was scaled down, so resuming can restore it
+	suspendedReplicasAnnotation = "frappe.tech/suspended-replicas"
+)
+
+// benchRedisComponents are the redis StatefulSets the bench runs unless redis is external
+var benchRedisComponents = []string{"redis-cache", "redis-queue"}
+
+// benchScalables returns the Deployments and redis StatefulSets of the bench, skipping
+// the ones that don't exist
+func (r *FrappeBenchReconciler) benchScalables(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) ([]client.Object, error) {
+	var objects []client.Object
+	for _, component := range r.benchDeploymentComponents(bench) {
+		deploy := &appsv1.Deployment{}
+		if err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%s", bench.Name, component), Namespace: bench.Namespace}, deploy); err == nil {
+			objects = append(objects, deploy)
+		} else if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	for _, component := range benchRedisComponents {
+		sts := &appsv1.StatefulSet{}
+		if err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%s", bench.Name, component), Namespace: bench.Namespace}, sts); err == nil {
+			objects = append(objects, sts)
+		} else if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// scalableReplicas returns the spec replicas field of a Deployment or StatefulSet
+func scalableReplicas(obj client.Object) **int32 {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &o.Spec.Replicas
+	case *appsv1.StatefulSet:
+		return &o.Spec.Replicas
+	}
+	return nil
+}
+
+// scaleDownBench scales the Deployments and redis StatefulSets of the bench to zero,
+// recording the replicas each had in the suspended-replicas annotation. Failures are
+// reported as events and don't stop the remaining objects from being scaled down; they
+// are returned together afterwards.
+func (r *FrappeBenchReconciler) scaleDownBench(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	logger := log.FromContext(ctx)
+
+	objects, err := r.benchScalables(ctx, bench)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, obj := range objects {
+		replicas := scalableReplicas(obj)
+		if *replicas == nil || **replicas == 0 {
+			continue
+		}
+		logger.Info("Scaling down", "object", obj.GetName())
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[suspendedReplicasAnnotation] = strconv.Itoa(int(**replicas))
+		obj.SetAnnotations(annotations)
+		*replicas = int32Ptr(0)
+		if err := r.Update(ctx, obj); err != nil {
+			logger.Error(err, "Failed to scale down", "object", obj.GetName())
+			r.Recorder.Event(bench, corev1.EventTypeWarning, "ScaleDownFailed", fmt.Sprintf("Failed to scale down %s: %v", obj.GetName(), err))
+			errs = append(errs, fmt.Errorf("failed to scale down %s: %w", obj.GetName(), err))
+		} else {
+			r.Recorder.Event(bench, corev1.EventTypeNormal, "ScaledDown", fmt.Sprintf("Scaled down %s", obj.GetName()))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// benchPodsTerminated reports whether no Deployment or redis StatefulSet of the bench
+// still has pods
+func (r *FrappeBenchReconciler) benchPodsTerminated(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	objects, err := r.benchScalables(ctx, bench)
+	if err != nil {
+		return false, err
+	}
+	terminated := true
+	for _, obj := range objects {
+		var replicas, ready int32
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			replicas, ready = o.Status.Replicas, o.Status.ReadyReplicas
+		case *appsv1.StatefulSet:
+			replicas, ready = o.Status.Replicas, o.Status.ReadyReplicas
+		}
+		if replicas > 0 || ready > 0 {
+			terminated = false
+			logger.Info("Waiting for pods to terminate", "object", obj.GetName(), "replicas", replicas)
+		}
+	}
+	return terminated, nil
+}
+
+// suspendBench scales a bench with spec.suspended to zero and marks it Suspended once
+// its pods are gone. Until then it stays Suspending and is requeued; a failed scale-down
+// is returned so it is retried.
+func (r *FrappeBenchReconciler) suspendBench(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (ctrl.Result, error) {
+	if err := r.scaleDownBench(ctx, bench); err != nil {
+		r.setSuspending(bench, "ScaleDownFailed", err.Error())
+		_ = r.updateStatus(ctx, bench)
+		return ctrl.Result{}, err
+	}
+	terminated, err := r.benchPodsTerminated(ctx, bench)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !terminated {
+		r.setSuspending(bench, "PodsTerminating", "Waiting for the bench pods to terminate")
+		return ctrl.Result{RequeueAfter: suspendPollInterval}, r.updateStatus(ctx, bench)
+	}
+
+	if meta.SetStatusCondition(&bench.Status.Conditions, metav1.Condition{
+		Type:               suspendedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Suspended",
+		Message:            "The bench is scaled to zero by spec.suspended",
+		ObservedGeneration: bench.Generation,
+	}) {
+		r.Recorder.Event(bench, corev1.EventTypeNormal, "Suspended", "Bench scaled to zero by spec.suspended")
+	}
+	bench.Status.Phase = "Suspended"
+	r.setCondition(bench, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  "Suspended",
+		Message: "The bench is suspended",
+	})
+	return ctrl.Result{}, r.updateStatus(ctx, bench)
+}
+
+// setSuspending marks a bench that is being scaled down for spec.suspended but still
+// has pods, or whose scale-down failed
+func (r *FrappeBenchReconciler) setSuspending(bench *vyogotechv1alpha1.FrappeBench, reason, message string) {
+	meta.SetStatusCondition(&bench.Status.Conditions, metav1.Condition{
+		Type:               suspendedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: bench.Generation,
+	})
+	bench.Status.Phase = "Suspending"
+	r.setCondition(bench, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  "Suspending",
+		Message: message,
+	})
+}
+
+// resumeBench restores the replicas recorded when a bench was suspended, including one
+// whose suspension didn't finish. It does nothing for benches that were never suspended
+// or are already resumed.
+func (r *FrappeBenchReconciler) resumeBench(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) error {
+	if cond := meta.FindStatusCondition(bench.Status.Conditions, suspendedCondition); cond == nil || cond.Reason == "Resumed" {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	objects, err := r.benchScalables(ctx, bench)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		value, ok := obj.GetAnnotations()[suspendedReplicasAnnotation]
+		if !ok {
+			continue
+		}
+		replicas, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			logger.Info("Ignoring invalid suspended replica count", "object", obj.GetName(), "value", value)
+			replicas = 1
+		}
+		logger.Info("Restoring replicas", "object", obj.GetName(), "replicas", replicas)
+		annotations := obj.GetAnnotations()
+		delete(annotations, suspendedReplicasAnnotation)
+		obj.SetAnnotations(annotations)
+		*scalableReplicas(obj) = int32Ptr(int32(replicas))
+		if err := r.Update(ctx, obj); err != nil {
+			return fmt.Errorf("failed to restore replicas of %s: %w", obj.GetName(), err)
+		}
+	}
+
+	r.Recorder.Event(bench, corev1.EventTypeNormal, "Resumed", "Bench replicas restored")
+	meta.SetStatusCondition(&bench.Status.Conditions, metav1.Condition{
+		Type:               suspendedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Resumed",
+		Message:            "The bench replicas are restored",
+		ObservedGeneration: bench.Generation,
+	})
+	bench.Status.Phase = "Provisioning"
+	return r.updateStatus(ctx, bench)
+}
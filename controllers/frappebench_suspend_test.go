@@ -0,0 +1,292 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestFrappeBenchReconciler_SuspendAndResume(t *testing.T) {
+	scheme := newPausedTestScheme()
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default", Finalizers: []string{frappeBenchFinalizer}},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15", Suspended: true},
+		Status:     vyogotechv1alpha1.FrappeBenchStatus{Phase: "Ready"},
+	}
+	gunicorn := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-gunicorn", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(3)},
+	}
+	// Already at zero, so it stays at zero when resumed
+	longWorker := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-worker-long", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(0)},
+	}
+	redisCache := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-redis-cache", Namespace: "default"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: int32Ptr(1)},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, gunicorn, longWorker, redisCache).WithStatusSubresource(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench", Namespace: "default"}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	assertReplicas := func(name string, obj interface{ GetAnnotations() map[string]string }, replicas *int32, want int32, wantAnnotation string) {
+		t.Helper()
+		if replicas == nil || *replicas != want {
+			t.Errorf("%s: expected %d replicas, got %v", name, want, replicas)
+		}
+		if got := obj.GetAnnotations()[suspendedReplicasAnnotation]; got != wantAnnotation {
+			t.Errorf("%s: expected suspended replicas annotation %q, got %q", name, wantAnnotation, got)
+		}
+	}
+	deploy, worker, sts := &appsv1.Deployment{}, &appsv1.Deployment{}, &appsv1.StatefulSet{}
+	fetch := func() {
+		t.Helper()
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+			t.Fatalf("Get gunicorn: %v", err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-long", Namespace: "default"}, worker); err != nil {
+			t.Fatalf("Get worker: %v", err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-redis-cache", Namespace: "default"}, sts); err != nil {
+			t.Fatalf("Get redis: %v", err)
+		}
+	}
+
+	fetch()
+	assertReplicas("gunicorn", deploy, deploy.Spec.Replicas, 0, "3")
+	assertReplicas("worker", worker, worker.Spec.Replicas, 0, "")
+	assertReplicas("redis", sts, sts.Spec.Replicas, 0, "1")
+
+	updated := &vyogotechv1alpha1.FrappeBench{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get bench: %v", err)
+	}
+	if updated.Status.Phase != "Suspended" {
+		t.Errorf("expected phase Suspended, got %s", updated.Status.Phase)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, suspendedCondition) {
+		t.Errorf("expected Suspended=True, got %+v", updated.Status.Conditions)
+	}
+	if ready := meta.FindStatusCondition(updated.Status.Conditions, "Ready"); ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != "Suspended" {
+		t.Errorf("expected Ready=False with reason Suspended, got %+v", ready)
+	}
+
+	updated.Spec.Suspended = false
+	if err := r.resumeBench(ctx, updated); err != nil {
+		t.Fatalf("resumeBench: %v", err)
+	}
+	fetch()
+	assertReplicas("gunicorn", deploy, deploy.Spec.Replicas, 3, "")
+	assertReplicas("worker", worker, worker.Spec.Replicas, 0, "")
+	assertReplicas("redis", sts, sts.Spec.Replicas, 1, "")
+
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get bench: %v", err)
+	}
+	if cond := meta.FindStatusCondition(updated.Status.Conditions, suspendedCondition); cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "Resumed" {
+		t.Errorf("expected Suspended=False with reason Resumed, got %+v", cond)
+	}
+	if updated.Status.Phase != "Provisioning" {
+		t.Errorf("expected phase Provisioning after resuming, got %s", updated.Status.Phase)
+	}
+}
+
+func TestFrappeBenchReconciler_SuspendWaitsForPods(t *testing.T) {
+	scheme := newPausedTestScheme()
+	newBench := func() *vyogotechv1alpha1.FrappeBench {
+		return &vyogotechv1alpha1.FrappeBench{
+			ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default", Finalizers: []string{frappeBenchFinalizer}},
+			Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15", Suspended: true},
+			Status:     vyogotechv1alpha1.FrappeBenchStatus{Phase: "Ready"},
+		}
+	}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "bench", Namespace: "default"}
+
+	t.Run("pods still running", func(t *testing.T) {
+		gunicorn := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "bench-gunicorn", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+			Status:     appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 2},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newBench(), gunicorn).WithStatusSubresource(&vyogotechv1alpha1.FrappeBench{}, gunicorn).Build()
+		r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		if result.RequeueAfter != suspendPollInterval {
+			t.Errorf("expected requeue after %s while pods terminate, got %s", suspendPollInterval, result.RequeueAfter)
+		}
+		bench := &vyogotechv1alpha1.FrappeBench{}
+		if err := c.Get(ctx, key, bench); err != nil {
+			t.Fatalf("Get bench: %v", err)
+		}
+		if cond := meta.FindStatusCondition(bench.Status.Conditions, suspendedCondition); cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "PodsTerminating" {
+			t.Errorf("expected Suspended=False with reason PodsTerminating, got %+v", cond)
+		}
+		if bench.Status.Phase != "Suspending" {
+			t.Errorf("expected phase Suspending, got %s", bench.Status.Phase)
+		}
+
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, gunicorn); err != nil {
+			t.Fatalf("Get gunicorn: %v", err)
+		}
+		gunicorn.Status = appsv1.DeploymentStatus{}
+		if err := c.Status().Update(ctx, gunicorn); err != nil {
+			t.Fatalf("Update gunicorn status: %v", err)
+		}
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		if err := c.Get(ctx, key, bench); err != nil {
+			t.Fatalf("Get bench: %v", err)
+		}
+		if !meta.IsStatusConditionTrue(bench.Status.Conditions, suspendedCondition) || bench.Status.Phase != "Suspended" {
+			t.Errorf("expected Suspended once the pods are gone, got phase %s and %+v", bench.Status.Phase, bench.Status.Conditions)
+		}
+	})
+
+	t.Run("scale-down failure", func(t *testing.T) {
+		gunicorn := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "bench-gunicorn", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+		}
+		redisCache := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "bench-redis-cache", Namespace: "default"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: int32Ptr(1)},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newBench(), gunicorn, redisCache).WithStatusSubresource(&vyogotechv1alpha1.FrappeBench{}).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					if obj.GetName() == "bench-redis-cache" {
+						return errors.New("conflict")
+					}
+					return c.Update(ctx, obj, opts...)
+				},
+			}).Build()
+		r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err == nil || !strings.Contains(err.Error(), "bench-redis-cache") {
+			t.Fatalf("expected the failed scale-down to be returned, got %v", err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, gunicorn); err != nil {
+			t.Fatalf("Get gunicorn: %v", err)
+		}
+		if *gunicorn.Spec.Replicas != 0 {
+			t.Errorf("expected gunicorn to be scaled down despite the redis failure, got %d", *gunicorn.Spec.Replicas)
+		}
+		bench := &vyogotechv1alpha1.FrappeBench{}
+		if err := c.Get(ctx, key, bench); err != nil {
+			t.Fatalf("Get bench: %v", err)
+		}
+		if cond := meta.FindStatusCondition(bench.Status.Conditions, suspendedCondition); cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "ScaleDownFailed" {
+			t.Errorf("expected Suspended=False with reason ScaleDownFailed, got %+v", cond)
+		}
+
+		// Resuming before the suspension finished restores what was scaled down
+		bench.Spec.Suspended = false
+		if err := r.resumeBench(ctx, bench); err != nil {
+			t.Fatalf("resumeBench: %v", err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, gunicorn); err != nil {
+			t.Fatalf("Get gunicorn: %v", err)
+		}
+		if *gunicorn.Spec.Replicas != 2 {
+			t.Errorf("expected gunicorn replicas restored to 2, got %d", *gunicorn.Spec.Replicas)
+		}
+	})
+}
+
+func TestReconcile_siteWaitsForSuspendedBench(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.SetFinalizers([]string{frappeSiteFinalizer})
+	bench.Spec.Suspended = true
+	bench.Status.Phase = "Suspended"
+	siteReconciler, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site", Namespace: "default"}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("expected the site to wait for the suspended bench, got %v", err)
+	}
+	updated := &vyogotechv1alpha1.FrappeSite{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if updated.Status.Phase != vyogotechv1alpha1.FrappeSitePhasePending {
+		t.Errorf("expected phase Pending, got %s", updated.Status.Phase)
+	}
+	benchReady := meta.FindStatusCondition(updated.Status.Conditions, "BenchReady")
+	if benchReady == nil || benchReady.Status != metav1.ConditionFalse || benchReady.Reason != "BenchNotReady" {
+		t.Errorf("expected BenchReady=False with reason BenchNotReady, got %+v", benchReady)
+	}
+}
+
+func TestReconcile_readySiteReportsSuspendedBench(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.SetFinalizers([]string{frappeSiteFinalizer})
+	site.Generation = 2
+	site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseReady
+	site.Status.ObservedGeneration = 2
+	bench.Spec.Suspended = true
+	bench.Status.Phase = "Suspended"
+	siteReconciler, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site", Namespace: "default"}
+
+	// Suspending the bench doesn't change the site's generation, so the Ready guard must
+	// not skip it
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	updated := &vyogotechv1alpha1.FrappeSite{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if updated.Status.Phase != vyogotechv1alpha1.FrappeSitePhasePending {
+		t.Errorf("expected phase Pending, got %s", updated.Status.Phase)
+	}
+	benchReady := meta.FindStatusCondition(updated.Status.Conditions, "BenchReady")
+	if benchReady == nil || benchReady.Reason != "BenchNotReady" {
+		t.Errorf("expected BenchReady reason BenchNotReady, got %+v", benchReady)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// componentUpdateStrategy returns the spec.updateStrategy override for a component
+func componentUpdateStrategy(bench *vyogotechv1alpha1.FrappeBench, component string) *vyogotechv1alpha1.ComponentUpdateStrategy {
+	strategies := bench.Spec.UpdateStrategy
+	if strategies == nil {
+		return nil
+	}
+	switch component {
+	case "gunicorn":
+		return strategies.Gunicorn
+	case "nginx":
+		return strategies.Nginx
+	}
+	return nil
+}
+
+// deploymentStrategy returns the Deployment strategy of a component. Every field the API
+// server would default is set, so it compares equal to the one read back from a Deployment.
+func deploymentStrategy(bench *vyogotechv1alpha1.FrappeBench, component string) appsv1.DeploymentStrategy {
+	strategy := componentUpdateStrategy(bench, component).WithDefaults()
+	if strategy.Type == vyogotechv1alpha1.UpdateStrategyRecreate {
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       strategy.MaxSurge,
+			MaxUnavailable: strategy.MaxUnavailable,
+		},
+	}
+}
+
+// syncDeploymentStrategy brings the strategy of an existing component Deployment in line
+// with the bench spec, reporting whether anything changed. Without an override a
+// Deployment whose strategy was never defaulted is left alone.
+func syncDeploymentStrategy(deploy *appsv1.Deployment, bench *vyogotechv1alpha1.FrappeBench, component string) bool {
+	if componentUpdateStrategy(bench, component) == nil && deploy.Spec.Strategy.Type == "" {
+		return false
+	}
+	strategy := deploymentStrategy(bench, component)
+	if reflect.DeepEqual(deploy.Spec.Strategy, strategy) {
+		return false
+	}
+	deploy.Spec.Strategy = strategy
+	return true
+}
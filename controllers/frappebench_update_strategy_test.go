@@ -0,0 +1,84 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEnsureWebDeployments_updateStrategy(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	zero, one := intstr.FromInt32(0), intstr.FromInt32(1)
+	bench.Spec.UpdateStrategy = &vyogotechv1alpha1.BenchUpdateStrategy{
+		Gunicorn: &vyogotechv1alpha1.ComponentUpdateStrategy{MaxSurge: &zero, MaxUnavailable: &one},
+		Nginx:    &vyogotechv1alpha1.ComponentUpdateStrategy{Type: vyogotechv1alpha1.UpdateStrategyRecreate},
+	}
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := r.ensureNginxDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureNginxDeployment: %v", err)
+	}
+	gunicorn, nginx := &appsv1.Deployment{}, &appsv1.Deployment{}
+	fetch := func() {
+		t.Helper()
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, gunicorn); err != nil {
+			t.Fatalf("Get gunicorn: %v", err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-nginx", Namespace: "default"}, nginx); err != nil {
+			t.Fatalf("Get nginx: %v", err)
+		}
+	}
+
+	fetch()
+	rolling := gunicorn.Spec.Strategy.RollingUpdate
+	if gunicorn.Spec.Strategy.Type != appsv1.RollingUpdateDeploymentStrategyType || rolling == nil ||
+		rolling.MaxSurge.IntValue() != 0 || rolling.MaxUnavailable.IntValue() != 1 {
+		t.Errorf("expected a rolling update without surge, got %+v", gunicorn.Spec.Strategy)
+	}
+	if nginx.Spec.Strategy.Type != appsv1.RecreateDeploymentStrategyType || nginx.Spec.Strategy.RollingUpdate != nil {
+		t.Errorf("expected Recreate for nginx, got %+v", nginx.Spec.Strategy)
+	}
+
+	// Dropping the overrides restores the Kubernetes default
+	bench.Spec.UpdateStrategy = nil
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := r.ensureNginxDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureNginxDeployment: %v", err)
+	}
+	fetch()
+	for name, deploy := range map[string]*appsv1.Deployment{"gunicorn": gunicorn, "nginx": nginx} {
+		rolling := deploy.Spec.Strategy.RollingUpdate
+		if deploy.Spec.Strategy.Type != appsv1.RollingUpdateDeploymentStrategyType || rolling == nil ||
+			rolling.MaxSurge.String() != "25%" || rolling.MaxUnavailable.String() != "25%" {
+			t.Errorf("%s: expected the default rolling update, got %+v", name, deploy.Spec.Strategy)
+		}
+	}
+}
@@ -19,11 +19,15 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	"github.com/vyogotech/frappe-operator/pkg/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -39,41 +43,133 @@ func (r *FrappeBenchReconciler) ensureWorkers(ctx context.Context, bench *vyogot
 	logger := log.FromContext(ctx)
 
 	// Check KEDA availability once
-	kedaAvailable := r.isKEDAAvailable(ctx)
+	kedaAvailable, err := r.isKEDAAvailable(ctx)
+	if err != nil {
+		return err
+	}
 	if !kedaAvailable {
 		logger.Info("KEDA not available, workers will use static replicas")
 	}
 
-	workers := []struct {
-		name      string
-		queue     string
-		resources func(*vyogotechv1alpha1.FrappeBench) corev1.ResourceRequirements
-	}{
-		{"default", "default", r.getWorkerDefaultResources},
-		{"long", "long", r.getWorkerLongResources},
-		{"short", "short", r.getWorkerShortResources},
-	}
-
+	workers := r.benchWorkers(bench)
 	for _, worker := range workers {
-		// Get autoscaling config for this worker
-		config := r.getWorkerAutoscalingConfig(bench, worker.name)
-		config = r.fillAutoscalingDefaults(config, worker.name)
-
 		// Determine replica count based on scaling mode
-		replicas := r.getWorkerReplicaCount(config, kedaAvailable)
+		replicas := r.getWorkerReplicaCount(worker.config, kedaAvailable)
 
 		// Create/update worker deployment
-		if err := r.ensureWorkerDeployment(ctx, bench, worker.name, worker.queue, replicas, worker.resources(bench), config, kedaAvailable); err != nil {
+		if err := r.ensureWorkerDeployment(ctx, bench, worker.name, worker.queue, replicas, worker.resources, worker.config, kedaAvailable); err != nil {
 			return err
 		}
 
 		// Create/update ScaledObject if autoscaling is enabled
-		if err := r.ensureScaledObject(ctx, bench, worker.name, config); err != nil {
+		if err := r.ensureScaledObject(ctx, bench, worker.name, worker.queue, worker.config); err != nil {
+			// A KEDA API that doesn't answer is reported like a failed probe
+			if isLookupTimeout(err) {
+				return err
+			}
 			logger.Error(err, "Failed to ensure ScaledObject", "worker", worker.name)
 			// Don't fail the reconciliation, just log the error
 		}
 	}
 
+	return r.deleteRemovedWorkers(ctx, bench, workers, kedaAvailable)
+}
+
+// benchWorker is a worker Deployment the bench runs, resolved from spec.workers
+// or the built-in default, long and short workers
+type benchWorker struct {
+	name      string
+	queue     string
+	resources corev1.ResourceRequirements
+	config    *vyogotechv1alpha1.WorkerAutoscaling
+}
+
+// builtinWorkerPools are the pools a bench runs when spec.workers is empty
+var builtinWorkerPools = []string{"default", "long", "short"}
+
+// benchWorkers returns the worker pools of a bench with queue, resources and
+// scaling config filled in
+func (r *FrappeBenchReconciler) benchWorkers(bench *vyogotechv1alpha1.FrappeBench) []benchWorker {
+	pools := bench.Spec.Workers
+	if len(pools) == 0 {
+		for _, name := range builtinWorkerPools {
+			pools = append(pools, vyogotechv1alpha1.WorkerPool{Name: name})
+		}
+	}
+
+	workers := make([]benchWorker, 0, len(pools))
+	for _, pool := range pools {
+		queue := pool.Queue
+		if queue == "" {
+			queue = pool.Name
+		}
+
+		config := pool.Autoscaling
+		if config == nil && pool.Replicas != nil {
+			config = &vyogotechv1alpha1.WorkerAutoscaling{Enabled: boolPtr(false), StaticReplicas: pool.Replicas}
+		}
+		if config == nil {
+			config = r.getWorkerAutoscalingConfig(bench, pool.Name)
+		}
+
+		workers = append(workers, benchWorker{
+			name:      pool.Name,
+			queue:     queue,
+			resources: r.getWorkerPoolResources(bench, pool),
+			config:    r.fillAutoscalingDefaults(config, pool.Name),
+		})
+	}
+	return workers
+}
+
+// getWorkerPoolResources returns the resources of a worker pool, falling back to the
+// componentResources entry of the matching built-in worker
+func (r *FrappeBenchReconciler) getWorkerPoolResources(bench *vyogotechv1alpha1.FrappeBench, pool vyogotechv1alpha1.WorkerPool) corev1.ResourceRequirements {
+	if pool.Resources != nil {
+		return corev1.ResourceRequirements{
+			Requests: pool.Resources.Requests,
+			Limits:   pool.Resources.Limits,
+		}
+	}
+	switch pool.Name {
+	case "long":
+		return r.getWorkerLongResources(bench)
+	case "short":
+		return r.getWorkerShortResources(bench)
+	}
+	return r.getWorkerDefaultResources(bench)
+}
+
+// deleteRemovedWorkers deletes the worker Deployments (and ScaledObjects) of pools
+// that are no longer part of the bench, e.g. after a pool is dropped from spec.workers
+func (r *FrappeBenchReconciler) deleteRemovedWorkers(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, workers []benchWorker, kedaAvailable bool) error {
+	logger := log.FromContext(ctx)
+
+	wanted := make(map[string]bool, len(workers))
+	for _, worker := range workers {
+		wanted[fmt.Sprintf("%s-worker-%s", bench.Name, worker.name)] = true
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(bench.Namespace), client.MatchingLabels(r.benchLabels(bench))); err != nil {
+		return err
+	}
+	prefix := fmt.Sprintf("%s-worker-", bench.Name)
+	for i := range deployments.Items {
+		deploy := &deployments.Items[i]
+		if !strings.HasPrefix(deploy.Name, prefix) || wanted[deploy.Name] || !metav1.IsControlledBy(deploy, bench) {
+			continue
+		}
+		logger.Info("Deleting Deployment of removed worker pool", "deployment", deploy.Name)
+		if err := r.Delete(ctx, deploy, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if kedaAvailable {
+			if err := r.deleteScaledObjectIfExists(ctx, bench, strings.TrimPrefix(deploy.Name, prefix)); err != nil {
+				logger.Error(err, "Failed to delete ScaledObject of removed worker pool", "deployment", deploy.Name)
+			}
+		}
+	}
 	return nil
 }
 
@@ -91,13 +187,64 @@ func (r *FrappeBenchReconciler) ensureWorkerDeployment(ctx context.Context, benc
 	if err == nil {
 		// Deployment exists, update it if needed
 		changed := false
-		image := r.getBenchImage(ctx, bench)
+		image := r.getComponentImage(ctx, bench, "worker")
 		if deploy.Spec.Template.Spec.Containers[0].Image != image {
 			logger.Info("Updating worker image", "worker", workerType, "oldImage", deploy.Spec.Template.Spec.Containers[0].Image, "newImage", image)
 			deploy.Spec.Template.Spec.Containers[0].Image = image
 			changed = true
 		}
 
+		// A pool's queue and resources can change without renaming the pool
+		podSpec := &deploy.Spec.Template.Spec
+		if args := workerArgs(queue); !slices.Equal(podSpec.Containers[0].Args, args) {
+			logger.Info("Updating worker queue", "worker", workerType, "queue", queue)
+			podSpec.Containers[0].Args = args
+			changed = true
+		}
+		if !equality.Semantic.DeepEqual(podSpec.Containers[0].Resources, workerResources) {
+			logger.Info("Updating worker resources", "worker", workerType)
+			podSpec.Containers[0].Resources = workerResources
+			changed = true
+		}
+
+		// Keep the drain hook and grace period in sync with the worker config
+		lifecycle := r.getWorkerLifecycle(config)
+		if !reflect.DeepEqual(podSpec.Containers[0].Lifecycle, lifecycle) {
+			logger.Info("Updating worker preStop hook", "worker", workerType, "drainOnStop", lifecycle != nil)
+			podSpec.Containers[0].Lifecycle = lifecycle
+			changed = true
+		}
+		if !reflect.DeepEqual(podSpec.TerminationGracePeriodSeconds, config.TerminationGracePeriodSeconds) {
+			logger.Info("Updating worker termination grace period", "worker", workerType)
+			podSpec.TerminationGracePeriodSeconds = config.TerminationGracePeriodSeconds
+			changed = true
+		}
+
+		if syncPodPlacement(podSpec, bench.Spec.PodConfig) {
+			logger.Info("Updating worker pod placement", "worker", workerType)
+			changed = true
+		}
+		if syncImagePullSecrets(podSpec, bench) {
+			logger.Info("Updating worker image pull secrets", "worker", workerType)
+			changed = true
+		}
+		if syncServiceAccount(podSpec, bench) {
+			logger.Info("Updating worker service account", "worker", workerType)
+			changed = true
+		}
+		if syncSharedAssets(podSpec, bench) {
+			logger.Info("Updating worker shared assets volume", "worker", workerType)
+			changed = true
+		}
+		if syncExtraVolumes(&deploy.Spec.Template, bench) {
+			logger.Info("Updating worker extra volumes", "worker", workerType)
+			changed = true
+		}
+		if syncComponentPodAnnotations(&deploy.Spec.Template, bench, fmt.Sprintf("worker-%s", workerType)) {
+			logger.Info("Updating worker pod annotations", "worker", workerType)
+			changed = true
+		}
+
 		// Only update replicas if NOT managed by KEDA (KEDA controls replicas)
 		if !kedaManaged && *deploy.Spec.Replicas != replicas {
 			logger.Info("Updating worker replicas", "worker", workerType, "oldReplicas", *deploy.Spec.Replicas, "newReplicas", replicas)
@@ -105,6 +252,12 @@ func (r *FrappeBenchReconciler) ensureWorkerDeployment(ctx context.Context, benc
 			changed = true
 		}
 
+		_, _, _, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
+		if syncOwnedLabels(&deploy.ObjectMeta, &deploy.Spec.Template, extraLabels) {
+			logger.Info("Updating worker labels", "worker", workerType)
+			changed = true
+		}
+
 		if changed {
 			return r.Update(ctx, deploy)
 		}
@@ -117,7 +270,7 @@ func (r *FrappeBenchReconciler) ensureWorkerDeployment(ctx context.Context, benc
 
 	logger.Info("Creating Worker Deployment", "deployment", deployName, "queue", queue, "replicas", replicas, "kedaManaged", kedaManaged)
 
-	image := r.getBenchImage(ctx, bench)
+	image := r.getComponentImage(ctx, bench, "worker")
 	pvcName := fmt.Sprintf("%s-sites", bench.Name)
 
 	// Add annotations to indicate scaling mode
@@ -130,26 +283,31 @@ func (r *FrappeBenchReconciler) ensureWorkerDeployment(ctx context.Context, benc
 	}
 
 	container := resources.NewContainerBuilder("worker", image).
-		WithArgs("bench", "worker", "--queue", queue).
-		WithVolumeMountSubPath("sites", "/home/frappe/frappe-bench/sites", "frappe-sites").
+		WithArgs(workerArgs(queue)...).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
 		WithResources(workerResources).
 		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		WithLifecycle(r.getWorkerLifecycle(config)).
 		WithEnv("USER", "frappe").
 		Build()
 
 	// Apply Pod Config
-	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, r.benchLabels(bench))
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(bench.Spec.PodConfig, ownedLabels(bench, r.benchLabels(bench)))
 
 	deploy, err = resources.NewDeploymentBuilder(deployName, bench.Namespace).
 		WithLabels(extraLabels).
 		WithExtraPodLabels(extraLabels).
 		WithSelector(r.componentLabels(bench, fmt.Sprintf("worker-%s", workerType))).
+		WithPodAnnotations(componentPodTemplateAnnotations(bench, fmt.Sprintf("worker-%s", workerType))).
 		WithAnnotations(annotations).
 		WithReplicas(replicas).
 		WithNodeSelector(nodeSelector).
 		WithAffinity(affinity).
 		WithTolerations(tolerations).
 		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(componentServiceAccount(bench)).
+		WithTerminationGracePeriod(config.TerminationGracePeriodSeconds).
 		WithContainer(container).
 		WithPVCVolume("sites", pvcName).
 		WithOwner(bench, r.Scheme).
@@ -157,12 +315,20 @@ func (r *FrappeBenchReconciler) ensureWorkerDeployment(ctx context.Context, benc
 	if err != nil {
 		return err
 	}
+	syncSharedAssets(&deploy.Spec.Template.Spec, bench)
+	syncExtraVolumes(&deploy.Spec.Template, bench)
 
 	return r.Create(ctx, deploy)
 }
 
-// isKEDAAvailable checks if KEDA CRDs are installed
-func (r *FrappeBenchReconciler) isKEDAAvailable(ctx context.Context) bool {
+// workerArgs is the command line of a worker consuming queue
+func workerArgs(queue string) []string {
+	return []string{"bench", "worker", "--queue", queue}
+}
+
+// isKEDAAvailable checks if KEDA CRDs are installed. The check is bounded by LookupTimeout;
+// a KEDA API that doesn't answer in time is reported as an error rather than a guess.
+func (r *FrappeBenchReconciler) isKEDAAvailable(ctx context.Context) (bool, error) {
 	// Create a minimal unstructured list to check if the resource exists
 	list := &metav1.PartialObjectMetadataList{}
 	list.SetGroupVersionKind(schema.GroupVersionKind{
@@ -172,20 +338,25 @@ func (r *FrappeBenchReconciler) isKEDAAvailable(ctx context.Context) bool {
 	})
 
 	// Attempt to list - if this succeeds, KEDA is available
-	err := r.Client.List(ctx, list, client.Limit(1))
+	err := withLookupTimeout(ctx, r.LookupTimeout, "KEDA ScaledObject API", func(ctx context.Context) error {
+		return r.Client.List(ctx, list, client.Limit(1))
+	})
+	if isLookupTimeout(err) {
+		return false, err
+	}
 
 	// NoMatchError means the CRD doesn't exist
 	if errors.IsNotFound(err) {
-		return false
+		return false, nil
 	}
 
 	// Any other error or success means KEDA is likely available
 	// We don't care about permission errors - just whether the CRD exists
-	return true
+	return true, nil
 }
 
 // ensureScaledObject creates or updates a KEDA ScaledObject for a worker
-func (r *FrappeBenchReconciler) ensureScaledObject(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, workerType string, config *vyogotechv1alpha1.WorkerAutoscaling) error {
+func (r *FrappeBenchReconciler) ensureScaledObject(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench, workerType, queue string, config *vyogotechv1alpha1.WorkerAutoscaling) error {
 	logger := log.FromContext(ctx)
 
 	// Skip if KEDA is not enabled for this worker
@@ -195,14 +366,28 @@ func (r *FrappeBenchReconciler) ensureScaledObject(ctx context.Context, bench *v
 	}
 
 	// Check if KEDA is available
-	if !r.isKEDAAvailable(ctx) {
+	kedaAvailable, err := r.isKEDAAvailable(ctx)
+	if err != nil {
+		return err
+	}
+	if !kedaAvailable {
 		logger.Info("KEDA not available, skipping ScaledObject creation", "worker", workerType)
 		return nil
 	}
 
+	redisAddress, authName, err := r.kedaRedisTarget(ctx, bench)
+	if err != nil {
+		return err
+	}
+	if err := withLookupTimeout(ctx, r.LookupTimeout, "KEDA TriggerAuthentication API", func(ctx context.Context) error {
+		return r.ensureRedisTriggerAuthentication(ctx, bench, authName)
+	}); err != nil {
+		return fmt.Errorf("failed to ensure redis TriggerAuthentication: %w", err)
+	}
+
 	scaledObjectName := fmt.Sprintf("%s-worker-%s", bench.Name, workerType)
 	deploymentName := fmt.Sprintf("%s-worker-%s", bench.Name, workerType)
-	queueName := fmt.Sprintf("rq:queue:%s", workerType)
+	queueName := fmt.Sprintf("rq:queue:%s", queue)
 
 	// Build the ScaledObject using unstructured
 	scaledObject := &unstructured.Unstructured{}
@@ -226,20 +411,22 @@ func (r *FrappeBenchReconciler) ensureScaledObject(ctx context.Context, bench *v
 		"maxReplicaCount": int64(*config.MaxReplicas),
 		"cooldownPeriod":  int64(*config.CooldownPeriod),
 		"pollingInterval": int64(*config.PollingInterval),
-		"triggers": []interface{}{
-			map[string]interface{}{
-				"type": "redis",
-				"metadata": map[string]interface{}{
-					"address":              r.getRedisAddress(bench),
-					"listName":             queueName,
-					"listLength":           fmt.Sprintf("%d", *config.QueueLength),
-					"enableTLS":            "false",
-					"databaseIndex":        "0",
-					"activationListLength": "1",
-				},
-			},
+	}
+	trigger := map[string]interface{}{
+		"type": "redis",
+		"metadata": map[string]interface{}{
+			"address":              redisAddress,
+			"listName":             queueName,
+			"listLength":           fmt.Sprintf("%d", *config.QueueLength),
+			"enableTLS":            "false",
+			"databaseIndex":        "0",
+			"activationListLength": "1",
 		},
 	}
+	if authName != "" {
+		trigger["authenticationRef"] = map[string]interface{}{"name": authName}
+	}
+	spec["triggers"] = []interface{}{trigger}
 
 	if err := unstructured.SetNestedField(scaledObject.Object, spec, "spec"); err != nil {
 		return fmt.Errorf("failed to set ScaledObject spec: %w", err)
@@ -250,22 +437,24 @@ func (r *FrappeBenchReconciler) ensureScaledObject(ctx context.Context, bench *v
 		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	// Create or update
-	existing := &unstructured.Unstructured{}
-	existing.SetGroupVersionKind(scaledObject.GroupVersionKind())
-	err := r.Get(ctx, types.NamespacedName{Name: scaledObjectName, Namespace: bench.Namespace}, existing)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			logger.Info("Creating ScaledObject", "worker", workerType, "name", scaledObjectName)
-			return r.Create(ctx, scaledObject)
+	// Create or update, bounded like the KEDA API probe
+	return withLookupTimeout(ctx, r.LookupTimeout, "KEDA ScaledObject API", func(ctx context.Context) error {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(scaledObject.GroupVersionKind())
+		err := r.Get(ctx, types.NamespacedName{Name: scaledObjectName, Namespace: bench.Namespace}, existing)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				logger.Info("Creating ScaledObject", "worker", workerType, "name", scaledObjectName)
+				return r.Create(ctx, scaledObject)
+			}
+			return err
 		}
-		return err
-	}
 
-	// Update existing
-	scaledObject.SetResourceVersion(existing.GetResourceVersion())
-	logger.Info("Updating ScaledObject", "worker", workerType, "name", scaledObjectName)
-	return r.Update(ctx, scaledObject)
+		// Update existing
+		scaledObject.SetResourceVersion(existing.GetResourceVersion())
+		logger.Info("Updating ScaledObject", "worker", workerType, "name", scaledObjectName)
+		return r.Update(ctx, scaledObject)
+	})
 }
 
 // deleteScaledObjectIfExists deletes a ScaledObject if it exists
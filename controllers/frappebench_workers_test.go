@@ -0,0 +1,204 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureWorkerDeployment_Lifecycle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	getWorker := func(workerType string) *appsv1.Deployment {
+		t.Helper()
+		deploy := &appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-" + workerType, Namespace: "default"}, deploy); err != nil {
+			t.Fatalf("Get %s worker: %v", workerType, err)
+		}
+		return deploy
+	}
+
+	for _, workerType := range []string{"long", "short"} {
+		config := r.fillAutoscalingDefaults(nil, workerType)
+		// Scale-to-zero with KEDA still gets the same lifecycle on every pod it removes
+		if err := r.ensureWorkerDeployment(ctx, bench, workerType, workerType, 0, corev1.ResourceRequirements{}, config, true); err != nil {
+			t.Fatalf("ensureWorkerDeployment(%s): %v", workerType, err)
+		}
+	}
+
+	long := getWorker("long")
+	if hook := long.Spec.Template.Spec.Containers[0].Lifecycle; hook == nil || hook.PreStop == nil || hook.PreStop.Exec == nil {
+		t.Errorf("expected long worker to drain via preStop hook, got %+v", hook)
+	}
+	if grace := long.Spec.Template.Spec.TerminationGracePeriodSeconds; grace == nil || *grace != 1500 {
+		t.Errorf("expected long worker grace period 1500, got %v", grace)
+	}
+
+	short := getWorker("short")
+	if hook := short.Spec.Template.Spec.Containers[0].Lifecycle; hook != nil {
+		t.Errorf("expected short worker to exit without draining, got %+v", hook)
+	}
+	if grace := short.Spec.Template.Spec.TerminationGracePeriodSeconds; grace == nil || *grace != 30 {
+		t.Errorf("expected short worker grace period 30, got %v", grace)
+	}
+
+	// Turning drain on for an existing worker updates the deployment in place
+	config := r.fillAutoscalingDefaults(&vyogotechv1alpha1.WorkerAutoscaling{
+		DrainOnStop:                   boolPtr(true),
+		TerminationGracePeriodSeconds: int64Ptr(120),
+	}, "short")
+	if err := r.ensureWorkerDeployment(ctx, bench, "short", "short", 0, corev1.ResourceRequirements{}, config, true); err != nil {
+		t.Fatalf("ensureWorkerDeployment(short) update: %v", err)
+	}
+	short = getWorker("short")
+	if hook := short.Spec.Template.Spec.Containers[0].Lifecycle; hook == nil || hook.PreStop == nil {
+		t.Errorf("expected short worker preStop hook after enabling drain, got %+v", hook)
+	}
+	if grace := short.Spec.Template.Spec.TerminationGracePeriodSeconds; grace == nil || *grace != 120 {
+		t.Errorf("expected short worker grace period 120, got %v", grace)
+	}
+}
+
+func TestWorkerDrainCommand_signalsPID1(t *testing.T) {
+	r := &FrappeBenchReconciler{}
+	hook := r.getWorkerLifecycle(&vyogotechv1alpha1.WorkerAutoscaling{DrainOnStop: boolPtr(true)})
+	if hook == nil || hook.PreStop == nil || hook.PreStop.Exec == nil {
+		t.Fatalf("expected a preStop exec hook, got %+v", hook)
+	}
+	command := hook.PreStop.Exec.Command
+	script := command[len(command)-1]
+	// bench worker is PID 1 and drains on SIGTERM; kill -1 would skip it and signal the
+	// work-horse running the job instead
+	if !strings.HasPrefix(script, "kill -TERM 1;") {
+		t.Errorf("expected the hook to signal PID 1, got %q", script)
+	}
+	if strings.Contains(script, "-1") {
+		t.Errorf("expected the hook not to signal every process, got %q", script)
+	}
+}
+
+func TestEnsureWorkers_CustomPools(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default", UID: "bench-uid"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	// Without spec.workers the bench runs the built-in pools
+	if err := r.ensureWorkers(ctx, bench); err != nil {
+		t.Fatalf("ensureWorkers: %v", err)
+	}
+	for _, name := range []string{"default", "long", "short"} {
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-" + name, Namespace: "default"}, &appsv1.Deployment{}); err != nil {
+			t.Errorf("expected built-in %s worker: %v", name, err)
+		}
+	}
+
+	replicas := int32(3)
+	bench.Spec.Workers = []vyogotechv1alpha1.WorkerPool{
+		{Name: "default"},
+		{Name: "integrations", Replicas: &replicas},
+		{Name: "reports", Queue: "long"},
+	}
+	if err := r.ensureWorkers(ctx, bench); err != nil {
+		t.Fatalf("ensureWorkers with pools: %v", err)
+	}
+
+	integrations := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-integrations", Namespace: "default"}, integrations); err != nil {
+		t.Fatalf("Get integrations worker: %v", err)
+	}
+	if args := integrations.Spec.Template.Spec.Containers[0].Args; len(args) != 4 || args[3] != "integrations" {
+		t.Errorf("expected integrations worker to consume its own queue, got args %v", args)
+	}
+	if integrations.Spec.Replicas == nil || *integrations.Spec.Replicas != 3 {
+		t.Errorf("expected 3 integrations replicas, got %v", integrations.Spec.Replicas)
+	}
+
+	reports := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-reports", Namespace: "default"}, reports); err != nil {
+		t.Fatalf("Get reports worker: %v", err)
+	}
+	if args := reports.Spec.Template.Spec.Containers[0].Args; args[3] != "long" {
+		t.Errorf("expected reports worker to consume the long queue, got args %v", args)
+	}
+
+	// Pointing an existing pool at another queue, or giving it resources, updates it in place
+	bench.Spec.Workers[2].Queue = "reports"
+	bench.Spec.Workers[2].Resources = &vyogotechv1alpha1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+	if err := r.ensureWorkers(ctx, bench); err != nil {
+		t.Fatalf("ensureWorkers after renaming the queue: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-reports", Namespace: "default"}, reports); err != nil {
+		t.Fatalf("Get reports worker: %v", err)
+	}
+	if args := reports.Spec.Template.Spec.Containers[0].Args; !reflect.DeepEqual(args, []string{"bench", "worker", "--queue", "reports"}) {
+		t.Errorf("expected reports worker to consume the renamed queue, got args %v", args)
+	}
+	if memory := reports.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory]; memory.String() != "1Gi" {
+		t.Errorf("expected the pool's memory request on the existing worker, got %v", reports.Spec.Template.Spec.Containers[0].Resources)
+	}
+
+	// Built-in pools left out of spec.workers are removed
+	for _, name := range []string{"long", "short"} {
+		if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-" + name, Namespace: "default"}, &appsv1.Deployment{}); err == nil {
+			t.Errorf("expected %s worker to be deleted", name)
+		}
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-worker-default", Namespace: "default"}, &appsv1.Deployment{}); err != nil {
+		t.Errorf("expected default worker to remain: %v", err)
+	}
+
+	components := r.benchDeploymentComponents(bench)
+	want := []string{"gunicorn", "nginx", "socketio", "scheduler", "worker-default", "worker-integrations", "worker-reports"}
+	if !reflect.DeepEqual(components, want) {
+		t.Errorf("benchDeploymentComponents = %v, want %v", components, want)
+	}
+}
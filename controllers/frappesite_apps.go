@@ -0,0 +1,125 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// uninstallAppsAnnotation records on the uninstall job which apps it removes
+const uninstallAppsAnnotation = "frappe.tech/uninstall-apps"
+
+// removedApps returns the apps in status.installedApps that are no longer in spec.apps.
+// frappe is never returned, removing it would break the site.
+func removedApps(site *vyogotechv1alpha1.FrappeSite) []string {
+	var removed []string
+	for _, app := range site.Status.InstalledApps {
+		if app != "frappe" && !slices.Contains(site.Spec.Apps, app) {
+			removed = append(removed, app)
+		}
+	}
+	return removed
+}
+
+// ensureSiteAppsUninstalled uninstalls apps that were removed from spec.apps and drops them
+// from status.installedApps. Returns true once no removed app is left to uninstall.
+func (r *FrappeSiteReconciler) ensureSiteAppsUninstalled(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	var apps []string
+	for _, app := range removedApps(site) {
+		if vyogotechv1alpha1.IsValidAppName(app) {
+			apps = append(apps, app)
+			continue
+		}
+		// Never installed either, the install step skips invalid names
+		r.Recorder.Event(site, corev1.EventTypeWarning, "InvalidAppName",
+			fmt.Sprintf("App '%s' contains invalid characters and will be skipped", app))
+		site.Status.InstalledApps = slices.DeleteFunc(site.Status.InstalledApps, func(installed string) bool { return installed == app })
+	}
+	if len(apps) == 0 {
+		return true, nil
+	}
+	jobName := fmt.Sprintf("%s-uninstall-apps", site.Name)
+	desiredKey := strings.Join(apps, ",")
+	job, err := runSiteJob(ctx, r.Client, site, jobName, siteJobKey{annotation: uninstallAppsAnnotation, value: desiredKey}, func() (*batchv1.Job, error) {
+		site.Status.AppInstallationStatus = fmt.Sprintf("Uninstalling %s...", desiredKey)
+		r.Recorder.Event(site, corev1.EventTypeNormal, "AppUninstalling", fmt.Sprintf("Uninstalling %s from %s", desiredKey, site.Spec.SiteName))
+		return r.buildUninstallAppsJob(ctx, site, bench, jobName, apps)
+	})
+	if errors.Is(err, errJobFailed) {
+		site.Status.AppInstallationStatus = fmt.Sprintf("Failed to uninstall %s", desiredKey)
+	}
+	if job == nil || err != nil {
+		return false, err
+	}
+
+	site.Status.InstalledApps = slices.DeleteFunc(site.Status.InstalledApps, func(installed string) bool {
+		return slices.Contains(apps, installed)
+	})
+	site.Status.AppInstallationStatus = fmt.Sprintf("Uninstalled %s", desiredKey)
+	r.Recorder.Event(site, corev1.EventTypeNormal, "AppUninstalled", fmt.Sprintf("Uninstalled %s from %s", desiredKey, site.Spec.SiteName))
+	return true, nil
+}
+
+// buildUninstallAppsJob returns the job that uninstalls apps from the site
+func (r *FrappeSiteReconciler) buildUninstallAppsJob(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, jobName string, apps []string) (*batchv1.Job, error) {
+	uninstallScript, err := scripts.RenderScript(scripts.AppUninstall, scripts.AppUninstallData{
+		SiteName: site.Spec.SiteName,
+		Apps:     apps,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render app uninstall script: %w", err)
+	}
+
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
+		"app":  "frappe",
+		"site": site.Name,
+	}))
+
+	container := resources.NewContainerBuilder("uninstall-apps", r.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(uninstallScript).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		Build()
+
+	return resources.NewJobBuilder(jobName, site.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationUninstallApps, bench.Name, site.Spec.SiteName)).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(2).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(site, r.Scheme).
+		Build()
+}
@@ -18,7 +18,9 @@ package controllers
 
 import (
 	"context"
+	"slices"
 	"strings"
+	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -60,15 +62,7 @@ var _ = Describe("FrappeSite App Installation", func() {
 			}
 
 			for _, name := range validNames {
-				isValid := true
-				for _, char := range name {
-					if !((char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
-						(char >= '0' && char <= '9') || char == '_' || char == '-') {
-						isValid = false
-						break
-					}
-				}
-				Expect(isValid).To(BeTrue(), "App name '%s' should be valid", name)
+				Expect(vyogotechv1alpha1.IsValidAppName(name)).To(BeTrue(), "App name '%s' should be valid", name)
 			}
 		})
 
@@ -83,15 +77,7 @@ var _ = Describe("FrappeSite App Installation", func() {
 			}
 
 			for _, name := range invalidNames {
-				isValid := true
-				for _, char := range name {
-					if !((char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
-						(char >= '0' && char <= '9') || char == '_' || char == '-') {
-						isValid = false
-						break
-					}
-				}
-				Expect(isValid).To(BeFalse(), "App name '%s' should be invalid", name)
+				Expect(vyogotechv1alpha1.IsValidAppName(name)).To(BeFalse(), "App name '%s' should be invalid", name)
 			}
 		})
 	})
@@ -698,3 +684,83 @@ var _ = Describe("FrappeSite App Installation", func() {
 		})
 	})
 })
+
+func TestEnsureSiteAppsUninstalled(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.Apps = []string{"erpnext"}
+	site.Status.InstalledApps = []string{"frappe", "erpnext", "hrms", "bad;app"}
+	r, c := newInitJobTestReconciler(site, bench)
+	recorder := record.NewFakeRecorder(20)
+	r.Recorder = recorder
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site-uninstall-apps", Namespace: "default"}
+
+	// frappe is never uninstalled
+	if removed := removedApps(site); !slices.Equal(removed, []string{"hrms", "bad;app"}) {
+		t.Errorf("expected hrms and bad;app to be removed, got %v", removed)
+	}
+
+	done, err := r.ensureSiteAppsUninstalled(ctx, site, bench)
+	if err != nil || done {
+		t.Fatalf("expected the uninstall job to start, got done=%v err=%v", done, err)
+	}
+	if slices.Contains(site.Status.InstalledApps, "bad;app") {
+		t.Errorf("expected the invalid app name to be dropped from the status")
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	script := job.Spec.Template.Spec.Containers[0].Args[0]
+	if !strings.Contains(script, `APPS=("hrms")`) || !strings.Contains(script, "uninstall-app") || strings.Contains(script, "bad;app") {
+		t.Errorf("expected the job to uninstall only hrms, got %q", script)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationUninstallApps {
+		t.Errorf("expected operation label %q, got %q", jobOperationUninstallApps, job.Labels[jobOperationLabel])
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	done, err = r.ensureSiteAppsUninstalled(ctx, site, bench)
+	if err != nil || !done {
+		t.Fatalf("expected the uninstall to complete, got done=%v err=%v", done, err)
+	}
+	if !slices.Equal(site.Status.InstalledApps, []string{"frappe", "erpnext"}) {
+		t.Errorf("expected hrms to be dropped from the status, got %v", site.Status.InstalledApps)
+	}
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	joined := strings.Join(events, "\n")
+	if !strings.Contains(joined, "AppUninstalling") || !strings.Contains(joined, "AppUninstalled") {
+		t.Errorf("expected AppUninstalling and AppUninstalled events, got %v", events)
+	}
+}
+
+func TestEnsureSiteAppsUninstalled_JobFailed(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Status.InstalledApps = []string{"hrms"}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "site-uninstall-apps",
+			Namespace:   "default",
+			Annotations: map[string]string{uninstallAppsAnnotation: "hrms"},
+		},
+		Status: batchv1.JobStatus{
+			Failed:     3,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+		},
+	}
+	r, _ := newInitJobTestReconciler(site, bench, job)
+
+	if _, err := r.ensureSiteAppsUninstalled(context.Background(), site, bench); err == nil {
+		t.Fatal("expected an error for the failed uninstall job")
+	}
+	if !slices.Equal(site.Status.InstalledApps, []string{"hrms"}) {
+		t.Errorf("expected hrms to stay in the status, got %v", site.Status.InstalledApps)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newCrossNamespaceSiteObjects returns a site in default referencing a Ready bench in benches
+func newCrossNamespaceSiteObjects() (*vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench) {
+	site, bench := newInitJobTestObjects()
+	bench.Namespace = "benches"
+	bench.Status.Phase = "Ready"
+	site.Spec.BenchRef.Namespace = "benches"
+	site.SetFinalizers([]string{frappeSiteFinalizer})
+	return site, bench
+}
+
+func TestReconcile_rejectsCrossNamespaceBench(t *testing.T) {
+	site, bench := newCrossNamespaceSiteObjects()
+	siteReconciler, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site", Namespace: "default"}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	if err == nil || !strings.Contains(err.Error(), "can only be mounted in namespace benches") {
+		t.Fatalf("expected the cross-namespace bench to be rejected, got %v", err)
+	}
+	updated := &vyogotechv1alpha1.FrappeSite{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if updated.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseFailed {
+		t.Errorf("expected phase Failed, got %s", updated.Status.Phase)
+	}
+	ready := meta.FindStatusCondition(updated.Status.Conditions, "Ready")
+	if ready == nil || ready.Reason != "CrossNamespaceBench" {
+		t.Errorf("expected Ready reason CrossNamespaceBench, got %+v", ready)
+	}
+	for _, ns := range []string{"default", "benches"} {
+		if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: ns}, &batchv1.Job{}); !errors.IsNotFound(err) {
+			t.Errorf("expected no init job in %s, got %v", ns, err)
+		}
+	}
+}
+
+func TestDeleteSite_crossNamespaceBench(t *testing.T) {
+	site, bench := newCrossNamespaceSiteObjects()
+	initSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "site-init-secrets", Namespace: "default"}}
+	r, c := newInitJobTestReconciler(site, bench, initSecret)
+	ctx := context.Background()
+
+	// The site never ran on the bench, so there is nothing to drop
+	if err := r.deleteSite(ctx, site); err != nil {
+		t.Fatalf("deleteSite: %v", err)
+	}
+	for _, ns := range []string{"default", "benches"} {
+		if err := c.Get(ctx, types.NamespacedName{Name: "site-delete", Namespace: ns}, &batchv1.Job{}); !errors.IsNotFound(err) {
+			t.Errorf("expected no deletion job in %s, got %v", ns, err)
+		}
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init-secrets", Namespace: "default"}, &corev1.Secret{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the init secret to be deleted, got %v", err)
+	}
+}
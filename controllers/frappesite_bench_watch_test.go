@@ -0,0 +1,94 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestSitesForBench(t *testing.T) {
+	newSite := func(name, namespace string, benchRef *vyogotechv1alpha1.NamespacedName) *vyogotechv1alpha1.FrappeSite {
+		return &vyogotechv1alpha1.FrappeSite{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       vyogotechv1alpha1.FrappeSiteSpec{SiteName: name + ".local", BenchRef: benchRef},
+		}
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "shared"}}
+	r, _ := newInitJobTestReconciler(
+		bench,
+		// Same namespace, benchRef namespace defaulted
+		newSite("local", "shared", &vyogotechv1alpha1.NamespacedName{Name: "bench"}),
+		// Cross-namespace benchRef
+		newSite("remote", "team-a", &vyogotechv1alpha1.NamespacedName{Name: "bench", Namespace: "shared"}),
+		// Same bench name in another namespace
+		newSite("other", "team-b", &vyogotechv1alpha1.NamespacedName{Name: "bench"}),
+		newSite("unrelated", "shared", &vyogotechv1alpha1.NamespacedName{Name: "other-bench"}),
+		newSite("no-ref", "shared", nil),
+	)
+
+	requests := r.sitesForBench(context.Background(), bench)
+	var got []string
+	for _, req := range requests {
+		got = append(got, req.Namespace+"/"+req.Name)
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "shared/local" || got[1] != "team-a/remote" {
+		t.Errorf("expected shared/local and team-a/remote, got %v", got)
+	}
+}
+
+func TestBenchChangeAffectsSites(t *testing.T) {
+	base := func() *vyogotechv1alpha1.FrappeBench {
+		return &vyogotechv1alpha1.FrappeBench{
+			ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+			Spec: vyogotechv1alpha1.FrappeBenchSpec{
+				FrappeVersion: "15",
+				ImageConfig:   &vyogotechv1alpha1.ImageConfig{Repository: "frappe/erpnext", Tag: "v15.0.0"},
+			},
+			Status: vyogotechv1alpha1.FrappeBenchStatus{Phase: "Provisioning"},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(*vyogotechv1alpha1.FrappeBench)
+		want   bool
+	}{
+		{name: "becomes ready", change: func(b *vyogotechv1alpha1.FrappeBench) { b.Status.Phase = "Ready" }, want: true},
+		{name: "image tag", change: func(b *vyogotechv1alpha1.FrappeBench) { b.Spec.ImageConfig.Tag = "v15.1.0" }, want: true},
+		{name: "image digest", change: func(b *vyogotechv1alpha1.FrappeBench) { b.Status.ImageDigest = "sha256:abc" }, want: true},
+		{name: "migrated", change: func(b *vyogotechv1alpha1.FrappeBench) { b.Status.MigratedImage = "frappe/erpnext:v15.1.0" }, want: true},
+		{name: "worker scaling", change: func(b *vyogotechv1alpha1.FrappeBench) {
+			b.Status.WorkerScaling = map[string]vyogotechv1alpha1.WorkerScalingStatus{"short": {Mode: "static", CurrentReplicas: 2}}
+		}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldBench, newBench := base(), base()
+			tt.change(newBench)
+			if got := benchChangeAffectsSites(event.UpdateEvent{ObjectOld: oldBench, ObjectNew: newBench}); got != tt.want {
+				t.Errorf("benchChangeAffectsSites() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
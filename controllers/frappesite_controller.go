@@ -19,11 +19,14 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	"github.com/vyogotech/frappe-operator/controllers/database"
 	"github.com/vyogotech/frappe-operator/pkg/backoff"
+	"github.com/vyogotech/frappe-operator/pkg/progress"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -35,10 +38,15 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
@@ -55,6 +63,23 @@ type FrappeSiteReconciler struct {
 	Recorder                record.EventRecorder
 	IsOpenShift             bool
 	MaxConcurrentReconciles int
+	// LookupTimeout bounds calls against optional operator APIs (MariaDB, CloudNativePG);
+	// defaults to 10s
+	LookupTimeout time.Duration
+	// ProvisioningBackoffBase and ProvisioningBackoffMax bound the requeue delay while a
+	// site waits for its bench or database; default to 10s and 5m
+	ProvisioningBackoffBase time.Duration
+	ProvisioningBackoffMax  time.Duration
+	// MaxProvisioningAttempts fails a site once it has waited this many backoff steps for
+	// its bench or database; 0 waits forever
+	MaxProvisioningAttempts int32
+	// LogReader tails the log of failed site init pods into status.initFailureLog; skipped when nil
+	LogReader progress.LogReader
+
+	// siteInits runs one site-init job at a time per bench whose sites PVC isn't ReadWriteMany
+	siteInits siteInitGate
+	// warnings keeps warnings re-checked on every reconcile from repeating their events
+	warnings eventDeduper
 }
 
 //+kubebuilder:rbac:groups=vyogo.tech,resources=frappesites,verbs=get;list;watch;create;update;patch;delete
@@ -65,7 +90,10 @@ type FrappeSiteReconciler struct {
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;ingressclasses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=secrets;services;configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=k8s.mariadb.com,resources=mariadbs;databases;users;grants,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=postgresql.cnpg.io,resources=clusters,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=postgresql.cnpg.io,resources=databases,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=route.openshift.io,resources=routes;routes/custom-host,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop
@@ -77,9 +105,12 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if err := r.Get(ctx, req.NamespacedName, site); err != nil {
 		if !errors.IsNotFound(err) {
 			ReconciliationErrors.WithLabelValues("frappesite", "fetch_error").Inc()
+		} else {
+			sitePhases.forget(req.NamespacedName)
 		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	sitePhases.observe(req.NamespacedName, string(site.Status.Phase))
 
 	logger.Info("Reconciling FrappeSite", "site", site.Name, "siteName", site.Spec.SiteName)
 	r.Recorder.Event(site, corev1.EventTypeNormal, "Reconciling", "Starting FrappeSite reconciliation")
@@ -93,10 +124,75 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		r.Recorder.Event(site, corev1.EventTypeNormal, "FinalizerAdded", "Finalizer added to FrappeSite")
 	}
 
-	// Early-exit guard
+	// A paused site keeps its jobs, Ingress and Route exactly as they are; deletion still proceeds
+	if site.GetDeletionTimestamp() == nil {
+		if setPausedCondition(&site.Status.Conditions, site.Spec.Paused, site.Generation) {
+			if site.Spec.Paused {
+				r.Recorder.Event(site, corev1.EventTypeNormal, "Paused", "Reconciliation paused by spec.paused")
+			}
+			if err := r.updateStatus(ctx, site); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		if site.Spec.Paused {
+			logger.Info("FrappeSite is paused, skipping reconciliation")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// Enforce the operator's required-label policy; label changes don't bump the generation,
+	// so this runs ahead of the early-exit guard
+	if site.GetDeletionTimestamp() == nil {
+		// A missing ConfigMap means no policy
+		operatorConfig, _ := r.getOperatorConfig(ctx, site.Namespace)
+		policy := labelPolicyFromConfig(operatorConfig)
+		missingLabels, policyChanged := policy.evaluate(site, &site.Status.Conditions)
+		if policyChanged && len(missingLabels) > 0 {
+			r.Recorder.Event(site, corev1.EventTypeWarning, "PolicyViolation", fmt.Sprintf("Missing required labels: %s", strings.Join(missingLabels, ", ")))
+		}
+		if policy.blocks(missingLabels, site.Status.Phase == vyogotechv1alpha1.FrappeSitePhaseReady) {
+			logger.Info("Required labels missing, holding provisioning", "missing", missingLabels)
+			site.Status.Phase = vyogotechv1alpha1.FrappeSitePhasePending
+			r.setCondition(site, metav1.Condition{
+				Type:    "Ready",
+				Status:  metav1.ConditionFalse,
+				Reason:  "PolicyViolation",
+				Message: fmt.Sprintf("Provisioning is blocked until the required labels are set: %s", strings.Join(missingLabels, ", ")),
+			})
+			return ctrl.Result{}, r.updateStatus(ctx, site)
+		}
+		if policyChanged {
+			if err := r.updateStatus(ctx, site); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	// Early-exit guard; rotated database credentials, an edited siteConfigSecretRef Secret
+	// and a suspended bench don't bump the generation either
 	if site.Status.Phase == vyogotechv1alpha1.FrappeSitePhaseReady && site.Status.ObservedGeneration == site.Generation {
-		logger.V(1).Info("Site is Ready and spec unchanged, skipping reconciliation")
-		return ctrl.Result{}, nil
+		suspended, err := r.benchSuspended(ctx, site)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		rotated, err := r.dbCredentialsRotated(ctx, site)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		secretChanged, err := r.siteConfigSecretChanged(ctx, site)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !rotated && !secretChanged && !suspended {
+			logger.V(1).Info("Site is Ready and spec unchanged, skipping reconciliation")
+			return ctrl.Result{}, nil
+		}
+		if rotated {
+			logger.Info("Database credentials changed, updating site_config.json")
+		}
+		if secretChanged {
+			logger.Info("Site config secret changed, updating site_config.json")
+		}
 	}
 
 	// Handle deletion
@@ -117,10 +213,14 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 			if err := r.deleteSite(ctx, site); err != nil {
 				logger.Error(err, "Failed to delete site, will requeue")
+				reason := "DeletionInProgress"
+				if isLookupTimeout(err) {
+					reason = "LookupTimeout"
+				}
 				r.setCondition(site, metav1.Condition{
 					Type:    "Terminating",
 					Status:  metav1.ConditionTrue,
-					Reason:  "DeletionInProgress",
+					Reason:  reason,
 					Message: fmt.Sprintf("Site deletion in progress: %v", err),
 				})
 				_ = r.updateStatus(ctx, site)
@@ -130,14 +230,14 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(15*time.Second, attempt, requeueBackoffMax)}, nil
 			}
 
-			// Cleanup remaining resources if any
-			// (Secret and Ingress cleanup already partially handled by site finalizer or owner refs)
+			// deleteSite removed the credential Secrets; the Ingress and jobs go with the owner references
 
 			logger.Info("FrappeSite cleanup complete, removing finalizer")
 			controllerutil.RemoveFinalizer(site, frappeSiteFinalizer)
 			if err := r.Update(ctx, site); err != nil {
 				return ctrl.Result{}, err
 			}
+			sitePhases.forget(req.NamespacedName)
 		}
 		return ctrl.Result{}, nil
 	}
@@ -157,12 +257,13 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if site.Spec.BenchRef == nil {
 		return r.failReconciliation(ctx, site, "benchRef is required", "ValidationFailed")
 	}
+	// The webhook rejects these too, but it may not be deployed
+	if err := site.ValidateBenchNamespace(); err != nil {
+		return r.failReconciliation(ctx, site, err.Error(), "CrossNamespaceBench")
+	}
 
 	bench := &vyogotechv1alpha1.FrappeBench{}
-	benchKey := types.NamespacedName{Name: site.Spec.BenchRef.Name, Namespace: site.Spec.BenchRef.Namespace}
-	if benchKey.Namespace == "" {
-		benchKey.Namespace = site.Namespace
-	}
+	benchKey := siteBenchKey(site)
 
 	if err := r.Get(ctx, benchKey, bench); err != nil {
 		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhasePending
@@ -178,6 +279,36 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(30*time.Second, attempt, requeueBackoffMax)}, nil
 	}
 
+	if benchMigrationPending(bench) {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhasePending
+		r.setCondition(site, metav1.Condition{
+			Type:    "BenchReady",
+			Status:  metav1.ConditionFalse,
+			Reason:  "BenchMigrating",
+			Message: fmt.Sprintf("Bench %s is migrating its sites", bench.Name),
+		})
+		_ = r.updateStatus(ctx, site)
+		return ctrl.Result{RequeueAfter: progressPollInterval}, nil
+	}
+
+	// Resolved before waiting on the bench, so the Domain column is filled in early
+	domain, domainSource := r.resolveDomain(ctx, site, bench)
+	site.Status.ResolvedDomain = domain
+	site.Status.DomainSource = domainSource
+
+	// A suspended bench comes back when resumed, so the site waits without giving up;
+	// the bench watch reconciles the site again then
+	if bench.Spec.Suspended {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhasePending
+		r.setCondition(site, metav1.Condition{
+			Type:    "BenchReady",
+			Status:  metav1.ConditionFalse,
+			Reason:  "BenchNotReady",
+			Message: fmt.Sprintf("Bench %s is suspended", bench.Name),
+		})
+		return ctrl.Result{}, r.updateStatus(ctx, site)
+	}
+
 	if bench.Status.Phase != "Ready" {
 		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhasePending
 		r.setCondition(site, metav1.Condition{
@@ -186,10 +317,7 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			Reason:  "BenchNotReady",
 			Message: fmt.Sprintf("Bench %s is not ready", bench.Name),
 		})
-		_ = r.updateStatus(ctx, site)
-		attempt := r.getRequeueAttempt(site)
-		_ = r.patchRequeueAttempt(ctx, site, attempt+1)
-		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+		return r.waitForProvisioning(ctx, site, waitBenchNotReady, fmt.Sprintf("Bench %s is not ready", bench.Name))
 	}
 
 	r.setCondition(site, metav1.Condition{
@@ -198,18 +326,41 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		Reason:  "BenchReady",
 		Message: "Referenced bench is ready",
 	})
+	clearProvisioningWait(site, waitBenchNotReady)
 
-	// Resolve Domain and DB Config
-	domain, domainSource := r.resolveDomain(ctx, site, bench)
-	site.Status.ResolvedDomain = domain
-	site.Status.DomainSource = domainSource
+	// Resolve DB Config
 	dbConfig := r.resolveDBConfig(site, bench)
+	// The webhook rejects this too, but it may not be deployed
+	if err := vyogotechv1alpha1.ValidateDBSecretNamespace(&dbConfig, site.Namespace); err != nil {
+		return r.failReconciliation(ctx, site, err.Error(), "CrossNamespaceDBSecret")
+	}
+
+	// Fail fast on referenced Secrets that don't exist instead of failing deep inside a job
+	missingSecret, err := findMissingSecret(ctx, r.Client, siteSecretRefs(site, dbConfig))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if setMissingSecretCondition(&site.Status.Conditions, missingSecret, site.Generation) && missingSecret != "" {
+		r.Recorder.Event(site, corev1.EventTypeWarning, "MissingSecret", missingSecret)
+	}
+	if missingSecret != "" {
+		logger.Info("Referenced Secret missing, holding provisioning", "reason", missingSecret)
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhasePending
+		r.setCondition(site, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "MissingSecret",
+			Message: missingSecret,
+		})
+		return ctrl.Result{RequeueAfter: missingSecretRequeue}, r.updateStatus(ctx, site)
+	}
 
 	// Provision Database
 	dbProvider, err := database.NewProvider(dbConfig, r.Client, r.Scheme)
 	if err != nil {
-		return r.failReconciliation(ctx, site, fmt.Sprintf("Failed to create database provider: %v", err), "DatabaseProviderFailed")
+		return r.failDatabase(ctx, site, "Failed to create database provider", err)
 	}
+	dbProvider = withProviderLookupTimeout(dbProvider, r.LookupTimeout)
 
 	dbReady, err := dbProvider.IsReady(ctx, site)
 	if err != nil || !dbReady {
@@ -217,7 +368,7 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			_, err = dbProvider.EnsureDatabase(ctx, site)
 		}
 		if err != nil {
-			return r.failReconciliation(ctx, site, fmt.Sprintf("Database provisioning failed: %v", err), "DatabaseFailed")
+			return r.failDatabase(ctx, site, "Database provisioning failed", err)
 		}
 		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
 		r.setCondition(site, metav1.Condition{
@@ -226,10 +377,7 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			Reason:  "Provisioning",
 			Message: "Database is being provisioned",
 		})
-		_ = r.updateStatus(ctx, site)
-		attempt := r.getRequeueAttempt(site)
-		_ = r.patchRequeueAttempt(ctx, site, attempt+1)
-		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+		return r.waitForProvisioning(ctx, site, waitDatabaseProvisioning, "Database is not ready")
 	}
 
 	r.setCondition(site, metav1.Condition{
@@ -238,15 +386,40 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		Reason:  "DatabaseReady",
 		Message: "Database is ready",
 	})
+	clearProvisioningWait(site, waitDatabaseProvisioning)
 
 	dbInfo, _ := dbProvider.EnsureDatabase(ctx, site)
 	dbCreds, _ := dbProvider.GetCredentials(ctx, site)
 	site.Status.DatabaseName = dbInfo.Name
 	site.Status.DatabaseCredentialsSecret = dbCreds.SecretName
+	site.Status.DatabaseCredentialsSecretNamespace = ""
+	if dbCreds.SecretNamespace != site.Namespace {
+		site.Status.DatabaseCredentialsSecretNamespace = dbCreds.SecretNamespace
+	}
 
 	// Initialize Site
 	siteReady, err := r.ensureSiteInitialized(ctx, site, bench, domain, dbInfo, dbCreds)
+	if isSiteInitQueued(err) {
+		logger.Info("Waiting for another site-init job on the bench, requeueing", "reason", err.Error())
+		message := fmt.Sprintf("Waiting for another site-init job on bench %s (%v)", bench.Name, err)
+		// Requeued every 15s; only a change of the job it waits for is worth an event
+		if cond := meta.FindStatusCondition(site.Status.Conditions, "Progressing"); cond == nil || cond.Reason != "InitQueued" || cond.Message != message {
+			r.Recorder.Event(site, corev1.EventTypeNormal, "InitQueued",
+				fmt.Sprintf("Sites volume of bench %s is ReadWriteOnce; %v", bench.Name, err))
+		}
+		r.setCondition(site, metav1.Condition{
+			Type:    "Progressing",
+			Status:  metav1.ConditionTrue,
+			Reason:  "InitQueued",
+			Message: message,
+		})
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+		return ctrl.Result{RequeueAfter: siteInitQueuedRequeue}, r.updateStatus(ctx, site)
+	}
 	if err != nil {
+		if isSiteInitTimeout(err) {
+			return r.failReconciliation(ctx, site, fmt.Sprintf("Site initialization failed: %v", err), "InitTimeout")
+		}
 		return r.failReconciliation(ctx, site, fmt.Sprintf("Site initialization failed: %v", err), "SiteInitializationFailed")
 	}
 
@@ -258,32 +431,189 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
 	}
 
+	// Run bench migrate once after initialization before the site is marked Ready
+	migrated, err := r.ensureSiteMigrated(ctx, site, bench)
+	if err != nil {
+		return r.failReconciliation(ctx, site, fmt.Sprintf("Site migration failed: %v", err), "SiteMigrationFailed")
+	}
+	if !migrated {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+		_ = r.updateStatus(ctx, site)
+		attempt := r.getRequeueAttempt(site)
+		_ = r.patchRequeueAttempt(ctx, site, attempt+1)
+		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+	}
+
+	// Rewrite site_config.json after the database credentials rotated
+	credsSynced, err := r.ensureSiteDBCredentials(ctx, site, bench, dbCreds)
+	if err != nil {
+		return r.failReconciliation(ctx, site, fmt.Sprintf("Database credentials update failed: %v", err), "DBCredentialsUpdateFailed")
+	}
+	if !credsSynced {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+		_ = r.updateStatus(ctx, site)
+		attempt := r.getRequeueAttempt(site)
+		_ = r.patchRequeueAttempt(ctx, site, attempt+1)
+		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+	}
+
+	// Uninstall apps removed from spec.apps
+	appsSynced, err := r.ensureSiteAppsUninstalled(ctx, site, bench)
+	if err != nil {
+		return r.failReconciliation(ctx, site, fmt.Sprintf("App uninstall failed: %v", err), "AppUninstallFailed")
+	}
+	if !appsSynced {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+		_ = r.updateStatus(ctx, site)
+		attempt := r.getRequeueAttempt(site)
+		_ = r.patchRequeueAttempt(ctx, site, attempt+1)
+		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+	}
+
+	// Apply spec.cors to site_config.json
+	corsApplied, err := r.ensureSiteCORS(ctx, site, bench)
+	if err != nil {
+		return r.failReconciliation(ctx, site, fmt.Sprintf("CORS configuration failed: %v", err), "CORSConfigFailed")
+	}
+	if !corsApplied {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+		_ = r.updateStatus(ctx, site)
+		attempt := r.getRequeueAttempt(site)
+		_ = r.patchRequeueAttempt(ctx, site, attempt+1)
+		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+	}
+
+	// Merge spec.siteConfig into site_config.json
+	siteConfigApplied, err := r.ensureSiteConfig(ctx, site, bench)
+	if err != nil {
+		return r.failReconciliation(ctx, site, fmt.Sprintf("Site config update failed: %v", err), "SiteConfigFailed")
+	}
+	if !siteConfigApplied {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+		_ = r.updateStatus(ctx, site)
+		attempt := r.getRequeueAttempt(site)
+		_ = r.patchRequeueAttempt(ctx, site, attempt+1)
+		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+	}
+
+	// Switch Frappe maintenance mode to match spec.maintenanceMode
+	maintenanceApplied, err := r.ensureSiteMaintenanceMode(ctx, site, bench)
+	if err != nil {
+		return r.failReconciliation(ctx, site, fmt.Sprintf("Maintenance mode switch failed: %v", err), "MaintenanceModeFailed")
+	}
+	if !maintenanceApplied {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+		_ = r.updateStatus(ctx, site)
+		attempt := r.getRequeueAttempt(site)
+		_ = r.patchRequeueAttempt(ctx, site, attempt+1)
+		return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+	}
+
+	// Publish the Ingress/Route only once nginx can serve it, so a new domain doesn't
+	// start out answering 503
+	if siteIngressEnabled(site) && !networkingPublished(site) {
+		ready, err := r.nginxHasReadyEndpoint(ctx, bench)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !ready {
+			logger.Info("Bench nginx has no ready endpoint, holding back the Ingress/Route")
+			site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+			r.setCondition(site, metav1.Condition{
+				Type:    networkingPendingCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  "NoReadyEndpoints",
+				Message: fmt.Sprintf("Waiting for a ready endpoint of Service %s-nginx", bench.Name),
+			})
+			return ctrl.Result{RequeueAfter: networkingPendingRequeue}, r.updateStatus(ctx, site)
+		}
+	}
+
+	// Hold back the public Ingress/Route until the site responds through nginx
+	if site.Spec.PublishWhenHealthy {
+		healthy, err := r.ensureSiteHealthy(ctx, site, bench, domain)
+		if err != nil {
+			r.setCondition(site, metav1.Condition{
+				Type:    "PublishGated",
+				Status:  metav1.ConditionTrue,
+				Reason:  "HealthCheckFailed",
+				Message: fmt.Sprintf("Site did not respond through nginx within %ds", siteHealthCheckTimeoutSeconds),
+			})
+			return r.failReconciliation(ctx, site, fmt.Sprintf("Site health check failed: %v", err), "HealthCheckFailed")
+		}
+		if !healthy {
+			site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+			r.setCondition(site, metav1.Condition{
+				Type:    "PublishGated",
+				Status:  metav1.ConditionTrue,
+				Reason:  "AwaitingHealthCheck",
+				Message: "Waiting for the site to respond through nginx before publishing it",
+			})
+			_ = r.updateStatus(ctx, site)
+			attempt := r.getRequeueAttempt(site)
+			_ = r.patchRequeueAttempt(ctx, site, attempt+1)
+			return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+		}
+		r.setCondition(site, metav1.Condition{
+			Type:    "PublishGated",
+			Status:  metav1.ConditionFalse,
+			Reason:  "HealthCheckPassed",
+			Message: "Site responded through nginx",
+		})
+	}
+
 	// External Access (Ingress/Route)
-	if site.Spec.Ingress == nil || site.Spec.Ingress.Enabled == nil || *site.Spec.Ingress.Enabled {
-		if r.IsOpenShift && (site.Spec.RouteConfig == nil || site.Spec.RouteConfig.Enabled == nil || *site.Spec.RouteConfig.Enabled) {
+	if siteIngressEnabled(site) {
+		// A missing ConfigMap means Routes stay the OpenShift default
+		operatorConfig, _ := r.getOperatorConfig(ctx, site.Namespace)
+		if siteUsesRoute(site, r.IsOpenShift, operatorConfig) {
+			if err := validateRouteTLS(site, bench); err != nil {
+				return r.failReconciliation(ctx, site, err.Error(), "RouteConfigInvalid")
+			}
 			if err := r.ensureRoute(ctx, site, bench, domain); err != nil {
 				return ctrl.Result{}, err
 			}
+			if err := r.deleteSiteIngress(ctx, site); err != nil {
+				return ctrl.Result{}, err
+			}
 		} else {
 			if err := r.ensureIngress(ctx, site, bench, domain); err != nil {
 				return ctrl.Result{}, err
 			}
+			if err := r.deleteSiteRoutes(ctx, site); err != nil {
+				return ctrl.Result{}, err
+			}
 		}
+		r.setCondition(site, metav1.Condition{
+			Type:    networkingPendingCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Published",
+			Message: "The Ingress or Route is published",
+		})
+	} else {
+		// Enabling it again waits for nginx like a new site
+		meta.RemoveStatusCondition(&site.Status.Conditions, networkingPendingCondition)
 	}
 
 	// Finalize status
 	site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseReady
 	site.Status.ObservedGeneration = site.Generation
-	site.Status.SiteURL = fmt.Sprintf("http://%s", domain)
-	if site.Spec.TLS.Enabled {
-		site.Status.SiteURL = fmt.Sprintf("https://%s", domain)
-	}
+	now := metav1.Now()
+	site.Status.LastReconcileTime = &now
+	site.Status.SiteURL = siteURL(site, bench, domain)
 
+	readyMessage := fmt.Sprintf("Site is ready at %s", site.Status.SiteURL)
+	if maintenanceModeApplied(site) {
+		readyMessage = fmt.Sprintf("Site is in maintenance mode at %s", site.Status.SiteURL)
+	}
+	if host := siteURLHostHeader(site.Status.SiteURL, domain); host != "" {
+		readyMessage = fmt.Sprintf("%s (send Host: %s)", readyMessage, host)
+	}
 	r.setCondition(site, metav1.Condition{
 		Type:    "Ready",
 		Status:  metav1.ConditionTrue,
 		Reason:  "SiteReady",
-		Message: fmt.Sprintf("Site is ready at %s", site.Status.SiteURL),
+		Message: readyMessage,
 	})
 	r.setCondition(site, metav1.Condition{
 		Type:   "Progressing",
@@ -300,6 +630,19 @@ func (r *FrappeSiteReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
+// benchSuspended reports whether the site's bench is suspended; a missing bench is left
+// to the full reconcile to report
+func (r *FrappeSiteReconciler) benchSuspended(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (bool, error) {
+	if site.Spec.BenchRef == nil {
+		return false, nil
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{}
+	if err := r.Get(ctx, siteBenchKey(site), bench); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return bench.Spec.Suspended, nil
+}
+
 func (r *FrappeSiteReconciler) failReconciliation(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, msg, reason string) (ctrl.Result, error) {
 	site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseFailed
 	r.setCondition(site, metav1.Condition{
@@ -313,12 +656,77 @@ func (r *FrappeSiteReconciler) failReconciliation(ctx context.Context, site *vyo
 	return ctrl.Result{}, fmt.Errorf("%s", msg)
 }
 
+// databaseErrorReasons are the condition reasons of the database provider error classes
+var databaseErrorReasons = map[database.ErrorClass]string{
+	database.ErrorClassNotFound:         "DatabaseNotFound",
+	database.ErrorClassPermissionDenied: "DatabasePermissionDenied",
+	database.ErrorClassTransient:        "DatabaseTransientError",
+	database.ErrorClassMisconfigured:    "DatabaseMisconfigured",
+}
+
+// failDatabase fails the reconcile on a database provider error, with the error's class as
+// the Ready and DatabaseReady reason. Transient errors are returned and retried by the
+// controller; a missing database server or denied permissions are fixed outside the site,
+// so those are requeued with backoff; a misconfigured dbConfig isn't retried until the
+// site changes. A database API that didn't answer within LookupTimeout is requeued after
+// lookupTimeoutRequeue.
+func (r *FrappeSiteReconciler) failDatabase(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, msg string, err error) (ctrl.Result, error) {
+	if isLookupTimeout(err) {
+		log.FromContext(ctx).Info("Database API did not respond, requeueing", "reason", err.Error())
+		r.setCondition(site, metav1.Condition{
+			Type:    "DatabaseReady",
+			Status:  metav1.ConditionFalse,
+			Reason:  "LookupTimeout",
+			Message: fmt.Sprintf("%s: %v", msg, err),
+		})
+		return ctrl.Result{RequeueAfter: lookupTimeoutRequeue}, r.updateStatus(ctx, site)
+	}
+	class := database.ClassifyError(err)
+	reason := databaseErrorReasons[class]
+	msg = fmt.Sprintf("%s: %v", msg, err)
+	r.setCondition(site, metav1.Condition{
+		Type:    "DatabaseReady",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: msg,
+	})
+	if class == database.ErrorClassTransient {
+		return r.failReconciliation(ctx, site, msg, reason)
+	}
+
+	site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseFailed
+	r.setCondition(site, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: msg,
+	})
+	r.Recorder.Event(site, corev1.EventTypeWarning, reason, msg)
+	if err := r.updateStatus(ctx, site); err != nil {
+		return ctrl.Result{}, err
+	}
+	if class == database.ErrorClassMisconfigured {
+		return ctrl.Result{}, nil
+	}
+	attempt := r.getRequeueAttempt(site)
+	_ = r.patchRequeueAttempt(ctx, site, attempt+1)
+	return ctrl.Result{RequeueAfter: backoff.ExponentialBackoff(requeueBackoffBase, attempt, requeueBackoffMax)}, nil
+}
+
 func (r *FrappeSiteReconciler) setCondition(site *vyogotechv1alpha1.FrappeSite, condition metav1.Condition) {
 	condition.ObservedGeneration = site.Generation
 	meta.SetStatusCondition(&site.Status.Conditions, condition)
 }
 
+// updateStatus writes the in-memory status, including ObservedGeneration and
+// LastReconcileTime, onto the latest copy of the site, so a conflict retry can't replace
+// them with the stored values. A site without a phase yet is written as Pending, so the
+// Phase and Ready columns are never blank.
 func (r *FrappeSiteReconciler) updateStatus(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) error {
+	if site.Status.Phase == "" {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhasePending
+	}
+	syncReadyCondition(&site.Status.Conditions, "Site", string(site.Status.Phase), site.Generation)
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		latest := &vyogotechv1alpha1.FrappeSite{}
 		if err := r.Get(ctx, types.NamespacedName{Name: site.Name, Namespace: site.Namespace}, latest); err != nil {
@@ -329,6 +737,7 @@ func (r *FrappeSiteReconciler) updateStatus(ctx context.Context, site *vyogotech
 			return err
 		}
 		site.ResourceVersion = latest.ResourceVersion
+		sitePhases.observe(types.NamespacedName{Name: site.Name, Namespace: site.Namespace}, string(site.Status.Phase))
 		return nil
 	})
 }
@@ -341,10 +750,102 @@ func (r *FrappeSiteReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if r.MaxConcurrentReconciles > 0 {
 		opts.MaxConcurrentReconciles = r.MaxConcurrentReconciles
 	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &vyogotechv1alpha1.FrappeSite{}, siteSecretsIndex, siteSecretsIndexValues); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		WithOptions(opts).
 		For(&vyogotechv1alpha1.FrappeSite{}).
 		Owns(&batchv1.Job{}).
 		Owns(&networkingv1.Ingress{}).
+		// Rewrite site_config.json when the database credentials rotate or the
+		// siteConfigSecretRef Secret changes
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.sitesForSecret)).
+		// Pick up a bench that became ready, changed its image or finished migrating
+		// right away instead of on the next requeue
+		Watches(&vyogotechv1alpha1.FrappeBench{}, handler.EnqueueRequestsFromMapFunc(r.sitesForBench),
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: benchChangeAffectsSites})).
 		Complete(r)
 }
+
+// siteBenchKey returns the key of the bench a site runs on; benchRef.namespace
+// defaults to the site's namespace
+func siteBenchKey(site *vyogotechv1alpha1.FrappeSite) types.NamespacedName {
+	key := types.NamespacedName{Name: site.Spec.BenchRef.Name, Namespace: site.Spec.BenchRef.Namespace}
+	if key.Namespace == "" {
+		key.Namespace = site.Namespace
+	}
+	return key
+}
+
+// siteSecretsIndex indexes sites by the Secrets they read, as <namespace>/<name>
+const siteSecretsIndex = "frappesite.secrets"
+
+// siteSecretsIndexValues returns the Secrets a site reads: its database credentials,
+// which may live in the database's namespace, and its siteConfigSecretRef
+func siteSecretsIndexValues(obj client.Object) []string {
+	site, ok := obj.(*vyogotechv1alpha1.FrappeSite)
+	if !ok {
+		return nil
+	}
+	var secrets []string
+	if site.Status.DatabaseCredentialsSecret != "" {
+		key := dbCredentialsSecretKey(site)
+		secrets = append(secrets, key.Namespace+"/"+key.Name)
+	}
+	if site.Spec.SiteConfigSecretRef != nil {
+		secrets = append(secrets, site.Namespace+"/"+site.Spec.SiteConfigSecretRef.Name)
+	}
+	return secrets
+}
+
+// sitesForSecret maps a Secret event to the sites reading it, through siteSecretsIndex
+func (r *FrappeSiteReconciler) sitesForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	sites := &vyogotechv1alpha1.FrappeSiteList{}
+	if err := r.List(ctx, sites, client.MatchingFields{siteSecretsIndex: obj.GetNamespace() + "/" + obj.GetName()}); err != nil {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(sites.Items))
+	for _, site := range sites.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: site.Name, Namespace: site.Namespace}})
+	}
+	return requests
+}
+
+// sitesForBench maps a FrappeBench event to the sites whose benchRef points at it,
+// in any namespace
+func (r *FrappeSiteReconciler) sitesForBench(ctx context.Context, obj client.Object) []reconcile.Request {
+	sites := &vyogotechv1alpha1.FrappeSiteList{}
+	if err := r.List(ctx, sites); err != nil {
+		return nil
+	}
+	benchKey := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+	var requests []reconcile.Request
+	for i := range sites.Items {
+		site := &sites.Items[i]
+		if site.Spec.BenchRef == nil || siteBenchKey(site) != benchKey {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: site.Name, Namespace: site.Namespace}})
+	}
+	return requests
+}
+
+// benchChangeAffectsSites filters bench updates down to the ones sites act on:
+// readiness, the image and migrations. Status-only churn such as worker scaling
+// doesn't requeue every site of the bench.
+func benchChangeAffectsSites(e event.UpdateEvent) bool {
+	oldBench, ok := e.ObjectOld.(*vyogotechv1alpha1.FrappeBench)
+	if !ok {
+		return false
+	}
+	newBench, ok := e.ObjectNew.(*vyogotechv1alpha1.FrappeBench)
+	if !ok {
+		return false
+	}
+	return oldBench.Status.Phase != newBench.Status.Phase ||
+		oldBench.Status.ImageDigest != newBench.Status.ImageDigest ||
+		oldBench.Status.MigratedImage != newBench.Status.MigratedImage ||
+		benchMigrationPending(oldBench) != benchMigrationPending(newBench) ||
+		!reflect.DeepEqual(oldBench.Spec.ImageConfig, newBench.Spec.ImageConfig)
+}
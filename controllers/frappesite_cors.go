@@ -0,0 +1,141 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// corsOriginsAnnotation records on the CORS job which origins it writes
+const corsOriginsAnnotation = "frappe.tech/cors-origins"
+
+// desiredCORSOrigins returns the origins spec.cors asks for; nil means allow_cors is removed
+func desiredCORSOrigins(site *vyogotechv1alpha1.FrappeSite) []string {
+	if site.Spec.CORS == nil {
+		return nil
+	}
+	return site.Spec.CORS.AllowOrigins
+}
+
+// validateCORSOrigins checks that every origin is "*" on its own or a bare scheme://host[:port].
+// Browsers send the Origin header without a path and Frappe compares it verbatim, so anything
+// else would never match.
+func validateCORSOrigins(origins []string) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			if len(origins) > 1 {
+				return fmt.Errorf("CORS origin \"*\" cannot be combined with other origins")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("invalid CORS origin %q: must be http(s)://host[:port] without a path", origin)
+		}
+	}
+	return nil
+}
+
+// ensureSiteCORS keeps allow_cors in the site's site_config.json in line with spec.cors.
+// Frappe reads site_config.json per request, so no restart is needed. Returns true once
+// the applied origins (status.corsOrigins) match the spec.
+func (r *FrappeSiteReconciler) ensureSiteCORS(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	desired := desiredCORSOrigins(site)
+	if err := validateCORSOrigins(desired); err != nil {
+		return false, err
+	}
+	if slices.Equal(site.Status.CORSOrigins, desired) {
+		return true, nil
+	}
+	jobName := fmt.Sprintf("%s-cors", site.Name)
+	desiredKey := strings.Join(desired, ",")
+	job, err := runSiteJob(ctx, r.Client, site, jobName, siteJobKey{annotation: corsOriginsAnnotation, value: desiredKey}, func() (*batchv1.Job, error) {
+		return r.buildCORSJob(ctx, site, bench, jobName, desired)
+	})
+	if errors.Is(err, errJobFailed) {
+		// Remove the failed job so the retry of the returned error runs it again
+		failed := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: jobName, Namespace: site.Namespace}}
+		if err := r.Delete(ctx, failed, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return false, err
+		}
+	}
+	if job == nil || err != nil {
+		return false, err
+	}
+
+	site.Status.CORSOrigins = slices.Clone(desired)
+	if len(desired) > 0 {
+		r.Recorder.Event(site, corev1.EventTypeNormal, "CORSConfigured", fmt.Sprintf("allow_cors set to %s", desiredKey))
+	} else {
+		r.Recorder.Event(site, corev1.EventTypeNormal, "CORSConfigured", "allow_cors removed")
+	}
+	return true, nil
+}
+
+// buildCORSJob returns the job that writes the origins to allow_cors in site_config.json
+func (r *FrappeSiteReconciler) buildCORSJob(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, jobName string, desired []string) (*batchv1.Job, error) {
+	corsScript, err := scripts.RenderScript(scripts.SiteCORSConfig, scripts.SiteCORSConfigData{
+		SiteName:     site.Spec.SiteName,
+		AllowOrigins: desired,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render CORS script: %w", err)
+	}
+
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
+		"app":  "frappe",
+		"site": site.Name,
+	}))
+
+	container := resources.NewContainerBuilder("cors", r.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(corsScript).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		Build()
+
+	return resources.NewJobBuilder(jobName, site.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationCORS, bench.Name, site.Spec.SiteName)).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(2).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(site, r.Scheme).
+		Build()
+}
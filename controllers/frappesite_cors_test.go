@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestValidateCORSOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		wantErr bool
+	}{
+		{name: "none", origins: nil},
+		{name: "wildcard", origins: []string{"*"}},
+		{name: "origins with port", origins: []string{"https://app.example.com", "http://localhost:3000"}},
+		{name: "wildcard with others", origins: []string{"*", "https://app.example.com"}, wantErr: true},
+		{name: "path", origins: []string{"https://app.example.com/"}, wantErr: true},
+		{name: "no scheme", origins: []string{"app.example.com"}, wantErr: true},
+		{name: "ftp", origins: []string{"ftp://app.example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCORSOrigins(tt.origins)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCORSOrigins(%v) error = %v, wantErr %v", tt.origins, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnsureSiteCORS(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.CORS = &vyogotechv1alpha1.CORSConfig{AllowOrigins: []string{"https://app.example.com"}}
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "site-cors", Namespace: "default"}
+
+	applied, err := r.ensureSiteCORS(ctx, site, bench)
+	if err != nil || applied {
+		t.Fatalf("expected CORS job to be started, got applied=%v err=%v", applied, err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("expected CORS job: %v", err)
+	}
+	if job.Annotations[corsOriginsAnnotation] != "https://app.example.com" {
+		t.Errorf("unexpected origins annotation %q", job.Annotations[corsOriginsAnnotation])
+	}
+	if script := job.Spec.Template.Spec.Containers[0].Args[0]; !strings.Contains(script, `origins = ["https://app.example.com"]`) {
+		t.Error("expected CORS job to write the requested origins")
+	}
+
+	// A failed job is deleted so the retry runs it again
+	job.Status.Failed = 1
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	if _, err := r.ensureSiteCORS(ctx, site, bench); err == nil {
+		t.Fatal("expected error after the CORS job failed")
+	}
+	if err := c.Get(ctx, jobKey, job); err == nil {
+		t.Fatal("expected the failed CORS job to be deleted")
+	}
+	if applied, err := r.ensureSiteCORS(ctx, site, bench); err != nil || applied {
+		t.Fatalf("expected CORS job to be recreated, got applied=%v err=%v", applied, err)
+	}
+	job = &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("expected recreated CORS job: %v", err)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	applied, err = r.ensureSiteCORS(ctx, site, bench)
+	if err != nil || !applied {
+		t.Fatalf("expected CORS to be applied, got applied=%v err=%v", applied, err)
+	}
+	if len(site.Status.CORSOrigins) != 1 || site.Status.CORSOrigins[0] != "https://app.example.com" {
+		t.Errorf("expected active origins in status, got %v", site.Status.CORSOrigins)
+	}
+
+	// Removing spec.cors replaces the finished job with one that drops allow_cors
+	site.Spec.CORS = nil
+	if applied, err := r.ensureSiteCORS(ctx, site, bench); err != nil || applied {
+		t.Fatalf("expected stale job to be replaced, got applied=%v err=%v", applied, err)
+	}
+	if err := c.Get(ctx, jobKey, job); err == nil {
+		t.Error("expected stale CORS job to be deleted")
+	}
+	if _, err := r.ensureSiteCORS(ctx, site, bench); err != nil {
+		t.Fatalf("ensureSiteCORS: %v", err)
+	}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("expected new CORS job: %v", err)
+	}
+	if job.Annotations[corsOriginsAnnotation] != "" {
+		t.Errorf("expected removal job, got origins %q", job.Annotations[corsOriginsAnnotation])
+	}
+
+	// Invalid origins fail without creating a job
+	site.Spec.CORS = &vyogotechv1alpha1.CORSConfig{AllowOrigins: []string{"*", "https://app.example.com"}}
+	if _, err := r.ensureSiteCORS(ctx, site, bench); err == nil {
+		t.Error("expected invalid origins to be rejected")
+	}
+}
@@ -243,6 +243,63 @@ func TestFrappeSiteReconciler_resolveDomain(t *testing.T) {
 	})
 }
 
+func TestResolveDomain_operatorDefault(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	operatorConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "frappe-operator-config", Namespace: "frappe-operator-system"},
+		Data:       map[string]string{"defaultDomainSuffix": "apps.internal"},
+	}
+	ingress := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ingress-nginx-controller",
+			Namespace:   "ingress-nginx",
+			Annotations: map[string]string{"external-dns.alpha.kubernetes.io/hostname": "*.detected.example.com"},
+		},
+	}
+	r := &FrappeSiteReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(operatorConfig, ingress).Build()}
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		domain      string
+		benchSuffix string
+		wantDomain  string
+		wantSource  string
+	}{
+		{"explicit domain wins", "erp.customer.com", ".bench.example.com", "erp.customer.com", "explicit"},
+		{"bench suffix wins over the operator default", "", ".bench.example.com", "mysite.bench.example.com", "bench-suffix"},
+		{"operator default wins over auto-detection", "", "", "mysite.apps.internal", "operator-default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &vyogotechv1alpha1.FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{Name: "mysite", Namespace: "default"},
+				Spec:       vyogotechv1alpha1.FrappeSiteSpec{SiteName: "mysite", Domain: tt.domain},
+			}
+			bench := &vyogotechv1alpha1.FrappeBench{}
+			if tt.benchSuffix != "" {
+				bench.Spec.DomainConfig = &vyogotechv1alpha1.DomainConfig{Suffix: tt.benchSuffix}
+			}
+			domain, source := r.resolveDomain(ctx, site, bench)
+			if domain != tt.wantDomain || source != tt.wantSource {
+				t.Errorf("expected %s (%s), got %s (%s)", tt.wantDomain, tt.wantSource, domain, source)
+			}
+		})
+	}
+
+	// Without the operator default, the suffix is auto-detected again
+	operatorConfig.Data = nil
+	if err := r.Update(ctx, operatorConfig); err != nil {
+		t.Fatalf("Update ConfigMap: %v", err)
+	}
+	site := &vyogotechv1alpha1.FrappeSite{Spec: vyogotechv1alpha1.FrappeSiteSpec{SiteName: "mysite"}}
+	if domain, source := r.resolveDomain(ctx, site, &vyogotechv1alpha1.FrappeBench{}); source != "auto-detected" {
+		t.Errorf("expected an auto-detected domain, got %s (%s)", domain, source)
+	}
+}
+
 func TestFrappeSiteReconciler_getRequeueAttempt(t *testing.T) {
 	r := &FrappeSiteReconciler{}
 	t.Run("nil annotations", func(t *testing.T) {
@@ -618,7 +675,7 @@ func TestFrappeSiteReconciler_Delete(t *testing.T) {
 	// Verify secret deleted
 	err = client.Get(context.TODO(), types.NamespacedName{Name: siteName + "-init-secrets", Namespace: namespace}, secret)
 	if !errors.IsNotFound(err) {
-		// t.Error("Secret should be deleted") // Fake client sometimes doesn't delete immediately in tests without track
+		t.Errorf("expected the init secrets to be deleted, got %v", err)
 	}
 
 	// Verify finalizer removed
@@ -628,3 +685,72 @@ func TestFrappeSiteReconciler_Delete(t *testing.T) {
 		t.Error("Finalizer not removed")
 	}
 }
+
+func TestDeleteSite_RemovesCredentialSecrets(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-delete", Namespace: "default"},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	deletionSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-deletion-secret", Namespace: "default"},
+		Data:       map[string][]byte{"db_root_password": []byte("root")},
+	}
+	initSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-init-secrets", Namespace: "default"},
+		Data:       map[string][]byte{"admin_password": []byte("admin")},
+	}
+	r, c := newInitJobTestReconciler(site, bench, job, deletionSecret, initSecret)
+	ctx := context.Background()
+
+	if err := r.deleteSite(ctx, site); err != nil {
+		t.Fatalf("deleteSite: %v", err)
+	}
+	for _, name := range []string{"site-deletion-secret", "site-init-secrets"} {
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &corev1.Secret{}); !errors.IsNotFound(err) {
+			t.Errorf("expected secret %s to be deleted after drop-site, got %v", name, err)
+		}
+	}
+}
+
+func TestDeleteSite_CleansUpPostgresRoleAndDatabase(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.DBConfig = vyogotechv1alpha1.DatabaseConfig{Provider: "postgres"}
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(database.PostgresClusterGVK)
+	cluster.SetName(database.SharedPostgresCluster)
+	cluster.SetNamespace("default")
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-delete", Namespace: "default"},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	r, c := newInitJobTestReconciler(site, bench, cluster, job)
+	ctx := context.Background()
+
+	provider, err := database.NewProvider(site.Spec.DBConfig, c, r.Scheme)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	if _, err := provider.EnsureDatabase(ctx, site); err != nil {
+		t.Fatalf("EnsureDatabase: %v", err)
+	}
+
+	if err := r.deleteSite(ctx, site); err != nil {
+		t.Fatalf("deleteSite: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: database.SharedPostgresCluster, Namespace: "default"}, cluster); err != nil {
+		t.Fatalf("get cluster: %v", err)
+	}
+	roles, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "managed", "roles")
+	if len(roles) != 1 || roles[0].(map[string]interface{})["ensure"] != "absent" {
+		t.Errorf("expected the site role to be marked absent, got %v", roles)
+	}
+	databases := &unstructured.UnstructuredList{}
+	databases.SetGroupVersionKind(database.PostgresDatabaseGVK.GroupVersion().WithKind("DatabaseList"))
+	if err := c.List(ctx, databases); err != nil {
+		t.Fatalf("list databases: %v", err)
+	}
+	if len(databases.Items) != 0 {
+		t.Errorf("expected the Database CR to be deleted, got %d", len(databases.Items))
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFailDatabase(t *testing.T) {
+	gr := schema.GroupResource{Group: "k8s.mariadb.com", Resource: "users"}
+	tests := []struct {
+		name        string
+		err         error
+		wantReason  string
+		wantError   bool
+		wantRequeue bool
+	}{
+		{"not found", &database.ProviderError{Class: database.ErrorClassNotFound, Err: errors.New("shared MariaDB instance not found")}, "DatabaseNotFound", false, true},
+		{"permission denied", fmt.Errorf("failed to ensure User CR: %w", apierrors.NewForbidden(gr, "site-user", errors.New("rbac"))), "DatabasePermissionDenied", false, true},
+		{"transient", errors.New("connection reset by peer"), "DatabaseTransientError", true, false},
+		{"misconfigured", &database.ProviderError{Class: database.ErrorClassMisconfigured, Err: errors.New("unsupported database mode: replica")}, "DatabaseMisconfigured", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site, bench := newInitJobTestObjects()
+			siteReconciler, _ := newInitJobTestReconciler(site, bench)
+			c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+			r := &FrappeSiteReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(10)}
+
+			result, err := r.failDatabase(context.Background(), site, "Database provisioning failed", tt.err)
+			if (err != nil) != tt.wantError {
+				t.Errorf("expected error %v, got %v", tt.wantError, err)
+			}
+			if (result.RequeueAfter > 0) != tt.wantRequeue {
+				t.Errorf("expected requeue %v, got %+v", tt.wantRequeue, result)
+			}
+			if site.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseFailed {
+				t.Errorf("expected phase Failed, got %s", site.Status.Phase)
+			}
+			for _, conditionType := range []string{"Ready", "DatabaseReady"} {
+				condition := meta.FindStatusCondition(site.Status.Conditions, conditionType)
+				if condition == nil || condition.Reason != tt.wantReason {
+					t.Errorf("expected %s reason %s, got %+v", conditionType, tt.wantReason, condition)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// dbCredentialsAnnotation records on the db-credentials job which Secret version it writes
+const dbCredentialsAnnotation = "frappe.tech/db-credentials-version"
+
+// dbCredentialsSecretKey returns where the site's database credentials Secret lives
+func dbCredentialsSecretKey(site *vyogotechv1alpha1.FrappeSite) types.NamespacedName {
+	namespace := site.Status.DatabaseCredentialsSecretNamespace
+	if namespace == "" {
+		namespace = site.Namespace
+	}
+	return types.NamespacedName{Name: site.Status.DatabaseCredentialsSecret, Namespace: namespace}
+}
+
+// dbCredentialsRotated reports whether the database credentials Secret changed since its
+// credentials were written to site_config.json
+func (r *FrappeSiteReconciler) dbCredentialsRotated(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (bool, error) {
+	if site.Status.DatabaseCredentialsSecret == "" || site.Status.DatabaseCredentialsVersion == "" {
+		return false, nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, dbCredentialsSecretKey(site), secret); err != nil {
+		// A deleted Secret is the database provider's to recreate
+		return false, client.IgnoreNotFound(err)
+	}
+	return secret.ResourceVersion != site.Status.DatabaseCredentialsVersion, nil
+}
+
+// ensureSiteDBCredentials rewrites db_user and db_password in site_config.json when the
+// database credentials Secret changed after the site was initialized, e.g. when the
+// MariaDB operator rotated the password. The provider reports the Secret's version in
+// dbCreds.Version; without it the Secret is read directly. The first call after initialization only records
+// the Secret's version, the init job wrote those credentials. Returns true once
+// site_config.json holds the current credentials.
+func (r *FrappeSiteReconciler) ensureSiteDBCredentials(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, dbCreds *database.DatabaseCredentials) (bool, error) {
+	if dbCreds == nil || dbCreds.SecretName == "" {
+		// SQLite has no credentials
+		return true, nil
+	}
+	version := dbCreds.Version
+	if version == "" {
+		credsSecret := &corev1.Secret{}
+		if err := r.Get(ctx, dbCredentialsSecretKey(site), credsSecret); err != nil {
+			return false, fmt.Errorf("failed to get database credentials secret: %w", err)
+		}
+		version = credsSecret.ResourceVersion
+	}
+	if site.Status.DatabaseCredentialsVersion == "" {
+		site.Status.DatabaseCredentialsVersion = version
+		return true, nil
+	}
+	if site.Status.DatabaseCredentialsVersion == version {
+		return true, nil
+	}
+	jobName := fmt.Sprintf("%s-db-credentials", site.Name)
+	job, err := runSiteJob(ctx, r.Client, site, jobName, siteJobKey{annotation: dbCredentialsAnnotation, value: version}, func() (*batchv1.Job, error) {
+		r.Recorder.Event(site, corev1.EventTypeNormal, "DBCredentialsRotated",
+			fmt.Sprintf("Secret %s changed, updating site_config.json", dbCreds.SecretName))
+		return r.buildDBCredentialsJob(ctx, site, bench, jobName, dbCreds)
+	})
+	if job == nil || err != nil {
+		return false, err
+	}
+
+	site.Status.DatabaseCredentialsVersion = version
+	r.Recorder.Event(site, corev1.EventTypeNormal, "DBCredentialsUpdated",
+		fmt.Sprintf("Wrote the rotated credentials of Secret %s to site_config.json", dbCreds.SecretName))
+	return true, nil
+}
+
+// buildDBCredentialsJob returns the job that writes dbCreds to the site's site_config.json
+func (r *FrappeSiteReconciler) buildDBCredentialsJob(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, jobName string, dbCreds *database.DatabaseCredentials) (*batchv1.Job, error) {
+	// The job can't mount a Secret from another namespace, so it reads a copy in the site's
+	secretName := fmt.Sprintf("%s-db-credentials", site.Name)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: site.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = map[string]string{"app": "frappe", "site": site.Name}
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = map[string][]byte{
+			"db_user":     []byte(dbCreds.Username),
+			"db_password": []byte(dbCreds.Password),
+		}
+		return controllerutil.SetControllerReference(site, secret, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write db credentials secret: %w", err)
+	}
+
+	credsScript, err := scripts.RenderScript(scripts.SiteDBCredentials, scripts.SiteDBCredentialsData{
+		SiteName: site.Spec.SiteName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render db credentials script: %w", err)
+	}
+
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
+		"app":  "frappe",
+		"site": site.Name,
+	}))
+
+	container := resources.NewContainerBuilder("db-credentials", r.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(credsScript).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithVolumeMountReadOnly("db-credentials", "/tmp/db-credentials").
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		Build()
+
+	return resources.NewJobBuilder(jobName, site.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationDBCredentials, bench.Name, site.Spec.SiteName)).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(2).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithSecretVolume("db-credentials", secretName, resources.Int32Ptr(0444)).
+		WithOwner(site, r.Scheme).
+		Build()
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnsureSiteDBCredentials(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Status.DatabaseCredentialsSecret = "site-db-password"
+	credsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-db-password", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("old")},
+	}
+	r, c := newInitJobTestReconciler(site, bench, credsSecret)
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "site-db-credentials", Namespace: "default"}
+	dbCreds := &database.DatabaseCredentials{Username: "site_user", Password: "old", SecretName: "site-db-password", SecretNamespace: "default"}
+
+	// The first pass only records the version the init job wrote
+	if synced, err := r.ensureSiteDBCredentials(ctx, site, bench, dbCreds); err != nil || !synced {
+		t.Fatalf("expected credentials to be in sync, got synced=%v err=%v", synced, err)
+	}
+	if site.Status.DatabaseCredentialsVersion == "" {
+		t.Fatalf("expected the credentials version to be recorded")
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Fatalf("expected no job for unchanged credentials, got %v", err)
+	}
+	if rotated, err := r.dbCredentialsRotated(ctx, site); err != nil || rotated {
+		t.Fatalf("expected no rotation, got rotated=%v err=%v", rotated, err)
+	}
+
+	// Rotate the password
+	credsSecret.Data["password"] = []byte("new")
+	if err := c.Update(ctx, credsSecret); err != nil {
+		t.Fatalf("Update Secret: %v", err)
+	}
+	if rotated, err := r.dbCredentialsRotated(ctx, site); err != nil || !rotated {
+		t.Fatalf("expected the rotation to be detected, got rotated=%v err=%v", rotated, err)
+	}
+	dbCreds.Password = "new"
+	if synced, err := r.ensureSiteDBCredentials(ctx, site, bench, dbCreds); err != nil || synced {
+		t.Fatalf("expected a pending update, got synced=%v err=%v", synced, err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationDBCredentials {
+		t.Errorf("expected operation label %q, got %q", jobOperationDBCredentials, job.Labels[jobOperationLabel])
+	}
+	assertSitesMount(t, "db credentials job", job.Spec.Template.Spec)
+	if !slices.ContainsFunc(job.Spec.Template.Spec.Volumes, func(v corev1.Volume) bool {
+		return v.Secret != nil && v.Secret.SecretName == "site-db-credentials"
+	}) {
+		t.Errorf("expected the credentials copy to be mounted")
+	}
+	copied := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-db-credentials", Namespace: "default"}, copied); err != nil {
+		t.Fatalf("Get credentials copy: %v", err)
+	}
+	if string(copied.Data["db_password"]) != "new" || string(copied.Data["db_user"]) != "site_user" {
+		t.Errorf("expected the rotated credentials in the copy, got %v", copied.Data)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if synced, err := r.ensureSiteDBCredentials(ctx, site, bench, dbCreds); err != nil || !synced {
+		t.Fatalf("expected credentials to be written, got synced=%v err=%v", synced, err)
+	}
+	if rotated, err := r.dbCredentialsRotated(ctx, site); err != nil || rotated {
+		t.Fatalf("expected no rotation after the update, got rotated=%v err=%v", rotated, err)
+	}
+}
+
+func TestEnsureSiteDBCredentials_ProviderVersion(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Status.DatabaseCredentialsSecret = "site-db-password"
+	site.Status.DatabaseCredentialsVersion = "41"
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+
+	// The provider's version is trusted without reading the Secret again
+	dbCreds := &database.DatabaseCredentials{Username: "site_user", Password: "new", SecretName: "site-db-password", SecretNamespace: "default", Version: "42"}
+	if synced, err := r.ensureSiteDBCredentials(ctx, site, bench, dbCreds); err != nil || synced {
+		t.Fatalf("expected a pending update, got synced=%v err=%v", synced, err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-db-credentials", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Annotations[dbCredentialsAnnotation] != "42" {
+		t.Errorf("expected the job to write version 42, got %q", job.Annotations[dbCredentialsAnnotation])
+	}
+}
+
+func TestEnsureSiteDBCredentials_NoSecret(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	r, _ := newInitJobTestReconciler(site, bench)
+
+	// SQLite sites have no credentials Secret
+	if synced, err := r.ensureSiteDBCredentials(context.Background(), site, bench, &database.DatabaseCredentials{}); err != nil || !synced {
+		t.Fatalf("expected nothing to do, got synced=%v err=%v", synced, err)
+	}
+}
+
+func TestSitesForSecret_Credentials(t *testing.T) {
+	mariadbSite := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+		Status:     vyogotechv1alpha1.FrappeSiteStatus{DatabaseCredentialsSecret: "shop-db-password"},
+	}
+	postgresSite := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "blog", Namespace: "default"},
+		Status: vyogotechv1alpha1.FrappeSiteStatus{
+			DatabaseCredentialsSecret:          "blog-role",
+			DatabaseCredentialsSecretNamespace: "databases",
+		},
+	}
+	r, _ := newInitJobTestReconciler(mariadbSite, postgresSite)
+	ctx := context.Background()
+
+	secret := func(name, namespace string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+	if reqs := r.sitesForSecret(ctx, secret("shop-db-password", "default")); len(reqs) != 1 || reqs[0].Name != "shop" {
+		t.Errorf("expected the MariaDB site to be enqueued, got %v", reqs)
+	}
+	if reqs := r.sitesForSecret(ctx, secret("blog-role", "databases")); len(reqs) != 1 || reqs[0].Name != "blog" {
+		t.Errorf("expected the PostgreSQL site to be enqueued, got %v", reqs)
+	}
+	if reqs := r.sitesForSecret(ctx, secret("blog-role", "default")); len(reqs) != 0 {
+		t.Errorf("expected a same-named Secret elsewhere to be ignored, got %v", reqs)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// dbWaitContainerName is the init container of the site init job that waits for the database
+const dbWaitContainerName = "wait-for-database"
+
+// defaultDatabaseWaitSeconds is how long the init job waits for the database unless
+// spec.initJob.databaseWaitSeconds says otherwise
+const defaultDatabaseWaitSeconds int32 = 300
+
+// databaseWaitSeconds returns spec.initJob.databaseWaitSeconds or its default
+func databaseWaitSeconds(site *vyogotechv1alpha1.FrappeSite) int32 {
+	if site.Spec.InitJob != nil && site.Spec.InitJob.DatabaseWaitSeconds != nil {
+		return *site.Spec.InitJob.DatabaseWaitSeconds
+	}
+	return defaultDatabaseWaitSeconds
+}
+
+// dbWaitInitContainer returns the init container that holds bench new-site back until the
+// database accepts connections, or nil when the wait is disabled or there is no database
+// host to reach. It reads the host and port from the mounted init secret.
+func (r *FrappeSiteReconciler) dbWaitInitContainer(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, dbInfo *database.DatabaseInfo) (*corev1.Container, error) {
+	timeout := databaseWaitSeconds(site)
+	if timeout == 0 || dbInfo == nil || dbInfo.Host == "" {
+		return nil, nil
+	}
+	waitScript, err := scripts.GetScript(scripts.WaitForDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wait for database script: %w", err)
+	}
+	container := resources.NewContainerBuilder(dbWaitContainerName, r.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(waitScript).
+		WithVolumeMount("site-secrets", "/tmp/site-secrets").
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		WithEnv("DB_WAIT_TIMEOUT", strconv.Itoa(int(timeout))).
+		Build()
+	return &container, nil
+}
+
+// waitingForDatabase reports whether a pod of the init job is still running its
+// wait-for-database init container
+func (r *FrappeSiteReconciler) waitingForDatabase(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, jobName string) (bool, error) {
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(site.Namespace), client.MatchingLabels{"job-name": jobName}); err != nil {
+		return false, err
+	}
+	for _, pod := range podList.Items {
+		if pod.Status.Phase != corev1.PodPending {
+			continue
+		}
+		for _, status := range pod.Status.InitContainerStatuses {
+			if status.Name == dbWaitContainerName && status.State.Running != nil {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEnsureSiteInitialized_waitForDatabase(t *testing.T) {
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	tests := []struct {
+		name        string
+		initJob     *vyogotechv1alpha1.InitJobConfig
+		host        string
+		wantTimeout string
+	}{
+		{name: "default", host: "mariadb.default.svc", wantTimeout: "300"},
+		{name: "configured", initJob: &vyogotechv1alpha1.InitJobConfig{DatabaseWaitSeconds: resources.Int32Ptr(60)}, host: "mariadb.default.svc", wantTimeout: "60"},
+		{name: "disabled", initJob: &vyogotechv1alpha1.InitJobConfig{DatabaseWaitSeconds: resources.Int32Ptr(0)}, host: "mariadb.default.svc"},
+		{name: "no database host", host: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site, bench := newInitJobTestObjects()
+			site.Spec.InitJob = tt.initJob
+			r, c := newInitJobTestReconciler(site, bench)
+			ctx := context.Background()
+
+			dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db", Host: tt.host, Port: "3306"}
+			if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+				t.Fatalf("ensureSiteInitialized: %v", err)
+			}
+			job := &batchv1.Job{}
+			if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+				t.Fatalf("Get Job: %v", err)
+			}
+
+			initContainers := job.Spec.Template.Spec.InitContainers
+			if tt.wantTimeout == "" {
+				if len(initContainers) != 0 {
+					t.Errorf("expected no init container, got %v", initContainers)
+				}
+				return
+			}
+			if len(initContainers) != 1 || initContainers[0].Name != dbWaitContainerName {
+				t.Fatalf("expected the %s init container, got %v", dbWaitContainerName, initContainers)
+			}
+			wait := initContainers[0]
+			if !strings.Contains(wait.Args[0], "/dev/tcp/") {
+				t.Errorf("expected the wait script to poll the database port, got %q", wait.Args[0])
+			}
+			if len(wait.Env) != 1 || wait.Env[0].Name != "DB_WAIT_TIMEOUT" || wait.Env[0].Value != tt.wantTimeout {
+				t.Errorf("expected DB_WAIT_TIMEOUT=%s, got %v", tt.wantTimeout, wait.Env)
+			}
+			if len(wait.VolumeMounts) != 1 || wait.VolumeMounts[0].Name != "site-secrets" {
+				t.Errorf("expected the init secret mount, got %v", wait.VolumeMounts)
+			}
+		})
+	}
+}
+
+func TestEnsureSiteInitialized_waitingForDatabaseEvent(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "site-init", Namespace: "default"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-init-abcde", Namespace: "default", Labels: map[string]string{"job-name": "site-init"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  dbWaitContainerName,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		},
+	}
+	r, _ := newInitJobTestReconciler(site, bench, job, pod)
+	recorder := record.NewFakeRecorder(5)
+	r.Recorder = recorder
+
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db", Host: "mariadb.default.svc", Port: "3306"}
+	ready, err := r.ensureSiteInitialized(context.Background(), site, bench, "site.local", dbInfo, nil)
+	if err != nil || ready {
+		t.Fatalf("expected the init job to be in progress, got ready=%v err=%v", ready, err)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "WaitingForDatabase") {
+			t.Errorf("expected a WaitingForDatabase event, got %q", event)
+		}
+	default:
+		t.Error("expected a WaitingForDatabase event")
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newExternalDBTestObjects() (*vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench, *corev1.Secret) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.DBConfig = vyogotechv1alpha1.DatabaseConfig{
+		Provider:            "external",
+		ConnectionSecretRef: &corev1.SecretReference{Name: "rds"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "rds", Namespace: "default"},
+		Data: map[string][]byte{
+			"host":     []byte("db.abc.eu-west-1.rds.amazonaws.com"),
+			"port":     []byte("3306"),
+			"database": []byte("site_db"),
+			"username": []byte("site_user"),
+			"password": []byte("s3cret"),
+		},
+	}
+	return site, bench, secret
+}
+
+func TestReconcile_ExternalDatabaseFromSecretOnly(t *testing.T) {
+	site, bench, secret := newExternalDBTestObjects()
+	site.Finalizers = []string{frappeSiteFinalizer}
+	bench.Status.Phase = "Ready"
+	r, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(site, bench, secret).WithStatusSubresource(site).Build()
+	r.Client = c
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site", Namespace: "default"}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, key, site); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if cond := meta.FindStatusCondition(site.Status.Conditions, "DatabaseReady"); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("expected DatabaseReady without a MariaDB CR, got %+v", cond)
+	}
+	if site.Status.DatabaseName != "site_db" {
+		t.Errorf("expected the database name from the secret, got %q", site.Status.DatabaseName)
+	}
+
+	initSecrets := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init-secrets", Namespace: "default"}, initSecrets); err != nil {
+		t.Fatalf("Get init secrets: %v", err)
+	}
+	for k, want := range map[string]string{
+		"db_host":     "db.abc.eu-west-1.rds.amazonaws.com",
+		"db_name":     "site_db",
+		"db_user":     "site_user",
+		"db_password": "s3cret",
+		"db_provider": "mariadb",
+	} {
+		if got := string(initSecrets.Data[k]); got != want {
+			t.Errorf("init secret %s = %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestDeleteSite_ExternalDatabase(t *testing.T) {
+	site, bench, secret := newExternalDBTestObjects()
+	r, c := newInitJobTestReconciler(site, bench, secret)
+	ctx := context.Background()
+
+	if err := r.deleteSite(ctx, site); err == nil {
+		t.Fatal("expected deleteSite to wait for the deletion job")
+	}
+	deletionSecret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-deletion-secret", Namespace: "default"}, deletionSecret); err != nil {
+		t.Fatalf("Get deletion secret: %v", err)
+	}
+	if string(deletionSecret.Data["db_root_user"]) != "site_user" || string(deletionSecret.Data["db_root_password"]) != "s3cret" {
+		t.Errorf("expected drop-site to use the connection secret credentials, got %v", deletionSecret.Data)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-delete", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get deletion job: %v", err)
+	}
+	if got := envValue(job.Spec.Template.Spec.Containers[0].Env, "SKIP_DB_DROP"); got != "0" {
+		t.Errorf("expected SKIP_DB_DROP=0, got %q", got)
+	}
+}
+
+func TestDeleteSite_SkipDropOnDelete(t *testing.T) {
+	site, bench, _ := newExternalDBTestObjects()
+	site.Spec.DBConfig.SkipDropOnDelete = true
+	// No connection secret: credentials aren't needed when the database is kept
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+
+	if err := r.deleteSite(ctx, site); err == nil {
+		t.Fatal("expected deleteSite to wait for the deletion job")
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-delete", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get deletion job: %v", err)
+	}
+	if got := envValue(job.Spec.Template.Spec.Containers[0].Env, "SKIP_DB_DROP"); got != "1" {
+		t.Errorf("expected SKIP_DB_DROP=1, got %q", got)
+	}
+}
+
+func envValue(env []corev1.EnvVar, name string) string {
+	for _, e := range env {
+		if e.Name == name {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+func TestReconcile_ExternalDatabaseSecretInAnotherNamespace(t *testing.T) {
+	site, bench, secret := newExternalDBTestObjects()
+	site.Finalizers = []string{frappeSiteFinalizer}
+	site.Spec.DBConfig.ConnectionSecretRef.Namespace = "other-tenant"
+	secret.Namespace = "other-tenant"
+	bench.Status.Phase = "Ready"
+	r, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(site, bench, secret).WithStatusSubresource(site).Build()
+	r.Client = c
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site", Namespace: "default"}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err == nil {
+		t.Fatal("expected the reconcile to fail on a Secret in another namespace")
+	}
+	if err := c.Get(ctx, key, site); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if site.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseFailed {
+		t.Errorf("expected phase Failed, got %q", site.Status.Phase)
+	}
+	if cond := meta.FindStatusCondition(site.Status.Conditions, "Ready"); cond == nil || cond.Reason != "CrossNamespaceDBSecret" {
+		t.Errorf("expected Ready reason CrossNamespaceDBSecret, got %+v", cond)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init-secrets", Namespace: "default"}, &corev1.Secret{}); err == nil {
+		t.Error("expected no init secrets built from another namespace's credentials")
+	}
+}
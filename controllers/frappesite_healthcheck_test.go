@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnsureSiteHealthy(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.PublishWhenHealthy = true
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+
+	healthy, err := r.ensureSiteHealthy(ctx, site, bench, "site.example.com")
+	if err != nil || healthy {
+		t.Fatalf("expected job to be created and not yet healthy, got healthy=%v err=%v", healthy, err)
+	}
+
+	key := types.NamespacedName{Name: "site-health-check", Namespace: "default"}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != siteHealthCheckTimeoutSeconds {
+		t.Errorf("expected active deadline %d, got %v", siteHealthCheckTimeoutSeconds, job.Spec.ActiveDeadlineSeconds)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	script := strings.Join(container.Args, " ")
+	if !strings.Contains(script, "http://bench-nginx:8080/api/method/ping") || !strings.Contains(script, `"Host: $DOMAIN"`) {
+		t.Errorf("expected health check through bench nginx with the site host, got %q", script)
+	}
+	if strings.Contains(script, "site.example.com") || len(container.Env) == 0 || container.Env[0].Name != "DOMAIN" || container.Env[0].Value != "site.example.com" {
+		t.Errorf("expected the domain in the DOMAIN env var only, got env %+v", container.Env)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if healthy, err := r.ensureSiteHealthy(ctx, site, bench, "site.example.com"); err != nil || !healthy {
+		t.Errorf("expected healthy after job success, got healthy=%v err=%v", healthy, err)
+	}
+
+	job.Status.Succeeded = 0
+	job.Status.Failed = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if _, err := r.ensureSiteHealthy(ctx, site, bench, "site.example.com"); err == nil {
+		t.Error("expected error after the health check job failed")
+	}
+
+	// The failed job is removed so the retry runs a fresh check that can recover
+	if err := c.Get(ctx, key, job); err == nil {
+		t.Fatal("expected the failed health check job to be deleted")
+	}
+	if healthy, err := r.ensureSiteHealthy(ctx, site, bench, "site.example.com"); err != nil || healthy {
+		t.Fatalf("expected a new health check job, got healthy=%v err=%v", healthy, err)
+	}
+	job = &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("expected a new health check job: %v", err)
+	}
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if healthy, err := r.ensureSiteHealthy(ctx, site, bench, "site.example.com"); err != nil || !healthy {
+		t.Errorf("expected healthy after the retried job succeeded, got healthy=%v err=%v", healthy, err)
+	}
+}
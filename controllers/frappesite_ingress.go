@@ -18,26 +18,188 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 
 	routev1 "github.com/openshift/api/route/v1"
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	"github.com/vyogotech/frappe-operator/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-// ensureIngress creates an Ingress for the site
+// defaultInternalURLTemplate points internal-only sites at the bench's nginx Service
+const defaultInternalURLTemplate = "http://{bench}-nginx.{namespace}.svc:{port}"
+
+// siteIngressEnabled reports whether the site is published through an Ingress or Route
+func siteIngressEnabled(site *vyogotechv1alpha1.FrappeSite) bool {
+	return site.Spec.Ingress == nil || site.Spec.Ingress.Enabled == nil || *site.Spec.Ingress.Enabled
+}
+
+// siteUsesRoute reports whether the site is published through an OpenShift Route rather
+// than an Ingress. On OpenShift Routes are the default unless the operator config sets
+// preferIngressOnOpenShift; routeConfig.enabled on the site overrides either default.
+func siteUsesRoute(site *vyogotechv1alpha1.FrappeSite, isOpenShift bool, operatorConfig *corev1.ConfigMap) bool {
+	if !isOpenShift {
+		return false
+	}
+	if site.Spec.RouteConfig != nil && site.Spec.RouteConfig.Enabled != nil {
+		return *site.Spec.RouteConfig.Enabled
+	}
+	return operatorConfig == nil || strings.TrimSpace(operatorConfig.Data["preferIngressOnOpenShift"]) != "true"
+}
+
+// siteIngressClassName returns the Ingress class of the site: spec.ingress.className, then
+// spec.ingressClassName, then the operator's defaultIngressClass, then "nginx"
+func siteIngressClassName(site *vyogotechv1alpha1.FrappeSite, operatorConfig *corev1.ConfigMap) string {
+	if site.Spec.Ingress != nil && site.Spec.Ingress.ClassName != "" {
+		return site.Spec.Ingress.ClassName
+	}
+	if site.Spec.IngressClassName != "" {
+		return site.Spec.IngressClassName
+	}
+	if operatorConfig != nil {
+		if className := strings.TrimSpace(operatorConfig.Data["defaultIngressClass"]); className != "" {
+			return className
+		}
+	}
+	return "nginx"
+}
+
+// defaultIngressAnnotations returns the defaultIngressAnnotations of the operator config,
+// a JSON object of annotations every site Ingress starts from
+func defaultIngressAnnotations(operatorConfig *corev1.ConfigMap) (map[string]string, error) {
+	if operatorConfig == nil || strings.TrimSpace(operatorConfig.Data["defaultIngressAnnotations"]) == "" {
+		return nil, nil
+	}
+	var annotations map[string]string
+	if err := json.Unmarshal([]byte(operatorConfig.Data["defaultIngressAnnotations"]), &annotations); err != nil {
+		return nil, fmt.Errorf("invalid defaultIngressAnnotations in operator config: %w", err)
+	}
+	return annotations, nil
+}
+
+// siteURL returns the URL reported in status.siteURL: the public domain, or for sites
+// without Ingress the in-cluster nginx address from ingress.internalURLTemplate
+func siteURL(site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, domain string) string {
+	if siteIngressEnabled(site) {
+		if site.Spec.TLS.Enabled {
+			return fmt.Sprintf("https://%s", domain)
+		}
+		return fmt.Sprintf("http://%s", domain)
+	}
+
+	template := defaultInternalURLTemplate
+	if site.Spec.Ingress.InternalURLTemplate != "" {
+		template = site.Spec.Ingress.InternalURLTemplate
+	}
+	return strings.NewReplacer(
+		"{bench}", bench.Name,
+		"{namespace}", bench.Namespace,
+		"{port}", "8080",
+		"{domain}", domain,
+	).Replace(template)
+}
+
+// siteURLHostHeader returns the Host header clients of status.siteURL must send, or "" when
+// the URL already names the site's domain. nginx selects the site from the Host header, so
+// the bench nginx Service address only reaches the site with the domain sent alongside it.
+func siteURLHostHeader(address, domain string) string {
+	if u, err := url.Parse(address); err == nil && strings.EqualFold(u.Hostname(), domain) {
+		return ""
+	}
+	return domain
+}
+
+// certManagerIssuerAnnotation returns the annotation that makes cert-manager issue the
+// Ingress certificate from tls.issuer
+func certManagerIssuerAnnotation(tls vyogotechv1alpha1.TLSConfig) string {
+	if tls.IssuerKind == "Issuer" {
+		return "cert-manager.io/issuer"
+	}
+	return "cert-manager.io/cluster-issuer"
+}
+
+// externalDNSHostnameAnnotation tells external-dns which hostname to publish for an Ingress or Route
+const externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+
+// externalDNSHostname returns the hostname to publish through external-dns, or "" when
+// the site has not opted in
+func externalDNSHostname(site *vyogotechv1alpha1.FrappeSite, domain string) string {
+	if site.Spec.DNS == nil || !site.Spec.DNS.ExternalDNS {
+		return ""
+	}
+	if site.Spec.DNS.ExternalDNSHostname != "" {
+		return site.Spec.DNS.ExternalDNSHostname
+	}
+	return domain
+}
+
+// syncExternalDNSAnnotation adds, updates or removes the external-dns hostname annotation
+// on an existing Ingress or Route, reporting whether anything changed. When external-dns is
+// off, an annotation the user set through the site's own annotations is left in place.
+func syncExternalDNSAnnotation(obj metav1.Object, hostname string, userAnnotations map[string]string) bool {
+	annotations := obj.GetAnnotations()
+	current, ok := annotations[externalDNSHostnameAnnotation]
+	if hostname == "" {
+		if _, userSet := userAnnotations[externalDNSHostnameAnnotation]; !ok || userSet {
+			return false
+		}
+		delete(annotations, externalDNSHostnameAnnotation)
+		obj.SetAnnotations(annotations)
+		return true
+	}
+	if ok && current == hostname {
+		return false
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[externalDNSHostnameAnnotation] = hostname
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// siteHosts returns the resolved domain followed by the site's aliases, without duplicates
+func siteHosts(site *vyogotechv1alpha1.FrappeSite, domain string) []string {
+	hosts := []string{domain}
+	seen := map[string]bool{domain: true}
+	for _, alias := range site.Spec.Aliases {
+		if alias == "" || seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		hosts = append(hosts, alias)
+	}
+	return hosts
+}
+
+// ingressHosts returns the hosts of an Ingress's rules in order
+func ingressHosts(ingress *networkingv1.Ingress) []string {
+	hosts := make([]string, 0, len(ingress.Spec.Rules))
+	for _, rule := range ingress.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+	return hosts
+}
+
+// ensureIngress creates an Ingress for the site with a rule for its domain and each alias
 func (r *FrappeSiteReconciler) ensureIngress(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, domain string) error {
 	logger := log.FromContext(ctx)
 
 	// Check if Ingress is disabled
-	if site.Spec.Ingress != nil && site.Spec.Ingress.Enabled != nil && !*site.Spec.Ingress.Enabled {
+	if !siteIngressEnabled(site) {
 		logger.Info("Ingress creation disabled by user", "site", site.Name)
 		return nil
 	}
@@ -45,22 +207,25 @@ func (r *FrappeSiteReconciler) ensureIngress(ctx context.Context, site *vyogotec
 	ingressName := fmt.Sprintf("%s-ingress", site.Name)
 	ingress := &networkingv1.Ingress{}
 
-	err := r.Get(ctx, types.NamespacedName{Name: ingressName, Namespace: site.Namespace}, ingress)
-	if err == nil {
-		logger.Info("Ingress already exists", "ingress", ingressName)
-		return nil
+	var userAnnotations map[string]string
+	if site.Spec.Ingress != nil {
+		userAnnotations = site.Spec.Ingress.Annotations
 	}
 
-	if !errors.IsNotFound(err) {
+	err := r.Get(ctx, types.NamespacedName{Name: ingressName, Namespace: site.Namespace}, ingress)
+	if err != nil && !errors.IsNotFound(err) {
 		return err
 	}
+	found := err == nil
 
-	logger.Info("Creating Ingress", "ingress", ingressName, "domain", domain)
-
-	// Determine ingress class
-	ingressClassName := "nginx" // Default
-	if site.Spec.IngressClassName != "" {
-		ingressClassName = site.Spec.IngressClassName
+	// Operator-wide defaults sit under the site's own class and annotations; a missing
+	// ConfigMap means no defaults
+	operatorConfig, _ := r.getOperatorConfig(ctx, site.Namespace)
+	ingressClassName := siteIngressClassName(site, operatorConfig)
+	operatorAnnotations, err := defaultIngressAnnotations(operatorConfig)
+	if err != nil {
+		// A broken default shouldn't keep sites offline; they are published without it
+		logger.Error(err, "Ignoring operator default Ingress annotations")
 	}
 
 	// Validate IngressClass existence (optional/warning)
@@ -68,6 +233,7 @@ func (r *FrappeSiteReconciler) ensureIngress(ctx context.Context, site *vyogotec
 
 	nginxSvcName := fmt.Sprintf("%s-nginx", bench.Name)
 	pathType := networkingv1.PathTypePrefix
+	hosts := siteHosts(site, domain)
 
 	builder := resources.NewIngressBuilder(ingressName, site.Namespace).
 		WithLabels(map[string]string{
@@ -77,9 +243,12 @@ func (r *FrappeSiteReconciler) ensureIngress(ctx context.Context, site *vyogotec
 		WithAnnotations(map[string]string{
 			"nginx.ingress.kubernetes.io/proxy-body-size": "100m",
 		}).
+		WithAnnotations(operatorAnnotations).
 		WithClassName(ingressClassName).
-		WithRule(domain, "/", pathType, nginxSvcName, 8080).
 		WithOwner(site, r.Scheme)
+	for _, host := range hosts {
+		builder.WithRule(host, "/", pathType, nginxSvcName, 8080)
+	}
 
 	// Add TLS if enabled
 	if site.Spec.TLS.Enabled {
@@ -87,26 +256,53 @@ func (r *FrappeSiteReconciler) ensureIngress(ctx context.Context, site *vyogotec
 		if tlsSecretName == "" {
 			tlsSecretName = fmt.Sprintf("%s-tls", site.Name)
 		}
-		builder.WithTLS([]string{domain}, tlsSecretName)
+		builder.WithTLS(hosts, tlsSecretName)
 
 		if site.Spec.TLS.Issuer != "" {
 			builder.WithAnnotations(map[string]string{
-				"cert-manager.io/cluster-issuer": site.Spec.TLS.Issuer,
+				certManagerIssuerAnnotation(site.Spec.TLS): site.Spec.TLS.Issuer,
 			})
 		}
 	}
 
 	// Merge additional annotations from site spec
-	if site.Spec.Ingress != nil && site.Spec.Ingress.Annotations != nil {
-		builder.WithAnnotations(site.Spec.Ingress.Annotations)
+	if userAnnotations != nil {
+		builder.WithAnnotations(userAnnotations)
 	}
 
-	ingress, err = builder.Build()
+	if hostname := externalDNSHostname(site, domain); hostname != "" {
+		builder.WithAnnotations(map[string]string{externalDNSHostnameAnnotation: hostname})
+	}
+
+	desired, err := builder.Build()
 	if err != nil {
 		return err
 	}
 
-	if err := r.Create(ctx, ingress); err != nil {
+	if found {
+		changed := false
+		if syncExternalDNSAnnotation(ingress, externalDNSHostname(site, domain), userAnnotations) {
+			logger.Info("Updating external-dns hostname annotation", "ingress", ingressName)
+			changed = true
+		}
+		// Adding or removing an alias rewrites the rules, dropping those of stale aliases
+		if !equality.Semantic.DeepEqual(ingressHosts(ingress), hosts) {
+			logger.Info("Updating Ingress hosts", "ingress", ingressName, "hosts", hosts)
+			ingress.Spec.Rules = desired.Spec.Rules
+			for i := range ingress.Spec.TLS {
+				ingress.Spec.TLS[i].Hosts = hosts
+			}
+			changed = true
+		}
+		if changed {
+			return r.Update(ctx, ingress)
+		}
+		logger.Info("Ingress already exists", "ingress", ingressName)
+		return nil
+	}
+
+	logger.Info("Creating Ingress", "ingress", ingressName, "hosts", hosts)
+	if err := r.Create(ctx, desired); err != nil {
 		return fmt.Errorf("failed to create Ingress: %w", err)
 	}
 
@@ -114,38 +310,172 @@ func (r *FrappeSiteReconciler) ensureIngress(ctx context.Context, site *vyogotec
 	return nil
 }
 
-// ensureRoute creates an OpenShift Route for the site
+// routeTermination returns the TLS termination of the site's Route, edge by default
+func routeTermination(site *vyogotechv1alpha1.FrappeSite) routev1.TLSTerminationType {
+	if site.Spec.RouteConfig != nil {
+		switch site.Spec.RouteConfig.TLSTermination {
+		case "passthrough":
+			return routev1.TLSTerminationPassthrough
+		case "reencrypt":
+			return routev1.TLSTerminationReencrypt
+		}
+	}
+	return routev1.TLSTerminationEdge
+}
+
+// validateRouteTLS rejects passthrough and reencrypt Routes to a bench whose nginx only speaks HTTP
+func validateRouteTLS(site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) error {
+	termination := routeTermination(site)
+	if termination != routev1.TLSTerminationEdge && !nginxTLSEnabled(bench) {
+		return fmt.Errorf("routeConfig.tlsTermination %s requires spec.nginxTLS on bench %s", termination, bench.Name)
+	}
+	return nil
+}
+
+// wildcardRouteHost returns the host of a subdomain wildcard Route covering domain and its
+// siblings: OpenShift serves *.example.com for host wildcard.example.com
+func wildcardRouteHost(domain string) (string, bool) {
+	_, parent, ok := strings.Cut(domain, ".")
+	if !ok || !strings.Contains(parent, ".") {
+		return "", false
+	}
+	return "wildcard." + parent, true
+}
+
+// routeWildcard returns the wildcard policy and host of the site's Route. A subdomain
+// policy that can't be honoured falls back to a plain Route and returns why.
+func routeWildcard(site *vyogotechv1alpha1.FrappeSite, domain string) (routev1.WildcardPolicyType, string, string) {
+	if site.Spec.RouteConfig == nil || site.Spec.RouteConfig.WildcardPolicy != "subdomain" {
+		return routev1.WildcardPolicyNone, domain, ""
+	}
+	if routeTermination(site) == routev1.TLSTerminationPassthrough {
+		return routev1.WildcardPolicyNone, domain, "routeConfig.wildcardPolicy subdomain cannot be combined with passthrough termination; creating a Route for the site's domain only"
+	}
+	host, ok := wildcardRouteHost(domain)
+	if !ok {
+		return routev1.WildcardPolicyNone, domain, fmt.Sprintf("routeConfig.wildcardPolicy subdomain needs a domain with a parent domain, got %q; creating a Route for the site's domain only", domain)
+	}
+	return routev1.WildcardPolicySubdomain, host, ""
+}
+
+// routeWildcardPolicyChanged reports whether an existing Route has a different wildcard
+// policy; the API server defaults an unset policy to None
+func routeWildcardPolicyChanged(route *routev1.Route, policy routev1.WildcardPolicyType) bool {
+	current := route.Spec.WildcardPolicy
+	if current == "" {
+		current = routev1.WildcardPolicyNone
+	}
+	return current != policy
+}
+
+// routeTLS returns the Route TLS config and the `<bench>-nginx` port it targets. Edge
+// Routes terminate at the router and reach nginx over http; passthrough and reencrypt
+// Routes reach nginx over https, reencrypt trusting the ca.crt of spec.nginxTLS.secretName.
+func (r *FrappeSiteReconciler) routeTLS(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) (*routev1.TLSConfig, intstr.IntOrString, error) {
+	if err := validateRouteTLS(site, bench); err != nil {
+		return nil, intstr.IntOrString{}, err
+	}
+
+	termination := routeTermination(site)
+	tls := &routev1.TLSConfig{
+		Termination:                   termination,
+		InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+	}
+	switch termination {
+	case routev1.TLSTerminationPassthrough:
+		return tls, intstr.FromString("https"), nil
+	case routev1.TLSTerminationReencrypt:
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: bench.Spec.NginxTLS.SecretName, Namespace: bench.Namespace}, secret); err != nil {
+			return nil, intstr.IntOrString{}, fmt.Errorf("failed to get nginx TLS secret %s: %w", bench.Spec.NginxTLS.SecretName, err)
+		}
+		tls.DestinationCACertificate = string(secret.Data["ca.crt"])
+		return tls, intstr.FromString("https"), nil
+	}
+	return tls, intstr.FromString("http"), nil
+}
+
+// syncRouteTLS points an existing Route at the desired termination and nginx port,
+// reporting whether anything changed. Certificates set on the Route by hand are kept.
+func syncRouteTLS(route *routev1.Route, tls *routev1.TLSConfig, targetPort intstr.IntOrString) bool {
+	if route.Spec.TLS != nil && route.Spec.TLS.Termination == tls.Termination && route.Spec.TLS.DestinationCACertificate == tls.DestinationCACertificate &&
+		route.Spec.Port != nil && route.Spec.Port.TargetPort == targetPort {
+		return false
+	}
+	if route.Spec.TLS == nil {
+		route.Spec.TLS = &routev1.TLSConfig{}
+	}
+	route.Spec.TLS.Termination = tls.Termination
+	route.Spec.TLS.InsecureEdgeTerminationPolicy = tls.InsecureEdgeTerminationPolicy
+	route.Spec.TLS.DestinationCACertificate = tls.DestinationCACertificate
+	if tls.Termination == routev1.TLSTerminationPassthrough {
+		// The router never sees the traffic in clear, so it can't present a certificate
+		route.Spec.TLS.Certificate = ""
+		route.Spec.TLS.Key = ""
+		route.Spec.TLS.CACertificate = ""
+	}
+	route.Spec.Port = &routev1.RoutePort{TargetPort: targetPort}
+	return true
+}
+
+// ensureRoute creates an OpenShift Route for the site's domain and one per alias
 func (r *FrappeSiteReconciler) ensureRoute(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, domain string) error {
 	logger := log.FromContext(ctx)
 
 	routeName := fmt.Sprintf("%s-route", site.Name)
 	route := &routev1.Route{}
 
-	err := r.Get(ctx, types.NamespacedName{Name: routeName, Namespace: site.Namespace}, route)
+	var userAnnotations map[string]string
+	if site.Spec.RouteConfig != nil {
+		userAnnotations = site.Spec.RouteConfig.Annotations
+	}
+
+	tls, targetPort, err := r.routeTLS(ctx, site, bench)
+	if err != nil {
+		return err
+	}
+	wildcardPolicy, host, wildcardWarning := routeWildcard(site, domain)
+
+	err = r.Get(ctx, types.NamespacedName{Name: routeName, Namespace: site.Namespace}, route)
+	if err == nil && routeWildcardPolicyChanged(route, wildcardPolicy) {
+		// spec.wildcardPolicy is immutable, so the Route is replaced
+		logger.Info("Recreating Route for new wildcard policy", "route", routeName, "wildcardPolicy", wildcardPolicy)
+		if err := r.Delete(ctx, route); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		err = errors.NewNotFound(routev1.Resource("routes"), routeName)
+	}
 	if err == nil {
-		logger.Info("Route already exists", "route", routeName)
-		return nil
+		changed := false
+		if syncExternalDNSAnnotation(route, externalDNSHostname(site, domain), userAnnotations) {
+			logger.Info("Updating external-dns hostname annotation", "route", routeName)
+			changed = true
+		}
+		if syncRouteTLS(route, tls, targetPort) {
+			logger.Info("Updating Route TLS termination", "route", routeName, "termination", tls.Termination)
+			changed = true
+		}
+		if changed {
+			if err := r.Update(ctx, route); err != nil {
+				return err
+			}
+		} else {
+			logger.Info("Route already exists", "route", routeName)
+		}
+		return r.ensureAliasRoutes(ctx, site, bench, domain, tls, targetPort)
 	}
 
 	if !errors.IsNotFound(err) {
 		return err
 	}
 
-	logger.Info("Creating OpenShift Route", "route", routeName, "domain", domain)
+	logger.Info("Creating OpenShift Route", "route", routeName, "host", host, "termination", tls.Termination, "wildcardPolicy", wildcardPolicy)
+	if wildcardWarning != "" {
+		r.Recorder.Event(site, corev1.EventTypeWarning, "WildcardPolicyIgnored", wildcardWarning)
+	}
 
 	nginxSvcName := fmt.Sprintf("%s-nginx", bench.Name)
 
-	// Determine TLS termination
-	tlsTermination := routev1.TLSTerminationEdge
-	if site.Spec.RouteConfig != nil && site.Spec.RouteConfig.TLSTermination != "" {
-		switch site.Spec.RouteConfig.TLSTermination {
-		case "passthrough":
-			tlsTermination = routev1.TLSTerminationPassthrough
-		case "reencrypt":
-			tlsTermination = routev1.TLSTerminationReencrypt
-		}
-	}
-
 	route = &routev1.Route{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      routeName,
@@ -156,32 +486,30 @@ func (r *FrappeSiteReconciler) ensureRoute(ctx context.Context, site *vyogotechv
 			},
 		},
 		Spec: routev1.RouteSpec{
-			Host: domain,
+			Host: host,
 			To: routev1.RouteTargetReference{
 				Kind: "Service",
 				Name: nginxSvcName,
 			},
 			Port: &routev1.RoutePort{
-				TargetPort: intstr.FromInt(8080),
-			},
-			TLS: &routev1.TLSConfig{
-				Termination:                   tlsTermination,
-				InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+				TargetPort: targetPort,
 			},
-			WildcardPolicy: routev1.WildcardPolicyNone,
+			TLS:            tls,
+			WildcardPolicy: wildcardPolicy,
 		},
 	}
-
 	// Add additional annotations from site spec
-	if site.Spec.RouteConfig != nil && site.Spec.RouteConfig.Annotations != nil {
+	if userAnnotations != nil {
 		if route.Annotations == nil {
 			route.Annotations = make(map[string]string)
 		}
-		for k, v := range site.Spec.RouteConfig.Annotations {
+		for k, v := range userAnnotations {
 			route.Annotations[k] = v
 		}
 	}
 
+	syncExternalDNSAnnotation(route, externalDNSHostname(site, domain), userAnnotations)
+
 	if err := controllerutil.SetControllerReference(site, route, r.Scheme); err != nil {
 		return err
 	}
@@ -190,5 +518,158 @@ func (r *FrappeSiteReconciler) ensureRoute(ctx context.Context, site *vyogotechv
 		return fmt.Errorf("failed to create Route: %w", err)
 	}
 
+	return r.ensureAliasRoutes(ctx, site, bench, domain, tls, targetPort)
+}
+
+// aliasRouteComponent labels the Routes created for spec.aliases
+const aliasRouteComponent = "alias-route"
+
+// aliasRouteName returns the name of the Route serving one of the site's aliases
+func aliasRouteName(site *vyogotechv1alpha1.FrappeSite, alias string) string {
+	return fmt.Sprintf("%s-route-%s", site.Name, alias)
+}
+
+// ensureAliasRoutes keeps one plain Route per alias next to the primary Route and
+// deletes the Routes of aliases that were removed from the spec
+func (r *FrappeSiteReconciler) ensureAliasRoutes(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, domain string, tls *routev1.TLSConfig, targetPort intstr.IntOrString) error {
+	logger := log.FromContext(ctx)
+
+	var userAnnotations map[string]string
+	if site.Spec.RouteConfig != nil {
+		userAnnotations = site.Spec.RouteConfig.Annotations
+	}
+	labels := map[string]string{
+		"app":       "frappe",
+		"site":      site.Name,
+		"component": aliasRouteComponent,
+	}
+
+	aliases := siteHosts(site, domain)[1:]
+	wanted := make(map[string]bool, len(aliases))
+	for _, alias := range aliases {
+		wanted[aliasRouteName(site, alias)] = true
+	}
+
+	routes := &routev1.RouteList{}
+	if err := r.List(ctx, routes, client.InNamespace(site.Namespace), client.MatchingLabels(labels)); err != nil {
+		return err
+	}
+	existing := make(map[string]*routev1.Route, len(routes.Items))
+	for i := range routes.Items {
+		route := &routes.Items[i]
+		if wanted[route.Name] {
+			existing[route.Name] = route
+			continue
+		}
+		logger.Info("Deleting Route of removed alias", "route", route.Name, "host", route.Spec.Host)
+		if err := r.Delete(ctx, route); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	for _, alias := range aliases {
+		routeName := aliasRouteName(site, alias)
+		hostname := ""
+		if externalDNSHostname(site, domain) != "" {
+			hostname = alias
+		}
+
+		if route, ok := existing[routeName]; ok {
+			changed := syncExternalDNSAnnotation(route, hostname, userAnnotations)
+			if syncRouteTLS(route, tls, targetPort) {
+				changed = true
+			}
+			if changed {
+				logger.Info("Updating alias Route", "route", routeName)
+				if err := r.Update(ctx, route); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		route := &routev1.Route{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      routeName,
+				Namespace: site.Namespace,
+				Labels:    labels,
+			},
+			Spec: routev1.RouteSpec{
+				Host: alias,
+				To: routev1.RouteTargetReference{
+					Kind: "Service",
+					Name: fmt.Sprintf("%s-nginx", bench.Name),
+				},
+				Port: &routev1.RoutePort{
+					TargetPort: targetPort,
+				},
+				TLS: tls.DeepCopy(),
+			},
+		}
+		if userAnnotations != nil {
+			route.Annotations = make(map[string]string)
+			for k, v := range userAnnotations {
+				route.Annotations[k] = v
+			}
+		}
+		syncExternalDNSAnnotation(route, hostname, userAnnotations)
+
+		if err := controllerutil.SetControllerReference(site, route, r.Scheme); err != nil {
+			return err
+		}
+		logger.Info("Creating alias Route", "route", routeName, "host", alias)
+		if err := r.Create(ctx, route); err != nil {
+			return fmt.Errorf("failed to create Route for alias %s: %w", alias, err)
+		}
+	}
+	return nil
+}
+
+// deleteSiteIngress removes the site's Ingress once it is published through a Route
+func (r *FrappeSiteReconciler) deleteSiteIngress(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) error {
+	ingress := &networkingv1.Ingress{}
+	err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-ingress", site.Name), Namespace: site.Namespace}, ingress)
+	if err != nil || !metav1.IsControlledBy(ingress, site) {
+		return client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).Info("Deleting Ingress replaced by a Route", "ingress", ingress.Name)
+	return client.IgnoreNotFound(r.Delete(ctx, ingress))
+}
+
+// deleteSiteRoutes removes the site's primary and alias Routes once it is published
+// through an Ingress. Routes only exist on OpenShift, elsewhere there is nothing to do.
+func (r *FrappeSiteReconciler) deleteSiteRoutes(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) error {
+	if !r.IsOpenShift {
+		return nil
+	}
+	routes := &routev1.RouteList{}
+	err := r.List(ctx, routes, client.InNamespace(site.Namespace), client.MatchingLabels{
+		"app":       "frappe",
+		"site":      site.Name,
+		"component": aliasRouteComponent,
+	})
+	if meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	primary := &routev1.Route{}
+	err = r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-route", site.Name), Namespace: site.Namespace}, primary)
+	if err == nil {
+		routes.Items = append(routes.Items, *primary)
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	for i := range routes.Items {
+		route := &routes.Items[i]
+		if !metav1.IsControlledBy(route, site) {
+			continue
+		}
+		log.FromContext(ctx).Info("Deleting Route replaced by an Ingress", "route", route.Name)
+		if err := r.Delete(ctx, route); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
 	return nil
 }
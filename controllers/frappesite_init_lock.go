@@ -0,0 +1,179 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// siteInitQueuedRequeue is how long a site waits for another site-init Job on its bench
+	siteInitQueuedRequeue = 15 * time.Second
+
+	// siteInitCreatedTTL is how long a Job created by this process counts as running
+	// without showing up in the cache, e.g. when it was deleted right away
+	siteInitCreatedTTL = time.Minute
+)
+
+// errSiteInitQueued marks a site whose init Job waits for another site-init Job on the
+// same bench to finish
+var errSiteInitQueued = errors.New("site-init job queued")
+
+// isSiteInitQueued reports whether err comes from a site-init Job held back by the bench lock
+func isSiteInitQueued(err error) bool {
+	return errors.Is(err, errSiteInitQueued)
+}
+
+// serializeSiteInits reports whether site-init Jobs on the bench must run one at a time.
+// A sites PVC without ReadWriteMany can only be attached to one node, so a second init
+// Job scheduled elsewhere would sit in ContainerCreating until the first one is done.
+func (r *FrappeSiteReconciler) serializeSiteInits(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-sites", bench.Name), Namespace: bench.Namespace}, pvc)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !hasAccessMode(pvc.Spec.AccessModes, corev1.ReadWriteMany), nil
+}
+
+// pvcNode returns the node a pod mounting the claim runs on, "" when no pod mounts it
+func (r *FrappeSiteReconciler) pvcNode(ctx context.Context, namespace, claimName string) (string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(namespace)); err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+				return pod.Spec.NodeName, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// pinToNode requires the pod to run on node, on top of any node affinity it already has
+func pinToNode(spec *corev1.PodSpec, node string) {
+	spec.Affinity = spec.Affinity.DeepCopy()
+	if spec.Affinity == nil {
+		spec.Affinity = &corev1.Affinity{}
+	}
+	if spec.Affinity.NodeAffinity == nil {
+		spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	required := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		required = &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{}}}
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = required
+	}
+	// Terms are ORed, so every one of them gets the node
+	for i := range required.NodeSelectorTerms {
+		required.NodeSelectorTerms[i].MatchFields = append(required.NodeSelectorTerms[i].MatchFields, corev1.NodeSelectorRequirement{
+			Key:      "metadata.name",
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{node},
+		})
+	}
+}
+
+// siteInitGate serializes site-init Job creation per bench so concurrent reconciles of
+// two sites on a ReadWriteOnce bench can't both see no running Job and create one each
+type siteInitGate struct {
+	mu sync.Mutex
+	// created holds Jobs this process created that the cache may not list yet
+	created map[types.NamespacedName]createdSiteInit
+}
+
+// createdSiteInit is a site-init Job siteInitGate created
+type createdSiteInit struct {
+	bench string
+	at    time.Time
+}
+
+// create creates the site-init Job unless another site-init Job of the bench is still
+// running, in which case it returns an error wrapping errSiteInitQueued
+func (g *siteInitGate) create(ctx context.Context, c client.Client, job *batchv1.Job, benchName string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	running, err := g.running(ctx, c, job.Namespace, benchName)
+	if err != nil {
+		return err
+	}
+	for _, key := range running {
+		if key != client.ObjectKeyFromObject(job) {
+			return fmt.Errorf("%w: waiting for %s on bench %s", errSiteInitQueued, key.Name, benchName)
+		}
+	}
+
+	if err := c.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	if g.created == nil {
+		g.created = make(map[types.NamespacedName]createdSiteInit)
+	}
+	g.created[client.ObjectKeyFromObject(job)] = createdSiteInit{bench: benchName, at: time.Now()}
+	return nil
+}
+
+// running returns the site-init Jobs of the bench that have neither succeeded nor failed,
+// including Jobs created by this process that haven't reached the cache yet
+func (g *siteInitGate) running(ctx context.Context, c client.Client, namespace, benchName string) ([]types.NamespacedName, error) {
+	jobs := &batchv1.JobList{}
+	labels := jobLabels(jobOperationInit, benchName, "")
+	if err := c.List(ctx, jobs, client.InNamespace(namespace), client.MatchingLabels(labels)); err != nil {
+		return nil, err
+	}
+
+	listed := make(map[types.NamespacedName]struct{}, len(jobs.Items))
+	var running []types.NamespacedName
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		key := client.ObjectKeyFromObject(job)
+		listed[key] = struct{}{}
+		if job.Status.Succeeded == 0 && !initJobFailed(job) {
+			running = append(running, key)
+		}
+	}
+	for key, created := range g.created {
+		if _, ok := listed[key]; ok || time.Since(created.at) > siteInitCreatedTTL {
+			delete(g.created, key)
+			continue
+		}
+		if created.bench == benchName && key.Namespace == namespace {
+			running = append(running, key)
+		}
+	}
+	return running, nil
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnsureSiteInitialized_serializesOnReadWriteOnceBench(t *testing.T) {
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	tests := []struct {
+		name       string
+		accessMode corev1.PersistentVolumeAccessMode
+		wantQueued bool
+	}{
+		{name: "ReadWriteOnce", accessMode: corev1.ReadWriteOnce, wantQueued: true},
+		{name: "ReadWriteMany", accessMode: corev1.ReadWriteMany},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, bench := newInitJobTestObjects()
+			second := first.DeepCopy()
+			second.Name = "second"
+			second.UID = "second-uid"
+			second.Spec.SiteName = "second.local"
+			pvc := &corev1.PersistentVolumeClaim{}
+			pvc.Name = "bench-sites"
+			pvc.Namespace = "default"
+			pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{tt.accessMode}
+			r, c := newInitJobTestReconciler(first, second, bench, pvc)
+			ctx := context.Background()
+
+			if _, err := r.ensureSiteInitialized(ctx, first, bench, "site.local", dbInfo, dbCreds); err != nil {
+				t.Fatalf("ensureSiteInitialized first: %v", err)
+			}
+			_, err := r.ensureSiteInitialized(ctx, second, bench, "second.local", dbInfo, dbCreds)
+			secondKey := types.NamespacedName{Name: "second-init", Namespace: "default"}
+			if !tt.wantQueued {
+				if err != nil {
+					t.Fatalf("expected both init jobs to run, got %v", err)
+				}
+				if err := c.Get(ctx, secondKey, &batchv1.Job{}); err != nil {
+					t.Errorf("expected the second init job, got %v", err)
+				}
+				return
+			}
+			if !isSiteInitQueued(err) {
+				t.Fatalf("expected the second site to be queued, got %v", err)
+			}
+			if err := c.Get(ctx, secondKey, &batchv1.Job{}); !errors.IsNotFound(err) {
+				t.Fatalf("expected no second init job while the first runs, got %v", err)
+			}
+
+			// Once the first job is done the second site gets its turn
+			firstJob := &batchv1.Job{}
+			if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, firstJob); err != nil {
+				t.Fatalf("Get first job: %v", err)
+			}
+			firstJob.Status.Succeeded = 1
+			if err := c.Status().Update(ctx, firstJob); err != nil {
+				t.Fatalf("Update first job: %v", err)
+			}
+			if _, err := r.ensureSiteInitialized(ctx, second, bench, "second.local", dbInfo, dbCreds); err != nil {
+				t.Fatalf("ensureSiteInitialized second: %v", err)
+			}
+			if err := c.Get(ctx, secondKey, &batchv1.Job{}); err != nil {
+				t.Errorf("expected the second init job after the first succeeded, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEnsureSiteInitialized_waitsForRunningInitJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []batchv1.JobCondition
+		wantQueued bool
+	}{
+		{name: "running", wantQueued: true},
+		{name: "failed", conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site, bench := newInitJobTestObjects()
+			other := &batchv1.Job{}
+			other.Name = "other-init"
+			other.Namespace = "default"
+			other.Labels = jobLabels(jobOperationInit, "bench", "other.local")
+			other.Status.Conditions = tt.conditions
+			pvc := &corev1.PersistentVolumeClaim{}
+			pvc.Name = "bench-sites"
+			pvc.Namespace = "default"
+			pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+			r, _ := newInitJobTestReconciler(site, bench, other, pvc)
+
+			dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+			_, err := r.ensureSiteInitialized(context.Background(), site, bench, "site.local", dbInfo, nil)
+			if got := isSiteInitQueued(err); got != tt.wantQueued {
+				t.Errorf("expected queued=%v, got %v", tt.wantQueued, err)
+			}
+		})
+	}
+}
+
+func TestEnsureSiteInitialized_pinsToPVCNode(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	pvc := &corev1.PersistentVolumeClaim{}
+	pvc.Name = "bench-sites"
+	pvc.Namespace = "default"
+	pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	gunicorn := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-gunicorn-abc", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName: "node-a",
+			Volumes: []corev1.Volume{{
+				Name:         "sites",
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "bench-sites"}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, c := newInitJobTestReconciler(site, bench, pvc, gunicorn)
+	ctx := context.Background()
+
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, nil); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get init job: %v", err)
+	}
+	affinity := job.Spec.Template.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		t.Fatalf("expected the init job to be pinned, got %+v", affinity)
+	}
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || len(terms[0].MatchFields) != 1 || terms[0].MatchFields[0].Values[0] != "node-a" {
+		t.Errorf("expected the init job to require node-a, got %+v", terms)
+	}
+}
+
+func TestPinToNode(t *testing.T) {
+	zone := corev1.NodeSelectorRequirement{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}
+	benchAffinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{zone}}, {}},
+		},
+	}}
+	spec := &corev1.PodSpec{Affinity: benchAffinity}
+	pinToNode(spec, "node-a")
+
+	for i, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if len(term.MatchFields) != 1 || term.MatchFields[0].Values[0] != "node-a" {
+			t.Errorf("expected term %d to require node-a, got %+v", i, term)
+		}
+	}
+	if len(benchAffinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchFields) != 0 {
+		t.Error("expected the bench's affinity to be left alone")
+	}
+}
+
+func TestSiteInitGate_expiresCreatedJobs(t *testing.T) {
+	_, c := newInitJobTestReconciler()
+	gate := &siteInitGate{created: map[types.NamespacedName]createdSiteInit{
+		{Name: "gone-init", Namespace: "default"}: {bench: "bench", at: time.Now().Add(-2 * siteInitCreatedTTL)},
+		{Name: "new-init", Namespace: "default"}:  {bench: "bench", at: time.Now()},
+	}}
+
+	running, err := gate.running(context.Background(), c, "default", "bench")
+	if err != nil {
+		t.Fatalf("running: %v", err)
+	}
+	if len(running) != 1 || running[0].Name != "new-init" {
+		t.Errorf("expected only the recently created job to count, got %v", running)
+	}
+	if _, ok := gate.created[types.NamespacedName{Name: "gone-init", Namespace: "default"}]; ok {
+		t.Error("expected the expired entry to be dropped")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// siteInitResult is what the site init script writes to its termination message
+type siteInitResult struct {
+	Installed []string `json:"installed"`
+	Skipped   []string `json:"skipped"`
+}
+
+// parseSiteInitResult reads the installed and skipped apps from the termination message
+// of the site init job
+func parseSiteInitResult(message string) (*siteInitResult, error) {
+	// Keep only the JSON document in case anything else ended up in the message
+	if start := strings.Index(message, "{"); start > 0 {
+		message = message[start:]
+	}
+	result := &siteInitResult{}
+	if err := json.Unmarshal([]byte(message), result); err != nil {
+		return nil, fmt.Errorf("failed to parse site init result: %w", err)
+	}
+	return result, nil
+}
+
+// recordInstalledApps sets status.installedApps and status.skippedApps from the result the
+// succeeded init job reported. Jobs from before the result existed, or whose pods are gone,
+// report nothing; every requested app is then assumed installed.
+func (r *FrappeSiteReconciler) recordInstalledApps(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, job *batchv1.Job) {
+	logger := log.FromContext(ctx)
+
+	if len(site.Spec.Apps) == 0 {
+		site.Status.AppInstallationStatus = "No apps specified - only frappe framework installed"
+		logger.Info("Site initialized with frappe framework only")
+		return
+	}
+
+	message, err := jobTerminationMessage(ctx, r, job)
+	if err == nil && message == "" {
+		err = fmt.Errorf("job %s reported no result", job.Name)
+	}
+	var result *siteInitResult
+	if err == nil {
+		result, err = parseSiteInitResult(message)
+	}
+	if err != nil {
+		logger.Info("Site init job reported no app result, assuming all requested apps are installed", "reason", err.Error())
+		site.Status.InstalledApps = slices.Clone(site.Spec.Apps)
+		site.Status.SkippedApps = nil
+		site.Status.AppInstallationStatus = fmt.Sprintf("Completed app installation for %d requested app(s) - check logs for any skipped apps", len(site.Spec.Apps))
+		r.Recorder.Event(site, corev1.EventTypeNormal, "AppsProcessed",
+			fmt.Sprintf("Processed app installation for: %v - check job logs for any skipped apps", site.Spec.Apps))
+		return
+	}
+
+	site.Status.InstalledApps = result.Installed
+	site.Status.SkippedApps = result.Skipped
+	logger.Info("App installation completed", "installedApps", result.Installed, "skippedApps", result.Skipped)
+	if len(result.Skipped) == 0 {
+		site.Status.AppInstallationStatus = fmt.Sprintf("Installed %d of %d requested app(s)", len(result.Installed), len(site.Spec.Apps))
+		r.Recorder.Event(site, corev1.EventTypeNormal, "AppsInstalled",
+			fmt.Sprintf("Installed apps: %s", strings.Join(result.Installed, ", ")))
+		return
+	}
+	site.Status.AppInstallationStatus = fmt.Sprintf("Installed %d of %d requested app(s), skipped %s because the bench does not have them",
+		len(result.Installed), len(site.Spec.Apps), strings.Join(result.Skipped, ", "))
+	r.Recorder.Event(site, corev1.EventTypeWarning, "AppsSkipped",
+		fmt.Sprintf("Skipped apps missing from bench %s: %s", site.Spec.BenchRef.Name, strings.Join(result.Skipped, ", ")))
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseSiteInitResult(t *testing.T) {
+	result, err := parseSiteInitResult(`{"installed": ["erpnext"], "skipped": ["hrms"]}`)
+	if err != nil {
+		t.Fatalf("parseSiteInitResult: %v", err)
+	}
+	if !slices.Equal(result.Installed, []string{"erpnext"}) || !slices.Equal(result.Skipped, []string{"hrms"}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, err := parseSiteInitResult("Site initialization complete!"); err == nil {
+		t.Error("expected an error for output without a result")
+	}
+}
+
+func TestEnsureSiteInitialized_recordsInstalledAndSkippedApps(t *testing.T) {
+	tests := []struct {
+		name          string
+		message       string
+		wantInstalled []string
+		wantSkipped   []string
+		wantStatus    string
+	}{
+		{
+			name:          "skipped app",
+			message:       `{"installed": ["erpnext"], "skipped": ["hrms"]}`,
+			wantInstalled: []string{"erpnext"},
+			wantSkipped:   []string{"hrms"},
+			wantStatus:    "Installed 1 of 2 requested app(s), skipped hrms",
+		},
+		{
+			name:          "all installed",
+			message:       `{"installed": ["erpnext", "hrms"], "skipped": []}`,
+			wantInstalled: []string{"erpnext", "hrms"},
+			wantStatus:    "Installed 2 of 2 requested app(s)",
+		},
+		{
+			name:          "no result",
+			wantInstalled: []string{"erpnext", "hrms"},
+			wantStatus:    "check logs for any skipped apps",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site, bench := newInitJobTestObjects()
+			site.Spec.Apps = []string{"erpnext", "hrms"}
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "site-init", Namespace: "default"},
+				Status:     batchv1.JobStatus{Succeeded: 1},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "site-init-abcde", Namespace: "default", Labels: map[string]string{"job-name": "site-init"}},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodSucceeded,
+					ContainerStatuses: []corev1.ContainerStatus{{
+						Name:  "site-init",
+						State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: tt.message}},
+					}},
+				},
+			}
+			r, _ := newInitJobTestReconciler(site, bench, job, pod)
+
+			dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+			ready, err := r.ensureSiteInitialized(context.Background(), site, bench, "site.local", dbInfo, nil)
+			if err != nil || !ready {
+				t.Fatalf("expected the site to be initialized, got ready=%v err=%v", ready, err)
+			}
+			if !slices.Equal(site.Status.InstalledApps, tt.wantInstalled) {
+				t.Errorf("expected installed apps %v, got %v", tt.wantInstalled, site.Status.InstalledApps)
+			}
+			if !slices.Equal(site.Status.SkippedApps, tt.wantSkipped) {
+				t.Errorf("expected skipped apps %v, got %v", tt.wantSkipped, site.Status.SkippedApps)
+			}
+			if !strings.Contains(site.Status.AppInstallationStatus, tt.wantStatus) {
+				t.Errorf("expected app installation status to contain %q, got %q", tt.wantStatus, site.Status.AppInstallationStatus)
+			}
+		})
+	}
+}
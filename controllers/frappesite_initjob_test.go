@@ -0,0 +1,340 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newInitJobTestReconciler(objs ...client.Object) (*FrappeSiteReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithIndex(&vyogotechv1alpha1.FrappeSite{}, siteSecretsIndex, siteSecretsIndexValues).
+		Build()
+	return &FrappeSiteReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}, c
+}
+
+func newInitJobTestObjects() (*vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench) {
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default", UID: "site-uid"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+		},
+	}
+	return site, bench
+}
+
+func TestEnsureSiteInitialized_InitScriptPreamble(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.InitScriptPreamble = &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "preamble"},
+		Key:                  "setup.sh",
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "preamble", Namespace: "default"},
+		Data:       map[string]string{"setup.sh": "apt-get install -y poppler-utils"},
+	}
+	r, c := newInitJobTestReconciler(site, bench, cm)
+	ctx := context.Background()
+
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+
+	var preambleVolume *corev1.Volume
+	for i := range job.Spec.Template.Spec.Volumes {
+		if job.Spec.Template.Spec.Volumes[i].Name == "site-preamble" {
+			preambleVolume = &job.Spec.Template.Spec.Volumes[i]
+		}
+	}
+	if preambleVolume == nil || preambleVolume.ConfigMap == nil {
+		t.Fatalf("expected ConfigMap volume site-preamble, got %+v", job.Spec.Template.Spec.Volumes)
+	}
+	if preambleVolume.ConfigMap.Name != "preamble" || preambleVolume.ConfigMap.Items[0].Key != "setup.sh" ||
+		preambleVolume.ConfigMap.Items[0].Path != initPreambleFileName {
+		t.Errorf("unexpected preamble projection: %+v", preambleVolume.ConfigMap)
+	}
+
+	container := job.Spec.Template.Spec.Containers[0]
+	mounted := false
+	for _, m := range container.VolumeMounts {
+		if m.Name == "site-preamble" {
+			mounted = m.MountPath == initPreambleMountPath && m.ReadOnly
+		}
+	}
+	if !mounted {
+		t.Errorf("expected read-only preamble mount at %s, got %+v", initPreambleMountPath, container.VolumeMounts)
+	}
+	for _, arg := range container.Args {
+		if strings.Contains(arg, cm.Data["setup.sh"]) {
+			t.Error("preamble content must not be inlined into the init script")
+		}
+	}
+}
+
+func TestEnsureSiteInitialized_InitScriptPreambleMissingKey(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.InitScriptPreamble = &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "preamble"},
+		Key:                  "setup.sh",
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "preamble", Namespace: "default"},
+		Data:       map[string]string{"other.sh": "true"},
+	}
+	r, c := newInitJobTestReconciler(site, bench, cm)
+	ctx := context.Background()
+
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err == nil {
+		t.Fatal("expected error for missing preamble key")
+	}
+
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err == nil {
+		t.Error("init job should not be created when the preamble key is missing")
+	}
+}
+
+func TestEnsureSiteInitialized_InitJobLimits(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Spec.BackoffLimit == nil || *job.Spec.BackoffLimit != defaultInitJobBackoffLimit {
+		t.Errorf("expected default backoffLimit %d, got %v", defaultInitJobBackoffLimit, job.Spec.BackoffLimit)
+	}
+	if job.Spec.ActiveDeadlineSeconds != nil {
+		t.Errorf("expected no deadline by default, got %d", *job.Spec.ActiveDeadlineSeconds)
+	}
+
+	// A failed pod with retries left is not a failure yet
+	job.Status.Failed = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if ready, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil || ready {
+		t.Fatalf("expected the job to keep retrying, got ready=%v err=%v", ready, err)
+	}
+
+	backoffLimit, deadline := int32(0), int64(300)
+	site.Spec.InitJob = &vyogotechv1alpha1.InitJobConfig{BackoffLimit: &backoffLimit, ActiveDeadlineSeconds: &deadline}
+	if err := c.Delete(ctx, job); err != nil {
+		t.Fatalf("Delete Job: %v", err)
+	}
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	job = &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if *job.Spec.BackoffLimit != 0 || job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != 300 {
+		t.Errorf("expected spec.initJob limits on the job, got backoffLimit=%v deadline=%v", job.Spec.BackoffLimit, job.Spec.ActiveDeadlineSeconds)
+	}
+}
+
+func TestEnsureSiteInitialized_ImagePullSecrets(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Spec.ImageConfig = &vyogotechv1alpha1.ImageConfig{
+		Repository:  "registry.example.com/frappe",
+		PullSecrets: []corev1.LocalObjectReference{{Name: "pull"}},
+	}
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if got := job.Spec.Template.Spec.ImagePullSecrets; len(got) != 1 || got[0].Name != "pull" {
+		t.Errorf("expected the bench pull secret on the init job, got %v", got)
+	}
+}
+
+func TestEnsureSiteInitialized_Env(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.Env = []vyogotechv1alpha1.SiteEnvVar{
+		{Name: "FRAPPE_REDIS_CACHE", Value: "redis://cache:6379"},
+		{Name: "API_TOKEN", SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "tokens"},
+			Key:                  "api",
+		}},
+	}
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	env := map[string]corev1.EnvVar{}
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e
+	}
+	if env["FRAPPE_REDIS_CACHE"].Value != "redis://cache:6379" {
+		t.Errorf("expected FRAPPE_REDIS_CACHE on the init container, got %+v", env["FRAPPE_REDIS_CACHE"])
+	}
+	if ref := env["API_TOKEN"].ValueFrom; ref == nil || ref.SecretKeyRef == nil || ref.SecretKeyRef.Name != "tokens" || ref.SecretKeyRef.Key != "api" {
+		t.Errorf("expected API_TOKEN from secret tokens/api, got %+v", env["API_TOKEN"])
+	}
+	// Credentials stay in the mounted secret files
+	if _, ok := env["DB_PASSWORD"]; ok {
+		t.Error("expected no DB_PASSWORD env var on the init container")
+	}
+
+	// Reserved names are refused even without the webhook
+	site.Spec.Env = []vyogotechv1alpha1.SiteEnvVar{{Name: "ADMIN_PASSWORD", Value: "x"}}
+	if err := c.Delete(ctx, job); err != nil {
+		t.Fatalf("Delete Job: %v", err)
+	}
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("expected a reserved env name to be rejected, got %v", err)
+	}
+}
+
+func TestEnsureSiteInitialized_DeadlineExceeded(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	deadline := int64(300)
+	site.Spec.InitJob = &vyogotechv1alpha1.InitJobConfig{ActiveDeadlineSeconds: &deadline}
+	r, c := newInitJobTestReconciler(site, bench)
+	recorder := record.NewFakeRecorder(20)
+	r.Recorder = recorder
+	ctx := context.Background()
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: batchv1.JobReasonDeadlineExceeded}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+
+	_, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds)
+	if !isSiteInitTimeout(err) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "SiteInitializationTimeout") {
+		t.Errorf("expected SiteInitializationTimeout event, got %q", event)
+	}
+}
+
+func TestEnsureSiteInitialized_JobTTL(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	ttl := int32(120)
+	bench.Spec.JobTTLSecondsAfterFinished = &ttl
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	key := types.NamespacedName{Name: "site-init", Namespace: "default"}
+
+	if _, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, key, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Spec.TTLSecondsAfterFinished == nil || *job.Spec.TTLSecondsAfterFinished != ttl {
+		t.Errorf("expected TTL %d, got %v", ttl, job.Spec.TTLSecondsAfterFinished)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if done, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil || !done {
+		t.Fatalf("expected the site to be initialized, got %v, %v", done, err)
+	}
+	if !meta.IsStatusConditionTrue(site.Status.Conditions, siteInitializedCondition) {
+		t.Errorf("expected the %s condition, got %+v", siteInitializedCondition, site.Status.Conditions)
+	}
+
+	// The TTL controller removes the finished Job; the site must not be initialized again
+	if err := c.Delete(ctx, job); err != nil {
+		t.Fatalf("Delete Job: %v", err)
+	}
+	if done, err := r.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil || !done {
+		t.Fatalf("expected the site to stay initialized, got %v, %v", done, err)
+	}
+	if err := c.Get(ctx, key, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("expected no new init Job, got %v", err)
+	}
+}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
@@ -27,6 +28,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -34,6 +36,99 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+const (
+	// initPreambleMountPath is where the spec.initScriptPreamble ConfigMap is mounted in the site-init job
+	initPreambleMountPath = "/tmp/site-preamble"
+	// initPreambleFileName is the file the referenced ConfigMap key is projected to
+	initPreambleFileName = "preamble.sh"
+	// siteHealthCheckTimeoutSeconds bounds how long spec.publishWhenHealthy waits for the site to respond
+	siteHealthCheckTimeoutSeconds = 600
+	// defaultInitJobBackoffLimit is how often a failed init job is retried unless spec.initJob says otherwise
+	defaultInitJobBackoffLimit int32 = 2
+	// siteJobFailureLogTailLines is how much of a failed site init or deletion pod's log is read
+	siteJobFailureLogTailLines = 50
+)
+
+// errSiteInitTimeout marks an init job stopped by spec.initJob.activeDeadlineSeconds
+var errSiteInitTimeout = stderrors.New("site initialization timed out")
+
+// isSiteInitTimeout reports whether err comes from an init job that exceeded its deadline
+func isSiteInitTimeout(err error) bool {
+	return stderrors.Is(err, errSiteInitTimeout)
+}
+
+// initJobLimits returns the backoff limit and deadline (nil for none) of the site's init job
+func initJobLimits(site *vyogotechv1alpha1.FrappeSite) (int32, *int64) {
+	backoffLimit := defaultInitJobBackoffLimit
+	if site.Spec.InitJob == nil {
+		return backoffLimit, nil
+	}
+	if site.Spec.InitJob.BackoffLimit != nil {
+		backoffLimit = *site.Spec.InitJob.BackoffLimit
+	}
+	return backoffLimit, site.Spec.InitJob.ActiveDeadlineSeconds
+}
+
+// siteInitEnv converts spec.env into environment variables for the init container
+func siteInitEnv(site *vyogotechv1alpha1.FrappeSite) []corev1.EnvVar {
+	env := make([]corev1.EnvVar, 0, len(site.Spec.Env))
+	for _, e := range site.Spec.Env {
+		if e.SecretKeyRef != nil {
+			env = append(env, corev1.EnvVar{
+				Name:      e.Name,
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: e.SecretKeyRef.DeepCopy()},
+			})
+			continue
+		}
+		env = append(env, corev1.EnvVar{Name: e.Name, Value: e.Value})
+	}
+	return env
+}
+
+// countSiteInitFailure counts a failed init job in JobFailures unless the site already
+// failed with reason, i.e. an earlier reconcile counted the same job
+func countSiteInitFailure(site *vyogotechv1alpha1.FrappeSite, reason string) {
+	ready := meta.FindStatusCondition(site.Status.Conditions, "Ready")
+	if ready != nil && ready.Status == metav1.ConditionFalse && ready.Reason == reason {
+		return
+	}
+	JobFailures.WithLabelValues(jobFailureSiteInit).Inc()
+}
+
+// initJobFailed reports whether the init job gave up: it is marked Failed or used up its
+// retries. Pods failing while retries are left don't count.
+func initJobFailed(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	if job.Spec.BackoffLimit == nil {
+		return job.Status.Failed > 0
+	}
+	return job.Status.Failed > *job.Spec.BackoffLimit
+}
+
+// jobDeadlineExceeded reports whether the job was stopped by its activeDeadlineSeconds
+func jobDeadlineExceeded(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue && cond.Reason == batchv1.JobReasonDeadlineExceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// siteInitializedCondition is set once the site's init job succeeded, so the site is
+// not initialized again after the job has been garbage collected
+const siteInitializedCondition = "Initialized"
+
+// siteInitialized reports whether the site's init job succeeded at some point. Sites
+// that were Ready before the condition existed have a site URL instead.
+func siteInitialized(site *vyogotechv1alpha1.FrappeSite) bool {
+	return meta.IsStatusConditionTrue(site.Status.Conditions, siteInitializedCondition) || site.Status.SiteURL != ""
+}
+
 // ensureSiteInitialized creates a Job to run bench new-site
 func (r *FrappeSiteReconciler) ensureSiteInitialized(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, domain string, dbInfo *database.DatabaseInfo, dbCreds *database.DatabaseCredentials) (bool, error) {
 	logger := log.FromContext(ctx)
@@ -46,56 +141,70 @@ func (r *FrappeSiteReconciler) ensureSiteInitialized(ctx context.Context, site *
 		// Job exists, check if it completed
 		if job.Status.Succeeded > 0 {
 			logger.Info("Site initialization job completed successfully", "job", jobName)
-
-			// Update status with requested apps
-			if len(site.Spec.Apps) > 0 {
-				site.Status.InstalledApps = site.Spec.Apps
-				site.Status.AppInstallationStatus = fmt.Sprintf("Completed app installation for %d requested app(s) - check logs for any skipped apps", len(site.Spec.Apps))
-				logger.Info("App installation process completed", "requestedApps", site.Spec.Apps)
-				r.Recorder.Event(site, corev1.EventTypeNormal, "AppsProcessed",
-					fmt.Sprintf("Processed app installation for: %v - check job logs for any skipped apps", site.Spec.Apps))
-			} else {
-				site.Status.AppInstallationStatus = "No apps specified - only frappe framework installed"
-				logger.Info("Site initialized with frappe framework only")
+			r.setCondition(site, metav1.Condition{
+				Type:    siteInitializedCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  "InitJobSucceeded",
+				Message: "Site initialization job completed successfully",
+			})
+			site.Status.InitFailureLog = ""
+			site.Status.InitFailureSummary = ""
+
+			// Update status with the installed apps; once recorded, ensureSiteAppsUninstalled keeps it current
+			if site.Status.InstalledApps != nil || site.Status.SkippedApps != nil {
+				return true, nil
 			}
-
+			r.recordInstalledApps(ctx, site, job)
 			return true, nil
 		}
 
-		if job.Status.Failed > 0 {
+		if jobDeadlineExceeded(job) {
+			logger.Error(nil, "Site initialization job exceeded its deadline", "job", jobName)
+			countSiteInitFailure(site, "InitTimeout")
+			deadline := int64(0)
+			if job.Spec.ActiveDeadlineSeconds != nil {
+				deadline = *job.Spec.ActiveDeadlineSeconds
+			}
+			r.Recorder.Event(site, corev1.EventTypeWarning, "SiteInitializationTimeout",
+				fmt.Sprintf("Site initialization job did not finish within %ds", deadline))
+			return false, fmt.Errorf("%w: job %s did not finish within %ds", errSiteInitTimeout, jobName, deadline)
+		}
+
+		if initJobFailed(job) {
 			logger.Error(nil, "Site initialization job failed", "job", jobName, "failedCount", job.Status.Failed)
+			countSiteInitFailure(site, "SiteInitializationFailed")
 			r.Recorder.Event(site, corev1.EventTypeWarning, "SiteInitializationFailed",
 				fmt.Sprintf("Site initialization job failed after %d attempt(s)", job.Status.Failed))
 
-			// Try to get pod logs for error details
-			podList := &corev1.PodList{}
-			listOpts := []client.ListOption{
-				client.InNamespace(site.Namespace),
-				client.MatchingLabels{"job-name": jobName},
+			failure, err := readJobFailure(ctx, r.Client, r.LogReader, job, "", siteJobFailureLogTailLines)
+			if err != nil {
+				// The log is best effort; the failure itself is still recorded
+				logger.Error(err, "Failed to read site initialization job log", "job", jobName)
 			}
-			if err := r.List(ctx, podList, listOpts...); err == nil && len(podList.Items) > 0 {
-				// Check the most recent pod for error messages
-				pod := podList.Items[len(podList.Items)-1]
-				if pod.Status.Phase == corev1.PodFailed {
-					logger.Error(nil, "Site initialization pod failed",
-						"pod", pod.Name,
-						"phase", pod.Status.Phase,
-						"reason", pod.Status.Reason,
-						"message", pod.Status.Message)
-
-					// Update status with failure information
-					if len(site.Spec.Apps) > 0 {
-						site.Status.AppInstallationStatus = fmt.Sprintf("Failed to install apps: %s", pod.Status.Message)
-						r.Recorder.Event(site, corev1.EventTypeWarning, "AppInstallationFailed",
-							fmt.Sprintf("Failed to install apps. Check pod %s logs for details", pod.Name))
-					}
+			site.Status.InitFailureLog = failure.LogTail
+			site.Status.InitFailureSummary = failure.Summary
+			if failure.Pod != "" {
+				logger.Error(nil, "Site initialization pod failed", "pod", failure.Pod, "summary", failure.Summary)
+				if len(site.Spec.Apps) > 0 {
+					site.Status.AppInstallationStatus = fmt.Sprintf("Failed to install apps: %s", failure.Summary)
+					r.Recorder.Event(site, corev1.EventTypeWarning, "AppInstallationFailed",
+						fmt.Sprintf("Failed to install apps. Check pod %s logs for details", failure.Pod))
 				}
 			}
 
+			if failure.Summary != "" {
+				return false, fmt.Errorf("site initialization job failed: %s", failure.Summary)
+			}
 			return false, fmt.Errorf("site initialization job failed")
 		}
 		// Job is still running
 		logger.Info("Site initialization job in progress", "job", jobName)
+		if waiting, err := r.waitingForDatabase(ctx, site, jobName); err != nil {
+			logger.Error(err, "Failed to list site initialization pods", "job", jobName)
+		} else if waiting {
+			r.Recorder.Event(site, corev1.EventTypeNormal, "WaitingForDatabase",
+				fmt.Sprintf("Site initialization job %s is waiting for the database to accept connections", jobName))
+		}
 		if len(site.Spec.Apps) > 0 {
 			site.Status.AppInstallationStatus = fmt.Sprintf("Installing %d app(s)...", len(site.Spec.Apps))
 		}
@@ -105,6 +214,10 @@ func (r *FrappeSiteReconciler) ensureSiteInitialized(ctx context.Context, site *
 	if !errors.IsNotFound(err) {
 		return false, err
 	}
+	if siteInitialized(site) {
+		// The succeeded init job was removed after its TTL
+		return true, nil
+	}
 
 	// Create the initialization job
 	logger.Info("Creating site initialization job",
@@ -115,14 +228,6 @@ func (r *FrappeSiteReconciler) ensureSiteInitialized(ctx context.Context, site *
 		"apps", site.Spec.Apps,
 		"appsCount", len(site.Spec.Apps))
 
-	if len(site.Spec.Apps) > 0 {
-		r.Recorder.Event(site, corev1.EventTypeNormal, "CreatingInitJob",
-			fmt.Sprintf("Creating initialization job to install %d app(s): %v", len(site.Spec.Apps), site.Spec.Apps))
-	} else {
-		r.Recorder.Event(site, corev1.EventTypeNormal, "CreatingInitJob",
-			"Creating initialization job (frappe framework only)")
-	}
-
 	// Get or generate admin password
 	adminPassword, err := r.ensureAdminPassword(ctx, site)
 	if err != nil {
@@ -135,6 +240,16 @@ func (r *FrappeSiteReconciler) ensureSiteInitialized(ctx context.Context, site *
 		return false, fmt.Errorf("failed to create init secret: %w", err)
 	}
 
+	// Make sure the optional preamble ConfigMap is in place before the job references it
+	if err := r.validateInitScriptPreamble(ctx, site); err != nil {
+		return false, err
+	}
+
+	// The webhook rejects these too, but it may not be deployed
+	if err := vyogotechv1alpha1.ValidateSiteEnv(site.Spec.Env); err != nil {
+		return false, err
+	}
+
 	// Load site init script from pkg/scripts
 	initScript, err := scripts.GetScript(scripts.SiteInit)
 	if err != nil {
@@ -142,34 +257,179 @@ func (r *FrappeSiteReconciler) ensureSiteInitialized(ctx context.Context, site *
 	}
 
 	// Apply Pod Config from Site Spec (init jobs use site config)
-	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(site.Spec.PodConfig, map[string]string{
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
 		"app":  "frappe",
 		"site": site.Name,
-	})
+	}))
 
 	// Get bench PVC name
 	pvcName := fmt.Sprintf("%s-sites", bench.Name)
 
 	// Build the container
-	container := resources.NewContainerBuilder("site-init", r.getBenchImage(ctx, bench)).
+	containerBuilder := resources.NewContainerBuilder("site-init", r.getBenchImage(ctx, bench)).
 		WithCommand("bash", "-c").
 		WithArgs(initScript).
-		WithVolumeMount("sites", "/home/frappe/frappe-bench/sites").
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
 		WithVolumeMount("site-secrets", "/tmp/site-secrets").
 		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
-		Build()
+		WithResources(siteJobResources(site.Spec.SizeHint)).
+		WithEnv("SHARED_ASSETS", sharedAssetsEnvValue(bench))
+	if site.Spec.InitScriptPreamble != nil {
+		containerBuilder = containerBuilder.WithVolumeMountReadOnly("site-preamble", initPreambleMountPath)
+	}
+	for _, env := range siteInitEnv(site) {
+		containerBuilder = containerBuilder.WithEnvFrom(env)
+	}
+	container := containerBuilder.Build()
+	dbWaitContainer, err := r.dbWaitInitContainer(ctx, site, bench, dbInfo)
+	if err != nil {
+		return false, err
+	}
 
 	// Build the job
-	job = resources.NewJobBuilder(jobName, site.Namespace).
+	backoffLimit, activeDeadline := initJobLimits(site)
+	// A missing ConfigMap means the default TTL
+	operatorConfig, _ := r.getOperatorConfig(ctx, site.Namespace)
+	jobBuilder := resources.NewJobBuilder(jobName, site.Namespace).
+		WithTTL(lifecycleJobTTL(bench, operatorConfig)).
 		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationInit, bench.Name, site.Spec.SiteName)).
 		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(backoffLimit).
 		WithNodeSelector(nodeSelector).
 		WithAffinity(affinity).
 		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
 		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
 		WithContainer(container).
 		WithPVCVolume("sites", pvcName).
 		WithSecretVolume("site-secrets", fmt.Sprintf("%s-init-secrets", site.Name), resources.Int32Ptr(0444)).
+		WithOwner(site, r.Scheme)
+	if preamble := site.Spec.InitScriptPreamble; preamble != nil {
+		jobBuilder = jobBuilder.WithVolume(corev1.Volume{
+			Name: "site-preamble",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: preamble.LocalObjectReference,
+					Items: []corev1.KeyToPath{
+						{Key: preamble.Key, Path: initPreambleFileName},
+					},
+					DefaultMode: resources.Int32Ptr(0444),
+					Optional:    preamble.Optional,
+				},
+			},
+		})
+	}
+	if dbWaitContainer != nil {
+		jobBuilder = jobBuilder.WithInitContainer(*dbWaitContainer)
+	}
+	if activeDeadline != nil {
+		jobBuilder = jobBuilder.WithActiveDeadline(*activeDeadline)
+	}
+	job = jobBuilder.MustBuild()
+	syncExtraVolumes(&job.Spec.Template, bench)
+
+	// Only one site-init Job at a time may mount a sites PVC that isn't ReadWriteMany
+	serialize, err := r.serializeSiteInits(ctx, bench)
+	if err != nil {
+		return false, err
+	}
+	if serialize {
+		// The claim can only be attached to the node the bench pods already run on
+		node, nodeErr := r.pvcNode(ctx, site.Namespace, pvcName)
+		if nodeErr != nil {
+			return false, nodeErr
+		}
+		if node != "" {
+			pinToNode(&job.Spec.Template.Spec, node)
+		}
+		err = r.siteInits.create(ctx, r.Client, job, bench.Name)
+	} else {
+		err = r.Create(ctx, job)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if len(site.Spec.Apps) > 0 {
+		r.Recorder.Event(site, corev1.EventTypeNormal, "CreatingInitJob",
+			fmt.Sprintf("Creating initialization job to install %d app(s): %v", len(site.Spec.Apps), site.Spec.Apps))
+	} else {
+		r.Recorder.Event(site, corev1.EventTypeNormal, "CreatingInitJob",
+			"Creating initialization job (frappe framework only)")
+	}
+
+	logger.Info("Site initialization job created", "job", jobName)
+	return false, nil // Not ready yet, job is running
+}
+
+// ensureSiteHealthy runs a one-shot Job that polls the site through the bench nginx.
+// Returns true once the job succeeds and an error if it failed or timed out; a failed
+// job is deleted so the next reconcile checks again.
+func (r *FrappeSiteReconciler) ensureSiteHealthy(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, domain string) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	jobName := fmt.Sprintf("%s-health-check", site.Name)
+	job := &batchv1.Job{}
+
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: site.Namespace}, job)
+	if err == nil {
+		if job.Status.Succeeded > 0 {
+			return true, nil
+		}
+		if job.Status.Failed > 0 {
+			r.Recorder.Event(site, corev1.EventTypeWarning, "HealthCheckFailed",
+				fmt.Sprintf("Site %s did not respond through nginx within %ds", domain, siteHealthCheckTimeoutSeconds))
+			// Remove the failed job so the retry of the returned error runs a fresh check
+			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				return false, err
+			}
+			return false, fmt.Errorf("site health check job failed")
+		}
+		logger.Info("Site health check job in progress", "job", jobName)
+		return false, nil
+	}
+
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	healthScript, err := scripts.RenderScript(scripts.SiteHealthCheck, scripts.SiteHealthCheckData{
+		NginxAddress: fmt.Sprintf("%s-nginx:8080", bench.Name),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to render site health check script: %w", err)
+	}
+
+	// Apply Pod Config from Site Spec
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
+		"app":  "frappe",
+		"site": site.Name,
+	}))
+
+	container := resources.NewContainerBuilder("health-check", r.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(healthScript).
+		WithEnv("DOMAIN", domain).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		Build()
+
+	job = resources.NewJobBuilder(jobName, site.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationHealthCheck, bench.Name, site.Spec.SiteName)).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(0).
+		WithActiveDeadline(siteHealthCheckTimeoutSeconds).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
 		WithOwner(site, r.Scheme).
 		MustBuild()
 
@@ -177,32 +437,109 @@ func (r *FrappeSiteReconciler) ensureSiteInitialized(ctx context.Context, site *
 		return false, err
 	}
 
-	logger.Info("Site initialization job created", "job", jobName)
-	return false, nil // Not ready yet, job is running
+	logger.Info("Site health check job created", "job", jobName, "domain", domain)
+	return false, nil
+}
+
+// validateInitScriptPreamble checks that the ConfigMap key referenced by
+// spec.initScriptPreamble exists so the init job doesn't get stuck on a missing mount
+func (r *FrappeSiteReconciler) validateInitScriptPreamble(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) error {
+	preamble := site.Spec.InitScriptPreamble
+	if preamble == nil {
+		return nil
+	}
+	if preamble.Name == "" || preamble.Key == "" {
+		return fmt.Errorf("initScriptPreamble requires both name and key")
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: preamble.Name, Namespace: site.Namespace}, cm); err != nil {
+		if errors.IsNotFound(err) && preamble.Optional != nil && *preamble.Optional {
+			return nil
+		}
+		return fmt.Errorf("failed to get init script preamble ConfigMap %s: %w", preamble.Name, err)
+	}
+	if _, ok := cm.Data[preamble.Key]; !ok {
+		if preamble.Optional != nil && *preamble.Optional {
+			return nil
+		}
+		return fmt.Errorf("init script preamble ConfigMap %s has no key %q", preamble.Name, preamble.Key)
+	}
+	return nil
+}
+
+// deleteSiteSecrets removes the credential Secrets of a deleted site before its finalizer
+// goes: `<site>-deletion-secret` holds the database root password and `<site>-init-secrets`
+// the admin and database passwords, so they aren't left to garbage collection
+func (r *FrappeSiteReconciler) deleteSiteSecrets(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) error {
+	for _, name := range []string{fmt.Sprintf("%s-deletion-secret", site.Name), fmt.Sprintf("%s-init-secrets", site.Name)} {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: site.Namespace}}
+		if err := r.Delete(ctx, secret); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete secret %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// cleanupSiteDatabase removes what the database provider created for the site outside
+// its owner references, e.g. the PostgreSQL role and Database CR. A site whose database
+// is kept on delete keeps them too.
+func (r *FrappeSiteReconciler) cleanupSiteDatabase(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) error {
+	dbConfig := r.resolveDBConfig(site, bench)
+	if dbConfig.SkipDropOnDelete {
+		return nil
+	}
+	dbProvider, err := database.NewProvider(dbConfig, r.Client, r.Scheme)
+	if err != nil {
+		return err
+	}
+	dbProvider = withProviderLookupTimeout(dbProvider, r.LookupTimeout)
+	resolved := site.DeepCopy()
+	resolved.Spec.DBConfig = dbConfig
+	if err := dbProvider.Cleanup(ctx, resolved); err != nil {
+		return fmt.Errorf("failed to clean up site database resources: %w", err)
+	}
+	return nil
+}
+
+// skipDBDropEnvValue tells site_delete.sh whether to archive the site directory without
+// dropping its database
+func skipDBDropEnvValue(skip bool) string {
+	if skip {
+		return "1"
+	}
+	return "0"
 }
 
 // deleteSite implements the site deletion logic
 func (r *FrappeSiteReconciler) deleteSite(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) error {
 	logger := log.FromContext(ctx)
 
+	// A site referencing a bench in another namespace was never created on it
+	if site.ValidateBenchNamespace() != nil {
+		logger.Info("Referenced bench is in another namespace, skipping site deletion job")
+		return r.deleteSiteSecrets(ctx, site)
+	}
+
 	// Get the referenced bench
 	bench := &vyogotechv1alpha1.FrappeBench{}
-	benchKey := types.NamespacedName{
-		Name:      site.Spec.BenchRef.Name,
-		Namespace: site.Spec.BenchRef.Namespace,
-	}
-	if benchKey.Namespace == "" {
-		benchKey.Namespace = site.Namespace
-	}
+	benchKey := siteBenchKey(site)
 
 	if err := r.Get(ctx, benchKey, bench); err != nil {
 		if errors.IsNotFound(err) {
 			logger.Info("Referenced bench not found, assuming it's already deleted")
-			return nil
+			return r.deleteSiteSecrets(ctx, site)
 		}
 		return fmt.Errorf("failed to get referenced bench for deletion: %w", err)
 	}
 
+	// Back the site up first when asked to; drop-site can't be undone
+	if site.Spec.BackupBeforeDelete {
+		if err := r.ensurePreDeleteBackup(ctx, site, bench); err != nil {
+			return err
+		}
+	}
+
 	// Create deletion job to run bench drop-site
 	jobName := fmt.Sprintf("%s-delete", site.Name)
 	job := &batchv1.Job{}
@@ -216,14 +553,19 @@ func (r *FrappeSiteReconciler) deleteSite(ctx context.Context, site *vyogotechv1
 		// Job doesn't exist, create it
 		logger.Info("Creating site deletion job", "job", jobName)
 
-		// Get MariaDB root credentials for deletion
-		rootUser, rootPassword, err := r.getMariaDBRootCredentials(ctx, site)
-		if err != nil {
-			if errors.IsNotFound(err) {
-				logger.Info("MariaDB instance not found, skipping site deletion job")
-				return nil
+		// Get database root credentials for deletion, unless the database is to be kept
+		var rootUser, rootPassword string
+		skipDBDrop := r.resolveDBConfig(site, bench).SkipDropOnDelete
+		if !skipDBDrop {
+			var dbKind string
+			rootUser, rootPassword, dbKind, err = r.getDBRootCredentials(ctx, site, bench)
+			if err != nil {
+				if errors.IsNotFound(err) {
+					logger.Info(dbKind + " instance not found, skipping site deletion job")
+					return r.deleteSiteSecrets(ctx, site)
+				}
+				return fmt.Errorf("failed to get %s root credentials: %w", dbKind, err)
 			}
-			return fmt.Errorf("failed to get MariaDB root credentials: %w", err)
 		}
 
 		// Create deletion secret with root credentials
@@ -270,28 +612,35 @@ func (r *FrappeSiteReconciler) deleteSite(ctx context.Context, site *vyogotechv1
 		}
 
 		// Apply Pod Config from Site Spec
-		nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(site.Spec.PodConfig, map[string]string{
+		nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
 			"app":  "frappe",
 			"site": site.Name,
-		})
+		}))
 
 		// Build the container
 		container := resources.NewContainerBuilder("site-delete", r.getBenchImage(ctx, bench)).
 			WithCommand("bash", "-c").
 			WithArgs(deleteScript).
-			WithVolumeMount("sites", "/home/frappe/frappe-bench/sites").
+			WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
 			WithVolumeMountReadOnly("deletion-secret", "/tmp/secrets").
+			WithEnv("SKIP_DB_DROP", skipDBDropEnvValue(skipDBDrop)).
 			WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
 			Build()
 
 		// Build the job
+		operatorConfig, _ := r.getOperatorConfig(ctx, site.Namespace)
 		job = resources.NewJobBuilder(jobName, site.Namespace).
+			WithTTL(lifecycleJobTTL(bench, operatorConfig)).
 			WithLabels(extraLabels).
+			WithLabels(jobLabels(jobOperationDelete, bench.Name, site.Spec.SiteName)).
 			WithExtraPodLabels(extraLabels).
 			WithNodeSelector(nodeSelector).
 			WithAffinity(affinity).
 			WithTolerations(tolerations).
+			WithPodAnnotations(jobPodAnnotations(bench)).
 			WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+			WithImagePullSecrets(imagePullSecrets(bench)).
+			WithServiceAccountName(jobServiceAccount(bench)).
 			WithContainer(container).
 			WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
 			WithSecretVolume("deletion-secret", deletionSecretName, resources.Int32Ptr(0400)).
@@ -308,6 +657,12 @@ func (r *FrappeSiteReconciler) deleteSite(ctx context.Context, site *vyogotechv1
 	// Job exists, check its status
 	if job.Status.Succeeded > 0 {
 		logger.Info("Site deletion job completed successfully")
+		if err := r.cleanupSiteDatabase(ctx, site, bench); err != nil {
+			return err
+		}
+		if err := r.deleteSiteSecrets(ctx, site); err != nil {
+			return err
+		}
 		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
 			return fmt.Errorf("failed to delete completed deletion job: %w", err)
 		}
@@ -315,6 +670,13 @@ func (r *FrappeSiteReconciler) deleteSite(ctx context.Context, site *vyogotechv1
 	}
 
 	if job.Status.Failed > 0 {
+		failure, err := readJobFailure(ctx, r.Client, r.LogReader, job, "", siteJobFailureLogTailLines)
+		if err != nil {
+			logger.Error(err, "Failed to read site deletion job log", "job", job.Name)
+		}
+		if failure.Summary != "" {
+			return fmt.Errorf("site deletion job failed: %s", failure.Summary)
+		}
 		return fmt.Errorf("site deletion job failed")
 	}
 
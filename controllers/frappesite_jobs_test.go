@@ -23,6 +23,7 @@ import (
 	. "github.com/onsi/gomega"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -129,6 +130,19 @@ var _ = Describe("FrappeSite Jobs", func() {
 
 			job := &batchv1.Job{}
 			Expect(fakeClient.Get(ctx, types.NamespacedName{Name: site.Name + "-delete", Namespace: site.Namespace}, job)).To(Succeed())
+
+			deletionSecretKey := types.NamespacedName{Name: site.Name + "-deletion-secret", Namespace: site.Namespace}
+			Expect(fakeClient.Get(ctx, deletionSecretKey, &corev1.Secret{})).To(Succeed())
+			initSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: site.Name + "-init-secrets", Namespace: site.Namespace}}
+			Expect(fakeClient.Create(ctx, initSecret)).To(Succeed())
+
+			// Once drop-site succeeded the root credentials are removed explicitly
+			job.Status.Succeeded = 1
+			Expect(fakeClient.Status().Update(ctx, job)).To(Succeed())
+			Expect(reconciler.deleteSite(ctx, site)).To(Succeed())
+
+			Expect(errors.IsNotFound(fakeClient.Get(ctx, deletionSecretKey, &corev1.Secret{}))).To(BeTrue())
+			Expect(errors.IsNotFound(fakeClient.Get(ctx, types.NamespacedName{Name: initSecret.Name, Namespace: site.Namespace}, &corev1.Secret{}))).To(BeTrue())
 		})
 	})
 
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// maintenanceModeCondition reports whether the site is in Frappe maintenance mode
+	maintenanceModeCondition = "MaintenanceMode"
+
+	// maintenanceModeAnnotation records on the maintenance mode job which mode it sets
+	maintenanceModeAnnotation = "frappe.tech/maintenance-mode"
+)
+
+// maintenanceModeApplied reports whether the site was last put into maintenance mode.
+// Sites without the condition were never switched and are not in maintenance mode.
+func maintenanceModeApplied(site *vyogotechv1alpha1.FrappeSite) bool {
+	return meta.IsStatusConditionTrue(site.Status.Conditions, maintenanceModeCondition)
+}
+
+// maintenanceModeValue returns the set-maintenance-mode argument for a mode
+func maintenanceModeValue(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// ensureSiteMaintenanceMode switches Frappe maintenance mode to match spec.maintenanceMode.
+// The applied mode is kept in the MaintenanceMode condition, so a job only runs when the
+// spec and the condition disagree. Returns true once they agree.
+func (r *FrappeSiteReconciler) ensureSiteMaintenanceMode(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	desired := site.Spec.MaintenanceMode
+	if maintenanceModeApplied(site) == desired {
+		return true, nil
+	}
+	mode := maintenanceModeValue(desired)
+	jobName := fmt.Sprintf("%s-maintenance-mode", site.Name)
+	job, err := runSiteJob(ctx, r.Client, site, jobName, siteJobKey{annotation: maintenanceModeAnnotation, value: mode}, func() (*batchv1.Job, error) {
+		return r.buildMaintenanceModeJob(ctx, site, bench, jobName, mode)
+	})
+	if job == nil || err != nil {
+		return false, err
+	}
+
+	if desired {
+		r.setCondition(site, metav1.Condition{
+			Type:    maintenanceModeCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "MaintenanceModeOn",
+			Message: fmt.Sprintf("%s serves the maintenance page", site.Spec.SiteName),
+		})
+		r.Recorder.Event(site, corev1.EventTypeNormal, "MaintenanceModeOn", "Maintenance mode turned on")
+	} else {
+		r.setCondition(site, metav1.Condition{
+			Type:    maintenanceModeCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "MaintenanceModeOff",
+			Message: "Site is serving users",
+		})
+		r.Recorder.Event(site, corev1.EventTypeNormal, "MaintenanceModeOff", "Maintenance mode turned off")
+	}
+	return true, nil
+}
+
+// buildMaintenanceModeJob returns the job that turns maintenance mode on the site on or off
+func (r *FrappeSiteReconciler) buildMaintenanceModeJob(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, jobName, mode string) (*batchv1.Job, error) {
+	maintenanceScript, err := scripts.RenderScript(scripts.SiteMaintenanceMode, scripts.SiteMaintenanceModeData{
+		SiteName: site.Spec.SiteName,
+		Mode:     mode,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render maintenance mode script: %w", err)
+	}
+
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
+		"app":  "frappe",
+		"site": site.Name,
+	}))
+
+	container := resources.NewContainerBuilder("maintenance-mode", r.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(maintenanceScript).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		Build()
+
+	return resources.NewJobBuilder(jobName, site.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationMaintenance, bench.Name, site.Spec.SiteName)).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(2).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(site, r.Scheme).
+		Build()
+}
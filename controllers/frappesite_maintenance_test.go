@@ -0,0 +1,106 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnsureSiteMaintenanceMode(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "site-maintenance-mode", Namespace: "default"}
+
+	// A new site is not in maintenance mode; nothing to do
+	if applied, err := r.ensureSiteMaintenanceMode(ctx, site, bench); err != nil || !applied {
+		t.Fatalf("expected no-op for a site out of maintenance mode, got applied=%v err=%v", applied, err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); err == nil {
+		t.Fatal("expected no maintenance mode job")
+	}
+
+	site.Spec.MaintenanceMode = true
+	applied, err := r.ensureSiteMaintenanceMode(ctx, site, bench)
+	if err != nil || applied {
+		t.Fatalf("expected maintenance mode job to be started, got applied=%v err=%v", applied, err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("expected maintenance mode job: %v", err)
+	}
+	if job.Annotations[maintenanceModeAnnotation] != "on" {
+		t.Errorf("unexpected mode annotation %q", job.Annotations[maintenanceModeAnnotation])
+	}
+	if script := job.Spec.Template.Spec.Containers[0].Args[0]; !strings.Contains(script, "bench --site site.local set-maintenance-mode on") {
+		t.Error("expected job to turn maintenance mode on")
+	}
+
+	// Still running: no second job, not applied
+	if applied, err := r.ensureSiteMaintenanceMode(ctx, site, bench); err != nil || applied {
+		t.Fatalf("expected job to be awaited, got applied=%v err=%v", applied, err)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	if applied, err := r.ensureSiteMaintenanceMode(ctx, site, bench); err != nil || !applied {
+		t.Fatalf("expected maintenance mode to be applied, got applied=%v err=%v", applied, err)
+	}
+	if !meta.IsStatusConditionTrue(site.Status.Conditions, maintenanceModeCondition) {
+		t.Errorf("expected MaintenanceMode condition true, got %+v", site.Status.Conditions)
+	}
+	// Applied: later reconciles don't touch the job
+	if applied, err := r.ensureSiteMaintenanceMode(ctx, site, bench); err != nil || !applied {
+		t.Fatalf("expected no-op once applied, got applied=%v err=%v", applied, err)
+	}
+
+	// Turning it off replaces the finished job with one that switches maintenance mode off
+	site.Spec.MaintenanceMode = false
+	if applied, err := r.ensureSiteMaintenanceMode(ctx, site, bench); err != nil || applied {
+		t.Fatalf("expected stale job to be replaced, got applied=%v err=%v", applied, err)
+	}
+	if err := c.Get(ctx, jobKey, job); err == nil {
+		t.Error("expected stale maintenance mode job to be deleted")
+	}
+	if _, err := r.ensureSiteMaintenanceMode(ctx, site, bench); err != nil {
+		t.Fatalf("ensureSiteMaintenanceMode: %v", err)
+	}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("expected new maintenance mode job: %v", err)
+	}
+	if job.Annotations[maintenanceModeAnnotation] != "off" {
+		t.Errorf("expected job turning maintenance mode off, got %q", job.Annotations[maintenanceModeAnnotation])
+	}
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	if applied, err := r.ensureSiteMaintenanceMode(ctx, site, bench); err != nil || !applied {
+		t.Fatalf("expected maintenance mode off to be applied, got applied=%v err=%v", applied, err)
+	}
+	if maintenanceModeApplied(site) {
+		t.Error("expected MaintenanceMode condition false")
+	}
+}
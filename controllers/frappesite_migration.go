@@ -0,0 +1,110 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// siteMigratedCondition is True once bench migrate ran on the site after initialization
+const siteMigratedCondition = "Migrated"
+
+// ensureSiteMigrated runs bench --site <site> migrate once after initialization when
+// spec.runMigrateOnInit is set, so the site is only marked Ready after its apps
+// finished migrating. Returns true once the Migrated condition is True.
+func (r *FrappeSiteReconciler) ensureSiteMigrated(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	if !site.Spec.RunMigrateOnInit || meta.IsStatusConditionTrue(site.Status.Conditions, siteMigratedCondition) {
+		return true, nil
+	}
+	jobName := fmt.Sprintf("%s-migrate", site.Name)
+	job, err := runSiteJob(ctx, r.Client, site, jobName, siteJobKey{}, func() (*batchv1.Job, error) {
+		r.setCondition(site, metav1.Condition{
+			Type:    siteMigratedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "MigrationRunning",
+			Message: fmt.Sprintf("Running bench migrate on %s", site.Spec.SiteName),
+		})
+		r.Recorder.Event(site, corev1.EventTypeNormal, "SiteMigrationStarted", fmt.Sprintf("Running bench migrate on %s", site.Spec.SiteName))
+		return r.buildSiteMigrateJob(ctx, site, bench, jobName)
+	})
+	if errors.Is(err, errJobFailed) {
+		r.setCondition(site, metav1.Condition{
+			Type:    siteMigratedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "MigrationFailed",
+			Message: fmt.Sprintf("Migration job %s failed", jobName),
+		})
+		r.Recorder.Event(site, corev1.EventTypeWarning, "SiteMigrationFailed", fmt.Sprintf("Migration job %s failed", jobName))
+	}
+	if job == nil || err != nil {
+		return false, err
+	}
+
+	log.FromContext(ctx).Info("Site migrated", "site", site.Spec.SiteName)
+	r.setCondition(site, metav1.Condition{
+		Type:    siteMigratedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "MigrationSucceeded",
+		Message: fmt.Sprintf("bench migrate finished for %s", site.Spec.SiteName),
+	})
+	r.Recorder.Event(site, corev1.EventTypeNormal, "SiteMigrated", fmt.Sprintf("Migrated %s after initialization", site.Spec.SiteName))
+	return true, nil
+}
+
+// buildSiteMigrateJob returns the job that runs bench migrate on the site
+func (r *FrappeSiteReconciler) buildSiteMigrateJob(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, jobName string) (*batchv1.Job, error) {
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
+		"app":  "frappe",
+		"site": site.Name,
+	}))
+
+	container := resources.NewContainerBuilder("migrate", r.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(fmt.Sprintf("cd /home/frappe/frappe-bench && bench --site %s migrate", site.Spec.SiteName)).
+		WithResources(siteJobResources(site.Spec.SizeHint)).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		WithEnv("USER", "frappe").
+		Build()
+
+	return resources.NewJobBuilder(jobName, site.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationMigrate, bench.Name, site.Spec.SiteName)).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(0).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(site, r.Scheme).
+		Build()
+}
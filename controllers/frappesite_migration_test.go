@@ -0,0 +1,111 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnsureSiteMigrated(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "site-migrate", Namespace: "default"}
+
+	// Without runMigrateOnInit the site is Ready straight after initialization
+	if migrated, err := r.ensureSiteMigrated(ctx, site, bench); err != nil || !migrated {
+		t.Fatalf("expected no-op without runMigrateOnInit, got migrated=%v err=%v", migrated, err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); err == nil {
+		t.Fatal("expected no migration job")
+	}
+
+	site.Spec.RunMigrateOnInit = true
+	if migrated, err := r.ensureSiteMigrated(ctx, site, bench); err != nil || migrated {
+		t.Fatalf("expected migration job to be started, got migrated=%v err=%v", migrated, err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("expected migration job: %v", err)
+	}
+	if script := job.Spec.Template.Spec.Containers[0].Args[0]; !strings.Contains(script, "bench --site site.local migrate") {
+		t.Errorf("expected job to migrate the site, got %q", script)
+	}
+	if cond := meta.FindStatusCondition(site.Status.Conditions, siteMigratedCondition); cond == nil || cond.Reason != "MigrationRunning" {
+		t.Errorf("expected Migrated condition MigrationRunning, got %+v", cond)
+	}
+
+	// Still running: not migrated
+	if migrated, err := r.ensureSiteMigrated(ctx, site, bench); err != nil || migrated {
+		t.Fatalf("expected job to be awaited, got migrated=%v err=%v", migrated, err)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	if migrated, err := r.ensureSiteMigrated(ctx, site, bench); err != nil || !migrated {
+		t.Fatalf("expected site to be migrated, got migrated=%v err=%v", migrated, err)
+	}
+	if !meta.IsStatusConditionTrue(site.Status.Conditions, siteMigratedCondition) {
+		t.Errorf("expected Migrated condition true, got %+v", site.Status.Conditions)
+	}
+
+	// Migrated once: a removed job is not run again
+	if err := c.Delete(ctx, job); err != nil {
+		t.Fatalf("delete job: %v", err)
+	}
+	if migrated, err := r.ensureSiteMigrated(ctx, site, bench); err != nil || !migrated {
+		t.Fatalf("expected no-op once migrated, got migrated=%v err=%v", migrated, err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); err == nil {
+		t.Error("expected no new migration job")
+	}
+}
+
+func TestEnsureSiteMigrated_failed(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.RunMigrateOnInit = true
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+
+	if _, err := r.ensureSiteMigrated(ctx, site, bench); err != nil {
+		t.Fatalf("ensureSiteMigrated: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-migrate", Namespace: "default"}, job); err != nil {
+		t.Fatalf("expected migration job: %v", err)
+	}
+	job.Status.Failed = 1
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	if migrated, err := r.ensureSiteMigrated(ctx, site, bench); err == nil || migrated {
+		t.Fatalf("expected failed migration to be reported, got migrated=%v err=%v", migrated, err)
+	}
+	if cond := meta.FindStatusCondition(site.Status.Conditions, siteMigratedCondition); cond == nil || cond.Reason != "MigrationFailed" {
+		t.Errorf("expected Migrated condition MigrationFailed, got %+v", cond)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// networkingPendingCondition is True while a site's Ingress or Route is held back
+	// because the bench nginx Service has no ready endpoint yet
+	networkingPendingCondition = "NetworkingPending"
+	// networkingPendingRequeue is how often a held back site checks the nginx endpoints again
+	networkingPendingRequeue = 10 * time.Second
+)
+
+// networkingPublished reports whether the site's Ingress or Route was created before; once
+// published it isn't withdrawn when the nginx pods restart. Sites that were Ready before
+// the condition existed have theirs already.
+func networkingPublished(site *vyogotechv1alpha1.FrappeSite) bool {
+	cond := meta.FindStatusCondition(site.Status.Conditions, networkingPendingCondition)
+	if cond == nil {
+		return site.Status.Phase == vyogotechv1alpha1.FrappeSitePhaseReady
+	}
+	return cond.Status == metav1.ConditionFalse
+}
+
+// nginxHasReadyEndpoint reports whether the `<bench>-nginx` Service the Ingress or Route
+// points at has at least one ready endpoint
+func (r *FrappeSiteReconciler) nginxHasReadyEndpoint(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	endpointSlices := &discoveryv1.EndpointSliceList{}
+	if err := r.List(ctx, endpointSlices, client.InNamespace(bench.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: fmt.Sprintf("%s-nginx", bench.Name)}); err != nil {
+		return false, err
+	}
+	for _, slice := range endpointSlices.Items {
+		for _, endpoint := range slice.Endpoints {
+			// A nil ready condition means ready
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_ingressWaitsForNginxEndpoints(t *testing.T) {
+	site, bench, secret := newExternalDBTestObjects()
+	site.Finalizers = []string{frappeSiteFinalizer}
+	bench.Status.Phase = "Ready"
+	initJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-init", Namespace: "default"},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	r, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(site, bench, secret, initJob).WithStatusSubresource(site).Build()
+	r.Client = c
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site", Namespace: "default"}
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if result.RequeueAfter != networkingPendingRequeue {
+		t.Errorf("expected a requeue after %s, got %+v", networkingPendingRequeue, result)
+	}
+	if err := c.Get(ctx, key, site); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if site.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseProvisioning {
+		t.Errorf("expected phase Provisioning, got %s", site.Status.Phase)
+	}
+	if cond := meta.FindStatusCondition(site.Status.Conditions, networkingPendingCondition); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("expected NetworkingPending=True, got %+v", cond)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-ingress", Namespace: "default"}, &networkingv1.Ingress{}); !errors.IsNotFound(err) {
+		t.Errorf("expected no Ingress before nginx is ready, got %v", err)
+	}
+
+	// A slice with only a not-ready endpoint still holds the Ingress back
+	ready := false
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Name: "bench-nginx-abcde", Namespace: "default", Labels: map[string]string{discoveryv1.LabelServiceName: "bench-nginx"}},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}}},
+	}
+	if err := c.Create(ctx, slice); err != nil {
+		t.Fatalf("Create EndpointSlice: %v", err)
+	}
+	if ok, err := r.nginxHasReadyEndpoint(ctx, bench); err != nil || ok {
+		t.Errorf("expected no ready endpoint, got %v, %v", ok, err)
+	}
+
+	ready = true
+	if err := c.Update(ctx, slice); err != nil {
+		t.Fatalf("Update EndpointSlice: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, key, site); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if site.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseReady {
+		t.Errorf("expected phase Ready, got %s", site.Status.Phase)
+	}
+	if cond := meta.FindStatusCondition(site.Status.Conditions, networkingPendingCondition); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Errorf("expected NetworkingPending=False, got %+v", cond)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-ingress", Namespace: "default"}, &networkingv1.Ingress{}); err != nil {
+		t.Errorf("expected the Ingress once nginx is ready, got %v", err)
+	}
+}
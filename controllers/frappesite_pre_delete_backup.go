@@ -0,0 +1,120 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// preDeleteBackupDir is where spec.backupBeforeDelete backups are written, relative to the
+// bench root. drop-site archives the site directory outside the sites volume, so the
+// backups can't stay under sites/<site>/private/backups.
+func preDeleteBackupDir(site *vyogotechv1alpha1.FrappeSite) string {
+	return "sites/pre-delete-backups/" + site.Spec.SiteName
+}
+
+// ensurePreDeleteBackup backs the site up with bench backup --with-files before it is
+// dropped and returns nil once the backup has completed. Like deleteSite it returns an
+// error while the backup job runs, so the deletion is requeued. Sites that were never
+// initialized have nothing to back up.
+func (r *FrappeSiteReconciler) ensurePreDeleteBackup(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) error {
+	if site.Status.PreDeleteBackupPath != "" || !meta.IsStatusConditionTrue(site.Status.Conditions, siteInitializedCondition) {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	jobName := fmt.Sprintf("%s-pre-delete-backup", site.Name)
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: site.Namespace}, job)
+	if err == nil {
+		if job.Status.Succeeded > 0 {
+			site.Status.PreDeleteBackupPath = preDeleteBackupDir(site)
+			logger.Info("Pre-delete backup completed", "path", site.Status.PreDeleteBackupPath)
+			r.Recorder.Event(site, corev1.EventTypeNormal, "PreDeleteBackupCompleted",
+				fmt.Sprintf("Site backed up to %s on the %s-sites volume before deletion", site.Status.PreDeleteBackupPath, bench.Name))
+			return nil
+		}
+		if jobFailed(job) {
+			r.Recorder.Event(site, corev1.EventTypeWarning, "PreDeleteBackupFailed",
+				fmt.Sprintf("Backup job %s failed; delete it to retry, or set spec.backupBeforeDelete to false to delete without a backup", jobName))
+			return fmt.Errorf("pre-delete backup job %s failed", jobName)
+		}
+		return fmt.Errorf("pre-delete backup job is still running")
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get pre-delete backup job: %w", err)
+	}
+
+	// The SiteBackup arguments, with the backup kept where drop-site won't archive it
+	backupReconciler := &SiteBackupReconciler{Client: r.Client, Scheme: r.Scheme}
+	args := append([]string{"bench"}, backupReconciler.buildBackupArgs(&vyogotechv1alpha1.SiteBackup{
+		Spec: vyogotechv1alpha1.SiteBackupSpec{
+			Site:       site.Spec.SiteName,
+			WithFiles:  true,
+			Compress:   true,
+			BackupPath: fmt.Sprintf("%s/pre-delete-backups/%s", sitesMountPath, site.Spec.SiteName),
+		},
+	})...)
+
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
+		"app":  "frappe",
+		"site": site.Name,
+	}))
+
+	container := resources.NewContainerBuilder("backup", r.getBenchImage(ctx, bench)).
+		WithCommand(backupCommand()...).
+		WithArgs(args...).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench)).
+		Build()
+
+	operatorConfig, _ := r.getOperatorConfig(ctx, site.Namespace)
+	job = resources.NewJobBuilder(jobName, site.Namespace).
+		WithTTL(lifecycleJobTTL(bench, operatorConfig)).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationBackup, bench.Name, site.Spec.SiteName)).
+		WithExtraPodLabels(extraLabels).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(site, r.Scheme).
+		MustBuild()
+
+	if err := r.Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to create pre-delete backup job: %w", err)
+	}
+	r.Recorder.Event(site, corev1.EventTypeNormal, "PreDeleteBackupStarted",
+		fmt.Sprintf("Backing the site up to %s before deletion", preDeleteBackupDir(site)))
+
+	return fmt.Errorf("pre-delete backup job created, waiting for completion")
+}
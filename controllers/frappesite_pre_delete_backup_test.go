@@ -0,0 +1,100 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDeleteSite_BackupBeforeDelete(t *testing.T) {
+	site, bench, _ := newExternalDBTestObjects()
+	site.Spec.DBConfig.SkipDropOnDelete = true
+	site.Spec.BackupBeforeDelete = true
+	meta.SetStatusCondition(&site.Status.Conditions, metav1.Condition{Type: siteInitializedCondition, Status: metav1.ConditionTrue, Reason: "JobCompleted"})
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	backupKey := types.NamespacedName{Name: "site-pre-delete-backup", Namespace: "default"}
+	deleteKey := types.NamespacedName{Name: "site-delete", Namespace: "default"}
+
+	err := r.deleteSite(ctx, site)
+	if err == nil || !strings.Contains(err.Error(), "pre-delete backup job created") {
+		t.Fatalf("expected deleteSite to wait for the backup job, got %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, backupKey, job); err != nil {
+		t.Fatalf("Get backup job: %v", err)
+	}
+	args := job.Spec.Template.Spec.Containers[0].Args
+	for _, want := range []string{"--with-files", "--backup-path", sitesMountPath + "/pre-delete-backups/site.local"} {
+		if !slices.Contains(args, want) {
+			t.Errorf("expected %q in the backup args %v", want, args)
+		}
+	}
+	assertSitesMount(t, "pre-delete backup job", job.Spec.Template.Spec)
+
+	// drop-site waits while the backup runs
+	if err := r.deleteSite(ctx, site); err == nil || !strings.Contains(err.Error(), "still running") {
+		t.Fatalf("expected deleteSite to keep waiting, got %v", err)
+	}
+	if err := c.Get(ctx, deleteKey, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Fatalf("expected no deletion job before the backup completed, got %v", err)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if err := r.deleteSite(ctx, site); err == nil || !strings.Contains(err.Error(), "site deletion job created") {
+		t.Fatalf("expected the deletion job once the backup completed, got %v", err)
+	}
+	if site.Status.PreDeleteBackupPath != "sites/pre-delete-backups/site.local" {
+		t.Errorf("expected the backup path in status, got %q", site.Status.PreDeleteBackupPath)
+	}
+	if err := c.Get(ctx, deleteKey, &batchv1.Job{}); err != nil {
+		t.Errorf("expected the deletion job: %v", err)
+	}
+}
+
+func TestDeleteSite_BackupBeforeDeleteFailed(t *testing.T) {
+	site, bench, _ := newExternalDBTestObjects()
+	site.Spec.DBConfig.SkipDropOnDelete = true
+	site.Spec.BackupBeforeDelete = true
+	meta.SetStatusCondition(&site.Status.Conditions, metav1.Condition{Type: siteInitializedCondition, Status: metav1.ConditionTrue, Reason: "JobCompleted"})
+	failed := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-pre-delete-backup", Namespace: "default"},
+		Status:     batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: "True"}}},
+	}
+	r, c := newInitJobTestReconciler(site, bench, failed)
+	ctx := context.Background()
+
+	// A failed backup never falls through to drop-site
+	if err := r.deleteSite(ctx, site); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Fatalf("expected the failed backup to block deletion, got %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-delete", Namespace: "default"}, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("expected no deletion job after a failed backup, got %v", err)
+	}
+}
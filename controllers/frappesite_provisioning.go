@@ -0,0 +1,129 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/backoff"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Provisioning waits tracked in status.provisioningWaitReason
+const (
+	waitBenchNotReady        = "BenchNotReady"
+	waitDatabaseProvisioning = "DatabaseProvisioning"
+)
+
+// provisioningWaitFailReasons are the Ready reasons a site fails with once a wait runs out of attempts
+var provisioningWaitFailReasons = map[string]string{
+	waitBenchNotReady:        "BenchReadyTimeout",
+	waitDatabaseProvisioning: "DatabaseProvisioningTimeout",
+}
+
+// provisioningBackoff returns the requeue delay bounds for provisioning waits
+func (r *FrappeSiteReconciler) provisioningBackoff() (base, max time.Duration) {
+	base, max = r.ProvisioningBackoffBase, r.ProvisioningBackoffMax
+	if base <= 0 {
+		base = requeueBackoffBase
+	}
+	if max <= 0 {
+		max = requeueBackoffMax
+	}
+	return base, max
+}
+
+// provisioningAttempt returns the backoff step a wait that started elapsed ago is on and
+// how long until the next one. The step follows from the elapsed time rather than from
+// the number of reconciles, so watch events during the wait don't use up attempts.
+func provisioningAttempt(elapsed, base, max time.Duration, limit int32) (int32, time.Duration) {
+	attempt := int32(1)
+	next := backoff.ExponentialBackoff(base, 0, max)
+	for elapsed >= next && (limit <= 0 || attempt <= limit) {
+		step := backoff.ExponentialBackoff(base, int(attempt), max)
+		if step == max && limit <= 0 {
+			// Every later step is max long, skip to the current one
+			skipped := (elapsed-next)/max + 1
+			return attempt + int32(skipped), next + time.Duration(skipped)*max - elapsed
+		}
+		next += step
+		attempt++
+	}
+	return attempt, next - elapsed
+}
+
+// waitForProvisioning requeues a site that is waiting on its bench or database with
+// exponential backoff, and fails it once the wait has lasted MaxProvisioningAttempts
+// backoff steps. Status is only written when the wait starts or moves to the next step.
+// A failed wait is retried when the spec changes or the bench watch enqueues the site again.
+func (r *FrappeSiteReconciler) waitForProvisioning(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, waitReason, msg string) (ctrl.Result, error) {
+	failReason := provisioningWaitFailReasons[waitReason]
+	ready := meta.FindStatusCondition(site.Status.Conditions, "Ready")
+	gaveUp := ready != nil && ready.Status == metav1.ConditionFalse && ready.Reason == failReason
+	if gaveUp && ready.ObservedGeneration == site.Generation && site.Status.ProvisioningWaitReason == waitReason {
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseFailed
+		return ctrl.Result{}, r.updateStatus(ctx, site)
+	}
+
+	if site.Status.ProvisioningWaitReason != waitReason || site.Status.ProvisioningWaitSince == nil || gaveUp {
+		now := metav1.Now()
+		site.Status.ProvisioningWaitReason = waitReason
+		site.Status.ProvisioningWaitSince = &now
+		site.Status.ProvisioningAttempts = 0
+	}
+	base, max := r.provisioningBackoff()
+	attempt, requeueAfter := provisioningAttempt(time.Since(site.Status.ProvisioningWaitSince.Time), base, max, r.MaxProvisioningAttempts)
+	changed := attempt != site.Status.ProvisioningAttempts
+	site.Status.ProvisioningAttempts = attempt
+
+	if r.MaxProvisioningAttempts > 0 && attempt > r.MaxProvisioningAttempts {
+		msg = fmt.Sprintf("%s; gave up after %d attempts since %s", msg, r.MaxProvisioningAttempts,
+			site.Status.ProvisioningWaitSince.UTC().Format(time.RFC3339))
+		log.FromContext(ctx).Info("Provisioning wait exhausted", "reason", waitReason, "attempts", r.MaxProvisioningAttempts)
+		site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseFailed
+		r.setCondition(site, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  failReason,
+			Message: msg,
+		})
+		r.Recorder.Event(site, corev1.EventTypeWarning, failReason, msg)
+		return ctrl.Result{}, r.updateStatus(ctx, site)
+	}
+
+	if changed {
+		_ = r.updateStatus(ctx, site)
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// clearProvisioningWait resets the wait tracking once the site is no longer waiting for waitReason
+func clearProvisioningWait(site *vyogotechv1alpha1.FrappeSite, waitReason string) {
+	if site.Status.ProvisioningWaitReason != waitReason {
+		return
+	}
+	site.Status.ProvisioningWaitReason = ""
+	site.Status.ProvisioningAttempts = 0
+	site.Status.ProvisioningWaitSince = nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFrappeSiteReconciler_ProvisioningBackoff(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	site, bench := newInitJobTestObjects()
+	bench.Status.Phase = "Provisioning"
+	site.SetFinalizers([]string{frappeSiteFinalizer})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, site).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{
+		Client:                  c,
+		Scheme:                  scheme,
+		Recorder:                record.NewFakeRecorder(20),
+		ProvisioningBackoffBase: 10 * time.Second,
+		ProvisioningBackoffMax:  30 * time.Second,
+		MaxProvisioningAttempts: 3,
+	}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "site", Namespace: "default"}}
+	get := func() *vyogotechv1alpha1.FrappeSite {
+		updated := &vyogotechv1alpha1.FrappeSite{}
+		if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Get site: %v", err)
+		}
+		return updated
+	}
+
+	// waitedFor moves the start of the wait back, as if the site had waited that long
+	waitedFor := func(d time.Duration) {
+		updated := get()
+		since := metav1.NewTime(time.Now().Add(-d))
+		updated.Status.ProvisioningWaitSince = &since
+		if err := c.Status().Update(ctx, updated); err != nil {
+			t.Fatalf("Update site status: %v", err)
+		}
+	}
+	expectRequeue := func(step string, want time.Duration) {
+		t.Helper()
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		// provisioningWaitSince is stored with second precision
+		if result.RequeueAfter > want || result.RequeueAfter < want-time.Second {
+			t.Errorf("%s: expected RequeueAfter of about %v, got %v", step, want, result.RequeueAfter)
+		}
+	}
+
+	expectRequeue("attempt 1", 10*time.Second)
+	// Watch events during a backoff step don't use up attempts
+	expectRequeue("attempt 1 again", 10*time.Second)
+	if attempts := get().Status.ProvisioningAttempts; attempts != 1 {
+		t.Errorf("expected to stay on attempt 1, got %d", attempts)
+	}
+
+	waitedFor(10 * time.Second)
+	expectRequeue("attempt 2", 20*time.Second)
+	waitedFor(30 * time.Second)
+	expectRequeue("attempt 3", 30*time.Second)
+	updated := get()
+	if updated.Status.ProvisioningWaitReason != waitBenchNotReady || updated.Status.ProvisioningAttempts != 3 {
+		t.Errorf("expected 3 BenchNotReady attempts, got %q/%d", updated.Status.ProvisioningWaitReason, updated.Status.ProvisioningAttempts)
+	}
+	if updated.Status.ProvisioningWaitSince == nil {
+		t.Error("expected provisioningWaitSince to be set")
+	}
+
+	// Waiting past the last step fails the site and stops requeueing
+	waitedFor(60 * time.Second)
+	result, err := r.Reconcile(ctx, req)
+	if err != nil || result.RequeueAfter != 0 {
+		t.Fatalf("expected no requeue once attempts are exhausted, got %v, %v", result, err)
+	}
+	updated = get()
+	if updated.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseFailed {
+		t.Errorf("expected phase Failed, got %s", updated.Status.Phase)
+	}
+	ready := meta.FindStatusCondition(updated.Status.Conditions, "Ready")
+	if ready == nil || ready.Reason != "BenchReadyTimeout" {
+		t.Errorf("expected Ready reason BenchReadyTimeout, got %+v", ready)
+	}
+
+	// A later reconcile of the same generation stays failed
+	result, err = r.Reconcile(ctx, req)
+	if err != nil || result.RequeueAfter != 0 {
+		t.Fatalf("expected failed site to stay put, got %v, %v", result, err)
+	}
+	if get().Status.ProvisioningAttempts != 4 {
+		t.Errorf("expected attempts to stay at 4, got %d", get().Status.ProvisioningAttempts)
+	}
+
+	// A spec change starts a fresh wait
+	updated = get()
+	updated.Generation++
+	if err := c.Update(ctx, updated); err != nil {
+		t.Fatalf("Update site: %v", err)
+	}
+	expectRequeue("after a spec change", 10*time.Second)
+	if updated = get(); updated.Status.ProvisioningAttempts != 1 || updated.Status.Phase != vyogotechv1alpha1.FrappeSitePhasePending {
+		t.Errorf("expected attempt 1 in phase Pending, got %d in %s", updated.Status.ProvisioningAttempts, updated.Status.Phase)
+	}
+}
+
+func TestProvisioningAttempt(t *testing.T) {
+	tests := []struct {
+		name        string
+		elapsed     time.Duration
+		limit       int32
+		wantAttempt int32
+		wantNext    time.Duration
+	}{
+		{name: "start", elapsed: 0, limit: 3, wantAttempt: 1, wantNext: time.Second},
+		{name: "second step", elapsed: 1500 * time.Millisecond, limit: 3, wantAttempt: 2, wantNext: 1500 * time.Millisecond},
+		{name: "capped step", elapsed: 4 * time.Second, limit: 3, wantAttempt: 3, wantNext: 2 * time.Second},
+		{name: "exhausted", elapsed: time.Hour, limit: 3, wantAttempt: 4},
+		{name: "unlimited skips capped steps", elapsed: 10 * time.Second, wantAttempt: 5, wantNext: 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt, next := provisioningAttempt(tt.elapsed, time.Second, 3*time.Second, tt.limit)
+			if attempt != tt.wantAttempt {
+				t.Errorf("expected attempt %d, got %d", tt.wantAttempt, attempt)
+			}
+			if tt.wantNext != 0 && next != tt.wantNext {
+				t.Errorf("expected the next step in %v, got %v", tt.wantNext, next)
+			}
+		})
+	}
+}
+
+func TestClearProvisioningWait(t *testing.T) {
+	site, _ := newInitJobTestObjects()
+	site.Status.ProvisioningWaitReason = waitDatabaseProvisioning
+	site.Status.ProvisioningAttempts = 2
+
+	clearProvisioningWait(site, waitBenchNotReady)
+	if site.Status.ProvisioningAttempts != 2 {
+		t.Error("a different wait reason must not be cleared")
+	}
+	clearProvisioningWait(site, waitDatabaseProvisioning)
+	if site.Status.ProvisioningWaitReason != "" || site.Status.ProvisioningAttempts != 0 {
+		t.Errorf("expected the wait to be cleared, got %+v", site.Status)
+	}
+}
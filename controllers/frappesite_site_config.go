@@ -0,0 +1,230 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// siteConfigAnnotation records on the site config job which values it writes
+	siteConfigAnnotation = "frappe.tech/site-config"
+
+	// siteConfigSecretMountPath is where the site config job mounts spec.siteConfigSecretRef
+	siteConfigSecretMountPath = "/tmp/site-config"
+)
+
+// operatorManagedSiteConfigKey reports whether the operator owns a site_config.json key.
+// spec.siteConfig can't override these, the site would lose its database or cache.
+func operatorManagedSiteConfigKey(key string) bool {
+	switch key {
+	case "host_name", "domains", "allow_cors", "encryption_key", "maintenance_mode":
+		return true
+	}
+	return strings.HasPrefix(key, "db_") || strings.HasPrefix(key, "redis_")
+}
+
+// siteConfigPatch is the change the site config job applies to site_config.json
+type siteConfigPatch struct {
+	// Set holds the spec.siteConfig values
+	Set map[string]string `json:"set,omitempty"`
+	// SecretKeys are read from the mounted siteConfigSecretRef Secret
+	SecretKeys []string `json:"secretKeys,omitempty"`
+	// Remove lists keys written earlier that are no longer wanted
+	Remove []string `json:"remove,omitempty"`
+	// Links are the spec.aliases; nginx picks the site directory from the Host header,
+	// so each alias gets a symlink to the site's directory
+	Links []string `json:"links,omitempty"`
+}
+
+// desiredSiteConfig works out the patch for spec.siteConfig, spec.siteConfigSecretRef and
+// spec.aliases (written as domains), the sorted keys it writes and a hash of their values ("" when nothing is wanted).
+// Secret values only go into the hash, never into the patch.
+func (r *FrappeSiteReconciler) desiredSiteConfig(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (siteConfigPatch, []string, string, error) {
+	patch := siteConfigPatch{Set: map[string]string{}}
+	values := map[string]string{}
+	var ignored []string
+	for key, value := range site.Spec.SiteConfig {
+		if operatorManagedSiteConfigKey(key) {
+			ignored = append(ignored, fmt.Sprintf("spec.siteConfig key %q", key))
+			continue
+		}
+		patch.Set[key] = value
+		values[key] = value
+	}
+	if ref := site.Spec.SiteConfigSecretRef; ref != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: site.Namespace}, secret); err != nil {
+			return siteConfigPatch{}, nil, "", fmt.Errorf("failed to get site config secret %s: %w", ref.Name, err)
+		}
+		for key, value := range secret.Data {
+			if operatorManagedSiteConfigKey(key) {
+				ignored = append(ignored, fmt.Sprintf("siteConfigSecretRef key %q", key))
+				continue
+			}
+			// Secret keys win over siteConfig
+			delete(patch.Set, key)
+			patch.SecretKeys = append(patch.SecretKeys, key)
+			values[key] = strings.TrimRight(string(value), "\r\n")
+		}
+		sort.Strings(patch.SecretKeys)
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		r.warnings.warn(r.Recorder, site, "SiteConfigKeyIgnored",
+			fmt.Sprintf("Ignoring keys managed by the operator: %s", strings.Join(ignored, ", ")))
+	} else {
+		r.warnings.resolve(site, "SiteConfigKeyIgnored")
+	}
+	if len(site.Spec.Aliases) > 0 {
+		domains, err := json.Marshal(site.Spec.Aliases)
+		if err != nil {
+			return siteConfigPatch{}, nil, "", err
+		}
+		patch.Set["domains"] = string(domains)
+		patch.Links = slices.Clone(site.Spec.Aliases)
+		values["domains"] = string(domains)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range site.Status.SiteConfigKeys {
+		if _, ok := values[key]; !ok {
+			patch.Remove = append(patch.Remove, key)
+		}
+	}
+	if len(values) == 0 {
+		return patch, nil, "", nil
+	}
+
+	// encoding/json sorts map keys, so the hash is stable
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return siteConfigPatch{}, nil, "", err
+	}
+	return patch, keys, fmt.Sprintf("%x", sha256.Sum256(encoded))[:16], nil
+}
+
+// siteConfigSecretChanged reports whether the values in the siteConfigSecretRef Secret
+// differ from the ones last written to site_config.json
+func (r *FrappeSiteReconciler) siteConfigSecretChanged(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (bool, error) {
+	if site.Spec.SiteConfigSecretRef == nil {
+		return false, nil
+	}
+	_, _, hash, err := r.desiredSiteConfig(ctx, site)
+	if apierrors.IsNotFound(err) {
+		// Let the full reconcile report the missing Secret
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return hash != site.Status.SiteConfigHash, nil
+}
+
+// ensureSiteConfig merges spec.siteConfig and spec.siteConfigSecretRef into the site's
+// site_config.json and removes keys dropped from them. Operator-managed keys are never
+// touched. Returns true once the applied values (status.siteConfigHash) match the spec.
+func (r *FrappeSiteReconciler) ensureSiteConfig(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) (bool, error) {
+	patch, keys, hash, err := r.desiredSiteConfig(ctx, site)
+	if err != nil {
+		return false, err
+	}
+	if hash == site.Status.SiteConfigHash && len(patch.Remove) == 0 {
+		return true, nil
+	}
+	jobName := fmt.Sprintf("%s-site-config", site.Name)
+	key := siteJobKey{annotation: siteConfigAnnotation, value: hash + ";" + strings.Join(patch.Remove, ",")}
+	job, err := runSiteJob(ctx, r.Client, site, jobName, key, func() (*batchv1.Job, error) {
+		return r.buildSiteConfigJob(ctx, site, bench, jobName, patch)
+	})
+	if job == nil || err != nil {
+		return false, err
+	}
+
+	site.Status.SiteConfigKeys = slices.Clone(keys)
+	site.Status.SiteConfigHash = hash
+	r.Recorder.Event(site, corev1.EventTypeNormal, "SiteConfigApplied",
+		fmt.Sprintf("site_config.json keys set: [%s], removed: [%s]", strings.Join(keys, ", "), strings.Join(patch.Remove, ", ")))
+	return true, nil
+}
+
+// buildSiteConfigJob returns the job that applies patch to the site's site_config.json
+func (r *FrappeSiteReconciler) buildSiteConfigJob(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, jobName string, patch siteConfigPatch) (*batchv1.Job, error) {
+	mergeScript, err := scripts.RenderScript(scripts.SiteConfigMerge, scripts.SiteConfigMergeData{
+		SiteName: site.Spec.SiteName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render site config script: %w", err)
+	}
+	encodedPatch, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeSelector, affinity, tolerations, extraLabels := applyPodConfig(siteJobPodConfig(site, bench), ownedLabels(site, map[string]string{
+		"app":  "frappe",
+		"site": site.Name,
+	}))
+
+	containerBuilder := resources.NewContainerBuilder("site-config", r.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(mergeScript).
+		WithEnv("SITE_CONFIG_PATCH", string(encodedPatch)).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(r.getContainerSecurityContext(ctx, bench))
+	if len(patch.SecretKeys) > 0 {
+		containerBuilder = containerBuilder.WithVolumeMountReadOnly("site-config", siteConfigSecretMountPath)
+	}
+
+	jobBuilder := resources.NewJobBuilder(jobName, site.Namespace).
+		WithLabels(extraLabels).
+		WithLabels(jobLabels(jobOperationSiteConfig, bench.Name, site.Spec.SiteName)).
+		WithExtraPodLabels(extraLabels).
+		WithBackoffLimit(2).
+		WithNodeSelector(nodeSelector).
+		WithAffinity(affinity).
+		WithTolerations(tolerations).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(r.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(containerBuilder.Build()).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(site, r.Scheme)
+	if len(patch.SecretKeys) > 0 {
+		jobBuilder = jobBuilder.WithSecretVolume("site-config", site.Spec.SiteConfigSecretRef.Name, nil)
+	}
+	return jobBuilder.Build()
+}
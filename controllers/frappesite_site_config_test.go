@@ -0,0 +1,232 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestOperatorManagedSiteConfigKey(t *testing.T) {
+	for _, key := range []string{"host_name", "domains", "db_password", "db_host", "redis_cache", "redis_socketio", "allow_cors", "encryption_key", "maintenance_mode"} {
+		if !operatorManagedSiteConfigKey(key) {
+			t.Errorf("expected %q to be operator-managed", key)
+		}
+	}
+	for _, key := range []string{"mail_server", "developer_mode", "max_file_size"} {
+		if operatorManagedSiteConfigKey(key) {
+			t.Errorf("expected %q to be user-settable", key)
+		}
+	}
+}
+
+func TestEnsureSiteConfig(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.SiteConfig = map[string]string{"mail_server": "smtp.example.com", "max_file_size": "10485760", "db_password": "nope"}
+	site.Spec.SiteConfigSecretRef = &corev1.LocalObjectReference{Name: "site-extra"}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-extra", Namespace: "default"},
+		Data:       map[string][]byte{"mail_password": []byte("s3cret"), "mail_server": []byte("smtp.internal")},
+	}
+	r, c := newInitJobTestReconciler(site, bench, secret)
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "site-site-config", Namespace: "default"}
+
+	applied, err := r.ensureSiteConfig(ctx, site, bench)
+	if err != nil || applied {
+		t.Fatalf("expected a pending site config job, got applied=%v err=%v", applied, err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationSiteConfig {
+		t.Errorf("expected operation label %q, got %q", jobOperationSiteConfig, job.Labels[jobOperationLabel])
+	}
+	assertSitesMount(t, "site config job", job.Spec.Template.Spec)
+
+	container := job.Spec.Template.Spec.Containers[0]
+	var patch siteConfigPatch
+	for _, env := range container.Env {
+		if env.Name == "SITE_CONFIG_PATCH" {
+			if err := json.Unmarshal([]byte(env.Value), &patch); err != nil {
+				t.Fatalf("SITE_CONFIG_PATCH is not JSON: %v", err)
+			}
+		}
+	}
+	if len(patch.Set) != 1 || patch.Set["max_file_size"] != "10485760" {
+		t.Errorf("expected only max_file_size to be set inline, got %v", patch.Set)
+	}
+	if !slices.Equal(patch.SecretKeys, []string{"mail_password", "mail_server"}) {
+		t.Errorf("expected the Secret keys to be read from the mount, got %v", patch.SecretKeys)
+	}
+	for _, env := range container.Env {
+		if strings.Contains(env.Value, "s3cret") || strings.Contains(env.Value, "nope") {
+			t.Errorf("expected no secret or operator-managed values in the job, got %s=%s", env.Name, env.Value)
+		}
+	}
+	if !slices.ContainsFunc(job.Spec.Template.Spec.Volumes, func(v corev1.Volume) bool {
+		return v.Secret != nil && v.Secret.SecretName == "site-extra"
+	}) {
+		t.Errorf("expected the site config Secret to be mounted")
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	applied, err = r.ensureSiteConfig(ctx, site, bench)
+	if err != nil || !applied {
+		t.Fatalf("expected site config to be applied, got applied=%v err=%v", applied, err)
+	}
+	if !slices.Equal(site.Status.SiteConfigKeys, []string{"mail_password", "mail_server", "max_file_size"}) {
+		t.Errorf("unexpected status.siteConfigKeys %v", site.Status.SiteConfigKeys)
+	}
+	if site.Status.SiteConfigHash == "" {
+		t.Errorf("expected status.siteConfigHash to be recorded")
+	}
+
+	// Re-running with the same values is a no-op
+	if applied, err := r.ensureSiteConfig(ctx, site, bench); err != nil || !applied {
+		t.Fatalf("expected no change, got applied=%v err=%v", applied, err)
+	}
+
+	// A trailing newline, as kubectl create secret --from-file leaves it, is not a change
+	secret.Data["mail_password"] = []byte("s3cret\n")
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatalf("Update Secret: %v", err)
+	}
+	if changed, err := r.siteConfigSecretChanged(ctx, site); err != nil || changed {
+		t.Fatalf("expected no change for a trailing newline, got changed=%v err=%v", changed, err)
+	}
+
+	// Changing a Secret value replaces the finished job
+	secret.Data["mail_password"] = []byte("rotated")
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatalf("Update Secret: %v", err)
+	}
+	if changed, err := r.siteConfigSecretChanged(ctx, site); err != nil || !changed {
+		t.Fatalf("expected the rotated Secret to be a change, got changed=%v err=%v", changed, err)
+	}
+	if applied, err := r.ensureSiteConfig(ctx, site, bench); err != nil || applied {
+		t.Fatalf("expected a pending update, got applied=%v err=%v", applied, err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the stale job to be deleted, got %v", err)
+	}
+}
+
+func TestSitesForSecret(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.SiteConfigSecretRef = &corev1.LocalObjectReference{Name: "site-extra"}
+	other := site.DeepCopy()
+	other.Name = "other"
+	other.Spec.SiteConfigSecretRef = nil
+	r, _ := newInitJobTestReconciler(site, other, bench)
+	ctx := context.Background()
+	secret := func(name, namespace string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+
+	if reqs := r.sitesForSecret(ctx, secret("site-extra", "default")); len(reqs) != 1 || reqs[0].Name != "site" {
+		t.Errorf("expected the site reading the Secret, got %v", reqs)
+	}
+	if reqs := r.sitesForSecret(ctx, secret("site-extra", "other")); len(reqs) != 0 {
+		t.Errorf("expected no sites for a Secret in another namespace, got %v", reqs)
+	}
+	if reqs := r.sitesForSecret(ctx, secret("unrelated", "default")); len(reqs) != 0 {
+		t.Errorf("expected no sites for an unrelated Secret, got %v", reqs)
+	}
+}
+
+func TestDesiredSiteConfig_Aliases(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.Aliases = []string{"www.example.com", "shop.example.org"}
+	site.Spec.SiteConfig = map[string]string{"domains": `["evil.example.com"]`}
+	r, _ := newInitJobTestReconciler(site, bench)
+
+	patch, keys, hash, err := r.desiredSiteConfig(context.Background(), site)
+	if err != nil {
+		t.Fatalf("desiredSiteConfig: %v", err)
+	}
+	if patch.Set["domains"] != `["www.example.com","shop.example.org"]` {
+		t.Errorf("expected the aliases as domains, got %q", patch.Set["domains"])
+	}
+	if !slices.Equal(patch.Links, site.Spec.Aliases) {
+		t.Errorf("expected a site link per alias, got %v", patch.Links)
+	}
+	if !slices.Equal(keys, []string{"domains"}) || hash == "" {
+		t.Errorf("expected domains to be tracked, got keys %v hash %q", keys, hash)
+	}
+
+	// Dropping the aliases removes domains and every link
+	site.Spec.Aliases = nil
+	site.Status.SiteConfigKeys = keys
+	patch, _, _, err = r.desiredSiteConfig(context.Background(), site)
+	if err != nil {
+		t.Fatalf("desiredSiteConfig: %v", err)
+	}
+	if !slices.Equal(patch.Remove, []string{"domains"}) || len(patch.Links) != 0 {
+		t.Errorf("expected domains to be removed without links, got remove %v links %v", patch.Remove, patch.Links)
+	}
+}
+
+func TestEnsureSiteConfig_RemovesDroppedKeys(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Status.SiteConfigKeys = []string{"developer_mode"}
+	site.Status.SiteConfigHash = "0123456789abcdef"
+	r, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+
+	if applied, err := r.ensureSiteConfig(ctx, site, bench); err != nil || applied {
+		t.Fatalf("expected a pending removal, got applied=%v err=%v", applied, err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-site-config", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if env := job.Spec.Template.Spec.Containers[0].Env; !slices.ContainsFunc(env, func(e corev1.EnvVar) bool {
+		return e.Name == "SITE_CONFIG_PATCH" && strings.Contains(e.Value, `"remove":["developer_mode"]`)
+	}) {
+		t.Errorf("expected the job to remove developer_mode, got %v", env)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	if applied, err := r.ensureSiteConfig(ctx, site, bench); err != nil || !applied {
+		t.Fatalf("expected the removal to be applied, got applied=%v err=%v", applied, err)
+	}
+	if len(site.Status.SiteConfigKeys) != 0 || site.Status.SiteConfigHash != "" {
+		t.Errorf("expected the status to be cleared, got %v %q", site.Status.SiteConfigKeys, site.Status.SiteConfigHash)
+	}
+
+	// Nothing wanted and nothing written: no job
+	if applied, err := r.ensureSiteConfig(ctx, site, bench); err != nil || !applied {
+		t.Fatalf("expected no work, got applied=%v err=%v", applied, err)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdateStatus_keepsReconcileMarkers(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Generation = 3
+	siteReconciler, _ := newInitJobTestReconciler(site, bench)
+	c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+
+	reconciled := &vyogotechv1alpha1.FrappeSite{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(site), reconciled); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+
+	// Someone else changes the site while it is reconciled, so the reconciled copy is stale
+	other := reconciled.DeepCopy()
+	other.Labels = map[string]string{"team": "erp"}
+	if err := c.Update(ctx, other); err != nil {
+		t.Fatalf("Update site: %v", err)
+	}
+
+	reconcileTime := metav1.NewTime(time.Now().Truncate(time.Second))
+	reconciled.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseReady
+	reconciled.Status.ObservedGeneration = reconciled.Generation
+	reconciled.Status.LastReconcileTime = &reconcileTime
+	if err := r.updateStatus(ctx, reconciled); err != nil {
+		t.Fatalf("updateStatus: %v", err)
+	}
+
+	stored := &vyogotechv1alpha1.FrappeSite{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(site), stored); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if stored.Status.ObservedGeneration != 3 {
+		t.Errorf("expected observedGeneration 3, got %d", stored.Status.ObservedGeneration)
+	}
+	if stored.Status.LastReconcileTime == nil || !stored.Status.LastReconcileTime.Equal(&reconcileTime) {
+		t.Errorf("expected lastReconcileTime %v, got %v", reconcileTime, stored.Status.LastReconcileTime)
+	}
+	if stored.Labels["team"] != "erp" {
+		t.Error("expected the concurrent label change to be kept")
+	}
+	if reconciled.ResourceVersion != stored.ResourceVersion {
+		t.Errorf("expected the reconciled copy to move to resourceVersion %s, got %s", stored.ResourceVersion, reconciled.ResourceVersion)
+	}
+}
+
+func TestUpdateStatus_readyColumn(t *testing.T) {
+	tests := []struct {
+		name       string
+		phase      vyogotechv1alpha1.FrappeSitePhase
+		ready      *metav1.Condition
+		wantPhase  vyogotechv1alpha1.FrappeSitePhase
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{name: "new site", wantPhase: vyogotechv1alpha1.FrappeSitePhasePending, wantStatus: metav1.ConditionFalse, wantReason: "Pending"},
+		{
+			name:       "stale Ready",
+			phase:      vyogotechv1alpha1.FrappeSitePhaseProvisioning,
+			ready:      &metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "SiteReady"},
+			wantPhase:  vyogotechv1alpha1.FrappeSitePhaseProvisioning,
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "Provisioning",
+		},
+		{
+			name:       "specific reason kept",
+			phase:      vyogotechv1alpha1.FrappeSitePhasePending,
+			ready:      &metav1.Condition{Type: "Ready", Status: metav1.ConditionFalse, Reason: "MissingSecret"},
+			wantPhase:  vyogotechv1alpha1.FrappeSitePhasePending,
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "MissingSecret",
+		},
+		{
+			name:       "ready site",
+			phase:      vyogotechv1alpha1.FrappeSitePhaseReady,
+			ready:      &metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "SiteReady"},
+			wantPhase:  vyogotechv1alpha1.FrappeSitePhaseReady,
+			wantStatus: metav1.ConditionTrue,
+			wantReason: "SiteReady",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site, bench := newInitJobTestObjects()
+			site.Status.Phase = tt.phase
+			if tt.ready != nil {
+				tt.ready.LastTransitionTime = metav1.Now()
+				site.Status.Conditions = []metav1.Condition{*tt.ready}
+			}
+			siteReconciler, _ := newInitJobTestReconciler()
+			c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+			r := &FrappeSiteReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(10)}
+			ctx := context.Background()
+
+			if err := r.updateStatus(ctx, site); err != nil {
+				t.Fatalf("updateStatus: %v", err)
+			}
+			stored := &vyogotechv1alpha1.FrappeSite{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(site), stored); err != nil {
+				t.Fatalf("Get site: %v", err)
+			}
+			if stored.Status.Phase != tt.wantPhase {
+				t.Errorf("expected phase %s, got %s", tt.wantPhase, stored.Status.Phase)
+			}
+			ready := meta.FindStatusCondition(stored.Status.Conditions, "Ready")
+			if ready == nil || ready.Status != tt.wantStatus || ready.Reason != tt.wantReason {
+				t.Errorf("expected Ready=%s reason %s, got %+v", tt.wantStatus, tt.wantReason, ready)
+			}
+		})
+	}
+}
@@ -6,16 +6,19 @@ package controllers
 
 import (
 	"context"
+	"slices"
 	"testing"
 
 	routev1 "github.com/openshift/api/route/v1"
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -55,6 +58,209 @@ func TestFrappeSiteReconciler_ensureIngress(t *testing.T) {
 	}
 }
 
+func TestFrappeSiteReconciler_ensureIngressAliases(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+			Aliases:  []string{"www.example.com", "site.example.com"},
+			TLS:      vyogotechv1alpha1.TLSConfig{Enabled: true},
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench).Build()
+	r := &FrappeSiteReconciler{Client: client, Scheme: scheme}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site-ingress", Namespace: "default"}
+
+	if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureIngress: %v", err)
+	}
+	ingress := &networkingv1.Ingress{}
+	if err := client.Get(ctx, key, ingress); err != nil {
+		t.Fatalf("Get Ingress: %v", err)
+	}
+	if hosts := ingressHosts(ingress); len(hosts) != 2 || hosts[0] != "site.example.com" || hosts[1] != "www.example.com" {
+		t.Errorf("expected rules for the domain and its alias, got %v", hosts)
+	}
+	if len(ingress.Spec.TLS) != 1 || len(ingress.Spec.TLS[0].Hosts) != 2 {
+		t.Errorf("expected the certificate to cover the alias, got %+v", ingress.Spec.TLS)
+	}
+
+	// Removing the alias drops its rule
+	site.Spec.Aliases = nil
+	if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureIngress: %v", err)
+	}
+	if err := client.Get(ctx, key, ingress); err != nil {
+		t.Fatalf("Get Ingress: %v", err)
+	}
+	if hosts := ingressHosts(ingress); len(hosts) != 1 || hosts[0] != "site.example.com" {
+		t.Errorf("expected the stale alias rule to be removed, got %v", hosts)
+	}
+	if hosts := ingress.Spec.TLS[0].Hosts; len(hosts) != 1 || hosts[0] != "site.example.com" {
+		t.Errorf("expected the alias to be dropped from the TLS hosts, got %v", hosts)
+	}
+}
+
+func TestFrappeSiteReconciler_ensureIngress_CertManager(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	bench := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"}}
+	tests := []struct {
+		name           string
+		tls            vyogotechv1alpha1.TLSConfig
+		wantAnnotation string
+		wantSecret     string
+	}{
+		{
+			name:           "cluster issuer by default",
+			tls:            vyogotechv1alpha1.TLSConfig{Enabled: true, Issuer: "letsencrypt-prod"},
+			wantAnnotation: "cert-manager.io/cluster-issuer",
+			wantSecret:     "site-tls",
+		},
+		{
+			name:           "namespaced issuer",
+			tls:            vyogotechv1alpha1.TLSConfig{Enabled: true, Issuer: "letsencrypt-staging", IssuerKind: "Issuer", SecretName: "custom-tls"},
+			wantAnnotation: "cert-manager.io/issuer",
+			wantSecret:     "custom-tls",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &vyogotechv1alpha1.FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+				Spec: vyogotechv1alpha1.FrappeSiteSpec{
+					SiteName: "site.local",
+					BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+					TLS:      tt.tls,
+				},
+			}
+			client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench).Build()
+			r := &FrappeSiteReconciler{Client: client, Scheme: scheme}
+			ctx := context.Background()
+			if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+				t.Fatalf("ensureIngress: %v", err)
+			}
+			ingress := &networkingv1.Ingress{}
+			if err := client.Get(ctx, types.NamespacedName{Name: "site-ingress", Namespace: "default"}, ingress); err != nil {
+				t.Fatalf("Get Ingress: %v", err)
+			}
+			if ingress.Annotations[tt.wantAnnotation] != tt.tls.Issuer {
+				t.Errorf("expected %s=%s, got %v", tt.wantAnnotation, tt.tls.Issuer, ingress.Annotations)
+			}
+			if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != tt.wantSecret ||
+				len(ingress.Spec.TLS[0].Hosts) != 1 || ingress.Spec.TLS[0].Hosts[0] != "site.example.com" {
+				t.Errorf("expected TLS for site.example.com in %s, got %+v", tt.wantSecret, ingress.Spec.TLS)
+			}
+		})
+	}
+}
+
+func TestFrappeSiteReconciler_ensureIngress_OperatorDefaults(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	bench := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"}}
+	defaults := map[string]string{
+		"defaultIngressClass":       "haproxy",
+		"defaultIngressAnnotations": `{"haproxy.org/timeout-server": "300s", "nginx.ingress.kubernetes.io/proxy-body-size": "50m"}`,
+	}
+	tests := []struct {
+		name            string
+		operatorConfig  map[string]string
+		className       string
+		ingress         *vyogotechv1alpha1.IngressConfig
+		wantClass       string
+		wantAnnotations map[string]string
+	}{
+		{
+			name:            "no operator defaults",
+			wantClass:       "nginx",
+			wantAnnotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "100m"},
+		},
+		{
+			name:           "operator defaults applied",
+			operatorConfig: defaults,
+			wantClass:      "haproxy",
+			wantAnnotations: map[string]string{
+				"haproxy.org/timeout-server":                  "300s",
+				"nginx.ingress.kubernetes.io/proxy-body-size": "50m",
+			},
+		},
+		{
+			name:           "site overrides operator defaults",
+			operatorConfig: defaults,
+			className:      "traefik",
+			ingress:        &vyogotechv1alpha1.IngressConfig{Annotations: map[string]string{"haproxy.org/timeout-server": "600s"}},
+			wantClass:      "traefik",
+			wantAnnotations: map[string]string{
+				"haproxy.org/timeout-server":                  "600s",
+				"nginx.ingress.kubernetes.io/proxy-body-size": "50m",
+			},
+		},
+		{
+			name:           "ingress className wins over ingressClassName",
+			operatorConfig: defaults,
+			className:      "traefik",
+			ingress:        &vyogotechv1alpha1.IngressConfig{ClassName: "contour"},
+			wantClass:      "contour",
+		},
+		{
+			name:            "invalid default annotations are ignored",
+			operatorConfig:  map[string]string{"defaultIngressAnnotations": "not json"},
+			wantClass:       "nginx",
+			wantAnnotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "100m"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &vyogotechv1alpha1.FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+				Spec: vyogotechv1alpha1.FrappeSiteSpec{
+					SiteName:         "site.local",
+					BenchRef:         &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+					IngressClassName: tt.className,
+					Ingress:          tt.ingress,
+				},
+			}
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench)
+			if tt.operatorConfig != nil {
+				builder.WithObjects(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "frappe-operator-config", Namespace: "frappe-operator-system"},
+					Data:       tt.operatorConfig,
+				})
+			}
+			client := builder.Build()
+			r := &FrappeSiteReconciler{Client: client, Scheme: scheme}
+			ctx := context.Background()
+			if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+				t.Fatalf("ensureIngress: %v", err)
+			}
+			ingress := &networkingv1.Ingress{}
+			if err := client.Get(ctx, types.NamespacedName{Name: "site-ingress", Namespace: "default"}, ingress); err != nil {
+				t.Fatalf("Get Ingress: %v", err)
+			}
+			if ingress.Spec.IngressClassName == nil || *ingress.Spec.IngressClassName != tt.wantClass {
+				t.Errorf("expected class %s, got %v", tt.wantClass, ingress.Spec.IngressClassName)
+			}
+			for key, value := range tt.wantAnnotations {
+				if ingress.Annotations[key] != value {
+					t.Errorf("expected %s=%s, got %v", key, value, ingress.Annotations)
+				}
+			}
+		})
+	}
+}
+
 func TestFrappeSiteReconciler_ensureIngress_Disabled(t *testing.T) {
 	scheme := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
@@ -119,3 +325,523 @@ func TestFrappeSiteReconciler_ensureRoute(t *testing.T) {
 		t.Errorf("expected to.Name bench-nginx, got %s", route.Spec.To.Name)
 	}
 }
+
+func TestFrappeSiteReconciler_ensureRouteAliases(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(routev1.AddToScheme(scheme))
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+			Aliases:  []string{"www.example.com", "shop.example.org"},
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench).Build()
+	r := &FrappeSiteReconciler{Client: client, Scheme: scheme}
+	ctx := context.Background()
+
+	if err := r.ensureRoute(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	for _, alias := range site.Spec.Aliases {
+		route := &routev1.Route{}
+		if err := client.Get(ctx, types.NamespacedName{Name: "site-route-" + alias, Namespace: "default"}, route); err != nil {
+			t.Fatalf("Get Route for %s: %v", alias, err)
+		}
+		if route.Spec.Host != alias || route.Spec.To.Name != "bench-nginx" {
+			t.Errorf("expected a Route for %s to bench-nginx, got host %s to %s", alias, route.Spec.Host, route.Spec.To.Name)
+		}
+	}
+
+	// Removing an alias deletes its Route and keeps the primary one
+	site.Spec.Aliases = []string{"www.example.com"}
+	if err := r.ensureRoute(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	routes := &routev1.RouteList{}
+	if err := client.List(ctx, routes); err != nil {
+		t.Fatalf("List Routes: %v", err)
+	}
+	var names []string
+	for _, route := range routes.Items {
+		names = append(names, route.Name)
+	}
+	if len(names) != 2 || !slices.Contains(names, "site-route") || !slices.Contains(names, "site-route-www.example.com") {
+		t.Errorf("expected the primary and www Routes only, got %v", names)
+	}
+}
+
+func TestFrappeSiteReconciler_ensureRouteTLSTermination(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(routev1.AddToScheme(scheme))
+	nginxTLS := &vyogotechv1alpha1.NginxTLSConfig{SecretName: "bench-nginx-cert"}
+	certSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-nginx-cert", Namespace: "default"},
+		Data:       map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key"), "ca.crt": []byte("bench-ca")},
+	}
+
+	tests := []struct {
+		name        string
+		termination string
+		nginxTLS    *vyogotechv1alpha1.NginxTLSConfig
+		wantErr     bool
+		wantType    routev1.TLSTerminationType
+		wantPort    string
+		wantDestCA  string
+	}{
+		{name: "edge by default", wantType: routev1.TLSTerminationEdge, wantPort: "http"},
+		{name: "edge", termination: "edge", nginxTLS: nginxTLS, wantType: routev1.TLSTerminationEdge, wantPort: "http"},
+		{name: "passthrough", termination: "passthrough", nginxTLS: nginxTLS, wantType: routev1.TLSTerminationPassthrough, wantPort: "https"},
+		{name: "reencrypt", termination: "reencrypt", nginxTLS: nginxTLS, wantType: routev1.TLSTerminationReencrypt, wantPort: "https", wantDestCA: "bench-ca"},
+		{name: "passthrough without nginx TLS", termination: "passthrough", wantErr: true},
+		{name: "reencrypt without nginx TLS", termination: "reencrypt", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &vyogotechv1alpha1.FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+				Spec: vyogotechv1alpha1.FrappeSiteSpec{
+					SiteName:    "site.local",
+					BenchRef:    &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+					RouteConfig: &vyogotechv1alpha1.RouteConfig{TLSTermination: tt.termination},
+				},
+			}
+			bench := &vyogotechv1alpha1.FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+				Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15", NginxTLS: tt.nginxTLS},
+			}
+			client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench, certSecret).Build()
+			r := &FrappeSiteReconciler{Client: client, Scheme: scheme}
+			ctx := context.Background()
+
+			err := r.ensureRoute(ctx, site, bench, "site.example.com")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected ensureRoute to reject the termination")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ensureRoute: %v", err)
+			}
+			route := &routev1.Route{}
+			if err := client.Get(ctx, types.NamespacedName{Name: "site-route", Namespace: "default"}, route); err != nil {
+				t.Fatalf("Get Route: %v", err)
+			}
+			if route.Spec.TLS == nil || route.Spec.TLS.Termination != tt.wantType {
+				t.Fatalf("expected %s termination, got %+v", tt.wantType, route.Spec.TLS)
+			}
+			if route.Spec.Port == nil || route.Spec.Port.TargetPort.StrVal != tt.wantPort {
+				t.Errorf("expected target port %s, got %+v", tt.wantPort, route.Spec.Port)
+			}
+			if route.Spec.TLS.DestinationCACertificate != tt.wantDestCA {
+				t.Errorf("expected destination CA %q, got %q", tt.wantDestCA, route.Spec.TLS.DestinationCACertificate)
+			}
+			if tt.wantType == routev1.TLSTerminationPassthrough && (route.Spec.TLS.Certificate != "" || route.Spec.TLS.Key != "") {
+				t.Errorf("expected no certificate on a passthrough Route, got %+v", route.Spec.TLS)
+			}
+		})
+	}
+}
+
+func TestFrappeSiteReconciler_ensureRouteSwitchesTermination(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(routev1.AddToScheme(scheme))
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeBenchSpec{
+			FrappeVersion: "15",
+			NginxTLS:      &vyogotechv1alpha1.NginxTLSConfig{SecretName: "bench-nginx-cert"},
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench).Build()
+	r := &FrappeSiteReconciler{Client: client, Scheme: scheme}
+	ctx := context.Background()
+
+	if err := r.ensureRoute(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	site.Spec.RouteConfig = &vyogotechv1alpha1.RouteConfig{TLSTermination: "passthrough"}
+	if err := r.ensureRoute(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	route := &routev1.Route{}
+	if err := client.Get(ctx, types.NamespacedName{Name: "site-route", Namespace: "default"}, route); err != nil {
+		t.Fatalf("Get Route: %v", err)
+	}
+	if route.Spec.TLS.Termination != routev1.TLSTerminationPassthrough || route.Spec.Port.TargetPort.StrVal != "https" {
+		t.Errorf("expected the existing Route to switch to passthrough on https, got %+v %+v", route.Spec.TLS, route.Spec.Port)
+	}
+}
+
+func TestFrappeSiteReconciler_ensureRouteWildcardPolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(routev1.AddToScheme(scheme))
+	nginxTLS := &vyogotechv1alpha1.NginxTLSConfig{SecretName: "bench-nginx-cert"}
+
+	tests := []struct {
+		name        string
+		routeConfig *vyogotechv1alpha1.RouteConfig
+		domain      string
+		wantPolicy  routev1.WildcardPolicyType
+		wantHost    string
+		wantEvent   bool
+	}{
+		{name: "none by default", domain: "acme.example.com", wantPolicy: routev1.WildcardPolicyNone, wantHost: "acme.example.com"},
+		{
+			name:        "subdomain",
+			routeConfig: &vyogotechv1alpha1.RouteConfig{WildcardPolicy: "subdomain"},
+			domain:      "acme.example.com",
+			wantPolicy:  routev1.WildcardPolicySubdomain,
+			wantHost:    "wildcard.example.com",
+		},
+		{
+			name:        "subdomain with passthrough",
+			routeConfig: &vyogotechv1alpha1.RouteConfig{WildcardPolicy: "subdomain", TLSTermination: "passthrough"},
+			domain:      "acme.example.com",
+			wantPolicy:  routev1.WildcardPolicyNone,
+			wantHost:    "acme.example.com",
+			wantEvent:   true,
+		},
+		{
+			name:        "subdomain without parent domain",
+			routeConfig: &vyogotechv1alpha1.RouteConfig{WildcardPolicy: "subdomain"},
+			domain:      "example.com",
+			wantPolicy:  routev1.WildcardPolicyNone,
+			wantHost:    "example.com",
+			wantEvent:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &vyogotechv1alpha1.FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+				Spec: vyogotechv1alpha1.FrappeSiteSpec{
+					SiteName:    tt.domain,
+					BenchRef:    &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+					RouteConfig: tt.routeConfig,
+				},
+			}
+			bench := &vyogotechv1alpha1.FrappeBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+				Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15", NginxTLS: nginxTLS},
+			}
+			client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench).Build()
+			recorder := record.NewFakeRecorder(5)
+			r := &FrappeSiteReconciler{Client: client, Scheme: scheme, Recorder: recorder}
+			ctx := context.Background()
+
+			if err := r.ensureRoute(ctx, site, bench, tt.domain); err != nil {
+				t.Fatalf("ensureRoute: %v", err)
+			}
+			route := &routev1.Route{}
+			if err := client.Get(ctx, types.NamespacedName{Name: "site-route", Namespace: "default"}, route); err != nil {
+				t.Fatalf("Get Route: %v", err)
+			}
+			if route.Spec.WildcardPolicy != tt.wantPolicy || route.Spec.Host != tt.wantHost {
+				t.Errorf("expected %s Route for %s, got %s for %s", tt.wantPolicy, tt.wantHost, route.Spec.WildcardPolicy, route.Spec.Host)
+			}
+			if got := len(recorder.Events) > 0; got != tt.wantEvent {
+				t.Errorf("expected WildcardPolicyIgnored event: %v, got %v", tt.wantEvent, got)
+			}
+			// The site URL keeps pointing at the site's own subdomain
+			if url := siteURL(site, bench, tt.domain); url != "http://"+tt.domain {
+				t.Errorf("expected site URL for %s, got %s", tt.domain, url)
+			}
+		})
+	}
+}
+
+func TestFrappeSiteReconciler_ensureRouteRecreatesOnWildcardChange(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(routev1.AddToScheme(scheme))
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "acme.example.com",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench).Build()
+	r := &FrappeSiteReconciler{Client: client, Scheme: scheme, Recorder: record.NewFakeRecorder(5)}
+	ctx := context.Background()
+
+	if err := r.ensureRoute(ctx, site, bench, "acme.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	site.Spec.RouteConfig = &vyogotechv1alpha1.RouteConfig{WildcardPolicy: "subdomain"}
+	if err := r.ensureRoute(ctx, site, bench, "acme.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	route := &routev1.Route{}
+	if err := client.Get(ctx, types.NamespacedName{Name: "site-route", Namespace: "default"}, route); err != nil {
+		t.Fatalf("Get Route: %v", err)
+	}
+	if route.Spec.WildcardPolicy != routev1.WildcardPolicySubdomain || route.Spec.Host != "wildcard.example.com" {
+		t.Errorf("expected the Route to be recreated as a subdomain wildcard, got %s for %s", route.Spec.WildcardPolicy, route.Spec.Host)
+	}
+}
+
+func TestFrappeSiteReconciler_ExternalDNS(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(routev1.AddToScheme(scheme))
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+			DNS:      &vyogotechv1alpha1.DNSConfig{ExternalDNS: true},
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench).Build()
+	r := &FrappeSiteReconciler{Client: client, Scheme: scheme}
+	ctx := context.Background()
+
+	getIngressAnnotation := func() (string, bool) {
+		ingress := &networkingv1.Ingress{}
+		if err := client.Get(ctx, types.NamespacedName{Name: "site-ingress", Namespace: "default"}, ingress); err != nil {
+			t.Fatalf("Get Ingress: %v", err)
+		}
+		hostname, ok := ingress.Annotations[externalDNSHostnameAnnotation]
+		return hostname, ok
+	}
+
+	// The resolved domain is published by default
+	if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureIngress: %v", err)
+	}
+	if hostname, _ := getIngressAnnotation(); hostname != "site.example.com" {
+		t.Errorf("expected hostname annotation site.example.com, got %q", hostname)
+	}
+
+	// An explicit hostname updates the existing Ingress
+	site.Spec.DNS.ExternalDNSHostname = "erp.example.com"
+	if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureIngress: %v", err)
+	}
+	if hostname, _ := getIngressAnnotation(); hostname != "erp.example.com" {
+		t.Errorf("expected hostname annotation erp.example.com, got %q", hostname)
+	}
+
+	// Opting out removes the annotation
+	site.Spec.DNS.ExternalDNS = false
+	if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureIngress: %v", err)
+	}
+	if hostname, ok := getIngressAnnotation(); ok {
+		t.Errorf("expected no hostname annotation once external-dns is off, got %q", hostname)
+	}
+
+	// ...but not one the user set through ingress.annotations
+	site.Spec.Ingress = &vyogotechv1alpha1.IngressConfig{Annotations: map[string]string{externalDNSHostnameAnnotation: "manual.example.com"}}
+	ingress := &networkingv1.Ingress{}
+	if err := client.Get(ctx, types.NamespacedName{Name: "site-ingress", Namespace: "default"}, ingress); err != nil {
+		t.Fatalf("Get Ingress: %v", err)
+	}
+	ingress.Annotations[externalDNSHostnameAnnotation] = "manual.example.com"
+	if err := client.Update(ctx, ingress); err != nil {
+		t.Fatalf("Update Ingress: %v", err)
+	}
+	if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureIngress: %v", err)
+	}
+	if hostname, _ := getIngressAnnotation(); hostname != "manual.example.com" {
+		t.Errorf("expected the user's annotation to be kept, got %q", hostname)
+	}
+
+	// Routes get the same annotation
+	site.Spec.DNS.ExternalDNS = true
+	if err := r.ensureRoute(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	route := &routev1.Route{}
+	if err := client.Get(ctx, types.NamespacedName{Name: "site-route", Namespace: "default"}, route); err != nil {
+		t.Fatalf("Get Route: %v", err)
+	}
+	if hostname := route.Annotations[externalDNSHostnameAnnotation]; hostname != "erp.example.com" {
+		t.Errorf("expected route hostname annotation erp.example.com, got %q", hostname)
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	disabled := false
+	bench := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "erp"}}
+
+	tests := []struct {
+		name    string
+		ingress *vyogotechv1alpha1.IngressConfig
+		tls     bool
+		want    string
+	}{
+		{name: "public", want: "http://site.example.com"},
+		{name: "public tls", tls: true, want: "https://site.example.com"},
+		{name: "internal", ingress: &vyogotechv1alpha1.IngressConfig{Enabled: &disabled}, want: "http://bench-nginx.erp.svc:8080"},
+		{
+			name: "internal template",
+			ingress: &vyogotechv1alpha1.IngressConfig{
+				Enabled:             &disabled,
+				InternalURLTemplate: "http://{domain}.mesh.internal:{port}/{bench}",
+			},
+			want: "http://site.example.com.mesh.internal:8080/bench",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &vyogotechv1alpha1.FrappeSite{
+				ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+				Spec: vyogotechv1alpha1.FrappeSiteSpec{
+					Ingress: tt.ingress,
+					TLS:     vyogotechv1alpha1.TLSConfig{Enabled: tt.tls},
+				},
+			}
+			if got := siteURL(site, bench, "site.example.com"); got != tt.want {
+				t.Errorf("siteURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSiteURLHostHeader(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{address: "https://site.example.com", want: ""},
+		{address: "http://site.example.com:8080/", want: ""},
+		{address: "http://bench-nginx.erp.svc:8080", want: "site.example.com"},
+		{address: "http://site.example.com.mesh.internal", want: "site.example.com"},
+	}
+	for _, tt := range tests {
+		if got := siteURLHostHeader(tt.address, "site.example.com"); got != tt.want {
+			t.Errorf("siteURLHostHeader(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestSiteUsesRoute(t *testing.T) {
+	enabled, disabled := true, false
+	preferIngress := &corev1.ConfigMap{Data: map[string]string{"preferIngressOnOpenShift": "true"}}
+	tests := []struct {
+		name           string
+		isOpenShift    bool
+		routeEnabled   *bool
+		operatorConfig *corev1.ConfigMap
+		want           bool
+	}{
+		{name: "kubernetes", want: false},
+		{name: "kubernetes with routes requested", routeEnabled: &enabled, want: false},
+		{name: "openshift default", isOpenShift: true, want: true},
+		{name: "openshift site opts out", isOpenShift: true, routeEnabled: &disabled, want: false},
+		{name: "openshift prefers ingress", isOpenShift: true, operatorConfig: preferIngress, want: false},
+		{name: "openshift prefers ingress, site opts in", isOpenShift: true, routeEnabled: &enabled, operatorConfig: preferIngress, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &vyogotechv1alpha1.FrappeSite{}
+			if tt.routeEnabled != nil {
+				site.Spec.RouteConfig = &vyogotechv1alpha1.RouteConfig{Enabled: tt.routeEnabled}
+			}
+			if got := siteUsesRoute(site, tt.isOpenShift, tt.operatorConfig); got != tt.want {
+				t.Errorf("siteUsesRoute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrappeSiteReconciler_switchIngressAndRoute(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(routev1.AddToScheme(scheme))
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default", UID: "site-uid"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+			Aliases:  []string{"www.example.com"},
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	// Not owned by the site, so it is left alone
+	foreign := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "other-ingress", Namespace: "default"}}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(site, bench, foreign).Build()
+	r := &FrappeSiteReconciler{Client: client, Scheme: scheme, IsOpenShift: true}
+	ctx := context.Background()
+
+	// Route to Ingress
+	if err := r.ensureRoute(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	if err := r.ensureIngress(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureIngress: %v", err)
+	}
+	if err := r.deleteSiteRoutes(ctx, site); err != nil {
+		t.Fatalf("deleteSiteRoutes: %v", err)
+	}
+	routes := &routev1.RouteList{}
+	if err := client.List(ctx, routes); err != nil {
+		t.Fatalf("List Routes: %v", err)
+	}
+	if len(routes.Items) != 0 {
+		t.Errorf("expected the primary and alias Routes to be deleted, got %d", len(routes.Items))
+	}
+
+	// Ingress to Route
+	if err := r.ensureRoute(ctx, site, bench, "site.example.com"); err != nil {
+		t.Fatalf("ensureRoute: %v", err)
+	}
+	if err := r.deleteSiteIngress(ctx, site); err != nil {
+		t.Fatalf("deleteSiteIngress: %v", err)
+	}
+	ingresses := &networkingv1.IngressList{}
+	if err := client.List(ctx, ingresses); err != nil {
+		t.Fatalf("List Ingresses: %v", err)
+	}
+	if len(ingresses.Items) != 1 || ingresses.Items[0].Name != "other-ingress" {
+		t.Errorf("expected only the foreign Ingress to remain, got %+v", ingresses.Items)
+	}
+
+	// Without OpenShift there are no Routes to look for
+	r.IsOpenShift = false
+	if err := r.deleteSiteRoutes(ctx, site); err != nil {
+		t.Fatalf("deleteSiteRoutes: %v", err)
+	}
+	if err := client.List(ctx, routes); err != nil || len(routes.Items) != 2 {
+		t.Errorf("expected the Routes to be kept off OpenShift, got %d (%v)", len(routes.Items), err)
+	}
+}
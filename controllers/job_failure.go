@@ -0,0 +1,161 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vyogotech/frappe-operator/pkg/progress"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxFailureSummaryLength bounds the summary so it fits a status field and an event
+const maxFailureSummaryLength = 256
+
+// jobFailure is what the pod of a failed Job tells about the failure
+type jobFailure struct {
+	// Pod is the failed pod, or the most recent one; empty when the job has no pods left
+	Pod string
+	// LogTail holds the last lines of the pod's log
+	LogTail string
+	// Summary is a best-effort one-line reason, e.g. a known Frappe error
+	Summary string
+}
+
+// frappeErrorPatterns match well-known failures in bench and Frappe output. The first
+// pattern matching the latest line wins, so specific patterns come before generic ones.
+var frappeErrorPatterns = []struct {
+	pattern *regexp.Regexp
+	// summary is expanded with the submatches of pattern
+	summary string
+}{
+	{regexp.MustCompile(`Access denied for user '([^']*)'`), "Database access denied for user $1"},
+	{regexp.MustCompile(`Can't connect to (?:MySQL|MariaDB) server on '?([^' (]+)`), "Cannot connect to the database at $1"},
+	{regexp.MustCompile(`(?:psycopg2?\.)?OperationalError: (?:connection to server|could not connect).*`), "Cannot connect to the PostgreSQL database"},
+	{regexp.MustCompile(`Unknown database '([^']*)'`), "Database $1 does not exist"},
+	{regexp.MustCompile(`(?:redis\.exceptions\.)?ConnectionError: Error \d+ connecting to ([^ .]+)`), "Cannot connect to redis at $1"},
+	{regexp.MustCompile(`Site (\S+) already exists`), "Site $1 already exists"},
+	{regexp.MustCompile(`Site (\S+) does not exist`), "Site $1 does not exist"},
+	{regexp.MustCompile(`No module named '([^']+)'`), "Python module $1 not found; is the app part of the bench image?"},
+	{regexp.MustCompile(`App (\S+) not (?:found|in apps\.txt)`), "App $1 is not available on the bench"},
+	{regexp.MustCompile(`No space left on device`), "No space left on device"},
+	{regexp.MustCompile(`Permission denied: '([^']+)'`), "Permission denied on $1"},
+	{regexp.MustCompile(`frappe\.exceptions\.(\w+): (.+)`), "$1: $2"},
+	{regexp.MustCompile(`frappe\.exceptions\.(\w+)$`), "$1"},
+}
+
+// genericErrorLine matches the closing line of a Python traceback or a shell error
+var genericErrorLine = regexp.MustCompile(`^(?:[\w.]+(?:Error|Exception)(?::\s.*)?|(?:ERROR|Error|FATAL)\b.*)$`)
+
+// summarizeJobLog returns a one-line reason for a failure log: the latest line matching
+// a known Frappe error, else the latest exception or error line, else ""
+func summarizeJobLog(log string) string {
+	lines := strings.Split(log, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		for _, p := range frappeErrorPatterns {
+			if match := p.pattern.FindStringSubmatchIndex(line); match != nil {
+				return truncateSummary(string(p.pattern.ExpandString(nil, p.summary, line, match)))
+			}
+		}
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if genericErrorLine.MatchString(line) {
+			return truncateSummary(line)
+		}
+	}
+	return ""
+}
+
+// truncateSummary cuts a summary to maxFailureSummaryLength
+func truncateSummary(summary string) string {
+	if len(summary) <= maxFailureSummaryLength {
+		return summary
+	}
+	return summary[:maxFailureSummaryLength-3] + "..."
+}
+
+// podFailureReason summarizes a failed pod from its status when its log says nothing useful
+func podFailureReason(pod *corev1.Pod) string {
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+		if terminated.Reason == "OOMKilled" {
+			return fmt.Sprintf("Container %s ran out of memory (OOMKilled)", status.Name)
+		}
+		return fmt.Sprintf("Container %s exited with code %d", status.Name, terminated.ExitCode)
+	}
+	return pod.Status.Message
+}
+
+// latestJobPod returns the job's most recent failed pod, falling back to its most
+// recent pod, or nil when the job has no pods left
+func latestJobPod(ctx context.Context, c client.Client, job *batchv1.Job) (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return nil, err
+	}
+
+	var latest, latestFailed *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if latest == nil || latest.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			latest = pod
+		}
+		if pod.Status.Phase == corev1.PodFailed && (latestFailed == nil || latestFailed.CreationTimestamp.Before(&pod.CreationTimestamp)) {
+			latestFailed = pod
+		}
+	}
+	if latestFailed != nil {
+		return latestFailed, nil
+	}
+	return latest, nil
+}
+
+// readJobFailure describes why a job failed from its latest failed pod: the last lines of
+// the pod's log and a summary of them. Without a log reader the summary comes from the pod
+// status alone. A log that can't be read is returned as an error next to what is known.
+func readJobFailure(ctx context.Context, c client.Client, logs progress.LogReader, job *batchv1.Job, container string, lines int64) (*jobFailure, error) {
+	pod, err := latestJobPod(ctx, c, job)
+	if err != nil {
+		return &jobFailure{}, err
+	}
+	if pod == nil {
+		return &jobFailure{}, nil
+	}
+
+	failure := &jobFailure{Pod: pod.Name}
+	if logs != nil {
+		failure.LogTail, err = logs.TailLog(ctx, pod.Namespace, pod.Name, container, lines)
+		failure.Summary = summarizeJobLog(failure.LogTail)
+	}
+	if failure.Summary == "" {
+		failure.Summary = podFailureReason(pod)
+	}
+	return failure, err
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/progress"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSummarizeJobLog(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{"db_access_denied.log", "Database access denied for user site_user"},
+		{"db_unreachable.log", "Cannot connect to the database at mariadb.databases.svc"},
+		{"site_exists.log", "Site site.local already exists"},
+		{"missing_app.log", "Python module erpnext not found; is the app part of the bench image?"},
+		{"validation_error.log", "ValidationError: Company is mandatory"},
+		{"redis_unreachable.log", "Cannot connect to redis at bench-redis-cache:6379"},
+		{"disk_full.log", "No space left on device"},
+		{"generic_exception.log", "KeyError: 'tax_category'"},
+		{"no_error.log", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			log, err := os.ReadFile(filepath.Join("testdata", "job_logs", tt.fixture))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			if got := summarizeJobLog(string(log)); got != tt.want {
+				t.Errorf("summarizeJobLog() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeJobLog_truncatesLongLines(t *testing.T) {
+	got := summarizeJobLog("frappe.exceptions.ValidationError: " + strings.Repeat("x", 500))
+	if len(got) != maxFailureSummaryLength || !strings.HasSuffix(got, "...") {
+		t.Errorf("expected a summary truncated to %d characters, got %d: %q", maxFailureSummaryLength, len(got), got)
+	}
+}
+
+func TestReadJobFailure(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "site-init", Namespace: "default"}}
+	older := metav1.NewTime(time.Now().Add(-time.Minute))
+	failedPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-init-a", Namespace: "default", Labels: map[string]string{"job-name": "site-init"}, CreationTimestamp: older},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "site-init",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+			}},
+		},
+	}
+	pendingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-init-b", Namespace: "default", Labels: map[string]string{"job-name": "site-init"}, CreationTimestamp: metav1.Now()},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	c := fake.NewClientBuilder().WithScheme(newPausedTestScheme()).WithObjects(failedPod, pendingPod).Build()
+	ctx := context.Background()
+
+	failure, err := readJobFailure(ctx, c, &fakeLogReader{logs: "Creating site\nSite site.local already exists\n"}, job, "", 50)
+	if err != nil {
+		t.Fatalf("readJobFailure: %v", err)
+	}
+	if failure.Pod != "site-init-a" {
+		t.Errorf("expected the failed pod, got %q", failure.Pod)
+	}
+	if failure.Summary != "Site site.local already exists" || !strings.Contains(failure.LogTail, "Creating site") {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+
+	// Without a log, or without a log reader, the pod status explains the failure
+	for _, logs := range []progress.LogReader{&fakeLogReader{logs: "Killed\n"}, nil} {
+		failure, err := readJobFailure(ctx, c, logs, job, "", 50)
+		if err != nil {
+			t.Fatalf("readJobFailure: %v", err)
+		}
+		if failure.Summary != "Container site-init ran out of memory (OOMKilled)" {
+			t.Errorf("expected the OOMKilled reason, got %q", failure.Summary)
+		}
+	}
+}
+
+func TestReconcile_siteInitFailureSummary(t *testing.T) {
+	site, bench, secret := newExternalDBTestObjects()
+	site.Finalizers = []string{frappeSiteFinalizer}
+	site.Spec.Apps = []string{"erpnext"}
+	bench.Status.Phase = "Ready"
+	initJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-init", Namespace: "default"},
+		Status:     batchv1.JobStatus{Failed: 3},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-init-x", Namespace: "default", Labels: map[string]string{"job-name": "site-init"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodFailed},
+	}
+	log, err := os.ReadFile(filepath.Join("testdata", "job_logs", "missing_app.log"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	base, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(base.Scheme).WithObjects(site, bench, secret, initJob, pod).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{Client: c, Scheme: base.Scheme, Recorder: record.NewFakeRecorder(20), LogReader: &fakeLogReader{logs: string(log)}}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "site", Namespace: "default"}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err == nil {
+		t.Fatal("expected the failed init job to fail the reconcile")
+	}
+	updated := &vyogotechv1alpha1.FrappeSite{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	want := "Python module erpnext not found; is the app part of the bench image?"
+	if updated.Status.InitFailureSummary != want {
+		t.Errorf("expected initFailureSummary %q, got %q", want, updated.Status.InitFailureSummary)
+	}
+	if !strings.Contains(updated.Status.InitFailureLog, "ModuleNotFoundError") {
+		t.Errorf("expected the log tail in initFailureLog, got %q", updated.Status.InitFailureLog)
+	}
+	if updated.Status.AppInstallationStatus != "Failed to install apps: "+want {
+		t.Errorf("unexpected appInstallationStatus %q", updated.Status.AppInstallationStatus)
+	}
+}
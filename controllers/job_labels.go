@@ -0,0 +1,98 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels set on every operator-created Job and its pods, so log aggregators can filter
+// job logs by operation, bench and site
+const (
+	jobOperationLabel = "frappe.tech/operation"
+	jobBenchLabel     = "frappe.tech/bench"
+	jobSiteLabel      = "frappe.tech/site"
+)
+
+// Job operations, the values of jobOperationLabel
+const (
+	jobOperationInit             = "init"
+	jobOperationConfigSync       = "config-sync"
+	jobOperationCommonSiteConfig = "common-site-config"
+	jobOperationMigrate          = "migrate"
+	jobOperationAppVersions      = "app-versions"
+	jobOperationCORS             = "cors"
+	jobOperationSiteConfig       = "site-config"
+	jobOperationDBCredentials    = "db-credentials"
+	jobOperationMaintenance      = "maintenance-mode"
+	jobOperationUninstallApps    = "uninstall-apps"
+	jobOperationHealthCheck      = "health-check"
+	jobOperationDelete           = "delete"
+	jobOperationBackup           = "backup"
+	jobOperationRestore          = "restore"
+	jobOperationCommand          = "command"
+	jobOperationWorkspace        = "workspace"
+	jobOperationDashboardChart   = "dashboard-chart"
+	jobOperationUser             = "user"
+)
+
+// jobLabels returns the labels for a Job running operation on a bench and, for site
+// operations, on the Frappe site siteName
+func jobLabels(operation, bench, siteName string) map[string]string {
+	labels := map[string]string{
+		jobOperationLabel: operation,
+		jobBenchLabel:     jobLabelValue(bench),
+	}
+	if siteName != "" {
+		labels[jobSiteLabel] = jobLabelValue(siteName)
+	}
+	return labels
+}
+
+// jobLabelValue shortens a name to the 63 characters a label value may hold
+func jobLabelValue(value string) string {
+	if len(value) <= validation.LabelValueMaxLength {
+		return value
+	}
+	return strings.TrimRight(value[:validation.LabelValueMaxLength], "-_.")
+}
+
+// labelJob adds labels to a Job and its pod template
+func labelJob(job *batchv1.Job, labels map[string]string) {
+	job.Labels = mergeLabels(job.Labels, labels)
+	job.Spec.Template.Labels = mergeLabels(job.Spec.Template.Labels, labels)
+}
+
+// labelCronJob adds labels to a CronJob, the Jobs it creates and their pod template
+func labelCronJob(cronJob *batchv1.CronJob, labels map[string]string) {
+	cronJob.Labels = mergeLabels(cronJob.Labels, labels)
+	cronJob.Spec.JobTemplate.Labels = mergeLabels(cronJob.Spec.JobTemplate.Labels, labels)
+	cronJob.Spec.JobTemplate.Spec.Template.Labels = mergeLabels(cronJob.Spec.JobTemplate.Spec.Template.Labels, labels)
+}
+
+func mergeLabels(existing, labels map[string]string) map[string]string {
+	if existing == nil {
+		existing = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		existing[k] = v
+	}
+	return existing
+}
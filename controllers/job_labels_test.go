@@ -0,0 +1,102 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestJobLabels(t *testing.T) {
+	labels := jobLabels(jobOperationInit, "bench", "")
+	if _, ok := labels[jobSiteLabel]; ok {
+		t.Errorf("bench jobs should not get a site label, got %v", labels)
+	}
+
+	long := strings.Repeat("a", 60) + ".example.com"
+	value := jobLabels(jobOperationBackup, "bench", long)[jobSiteLabel]
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		t.Errorf("expected a valid label value for %q, got %q: %v", long, value, errs)
+	}
+}
+
+// assertJobLabels checks the operation, bench and site labels on a job's metadata and pod template
+func assertJobLabels(t *testing.T, name string, jobMeta, podMeta metav1.ObjectMeta, operation, site string) {
+	t.Helper()
+	want := jobLabels(operation, "bench", site)
+	for _, m := range []metav1.ObjectMeta{jobMeta, podMeta} {
+		for k, v := range want {
+			if m.Labels[k] != v {
+				t.Errorf("%s: expected label %s=%s, got %v", name, k, v, m.Labels)
+			}
+		}
+	}
+}
+
+func TestJobLabelsOnOperatorJobs(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	benchReconciler := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if _, err := benchReconciler.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+		t.Fatalf("ensureBenchInitialized: %v", err)
+	}
+	benchInit := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-init", Namespace: "default"}, benchInit); err != nil {
+		t.Fatalf("Get bench-init Job: %v", err)
+	}
+	assertJobLabels(t, "bench-init", benchInit.ObjectMeta, benchInit.Spec.Template.ObjectMeta, jobOperationInit, "")
+
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	if _, err := siteReconciler.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	siteInit := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, siteInit); err != nil {
+		t.Fatalf("Get site-init Job: %v", err)
+	}
+	assertJobLabels(t, "site-init", siteInit.ObjectMeta, siteInit.Spec.Template.ObjectMeta, jobOperationInit, "site.local")
+
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", Schedule: "0 2 * * *"},
+	}
+	backupReconciler := &SiteBackupReconciler{Client: c, Scheme: siteReconciler.Scheme}
+	backupJob := backupReconciler.buildBackupJob(siteBackup, bench)
+	assertJobLabels(t, "backup", backupJob.ObjectMeta, backupJob.Spec.Template.ObjectMeta, jobOperationBackup, "site.local")
+	cronJob := backupReconciler.buildBackupCronJob(siteBackup, bench)
+	assertJobLabels(t, "scheduled backup", cronJob.Spec.JobTemplate.ObjectMeta, cronJob.Spec.JobTemplate.Spec.Template.ObjectMeta, jobOperationBackup, "site.local")
+
+	siteRestore := &vyogotechv1alpha1.SiteRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteRestoreSpec{Site: "site.local"},
+	}
+	restoreReconciler := &SiteRestoreReconciler{Client: c, Scheme: siteReconciler.Scheme}
+	restoreJob := restoreReconciler.buildRestoreJob(siteRestore, bench)
+	assertJobLabels(t, "restore", restoreJob.ObjectMeta, restoreJob.Spec.Template.ObjectMeta, jobOperationRestore, "site.local")
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/progress"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// progressPollInterval bounds how often job logs are read (and status written) for progress
+	progressPollInterval = 30 * time.Second
+
+	// progressTailLines is how much of the job log is read when looking for the latest marker
+	progressTailLines = 20
+)
+
+// readJobProgress returns the latest progress marker printed by the running pod of a job,
+// or nil when the pod hasn't started or hasn't reported anything yet
+func readJobProgress(ctx context.Context, c client.Client, logs progress.LogReader, job *batchv1.Job, container string) (*vyogotechv1alpha1.OperationProgress, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		tail, err := logs.TailLog(ctx, pod.Namespace, pod.Name, container, progressTailLines)
+		if err != nil {
+			return nil, err
+		}
+		report, found := progress.Parse(tail)
+		if !found {
+			return nil, nil
+		}
+		percent := report.Percent()
+		// Totals are estimates; never claim completion before the job does
+		if percent > 99 {
+			percent = 99
+		}
+		return &vyogotechv1alpha1.OperationProgress{
+			Stage:          report.Stage,
+			Percent:        percent,
+			BytesProcessed: report.BytesProcessed,
+			BytesTotal:     report.BytesTotal,
+			LastUpdated:    metav1.Now(),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// progressChanged reports whether the new progress is worth a status write
+func progressChanged(current, next *vyogotechv1alpha1.OperationProgress) bool {
+	if next == nil {
+		return false
+	}
+	return current == nil || current.Stage != next.Stage || current.Percent != next.Percent
+}
+
+// completeProgress marks recorded progress as finished when its job succeeds
+func completeProgress(p *vyogotechv1alpha1.OperationProgress) {
+	if p == nil {
+		return
+	}
+	p.Percent = 100
+	p.LastUpdated = metav1.Now()
+}
+
+// jobExitCode returns the exit code of the first container of the pod that decided a
+// finished job: its latest succeeded pod, or its latest failed pod. nil when that pod
+// is gone or was killed before its container terminated.
+func jobExitCode(ctx context.Context, c client.Client, job *batchv1.Job) (*int32, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return nil, err
+	}
+
+	phase := corev1.PodFailed
+	if job.Status.Succeeded > 0 {
+		phase = corev1.PodSucceeded
+	}
+	var latest *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == phase && (latest == nil || latest.CreationTimestamp.Before(&pod.CreationTimestamp)) {
+			latest = pod
+		}
+	}
+	if latest == nil || len(latest.Status.ContainerStatuses) == 0 {
+		return nil, nil
+	}
+	terminated := latest.Status.ContainerStatuses[0].State.Terminated
+	if terminated == nil {
+		return nil, nil
+	}
+	exitCode := terminated.ExitCode
+	return &exitCode, nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeLogReader returns canned logs
+type fakeLogReader struct {
+	logs string
+}
+
+func (f *fakeLogReader) TailLog(ctx context.Context, namespace, pod, container string, lines int64) (string, error) {
+	return f.logs, nil
+}
+
+func TestSiteBackupProgress(t *testing.T) {
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local"},
+		Status:     vyogotechv1alpha1.SiteBackupStatus{Phase: "Running"},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "backup-backup", Namespace: "default"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-backup-xyz", Namespace: "default", Labels: map[string]string{"job-name": job.Name}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(siteBackup, job, pod).
+		WithStatusSubresource(&vyogotechv1alpha1.SiteBackup{}).Build()
+	logs := &fakeLogReader{logs: "Starting backup\nFRAPPE_PROGRESS 256 1024 Backing up\n"}
+	r := &SiteBackupReconciler{Client: c, Scheme: scheme, LogReader: logs}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "backup", Namespace: "default"}
+
+	result, err := r.updateSiteBackupProgress(ctx, siteBackup, job)
+	if err != nil {
+		t.Fatalf("updateSiteBackupProgress: %v", err)
+	}
+	if result.RequeueAfter != progressPollInterval {
+		t.Errorf("expected requeue after %v, got %v", progressPollInterval, result.RequeueAfter)
+	}
+	updated := &vyogotechv1alpha1.SiteBackup{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get SiteBackup: %v", err)
+	}
+	p := updated.Status.Progress
+	if p == nil || p.Percent != 25 || p.BytesProcessed != 256 || p.BytesTotal != 1024 || p.Stage != "Backing up" {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+
+	// Unchanged progress must not trigger another status write
+	version := updated.ResourceVersion
+	if _, err := r.updateSiteBackupProgress(ctx, updated, job); err != nil {
+		t.Fatalf("updateSiteBackupProgress: %v", err)
+	}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get SiteBackup: %v", err)
+	}
+	if updated.ResourceVersion != version {
+		t.Error("expected no status write when progress is unchanged")
+	}
+
+	// Estimates may overshoot; a running job never reports 100%
+	logs.logs = "FRAPPE_PROGRESS 2048 1024 Backing up\n"
+	if _, err := r.updateSiteBackupProgress(ctx, updated, job); err != nil {
+		t.Fatalf("updateSiteBackupProgress: %v", err)
+	}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get SiteBackup: %v", err)
+	}
+	if updated.Status.Progress.Percent != 99 {
+		t.Errorf("expected running progress capped at 99, got %d", updated.Status.Progress.Percent)
+	}
+
+	if err := r.updateSiteBackupStatus(ctx, updated, "Succeeded", "Backup completed successfully", job.Name); err != nil {
+		t.Fatalf("updateSiteBackupStatus: %v", err)
+	}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get SiteBackup: %v", err)
+	}
+	if updated.Status.Progress.Percent != 100 {
+		t.Errorf("expected 100%% after success, got %d", updated.Status.Progress.Percent)
+	}
+}
+
+func TestRestoreScriptEmitsProgress(t *testing.T) {
+	r := &SiteRestoreReconciler{}
+	siteRestore := &vyogotechv1alpha1.SiteRestore{
+		Spec: vyogotechv1alpha1.SiteRestoreSpec{
+			Site: "site.local",
+			DatabaseBackupSource: vyogotechv1alpha1.BackupSource{
+				S3: &vyogotechv1alpha1.S3DownloadConfig{Key: "db.sql.gz"},
+			},
+		},
+	}
+	script := r.buildRestoreScript(siteRestore)
+	for _, want := range []string{"Callback=report", `print(f"FRAPPE_PROGRESS {state['done']} {size} {stage}"`, "FRAPPE_PROGRESS 0 0 Importing"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected restore script to contain %q", want)
+		}
+	}
+}
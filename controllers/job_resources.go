@@ -0,0 +1,116 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// siteJobResources returns the resource preset for a site's init, migrate, backup and
+// restore jobs. Big sites need the memory to dump and import their database; without a
+// sizeHint jobs keep running without requests or limits.
+func siteJobResources(sizeHint string) corev1.ResourceRequirements {
+	switch sizeHint {
+	case "small":
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("200m"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		}
+	case "medium":
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		}
+	case "large":
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("2Gi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		}
+	}
+	return corev1.ResourceRequirements{}
+}
+
+// sizeHintPresets ranks the sizeHint presets from smallest to largest
+var sizeHintPresets = []string{"small", "medium", "large"}
+
+// largerSizeHint returns whichever of a and b names the larger preset
+func largerSizeHint(a, b string) string {
+	if slices.Index(sizeHintPresets, b) > slices.Index(sizeHintPresets, a) {
+		return b
+	}
+	return a
+}
+
+// findSiteByName returns the FrappeSite serving siteName in namespace, or nil when there
+// is no such FrappeSite
+func findSiteByName(ctx context.Context, c client.Client, namespace, siteName string) (*vyogotechv1alpha1.FrappeSite, error) {
+	sites := &vyogotechv1alpha1.FrappeSiteList{}
+	if err := c.List(ctx, sites, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range sites.Items {
+		if sites.Items[i].Spec.SiteName == siteName {
+			return &sites.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// findReadySiteBench returns the FrappeSite serving siteName in namespace and its bench,
+// or nils when there is no such FrappeSite or it isn't Ready
+func findReadySiteBench(ctx context.Context, c client.Client, namespace, siteName string) (*vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench, error) {
+	site, err := findSiteByName(ctx, c, namespace, siteName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if site == nil || site.Spec.BenchRef == nil || site.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseReady {
+		return nil, nil, nil
+	}
+	benchNamespace := site.Spec.BenchRef.Namespace
+	if benchNamespace == "" {
+		benchNamespace = site.Namespace
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{}
+	if err := c.Get(ctx, client.ObjectKey{Name: site.Spec.BenchRef.Name, Namespace: benchNamespace}, bench); err != nil {
+		return nil, nil, err
+	}
+	return site, bench, nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSiteJobResources(t *testing.T) {
+	if got := siteJobResources(""); got.Requests != nil || got.Limits != nil {
+		t.Errorf("expected no resources without a sizeHint, got %+v", got)
+	}
+
+	// Each preset must allow more memory than the one below it
+	previous := int64(0)
+	for _, hint := range []string{"small", "medium", "large"} {
+		res := siteJobResources(hint)
+		if res.Requests.Memory().IsZero() || res.Limits.Memory().Cmp(*res.Requests.Memory()) < 0 {
+			t.Errorf("%s: expected memory limit >= request, got %+v", hint, res)
+		}
+		if limit := res.Limits.Memory().Value(); limit <= previous {
+			t.Errorf("%s: expected memory limit above the smaller preset, got %d", hint, limit)
+		} else {
+			previous = limit
+		}
+	}
+}
+
+func TestLargerSizeHint(t *testing.T) {
+	for _, tt := range []struct{ a, b, want string }{
+		{"", "", ""},
+		{"", "small", "small"},
+		{"large", "medium", "large"},
+		{"small", "medium", "medium"},
+		{"medium", "", "medium"},
+	} {
+		if got := largerSizeHint(tt.a, tt.b); got != tt.want {
+			t.Errorf("largerSizeHint(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureBenchMigrated_sizedForLargestSite(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.SizeHint = "small"
+	big := site.DeepCopy()
+	big.Name = "big"
+	big.Spec.SiteName = "big.local"
+	big.Spec.SizeHint = "medium"
+	// Sites of another bench don't size this bench's migration
+	other := site.DeepCopy()
+	other.Name = "other-bench-site"
+	other.Spec.BenchRef = &vyogotechv1alpha1.NamespacedName{Name: "other"}
+	other.Spec.SizeHint = "large"
+	bench.Status.MigratedImage = "docker.io/frappe/erpnext:14"
+	siteReconciler, c := newInitJobTestReconciler(site, big, other, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureBenchMigrated(ctx, bench); err != nil {
+		t.Fatalf("ensureBenchMigrated: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-migrate", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	want := siteJobResources("medium")
+	if got := job.Spec.Template.Spec.Containers[0].Resources; !got.Limits.Memory().Equal(*want.Limits.Memory()) {
+		t.Errorf("expected the medium preset of the bench's biggest site, got %+v", got)
+	}
+}
+
+func TestSiteBackupReconciler_sizeHint(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.SizeHint = "large"
+	site.Spec.BenchRef.Namespace = "default"
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local"},
+	}
+	siteReconciler, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).
+		WithObjects(site, bench, siteBackup).WithStatusSubresource(siteBackup).Build()
+	r := &SiteBackupReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	// The first reconcile adds the finalizer, the second creates the job
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "backup", Namespace: "default"}}); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+	}
+
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "backup-backup", Namespace: "default"}, job); err != nil {
+		t.Fatalf("expected backup job: %v", err)
+	}
+	limit := job.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory]
+	want := siteJobResources("large").Limits[corev1.ResourceMemory]
+	if limit.Cmp(want) != 0 {
+		t.Errorf("expected large preset memory limit %s, got %s", want.String(), limit.String())
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// errJobFailed marks a job run by runSiteJob that gave up
+var errJobFailed = errors.New("job failed")
+
+// siteJobKey is the state a job run by runSiteJob applies, recorded on the job in
+// annotation. A zero siteJobKey runs the job once, whatever the spec says.
+type siteJobKey struct {
+	annotation string
+	value      string
+}
+
+// runSiteJob drives the one-shot job name of owner, e.g. `<site>-site-config`. A job
+// applying another key than the current one is deleted, so the next reconcile creates
+// one for the current state. Without a job, build returns the one to create and
+// runSiteJob records the key on it. Returns the job once it has succeeded, nil while it
+// is created or running, and an error wrapping errJobFailed once it gave up.
+func runSiteJob(ctx context.Context, c client.Client, owner client.Object, name string, key siteJobKey, build func() (*batchv1.Job, error)) (*batchv1.Job, error) {
+	logger := log.FromContext(ctx)
+
+	job := &batchv1.Job{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: owner.GetNamespace()}, job)
+	if apierrors.IsNotFound(err) {
+		job, err = build()
+		if err != nil {
+			return nil, err
+		}
+		if key.annotation != "" {
+			if job.Annotations == nil {
+				job.Annotations = map[string]string{}
+			}
+			job.Annotations[key.annotation] = key.value
+		}
+		logger.Info("Creating job", "job", name)
+		return nil, c.Create(ctx, job)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if key.annotation != "" && job.Annotations[key.annotation] != key.value {
+		// Left over from an earlier change
+		logger.Info("Replacing stale job", "job", name)
+		return nil, client.IgnoreNotFound(c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	}
+	if jobFailed(job) {
+		return nil, fmt.Errorf("%w: %s", errJobFailed, name)
+	}
+	if job.Status.Succeeded == 0 {
+		return nil, nil
+	}
+	return job, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRunSiteJob(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	_, c := newInitJobTestReconciler(site, bench)
+	ctx := context.Background()
+	jobKey := types.NamespacedName{Name: "site-test", Namespace: "default"}
+	builds := 0
+	build := func() (*batchv1.Job, error) {
+		builds++
+		return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: jobKey.Name, Namespace: jobKey.Namespace}}, nil
+	}
+	run := func(value string) (*batchv1.Job, error) {
+		return runSiteJob(ctx, c, site, jobKey.Name, siteJobKey{annotation: "frappe.tech/test", value: value}, build)
+	}
+
+	if job, err := run("a"); job != nil || err != nil {
+		t.Fatalf("expected the job to be created, got job=%v err=%v", job, err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Annotations["frappe.tech/test"] != "a" {
+		t.Errorf("expected the key on the job, got %v", job.Annotations)
+	}
+
+	// A failed pod the job still retries is not a failed job
+	job.Status.Failed = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	if got, err := run("a"); got != nil || err != nil {
+		t.Fatalf("expected the retrying job to be running, got job=%v err=%v", got, err)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	if got, err := run("a"); got == nil || err != nil {
+		t.Fatalf("expected the succeeded job, got job=%v err=%v", got, err)
+	}
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	if _, err := run("a"); !errors.Is(err, errJobFailed) {
+		t.Fatalf("expected errJobFailed, got %v", err)
+	}
+
+	// A job for another key is replaced, whatever its state
+	if got, err := run("b"); got != nil || err != nil {
+		t.Fatalf("expected the stale job to be deleted, got job=%v err=%v", got, err)
+	}
+	if err := c.Get(ctx, jobKey, job); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the stale job to be gone, got %v", err)
+	}
+	if _, err := run("b"); err != nil {
+		t.Fatalf("runSiteJob: %v", err)
+	}
+	if builds != 2 {
+		t.Errorf("expected two builds, got %d", builds)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// policyViolationCondition is set on benches and sites that lack the operator's required labels
+const policyViolationCondition = "PolicyViolation"
+
+// labelPolicy is the required-label policy from the operator ConfigMap
+// (requiredLabels: comma-separated label keys, labelPolicyMode: warn or block)
+type labelPolicy struct {
+	requiredLabels []string
+	block          bool
+}
+
+// labelPolicyFromConfig reads the label policy; a missing ConfigMap means no policy
+func labelPolicyFromConfig(operatorConfig *corev1.ConfigMap) labelPolicy {
+	policy := labelPolicy{}
+	if operatorConfig == nil {
+		return policy
+	}
+	for _, key := range strings.Split(operatorConfig.Data["requiredLabels"], ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			policy.requiredLabels = append(policy.requiredLabels, key)
+		}
+	}
+	policy.block = strings.TrimSpace(operatorConfig.Data["labelPolicyMode"]) == "block"
+	return policy
+}
+
+// evaluate records the PolicyViolation condition for obj and returns the missing labels
+// and whether the conditions changed. Objects never flagged get no condition at all.
+func (p labelPolicy) evaluate(obj metav1.Object, conditions *[]metav1.Condition) ([]string, bool) {
+	var missing []string
+	for _, key := range p.requiredLabels {
+		if obj.GetLabels()[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		return missing, meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               policyViolationCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "MissingRequiredLabels",
+			Message:            fmt.Sprintf("Missing required labels: %s", strings.Join(missing, ", ")),
+			ObservedGeneration: obj.GetGeneration(),
+		})
+	}
+	if meta.FindStatusCondition(*conditions, policyViolationCondition) == nil {
+		return nil, false
+	}
+	return nil, meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               policyViolationCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Compliant",
+		Message:            "All required labels are present",
+		ObservedGeneration: obj.GetGeneration(),
+	})
+}
+
+// blocks reports whether provisioning must wait for the missing labels. Only block mode
+// holds anything back, and never a resource that has already been provisioned.
+func (p labelPolicy) blocks(missing []string, provisioned bool) bool {
+	return p.block && len(missing) > 0 && !provisioned
+}
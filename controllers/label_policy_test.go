@@ -0,0 +1,123 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLabelPolicyEvaluate(t *testing.T) {
+	policy := labelPolicyFromConfig(&corev1.ConfigMap{Data: map[string]string{"requiredLabels": " cost-center, team ,"}})
+	if len(policy.requiredLabels) != 2 || policy.block {
+		t.Fatalf("expected two required labels in warn mode, got %+v", policy)
+	}
+
+	site, _ := newInitJobTestObjects()
+	site.Labels = map[string]string{"team": "erp", "cost-center": ""}
+	missing, changed := policy.evaluate(site, &site.Status.Conditions)
+	if len(missing) != 1 || missing[0] != "cost-center" || !changed {
+		t.Fatalf("expected cost-center to be missing, got %v (changed=%v)", missing, changed)
+	}
+	if !meta.IsStatusConditionTrue(site.Status.Conditions, policyViolationCondition) {
+		t.Error("expected PolicyViolation=True")
+	}
+	if policy.blocks(missing, false) {
+		t.Error("warn mode must not block provisioning")
+	}
+
+	site.Labels["cost-center"] = "cc-42"
+	if missing, changed := policy.evaluate(site, &site.Status.Conditions); len(missing) != 0 || !changed {
+		t.Errorf("expected violation to clear, got %v (changed=%v)", missing, changed)
+	}
+	if !meta.IsStatusConditionFalse(site.Status.Conditions, policyViolationCondition) {
+		t.Error("expected PolicyViolation=False once compliant")
+	}
+
+	// Compliant objects that were never flagged get no condition
+	_, bench := newInitJobTestObjects()
+	bench.Labels = site.Labels
+	if _, changed := policy.evaluate(bench, &bench.Status.Conditions); changed || len(bench.Status.Conditions) != 0 {
+		t.Errorf("expected no condition on a compliant bench, got %v", bench.Status.Conditions)
+	}
+
+	// A missing ConfigMap enforces nothing
+	if _, changed := labelPolicyFromConfig(nil).evaluate(&vyogotechv1alpha1.FrappeSite{}, &site.Status.Conditions); changed {
+		t.Error("expected no policy without a ConfigMap")
+	}
+}
+
+func TestLabelPolicyBlocksOnlyUnprovisionedSites(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	operatorConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "frappe-operator-config", Namespace: "frappe-operator-system"},
+		Data:       map[string]string{"requiredLabels": "team", "labelPolicyMode": "block"},
+	}
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(site, bench, operatorConfig).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "site", Namespace: "default"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	got := &vyogotechv1alpha1.FrappeSite{}
+	if err := c.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if got.Status.Phase != vyogotechv1alpha1.FrappeSitePhasePending {
+		t.Errorf("expected unlabelled site to be held in Pending, got %q", got.Status.Phase)
+	}
+	if ready := meta.FindStatusCondition(got.Status.Conditions, "Ready"); ready == nil || ready.Reason != "PolicyViolation" {
+		t.Errorf("expected Ready=False with reason PolicyViolation, got %+v", ready)
+	}
+
+	// An already provisioned site is flagged but keeps running
+	got.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseReady
+	got.Status.ObservedGeneration = got.Generation
+	got.Status.Conditions = nil
+	if err := c.Status().Update(ctx, got); err != nil {
+		t.Fatalf("update site status: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if got.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseReady {
+		t.Errorf("expected Ready site to stay Ready, got %q", got.Status.Phase)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, policyViolationCondition) {
+		t.Error("expected Ready site to be flagged with PolicyViolation")
+	}
+}
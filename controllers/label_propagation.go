@@ -0,0 +1,102 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// propagatePrefix marks FrappeBench and FrappeSite labels and annotations that are copied,
+// without the prefix, onto the objects and pods the operator creates for them, e.g.
+// frappe.tech/propagate-cost-center: finance becomes cost-center: finance
+const propagatePrefix = "frappe.tech/propagate-"
+
+// managedLabelKeys are the labels the operator selects its objects by; propagated and
+// podConfig labels never override them
+var managedLabelKeys = map[string]bool{
+	"app":       true,
+	"bench":     true,
+	"component": true,
+	"site":      true,
+}
+
+// propagated returns the propagatePrefix entries of m with the prefix removed
+func propagated(m map[string]string) map[string]string {
+	var out map[string]string
+	for k, v := range m {
+		key := strings.TrimPrefix(k, propagatePrefix)
+		if key == k || key == "" || managedLabelKeys[key] {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[key] = v
+	}
+	return out
+}
+
+// withoutManagedLabels drops the operator-managed keys from user-supplied labels
+func withoutManagedLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if !managedLabelKeys[k] {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// ownedLabels returns the labels of an object created for owner: the owner's propagated
+// labels overlaid with the operator's own
+func ownedLabels(owner metav1.Object, operatorLabels map[string]string) map[string]string {
+	return resources.MergeLabels(propagated(owner.GetLabels()), operatorLabels)
+}
+
+// syncOwnedLabels merges labels into an existing object's metadata and, when template is
+// set, its pod template's, so labels added to the owner after the object was created
+// reach it too. Selectors are immutable and left alone, and labels the owner no longer
+// carries stay on the object.
+func syncOwnedLabels(meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec, labels map[string]string) bool {
+	metas := []*metav1.ObjectMeta{meta}
+	if template != nil {
+		metas = append(metas, &template.ObjectMeta)
+	}
+	changed := false
+	for _, m := range metas {
+		for k, v := range labels {
+			if current, ok := m.Labels[k]; ok && current == v {
+				continue
+			}
+			if m.Labels == nil {
+				m.Labels = make(map[string]string)
+			}
+			m.Labels[k] = v
+			changed = true
+		}
+	}
+	return changed
+}
+
+// propagatedAnnotations returns the owner's annotations to set on the pods created for it
+func propagatedAnnotations(owner metav1.Object) map[string]string {
+	return propagated(owner.GetAnnotations())
+}
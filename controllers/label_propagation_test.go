@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestPropagated(t *testing.T) {
+	got := propagated(map[string]string{
+		"frappe.tech/propagate-cost-center": "finance",
+		"frappe.tech/propagate-app":         "other",
+		"frappe.tech/propagate-":            "empty",
+		"team":                              "payments",
+	})
+	want := map[string]string{"cost-center": "finance"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("propagated() = %v, want %v", got, want)
+	}
+	if got := propagated(map[string]string{"team": "payments"}); got != nil {
+		t.Errorf("expected nil without propagated keys, got %v", got)
+	}
+}
+
+func TestLabelPropagation_benchDeployment(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Labels = map[string]string{
+		"frappe.tech/propagate-cost-center": "finance",
+		"frappe.tech/propagate-bench":       "other",
+		"unrelated":                         "skip",
+	}
+	bench.Annotations = map[string]string{"frappe.tech/propagate-owner": "team-a"}
+	bench.Spec.PodConfig = &vyogotechv1alpha1.PodConfig{Labels: map[string]string{"tier": "web", "app": "other"}}
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("Get deployment: %v", err)
+	}
+
+	want := map[string]string{"app": "frappe", "bench": "bench", "cost-center": "finance", "tier": "web"}
+	for name, labels := range map[string]map[string]string{
+		"deployment": deploy.Labels,
+		"pod":        deploy.Spec.Template.Labels,
+	} {
+		for k, v := range want {
+			if labels[k] != v {
+				t.Errorf("%s label %s: got %q, want %q", name, k, labels[k], v)
+			}
+		}
+		if _, ok := labels["unrelated"]; ok {
+			t.Errorf("%s: expected labels without the propagate prefix to stay on the bench", name)
+		}
+	}
+	if deploy.Spec.Template.Labels["component"] != "gunicorn" {
+		t.Errorf("expected component label gunicorn, got %q", deploy.Spec.Template.Labels["component"])
+	}
+	if got := deploy.Spec.Template.Annotations["owner"]; got != "team-a" {
+		t.Errorf("expected propagated pod annotation owner=team-a, got %q", got)
+	}
+}
+
+func TestLabelPropagation_existingObjects(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := r.ensureGunicornService(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornService: %v", err)
+	}
+
+	// Labels added after the objects were created reach them on the next reconcile
+	bench.Labels = map[string]string{"frappe.tech/propagate-cost-center": "finance"}
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := r.ensureGunicornService(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornService: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("Get deployment: %v", err)
+	}
+	if deploy.Labels["cost-center"] != "finance" || deploy.Spec.Template.Labels["cost-center"] != "finance" {
+		t.Errorf("expected the deployment and its pods to pick up cost-center, got %v and %v", deploy.Labels, deploy.Spec.Template.Labels)
+	}
+	if _, ok := deploy.Spec.Selector.MatchLabels["cost-center"]; ok {
+		t.Errorf("expected the selector to be left alone, got %v", deploy.Spec.Selector.MatchLabels)
+	}
+	svc := &corev1.Service{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, svc); err != nil {
+		t.Fatalf("Get service: %v", err)
+	}
+	if svc.Labels["cost-center"] != "finance" {
+		t.Errorf("expected the service to pick up cost-center, got %v", svc.Labels)
+	}
+	if _, ok := svc.Spec.Selector["cost-center"]; ok {
+		t.Errorf("expected the service selector to be left alone, got %v", svc.Spec.Selector)
+	}
+}
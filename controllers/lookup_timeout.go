@@ -0,0 +1,107 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+)
+
+// defaultLookupTimeout bounds API calls against CRD groups owned by optional operators
+// (MariaDB, KEDA) when the reconciler doesn't set LookupTimeout
+const defaultLookupTimeout = 10 * time.Second
+
+// lookupTimeoutRequeue is how long a reconcile waits after such a lookup timed out
+const lookupTimeoutRequeue = 30 * time.Second
+
+// errLookupTimeout marks a lookup that a degraded optional API didn't answer in time
+var errLookupTimeout = errors.New("lookup timed out")
+
+// withLookupTimeout runs lookup with a context bounded by timeout (defaultLookupTimeout
+// when zero), so a slow aggregated or CRD API can't stall the reconcile. A lookup that
+// runs out of time returns an error wrapping errLookupTimeout.
+func withLookupTimeout(ctx context.Context, timeout time.Duration, what string, lookup func(context.Context) error) error {
+	if timeout <= 0 {
+		timeout = defaultLookupTimeout
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := lookup(lookupCtx)
+	if err != nil && ctx.Err() == nil && (errors.Is(err, context.DeadlineExceeded) || lookupCtx.Err() == context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s did not respond within %s", errLookupTimeout, what, timeout)
+	}
+	return err
+}
+
+// isLookupTimeout reports whether err comes from a lookup that timed out
+func isLookupTimeout(err error) bool {
+	return errors.Is(err, errLookupTimeout)
+}
+
+// lookupTimeoutProvider runs every call of a database provider through withLookupTimeout;
+// the MariaDB and CloudNativePG providers read and write CRDs of optional operators
+type lookupTimeoutProvider struct {
+	inner   database.Provider
+	timeout time.Duration
+}
+
+// withProviderLookupTimeout bounds the calls of inner by timeout
+func withProviderLookupTimeout(inner database.Provider, timeout time.Duration) database.Provider {
+	return &lookupTimeoutProvider{inner: inner, timeout: timeout}
+}
+
+func (p *lookupTimeoutProvider) EnsureDatabase(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (*database.DatabaseInfo, error) {
+	var info *database.DatabaseInfo
+	err := withLookupTimeout(ctx, p.timeout, "database API", func(ctx context.Context) error {
+		var err error
+		info, err = p.inner.EnsureDatabase(ctx, site)
+		return err
+	})
+	return info, err
+}
+
+func (p *lookupTimeoutProvider) IsReady(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (bool, error) {
+	var ready bool
+	err := withLookupTimeout(ctx, p.timeout, "database API", func(ctx context.Context) error {
+		var err error
+		ready, err = p.inner.IsReady(ctx, site)
+		return err
+	})
+	return ready, err
+}
+
+func (p *lookupTimeoutProvider) GetCredentials(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (*database.DatabaseCredentials, error) {
+	var creds *database.DatabaseCredentials
+	err := withLookupTimeout(ctx, p.timeout, "database API", func(ctx context.Context) error {
+		var err error
+		creds, err = p.inner.GetCredentials(ctx, site)
+		return err
+	})
+	return creds, err
+}
+
+func (p *lookupTimeoutProvider) Cleanup(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) error {
+	return withLookupTimeout(ctx, p.timeout, "database API", func(ctx context.Context) error {
+		return p.inner.Cleanup(ctx, site)
+	})
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// newSlowOptionalAPIClient returns a fake client whose unstructured Gets and metadata
+// Lists (the MariaDB and KEDA lookups) hang until their context is done, like a
+// degraded API server extension
+func newSlowOptionalAPIClient(objs ...client.Object) (client.Client, *runtime.Scheme) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*unstructured.Unstructured); ok {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}).Build()
+	return c, scheme
+}
+
+func TestIsKEDAAvailableTimesOut(t *testing.T) {
+	c, scheme := newSlowOptionalAPIClient()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20), LookupTimeout: 20 * time.Millisecond}
+
+	start := time.Now()
+	available, err := r.isKEDAAvailable(context.Background())
+	if !isLookupTimeout(err) {
+		t.Fatalf("expected a lookup timeout, got available=%v err=%v", available, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("lookup was not bounded, took %s", elapsed)
+	}
+
+	_, bench := newInitJobTestObjects()
+	if err := r.ensureWorkers(context.Background(), bench); !isLookupTimeout(err) {
+		t.Errorf("expected ensureWorkers to surface the timeout, got %v", err)
+	}
+}
+
+func TestGetMariaDBRootCredentialsTimesOut(t *testing.T) {
+	site, _ := newInitJobTestObjects()
+	site.Spec.DBConfig.Mode = "shared"
+	c, scheme := newSlowOptionalAPIClient(site)
+	r := &FrappeSiteReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20), LookupTimeout: 20 * time.Millisecond}
+
+	if _, _, err := r.getMariaDBRootCredentials(context.Background(), site); !isLookupTimeout(err) {
+		t.Fatalf("expected a lookup timeout, got %v", err)
+	}
+}
+
+func TestWithLookupTimeoutIgnoresCallerCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := withLookupTimeout(ctx, time.Second, "test API", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err == nil || isLookupTimeout(err) {
+		t.Errorf("expected the caller's cancellation to pass through, got %v", err)
+	}
+}
+
+func TestDatabaseProviderTimesOut(t *testing.T) {
+	site, _ := newInitJobTestObjects()
+	site.Spec.DBConfig.Mode = "shared"
+	c, scheme := newSlowOptionalAPIClient(site)
+	provider := withProviderLookupTimeout(database.NewMariaDBProvider(c, scheme), 20*time.Millisecond)
+	ctx := context.Background()
+
+	if _, err := provider.IsReady(ctx, site); !isLookupTimeout(err) {
+		t.Errorf("expected IsReady to time out, got %v", err)
+	}
+	if _, err := provider.EnsureDatabase(ctx, site); !isLookupTimeout(err) {
+		t.Errorf("expected EnsureDatabase to time out looking up the shared MariaDB, got %v", err)
+	}
+	if _, err := provider.GetCredentials(ctx, site); !isLookupTimeout(err) {
+		t.Errorf("expected GetCredentials to time out, got %v", err)
+	}
+}
+
+func TestEnsureScaledObjectTimesOut(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	// KEDA's API is discovered, but ScaledObjects don't come back
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if u, ok := obj.(*unstructured.Unstructured); ok && u.GetKind() == "ScaledObject" {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return nil
+		},
+	}).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20), LookupTimeout: 20 * time.Millisecond}
+	enabled := true
+	config := &vyogotechv1alpha1.WorkerAutoscaling{
+		Enabled:         &enabled,
+		MinReplicas:     int32Ptr(0),
+		MaxReplicas:     int32Ptr(3),
+		CooldownPeriod:  int32Ptr(60),
+		PollingInterval: int32Ptr(30),
+		QueueLength:     int32Ptr(5),
+	}
+
+	if err := r.ensureScaledObject(context.Background(), bench, "default", "default", config); !isLookupTimeout(err) {
+		t.Errorf("expected a lookup timeout, got %v", err)
+	}
+}
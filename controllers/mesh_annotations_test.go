@@ -0,0 +1,181 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestJobPodAnnotations(t *testing.T) {
+	bench := &vyogotechv1alpha1.FrappeBench{}
+	if got := jobPodAnnotations(bench); got != nil {
+		t.Errorf("expected no annotations by default, got %v", got)
+	}
+
+	bench.Spec.ExcludeJobsFromMesh = true
+	got := jobPodAnnotations(bench)
+	if got["sidecar.istio.io/inject"] != "false" || got["linkerd.io/inject"] != "disabled" {
+		t.Errorf("expected istio and linkerd exclusion annotations, got %v", got)
+	}
+	got["extra"] = "x"
+	if _, ok := meshExclusionAnnotations["extra"]; ok {
+		t.Error("jobPodAnnotations must return a copy")
+	}
+}
+
+func TestMeshAnnotationsLandOnIntendedPods(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Spec.ExcludeJobsFromMesh = true
+	bench.Spec.ComponentPodAnnotations = &vyogotechv1alpha1.ComponentPodAnnotations{
+		Gunicorn: map[string]string{"sidecar.istio.io/inject": "true"},
+	}
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	benchReconciler := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := benchReconciler.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	if err := benchReconciler.ensureScheduler(ctx, bench); err != nil {
+		t.Fatalf("ensureScheduler: %v", err)
+	}
+	gunicorn := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, gunicorn); err != nil {
+		t.Fatalf("Get gunicorn Deployment: %v", err)
+	}
+	if gunicorn.Spec.Template.Annotations["sidecar.istio.io/inject"] != "true" {
+		t.Errorf("expected gunicorn pods to opt into the mesh, got %v", gunicorn.Spec.Template.Annotations)
+	}
+	scheduler := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-scheduler", Namespace: "default"}, scheduler); err != nil {
+		t.Fatalf("Get scheduler Deployment: %v", err)
+	}
+	if _, ok := scheduler.Spec.Template.Annotations["sidecar.istio.io/inject"]; ok {
+		t.Errorf("scheduler pods should not get gunicorn annotations, got %v", scheduler.Spec.Template.Annotations)
+	}
+
+	if _, err := benchReconciler.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+		t.Fatalf("ensureBenchInitialized: %v", err)
+	}
+	benchInit := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-init", Namespace: "default"}, benchInit); err != nil {
+		t.Fatalf("Get bench-init Job: %v", err)
+	}
+	if benchInit.Spec.Template.Annotations["sidecar.istio.io/inject"] != "false" {
+		t.Errorf("expected bench-init job to be excluded from the mesh, got %v", benchInit.Spec.Template.Annotations)
+	}
+
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	if _, err := siteReconciler.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	siteInit := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, siteInit); err != nil {
+		t.Fatalf("Get site-init Job: %v", err)
+	}
+	if siteInit.Spec.Template.Annotations["linkerd.io/inject"] != "disabled" {
+		t.Errorf("expected site-init job to be excluded from the mesh, got %v", siteInit.Spec.Template.Annotations)
+	}
+
+	backupReconciler := &SiteBackupReconciler{Scheme: siteReconciler.Scheme}
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local"},
+	}
+	backupJob := backupReconciler.buildBackupJob(siteBackup, bench)
+	if backupJob.Spec.Template.Annotations["sidecar.istio.io/inject"] != "false" {
+		t.Errorf("expected backup job to be excluded from the mesh, got %v", backupJob.Spec.Template.Annotations)
+	}
+}
+
+func TestComponentPodAnnotationsSyncedOnUpdate(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	getTemplateAnnotations := func(name string) map[string]string {
+		t.Helper()
+		deploy := &appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, deploy); err != nil {
+			t.Fatalf("Get %s: %v", name, err)
+		}
+		return deploy.Spec.Template.Annotations
+	}
+	ensure := func() {
+		t.Helper()
+		if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+			t.Fatalf("ensureGunicornDeployment: %v", err)
+		}
+		config := r.fillAutoscalingDefaults(nil, "long")
+		if err := r.ensureWorkerDeployment(ctx, bench, "long", "long", 1, corev1.ResourceRequirements{}, config, false); err != nil {
+			t.Fatalf("ensureWorkerDeployment: %v", err)
+		}
+	}
+
+	// Deployments created before any annotations were configured
+	ensure()
+	gunicorn := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, gunicorn); err != nil {
+		t.Fatalf("Get gunicorn: %v", err)
+	}
+	gunicorn.Spec.Template.Annotations = map[string]string{restartedAtAnnotation: "2024-01-01T00:00:00Z"}
+	if err := c.Update(ctx, gunicorn); err != nil {
+		t.Fatalf("Update gunicorn: %v", err)
+	}
+
+	bench.Spec.ComponentPodAnnotations = &vyogotechv1alpha1.ComponentPodAnnotations{
+		Gunicorn:   map[string]string{"sidecar.istio.io/inject": "true", "prometheus.io/scrape": "true"},
+		WorkerLong: map[string]string{"cluster-autoscaler.kubernetes.io/safe-to-evict": "false"},
+	}
+	ensure()
+	annotations := getTemplateAnnotations("bench-gunicorn")
+	if annotations["sidecar.istio.io/inject"] != "true" || annotations["prometheus.io/scrape"] != "true" {
+		t.Errorf("expected the new annotations on the existing gunicorn Deployment, got %v", annotations)
+	}
+	if annotations[restartedAtAnnotation] == "" {
+		t.Errorf("expected annotations set by others to be kept, got %v", annotations)
+	}
+	if annotations := getTemplateAnnotations("bench-worker-long"); annotations["cluster-autoscaler.kubernetes.io/safe-to-evict"] != "false" {
+		t.Errorf("expected the new annotation on the existing worker Deployment, got %v", annotations)
+	}
+
+	// Removing an annotation from the bench removes it from the pods
+	bench.Spec.ComponentPodAnnotations.Gunicorn = map[string]string{"prometheus.io/scrape": "false"}
+	bench.Spec.ComponentPodAnnotations.WorkerLong = nil
+	ensure()
+	annotations = getTemplateAnnotations("bench-gunicorn")
+	if _, ok := annotations["sidecar.istio.io/inject"]; ok || annotations["prometheus.io/scrape"] != "false" {
+		t.Errorf("expected the removed annotation to be dropped and the changed one updated, got %v", annotations)
+	}
+	if annotations[restartedAtAnnotation] == "" {
+		t.Errorf("expected annotations set by others to be kept, got %v", annotations)
+	}
+	if annotations := getTemplateAnnotations("bench-worker-long"); len(annotations) != 0 {
+		t.Errorf("expected the worker annotations to be removed, got %v", annotations)
+	}
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package controllers
 
 import (
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
@@ -58,8 +61,102 @@ var (
 		},
 		[]string{"controller", "namespace"},
 	)
+
+	// BenchInitJobsRunning tracks bench-init Jobs running across the cluster
+	BenchInitJobsRunning = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "frappe_operator_bench_init_jobs_running",
+			Help: "Number of bench-init jobs currently running across all namespaces",
+		},
+	)
+
+	// SitesByPhase counts FrappeSites per phase; it is kept up to date through sitePhases
+	SitesByPhase = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "frappe_sites_total",
+			Help: "Number of FrappeSites by phase",
+		},
+		[]string{"phase"},
+	)
+
+	// BenchReady reports 1 for a Ready FrappeBench and 0 otherwise
+	BenchReady = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "frappe_bench_ready",
+			Help: "Whether a FrappeBench is Ready (1) or not (0)",
+		},
+		[]string{"namespace", "bench"},
+	)
+
+	// JobFailures counts failed bench-init, site-init and backup jobs
+	JobFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "frappe_job_failures_total",
+			Help: "Total number of failed bench-init, site-init and backup jobs",
+		},
+		[]string{"job"},
+	)
+
+	// sitePhases feeds SitesByPhase from the FrappeSite reconciler
+	sitePhases = newPhaseTracker(SitesByPhase)
+)
+
+// Values of the JobFailures job label
+const (
+	jobFailureBenchInit = "bench-init"
+	jobFailureSiteInit  = "site-init"
+	jobFailureBackup    = "backup"
 )
 
+// phaseTracker remembers the last observed phase of every object so a per-phase gauge
+// moves an object between phases instead of counting it twice, and drops it on deletion
+type phaseTracker struct {
+	mu     sync.Mutex
+	phases map[types.NamespacedName]string
+	gauge  *prometheus.GaugeVec
+}
+
+func newPhaseTracker(gauge *prometheus.GaugeVec) *phaseTracker {
+	return &phaseTracker{phases: map[types.NamespacedName]string{}, gauge: gauge}
+}
+
+// observe records the current phase of an object; objects without a phase yet count as Pending
+func (t *phaseTracker) observe(key types.NamespacedName, phase string) {
+	if phase == "" {
+		phase = "Pending"
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old, tracked := t.phases[key]
+	if tracked && old == phase {
+		return
+	}
+	if tracked {
+		t.gauge.WithLabelValues(old).Dec()
+	}
+	t.phases[key] = phase
+	t.gauge.WithLabelValues(phase).Inc()
+}
+
+// forget removes a deleted object from the gauge
+func (t *phaseTracker) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if old, tracked := t.phases[key]; tracked {
+		t.gauge.WithLabelValues(old).Dec()
+		delete(t.phases, key)
+	}
+}
+
+// observeBenchReady updates BenchReady for a bench
+func observeBenchReady(namespace, name, phase string) {
+	ready := 0.0
+	if phase == "Ready" {
+		ready = 1
+	}
+	BenchReady.WithLabelValues(namespace, name).Set(ready)
+}
+
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(
@@ -67,5 +164,9 @@ func init() {
 		ReconciliationErrors,
 		JobStatus,
 		ResourceTotal,
+		BenchInitJobsRunning,
+		SitesByPhase,
+		BenchReady,
+		JobFailures,
 	)
 }
@@ -17,11 +17,16 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 func TestMetricsRegistration(t *testing.T) {
@@ -84,3 +89,57 @@ func TestResourceTotal(t *testing.T) {
 	value := testutil.ToFloat64(ResourceTotal.WithLabelValues("frappesite", "default"))
 	assert.Equal(t, float64(5), value, "Resource total should be 5")
 }
+
+func TestPhaseTracker(t *testing.T) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_phases"}, []string{"phase"})
+	tracker := newPhaseTracker(gauge)
+	a := types.NamespacedName{Namespace: "default", Name: "a"}
+	b := types.NamespacedName{Namespace: "other", Name: "a"}
+
+	tracker.observe(a, "")
+	tracker.observe(b, "Provisioning")
+	tracker.observe(b, "Provisioning")
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge.WithLabelValues("Pending")), "a site without a phase counts as Pending")
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge.WithLabelValues("Provisioning")), "observing the same phase twice counts once")
+
+	tracker.observe(a, "Provisioning")
+	tracker.observe(b, "Ready")
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge.WithLabelValues("Pending")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge.WithLabelValues("Provisioning")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge.WithLabelValues("Ready")))
+
+	tracker.forget(b)
+	tracker.forget(b)
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge.WithLabelValues("Ready")), "a deleted site leaves its phase exactly once")
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge.WithLabelValues("Provisioning")))
+}
+
+func TestSitePhaseMetrics_Deletion(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Status.Phase = "Provisioning"
+	site.SetFinalizers([]string{frappeSiteFinalizer})
+	r, c := newInitJobTestReconciler(site, bench)
+	r.Recorder = record.NewFakeRecorder(50)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "site", Namespace: "default"}}
+	before := testutil.ToFloat64(SitesByPhase.WithLabelValues("Pending"))
+
+	_, _ = r.Reconcile(ctx, req)
+	assert.Equal(t, before+1, testutil.ToFloat64(SitesByPhase.WithLabelValues("Pending")), "a site waiting for its bench is Pending")
+
+	latest := &vyogotechv1alpha1.FrappeSite{}
+	assert.NoError(t, c.Get(ctx, req.NamespacedName, latest))
+	latest.SetFinalizers(nil)
+	assert.NoError(t, c.Update(ctx, latest))
+	assert.NoError(t, c.Delete(ctx, latest))
+	_, _ = r.Reconcile(ctx, req)
+	assert.Equal(t, before, testutil.ToFloat64(SitesByPhase.WithLabelValues("Pending")), "a deleted site is no longer counted")
+}
+
+func TestBenchReadyMetric(t *testing.T) {
+	observeBenchReady("default", "metrics-bench", "Provisioning")
+	assert.Equal(t, float64(0), testutil.ToFloat64(BenchReady.WithLabelValues("default", "metrics-bench")))
+	observeBenchReady("default", "metrics-bench", "Ready")
+	assert.Equal(t, float64(1), testutil.ToFloat64(BenchReady.WithLabelValues("default", "metrics-bench")))
+	BenchReady.DeleteLabelValues("default", "metrics-bench")
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// pausedCondition is set on benches and sites with spec.paused
+const pausedCondition = "Paused"
+
+// setPausedCondition records the Paused condition and reports whether the conditions
+// changed. Objects never paused get no condition at all.
+func setPausedCondition(conditions *[]metav1.Condition, paused bool, generation int64) bool {
+	if paused {
+		return meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               pausedCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Paused",
+			Message:            "Reconciliation is paused by spec.paused",
+			ObservedGeneration: generation,
+		})
+	}
+	if meta.FindStatusCondition(*conditions, pausedCondition) == nil {
+		return false
+	}
+	return meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               pausedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Resumed",
+		Message:            "Reconciliation resumed",
+		ObservedGeneration: generation,
+	})
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newPausedTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func TestFrappeBenchReconciler_Paused(t *testing.T) {
+	scheme := newPausedTestScheme()
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15", Paused: true},
+	}
+	replicas := int32(3)
+	gunicorn := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-gunicorn", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, gunicorn).WithStatusSubresource(bench).Build()
+	r := &FrappeBenchReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "bench", Namespace: "default"}}
+
+	result, err := r.Reconcile(ctx, req)
+	if err != nil || !result.IsZero() {
+		t.Fatalf("expected a paused bench to return without requeue, got %v, %v", result, err)
+	}
+
+	updated := &vyogotechv1alpha1.FrappeBench{}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get bench: %v", err)
+	}
+	if len(updated.Finalizers) == 0 {
+		t.Error("expected the finalizer to be added while paused")
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, pausedCondition) {
+		t.Errorf("expected Paused=True, got %+v", updated.Status.Conditions)
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace("default")); err != nil {
+		t.Fatalf("List deployments: %v", err)
+	}
+	if len(deployments.Items) != 1 {
+		t.Errorf("expected no deployments to be created while paused, got %d", len(deployments.Items))
+	}
+	existing := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, existing); err != nil {
+		t.Fatalf("Get deployment: %v", err)
+	}
+	if existing.ResourceVersion != "999" || *existing.Spec.Replicas != 3 {
+		t.Errorf("expected the existing deployment to be left alone, got rv %s replicas %d", existing.ResourceVersion, *existing.Spec.Replicas)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(ctx, jobs, client.InNamespace("default")); err != nil {
+		t.Fatalf("List jobs: %v", err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("expected no jobs while paused, got %d", len(jobs.Items))
+	}
+}
+
+func TestFrappeSiteReconciler_Paused(t *testing.T) {
+	scheme := newPausedTestScheme()
+	site, bench := newInitJobTestObjects()
+	bench.Status.Phase = "Ready"
+	site.Spec.Paused = true
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "site", Namespace: "default"}}
+
+	result, err := r.Reconcile(ctx, req)
+	if err != nil || !result.IsZero() {
+		t.Fatalf("expected a paused site to return without requeue, got %v, %v", result, err)
+	}
+
+	updated := &vyogotechv1alpha1.FrappeSite{}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if len(updated.Finalizers) == 0 {
+		t.Error("expected the finalizer to be added while paused")
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, pausedCondition) {
+		t.Errorf("expected Paused=True, got %+v", updated.Status.Conditions)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(ctx, jobs, client.InNamespace("default")); err != nil {
+		t.Fatalf("List jobs: %v", err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("expected no jobs while paused, got %d", len(jobs.Items))
+	}
+
+	// Resuming clears the condition and provisions again
+	updated.Spec.Paused = false
+	if err := c.Update(ctx, updated); err != nil {
+		t.Fatalf("Update site: %v", err)
+	}
+	// Without a database the resumed reconcile fails further on; only the condition matters here
+	_, _ = r.Reconcile(ctx, req)
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get site: %v", err)
+	}
+	if cond := meta.FindStatusCondition(updated.Status.Conditions, pausedCondition); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Errorf("expected Paused=False after resume, got %+v", cond)
+	}
+}
+
+func TestFrappeSiteReconciler_PausedDeletion(t *testing.T) {
+	scheme := newPausedTestScheme()
+	now := metav1.Now()
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "site",
+			Namespace:         "default",
+			DeletionTimestamp: &now,
+			Finalizers:        []string{frappeSiteFinalizer},
+		},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+			DBConfig: vyogotechv1alpha1.DatabaseConfig{Provider: "mariadb", Mode: "dedicated"},
+			Paused:   true,
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(site, bench).WithStatusSubresource(site).Build()
+	r := &FrappeSiteReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "site", Namespace: "default"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	updated := &vyogotechv1alpha1.FrappeSite{}
+	err := c.Get(ctx, req.NamespacedName, updated)
+	if err == nil && len(updated.Finalizers) != 0 {
+		t.Error("expected a paused site to still be deleted")
+	} else if err != nil && !errors.IsNotFound(err) {
+		t.Fatalf("Get site: %v", err)
+	}
+}
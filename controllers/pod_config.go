@@ -1,7 +1,13 @@
 package controllers
 
 import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -21,13 +27,16 @@ func applyPodConfig(config *vyogotechv1alpha1.PodConfig, initialLabels map[strin
 		return nil, nil, nil, labels
 	}
 
-	// Apply custom labels
-	for k, v := range config.Labels {
-		labels[k] = v
-	}
+	// Apply custom labels; the initial (operator) labels win on conflicts
+	labels = resources.MergeLabels(withoutManagedLabels(config.Labels), labels)
 
-	// Node Selector
-	nodeSelector = config.NodeSelector
+	// Node Selector, copied so GeoTag entries don't leak into the spec
+	if len(config.NodeSelector) > 0 {
+		nodeSelector = make(map[string]string, len(config.NodeSelector))
+		for k, v := range config.NodeSelector {
+			nodeSelector[k] = v
+		}
+	}
 
 	// Tolerations
 	tolerations = config.Tolerations
@@ -35,61 +44,185 @@ func applyPodConfig(config *vyogotechv1alpha1.PodConfig, initialLabels map[strin
 	// Affinity
 	affinity = config.Affinity
 
-	// Geo Tag Logic
+	// Geo Tag maps to the well-known topology node labels
 	if config.GeoTag != nil {
-		if labels == nil {
-			labels = make(map[string]string)
-		}
-
-		geoRequirements := []corev1.NodeSelectorRequirement{}
-
+		geoLabels := map[string]string{}
 		if config.GeoTag.Region != "" {
-			labels["topology.kubernetes.io/region"] = config.GeoTag.Region
-			geoRequirements = append(geoRequirements, corev1.NodeSelectorRequirement{
-				Key:      "topology.kubernetes.io/region",
-				Operator: corev1.NodeSelectorOpIn,
-				Values:   []string{config.GeoTag.Region},
-			})
+			geoLabels["topology.kubernetes.io/region"] = config.GeoTag.Region
 		}
-
 		if config.GeoTag.Zone != "" {
-			labels["topology.kubernetes.io/zone"] = config.GeoTag.Zone
-			geoRequirements = append(geoRequirements, corev1.NodeSelectorRequirement{
-				Key:      "topology.kubernetes.io/zone",
-				Operator: corev1.NodeSelectorOpIn,
-				Values:   []string{config.GeoTag.Zone},
-			})
+			geoLabels["topology.kubernetes.io/zone"] = config.GeoTag.Zone
 		}
-
-		if len(geoRequirements) > 0 {
-			if affinity == nil {
-				affinity = &corev1.Affinity{}
-			}
-			if affinity.NodeAffinity == nil {
-				affinity.NodeAffinity = &corev1.NodeAffinity{}
-			}
-			if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
-				affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+		for k, v := range geoLabels {
+			labels[k] = v
+			if nodeSelector == nil {
+				nodeSelector = make(map[string]string)
 			}
+			nodeSelector[k] = v
+		}
+	}
 
-			// Add a new term or merge into existing terms? 
-			// For simplicity, we add it as a requirement to all existing terms if they exist,
-			// or create a new term if none exist.
-			if len(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) == 0 {
-				affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = []corev1.NodeSelectorTerm{
-					{
-						MatchExpressions: geoRequirements,
-					},
-				}
-			} else {
-				for i := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
-					affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[i].MatchExpressions = append(
-						affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[i].MatchExpressions,
-						geoRequirements...)
-				}
+	return nodeSelector, affinity, tolerations, labels
+}
+
+// syncPodPlacement brings the nodeSelector, affinity and tolerations of an existing pod
+// spec in line with the pod config, reporting whether anything changed
+func syncPodPlacement(spec *corev1.PodSpec, config *vyogotechv1alpha1.PodConfig) bool {
+	nodeSelector, affinity, tolerations, _ := applyPodConfig(config, nil)
+	changed := false
+	// nil and empty are the same to the API server
+	if (len(spec.NodeSelector) > 0 || len(nodeSelector) > 0) && !reflect.DeepEqual(spec.NodeSelector, nodeSelector) {
+		spec.NodeSelector = nodeSelector
+		changed = true
+	}
+	if !reflect.DeepEqual(spec.Affinity, affinity) {
+		spec.Affinity = affinity
+		changed = true
+	}
+	if (len(spec.Tolerations) > 0 || len(tolerations) > 0) && !reflect.DeepEqual(spec.Tolerations, tolerations) {
+		spec.Tolerations = tolerations
+		changed = true
+	}
+	return changed
+}
+
+// hasPodPlacement reports whether a pod config constrains where pods are scheduled
+func hasPodPlacement(config *vyogotechv1alpha1.PodConfig) bool {
+	return config != nil && (len(config.NodeSelector) > 0 || config.Affinity != nil || len(config.Tolerations) > 0 || config.GeoTag != nil)
+}
+
+// benchJobPodConfig returns the pod config for jobs running against a bench:
+// spec.jobPlacement when set, otherwise the bench's podConfig
+func benchJobPodConfig(bench *vyogotechv1alpha1.FrappeBench) *vyogotechv1alpha1.PodConfig {
+	if bench == nil {
+		return nil
+	}
+	if bench.Spec.JobPlacement != nil {
+		return bench.Spec.JobPlacement
+	}
+	return bench.Spec.PodConfig
+}
+
+// siteJobPodConfig returns the pod config for a site's jobs. The site's podConfig keeps its
+// labels, but when it doesn't place the pods itself the jobs inherit the bench's job
+// placement, so they schedule where the bench's sites PVC can be attached.
+func siteJobPodConfig(site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) *vyogotechv1alpha1.PodConfig {
+	config := site.Spec.PodConfig
+	placement := benchJobPodConfig(bench)
+	if hasPodPlacement(config) || !hasPodPlacement(placement) {
+		return config
+	}
+	merged := &vyogotechv1alpha1.PodConfig{}
+	if config != nil {
+		merged.Labels = config.Labels
+	}
+	merged.NodeSelector = placement.NodeSelector
+	merged.Affinity = placement.Affinity
+	merged.Tolerations = placement.Tolerations
+	merged.GeoTag = placement.GeoTag
+	return merged
+}
+
+// meshExclusionAnnotations opt a pod out of Istio and Linkerd sidecar injection
+var meshExclusionAnnotations = map[string]string{
+	"sidecar.istio.io/inject": "false",
+	"linkerd.io/inject":       "disabled",
+}
+
+// componentPodAnnotations returns the user-configured pod annotations for a bench component
+func componentPodAnnotations(bench *vyogotechv1alpha1.FrappeBench, component string) map[string]string {
+	propagated := propagatedAnnotations(bench)
+	annotations := bench.Spec.ComponentPodAnnotations
+	if annotations == nil {
+		return propagated
+	}
+	var own map[string]string
+	switch component {
+	case "gunicorn":
+		own = annotations.Gunicorn
+	case "nginx":
+		own = annotations.Nginx
+	case "scheduler":
+		own = annotations.Scheduler
+	case "socketio":
+		own = annotations.Socketio
+	case "worker-default":
+		own = annotations.WorkerDefault
+	case "worker-long":
+		own = annotations.WorkerLong
+	case "worker-short":
+		own = annotations.WorkerShort
+	}
+	if len(propagated) == 0 {
+		return own
+	}
+	// The component's own annotations win over propagated ones
+	return resources.MergeLabels(propagated, own)
+}
+
+// podAnnotationKeysAnnotation lists the pod template annotations that came from
+// componentPodAnnotations, so keys removed from the bench are removed from the pods too
+// while annotations set by others (e.g. restartedAt) are left alone
+const podAnnotationKeysAnnotation = "vyogo.tech/pod-annotation-keys"
+
+// componentPodTemplateAnnotations returns componentPodAnnotations together with the list
+// of their keys, for a component's pod template
+func componentPodTemplateAnnotations(bench *vyogotechv1alpha1.FrappeBench, component string) map[string]string {
+	annotations := componentPodAnnotations(bench, component)
+	if len(annotations) == 0 {
+		return nil
+	}
+	out := maps.Clone(annotations)
+	out[podAnnotationKeysAnnotation] = strings.Join(slices.Sorted(maps.Keys(annotations)), ",")
+	return out
+}
+
+// syncComponentPodAnnotations brings the annotations of an existing component pod template
+// in line with the bench, reporting whether anything changed
+func syncComponentPodAnnotations(template *corev1.PodTemplateSpec, bench *vyogotechv1alpha1.FrappeBench, component string) bool {
+	desired := componentPodTemplateAnnotations(bench, component)
+	changed := false
+	// Drop what an earlier reconcile set and the bench no longer asks for
+	if previous := template.Annotations[podAnnotationKeysAnnotation]; previous != "" {
+		for _, key := range append(strings.Split(previous, ","), podAnnotationKeysAnnotation) {
+			if _, ok := desired[key]; ok {
+				continue
+			}
+			if _, ok := template.Annotations[key]; ok {
+				delete(template.Annotations, key)
+				changed = true
 			}
 		}
 	}
+	for k, v := range desired {
+		if value, ok := template.Annotations[k]; ok && value == v {
+			continue
+		}
+		if template.Annotations == nil {
+			template.Annotations = make(map[string]string)
+		}
+		template.Annotations[k] = v
+		changed = true
+	}
+	return changed
+}
 
-	return nodeSelector, affinity, tolerations, labels
+// jobPodAnnotations returns the pod annotations for one-shot jobs running against a bench:
+// the bench's propagated annotations and, when it excludes jobs from the mesh, the mesh
+// exclusion annotations. Returns nil when there are none.
+func jobPodAnnotations(bench *vyogotechv1alpha1.FrappeBench) map[string]string {
+	if bench == nil {
+		return nil
+	}
+	annotations := propagatedAnnotations(bench)
+	if !bench.Spec.ExcludeJobsFromMesh {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = make(map[string]string, len(meshExclusionAnnotations))
+	}
+	for k, v := range meshExclusionAnnotations {
+		annotations[k] = v
+	}
+	return annotations
 }
@@ -1,31 +1,36 @@
 package controllers
 
 import (
+	"context"
 	"testing"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestApplyPodConfig(t *testing.T) {
 	tests := []struct {
-		name       string
-		podConfig  *vyogotechv1alpha1.PodConfig
-		initial    struct {
+		name      string
+		podConfig *vyogotechv1alpha1.PodConfig
+		initial   struct {
 			labels map[string]string
 		}
-		wantLabels map[string]string
+		wantLabels       map[string]string
 		wantNodeSelector map[string]string
-		wantAffinity *corev1.Affinity
-		wantTolerations []corev1.Toleration
+		wantAffinity     *corev1.Affinity
+		wantTolerations  []corev1.Toleration
 	}{
 		{
 			name: "Basic labels and nodeSelector",
 			podConfig: &vyogotechv1alpha1.PodConfig{
-				Labels: map[string]string{"foo": "bar"},
+				Labels:       map[string]string{"foo": "bar"},
 				NodeSelector: map[string]string{"disk": "ssd"},
 			},
-			wantLabels: map[string]string{"foo": "bar"},
+			wantLabels:       map[string]string{"foo": "bar"},
 			wantNodeSelector: map[string]string{"disk": "ssd"},
 		},
 		{
@@ -33,34 +38,27 @@ func TestApplyPodConfig(t *testing.T) {
 			podConfig: &vyogotechv1alpha1.PodConfig{
 				GeoTag: &vyogotechv1alpha1.GeoTagConfig{
 					Region: "us-east-1",
-					Zone: "us-east-1a",
+					Zone:   "us-east-1a",
 				},
 			},
 			wantLabels: map[string]string{
 				"topology.kubernetes.io/region": "us-east-1",
-				"topology.kubernetes.io/zone": "us-east-1a",
+				"topology.kubernetes.io/zone":   "us-east-1a",
 			},
-			wantAffinity: &corev1.Affinity{
-				NodeAffinity: &corev1.NodeAffinity{
-					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-						NodeSelectorTerms: []corev1.NodeSelectorTerm{
-							{
-								MatchExpressions: []corev1.NodeSelectorRequirement{
-									{
-										Key: "topology.kubernetes.io/region",
-										Operator: corev1.NodeSelectorOpIn,
-										Values: []string{"us-east-1"},
-									},
-									{
-										Key: "topology.kubernetes.io/zone",
-										Operator: corev1.NodeSelectorOpIn,
-										Values: []string{"us-east-1a"},
-									},
-								},
-							},
-						},
-					},
-				},
+			wantNodeSelector: map[string]string{
+				"topology.kubernetes.io/region": "us-east-1",
+				"topology.kubernetes.io/zone":   "us-east-1a",
+			},
+		},
+		{
+			name: "GeoTag merges with nodeSelector",
+			podConfig: &vyogotechv1alpha1.PodConfig{
+				NodeSelector: map[string]string{"kubernetes.io/arch": "amd64"},
+				GeoTag:       &vyogotechv1alpha1.GeoTagConfig{Region: "us-east-1"},
+			},
+			wantNodeSelector: map[string]string{
+				"kubernetes.io/arch":            "amd64",
+				"topology.kubernetes.io/region": "us-east-1",
 			},
 		},
 		{
@@ -76,6 +74,20 @@ func TestApplyPodConfig(t *testing.T) {
 				"custom": "value",
 			},
 		},
+		{
+			name: "Operator labels win over pod config labels",
+			podConfig: &vyogotechv1alpha1.PodConfig{
+				Labels: map[string]string{"app": "other", "component": "other", "team": "payments"},
+			},
+			initial: struct{ labels map[string]string }{
+				labels: map[string]string{"app": "frappe"},
+			},
+			wantLabels: map[string]string{
+				"app":       "frappe",
+				"component": "",
+				"team":      "payments",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -86,7 +98,7 @@ func TestApplyPodConfig(t *testing.T) {
 					labels[k] = v
 				}
 			}
-			
+
 			nodeSelector, affinity, tolerations, finalLabels := applyPodConfig(tt.podConfig, labels)
 
 			// Check Labels
@@ -123,3 +135,95 @@ func TestApplyPodConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncPodPlacement(t *testing.T) {
+	config := &vyogotechv1alpha1.PodConfig{
+		NodeSelector: map[string]string{"kubernetes.io/arch": "amd64"},
+		Tolerations:  []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "frappe", Effect: corev1.TaintEffectNoSchedule}},
+	}
+	spec := &corev1.PodSpec{}
+
+	if !syncPodPlacement(spec, config) {
+		t.Fatal("expected placement to change")
+	}
+	if spec.NodeSelector["kubernetes.io/arch"] != "amd64" || len(spec.Tolerations) != 1 {
+		t.Errorf("expected nodeSelector and tolerations to be applied, got %v and %v", spec.NodeSelector, spec.Tolerations)
+	}
+	if syncPodPlacement(spec, config) {
+		t.Error("expected no change when placement already matches")
+	}
+	if config.NodeSelector["topology.kubernetes.io/region"] != "" {
+		t.Error("expected the pod config to be left untouched")
+	}
+
+	// Removing the pod config clears the placement again
+	if !syncPodPlacement(spec, nil) {
+		t.Fatal("expected placement to change")
+	}
+	if spec.NodeSelector != nil || spec.Tolerations != nil {
+		t.Errorf("expected placement to be cleared, got %v and %v", spec.NodeSelector, spec.Tolerations)
+	}
+}
+
+func TestSiteJobPodConfig(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	if siteJobPodConfig(site, bench) != nil {
+		t.Error("expected no pod config without any placement")
+	}
+
+	bench.Spec.PodConfig = &vyogotechv1alpha1.PodConfig{
+		Labels:       map[string]string{"tier": "web"},
+		NodeSelector: map[string]string{"topology.kubernetes.io/zone": "zone-a"},
+	}
+	site.Spec.PodConfig = &vyogotechv1alpha1.PodConfig{Labels: map[string]string{"team": "payments"}}
+	config := siteJobPodConfig(site, bench)
+	if config.NodeSelector["topology.kubernetes.io/zone"] != "zone-a" {
+		t.Errorf("expected the bench placement to be inherited, got %v", config.NodeSelector)
+	}
+	if config.Labels["team"] != "payments" || config.Labels["tier"] != "" {
+		t.Errorf("expected only the site's labels, got %v", config.Labels)
+	}
+
+	// jobPlacement takes precedence over the bench's podConfig
+	bench.Spec.JobPlacement = &vyogotechv1alpha1.PodConfig{NodeSelector: map[string]string{"topology.kubernetes.io/zone": "zone-b"}}
+	if got := siteJobPodConfig(site, bench).NodeSelector["topology.kubernetes.io/zone"]; got != "zone-b" {
+		t.Errorf("expected the job placement zone, got %q", got)
+	}
+
+	// A site that places its own jobs keeps its placement
+	site.Spec.PodConfig.NodeSelector = map[string]string{"disk": "ssd"}
+	if config := siteJobPodConfig(site, bench); config != site.Spec.PodConfig {
+		t.Errorf("expected the site's own pod config, got %+v", config)
+	}
+}
+
+func TestJobPlacement_initJobs(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	bench.Spec.PodConfig = &vyogotechv1alpha1.PodConfig{NodeSelector: map[string]string{"topology.kubernetes.io/zone": "zone-a"}}
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+		t.Fatalf("ensureBenchInitialized: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get bench init Job: %v", err)
+	}
+	if got := job.Spec.Template.Spec.NodeSelector["topology.kubernetes.io/zone"]; got != "zone-a" {
+		t.Errorf("expected the bench nodeSelector on the init job, got %v", job.Spec.Template.Spec.NodeSelector)
+	}
+
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	if _, err := siteReconciler.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get site init Job: %v", err)
+	}
+	if got := job.Spec.Template.Spec.NodeSelector["topology.kubernetes.io/zone"]; got != "zone-a" {
+		t.Errorf("expected the bench nodeSelector on the site init job, got %v", job.Spec.Template.Spec.NodeSelector)
+	}
+}
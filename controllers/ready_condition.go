@@ -0,0 +1,44 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// syncReadyCondition keeps the Ready printer column in step with the phase of a bench or
+// site that isn't Ready: a missing Ready condition, or one still True from before, becomes
+// False with the phase as reason. Conditions set by the reconcile with a specific reason
+// are left alone.
+func syncReadyCondition(conditions *[]metav1.Condition, kind, phase string, generation int64) {
+	if phase == "Ready" {
+		return
+	}
+	if ready := meta.FindStatusCondition(*conditions, "Ready"); ready != nil && ready.Status != metav1.ConditionTrue {
+		return
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionFalse,
+		Reason:             phase,
+		Message:            fmt.Sprintf("%s is %s", kind, phase),
+		ObservedGeneration: generation,
+	})
+}
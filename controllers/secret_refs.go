@@ -0,0 +1,169 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// missingSecretCondition is set on resources whose referenced Secrets or keys don't exist
+const missingSecretCondition = "MissingSecret"
+
+// missingSecretRequeue is how often a resource held back by a missing Secret checks
+// again; Secrets aren't watched
+const missingSecretRequeue = 30 * time.Second
+
+// secretRef is a Secret referenced from a spec, with the keys the operator reads from it
+type secretRef struct {
+	// field is the spec path of the reference, for messages
+	field     string
+	namespace string
+	name      string
+	keys      []string
+}
+
+// findMissingSecret checks that every referenced Secret exists and has its keys, and
+// returns a message naming the first one that doesn't ("" when all are present)
+func findMissingSecret(ctx context.Context, c client.Client, refs []secretRef) (string, error) {
+	for _, ref := range refs {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Name: ref.name, Namespace: ref.namespace}, secret); err != nil {
+			if errors.IsNotFound(err) {
+				return fmt.Sprintf("Secret %s/%s referenced by %s not found", ref.namespace, ref.name, ref.field), nil
+			}
+			return "", err
+		}
+		for _, key := range ref.keys {
+			if _, ok := secret.Data[key]; !ok {
+				return fmt.Sprintf("Secret %s/%s referenced by %s has no key %q", ref.namespace, ref.name, ref.field, key), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// setMissingSecretCondition records the MissingSecret condition for message (empty when
+// every Secret is present) and reports whether the conditions changed. Objects never
+// flagged get no condition at all.
+func setMissingSecretCondition(conditions *[]metav1.Condition, message string, generation int64) bool {
+	if message != "" {
+		return meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               missingSecretCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "SecretNotFound",
+			Message:            message,
+			ObservedGeneration: generation,
+		})
+	}
+	if meta.FindStatusCondition(*conditions, missingSecretCondition) == nil {
+		return false
+	}
+	return meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               missingSecretCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "SecretsFound",
+		Message:            "All referenced Secrets are present",
+		ObservedGeneration: generation,
+	})
+}
+
+// secretReference resolves a SecretReference, which defaults to namespace
+func secretReference(field string, ref *corev1.SecretReference, namespace string, keys ...string) secretRef {
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	return secretRef{field: field, namespace: namespace, name: ref.Name, keys: keys}
+}
+
+// benchSecretRefs lists the Secrets a FrappeBench references
+func benchSecretRefs(bench *vyogotechv1alpha1.FrappeBench) []secretRef {
+	var refs []secretRef
+	if db := bench.Spec.DBConfig; db != nil && db.Provider == "external" && db.ConnectionSecretRef != nil {
+		// Sites read it from their own namespace, which is the bench's
+		refs = append(refs, secretRef{field: "spec.dbConfig.connectionSecretRef", namespace: bench.Namespace, name: db.ConnectionSecretRef.Name, keys: []string{"username", "password"}})
+	}
+	if redis := bench.Spec.RedisConfig; redis != nil && redis.ConnectionSecretRef != nil {
+		// Always read in the bench's namespace, see resolveRedisURLs
+		refs = append(refs, secretRef{field: "spec.redisConfig.connectionSecretRef", namespace: bench.Namespace, name: redis.ConnectionSecretRef.Name})
+	}
+	if bench.Spec.CommonSiteConfigSecretRef != nil {
+		refs = append(refs, secretRef{field: "spec.commonSiteConfigSecretRef", namespace: bench.Namespace, name: bench.Spec.CommonSiteConfigSecretRef.Name})
+	}
+	if bench.Spec.FPMConfig != nil {
+		for i, repo := range bench.Spec.FPMConfig.Repositories {
+			if repo.AuthSecretRef != nil {
+				field := fmt.Sprintf("spec.fpmConfig.repositories[%d].authSecretRef", i)
+				refs = append(refs, secretReference(field, repo.AuthSecretRef, bench.Namespace, "username", "password"))
+			}
+		}
+	}
+	return refs
+}
+
+// siteSecretRefs lists the Secrets a FrappeSite references, given its resolved database
+// config. The external database provider always reads its Secret from the site's namespace.
+func siteSecretRefs(site *vyogotechv1alpha1.FrappeSite, dbConfig vyogotechv1alpha1.DatabaseConfig) []secretRef {
+	var refs []secretRef
+	// The admin password is only read by the init job; once the site exists, deleting the
+	// Secret doesn't affect it and mustn't take the site out of Ready
+	if site.Spec.AdminPasswordSecretRef != nil && !siteInitialized(site) {
+		refs = append(refs, secretReference("spec.adminPasswordSecretRef", site.Spec.AdminPasswordSecretRef, site.Namespace, "password"))
+	}
+	if dbConfig.Provider == "external" && dbConfig.ConnectionSecretRef != nil {
+		refs = append(refs, secretRef{
+			field:     "spec.dbConfig.connectionSecretRef",
+			namespace: site.Namespace,
+			name:      dbConfig.ConnectionSecretRef.Name,
+			keys:      []string{"username", "password"},
+		})
+	}
+	if site.Spec.SiteConfigSecretRef != nil {
+		refs = append(refs, secretRef{field: "spec.siteConfigSecretRef", namespace: site.Namespace, name: site.Spec.SiteConfigSecretRef.Name})
+	}
+	return refs
+}
+
+// siteBackupSecretRefs lists the Secrets a SiteBackup references
+func siteBackupSecretRefs(siteBackup *vyogotechv1alpha1.SiteBackup) []secretRef {
+	var refs []secretRef
+	if storage := siteBackup.Spec.Storage; storage != nil && storage.S3 != nil {
+		for _, selector := range []struct {
+			field string
+			ref   corev1.SecretKeySelector
+		}{
+			{"spec.storage.s3.accessKeySecret", storage.S3.AccessKeySecret},
+			{"spec.storage.s3.secretKeySecret", storage.S3.SecretKeySecret},
+		} {
+			if selector.ref.Optional != nil && *selector.ref.Optional {
+				continue
+			}
+			// The backup job reads them in the namespace it runs in
+			refs = append(refs, secretRef{field: selector.field, namespace: backupExecutionNamespace(siteBackup), name: selector.ref.Name, keys: []string{selector.ref.Key}})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFindMissingSecret(t *testing.T) {
+	_, bench := newInitJobTestObjects()
+	bench.Spec.DBConfig = &vyogotechv1alpha1.DatabaseConfig{
+		Provider:            "external",
+		ConnectionSecretRef: &corev1.SecretReference{Name: "db-creds"},
+	}
+	bench.Spec.FPMConfig = &vyogotechv1alpha1.FPMConfig{
+		Repositories: []vyogotechv1alpha1.FPMRepository{
+			{Name: "private", URL: "https://fpm.example.com", AuthSecretRef: &corev1.SecretReference{Name: "fpm-auth", Namespace: "shared"}},
+		},
+	}
+	dbCreds := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-creds", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("frappe")},
+	}
+	_, c := newInitJobTestReconciler(dbCreds)
+	ctx := context.Background()
+
+	message, err := findMissingSecret(ctx, c, benchSecretRefs(bench))
+	if err != nil {
+		t.Fatalf("findMissingSecret: %v", err)
+	}
+	if !strings.Contains(message, "default/db-creds") || !strings.Contains(message, `"password"`) {
+		t.Errorf("expected the missing password key to be named, got %q", message)
+	}
+
+	dbCreds.Data["password"] = []byte("secret")
+	if err := c.Update(ctx, dbCreds); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	message, err = findMissingSecret(ctx, c, benchSecretRefs(bench))
+	if err != nil {
+		t.Fatalf("findMissingSecret: %v", err)
+	}
+	if !strings.Contains(message, "shared/fpm-auth") || !strings.Contains(message, "spec.fpmConfig.repositories[0].authSecretRef") {
+		t.Errorf("expected the missing FPM auth Secret to be named, got %q", message)
+	}
+}
+
+func TestSiteSecretRefs_initOnlyRefsAfterInit(t *testing.T) {
+	site, _ := newInitJobTestObjects()
+	site.Spec.AdminPasswordSecretRef = &corev1.SecretReference{Name: "admin"}
+	site.Spec.SiteConfigSecretRef = &corev1.LocalObjectReference{Name: "site-config"}
+	dbConfig := vyogotechv1alpha1.DatabaseConfig{Provider: "external", ConnectionSecretRef: &corev1.SecretReference{Name: "db-creds"}}
+	dbCreds := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-creds", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("frappe"), "password": []byte("secret")},
+	}
+	siteConfig := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "site-config", Namespace: "default"}}
+	_, c := newInitJobTestReconciler(dbCreds, siteConfig)
+	ctx := context.Background()
+
+	// Before the site exists the admin password Secret is required
+	message, err := findMissingSecret(ctx, c, siteSecretRefs(site, dbConfig))
+	if err != nil {
+		t.Fatalf("findMissingSecret: %v", err)
+	}
+	if !strings.Contains(message, "spec.adminPasswordSecretRef") {
+		t.Errorf("expected the missing admin password Secret to be named, got %q", message)
+	}
+
+	// Once initialized, only the Secrets the running site reads are checked
+	meta.SetStatusCondition(&site.Status.Conditions, metav1.Condition{Type: siteInitializedCondition, Status: metav1.ConditionTrue, Reason: "InitJobSucceeded"})
+	message, err = findMissingSecret(ctx, c, siteSecretRefs(site, dbConfig))
+	if err != nil {
+		t.Fatalf("findMissingSecret: %v", err)
+	}
+	if message != "" {
+		t.Errorf("expected an initialized site not to need the admin password Secret, got %q", message)
+	}
+	if err := c.Delete(ctx, siteConfig); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	message, err = findMissingSecret(ctx, c, siteSecretRefs(site, dbConfig))
+	if err != nil {
+		t.Fatalf("findMissingSecret: %v", err)
+	}
+	if !strings.Contains(message, "spec.siteConfigSecretRef") {
+		t.Errorf("expected the site config Secret to still be required, got %q", message)
+	}
+}
+
+func TestSetMissingSecretCondition(t *testing.T) {
+	var conditions []metav1.Condition
+	if setMissingSecretCondition(&conditions, "", 1) || len(conditions) != 0 {
+		t.Fatal("expected no condition while nothing was ever missing")
+	}
+	if !setMissingSecretCondition(&conditions, "Secret default/x referenced by spec.adminPasswordSecretRef not found", 1) {
+		t.Fatal("expected the condition to be set")
+	}
+	if !setMissingSecretCondition(&conditions, "", 1) {
+		t.Fatal("expected the condition to clear")
+	}
+	if cond := meta.FindStatusCondition(conditions, missingSecretCondition); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Errorf("expected MissingSecret=False once the Secret exists, got %+v", cond)
+	}
+}
+
+func TestSiteBackupReconciler_missingSecret(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	site.Spec.BenchRef.Namespace = "default"
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec: vyogotechv1alpha1.SiteBackupSpec{
+			Site: "site.local",
+			Storage: &vyogotechv1alpha1.BackupStorageConfig{
+				Type: "s3",
+				S3: &vyogotechv1alpha1.S3Config{
+					Endpoint:        "https://s3.example.com",
+					Bucket:          "backups",
+					AccessKeySecret: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "s3"}, Key: "access-key"},
+					SecretKeySecret: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "s3"}, Key: "secret-key"},
+				},
+			},
+		},
+	}
+	siteReconciler, _ := newInitJobTestReconciler()
+	c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).
+		WithObjects(site, bench, siteBackup).WithStatusSubresource(siteBackup).Build()
+	r := &SiteBackupReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "backup", Namespace: "default"}}
+	jobKey := types.NamespacedName{Name: "backup-backup", Namespace: "default"}
+
+	// The first reconcile adds the finalizer, the second checks the Secrets
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+	}
+	if err := c.Get(ctx, req.NamespacedName, siteBackup); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	cond := meta.FindStatusCondition(siteBackup.Status.Conditions, missingSecretCondition)
+	if cond == nil || cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, "default/s3") {
+		t.Fatalf("expected MissingSecret=True naming default/s3, got %+v", cond)
+	}
+	if siteBackup.Status.Phase != "Pending" {
+		t.Errorf("expected phase Pending, got %q", siteBackup.Status.Phase)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); err == nil {
+		t.Fatal("expected no backup job while the Secret is missing")
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "default"},
+		Data:       map[string][]byte{"access-key": []byte("a"), "secret-key": []byte("s")},
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); err != nil {
+		t.Fatalf("expected backup job once the Secret exists: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, siteBackup); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if cond := meta.FindStatusCondition(siteBackup.Status.Conditions, missingSecretCondition); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Errorf("expected MissingSecret=False, got %+v", cond)
+	}
+}
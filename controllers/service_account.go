@@ -0,0 +1,58 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// componentServiceAccount returns the ServiceAccount of the bench deployments, empty for
+// the namespace default
+func componentServiceAccount(bench *vyogotechv1alpha1.FrappeBench) string {
+	return bench.Spec.ServiceAccountName
+}
+
+// jobServiceAccount returns the ServiceAccount of the bench and site Jobs, empty for the
+// namespace default
+func jobServiceAccount(bench *vyogotechv1alpha1.FrappeBench) string {
+	if bench.Spec.JobServiceAccountName != "" {
+		return bench.Spec.JobServiceAccountName
+	}
+	return bench.Spec.ServiceAccountName
+}
+
+// syncServiceAccount brings the ServiceAccount of an existing deployment's pod template in
+// line with spec.serviceAccountName, reporting whether anything changed. The API server
+// fills serviceAccountName and the deprecated serviceAccount in from each other, so both
+// are compared as defaulted and both are set; otherwise clearing the name would be undone.
+func syncServiceAccount(spec *corev1.PodSpec, bench *vyogotechv1alpha1.FrappeBench) bool {
+	name := componentServiceAccount(bench)
+	current, deprecated := spec.ServiceAccountName, spec.DeprecatedServiceAccount
+	if current == "" {
+		current = deprecated
+	}
+	if deprecated == "" {
+		deprecated = current
+	}
+	if current == name && deprecated == name {
+		return false
+	}
+	spec.ServiceAccountName = name
+	spec.DeprecatedServiceAccount = name
+	return true
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestServiceAccountName(t *testing.T) {
+	tests := []struct {
+		name          string
+		componentSA   string
+		jobSA         string
+		wantComponent string
+		wantJob       string
+	}{
+		{name: "unset"},
+		{name: "bench service account", componentSA: "frappe", wantComponent: "frappe", wantJob: "frappe"},
+		{name: "job override", componentSA: "frappe", jobSA: "frappe-jobs", wantComponent: "frappe", wantJob: "frappe-jobs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site, bench := newInitJobTestObjects()
+			bench.Spec.ServiceAccountName = tt.componentSA
+			bench.Spec.JobServiceAccountName = tt.jobSA
+			siteReconciler, c := newInitJobTestReconciler(site, bench)
+			r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+			ctx := context.Background()
+
+			if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+				t.Fatalf("ensureGunicornDeployment: %v", err)
+			}
+			deploy := &appsv1.Deployment{}
+			if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+				t.Fatalf("Get gunicorn Deployment: %v", err)
+			}
+			if got := deploy.Spec.Template.Spec.ServiceAccountName; got != tt.wantComponent {
+				t.Errorf("expected gunicorn service account %q, got %q", tt.wantComponent, got)
+			}
+
+			if _, err := r.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+				t.Fatalf("ensureBenchInitialized: %v", err)
+			}
+			job := &batchv1.Job{}
+			if err := c.Get(ctx, types.NamespacedName{Name: "bench-init", Namespace: "default"}, job); err != nil {
+				t.Fatalf("Get bench init Job: %v", err)
+			}
+			if got := job.Spec.Template.Spec.ServiceAccountName; got != tt.wantJob {
+				t.Errorf("expected bench init service account %q, got %q", tt.wantJob, got)
+			}
+
+			dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+			dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+			if _, err := siteReconciler.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+				t.Fatalf("ensureSiteInitialized: %v", err)
+			}
+			if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, job); err != nil {
+				t.Fatalf("Get site init Job: %v", err)
+			}
+			if got := job.Spec.Template.Spec.ServiceAccountName; got != tt.wantJob {
+				t.Errorf("expected site init service account %q, got %q", tt.wantJob, got)
+			}
+		})
+	}
+}
+
+func TestSyncServiceAccount(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	r := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	bench.Spec.ServiceAccountName = "frappe"
+	if err := r.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("Get gunicorn Deployment: %v", err)
+	}
+	if got := deploy.Spec.Template.Spec.ServiceAccountName; got != "frappe" {
+		t.Errorf("expected the existing deployment to move to service account frappe, got %q", got)
+	}
+}
+
+func TestSyncServiceAccount_defaulted(t *testing.T) {
+	bench := &vyogotechv1alpha1.FrappeBench{}
+	tests := []struct {
+		name       string
+		sa         string
+		spec       corev1.PodSpec
+		wantChange bool
+	}{
+		{name: "defaulted match", sa: "frappe", spec: corev1.PodSpec{ServiceAccountName: "frappe", DeprecatedServiceAccount: "frappe"}},
+		{name: "only the deprecated field", sa: "frappe", spec: corev1.PodSpec{DeprecatedServiceAccount: "frappe"}},
+		{name: "unset", spec: corev1.PodSpec{}},
+		// The API server would copy serviceAccount back into serviceAccountName
+		{name: "cleared name", spec: corev1.PodSpec{ServiceAccountName: "frappe", DeprecatedServiceAccount: "frappe"}, wantChange: true},
+		{name: "renamed", sa: "frappe-web", spec: corev1.PodSpec{ServiceAccountName: "frappe", DeprecatedServiceAccount: "frappe"}, wantChange: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bench.Spec.ServiceAccountName = tt.sa
+			spec := tt.spec
+			if got := syncServiceAccount(&spec, bench); got != tt.wantChange {
+				t.Errorf("syncServiceAccount() = %v, want %v", got, tt.wantChange)
+			}
+			if tt.wantChange && (spec.ServiceAccountName != tt.sa || spec.DeprecatedServiceAccount != tt.sa) {
+				t.Errorf("expected both fields to be %q, got %q and %q", tt.sa, spec.ServiceAccountName, spec.DeprecatedServiceAccount)
+			}
+		})
+	}
+}
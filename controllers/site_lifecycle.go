@@ -119,22 +119,17 @@ func (r *FrappeSiteReconciler) ensureInitSecrets(ctx context.Context, site *vyog
 	if site.Spec.DBConfig.Provider != "" {
 		dbProvider = site.Spec.DBConfig.Provider
 	}
+	// The provider knows the actual engine, which may come from the bench defaults
+	if dbInfo != nil && dbInfo.Provider != "" {
+		dbProvider = dbInfo.Provider
+	}
 
 	// Get apps to install if specified
 	appsToInstall := ""
 	if len(site.Spec.Apps) > 0 {
 		var validApps []string
 		for _, app := range site.Spec.Apps {
-			isValid := true
-			for _, char := range app {
-				if !((char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
-					(char >= '0' && char <= '9') || char == '_' || char == '-') {
-					isValid = false
-					break
-				}
-			}
-
-			if !isValid {
+			if !vyogotechv1alpha1.IsValidAppName(app) {
 				r.Recorder.Event(site, corev1.EventTypeWarning, "InvalidAppName",
 					fmt.Sprintf("App '%s' contains invalid characters and will be skipped", app))
 			} else {
@@ -170,6 +165,17 @@ func (r *FrappeSiteReconciler) ensureInitSecrets(ctx context.Context, site *vyog
 		secretData["db_password"] = []byte(dbCreds.Password)
 	}
 
+	// Redis URLs of the bench, which may point at an external redis with a password
+	redisCache, redisQueue, err := resolveRedisURLs(ctx, r.Client, bench)
+	if err != nil {
+		if isRedisConfigInvalid(err) {
+			r.Recorder.Event(site, corev1.EventTypeWarning, "RedisConfigInvalid", err.Error())
+		}
+		return err
+	}
+	secretData[redisCacheURLKey] = []byte(redisCache)
+	secretData[redisQueueURLKey] = []byte(redisQueue)
+
 	// Create or update the secret
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -189,7 +195,7 @@ func (r *FrappeSiteReconciler) ensureInitSecrets(ctx context.Context, site *vyog
 	}
 
 	var existing corev1.Secret
-	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: site.Namespace}, &existing)
+	err = r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: site.Namespace}, &existing)
 	if err != nil && errors.IsNotFound(err) {
 		if err := r.Create(ctx, secret); err != nil {
 			return err
@@ -246,6 +252,9 @@ func (r *FrappeSiteReconciler) resolveDBConfig(site *vyogotechv1alpha1.FrappeSit
 	if config.Resources == nil {
 		config.Resources = bench.Spec.DBConfig.Resources
 	}
+	if !config.SkipDropOnDelete {
+		config.SkipDropOnDelete = bench.Spec.DBConfig.SkipDropOnDelete
+	}
 
 	return config
 }
@@ -261,6 +270,12 @@ func (r *FrappeSiteReconciler) resolveDomain(ctx context.Context, site *vyogotec
 		return domain, "bench-suffix"
 	}
 
+	// A missing ConfigMap means no operator-wide suffix
+	operatorConfig, _ := r.getOperatorConfig(ctx, site.Namespace)
+	if suffix := operatorDomainSuffix(operatorConfig); suffix != "" {
+		return site.Spec.SiteName + suffix, "operator-default"
+	}
+
 	autoDetect := true
 	if bench.Spec.DomainConfig != nil && bench.Spec.DomainConfig.AutoDetect != nil {
 		autoDetect = *bench.Spec.DomainConfig.AutoDetect
@@ -278,6 +293,35 @@ func (r *FrappeSiteReconciler) resolveDomain(ctx context.Context, site *vyogotec
 	return site.Spec.SiteName, "sitename-default"
 }
 
+// operatorDomainSuffix returns the defaultDomainSuffix of the operator config, with a
+// leading dot added when it's missing
+func operatorDomainSuffix(operatorConfig *corev1.ConfigMap) string {
+	if operatorConfig == nil {
+		return ""
+	}
+	suffix := strings.TrimSpace(operatorConfig.Data["defaultDomainSuffix"])
+	if suffix != "" && !strings.HasPrefix(suffix, ".") {
+		suffix = "." + suffix
+	}
+	return suffix
+}
+
+// getDBRootCredentials retrieves the root credentials of the site's database server,
+// which bench needs to drop or recreate the site database. dbKind names the server for
+// messages.
+func (r *FrappeSiteReconciler) getDBRootCredentials(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) (user, password, dbKind string, err error) {
+	switch dbConfig := r.resolveDBConfig(site, bench); dbConfig.Provider {
+	case "postgres":
+		user, password, err = r.getPostgresRootCredentials(ctx, site)
+		return user, password, "PostgreSQL", err
+	case "external":
+		user, password, err = r.getExternalDBCredentials(ctx, site, dbConfig)
+		return user, password, "external database", err
+	}
+	user, password, err = r.getMariaDBRootCredentials(ctx, site)
+	return user, password, "MariaDB", err
+}
+
 // getMariaDBRootCredentials retrieves root credentials for database operations
 func (r *FrappeSiteReconciler) getMariaDBRootCredentials(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (string, string, error) {
 	if site.Spec.DBConfig.Mode == "dedicated" {
@@ -310,7 +354,9 @@ func (r *FrappeSiteReconciler) getMariaDBRootCredentials(ctx context.Context, si
 			Version: "v1alpha1",
 			Kind:    "MariaDB",
 		})
-		err := r.Get(ctx, types.NamespacedName{Name: mariadbName, Namespace: mariadbNamespace}, mariadbCR)
+		err := withLookupTimeout(ctx, r.LookupTimeout, "MariaDB API", func(ctx context.Context) error {
+			return r.Get(ctx, types.NamespacedName{Name: mariadbName, Namespace: mariadbNamespace}, mariadbCR)
+		})
 		if err != nil {
 			return "", "", err
 		}
@@ -339,6 +385,57 @@ func (r *FrappeSiteReconciler) getMariaDBRootCredentials(ctx context.Context, si
 	return "", "", fmt.Errorf("unsupported database mode: %s", site.Spec.DBConfig.Mode)
 }
 
+// getExternalDBCredentials returns the username and password of the external provider's
+// connectionSecretRef; there is no separate root account for a database the operator doesn't run
+func (r *FrappeSiteReconciler) getExternalDBCredentials(ctx context.Context, site *vyogotechv1alpha1.FrappeSite, dbConfig vyogotechv1alpha1.DatabaseConfig) (string, string, error) {
+	ref := dbConfig.ConnectionSecretRef
+	if ref == nil {
+		return "", "", fmt.Errorf("connectionSecretRef is required for external database provider to drop the site")
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: site.Namespace}, secret); err != nil {
+		return "", "", fmt.Errorf("failed to get database secret %s: %w", ref.Name, err)
+	}
+	username, password := secret.Data["username"], secret.Data["password"]
+	if len(username) == 0 || len(password) == 0 {
+		return "", "", fmt.Errorf("username or password key not found in secret %s", ref.Name)
+	}
+	return string(username), string(password), nil
+}
+
+// getPostgresRootCredentials retrieves the superuser credentials of the site's CloudNativePG
+// Cluster, which needs spec.enableSuperuserAccess for bench drop-site
+func (r *FrappeSiteReconciler) getPostgresRootCredentials(ctx context.Context, site *vyogotechv1alpha1.FrappeSite) (string, string, error) {
+	clusterName, clusterNamespace, err := database.PostgresClusterRef(site)
+	if err != nil {
+		return "", "", err
+	}
+
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(database.PostgresClusterGVK)
+	err = withLookupTimeout(ctx, r.LookupTimeout, "CloudNativePG API", func(ctx context.Context) error {
+		return r.Get(ctx, types.NamespacedName{Name: clusterName, Namespace: clusterNamespace}, cluster)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	secretName, _, _ := unstructured.NestedString(cluster.Object, "spec", "superuserSecret", "name")
+	if secretName == "" {
+		secretName = fmt.Sprintf("%s-superuser", clusterName)
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: clusterNamespace}, secret); err != nil {
+		return "", "", fmt.Errorf("failed to get PostgreSQL superuser secret %s (is spec.enableSuperuserAccess set on the Cluster?): %w", secretName, err)
+	}
+
+	username, password := secret.Data[corev1.BasicAuthUsernameKey], secret.Data[corev1.BasicAuthPasswordKey]
+	if len(username) == 0 || len(password) == 0 {
+		return "", "", fmt.Errorf("username or password key not found in secret %s", secretName)
+	}
+	return string(username), string(password), nil
+}
+
 // getRequeueAttempt returns the current requeue attempt from the site annotation
 func (r *FrappeSiteReconciler) getRequeueAttempt(site *vyogotechv1alpha1.FrappeSite) int {
 	if site.Annotations == nil {
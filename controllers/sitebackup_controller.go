@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -35,15 +36,23 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/progress"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
 )
 
-const siteBackupFinalizer = "vyogo.tech/finalizer"
+const (
+	siteBackupFinalizer = "vyogo.tech/finalizer"
+	// siteBackupLogTailLines is how much of a failed backup pod's log is kept in status.logTail
+	siteBackupLogTailLines = 50
+)
 
 // SiteBackupReconciler reconciles a SiteBackup object
 type SiteBackupReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// LogReader tails backup job logs for progress markers; progress is not reported when nil
+	LogReader progress.LogReader
 }
 
 //+kubebuilder:rbac:groups=vyogo.tech,resources=sitebackups,verbs=get;list;watch;create;update;patch;delete
@@ -51,6 +60,11 @@ type SiteBackupReconciler struct {
 //+kubebuilder:rbac:groups=vyogo.tech,resources=sitebackups/finalizers,verbs=update
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;create;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;update;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -88,34 +102,81 @@ func (r *SiteBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
+	// Nothing is read or created in a foreign execution namespace unless it is allowed
+	if allowed, err := r.checkExecutionNamespace(ctx, siteBackup); err != nil || !allowed {
+		return ctrl.Result{}, err
+	}
+
 	// Find the associated FrappeSite
 	siteList := &vyogotechv1alpha1.FrappeSiteList{}
 	if err := r.List(ctx, siteList, client.InNamespace(req.Namespace)); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	var benchRef *vyogotechv1alpha1.NamespacedName
-	for _, site := range siteList.Items {
-		if site.Spec.SiteName == siteBackup.Spec.Site {
-			benchRef = site.Spec.BenchRef
+	var site *vyogotechv1alpha1.FrappeSite
+	for i := range siteList.Items {
+		if siteList.Items[i].Spec.SiteName == siteBackup.Spec.Site {
+			site = &siteList.Items[i]
 			break
 		}
 	}
 
-	if benchRef == nil {
+	if site == nil || site.Spec.BenchRef == nil {
 		err := fmt.Errorf("no FrappeSite found for site %s", siteBackup.Spec.Site)
 		logger.Error(err, "cannot proceed with backup")
 		return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Failed", err.Error(), "")
 	}
+	// The backup job mounts the bench's sites PVC, which only works in the bench's
+	// namespace or through spec.executionNamespace's volume mirror
+	if err := site.ValidateBenchNamespace(); err != nil {
+		logger.Error(err, "cannot proceed with backup")
+		return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Failed", err.Error(), "")
+	}
+	sizeHint := site.Spec.SizeHint
 
 	// Get the bench
 	bench := &vyogotechv1alpha1.FrappeBench{}
-	if err := r.Get(ctx, client.ObjectKey{Name: benchRef.Name, Namespace: benchRef.Namespace}, bench); err != nil {
+	if err := r.Get(ctx, siteBenchKey(site), bench); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Fail fast on referenced Secrets that don't exist instead of failing deep inside a job
+	if siteBackup.Status.Phase != "Succeeded" && siteBackup.Status.Phase != "Failed" {
+		missingSecret, err := findMissingSecret(ctx, r.Client, siteBackupSecretRefs(siteBackup))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.recordMissingSecret(ctx, siteBackup, missingSecret); err != nil {
+			return ctrl.Result{}, err
+		}
+		if missingSecret != "" {
+			logger.Info("Referenced Secret missing, holding backup", "reason", missingSecret)
+			return ctrl.Result{RequeueAfter: missingSecretRequeue}, nil
+		}
+	}
+
+	if err := validateBackupStorage(siteBackup); err != nil {
+		logger.Error(err, "invalid backup storage")
+		return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Failed", err.Error(), "")
+	}
+	if err := validateBackupMode(siteBackup); err != nil {
+		logger.Error(err, "invalid backup mode")
+		return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Failed", err.Error(), "")
+	}
+
+	if err := r.ensureBackupVolume(ctx, siteBackup, bench); err != nil {
+		if stderrors.Is(err, errBackupVolumeUnsupported) {
+			logger.Error(err, "cannot run backup in execution namespace")
+			return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Failed", err.Error(), "")
+		}
+		return ctrl.Result{}, err
+	}
+	if err := r.ensureRedactedConfigSecret(ctx, siteBackup); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	if siteBackup.Spec.Schedule == "" {
-		result, err := r.reconcileOneTimeBackup(ctx, siteBackup, bench)
+		result, err := r.reconcileOneTimeBackup(ctx, siteBackup, bench, sizeHint)
 		if err != nil {
 			ReconciliationErrors.WithLabelValues("sitebackup", "backup_error").Inc()
 			ReconciliationDuration.WithLabelValues("sitebackup", "error").Observe(time.Since(startTime).Seconds())
@@ -124,7 +185,7 @@ func (r *SiteBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 		return result, err
 	} else {
-		result, err := r.reconcileScheduledBackup(ctx, siteBackup, bench)
+		result, err := r.reconcileScheduledBackup(ctx, siteBackup, bench, sizeHint)
 		if err != nil {
 			ReconciliationErrors.WithLabelValues("sitebackup", "schedule_error").Inc()
 			ReconciliationDuration.WithLabelValues("sitebackup", "error").Observe(time.Since(startTime).Seconds())
@@ -137,16 +198,17 @@ func (r *SiteBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 func (r *SiteBackupReconciler) handleFinalizer(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup) error {
 	logger := log.FromContext(ctx)
-	jobName := siteBackup.Name + "-backup"
+	jobName := backupJobName(siteBackup)
+	jobNamespace := backupExecutionNamespace(siteBackup)
 
 	if siteBackup.Spec.Schedule == "" {
 		// One-time backup: delete Job
 		job := &batchv1.Job{}
-		err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: siteBackup.Namespace}, job)
+		err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: jobNamespace}, job)
 		if err != nil && !errors.IsNotFound(err) {
 			return err
 		}
-		if err == nil {
+		if err == nil && (!isCrossNamespaceBackup(siteBackup) || ownsBackupObject(siteBackup, job)) {
 			logger.Info("Deleting associated Job", "Job", job.Name)
 			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
 				return err
@@ -155,27 +217,37 @@ func (r *SiteBackupReconciler) handleFinalizer(ctx context.Context, siteBackup *
 	} else {
 		// Scheduled backup: delete CronJob
 		cronJob := &batchv1.CronJob{}
-		err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: siteBackup.Namespace}, cronJob)
+		err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: jobNamespace}, cronJob)
 		if err != nil && !errors.IsNotFound(err) {
 			return err
 		}
-		if err == nil {
+		if err == nil && (!isCrossNamespaceBackup(siteBackup) || ownsBackupObject(siteBackup, cronJob)) {
 			logger.Info("Deleting associated CronJob", "CronJob", cronJob.Name)
 			if err := r.Delete(ctx, cronJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
 				return err
 			}
 		}
 	}
-	return nil
+	if err := r.deleteRedactedConfigJobSecret(ctx, siteBackup); err != nil {
+		return err
+	}
+	return r.deleteBackupVolume(ctx, siteBackup)
 }
 
 // reconcileOneTimeBackup handles one-time backup creation and status updates
-func (r *SiteBackupReconciler) reconcileOneTimeBackup(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench) (ctrl.Result, error) {
+func (r *SiteBackupReconciler) reconcileOneTimeBackup(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench, sizeHint string) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
-	jobName := siteBackup.Name + "-backup"
+	jobName := backupJobName(siteBackup)
+
+	// Jobs in another namespace can't be owned by the SiteBackup, so their status
+	// changes don't trigger a reconcile; poll them instead
+	var pollResult ctrl.Result
+	if isCrossNamespaceBackup(siteBackup) {
+		pollResult.RequeueAfter = progressPollInterval
+	}
 
 	job := &batchv1.Job{}
-	err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: siteBackup.Namespace}, job)
+	err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: backupExecutionNamespace(siteBackup)}, job)
 
 	if errors.IsNotFound(err) {
 		if siteBackup.Status.Phase == "Succeeded" || siteBackup.Status.Phase == "Failed" {
@@ -183,12 +255,13 @@ func (r *SiteBackupReconciler) reconcileOneTimeBackup(ctx context.Context, siteB
 			return ctrl.Result{}, nil
 		}
 		job = r.buildBackupJob(siteBackup, bench)
+		job.Spec.Template.Spec.Containers[0].Resources = siteJobResources(sizeHint)
 		if err := r.Create(ctx, job); err != nil {
 			logger.Error(err, "Failed to create backup job")
 			return ctrl.Result{}, err
 		}
-		logger.Info("Created backup job", "job", job.Name)
-		return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Running", "Backup job created", job.Name)
+		logger.Info("Created backup job", "job", job.Name, "namespace", job.Namespace)
+		return pollResult, r.updateSiteBackupStatus(ctx, siteBackup, "Running", "Backup job created", job.Name)
 	}
 
 	if err != nil {
@@ -198,29 +271,54 @@ func (r *SiteBackupReconciler) reconcileOneTimeBackup(ctx context.Context, siteB
 
 	if job.Status.Succeeded > 0 {
 		if siteBackup.Status.Phase != "Succeeded" {
-			return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Succeeded", "Backup completed successfully", job.Name)
+			return ctrl.Result{}, r.recordSiteBackupSuccess(ctx, siteBackup, job)
 		}
 	} else if job.Status.Failed > 0 {
 		if siteBackup.Status.Phase != "Failed" {
-			return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Failed", "Backup job failed", job.Name)
+			JobFailures.WithLabelValues(jobFailureBackup).Inc()
+			return ctrl.Result{}, r.recordSiteBackupFailure(ctx, siteBackup, job)
 		}
 	} else {
 		if siteBackup.Status.Phase != "Running" {
-			return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Running", "Backup job running", job.Name)
+			return pollResult, r.updateSiteBackupStatus(ctx, siteBackup, "Running", "Backup job running", job.Name)
 		}
+		if r.LogReader != nil {
+			return r.updateSiteBackupProgress(ctx, siteBackup, job)
+		}
+		return pollResult, nil
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// updateSiteBackupProgress records the latest progress of a running backup job and
+// polls again after progressPollInterval; status is only written when progress moved
+func (r *SiteBackupReconciler) updateSiteBackupProgress(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, job *batchv1.Job) (ctrl.Result, error) {
+	next, err := readJobProgress(ctx, r.Client, r.LogReader, job, "backup")
+	if err != nil {
+		// Progress is best effort; keep polling without failing the backup
+		log.FromContext(ctx).Error(err, "Failed to read backup progress", "job", job.Name)
+		return ctrl.Result{RequeueAfter: progressPollInterval}, nil
+	}
+	if !progressChanged(siteBackup.Status.Progress, next) {
+		return ctrl.Result{RequeueAfter: progressPollInterval}, nil
+	}
+
+	latest := &vyogotechv1alpha1.SiteBackup{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(siteBackup), latest); err != nil {
+		return ctrl.Result{}, err
+	}
+	latest.Status.Progress = next
+	return ctrl.Result{RequeueAfter: progressPollInterval}, r.Status().Update(ctx, latest)
+}
+
 // reconcileScheduledBackup handles scheduled backup creation
-func (r *SiteBackupReconciler) reconcileScheduledBackup(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench) (ctrl.Result, error) {
+func (r *SiteBackupReconciler) reconcileScheduledBackup(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench, sizeHint string) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
-	cronJobName := siteBackup.Name + "-backup"
-
 	desiredCronJob := r.buildBackupCronJob(siteBackup, bench)
+	desiredCronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Resources = siteJobResources(sizeHint)
 	currentCronJob := &batchv1.CronJob{}
-	err := r.Get(ctx, client.ObjectKey{Name: cronJobName, Namespace: siteBackup.Namespace}, currentCronJob)
+	err := r.Get(ctx, client.ObjectKeyFromObject(desiredCronJob), currentCronJob)
 
 	if errors.IsNotFound(err) {
 		if err := r.Create(ctx, desiredCronJob); err != nil {
@@ -239,6 +337,10 @@ func (r *SiteBackupReconciler) reconcileScheduledBackup(ctx context.Context, sit
 	// CronJob exists, check if it needs updating
 	if !reflect.DeepEqual(desiredCronJob.Spec, currentCronJob.Spec) {
 		currentCronJob.Spec = desiredCronJob.Spec
+		if currentCronJob.Labels == nil {
+			currentCronJob.Labels = map[string]string{}
+		}
+		currentCronJob.Labels["backupMode"] = desiredCronJob.Labels["backupMode"]
 		if err := r.Update(ctx, currentCronJob); err != nil {
 			logger.Error(err, "Failed to update backup cronjob")
 			return ctrl.Result{}, err
@@ -251,13 +353,32 @@ func (r *SiteBackupReconciler) reconcileScheduledBackup(ctx context.Context, sit
 		return ctrl.Result{}, r.updateSiteBackupStatus(ctx, siteBackup, "Scheduled", "Scheduled backup active", currentCronJob.Name)
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, r.recordScheduledBackupFiles(ctx, siteBackup, currentCronJob)
+}
+
+// backupCommand runs the backup through the progress wrapper script; the job args
+// start with "bench" (the script's $0) followed by the bench arguments
+func backupCommand() []string {
+	return []string{"bash", "-c", scripts.MustGetScript(scripts.BackupProgress)}
+}
+
+// backupEnv returns the environment of the backup container
+func backupEnv(siteBackup *vyogotechv1alpha1.SiteBackup) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	if siteBackup.Spec.RedactConfig {
+		env = append(env, redactedConfigEnv(siteBackup)...)
+	}
+	env = append(env, backupModeEnv(siteBackup)...)
+	return append(env, backupS3Env(siteBackup)...)
 }
 
-// buildBackupArgs creates the command arguments for the backup job
+// buildBackupArgs creates the command arguments for the backup job. A files-only backup
+// drops the database options; bench still dumps the database, which the backup script
+// discards.
 func (r *SiteBackupReconciler) buildBackupArgs(siteBackup *vyogotechv1alpha1.SiteBackup) []string {
+	mode := backupMode(siteBackup)
 	args := []string{"--site", siteBackup.Spec.Site, "backup"}
-	if siteBackup.Spec.WithFiles {
+	if mode != backupModeDBOnly {
 		args = append(args, "--with-files")
 	}
 	if siteBackup.Spec.Compress {
@@ -266,7 +387,7 @@ func (r *SiteBackupReconciler) buildBackupArgs(siteBackup *vyogotechv1alpha1.Sit
 	if siteBackup.Spec.BackupPath != "" {
 		args = append(args, "--backup-path", siteBackup.Spec.BackupPath)
 	}
-	if siteBackup.Spec.BackupPathDB != "" {
+	if siteBackup.Spec.BackupPathDB != "" && mode != backupModeFilesOnly {
 		args = append(args, "--backup-path-db", siteBackup.Spec.BackupPathDB)
 	}
 	if siteBackup.Spec.BackupPathConf != "" {
@@ -278,10 +399,10 @@ func (r *SiteBackupReconciler) buildBackupArgs(siteBackup *vyogotechv1alpha1.Sit
 	if siteBackup.Spec.BackupPathPrivateFiles != "" {
 		args = append(args, "--backup-path-private-files", siteBackup.Spec.BackupPathPrivateFiles)
 	}
-	if len(siteBackup.Spec.Exclude) > 0 {
+	if len(siteBackup.Spec.Exclude) > 0 && mode != backupModeFilesOnly {
 		args = append(args, "--exclude", strings.Join(siteBackup.Spec.Exclude, ","))
 	}
-	if len(siteBackup.Spec.Include) > 0 {
+	if len(siteBackup.Spec.Include) > 0 && mode != backupModeFilesOnly {
 		args = append(args, "--include", strings.Join(siteBackup.Spec.Include, ","))
 	}
 	if siteBackup.Spec.IgnoreBackupConf {
@@ -295,34 +416,41 @@ func (r *SiteBackupReconciler) buildBackupArgs(siteBackup *vyogotechv1alpha1.Sit
 
 // buildBackupJob creates a Job for one-time backup
 func (r *SiteBackupReconciler) buildBackupJob(siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench) *batchv1.Job {
-	jobName := siteBackup.Name + "-backup"
-	args := r.buildBackupArgs(siteBackup)
+	args := append([]string{"bench"}, r.buildBackupArgs(siteBackup)...)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      jobName,
-			Namespace: siteBackup.Namespace,
+			Name:      backupJobName(siteBackup),
+			Namespace: backupExecutionNamespace(siteBackup),
 			Labels: map[string]string{
 				"app":        "frappe",
 				"site":       siteBackup.Spec.Site,
 				"backup":     "true",
 				"backupType": "one-time",
+				"backupMode": backupMode(siteBackup),
 			},
 		},
 		Spec: batchv1.JobSpec{
 			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: jobPodAnnotations(bench),
+				},
 				Spec: corev1.PodSpec{
-					RestartPolicy: corev1.RestartPolicyNever,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ImagePullSecrets:   imagePullSecrets(bench),
+					ServiceAccountName: jobServiceAccount(bench),
 					Containers: []corev1.Container{
 						{
 							Name:    "backup",
 							Image:   r.getBenchImage(bench),
-							Command: []string{"bench"},
+							Command: backupCommand(),
 							Args:    args,
+							Env:     backupEnv(siteBackup),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "sites",
-									MountPath: "/home/frappe/frappe-bench/sites",
+									MountPath: sitesMountPath,
+									SubPath:   sitesVolumeSubPath,
 								},
 							},
 						},
@@ -332,7 +460,7 @@ func (r *SiteBackupReconciler) buildBackupJob(siteBackup *vyogotechv1alpha1.Site
 							Name: "sites",
 							VolumeSource: corev1.VolumeSource{
 								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-									ClaimName: r.getSitesPVCName(bench),
+									ClaimName: r.backupClaimName(siteBackup, bench),
 								},
 							},
 						},
@@ -341,46 +469,67 @@ func (r *SiteBackupReconciler) buildBackupJob(siteBackup *vyogotechv1alpha1.Site
 			},
 		},
 	}
+	labelJob(job, jobLabels(jobOperationBackup, bench.Name, siteBackup.Spec.Site))
 	applyDefaultJobTTL(&job.Spec)
+	syncPodPlacement(&job.Spec.Template.Spec, benchJobPodConfig(bench))
 
-	controllerutil.SetControllerReference(siteBackup, job, r.Scheme)
+	r.setBackupOwner(siteBackup, job)
 	return job
 }
 
+// backupConcurrencyPolicy returns spec.concurrencyPolicy, defaulting to Forbid so a slow
+// backup is never overlapped by the next run
+func backupConcurrencyPolicy(siteBackup *vyogotechv1alpha1.SiteBackup) batchv1.ConcurrencyPolicy {
+	if siteBackup.Spec.ConcurrencyPolicy == "" {
+		return batchv1.ForbidConcurrent
+	}
+	return batchv1.ConcurrencyPolicy(siteBackup.Spec.ConcurrencyPolicy)
+}
+
 // buildBackupCronJob creates a CronJob for scheduled backup
 func (r *SiteBackupReconciler) buildBackupCronJob(siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench) *batchv1.CronJob {
-	cronJobName := siteBackup.Name + "-backup"
-	args := r.buildBackupArgs(siteBackup)
+	args := append([]string{"bench"}, r.buildBackupArgs(siteBackup)...)
 
 	cronJob := &batchv1.CronJob{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      cronJobName,
-			Namespace: siteBackup.Namespace,
+			Name:      backupJobName(siteBackup),
+			Namespace: backupExecutionNamespace(siteBackup),
 			Labels: map[string]string{
 				"app":        "frappe",
 				"site":       siteBackup.Spec.Site,
 				"backup":     "true",
 				"backupType": "scheduled",
+				"backupMode": backupMode(siteBackup),
 			},
 		},
 		Spec: batchv1.CronJobSpec{
-			Schedule:          siteBackup.Spec.Schedule,
-			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			Schedule:                   siteBackup.Spec.Schedule,
+			ConcurrencyPolicy:          backupConcurrencyPolicy(siteBackup),
+			SuccessfulJobsHistoryLimit: siteBackup.Spec.SuccessfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     siteBackup.Spec.FailedJobsHistoryLimit,
+			StartingDeadlineSeconds:    siteBackup.Spec.StartingDeadlineSeconds,
 			JobTemplate: batchv1.JobTemplateSpec{
 				Spec: batchv1.JobSpec{
 					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: jobPodAnnotations(bench),
+						},
 						Spec: corev1.PodSpec{
-							RestartPolicy: corev1.RestartPolicyNever,
+							RestartPolicy:      corev1.RestartPolicyNever,
+							ImagePullSecrets:   imagePullSecrets(bench),
+							ServiceAccountName: jobServiceAccount(bench),
 							Containers: []corev1.Container{
 								{
 									Name:    "backup",
 									Image:   r.getBenchImage(bench),
-									Command: []string{"bench"},
+									Command: backupCommand(),
 									Args:    args,
+									Env:     backupEnv(siteBackup),
 									VolumeMounts: []corev1.VolumeMount{
 										{
 											Name:      "sites",
-											MountPath: "/home/frappe/frappe-bench/sites",
+											MountPath: sitesMountPath,
+											SubPath:   sitesVolumeSubPath,
 										},
 									},
 								},
@@ -390,7 +539,7 @@ func (r *SiteBackupReconciler) buildBackupCronJob(siteBackup *vyogotechv1alpha1.
 									Name: "sites",
 									VolumeSource: corev1.VolumeSource{
 										PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-											ClaimName: r.getSitesPVCName(bench),
+											ClaimName: r.backupClaimName(siteBackup, bench),
 										},
 									},
 								},
@@ -402,14 +551,34 @@ func (r *SiteBackupReconciler) buildBackupCronJob(siteBackup *vyogotechv1alpha1.
 		},
 	}
 
-	controllerutil.SetControllerReference(siteBackup, cronJob, r.Scheme)
+	labelCronJob(cronJob, jobLabels(jobOperationBackup, bench.Name, siteBackup.Spec.Site))
+	syncPodPlacement(&cronJob.Spec.JobTemplate.Spec.Template.Spec, benchJobPodConfig(bench))
+	r.setBackupOwner(siteBackup, cronJob)
 	applyDefaultJobTTL(&cronJob.Spec.JobTemplate.Spec)
 
 	return cronJob
 }
 
+// setBackupOwner sets the SiteBackup as controller of obj; owner references can't cross
+// namespaces, so objects in another execution namespace are labelled instead and
+// cleaned up by the finalizer
+func (r *SiteBackupReconciler) setBackupOwner(siteBackup *vyogotechv1alpha1.SiteBackup, obj client.Object) {
+	if isCrossNamespaceBackup(siteBackup) {
+		labels := obj.GetLabels()
+		for k, v := range backupOwnerLabels(siteBackup) {
+			labels[k] = v
+		}
+		obj.SetLabels(labels)
+		return
+	}
+	controllerutil.SetControllerReference(siteBackup, obj, r.Scheme)
+}
+
 // getBenchImage returns the image to use for the bench
 func (r *SiteBackupReconciler) getBenchImage(bench *vyogotechv1alpha1.FrappeBench) string {
+	if image := channelImage(bench); image != "" {
+		return image
+	}
 	if bench.Spec.ImageConfig != nil && bench.Spec.ImageConfig.Repository != "" {
 		image := bench.Spec.ImageConfig.Repository
 		if bench.Spec.ImageConfig.Tag != "" {
@@ -430,6 +599,12 @@ func (r *SiteBackupReconciler) getSitesPVCName(bench *vyogotechv1alpha1.FrappeBe
 
 // updateSiteBackupStatus updates the status of a SiteBackup resource
 func (r *SiteBackupReconciler) updateSiteBackupStatus(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, phase, message, jobName string) error {
+	return r.updateSiteBackupStatusWith(ctx, siteBackup, phase, message, jobName, nil)
+}
+
+// updateSiteBackupStatusWith updates the status of a SiteBackup resource like
+// updateSiteBackupStatus, then applies update (when set) in the same write
+func (r *SiteBackupReconciler) updateSiteBackupStatusWith(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, phase, message, jobName string, update func(*vyogotechv1alpha1.SiteBackupStatus)) error {
 	// Re-get the latest version to avoid conflicts
 	latest := &vyogotechv1alpha1.SiteBackup{}
 	if err := r.Get(ctx, client.ObjectKeyFromObject(siteBackup), latest); err != nil {
@@ -439,11 +614,57 @@ func (r *SiteBackupReconciler) updateSiteBackupStatus(ctx context.Context, siteB
 	latest.Status.Phase = phase
 	latest.Status.Message = message
 	latest.Status.LastBackupJob = jobName
+	latest.Status.Mode = backupMode(latest)
 
 	if phase == "Succeeded" {
 		latest.Status.LastBackup = metav1.Now()
+		latest.Status.S3 = backupS3Location(latest, jobName)
+		completeProgress(latest.Status.Progress)
 	}
+	if update != nil {
+		update(&latest.Status)
+	}
+
+	return r.Status().Update(ctx, latest)
+}
 
+// recordSiteBackupFailure marks the backup failed with the tail of the failed pod's log
+// and a summary of it
+func (r *SiteBackupReconciler) recordSiteBackupFailure(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, job *batchv1.Job) error {
+	failure, err := readJobFailure(ctx, r.Client, r.LogReader, job, "backup", siteBackupLogTailLines)
+	if err != nil {
+		// The log is best effort; the failure itself is still recorded
+		log.FromContext(ctx).Error(err, "Failed to read backup job log", "job", job.Name)
+	}
+	message := "Backup job failed"
+	if failure.Summary != "" {
+		message += ": " + failure.Summary
+	}
+	return r.updateSiteBackupStatusWith(ctx, siteBackup, "Failed", message, job.Name, func(status *vyogotechv1alpha1.SiteBackupStatus) {
+		status.LogTail = failure.LogTail
+		status.FailureSummary = failure.Summary
+	})
+}
+
+// recordMissingSecret records the MissingSecret condition and, while a Secret is
+// missing, holds the backup in Pending with the Secret named in the message
+func (r *SiteBackupReconciler) recordMissingSecret(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, missingSecret string) error {
+	latest := &vyogotechv1alpha1.SiteBackup{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(siteBackup), latest); err != nil {
+		return err
+	}
+	changed := setMissingSecretCondition(&latest.Status.Conditions, missingSecret, latest.Generation)
+	if missingSecret != "" && (latest.Status.Phase != "Pending" || latest.Status.Message != missingSecret) {
+		latest.Status.Phase = "Pending"
+		latest.Status.Message = missingSecret
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if missingSecret != "" {
+		r.Recorder.Event(siteBackup, corev1.EventTypeWarning, "MissingSecret", missingSecret)
+	}
 	return r.Status().Update(ctx, latest)
 }
 
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -138,8 +139,12 @@ func TestSiteBackupReconciler_buildBackupJob(t *testing.T) {
 	if len(job.Spec.Template.Spec.Containers) != 1 {
 		t.Fatal("expected 1 container")
 	}
-	if job.Spec.Template.Spec.Containers[0].Command[0] != "bench" {
-		t.Error("expected command bench")
+	container := job.Spec.Template.Spec.Containers[0]
+	if container.Command[0] != "bash" || !strings.Contains(container.Command[2], "FRAPPE_PROGRESS") {
+		t.Errorf("expected backup to run through the progress wrapper, got %v", container.Command)
+	}
+	if len(container.Args) < 4 || container.Args[0] != "bench" || container.Args[1] != "--site" {
+		t.Errorf("expected args to start with bench --site, got %v", container.Args)
 	}
 	if job.Spec.TTLSecondsAfterFinished == nil {
 		t.Error("expected TTL on job")
@@ -257,10 +262,97 @@ var _ = Describe("SiteBackup Controller", func() {
 				return k8sClient.Get(ctx, jobKey.NamespacedName, job)
 			}, "10s", "1s").Should(Succeed())
 
-			Expect(job.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{"bench"}))
-			Expect(job.Spec.Template.Spec.Containers[0].Args).To(ContainElements("--site", "test-site.local", "backup"))
+			Expect(job.Spec.Template.Spec.Containers[0].Command[0]).To(Equal("bash"))
+			Expect(job.Spec.Template.Spec.Containers[0].Args).To(ContainElements("bench", "--site", "test-site.local", "backup"))
 			Expect(job.Spec.TTLSecondsAfterFinished).NotTo(BeNil())
 			Expect(*job.Spec.TTLSecondsAfterFinished).To(Equal(resources.DefaultJobTTL))
 		})
 	})
 })
+
+func TestSiteBackupReconciler_redactConfig(t *testing.T) {
+	r := &SiteBackupReconciler{Scheme: runtime.NewScheme()}
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-backup", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", Schedule: "0 2 * * *", RedactConfig: true},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+
+	for name, container := range map[string]corev1.Container{
+		"job":     r.buildBackupJob(siteBackup, bench).Spec.Template.Spec.Containers[0],
+		"cronjob": r.buildBackupCronJob(siteBackup, bench).Spec.JobTemplate.Spec.Template.Spec.Containers[0],
+	} {
+		if len(container.Env) != 2 || container.Env[0].Name != "REDACT_CONFIG" || container.Env[0].Value != "true" {
+			t.Errorf("%s: expected REDACT_CONFIG=true, got %v", name, container.Env)
+		}
+		if ref := container.Env[len(container.Env)-1].ValueFrom; ref == nil || ref.SecretKeyRef == nil || ref.SecretKeyRef.Name != "my-backup-redacted-config" {
+			t.Errorf("%s: expected REDACT_CONFIG_KEY from the redacted config Secret, got %v", name, container.Env)
+		}
+		if !strings.Contains(container.Command[2], redactedConfigValue) {
+			t.Errorf("%s: expected the backup wrapper to redact the config backup", name)
+		}
+	}
+
+	siteBackup.Spec.RedactConfig = false
+	if env := r.buildBackupJob(siteBackup, bench).Spec.Template.Spec.Containers[0].Env; len(env) != 0 {
+		t.Errorf("expected no env without redactConfig, got %v", env)
+	}
+}
+
+func TestSiteBackupReconciler_cronJobLimits(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(batchv1.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	successful, failed := int32(2), int32(1)
+	deadline := int64(600)
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-backup", Namespace: "default"},
+		Spec: vyogotechv1alpha1.SiteBackupSpec{
+			Site:                       "site.local",
+			Schedule:                   "0 2 * * *",
+			SuccessfulJobsHistoryLimit: &successful,
+			FailedJobsHistoryLimit:     &failed,
+			StartingDeadlineSeconds:    &deadline,
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(siteBackup).WithStatusSubresource(siteBackup).Build()
+	r := &SiteBackupReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "my-backup-backup", Namespace: "default"}
+
+	if _, err := r.reconcileScheduledBackup(ctx, siteBackup, bench, ""); err != nil {
+		t.Fatalf("reconcileScheduledBackup: %v", err)
+	}
+	cronJob := &batchv1.CronJob{}
+	if err := c.Get(ctx, key, cronJob); err != nil {
+		t.Fatalf("Get CronJob: %v", err)
+	}
+	if cronJob.Spec.ConcurrencyPolicy != batchv1.ForbidConcurrent {
+		t.Errorf("expected Forbid by default, got %s", cronJob.Spec.ConcurrencyPolicy)
+	}
+	if *cronJob.Spec.SuccessfulJobsHistoryLimit != 2 || *cronJob.Spec.FailedJobsHistoryLimit != 1 || *cronJob.Spec.StartingDeadlineSeconds != 600 {
+		t.Errorf("expected history limits 2/1 and deadline 600, got %v/%v/%v",
+			*cronJob.Spec.SuccessfulJobsHistoryLimit, *cronJob.Spec.FailedJobsHistoryLimit, *cronJob.Spec.StartingDeadlineSeconds)
+	}
+
+	// Changing the limits updates the existing CronJob
+	successful = 5
+	siteBackup.Spec.ConcurrencyPolicy = "Replace"
+	if _, err := r.reconcileScheduledBackup(ctx, siteBackup, bench, ""); err != nil {
+		t.Fatalf("reconcileScheduledBackup: %v", err)
+	}
+	if err := c.Get(ctx, key, cronJob); err != nil {
+		t.Fatalf("Get CronJob: %v", err)
+	}
+	if *cronJob.Spec.SuccessfulJobsHistoryLimit != 5 || cronJob.Spec.ConcurrencyPolicy != batchv1.ReplaceConcurrent {
+		t.Errorf("expected the CronJob to be updated, got limit %d and policy %s", *cronJob.Spec.SuccessfulJobsHistoryLimit, cronJob.Spec.ConcurrencyPolicy)
+	}
+}
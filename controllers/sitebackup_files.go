@@ -0,0 +1,134 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// backupResult is what the backup script writes to its termination message
+type backupResult struct {
+	Files      []vyogotechv1alpha1.BackupFile `json:"files"`
+	TotalBytes int64                          `json:"totalBytes"`
+	// RedactedConfig is the Fernet token of the values redactConfig removed
+	RedactedConfig string `json:"redactedConfig,omitempty"`
+}
+
+// parseBackupResult reads the backup files from the termination message of a backup job
+func parseBackupResult(message string) (*backupResult, error) {
+	// Keep only the JSON document in case anything else ended up in the message
+	if start := strings.Index(message, "{"); start > 0 {
+		message = message[start:]
+	}
+	result := &backupResult{}
+	if err := json.Unmarshal([]byte(message), result); err != nil {
+		return nil, fmt.Errorf("failed to parse backup result: %w", err)
+	}
+	return result, nil
+}
+
+// readBackupResult returns the files a succeeded backup job reported, or nil for jobs from
+// before the result existed or whose pods are gone
+func (r *SiteBackupReconciler) readBackupResult(ctx context.Context, job *batchv1.Job) *backupResult {
+	message, err := jobTerminationMessage(ctx, r, job)
+	if err == nil && message == "" {
+		err = fmt.Errorf("job %s reported no result", job.Name)
+	}
+	var result *backupResult
+	if err == nil {
+		result, err = parseBackupResult(message)
+	}
+	if err != nil {
+		log.FromContext(ctx).Info("Backup job reported no files", "job", job.Name, "reason", err.Error())
+		return nil
+	}
+	return result
+}
+
+// setBackupFiles records the files of the last successful backup in status
+func setBackupFiles(status *vyogotechv1alpha1.SiteBackupStatus, result *backupResult) {
+	status.BackupFiles = nil
+	status.TotalBytes = 0
+	if result != nil {
+		status.BackupFiles = result.Files
+		status.TotalBytes = result.TotalBytes
+	}
+}
+
+// recordSiteBackupSuccess marks a one-time backup succeeded with the files its job wrote
+func (r *SiteBackupReconciler) recordSiteBackupSuccess(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, job *batchv1.Job) error {
+	message := "Backup completed successfully"
+	if location := backupS3Location(siteBackup, job.Name); location != nil {
+		message = fmt.Sprintf("Backup uploaded to s3://%s/%s", location.Bucket, location.Key)
+	}
+	result := r.readBackupResult(ctx, job)
+	if err := r.storeRedactedConfig(ctx, siteBackup, result); err != nil {
+		return err
+	}
+	return r.updateSiteBackupStatusWith(ctx, siteBackup, "Succeeded", message, job.Name, func(status *vyogotechv1alpha1.SiteBackupStatus) {
+		setBackupFiles(status, result)
+	})
+}
+
+// recordScheduledBackupFiles records the files of the latest successful run of a backup
+// CronJob once the CronJob reports a success newer than status.lastBackup
+func (r *SiteBackupReconciler) recordScheduledBackupFiles(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, cronJob *batchv1.CronJob) error {
+	lastSuccess := cronJob.Status.LastSuccessfulTime
+	if lastSuccess == nil || !siteBackup.Status.LastBackup.Before(lastSuccess) {
+		return nil
+	}
+
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(cronJob.Namespace)); err != nil {
+		return err
+	}
+	var latestJob *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !metav1.IsControlledBy(job, cronJob) || job.Status.Succeeded == 0 {
+			continue
+		}
+		if latestJob == nil || latestJob.CreationTimestamp.Before(&job.CreationTimestamp) {
+			latestJob = job
+		}
+	}
+	// The job may already be gone with successfulJobsHistoryLimit: 0
+	var result *backupResult
+	if latestJob != nil {
+		result = r.readBackupResult(ctx, latestJob)
+	}
+	if err := r.storeRedactedConfig(ctx, siteBackup, result); err != nil {
+		return err
+	}
+
+	latest := &vyogotechv1alpha1.SiteBackup{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(siteBackup), latest); err != nil {
+		return err
+	}
+	latest.Status.LastBackup = *lastSuccess
+	setBackupFiles(&latest.Status, result)
+	return r.Status().Update(ctx, latest)
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testBackupResult = `{"files": [` +
+	`{"path": "/home/frappe/frappe-bench/sites/site.local/private/backups/20240101_020000-site_local-database.sql.gz", "size": 2048}, ` +
+	`{"path": "/home/frappe/frappe-bench/sites/site.local/private/backups/20240101_020000-site_local-site_config_backup.json", "size": 512}` +
+	`], "totalBytes": 2560}`
+
+// succeededBackupPod is a finished backup pod of job that reported message
+func succeededBackupPod(job *batchv1.Job, message string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: job.Name + "-xyz", Namespace: job.Namespace, Labels: map[string]string{"job-name": job.Name}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "backup",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}},
+			}},
+		},
+	}
+}
+
+func TestParseBackupResult(t *testing.T) {
+	result, err := parseBackupResult("Backup files: " + testBackupResult)
+	if err != nil {
+		t.Fatalf("parseBackupResult: %v", err)
+	}
+	if len(result.Files) != 2 || result.Files[0].Size != 2048 || result.TotalBytes != 2560 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, err := parseBackupResult("Backup completed successfully!"); err == nil {
+		t.Error("expected an error for a message without a result")
+	}
+}
+
+func TestSiteBackupFilesInStatus(t *testing.T) {
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local"},
+		Status:     vyogotechv1alpha1.SiteBackupStatus{Phase: "Running"},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "backup-backup", Namespace: "default"}}
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(siteBackup, job, succeededBackupPod(job, testBackupResult)).
+		WithStatusSubresource(&vyogotechv1alpha1.SiteBackup{}).Build()
+	r := &SiteBackupReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "backup", Namespace: "default"}
+
+	if err := r.recordSiteBackupSuccess(ctx, siteBackup, job); err != nil {
+		t.Fatalf("recordSiteBackupSuccess: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteBackup{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get SiteBackup: %v", err)
+	}
+	want := []vyogotechv1alpha1.BackupFile{
+		{Path: "/home/frappe/frappe-bench/sites/site.local/private/backups/20240101_020000-site_local-database.sql.gz", Size: 2048},
+		{Path: "/home/frappe/frappe-bench/sites/site.local/private/backups/20240101_020000-site_local-site_config_backup.json", Size: 512},
+	}
+	if updated.Status.Phase != "Succeeded" || !reflect.DeepEqual(updated.Status.BackupFiles, want) || updated.Status.TotalBytes != 2560 {
+		t.Errorf("expected the reported files in status, got %+v", updated.Status)
+	}
+}
+
+func TestScheduledBackupFilesInStatus(t *testing.T) {
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", Schedule: "0 2 * * *"},
+		Status:     vyogotechv1alpha1.SiteBackupStatus{Phase: "Scheduled"},
+	}
+	lastSuccess := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly-backup", Namespace: "default", UID: "cronjob-uid"},
+		Status:     batchv1.CronJobStatus{LastSuccessfulTime: &lastSuccess},
+	}
+	owner := []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "CronJob", Name: cronJob.Name, UID: cronJob.UID, Controller: boolPtr(true)}}
+	older := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly-backup-1", Namespace: "default", OwnerReferences: owner, CreationTimestamp: metav1.NewTime(lastSuccess.Add(-24 * time.Hour))},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	latest := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly-backup-2", Namespace: "default", OwnerReferences: owner, CreationTimestamp: lastSuccess},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(siteBackup, cronJob, older, latest, succeededBackupPod(older, `{"files": [], "totalBytes": 0}`), succeededBackupPod(latest, testBackupResult)).
+		WithStatusSubresource(&vyogotechv1alpha1.SiteBackup{}).Build()
+	r := &SiteBackupReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "nightly", Namespace: "default"}
+
+	if err := r.recordScheduledBackupFiles(ctx, siteBackup, cronJob); err != nil {
+		t.Fatalf("recordScheduledBackupFiles: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteBackup{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get SiteBackup: %v", err)
+	}
+	if !updated.Status.LastBackup.Equal(&lastSuccess) {
+		t.Errorf("expected lastBackup %v, got %v", lastSuccess, updated.Status.LastBackup)
+	}
+	if len(updated.Status.BackupFiles) != 2 || updated.Status.TotalBytes != 2560 {
+		t.Errorf("expected the files of the latest run, got %+v", updated.Status)
+	}
+
+	// The same run isn't recorded twice
+	version := updated.ResourceVersion
+	if err := r.recordScheduledBackupFiles(ctx, updated, cronJob); err != nil {
+		t.Fatalf("recordScheduledBackupFiles: %v", err)
+	}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatalf("Get SiteBackup: %v", err)
+	}
+	if updated.ResourceVersion != version {
+		t.Error("expected no status write for a run already recorded")
+	}
+}
+
+func TestSiteBackupStoresRedactedConfig(t *testing.T) {
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default", UID: "backup-uid"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", RedactConfig: true},
+		Status:     vyogotechv1alpha1.SiteBackupStatus{Phase: "Running"},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "backup-backup", Namespace: "default"}}
+	message := strings.TrimSuffix(testBackupResult, "}") + `, "redactedConfig": "gAAAAAB-token"}`
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(siteBackup, job, succeededBackupPod(job, message)).
+		WithStatusSubresource(&vyogotechv1alpha1.SiteBackup{}).Build()
+	r := &SiteBackupReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+
+	if err := r.ensureRedactedConfigSecret(ctx, siteBackup); err != nil {
+		t.Fatalf("ensureRedactedConfigSecret: %v", err)
+	}
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Name: "backup-redacted-config", Namespace: "default"}
+	if err := c.Get(ctx, key, secret); err != nil {
+		t.Fatalf("Get redacted config Secret: %v", err)
+	}
+	if len(secret.Data[redactedConfigKeyKey]) != 44 {
+		t.Errorf("expected a url-safe base64 Fernet key, got %q", secret.Data[redactedConfigKeyKey])
+	}
+	if !metav1.IsControlledBy(secret, siteBackup) {
+		t.Error("expected the Secret to be owned by the SiteBackup")
+	}
+	fernetKey := string(secret.Data[redactedConfigKeyKey])
+
+	if err := r.recordSiteBackupSuccess(ctx, siteBackup, job); err != nil {
+		t.Fatalf("recordSiteBackupSuccess: %v", err)
+	}
+	if err := c.Get(ctx, key, secret); err != nil {
+		t.Fatalf("Get redacted config Secret: %v", err)
+	}
+	if string(secret.Data[redactedConfigValuesKey]) != "gAAAAAB-token" {
+		t.Errorf("expected the reported token in the Secret, got %q", secret.Data[redactedConfigValuesKey])
+	}
+	if string(secret.Data[redactedConfigKeyKey]) != fernetKey {
+		t.Error("expected the key to be kept")
+	}
+	// The key is created once, later reconciles reuse it
+	if err := r.ensureRedactedConfigSecret(ctx, siteBackup); err != nil {
+		t.Fatalf("ensureRedactedConfigSecret: %v", err)
+	}
+	if err := c.Get(ctx, key, secret); err != nil || string(secret.Data[redactedConfigKeyKey]) != fernetKey {
+		t.Errorf("expected the key to survive a reconcile, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+)
+
+// Backup modes of spec.mode
+const (
+	backupModeFull      = "full"
+	backupModeDBOnly    = "db-only"
+	backupModeFilesOnly = "files-only"
+)
+
+// backupMode returns spec.mode, or the mode implied by spec.withFiles when it isn't set
+func backupMode(siteBackup *vyogotechv1alpha1.SiteBackup) string {
+	switch {
+	case siteBackup.Spec.Mode != "":
+		return siteBackup.Spec.Mode
+	case siteBackup.Spec.WithFiles:
+		return backupModeFull
+	default:
+		return backupModeDBOnly
+	}
+}
+
+// validateBackupMode rejects spec fields that contradict spec.mode
+func validateBackupMode(siteBackup *vyogotechv1alpha1.SiteBackup) error {
+	spec := siteBackup.Spec
+	switch spec.Mode {
+	case backupModeDBOnly:
+		if spec.WithFiles {
+			return fmt.Errorf("spec.withFiles cannot be set with spec.mode db-only")
+		}
+		if spec.BackupPathFiles != "" || spec.BackupPathPrivateFiles != "" {
+			return fmt.Errorf("spec.backupPathFiles and spec.backupPathPrivateFiles cannot be set with spec.mode db-only")
+		}
+	case backupModeFilesOnly:
+		if spec.BackupPathDB != "" {
+			return fmt.Errorf("spec.backupPathDB cannot be set with spec.mode files-only")
+		}
+		if len(spec.Include) > 0 || len(spec.Exclude) > 0 {
+			return fmt.Errorf("spec.include and spec.exclude select DocTypes of the database backup and cannot be set with spec.mode files-only")
+		}
+	}
+	return nil
+}
+
+// backupModeEnv tells the backup script to discard the database dump of a files-only backup
+func backupModeEnv(siteBackup *vyogotechv1alpha1.SiteBackup) []corev1.EnvVar {
+	if backupMode(siteBackup) != backupModeFilesOnly {
+		return nil
+	}
+	return []corev1.EnvVar{{Name: "BACKUP_MODE", Value: backupModeFilesOnly}}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+)
+
+func TestBuildBackupArgs_mode(t *testing.T) {
+	r := &SiteBackupReconciler{}
+	tests := []struct {
+		name string
+		spec vyogotechv1alpha1.SiteBackupSpec
+		want []string
+	}{
+		{
+			name: "legacy without files",
+			spec: vyogotechv1alpha1.SiteBackupSpec{Site: "site.local"},
+			want: []string{"--site", "site.local", "backup"},
+		},
+		{
+			name: "legacy with files",
+			spec: vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", WithFiles: true},
+			want: []string{"--site", "site.local", "backup", "--with-files"},
+		},
+		{
+			name: "full",
+			spec: vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", Mode: backupModeFull, Compress: true},
+			want: []string{"--site", "site.local", "backup", "--with-files", "--compress"},
+		},
+		{
+			name: "db-only",
+			spec: vyogotechv1alpha1.SiteBackupSpec{
+				Site:         "site.local",
+				Mode:         backupModeDBOnly,
+				BackupPathDB: "/backups/db.sql.gz",
+				Exclude:      []string{"Error Log"},
+			},
+			want: []string{"--site", "site.local", "backup", "--backup-path-db", "/backups/db.sql.gz", "--exclude", "Error Log"},
+		},
+		{
+			name: "files-only",
+			spec: vyogotechv1alpha1.SiteBackupSpec{
+				Site:                   "site.local",
+				Mode:                   backupModeFilesOnly,
+				BackupPathFiles:        "/backups/files.tar",
+				BackupPathPrivateFiles: "/backups/private-files.tar",
+			},
+			want: []string{"--site", "site.local", "backup", "--with-files",
+				"--backup-path-files", "/backups/files.tar", "--backup-path-private-files", "/backups/private-files.tar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.buildBackupArgs(&vyogotechv1alpha1.SiteBackup{Spec: tt.spec})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected args %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateBackupMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    vyogotechv1alpha1.SiteBackupSpec
+		wantErr bool
+	}{
+		{name: "legacy", spec: vyogotechv1alpha1.SiteBackupSpec{WithFiles: true, BackupPathDB: "/b/db.sql.gz"}},
+		{name: "full with everything", spec: vyogotechv1alpha1.SiteBackupSpec{Mode: backupModeFull, WithFiles: true, BackupPathFiles: "/b/f.tar", Include: []string{"User"}}},
+		{name: "db-only", spec: vyogotechv1alpha1.SiteBackupSpec{Mode: backupModeDBOnly, BackupPathDB: "/b/db.sql.gz"}},
+		{name: "db-only with files", spec: vyogotechv1alpha1.SiteBackupSpec{Mode: backupModeDBOnly, WithFiles: true}, wantErr: true},
+		{name: "db-only with a files path", spec: vyogotechv1alpha1.SiteBackupSpec{Mode: backupModeDBOnly, BackupPathPrivateFiles: "/b/p.tar"}, wantErr: true},
+		{name: "files-only", spec: vyogotechv1alpha1.SiteBackupSpec{Mode: backupModeFilesOnly, WithFiles: true, BackupPathFiles: "/b/f.tar"}},
+		{name: "files-only with a db path", spec: vyogotechv1alpha1.SiteBackupSpec{Mode: backupModeFilesOnly, BackupPathDB: "/b/db.sql.gz"}, wantErr: true},
+		{name: "files-only with include", spec: vyogotechv1alpha1.SiteBackupSpec{Mode: backupModeFilesOnly, Include: []string{"User"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBackupMode(&vyogotechv1alpha1.SiteBackup{Spec: tt.spec})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSiteBackupReconciler_modeLabelAndStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vyogotechv1alpha1.AddToScheme(scheme)
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "sb", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", Schedule: "*/15 * * * *", Mode: backupModeFilesOnly},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(siteBackup).WithStatusSubresource(siteBackup).Build()
+	r := &SiteBackupReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+
+	if got := r.buildBackupJob(siteBackup, bench).Labels["backupMode"]; got != backupModeFilesOnly {
+		t.Errorf("expected backupMode label files-only on the job, got %q", got)
+	}
+	cronJob := r.buildBackupCronJob(siteBackup, bench)
+	if got := cronJob.Labels["backupMode"]; got != backupModeFilesOnly {
+		t.Errorf("expected backupMode label files-only on the cronjob, got %q", got)
+	}
+	env := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env
+	if len(env) != 1 || env[0].Name != "BACKUP_MODE" || env[0].Value != backupModeFilesOnly {
+		t.Errorf("expected BACKUP_MODE=files-only, got %v", env)
+	}
+
+	if err := r.updateSiteBackupStatus(ctx, siteBackup, "Scheduled", "Scheduled backup created", cronJob.Name); err != nil {
+		t.Fatalf("updateSiteBackupStatus: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteBackup{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "sb", Namespace: "default"}, updated); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if updated.Status.Mode != backupModeFilesOnly {
+		t.Errorf("expected status.mode files-only, got %q", updated.Status.Mode)
+	}
+
+	// Without spec.mode the label reflects withFiles
+	siteBackup.Spec.Mode = ""
+	if got := r.buildBackupJob(siteBackup, bench).Labels["backupMode"]; got != backupModeDBOnly {
+		t.Errorf("expected backupMode label db-only without withFiles, got %q", got)
+	}
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+)
+
+// errBackupVolumeUnsupported marks cross-namespace backups that can never run as
+// configured; the SiteBackup is failed instead of retried
+var errBackupVolumeUnsupported = errors.New("sites volume cannot be used from the execution namespace")
+
+// Labels that tie resources in the execution namespace back to their SiteBackup, which
+// can't own them through an owner reference
+const (
+	backupOwnerNameLabel      = "vyogo.tech/sitebackup"
+	backupOwnerNamespaceLabel = "vyogo.tech/sitebackup-namespace"
+)
+
+// executionNamespaceCondition reports whether a cross-namespace backup may run in its
+// spec.executionNamespace
+const executionNamespaceCondition = "ExecutionNamespaceAllowed"
+
+// backupExecutionNamespaceAllowed reports whether namespace is listed in the operator
+// config's backupExecutionNamespaces (comma-separated). A backup job runs the bench image
+// with the bench's service account and reads Secrets in its namespace, so only namespaces
+// an administrator set aside for backups may be targeted; an empty list allows none.
+func backupExecutionNamespaceAllowed(operatorConfig *corev1.ConfigMap, namespace string) bool {
+	if operatorConfig == nil {
+		return false
+	}
+	for _, allowed := range strings.Split(operatorConfig.Data["backupExecutionNamespaces"], ",") {
+		if strings.TrimSpace(allowed) == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// checkExecutionNamespace records in the ExecutionNamespaceAllowed condition whether the
+// backup may run in its execution namespace. A namespace that isn't allowed fails the
+// SiteBackup before anything is created or read there.
+func (r *SiteBackupReconciler) checkExecutionNamespace(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup) (bool, error) {
+	if !isCrossNamespaceBackup(siteBackup) {
+		return true, nil
+	}
+	execNamespace := backupExecutionNamespace(siteBackup)
+	operatorConfig, err := r.getOperatorConfig(ctx)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	condition := metav1.Condition{
+		Type:               executionNamespaceCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "NamespaceAllowed",
+		Message:            fmt.Sprintf("Namespace %s is listed in backupExecutionNamespaces", execNamespace),
+		ObservedGeneration: siteBackup.Generation,
+	}
+	allowed := backupExecutionNamespaceAllowed(operatorConfig, execNamespace)
+	if !allowed {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NamespaceNotAllowed"
+		condition.Message = fmt.Sprintf("executionNamespace %s is not listed in the operator config's backupExecutionNamespaces", execNamespace)
+	}
+	if existing := meta.FindStatusCondition(siteBackup.Status.Conditions, executionNamespaceCondition); existing != nil &&
+		existing.Status == condition.Status && existing.ObservedGeneration == condition.ObservedGeneration {
+		return allowed, nil
+	}
+
+	if allowed {
+		latest := &vyogotechv1alpha1.SiteBackup{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(siteBackup), latest); err != nil {
+			return false, err
+		}
+		meta.SetStatusCondition(&latest.Status.Conditions, condition)
+		return true, r.Status().Update(ctx, latest)
+	}
+	log.FromContext(ctx).Info("Execution namespace not allowed, failing backup", "namespace", execNamespace)
+	r.Recorder.Event(siteBackup, corev1.EventTypeWarning, "ExecutionNamespaceNotAllowed", condition.Message)
+	return false, r.updateSiteBackupStatusWith(ctx, siteBackup, "Failed", condition.Message, "", func(status *vyogotechv1alpha1.SiteBackupStatus) {
+		meta.SetStatusCondition(&status.Conditions, condition)
+	})
+}
+
+// ownsBackupObject reports whether obj in the execution namespace was created for
+// siteBackup, so the finalizer never deletes objects it didn't create
+func ownsBackupObject(siteBackup *vyogotechv1alpha1.SiteBackup, obj client.Object) bool {
+	labels := obj.GetLabels()
+	return labels[backupOwnerNameLabel] == siteBackup.Name && labels[backupOwnerNamespaceLabel] == siteBackup.Namespace
+}
+
+// backupExecutionNamespace returns the namespace the backup job runs in
+func backupExecutionNamespace(siteBackup *vyogotechv1alpha1.SiteBackup) string {
+	if siteBackup.Spec.ExecutionNamespace != "" {
+		return siteBackup.Spec.ExecutionNamespace
+	}
+	return siteBackup.Namespace
+}
+
+// isCrossNamespaceBackup reports whether the backup job runs outside the SiteBackup's namespace
+func isCrossNamespaceBackup(siteBackup *vyogotechv1alpha1.SiteBackup) bool {
+	return backupExecutionNamespace(siteBackup) != siteBackup.Namespace
+}
+
+// backupJobName returns the Job/CronJob name; jobs in a shared execution namespace are
+// prefixed with the SiteBackup's namespace so backups from different namespaces don't collide
+func backupJobName(siteBackup *vyogotechv1alpha1.SiteBackup) string {
+	if isCrossNamespaceBackup(siteBackup) {
+		return fmt.Sprintf("%s-%s-backup", siteBackup.Namespace, siteBackup.Name)
+	}
+	return siteBackup.Name + "-backup"
+}
+
+// backupVolumeName names both the mirror PersistentVolume and its claim in the execution namespace
+func backupVolumeName(siteBackup *vyogotechv1alpha1.SiteBackup) string {
+	return fmt.Sprintf("%s-%s-sites", siteBackup.Namespace, siteBackup.Name)
+}
+
+// needsBackupVolumeMirror reports whether the bench's sites PVC lives outside the execution namespace
+func needsBackupVolumeMirror(siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench) bool {
+	return siteBackup.Spec.ExecutionNamespace != "" && siteBackup.Spec.ExecutionNamespace != bench.Namespace
+}
+
+// backupClaimName returns the sites claim the backup job mounts
+func (r *SiteBackupReconciler) backupClaimName(siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench) string {
+	if needsBackupVolumeMirror(siteBackup, bench) {
+		return backupVolumeName(siteBackup)
+	}
+	return r.getSitesPVCName(bench)
+}
+
+// backupOwnerLabels identifies the SiteBackup that created a resource in the execution namespace
+func backupOwnerLabels(siteBackup *vyogotechv1alpha1.SiteBackup) map[string]string {
+	return map[string]string{
+		backupOwnerNameLabel:      siteBackup.Name,
+		backupOwnerNamespaceLabel: siteBackup.Namespace,
+	}
+}
+
+// ensureBackupVolume makes the bench's sites volume mountable from the execution namespace.
+// A PVC can only be mounted in its own namespace, so the PersistentVolume behind it is bound
+// a second time through a statically provisioned mirror PV and claim. This only works for
+// ReadWriteMany volumes whose source can be attached twice (CSI or NFS); anything else
+// returns errBackupVolumeUnsupported.
+func (r *SiteBackupReconciler) ensureBackupVolume(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, bench *vyogotechv1alpha1.FrappeBench) error {
+	if !needsBackupVolumeMirror(siteBackup, bench) {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+	execNamespace := siteBackup.Spec.ExecutionNamespace
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: execNamespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("%w: namespace %s does not exist", errBackupVolumeUnsupported, execNamespace)
+		}
+		return err
+	}
+
+	source := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.getSitesPVCName(bench), Namespace: bench.Namespace}, source); err != nil {
+		return err
+	}
+	if !hasAccessMode(source.Spec.AccessModes, corev1.ReadWriteMany) {
+		return fmt.Errorf("%w: PVC %s/%s must be ReadWriteMany to be mounted from namespace %s",
+			errBackupVolumeUnsupported, source.Namespace, source.Name, execNamespace)
+	}
+	if source.Status.Phase != corev1.ClaimBound || source.Spec.VolumeName == "" {
+		return fmt.Errorf("PVC %s/%s is not bound yet", source.Namespace, source.Name)
+	}
+
+	sourcePV := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, client.ObjectKey{Name: source.Spec.VolumeName}, sourcePV); err != nil {
+		return err
+	}
+	if sourcePV.Spec.CSI == nil && sourcePV.Spec.NFS == nil {
+		return fmt.Errorf("%w: PersistentVolume %s is not backed by CSI or NFS and cannot be bound in namespace %s",
+			errBackupVolumeUnsupported, sourcePV.Name, execNamespace)
+	}
+
+	name := backupVolumeName(siteBackup)
+	labels := backupOwnerLabels(siteBackup)
+	labels["app"] = "frappe"
+
+	mirrorPV := &corev1.PersistentVolume{}
+	err := r.Get(ctx, client.ObjectKey{Name: name}, mirrorPV)
+	if apierrors.IsNotFound(err) {
+		mirrorPV = &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec: corev1.PersistentVolumeSpec{
+				Capacity:    sourcePV.Spec.Capacity,
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: sourcePV.Spec.CSI.DeepCopy(),
+					NFS: sourcePV.Spec.NFS.DeepCopy(),
+				},
+				// Retain so deleting the mirror never touches the bench's data
+				PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+				MountOptions:                  sourcePV.Spec.MountOptions,
+				VolumeMode:                    sourcePV.Spec.VolumeMode,
+				ClaimRef: &corev1.ObjectReference{
+					Kind:       "PersistentVolumeClaim",
+					APIVersion: "v1",
+					Name:       name,
+					Namespace:  execNamespace,
+				},
+			},
+		}
+		if err := r.Create(ctx, mirrorPV); err != nil {
+			return fmt.Errorf("failed to create mirror PersistentVolume %s: %w", name, err)
+		}
+		logger.Info("Created mirror PersistentVolume for cross-namespace backup", "pv", name, "source", sourcePV.Name)
+	} else if err != nil {
+		return err
+	}
+
+	mirrorPVC := &corev1.PersistentVolumeClaim{}
+	err = r.Get(ctx, client.ObjectKey{Name: name, Namespace: execNamespace}, mirrorPVC)
+	if apierrors.IsNotFound(err) {
+		storageClass := ""
+		mirrorPVC = &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: execNamespace, Labels: labels},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				StorageClassName: &storageClass,
+				VolumeName:       name,
+				VolumeMode:       sourcePV.Spec.VolumeMode,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: sourcePV.Spec.Capacity[corev1.ResourceStorage]},
+				},
+			},
+		}
+		if err := r.Create(ctx, mirrorPVC); err != nil {
+			return fmt.Errorf("failed to create mirror PVC %s/%s: %w", execNamespace, name, err)
+		}
+		logger.Info("Created mirror PVC for cross-namespace backup", "pvc", name, "namespace", execNamespace)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// deleteBackupVolume removes the mirror claim and PersistentVolume; the PV is retained
+// so the underlying storage is left untouched
+func (r *SiteBackupReconciler) deleteBackupVolume(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup) error {
+	if siteBackup.Spec.ExecutionNamespace == "" {
+		return nil
+	}
+	name := backupVolumeName(siteBackup)
+
+	for _, obj := range []client.Object{&corev1.PersistentVolumeClaim{}, &corev1.PersistentVolume{}} {
+		key := client.ObjectKey{Name: name}
+		if _, ok := obj.(*corev1.PersistentVolumeClaim); ok {
+			key.Namespace = siteBackup.Spec.ExecutionNamespace
+		}
+		if err := r.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !ownsBackupObject(siteBackup, obj) {
+			continue
+		}
+		if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasAccessMode reports whether modes contains mode
+func hasAccessMode(modes []corev1.PersistentVolumeAccessMode, mode corev1.PersistentVolumeAccessMode) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newCrossNamespaceBackupObjects(accessMode corev1.PersistentVolumeAccessMode, source corev1.PersistentVolumeSource) (*vyogotechv1alpha1.SiteBackup, *vyogotechv1alpha1.FrappeBench, []runtime.Object) {
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", ExecutionNamespace: "backups"},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench-sites", Namespace: "default"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+			VolumeName:  "pvc-123",
+		},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-123"},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:               corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			AccessModes:            []corev1.PersistentVolumeAccessMode{accessMode},
+			PersistentVolumeSource: source,
+		},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "backups"}}
+	return siteBackup, bench, []runtime.Object{siteBackup, bench, pvc, pv, ns}
+}
+
+func newCrossNamespaceBackupReconciler(objs ...runtime.Object) *SiteBackupReconciler {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+	return &SiteBackupReconciler{Client: c, Scheme: scheme}
+}
+
+func TestCrossNamespaceBackupMirrorsVolume(t *testing.T) {
+	nfs := corev1.PersistentVolumeSource{NFS: &corev1.NFSVolumeSource{Server: "nfs.local", Path: "/exports/bench"}}
+	siteBackup, bench, objs := newCrossNamespaceBackupObjects(corev1.ReadWriteMany, nfs)
+	r := newCrossNamespaceBackupReconciler(objs...)
+	ctx := context.Background()
+
+	if err := r.ensureBackupVolume(ctx, siteBackup, bench); err != nil {
+		t.Fatalf("ensureBackupVolume: %v", err)
+	}
+
+	mirrorPV := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "default-nightly-sites"}, mirrorPV); err != nil {
+		t.Fatalf("expected mirror PV: %v", err)
+	}
+	if mirrorPV.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+		t.Errorf("mirror PV must retain data, got %s", mirrorPV.Spec.PersistentVolumeReclaimPolicy)
+	}
+	if mirrorPV.Spec.NFS == nil || mirrorPV.Spec.NFS.Path != "/exports/bench" {
+		t.Errorf("expected NFS source to be copied, got %+v", mirrorPV.Spec.PersistentVolumeSource)
+	}
+	if ref := mirrorPV.Spec.ClaimRef; ref == nil || ref.Namespace != "backups" || ref.Name != "default-nightly-sites" {
+		t.Errorf("expected mirror PV reserved for the mirror claim, got %+v", ref)
+	}
+
+	mirrorPVC := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "default-nightly-sites", Namespace: "backups"}, mirrorPVC); err != nil {
+		t.Fatalf("expected mirror PVC: %v", err)
+	}
+	if mirrorPVC.Spec.VolumeName != mirrorPV.Name {
+		t.Errorf("expected mirror PVC bound to %s, got %s", mirrorPV.Name, mirrorPVC.Spec.VolumeName)
+	}
+
+	job := r.buildBackupJob(siteBackup, bench)
+	if job.Namespace != "backups" || job.Name != "default-nightly-backup" {
+		t.Errorf("job name/ns: got %s/%s", job.Namespace, job.Name)
+	}
+	if len(job.OwnerReferences) != 0 {
+		t.Error("cross-namespace job must not carry an owner reference")
+	}
+	if job.Labels[backupOwnerNamespaceLabel] != "default" || job.Labels[backupOwnerNameLabel] != "nightly" {
+		t.Errorf("expected owner labels on job, got %v", job.Labels)
+	}
+	if claim := job.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName; claim != "default-nightly-sites" {
+		t.Errorf("expected job to mount the mirror claim, got %s", claim)
+	}
+
+	if err := r.deleteBackupVolume(ctx, siteBackup); err != nil {
+		t.Fatalf("deleteBackupVolume: %v", err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: "default-nightly-sites"}, mirrorPV); err == nil {
+		t.Error("expected mirror PV to be deleted")
+	}
+	source := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, types.NamespacedName{Name: "pvc-123"}, source); err != nil {
+		t.Errorf("source PV must be left alone: %v", err)
+	}
+}
+
+func TestCrossNamespaceBackupRejectsUnsupportedVolumes(t *testing.T) {
+	csi := corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com", VolumeHandle: "vol-1"}}
+	hostPath := corev1.PersistentVolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/data"}}
+
+	tests := []struct {
+		name       string
+		accessMode corev1.PersistentVolumeAccessMode
+		source     corev1.PersistentVolumeSource
+		namespace  string
+	}{
+		{name: "ReadWriteOnce", accessMode: corev1.ReadWriteOnce, source: csi, namespace: "backups"},
+		{name: "hostPath", accessMode: corev1.ReadWriteMany, source: hostPath, namespace: "backups"},
+		{name: "missing namespace", accessMode: corev1.ReadWriteMany, source: csi, namespace: "nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siteBackup, bench, objs := newCrossNamespaceBackupObjects(tt.accessMode, tt.source)
+			siteBackup.Spec.ExecutionNamespace = tt.namespace
+			r := newCrossNamespaceBackupReconciler(objs...)
+
+			err := r.ensureBackupVolume(context.Background(), siteBackup, bench)
+			if !errors.Is(err, errBackupVolumeUnsupported) {
+				t.Errorf("expected errBackupVolumeUnsupported, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCrossNamespaceBackupRequiresAllowedNamespace(t *testing.T) {
+	nfs := corev1.PersistentVolumeSource{NFS: &corev1.NFSVolumeSource{Server: "nfs.local", Path: "/exports/bench"}}
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+		},
+	}
+	tests := []struct {
+		name        string
+		allowed     string
+		wantAllowed bool
+	}{
+		{name: "no allowlist", wantAllowed: false},
+		{name: "other namespaces allowed", allowed: "backup-system", wantAllowed: false},
+		{name: "namespace allowed", allowed: "backup-system, backups", wantAllowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siteBackup, _, objs := newCrossNamespaceBackupObjects(corev1.ReadWriteMany, nfs)
+			objs = append(objs, site)
+			if tt.allowed != "" {
+				objs = append(objs, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "frappe-operator-config", Namespace: "frappe-operator-system"},
+					Data:       map[string]string{"backupExecutionNamespaces": tt.allowed},
+				})
+			}
+			scheme := runtime.NewScheme()
+			utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+			utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+			c := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).
+				WithStatusSubresource(&vyogotechv1alpha1.SiteBackup{}).Build()
+			r := &SiteBackupReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+			ctx := context.Background()
+			key := types.NamespacedName{Name: siteBackup.Name, Namespace: siteBackup.Namespace}
+
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			updated := &vyogotechv1alpha1.SiteBackup{}
+			if err := c.Get(ctx, key, updated); err != nil {
+				t.Fatalf("Get SiteBackup: %v", err)
+			}
+			condition := meta.FindStatusCondition(updated.Status.Conditions, executionNamespaceCondition)
+			if condition == nil || (condition.Status == metav1.ConditionTrue) != tt.wantAllowed {
+				t.Fatalf("expected %s allowed=%v, got %+v", executionNamespaceCondition, tt.wantAllowed, condition)
+			}
+
+			job := &batchv1.Job{}
+			jobErr := c.Get(ctx, types.NamespacedName{Name: "default-nightly-backup", Namespace: "backups"}, job)
+			pvErr := c.Get(ctx, types.NamespacedName{Name: "default-nightly-sites"}, &corev1.PersistentVolume{})
+			if tt.wantAllowed {
+				if jobErr != nil || pvErr != nil {
+					t.Errorf("expected the job and mirror volume in the allowed namespace: %v, %v", jobErr, pvErr)
+				}
+				return
+			}
+			if updated.Status.Phase != "Failed" || condition.Reason != "NamespaceNotAllowed" {
+				t.Errorf("expected the backup to fail with NamespaceNotAllowed, got %s: %+v", updated.Status.Phase, condition)
+			}
+			if jobErr == nil || pvErr == nil {
+				t.Error("expected nothing to be created for a namespace that isn't allowed")
+			}
+		})
+	}
+}
+
+func TestCrossNamespaceBackupFinalizerKeepsForeignObjects(t *testing.T) {
+	nfs := corev1.PersistentVolumeSource{NFS: &corev1.NFSVolumeSource{Server: "nfs.local", Path: "/exports/bench"}}
+	siteBackup, _, objs := newCrossNamespaceBackupObjects(corev1.ReadWriteMany, nfs)
+	// Objects in the execution namespace that happen to carry the backup's names
+	foreignJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "default-nightly-backup", Namespace: "backups"}}
+	foreignPVC := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "default-nightly-sites", Namespace: "backups"}}
+	r := newCrossNamespaceBackupReconciler(append(objs, foreignJob, foreignPVC)...)
+	ctx := context.Background()
+
+	if err := r.handleFinalizer(ctx, siteBackup); err != nil {
+		t.Fatalf("handleFinalizer: %v", err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: foreignJob.Name, Namespace: "backups"}, &batchv1.Job{}); err != nil {
+		t.Errorf("expected a job not labelled for the backup to be kept: %v", err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: foreignPVC.Name, Namespace: "backups"}, &corev1.PersistentVolumeClaim{}); err != nil {
+		t.Errorf("expected a claim not labelled for the backup to be kept: %v", err)
+	}
+}
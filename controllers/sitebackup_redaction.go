@@ -0,0 +1,180 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Keys of the Secret holding the values redactConfig removed from the config backup
+const (
+	// redactedConfigKeyKey holds the Fernet key the backup job encrypts the values with
+	redactedConfigKeyKey = "key"
+
+	// redactedConfigValuesKey holds the encrypted values of the latest backup run
+	redactedConfigValuesKey = "values"
+)
+
+// redactedConfigSecretName is the Secret, owned by the SiteBackup, that keeps the values
+// redacted from its config backups so SiteRestore can put them back
+func redactedConfigSecretName(siteBackup *vyogotechv1alpha1.SiteBackup) string {
+	return siteBackup.Name + "-redacted-config"
+}
+
+// redactedConfigJobSecretName is the Secret the backup job reads the encryption key from.
+// A job in another namespace can't read the SiteBackup's Secret, so it gets a copy of
+// the key there.
+func redactedConfigJobSecretName(siteBackup *vyogotechv1alpha1.SiteBackup) string {
+	if isCrossNamespaceBackup(siteBackup) {
+		return fmt.Sprintf("%s-%s-redacted-config", siteBackup.Namespace, siteBackup.Name)
+	}
+	return redactedConfigSecretName(siteBackup)
+}
+
+// newFernetKey returns a random key in the url-safe base64 form Python's Fernet expects
+func newFernetKey() ([]byte, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	return []byte(base64.URLEncoding.EncodeToString(raw)), nil
+}
+
+// ensureRedactedConfigSecret creates the SiteBackup's redacted config Secret with a fresh
+// encryption key, and the key's copy in the execution namespace. The backup job only
+// ever reports the redacted values encrypted with that key, so they never appear in
+// plain text in its termination message.
+func (r *SiteBackupReconciler) ensureRedactedConfigSecret(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup) error {
+	if !siteBackup.Spec.RedactConfig {
+		return nil
+	}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Name: redactedConfigSecretName(siteBackup), Namespace: siteBackup.Namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		key, err := newFernetKey()
+		if err != nil {
+			return err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      redactedConfigSecretName(siteBackup),
+				Namespace: siteBackup.Namespace,
+				Labels:    map[string]string{"app": "frappe", "site": siteBackup.Spec.Site, "backup": "true"},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{redactedConfigKeyKey: key},
+		}
+		if err := controllerutil.SetControllerReference(siteBackup, secret, r.Scheme); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("Creating redacted config secret", "secret", secret.Name)
+		if err := r.Create(ctx, secret); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if !isCrossNamespaceBackup(siteBackup) {
+		return nil
+	}
+
+	key := secret.Data[redactedConfigKeyKey]
+	jobSecret := &corev1.Secret{}
+	err = r.Get(ctx, client.ObjectKey{Name: redactedConfigJobSecretName(siteBackup), Namespace: backupExecutionNamespace(siteBackup)}, jobSecret)
+	if apierrors.IsNotFound(err) {
+		jobSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      redactedConfigJobSecretName(siteBackup),
+				Namespace: backupExecutionNamespace(siteBackup),
+				Labels:    backupOwnerLabels(siteBackup),
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{redactedConfigKeyKey: key},
+		}
+		return r.Create(ctx, jobSecret)
+	}
+	if err != nil {
+		return err
+	}
+	if !ownsBackupObject(siteBackup, jobSecret) || bytes.Equal(jobSecret.Data[redactedConfigKeyKey], key) {
+		return nil
+	}
+	jobSecret.Data = map[string][]byte{redactedConfigKeyKey: key}
+	return r.Update(ctx, jobSecret)
+}
+
+// deleteRedactedConfigJobSecret removes the key's copy from the execution namespace; the
+// SiteBackup's own Secret is garbage collected through its owner reference
+func (r *SiteBackupReconciler) deleteRedactedConfigJobSecret(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup) error {
+	if !isCrossNamespaceBackup(siteBackup) {
+		return nil
+	}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Name: redactedConfigJobSecretName(siteBackup), Namespace: backupExecutionNamespace(siteBackup)}, secret)
+	if err != nil || !ownsBackupObject(siteBackup, secret) {
+		return client.IgnoreNotFound(err)
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, secret))
+}
+
+// storeRedactedConfig keeps the encrypted values a backup run reported in the SiteBackup's
+// redacted config Secret, for SiteRestore's redactedConfigSecretRef
+func (r *SiteBackupReconciler) storeRedactedConfig(ctx context.Context, siteBackup *vyogotechv1alpha1.SiteBackup, result *backupResult) error {
+	if !siteBackup.Spec.RedactConfig || result == nil || result.RedactedConfig == "" {
+		return nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Name: redactedConfigSecretName(siteBackup), Namespace: siteBackup.Namespace}, secret); err != nil {
+		return err
+	}
+	if string(secret.Data[redactedConfigValuesKey]) == result.RedactedConfig {
+		return nil
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[redactedConfigValuesKey] = []byte(result.RedactedConfig)
+	return r.Update(ctx, secret)
+}
+
+// redactedConfigEnv passes the encryption key to the backup job
+func redactedConfigEnv(siteBackup *vyogotechv1alpha1.SiteBackup) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "REDACT_CONFIG", Value: "true"},
+		{
+			Name: "REDACT_CONFIG_KEY",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: redactedConfigJobSecretName(siteBackup)},
+					Key:                  redactedConfigKeyKey,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+)
+
+// backupStorageTypeS3 keeps backups in the bucket only
+const backupStorageTypeS3 = "s3"
+
+// backupS3Config returns the bucket the backup is uploaded to, or nil when backups stay
+// on the sites volume
+func backupS3Config(siteBackup *vyogotechv1alpha1.SiteBackup) *vyogotechv1alpha1.S3Config {
+	if siteBackup.Spec.Storage == nil {
+		return nil
+	}
+	return siteBackup.Spec.Storage.S3
+}
+
+// validateBackupStorage rejects storage.type s3 without a bucket to upload to
+func validateBackupStorage(siteBackup *vyogotechv1alpha1.SiteBackup) error {
+	if storage := siteBackup.Spec.Storage; storage != nil && storage.Type == backupStorageTypeS3 && storage.S3 == nil {
+		return fmt.Errorf("spec.storage.type is s3 but spec.storage.s3 is not set")
+	}
+	return nil
+}
+
+// backupS3Prefix is the key prefix the files of one backup job are uploaded under
+func backupS3Prefix(site, jobName string) string {
+	return site + "/" + jobName
+}
+
+// backupS3Location returns where a one-time backup job uploaded the database backup, or
+// the public files archive of a files-only backup, or nil without S3 storage
+func backupS3Location(siteBackup *vyogotechv1alpha1.SiteBackup, jobName string) *vyogotechv1alpha1.BackupS3Location {
+	s3 := backupS3Config(siteBackup)
+	if s3 == nil {
+		return nil
+	}
+	// bench backup names the dump <timestamp>-<site>-database.sql.gz, uploaded without the
+	// prefix, unless backupPathDB names the file
+	name := "database.sql.gz"
+	switch {
+	case backupMode(siteBackup) == backupModeFilesOnly && siteBackup.Spec.BackupPathFiles != "":
+		name = path.Base(siteBackup.Spec.BackupPathFiles)
+	case backupMode(siteBackup) == backupModeFilesOnly && siteBackup.Spec.Compress:
+		name = "files.tgz"
+	case backupMode(siteBackup) == backupModeFilesOnly:
+		name = "files.tar"
+	case siteBackup.Spec.BackupPathDB != "":
+		name = path.Base(siteBackup.Spec.BackupPathDB)
+	}
+	return &vyogotechv1alpha1.BackupS3Location{
+		Bucket: s3.Bucket,
+		Key:    backupS3Prefix(siteBackup.Spec.Site, jobName) + "/" + name,
+	}
+}
+
+// backupS3Env configures the upload step of the backup script. The key prefix uses the
+// pod's job-name label so every run of a scheduled backup gets its own prefix.
+func backupS3Env(siteBackup *vyogotechv1alpha1.SiteBackup) []corev1.EnvVar {
+	s3 := backupS3Config(siteBackup)
+	if s3 == nil {
+		return nil
+	}
+	env := []corev1.EnvVar{
+		{
+			Name: "JOB_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.labels['job-name']"},
+			},
+		},
+		{Name: "S3_BUCKET", Value: s3.Bucket},
+		{Name: "S3_PREFIX", Value: backupS3Prefix(siteBackup.Spec.Site, "$(JOB_NAME)")},
+		{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+		{Name: "S3_USE_SSL", Value: strconv.FormatBool(s3.UseSSL)},
+	}
+	if s3.Region != "" {
+		env = append(env, corev1.EnvVar{Name: "S3_REGION", Value: s3.Region})
+	}
+	env = append(env,
+		corev1.EnvVar{Name: "AWS_ACCESS_KEY_ID", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: s3.AccessKeySecret.DeepCopy()}},
+		corev1.EnvVar{Name: "AWS_SECRET_ACCESS_KEY", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: s3.SecretKeySecret.DeepCopy()}},
+	)
+	if siteBackup.Spec.Storage.Type == backupStorageTypeS3 {
+		env = append(env, corev1.EnvVar{Name: "S3_REMOVE_LOCAL", Value: "true"})
+	}
+	return env
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+)
+
+func newS3SiteBackup(storageType string) *vyogotechv1alpha1.SiteBackup {
+	return &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-backup", Namespace: "default"},
+		Spec: vyogotechv1alpha1.SiteBackupSpec{
+			Site: "site.local",
+			Storage: &vyogotechv1alpha1.BackupStorageConfig{
+				Type: storageType,
+				S3: &vyogotechv1alpha1.S3Config{
+					Endpoint: "minio.minio:9000",
+					Bucket:   "backups",
+					AccessKeySecret: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "minio-creds"}, Key: "access-key",
+					},
+					SecretKeySecret: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "minio-creds"}, Key: "secret-key",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestBackupS3Env(t *testing.T) {
+	r := &SiteBackupReconciler{Scheme: runtime.NewScheme()}
+	siteBackup := newS3SiteBackup(backupStorageTypeS3)
+	siteBackup.Spec.Schedule = "0 2 * * *"
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "15"},
+	}
+
+	for name, container := range map[string]corev1.Container{
+		"job":     r.buildBackupJob(siteBackup, bench).Spec.Template.Spec.Containers[0],
+		"cronjob": r.buildBackupCronJob(siteBackup, bench).Spec.JobTemplate.Spec.Template.Spec.Containers[0],
+	} {
+		env := map[string]corev1.EnvVar{}
+		for _, e := range container.Env {
+			env[e.Name] = e
+		}
+		if env["S3_BUCKET"].Value != "backups" || env["S3_ENDPOINT"].Value != "minio.minio:9000" {
+			t.Errorf("%s: expected bucket and endpoint, got %v", name, container.Env)
+		}
+		if env["S3_USE_SSL"].Value != "false" || env["S3_REMOVE_LOCAL"].Value != "true" {
+			t.Errorf("%s: expected S3_USE_SSL=false and S3_REMOVE_LOCAL=true, got %v", name, container.Env)
+		}
+		if env["S3_PREFIX"].Value != "site.local/$(JOB_NAME)" || env["JOB_NAME"].ValueFrom == nil {
+			t.Errorf("%s: expected the key prefix to use the job name, got %v", name, container.Env)
+		}
+		if ref := env["AWS_SECRET_ACCESS_KEY"].ValueFrom; ref == nil || ref.SecretKeyRef.Name != "minio-creds" || ref.SecretKeyRef.Key != "secret-key" {
+			t.Errorf("%s: expected the secret key from minio-creds, got %v", name, container.Env)
+		}
+		if !strings.Contains(container.Command[2], "upload_file") {
+			t.Errorf("%s: expected the backup wrapper to upload to S3", name)
+		}
+	}
+
+	siteBackup.Spec.Storage.Type = "pvc"
+	for _, e := range backupEnv(siteBackup) {
+		if e.Name == "S3_REMOVE_LOCAL" {
+			t.Error("expected local backup files to be kept with storage.type pvc")
+		}
+	}
+
+	siteBackup.Spec.Storage = nil
+	if env := backupEnv(siteBackup); len(env) != 0 {
+		t.Errorf("expected no env without storage, got %v", env)
+	}
+}
+
+func TestValidateBackupStorage(t *testing.T) {
+	siteBackup := newS3SiteBackup(backupStorageTypeS3)
+	if err := validateBackupStorage(siteBackup); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	siteBackup.Spec.Storage.S3 = nil
+	if err := validateBackupStorage(siteBackup); err == nil {
+		t.Error("expected an error for storage.type s3 without storage.s3")
+	}
+}
+
+func TestBackupS3LocationInStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vyogotechv1alpha1.AddToScheme(scheme)
+	siteBackup := newS3SiteBackup(backupStorageTypeS3)
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(siteBackup).WithStatusSubresource(&vyogotechv1alpha1.SiteBackup{}).Build()
+	r := &SiteBackupReconciler{Client: client}
+	ctx := context.Background()
+
+	if err := r.updateSiteBackupStatus(ctx, siteBackup, "Succeeded", "done", "my-backup-backup"); err != nil {
+		t.Fatalf("updateSiteBackupStatus: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteBackup{}
+	if err := client.Get(ctx, types.NamespacedName{Name: "my-backup", Namespace: "default"}, updated); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	want := vyogotechv1alpha1.BackupS3Location{Bucket: "backups", Key: "site.local/my-backup-backup/database.sql.gz"}
+	if updated.Status.S3 == nil || *updated.Status.S3 != want {
+		t.Errorf("expected status.s3 %+v, got %+v", want, updated.Status.S3)
+	}
+
+	siteBackup.Spec.BackupPathDB = "/backups/db.sql.gz"
+	if location := backupS3Location(siteBackup, "my-backup-backup"); location.Key != "site.local/my-backup-backup/db.sql.gz" {
+		t.Errorf("expected the key to use the backupPathDB file name, got %s", location.Key)
+	}
+}
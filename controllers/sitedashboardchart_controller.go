@@ -18,14 +18,35 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
+)
+
+const (
+	// siteDashboardChartFinalizer removes the chart from the site before the
+	// SiteDashboardChart is deleted
+	siteDashboardChartFinalizer = "vyogo.tech/dashboard-chart-finalizer"
+	// dashboardChartAppliedCondition reports whether the site's chart matches the spec
+	dashboardChartAppliedCondition = "Applied"
 )
 
 // SiteDashboardChartReconciler reconciles a SiteDashboardChart object
@@ -38,27 +59,396 @@ type SiteDashboardChartReconciler struct {
 //+kubebuilder:rbac:groups=vyogo.tech,resources=sitedashboardcharts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=vyogo.tech,resources=sitedashboardcharts/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=vyogo.tech,resources=sitedashboardcharts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=vyogo.tech,resources=frappesites,verbs=get;list;watch
+//+kubebuilder:rbac:groups=vyogo.tech,resources=frappebenches,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the SiteDashboardChart object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
+// Reconcile replaces the site's Dashboard Chart named spec.chartName with the one the spec
+// describes, in a Job running `bench --site <site> execute` with frappe.delete_doc_if_exists
+// and frappe.client.insert. The chart's hash is kept in status.hash, so an unchanged spec
+// is not applied again, whether the last attempt succeeded or failed. A renamed chart
+// removes the chart of the old name, and the finalizer removes the chart from the site
+// when the SiteDashboardChart is deleted.
 func (r *SiteDashboardChartReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+
+	chart := &vyogotechv1alpha1.SiteDashboardChart{}
+	if err := r.Get(ctx, req.NamespacedName, chart); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !chart.DeletionTimestamp.IsZero() {
+		return r.finalizeChart(ctx, chart)
+	}
+	if !controllerutil.ContainsFinalizer(chart, siteDashboardChartFinalizer) {
+		controllerutil.AddFinalizer(chart, siteDashboardChartFinalizer)
+		if err := r.Update(ctx, chart); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	insert, err := dashboardChartInsertKwargs(chart)
+	if err != nil {
+		if chart.Status.Phase == "Failed" && chart.Status.Message == err.Error() {
+			return ctrl.Result{}, nil
+		}
+		r.Recorder.Event(chart, corev1.EventTypeWarning, "InvalidDashboardChart", err.Error())
+		return ctrl.Result{}, r.updateChartStatus(ctx, chart, func(status *vyogotechv1alpha1.SiteDashboardChartStatus) {
+			status.Phase = "Failed"
+			status.Hash = ""
+			status.Message = err.Error()
+			setChartApplied(status, chart.Generation, metav1.ConditionFalse, "InvalidSpec", err.Error())
+		})
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(insert))[:16]
+	if chart.Status.Hash == hash && (chart.Status.Phase == "Applied" || chart.Status.Phase == "Failed") {
+		return ctrl.Result{}, nil
+	}
+
+	jobName := fmt.Sprintf("%s-apply-%s", chart.Name, hash[:8])
+	job := &batchv1.Job{}
+	err = r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: chart.Namespace}, job)
+	if errors.IsNotFound(err) {
+		return r.startApplyJob(ctx, chart, insert, hash, jobName)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if job.Status.Succeeded > 0 {
+		logger.Info("Dashboard chart applied", "chart", chart.Spec.ChartName, "site", chart.Spec.Site)
+		r.Recorder.Event(chart, corev1.EventTypeNormal, "DashboardChartApplied",
+			fmt.Sprintf("Applied dashboard chart %s to %s", chart.Spec.ChartName, chart.Spec.Site))
+		return ctrl.Result{}, r.updateChartStatus(ctx, chart, func(status *vyogotechv1alpha1.SiteDashboardChartStatus) {
+			status.Phase = "Applied"
+			status.ChartName = chart.Spec.ChartName
+			status.Hash = hash
+			status.JobName = job.Name
+			status.Message = "Dashboard chart applied"
+			now := metav1.Now()
+			status.LastAppliedTime = &now
+			setChartApplied(status, chart.Generation, metav1.ConditionTrue, "Applied", "Dashboard chart matches the spec")
+		})
+	}
+	if jobFailed(job) {
+		message := fmt.Sprintf("Job %s failed to apply dashboard chart %s", job.Name, chart.Spec.ChartName)
+		r.Recorder.Event(chart, corev1.EventTypeWarning, "DashboardChartApplyFailed", message)
+		return ctrl.Result{}, r.updateChartStatus(ctx, chart, func(status *vyogotechv1alpha1.SiteDashboardChartStatus) {
+			status.Phase = "Failed"
+			status.Hash = hash
+			status.JobName = job.Name
+			status.Message = message
+			setChartApplied(status, chart.Generation, metav1.ConditionFalse, "ApplyFailed", message)
+		})
+	}
+
+	if chart.Status.Phase == "Applying" && chart.Status.JobName == job.Name {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, r.updateChartStatus(ctx, chart, func(status *vyogotechv1alpha1.SiteDashboardChartStatus) {
+		status.Phase = "Applying"
+		status.Hash = hash
+		status.JobName = job.Name
+		status.Message = fmt.Sprintf("Applying dashboard chart %s", chart.Spec.ChartName)
+		setChartApplied(status, chart.Generation, metav1.ConditionFalse, "Applying", status.Message)
+	})
+}
+
+// startApplyJob starts the Job replacing the chart once the site is Ready. A site that
+// doesn't exist or isn't Ready yet is waited for.
+func (r *SiteDashboardChartReconciler) startApplyJob(ctx context.Context, chart *vyogotechv1alpha1.SiteDashboardChart, insert []byte, hash, jobName string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	site, bench, err := findReadySiteBench(ctx, r.Client, chart.Namespace, chart.Spec.Site)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if bench == nil {
+		message := fmt.Sprintf("Waiting for FrappeSite %s to be Ready", chart.Spec.Site)
+		if chart.Status.Phase == "Pending" && chart.Status.Message == message {
+			return ctrl.Result{RequeueAfter: missingSecretRequeue}, nil
+		}
+		return ctrl.Result{RequeueAfter: missingSecretRequeue}, r.updateChartStatus(ctx, chart, func(status *vyogotechv1alpha1.SiteDashboardChartStatus) {
+			status.Phase = "Pending"
+			status.Message = message
+			setChartApplied(status, chart.Generation, metav1.ConditionFalse, "SiteNotReady", message)
+		})
+	}
+
+	// Replace the chart of the spec's name, and drop the one of the old name after a rename
+	remove := []string{chart.Spec.ChartName}
+	if chart.Status.ChartName != "" && chart.Status.ChartName != chart.Spec.ChartName {
+		remove = append(remove, chart.Status.ChartName)
+	}
+	job, err := r.buildChartJob(ctx, chart, site, bench, jobName, remove, insert)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Created dashboard chart job", "job", jobName, "chart", chart.Spec.ChartName)
+	r.Recorder.Event(chart, corev1.EventTypeNormal, "DashboardChartApplying",
+		fmt.Sprintf("Applying dashboard chart %s to %s", chart.Spec.ChartName, chart.Spec.Site))
+	return ctrl.Result{}, r.updateChartStatus(ctx, chart, func(status *vyogotechv1alpha1.SiteDashboardChartStatus) {
+		status.Phase = "Applying"
+		status.Hash = hash
+		status.JobName = jobName
+		status.Message = fmt.Sprintf("Applying dashboard chart %s", chart.Spec.ChartName)
+		setChartApplied(status, chart.Generation, metav1.ConditionFalse, "Applying", status.Message)
+	})
+}
+
+// finalizeChart removes the chart from the site, then the finalizer. When the site is gone
+// or not Ready there is nothing to remove it from, and a failed removal is reported but
+// doesn't hold up the deletion.
+func (r *SiteDashboardChartReconciler) finalizeChart(ctx context.Context, chart *vyogotechv1alpha1.SiteDashboardChart) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(chart, siteDashboardChartFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// A chart was only created once a job ran, and an attempt may have created it under the
+	// spec's name before failing
+	var remove []string
+	if chart.Status.ChartName != "" {
+		remove = append(remove, chart.Status.ChartName)
+	}
+	if chart.Status.Hash != "" && chart.Spec.ChartName != chart.Status.ChartName {
+		remove = append(remove, chart.Spec.ChartName)
+	}
 
-	// TODO(user): your logic here
+	if len(remove) > 0 {
+		site, bench, err := findReadySiteBench(ctx, r.Client, chart.Namespace, chart.Spec.Site)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if bench == nil {
+			logger.Info("Site not Ready, leaving dashboard chart in place", "site", chart.Spec.Site)
+		} else {
+			jobName := fmt.Sprintf("%s-delete", chart.Name)
+			job := &batchv1.Job{}
+			err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: chart.Namespace}, job)
+			if errors.IsNotFound(err) {
+				job, err := r.buildChartJob(ctx, chart, site, bench, jobName, remove, nil)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				logger.Info("Created dashboard chart removal job", "job", jobName, "chart", strings.Join(remove, ", "))
+				return ctrl.Result{}, r.Create(ctx, job)
+			}
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			switch {
+			case job.Status.Succeeded > 0:
+				r.Recorder.Event(chart, corev1.EventTypeNormal, "DashboardChartRemoved",
+					fmt.Sprintf("Removed dashboard chart %s from %s", strings.Join(remove, ", "), chart.Spec.Site))
+			case jobFailed(job):
+				r.Recorder.Event(chart, corev1.EventTypeWarning, "DashboardChartRemoveFailed",
+					fmt.Sprintf("Job %s failed to remove dashboard chart %s from %s", job.Name, strings.Join(remove, ", "), chart.Spec.Site))
+			default:
+				return ctrl.Result{}, nil
+			}
+		}
+	}
 
-	return ctrl.Result{}, nil
+	controllerutil.RemoveFinalizer(chart, siteDashboardChartFinalizer)
+	return ctrl.Result{}, r.Update(ctx, chart)
+}
+
+// buildChartJob returns the Job deleting the charts named in remove, then inserting the
+// chart described by insert unless it is nil
+func (r *SiteDashboardChartReconciler) buildChartJob(ctx context.Context, chart *vyogotechv1alpha1.SiteDashboardChart, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench, jobName string, remove []string, insert []byte) (*batchv1.Job, error) {
+	script, err := scripts.RenderScript(scripts.DashboardChart, scripts.DashboardChartData{SiteName: chart.Spec.Site})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render dashboard chart script: %w", err)
+	}
+	deletes := make([]string, 0, len(remove))
+	for _, name := range remove {
+		args, err := json.Marshal([]any{"Dashboard Chart", name, 1})
+		if err != nil {
+			return nil, err
+		}
+		deletes = append(deletes, string(args))
+	}
+
+	// The site controller's helpers pick the bench image and security contexts
+	siteReconciler := &FrappeSiteReconciler{Client: r.Client, Scheme: r.Scheme}
+	container := resources.NewContainerBuilder("dashboard-chart", siteReconciler.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(script).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(siteReconciler.getContainerSecurityContext(ctx, bench)).
+		WithEnv("USER", "frappe").
+		WithEnv("CHART_DELETE", strings.Join(deletes, "\n")).
+		WithEnv("CHART_INSERT", string(insert)).
+		Build()
+
+	job, err := resources.NewJobBuilder(jobName, chart.Namespace).
+		WithLabels(map[string]string{"app": "frappe", "site": site.Name}).
+		WithLabels(jobLabels(jobOperationDashboardChart, bench.Name, chart.Spec.Site)).
+		WithBackoffLimit(0).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(siteReconciler.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(chart, r.Scheme).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	applyDefaultJobTTL(&job.Spec)
+	return job, nil
+}
+
+// updateChartStatus applies update to the latest version of the SiteDashboardChart's status
+func (r *SiteDashboardChartReconciler) updateChartStatus(ctx context.Context, chart *vyogotechv1alpha1.SiteDashboardChart, update func(*vyogotechv1alpha1.SiteDashboardChartStatus)) error {
+	latest := &vyogotechv1alpha1.SiteDashboardChart{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(chart), latest); err != nil {
+		return err
+	}
+	update(&latest.Status)
+	return r.Status().Update(ctx, latest)
+}
+
+// setChartApplied sets the Applied condition
+func setChartApplied(status *vyogotechv1alpha1.SiteDashboardChartStatus, generation int64, conditionStatus metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               dashboardChartAppliedCondition,
+		Status:             conditionStatus,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SiteDashboardChartReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vyogotechv1alpha1.SiteDashboardChart{}).
+		Owns(&batchv1.Job{}).
 		Complete(r)
 }
+
+// dashboardChartDoc is the Dashboard Chart document, in the field names of Frappe's
+// Dashboard Chart doctype. Flags are ints: older bench execute evaluates --kwargs as
+// Python, where JSON's true and false are undefined.
+type dashboardChartDoc struct {
+	Doctype                  string `json:"doctype"`
+	Name                     string `json:"name"`
+	ChartName                string `json:"chart_name"`
+	ChartType                string `json:"chart_type"`
+	Type                     string `json:"type"`
+	DocumentType             string `json:"document_type"`
+	ValueBasedOn             string `json:"value_based_on,omitempty"`
+	Timeseries               int    `json:"timeseries"`
+	BasedOn                  string `json:"based_on,omitempty"`
+	Timespan                 string `json:"timespan,omitempty"`
+	TimeInterval             string `json:"time_interval,omitempty"`
+	GroupByType              string `json:"group_by_type,omitempty"`
+	GroupByBasedOn           string `json:"group_by_based_on,omitempty"`
+	AggregateFunctionBasedOn string `json:"aggregate_function_based_on,omitempty"`
+	NumberOfGroups           int32  `json:"number_of_groups,omitempty"`
+	FiltersJSON              string `json:"filters_json"`
+	Color                    string `json:"color,omitempty"`
+	Module                   string `json:"module,omitempty"`
+	IsPublic                 int    `json:"is_public"`
+}
+
+// dashboardChartInsertKwargs renders the spec as the keyword arguments of
+// frappe.client.insert, {"doc": <Dashboard Chart>}
+func dashboardChartInsertKwargs(chart *vyogotechv1alpha1.SiteDashboardChart) ([]byte, error) {
+	spec := chart.Spec
+	if errs := validation.IsDNS1123Subdomain(spec.Site); len(errs) > 0 {
+		return nil, fmt.Errorf("site %q is not a valid site name: %s", spec.Site, errs[0])
+	}
+	if spec.ChartName == "" {
+		return nil, fmt.Errorf("chartName is required")
+	}
+	if spec.DocumentType == "" {
+		return nil, fmt.Errorf("documentType is required")
+	}
+
+	doc := dashboardChartDoc{
+		Doctype:      "Dashboard Chart",
+		Name:         spec.ChartName,
+		ChartName:    spec.ChartName,
+		ChartType:    spec.ChartType,
+		Type:         spec.Type,
+		DocumentType: spec.DocumentType,
+		Color:        spec.Color,
+		Module:       spec.Module,
+		IsPublic:     1,
+	}
+	if doc.ChartType == "" {
+		doc.ChartType = "Count"
+	}
+	if doc.Type == "" {
+		doc.Type = "Line"
+	}
+
+	switch doc.ChartType {
+	case "Group By":
+		groupBy := spec.GroupBy
+		if groupBy == nil || groupBy.Field == "" {
+			return nil, fmt.Errorf("chartType Group By needs groupBy.field")
+		}
+		doc.GroupByBasedOn = groupBy.Field
+		doc.GroupByType = groupBy.Type
+		if doc.GroupByType == "" {
+			doc.GroupByType = "Count"
+		}
+		if doc.GroupByType != "Count" {
+			if groupBy.AggregateField == "" {
+				return nil, fmt.Errorf("groupBy.type %s needs groupBy.aggregateField", doc.GroupByType)
+			}
+			doc.AggregateFunctionBasedOn = groupBy.AggregateField
+		}
+		doc.NumberOfGroups = groupBy.NumberOfGroups
+	default:
+		if doc.ChartType != "Count" {
+			if spec.ValueBasedOn == "" {
+				return nil, fmt.Errorf("chartType %s needs valueBasedOn", doc.ChartType)
+			}
+			doc.ValueBasedOn = spec.ValueBasedOn
+		}
+		timeSeries := spec.TimeSeries
+		if timeSeries == nil || timeSeries.BasedOn == "" {
+			return nil, fmt.Errorf("chartType %s needs timeSeries.basedOn", doc.ChartType)
+		}
+		doc.Timeseries = 1
+		doc.BasedOn = timeSeries.BasedOn
+		doc.Timespan = timeSeries.Timespan
+		if doc.Timespan == "" {
+			doc.Timespan = "Last Year"
+		}
+		doc.TimeInterval = timeSeries.TimeInterval
+		if doc.TimeInterval == "" {
+			doc.TimeInterval = "Monthly"
+		}
+	}
+
+	// filters_json holds filters in list form: [doctype, field, operator, value]
+	filters := make([][]string, 0, len(spec.Filters))
+	for _, filter := range spec.Filters {
+		if filter.Field == "" {
+			return nil, fmt.Errorf("every filter needs a field")
+		}
+		operator := filter.Operator
+		if operator == "" {
+			operator = "="
+		}
+		filters = append(filters, []string{spec.DocumentType, filter.Field, operator, filter.Value})
+	}
+	filtersJSON, err := json.Marshal(filters)
+	if err != nil {
+		return nil, err
+	}
+	doc.FiltersJSON = string(filtersJSON)
+
+	return json.Marshal(map[string]dashboardChartDoc{"doc": doc})
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newSiteDashboardChartTestReconciler(objs ...client.Object) (*SiteDashboardChartReconciler, client.Client, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&vyogotechv1alpha1.SiteDashboardChart{}).Build()
+	recorder := record.NewFakeRecorder(10)
+	return &SiteDashboardChartReconciler{Client: c, Scheme: scheme, Recorder: recorder}, c, recorder
+}
+
+func newSiteDashboardChartTestObjects() (*vyogotechv1alpha1.SiteDashboardChart, *vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench) {
+	site, bench := newInitJobTestObjects()
+	site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseReady
+	chart := &vyogotechv1alpha1.SiteDashboardChart{
+		ObjectMeta: metav1.ObjectMeta{Name: "invoices", Namespace: "default", UID: "chart-uid"},
+		Spec: vyogotechv1alpha1.SiteDashboardChartSpec{
+			Site:         "site.local",
+			ChartName:    "Monthly Invoices",
+			ChartType:    "Sum",
+			Type:         "Bar",
+			DocumentType: "Sales Invoice",
+			ValueBasedOn: "grand_total",
+			TimeSeries:   &vyogotechv1alpha1.DashboardChartTimeSeries{BasedOn: "posting_date"},
+			Filters: []vyogotechv1alpha1.DashboardChartFilter{
+				{Field: "docstatus", Value: "1"},
+				{Field: "status", Operator: "!=", Value: "Cancelled"},
+			},
+		},
+	}
+	return chart, site, bench
+}
+
+func getDashboardChartTestJob(t *testing.T, c client.Client, name string) *batchv1.Job {
+	t.Helper()
+	job := &batchv1.Job{}
+	if err := c.Get(context.Background(), types.NamespacedName{Name: name, Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job %s: %v", name, err)
+	}
+	return job
+}
+
+func jobEnv(job *batchv1.Job, name string) string {
+	for _, env := range job.Spec.Template.Spec.Containers[0].Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+func TestDashboardChartInsertKwargs(t *testing.T) {
+	chart, _, _ := newSiteDashboardChartTestObjects()
+
+	kwargs, err := dashboardChartInsertKwargs(chart)
+	if err != nil {
+		t.Fatalf("dashboardChartInsertKwargs: %v", err)
+	}
+	var decoded map[string]dashboardChartDoc
+	if err := json.Unmarshal(kwargs, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	doc := decoded["doc"]
+	want := dashboardChartDoc{
+		Doctype:      "Dashboard Chart",
+		Name:         "Monthly Invoices",
+		ChartName:    "Monthly Invoices",
+		ChartType:    "Sum",
+		Type:         "Bar",
+		DocumentType: "Sales Invoice",
+		ValueBasedOn: "grand_total",
+		Timeseries:   1,
+		BasedOn:      "posting_date",
+		Timespan:     "Last Year",
+		TimeInterval: "Monthly",
+		FiltersJSON:  `[["Sales Invoice","docstatus","=","1"],["Sales Invoice","status","!=","Cancelled"]]`,
+		IsPublic:     1,
+	}
+	if doc != want {
+		t.Errorf("doc = %+v, want %+v", doc, want)
+	}
+	if strings.Contains(string(kwargs), "true") || strings.Contains(string(kwargs), "false") {
+		t.Errorf("expected no JSON booleans in the kwargs, got %s", kwargs)
+	}
+
+	groupBy := chart.DeepCopy()
+	groupBy.Spec.ChartType = "Group By"
+	groupBy.Spec.Type = "Pie"
+	groupBy.Spec.TimeSeries = nil
+	groupBy.Spec.GroupBy = &vyogotechv1alpha1.DashboardChartGroupBy{Field: "customer", Type: "Sum", AggregateField: "grand_total", NumberOfGroups: 5}
+	kwargs, err = dashboardChartInsertKwargs(groupBy)
+	if err != nil {
+		t.Fatalf("dashboardChartInsertKwargs: %v", err)
+	}
+	if err := json.Unmarshal(kwargs, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	doc = decoded["doc"]
+	if doc.GroupByBasedOn != "customer" || doc.GroupByType != "Sum" || doc.AggregateFunctionBasedOn != "grand_total" ||
+		doc.NumberOfGroups != 5 || doc.Timeseries != 0 || doc.ValueBasedOn != "" {
+		t.Errorf("unexpected group by chart: %+v", doc)
+	}
+
+	for name, mutate := range map[string]func(*vyogotechv1alpha1.SiteDashboardChartSpec){
+		"sum without valueBasedOn":  func(s *vyogotechv1alpha1.SiteDashboardChartSpec) { s.ValueBasedOn = "" },
+		"count without time series": func(s *vyogotechv1alpha1.SiteDashboardChartSpec) { s.ChartType = "Count"; s.TimeSeries = nil },
+		"group by without field":    func(s *vyogotechv1alpha1.SiteDashboardChartSpec) { s.ChartType = "Group By" },
+		"filter without field":      func(s *vyogotechv1alpha1.SiteDashboardChartSpec) { s.Filters[0].Field = "" },
+		"site name with a space":    func(s *vyogotechv1alpha1.SiteDashboardChartSpec) { s.Site = "site local" },
+		"missing source document":   func(s *vyogotechv1alpha1.SiteDashboardChartSpec) { s.DocumentType = "" },
+	} {
+		invalid := chart.DeepCopy()
+		mutate(&invalid.Spec)
+		if _, err := dashboardChartInsertKwargs(invalid); err == nil {
+			t.Errorf("expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestSiteDashboardChartReconcile(t *testing.T) {
+	chart, site, bench := newSiteDashboardChartTestObjects()
+	r, c, recorder := newSiteDashboardChartTestReconciler(chart, site, bench)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "invoices", Namespace: "default"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteDashboardChart{}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteDashboardChart: %v", err)
+	}
+	if len(updated.Finalizers) != 1 || updated.Finalizers[0] != siteDashboardChartFinalizer {
+		t.Errorf("expected the dashboard chart finalizer, got %v", updated.Finalizers)
+	}
+	if updated.Status.Phase != "Applying" || updated.Status.Hash == "" || updated.Status.ChartName != "" {
+		t.Fatalf("expected Applying with a hash, got %+v", updated.Status)
+	}
+	job := getDashboardChartTestJob(t, c, updated.Status.JobName)
+	if job.Labels[jobOperationLabel] != jobOperationDashboardChart {
+		t.Errorf("expected operation label %q, got %q", jobOperationDashboardChart, job.Labels[jobOperationLabel])
+	}
+	if got := jobEnv(job, "CHART_DELETE"); got != `["Dashboard Chart","Monthly Invoices",1]` {
+		t.Errorf("expected the chart to be replaced, CHART_DELETE = %q", got)
+	}
+	if !strings.Contains(jobEnv(job, "CHART_INSERT"), `"chart_name":"Monthly Invoices"`) {
+		t.Errorf("expected the chart in CHART_INSERT, got %q", jobEnv(job, "CHART_INSERT"))
+	}
+	if !strings.Contains(job.Spec.Template.Spec.Containers[0].Args[0], "bench --site site.local execute frappe.client.insert") {
+		t.Error("expected the job to insert the chart with bench execute")
+	}
+	<-recorder.Events
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteDashboardChart: %v", err)
+	}
+	if updated.Status.Phase != "Applied" || updated.Status.ChartName != "Monthly Invoices" || updated.Status.LastAppliedTime == nil {
+		t.Errorf("expected Applied, got %+v", updated.Status)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, dashboardChartAppliedCondition) {
+		t.Errorf("expected the Applied condition, got %+v", updated.Status.Conditions)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "DashboardChartApplied") {
+		t.Errorf("expected DashboardChartApplied event, got %q", event)
+	}
+
+	// A rename replaces the chart and removes the one of the old name
+	updated.Spec.ChartName = "Invoices by Month"
+	if err := c.Update(ctx, updated); err != nil {
+		t.Fatalf("Update SiteDashboardChart: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteDashboardChart: %v", err)
+	}
+	if updated.Status.JobName == job.Name {
+		t.Fatal("expected a new apply job for the changed spec")
+	}
+	renamed := getDashboardChartTestJob(t, c, updated.Status.JobName)
+	want := "[\"Dashboard Chart\",\"Invoices by Month\",1]\n[\"Dashboard Chart\",\"Monthly Invoices\",1]"
+	if got := jobEnv(renamed, "CHART_DELETE"); got != want {
+		t.Errorf("CHART_DELETE = %q, want %q", got, want)
+	}
+}
+
+func TestSiteDashboardChartDeletion(t *testing.T) {
+	chart, site, bench := newSiteDashboardChartTestObjects()
+	chart.Finalizers = []string{siteDashboardChartFinalizer}
+	chart.Status = vyogotechv1alpha1.SiteDashboardChartStatus{Phase: "Applied", ChartName: "Monthly Invoices", Hash: "0123456789abcdef"}
+	r, c, recorder := newSiteDashboardChartTestReconciler(chart, site, bench)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "invoices", Namespace: "default"}}
+
+	if err := c.Delete(ctx, chart); err != nil {
+		t.Fatalf("Delete SiteDashboardChart: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	job := getDashboardChartTestJob(t, c, "invoices-delete")
+	if got := jobEnv(job, "CHART_DELETE"); got != `["Dashboard Chart","Monthly Invoices",1]` || jobEnv(job, "CHART_INSERT") != "" {
+		t.Errorf("expected a removal job, got CHART_DELETE=%q CHART_INSERT=%q", got, jobEnv(job, "CHART_INSERT"))
+	}
+
+	// The finalizer stays until the chart is removed from the site
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &vyogotechv1alpha1.SiteDashboardChart{}); err != nil {
+		t.Fatalf("expected the SiteDashboardChart to wait for the removal job: %v", err)
+	}
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &vyogotechv1alpha1.SiteDashboardChart{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the SiteDashboardChart to be gone, got %v", err)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "DashboardChartRemoved") {
+		t.Errorf("expected DashboardChartRemoved event, got %q", event)
+	}
+}
+
+func TestSiteDashboardChartDeletionWithoutSite(t *testing.T) {
+	chart, _, _ := newSiteDashboardChartTestObjects()
+	chart.Finalizers = []string{siteDashboardChartFinalizer}
+	chart.Status = vyogotechv1alpha1.SiteDashboardChartStatus{Phase: "Applied", ChartName: "Monthly Invoices", Hash: "0123456789abcdef"}
+	r, c, _ := newSiteDashboardChartTestReconciler(chart)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "invoices", Namespace: "default"}}
+
+	if err := c.Delete(ctx, chart); err != nil {
+		t.Fatalf("Delete SiteDashboardChart: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &vyogotechv1alpha1.SiteDashboardChart{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the SiteDashboardChart to be released without a site, got %v", err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(ctx, jobs); err != nil {
+		t.Fatalf("List Jobs: %v", err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("expected no removal job without a site, got %d", len(jobs.Items))
+	}
+}
+
+func TestSiteDashboardChartReconcileFailure(t *testing.T) {
+	chart, site, bench := newSiteDashboardChartTestObjects()
+	r, c, recorder := newSiteDashboardChartTestReconciler(chart, site, bench)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "invoices", Namespace: "default"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	<-recorder.Events
+	updated := &vyogotechv1alpha1.SiteDashboardChart{}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteDashboardChart: %v", err)
+	}
+	job := getDashboardChartTestJob(t, c, updated.Status.JobName)
+	job.Status.Failed = 1
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteDashboardChart: %v", err)
+	}
+	condition := meta.FindStatusCondition(updated.Status.Conditions, dashboardChartAppliedCondition)
+	if updated.Status.Phase != "Failed" || condition == nil || condition.Reason != "ApplyFailed" {
+		t.Errorf("expected Failed with ApplyFailed, got %+v", updated.Status)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "Warning DashboardChartApplyFailed") {
+		t.Errorf("expected DashboardChartApplyFailed event, got %q", event)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// jobCommandLabel records on a SiteJob's Job which bench command it runs
+const jobCommandLabel = "frappe.tech/command"
+
+// benchDir is the bench directory of the frappe images, where bench commands run
+const benchDir = "/home/frappe/frappe-bench"
+
+var (
+	// siteJobConfigKeyPattern matches site_config.json keys set-config may write
+	siteJobConfigKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// siteJobMethodPattern matches dotted Python paths such as frappe.utils.now
+	siteJobMethodPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)+$`)
+)
+
+// siteJobArgs returns the bench arguments of spec.command, rejecting arguments bench could
+// mistake for options. The arguments are passed to bench as they are, without a shell.
+func siteJobArgs(siteJob *vyogotechv1alpha1.SiteJob) ([]string, error) {
+	site := siteJob.Spec.Site
+	if errs := validation.IsDNS1123Subdomain(site); len(errs) > 0 {
+		return nil, fmt.Errorf("site %q is not a valid site name: %s", site, errs[0])
+	}
+
+	switch siteJob.Spec.Command {
+	case vyogotechv1alpha1.SiteJobClearCache:
+		return []string{"--site", site, "clear-cache"}, nil
+
+	case vyogotechv1alpha1.SiteJobMigrate:
+		args := []string{"--site", site, "migrate"}
+		if siteJob.Spec.Migrate != nil && siteJob.Spec.Migrate.SkipFailing {
+			args = append(args, "--skip-failing")
+		}
+		return args, nil
+
+	case vyogotechv1alpha1.SiteJobBuild:
+		args := []string{"build"}
+		if siteJob.Spec.Build != nil && siteJob.Spec.Build.App != "" {
+			if !vyogotechv1alpha1.IsValidAppName(siteJob.Spec.Build.App) {
+				return nil, fmt.Errorf("build.app %q is not a valid app name", siteJob.Spec.Build.App)
+			}
+			args = append(args, "--app", siteJob.Spec.Build.App)
+		}
+		return args, nil
+
+	case vyogotechv1alpha1.SiteJobSetConfig:
+		setConfig := siteJob.Spec.SetConfig
+		if setConfig == nil {
+			return nil, fmt.Errorf("setConfig must be specified for command set-config")
+		}
+		if !siteJobConfigKeyPattern.MatchString(setConfig.Key) {
+			return nil, fmt.Errorf("setConfig.key %q is not a valid site config key", setConfig.Key)
+		}
+		if operatorManagedSiteConfigKey(setConfig.Key) {
+			return nil, fmt.Errorf("setConfig.key %q is managed by the operator", setConfig.Key)
+		}
+		args := []string{"--site", site, "set-config"}
+		if setConfig.Parse {
+			args = append(args, "--parse")
+		}
+		// "--" keeps a value starting with a dash from being read as an option
+		return append(args, "--", setConfig.Key, setConfig.Value), nil
+
+	case vyogotechv1alpha1.SiteJobExecute:
+		execute := siteJob.Spec.Execute
+		if execute == nil {
+			return nil, fmt.Errorf("execute must be specified for command execute")
+		}
+		if !siteJobMethodPattern.MatchString(execute.Method) {
+			return nil, fmt.Errorf("execute.method %q is not a dotted Python path", execute.Method)
+		}
+		args := []string{"--site", site, "execute", execute.Method}
+		if len(execute.Args) > 0 {
+			encoded, err := json.Marshal(execute.Args)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "--args", string(encoded))
+		}
+		if len(execute.Kwargs) > 0 {
+			encoded, err := json.Marshal(execute.Kwargs)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "--kwargs", string(encoded))
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("unsupported command %q", siteJob.Spec.Command)
+}
+
+// createSiteJobJob starts the Job running spec.command on the site's bench. A command
+// that can't run is recorded as Failed rather than retried.
+func (r *SiteJobReconciler) createSiteJobJob(ctx context.Context, siteJob *vyogotechv1alpha1.SiteJob) error {
+	logger := log.FromContext(ctx)
+
+	fail := func(reason, message string) error {
+		r.Recorder.Event(siteJob, corev1.EventTypeWarning, reason, message)
+		return r.updateSiteJobStatus(ctx, siteJob, func(status *vyogotechv1alpha1.SiteJobStatus) {
+			status.Phase = "Failed"
+			status.Message = message
+			now := metav1.Now()
+			status.CompletionTime = &now
+		})
+	}
+
+	args, err := siteJobArgs(siteJob)
+	if err != nil {
+		return fail("InvalidCommand", err.Error())
+	}
+
+	site, err := findSiteByName(ctx, r.Client, siteJob.Namespace, siteJob.Spec.Site)
+	if err != nil {
+		return err
+	}
+	if site == nil || site.Spec.BenchRef == nil {
+		return fail("SiteNotFound", fmt.Sprintf("no FrappeSite found for site %s", siteJob.Spec.Site))
+	}
+	benchNamespace := site.Spec.BenchRef.Namespace
+	if benchNamespace == "" {
+		benchNamespace = site.Namespace
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{}
+	if err := r.Get(ctx, client.ObjectKey{Name: site.Spec.BenchRef.Name, Namespace: benchNamespace}, bench); err != nil {
+		return err
+	}
+
+	job := r.buildSiteJobJob(siteJob, bench, args)
+	job.Spec.Template.Spec.Containers[0].Resources = siteJobResources(site.Spec.SizeHint)
+	if err := r.Create(ctx, job); err != nil {
+		return err
+	}
+	logger.Info("Created site job", "job", job.Name, "command", siteJob.Spec.Command)
+	return r.updateSiteJobStatus(ctx, siteJob, func(status *vyogotechv1alpha1.SiteJobStatus) {
+		status.Phase = "Running"
+		status.JobName = job.Name
+		status.Message = fmt.Sprintf("Running bench %s", siteJob.Spec.Command)
+	})
+}
+
+// buildSiteJobJob creates the Job running bench with args against the bench's sites volume
+func (r *SiteJobReconciler) buildSiteJobJob(siteJob *vyogotechv1alpha1.SiteJob, bench *vyogotechv1alpha1.FrappeBench, args []string) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      siteJobName(siteJob),
+			Namespace: siteJob.Namespace,
+			Labels: map[string]string{
+				"app":           "frappe",
+				"site":          siteJob.Spec.Site,
+				jobCommandLabel: string(siteJob.Spec.Command),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          int32Ptr(0),
+			ActiveDeadlineSeconds: siteJobActiveDeadline(siteJob),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: jobPodAnnotations(bench),
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ImagePullSecrets:   imagePullSecrets(bench),
+					ServiceAccountName: jobServiceAccount(bench),
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: boolPtr(true),
+						SeccompProfile: &corev1.SeccompProfile{
+							Type: corev1.SeccompProfileTypeRuntimeDefault,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:       "bench",
+							Image:      r.getBenchImage(bench),
+							Command:    []string{"bench"},
+							Args:       args,
+							WorkingDir: benchDir,
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "sites",
+									MountPath: sitesMountPath,
+									SubPath:   sitesVolumeSubPath,
+								},
+							},
+							SecurityContext: &corev1.SecurityContext{
+								RunAsNonRoot:             boolPtr(true),
+								AllowPrivilegeEscalation: boolPtr(false),
+								Capabilities: &corev1.Capabilities{
+									Drop: []corev1.Capability{"ALL"},
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "sites",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: fmt.Sprintf("%s-sites", bench.Name),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	labelJob(job, jobLabels(jobOperationCommand, bench.Name, siteJob.Spec.Site))
+	applyDefaultJobTTL(&job.Spec)
+	controllerutil.SetControllerReference(siteJob, job, r.Scheme)
+	return job
+}
+
+// getBenchImage returns the image to use for the bench
+func (r *SiteJobReconciler) getBenchImage(bench *vyogotechv1alpha1.FrappeBench) string {
+	if image := channelImage(bench); image != "" {
+		return image
+	}
+	if bench.Spec.ImageConfig != nil && bench.Spec.ImageConfig.Repository != "" {
+		image := bench.Spec.ImageConfig.Repository
+		if bench.Spec.ImageConfig.Tag != "" {
+			return fmt.Sprintf("%s:%s", image, bench.Spec.ImageConfig.Tag)
+		}
+		return fmt.Sprintf("%s:%s", image, bench.Spec.FrappeVersion)
+	}
+	return fmt.Sprintf("frappe/erpnext:%s", bench.Spec.FrappeVersion)
+}
@@ -18,7 +18,13 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -26,39 +32,174 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/progress"
 )
 
+// siteJobLogTailLines is how much of a failed job's log is kept in status.logTail
+const siteJobLogTailLines = 50
+
 // SiteJobReconciler reconciles a SiteJob object
 type SiteJobReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// LogReader tails the log of failed job pods into status.logTail; skipped when nil
+	LogReader progress.LogReader
 }
 
 //+kubebuilder:rbac:groups=vyogo.tech,resources=sitejobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=vyogo.tech,resources=sitejobs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=vyogo.tech,resources=sitejobs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=vyogo.tech,resources=frappesites,verbs=get;list;watch
+//+kubebuilder:rbac:groups=vyogo.tech,resources=frappebenches,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the SiteJob object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
+// Reconcile runs spec.command in a Job against the site's bench and tracks it: it keeps
+// the Job's activeDeadlineSeconds in line with spec.timeoutSeconds and records the
+// outcome, including the exit code and the tail of the failed pod's log, in status.
 func (r *SiteJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+
+	siteJob := &vyogotechv1alpha1.SiteJob{}
+	if err := r.Get(ctx, req.NamespacedName, siteJob); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if siteJob.Status.Phase == "Succeeded" || siteJob.Status.Phase == "Failed" {
+		return ctrl.Result{}, nil
+	}
+
+	job := &batchv1.Job{}
+	jobName := siteJobName(siteJob)
+	if err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: siteJob.Namespace}, job); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if siteJob.Spec.Command != "" {
+			return ctrl.Result{}, r.createSiteJobJob(ctx, siteJob)
+		}
+		if siteJob.Status.Phase == "Pending" {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.updateSiteJobStatus(ctx, siteJob, func(status *vyogotechv1alpha1.SiteJobStatus) {
+			status.Phase = "Pending"
+			status.Message = fmt.Sprintf("Waiting for Job %s", jobName)
+		})
+	}
+
+	if job.Status.Succeeded > 0 {
+		r.Recorder.Event(siteJob, corev1.EventTypeNormal, "JobSucceeded", fmt.Sprintf("Job %s completed", job.Name))
+		exitCode, err := jobExitCode(ctx, r.Client, job)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.updateSiteJobStatus(ctx, siteJob, func(status *vyogotechv1alpha1.SiteJobStatus) {
+			status.Phase = "Succeeded"
+			status.JobName = job.Name
+			status.Message = "Job completed successfully"
+			status.ExitCode = exitCode
+			now := metav1.Now()
+			status.CompletionTime = &now
+		})
+	}
+
+	if jobFailed(job) {
+		return ctrl.Result{}, r.recordSiteJobFailure(ctx, siteJob, job)
+	}
+
+	// A timeout changed while the job runs still applies to it
+	if deadline := siteJobActiveDeadline(siteJob); !reflect.DeepEqual(job.Spec.ActiveDeadlineSeconds, deadline) {
+		logger.Info("Updating job deadline", "job", job.Name, "timeoutSeconds", deadline)
+		job.Spec.ActiveDeadlineSeconds = deadline
+		if err := r.Update(ctx, job); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if siteJob.Status.Phase == "Running" {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, r.updateSiteJobStatus(ctx, siteJob, func(status *vyogotechv1alpha1.SiteJobStatus) {
+		status.Phase = "Running"
+		status.JobName = job.Name
+		status.Message = "Job running"
+	})
+}
+
+// recordSiteJobFailure marks the SiteJob failed with the tail of the failed pod's log and
+// a summary of it.
+// A job killed by its deadline is reported as JobTimedOut rather than JobFailed.
+func (r *SiteJobReconciler) recordSiteJobFailure(ctx context.Context, siteJob *vyogotechv1alpha1.SiteJob, job *batchv1.Job) error {
+	failure, err := readJobFailure(ctx, r.Client, r.LogReader, job, "", siteJobLogTailLines)
+	if err != nil {
+		// The log is best effort; the failure itself is still recorded
+		log.FromContext(ctx).Error(err, "Failed to read job log", "job", job.Name)
+	}
 
-	// TODO(user): your logic here
+	exitCode, err := jobExitCode(ctx, r.Client, job)
+	if err != nil {
+		return err
+	}
+
+	reason, message := "JobFailed", fmt.Sprintf("Job %s failed", job.Name)
+	if exitCode != nil {
+		message = fmt.Sprintf("Job %s failed with exit code %d", job.Name, *exitCode)
+	}
+	if jobDeadlineExceeded(job) {
+		reason = "JobTimedOut"
+		message = fmt.Sprintf("Job %s exceeded its timeout of %ds", job.Name, *job.Spec.ActiveDeadlineSeconds)
+	} else if failure.Summary != "" {
+		message += ": " + failure.Summary
+	}
+	r.Recorder.Event(siteJob, corev1.EventTypeWarning, reason, message)
+
+	return r.updateSiteJobStatus(ctx, siteJob, func(status *vyogotechv1alpha1.SiteJobStatus) {
+		status.Phase = "Failed"
+		status.JobName = job.Name
+		status.Message = message
+		status.LogTail = failure.LogTail
+		status.FailureSummary = failure.Summary
+		status.ExitCode = exitCode
+		now := metav1.Now()
+		status.CompletionTime = &now
+	})
+}
+
+// updateSiteJobStatus applies update to the latest version of the SiteJob's status
+func (r *SiteJobReconciler) updateSiteJobStatus(ctx context.Context, siteJob *vyogotechv1alpha1.SiteJob, update func(*vyogotechv1alpha1.SiteJobStatus)) error {
+	latest := &vyogotechv1alpha1.SiteJob{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(siteJob), latest); err != nil {
+		return err
+	}
+	update(&latest.Status)
+	return r.Status().Update(ctx, latest)
+}
+
+// siteJobName is the name of the Job running a SiteJob
+func siteJobName(siteJob *vyogotechv1alpha1.SiteJob) string {
+	return siteJob.Name + "-job"
+}
+
+// siteJobActiveDeadline returns the activeDeadlineSeconds of a SiteJob's Job
+func siteJobActiveDeadline(siteJob *vyogotechv1alpha1.SiteJob) *int64 {
+	return siteJob.Spec.TimeoutSeconds
+}
 
-	return ctrl.Result{}, nil
+// jobFailed reports whether a Job has given up, i.e. carries a true Failed condition
+func jobFailed(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SiteJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vyogotechv1alpha1.SiteJob{}).
+		Owns(&batchv1.Job{}).
 		Complete(r)
 }
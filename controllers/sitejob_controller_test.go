@@ -0,0 +1,304 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newSiteJobTestReconciler(objs ...client.Object) (*SiteJobReconciler, client.Client, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&vyogotechv1alpha1.SiteJob{}).Build()
+	recorder := record.NewFakeRecorder(10)
+	logs := &fakeLogReader{logs: "Traceback (most recent call last):\nfrappe.exceptions.ValidationError: boom\n"}
+	return &SiteJobReconciler{Client: c, Scheme: scheme, Recorder: recorder, LogReader: logs}, c, recorder
+}
+
+func TestSiteJobAppliesTimeout(t *testing.T) {
+	timeout := int64(600)
+	siteJob := &vyogotechv1alpha1.SiteJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "clear-cache", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteJobSpec{TimeoutSeconds: &timeout},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "clear-cache-job", Namespace: "default"}}
+	r, c, _ := newSiteJobTestReconciler(siteJob, job)
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "clear-cache", Namespace: "default"}}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	updatedJob := &batchv1.Job{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(job), updatedJob); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if d := updatedJob.Spec.ActiveDeadlineSeconds; d == nil || *d != 600 {
+		t.Errorf("expected activeDeadlineSeconds 600, got %v", d)
+	}
+	updated := &vyogotechv1alpha1.SiteJob{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(siteJob), updated); err != nil {
+		t.Fatalf("Get SiteJob: %v", err)
+	}
+	if updated.Status.Phase != "Running" || updated.Status.JobName != "clear-cache-job" {
+		t.Errorf("expected Running with job name, got %+v", updated.Status)
+	}
+}
+
+func TestSiteJobFailureCapturesLogTail(t *testing.T) {
+	deadline := int64(60)
+	tests := []struct {
+		name       string
+		reason     string
+		wantEvent  string
+		wantPrefix string
+	}{
+		{name: "failed", reason: "BackoffLimitExceeded", wantEvent: "JobFailed", wantPrefix: "Job clear-cache-job failed"},
+		{name: "timed out", reason: batchv1.JobReasonDeadlineExceeded, wantEvent: "JobTimedOut", wantPrefix: "Job clear-cache-job exceeded its timeout of 60s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siteJob := &vyogotechv1alpha1.SiteJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "clear-cache", Namespace: "default"},
+				Spec:       vyogotechv1alpha1.SiteJobSpec{TimeoutSeconds: &deadline},
+			}
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "clear-cache-job", Namespace: "default"},
+				Spec:       batchv1.JobSpec{ActiveDeadlineSeconds: &deadline},
+				Status: batchv1.JobStatus{
+					Failed:     1,
+					Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: tt.reason}},
+				},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "clear-cache-job-abc", Namespace: "default", Labels: map[string]string{"job-name": job.Name}},
+				Status:     corev1.PodStatus{Phase: corev1.PodFailed},
+			}
+			r, c, recorder := newSiteJobTestReconciler(siteJob, job, pod)
+			ctx := context.Background()
+
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "clear-cache", Namespace: "default"}}); err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+
+			updated := &vyogotechv1alpha1.SiteJob{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(siteJob), updated); err != nil {
+				t.Fatalf("Get SiteJob: %v", err)
+			}
+			if updated.Status.Phase != "Failed" || updated.Status.CompletionTime == nil {
+				t.Errorf("expected Failed with completion time, got %+v", updated.Status)
+			}
+			if !strings.HasPrefix(updated.Status.Message, tt.wantPrefix) {
+				t.Errorf("expected message %q, got %q", tt.wantPrefix, updated.Status.Message)
+			}
+			if !strings.Contains(updated.Status.LogTail, "ValidationError: boom") {
+				t.Errorf("expected log tail in status, got %q", updated.Status.LogTail)
+			}
+			if event := <-recorder.Events; !strings.Contains(event, tt.wantEvent) {
+				t.Errorf("expected %s event, got %q", tt.wantEvent, event)
+			}
+		})
+	}
+}
+
+func TestSiteJobArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    vyogotechv1alpha1.SiteJobSpec
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "clear-cache",
+			spec: vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobClearCache},
+			want: []string{"--site", "site.local", "clear-cache"},
+		},
+		{
+			name: "migrate skipping failing patches",
+			spec: vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobMigrate,
+				Migrate: &vyogotechv1alpha1.SiteJobMigrateArgs{SkipFailing: true}},
+			want: []string{"--site", "site.local", "migrate", "--skip-failing"},
+		},
+		{
+			name: "build one app",
+			spec: vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobBuild,
+				Build: &vyogotechv1alpha1.SiteJobBuildArgs{App: "erpnext"}},
+			want: []string{"build", "--app", "erpnext"},
+		},
+		{
+			name: "set-config",
+			spec: vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobSetConfig,
+				SetConfig: &vyogotechv1alpha1.SiteJobSetConfigArgs{Key: "max_file_size", Value: "-1", Parse: true}},
+			want: []string{"--site", "site.local", "set-config", "--parse", "--", "max_file_size", "-1"},
+		},
+		{
+			name: "execute",
+			spec: vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobExecute,
+				Execute: &vyogotechv1alpha1.SiteJobExecuteArgs{Method: "frappe.db.get_value", Args: []string{"User", "Administrator"}, Kwargs: map[string]string{"fieldname": "email"}}},
+			want: []string{"--site", "site.local", "execute", "frappe.db.get_value", "--args", `["User","Administrator"]`, "--kwargs", `{"fieldname":"email"}`},
+		},
+		{
+			name: "set-config of an operator-managed key",
+			spec: vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobSetConfig,
+				SetConfig: &vyogotechv1alpha1.SiteJobSetConfigArgs{Key: "db_password", Value: "x"}},
+			wantErr: true,
+		},
+		{
+			name: "execute with shell in the method",
+			spec: vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobExecute,
+				Execute: &vyogotechv1alpha1.SiteJobExecuteArgs{Method: "os.system; rm -rf /"}},
+			wantErr: true,
+		},
+		{
+			name:    "execute without arguments",
+			spec:    vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobExecute},
+			wantErr: true,
+		},
+		{
+			name:    "option as site",
+			spec:    vyogotechv1alpha1.SiteJobSpec{Site: "--help", Command: vyogotechv1alpha1.SiteJobClearCache},
+			wantErr: true,
+		},
+		{
+			name:    "unknown command",
+			spec:    vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: "console"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := siteJobArgs(&vyogotechv1alpha1.SiteJob{Spec: tt.spec})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("siteJobArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("siteJobArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSiteJobRunsCommand(t *testing.T) {
+	siteJob := &vyogotechv1alpha1.SiteJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "clear-cache", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobClearCache},
+	}
+	site := &vyogotechv1alpha1.FrappeSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "site", Namespace: "default"},
+		Spec: vyogotechv1alpha1.FrappeSiteSpec{
+			SiteName: "site.local",
+			BenchRef: &vyogotechv1alpha1.NamespacedName{Name: "bench"},
+		},
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.FrappeBenchSpec{FrappeVersion: "v15"},
+	}
+	r, c, _ := newSiteJobTestReconciler(siteJob, site, bench)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "clear-cache", Namespace: "default"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "clear-cache-job", Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if !slices.Equal(container.Command, []string{"bench"}) || !slices.Equal(container.Args, []string{"--site", "site.local", "clear-cache"}) {
+		t.Errorf("expected bench to run without a shell, got %q %q", container.Command, container.Args)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationCommand || job.Labels[jobCommandLabel] != "clear-cache" {
+		t.Errorf("unexpected job labels %v", job.Labels)
+	}
+	assertSitesMount(t, "site job", job.Spec.Template.Spec)
+
+	// The exit code of the finished pod lands in status
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job status: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "clear-cache-job-abc", Namespace: "default", Labels: map[string]string{"job-name": job.Name}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "bench",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+			}},
+		},
+	}
+	if err := c.Create(ctx, pod); err != nil {
+		t.Fatalf("Create Pod: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteJob{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(siteJob), updated); err != nil {
+		t.Fatalf("Get SiteJob: %v", err)
+	}
+	if updated.Status.Phase != "Succeeded" || updated.Status.ExitCode == nil || *updated.Status.ExitCode != 0 {
+		t.Errorf("expected Succeeded with exit code 0, got %+v", updated.Status)
+	}
+}
+
+func TestSiteJobRejectsInvalidCommand(t *testing.T) {
+	siteJob := &vyogotechv1alpha1.SiteJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "set-config", Namespace: "default"},
+		Spec: vyogotechv1alpha1.SiteJobSpec{Site: "site.local", Command: vyogotechv1alpha1.SiteJobSetConfig,
+			SetConfig: &vyogotechv1alpha1.SiteJobSetConfigArgs{Key: "host_name", Value: "evil.example.com"}},
+	}
+	r, c, recorder := newSiteJobTestReconciler(siteJob)
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "set-config", Namespace: "default"}}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteJob{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(siteJob), updated); err != nil {
+		t.Fatalf("Get SiteJob: %v", err)
+	}
+	if updated.Status.Phase != "Failed" || !strings.Contains(updated.Status.Message, "managed by the operator") {
+		t.Errorf("expected the command to be rejected, got %+v", updated.Status)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "set-config-job", Namespace: "default"}, &batchv1.Job{}); err == nil {
+		t.Error("expected no Job for a rejected command")
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "InvalidCommand") {
+		t.Errorf("expected InvalidCommand event, got %q", event)
+	}
+}
@@ -19,6 +19,8 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -32,6 +34,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/progress"
 )
 
 // SiteRestoreReconciler reconciles a SiteRestore object
@@ -39,12 +42,20 @@ type SiteRestoreReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// LogReader tails restore job logs for progress markers; progress is not reported when nil
+	LogReader progress.LogReader
+	// LookupTimeout bounds the VolumeSnapshot API probe; defaultLookupTimeout when zero
+	LookupTimeout time.Duration
 }
 
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siterestores,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siterestores/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siterestores/finalizers,verbs=update
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+//+kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
 
 func (r *SiteRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -73,7 +84,56 @@ func (r *SiteRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: siteRestore.Namespace}, job)
 
 	if errors.IsNotFound(err) {
+		site, err := findSiteByName(ctx, r.Client, siteRestore.Namespace, siteRestore.Spec.Site)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		// Restoring into a site that is still being created would race its init job
+		if site != nil && siteInitializing(site) {
+			message := fmt.Sprintf("Waiting for site %s to finish initializing", siteRestore.Spec.Site)
+			if siteRestore.Status.Phase != "Pending" || siteRestore.Status.Message != message {
+				logger.Info("Target site is initializing, holding restore", "site", site.Name)
+				if err := r.updateStatus(ctx, siteRestore, "Pending", message, ""); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			return ctrl.Result{RequeueAfter: restoreSiteInitPollInterval}, nil
+		}
+
+		if siteRestore.Spec.VolumeSnapshot != nil {
+			cut, failure, err := r.ensureVolumeSnapshot(ctx, siteRestore, bench)
+			if isLookupTimeout(err) {
+				logger.Info("VolumeSnapshot API lookup timed out, requeueing", "error", err.Error())
+				return ctrl.Result{RequeueAfter: lookupTimeoutRequeue}, nil
+			}
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if failure != "" {
+				return ctrl.Result{}, r.updateStatus(ctx, siteRestore, "Failed", failure, "")
+			}
+			if !cut {
+				return ctrl.Result{RequeueAfter: volumeSnapshotPollInterval}, nil
+			}
+		}
+
+		var sizeHint, rootSecretName string
+		if site != nil {
+			sizeHint = site.Spec.SizeHint
+			rootSecretName, err = r.ensureDBRootSecret(ctx, siteRestore, site, bench)
+			if isLookupTimeout(err) {
+				logger.Info("Database API lookup timed out, requeueing", "error", err.Error())
+				return ctrl.Result{RequeueAfter: lookupTimeoutRequeue}, nil
+			}
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
 		job = r.buildRestoreJob(siteRestore, bench)
+		if rootSecretName != "" {
+			job.Spec.Template.Spec.Containers[0].Env = append(job.Spec.Template.Spec.Containers[0].Env, dbRootEnv(rootSecretName)...)
+		}
+		job.Spec.Template.Spec.Containers[0].Resources = siteJobResources(sizeHint)
 		if err := r.Create(ctx, job); err != nil {
 			logger.Error(err, "Failed to create restore job")
 			return ctrl.Result{}, err
@@ -93,9 +153,127 @@ func (r *SiteRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, r.updateStatus(ctx, siteRestore, "Failed", "Restore job failed", job.Name)
 	}
 
+	if r.LogReader != nil {
+		return r.updateProgress(ctx, siteRestore, job)
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// updateProgress records the latest progress of a running restore job and polls
+// again after progressPollInterval; status is only written when progress moved
+func (r *SiteRestoreReconciler) updateProgress(ctx context.Context, siteRestore *vyogotechv1alpha1.SiteRestore, job *batchv1.Job) (ctrl.Result, error) {
+	next, err := readJobProgress(ctx, r.Client, r.LogReader, job, "restore")
+	if err != nil {
+		// Progress is best effort; keep polling without failing the restore
+		log.FromContext(ctx).Error(err, "Failed to read restore progress", "job", job.Name)
+		return ctrl.Result{RequeueAfter: progressPollInterval}, nil
+	}
+	if !progressChanged(siteRestore.Status.Progress, next) {
+		return ctrl.Result{RequeueAfter: progressPollInterval}, nil
+	}
+
+	latest := &vyogotechv1alpha1.SiteRestore{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(siteRestore), latest); err != nil {
+		return ctrl.Result{}, err
+	}
+	latest.Status.Progress = next
+	return ctrl.Result{RequeueAfter: progressPollInterval}, r.Status().Update(ctx, latest)
+}
+
+// restoreSiteInitPollInterval is how often a restore held for an initializing site checks again
+const restoreSiteInitPollInterval = 30 * time.Second
+
+// siteInitializing reports whether the site has not finished its first initialization
+func siteInitializing(site *vyogotechv1alpha1.FrappeSite) bool {
+	return site.Status.Phase == "" || site.Status.Phase == vyogotechv1alpha1.FrappeSitePhasePending ||
+		site.Status.Phase == vyogotechv1alpha1.FrappeSitePhaseProvisioning
+}
+
+// restoreDBRootSecretName is the Secret passing database root credentials to the restore job
+func restoreDBRootSecretName(siteRestore *vyogotechv1alpha1.SiteRestore) string {
+	return siteRestore.Name + "-db-root"
+}
+
+// ensureDBRootSecret copies the root credentials of the site's database server into a
+// Secret owned by the SiteRestore, since bench restore drops and recreates the site
+// database. It returns the Secret name, or "" for providers the operator holds no root
+// credentials for (bench then falls back to root_password in common_site_config.json).
+func (r *SiteRestoreReconciler) ensureDBRootSecret(ctx context.Context, siteRestore *vyogotechv1alpha1.SiteRestore, site *vyogotechv1alpha1.FrappeSite, bench *vyogotechv1alpha1.FrappeBench) (string, error) {
+	siteReconciler := &FrappeSiteReconciler{Client: r.Client, Scheme: r.Scheme, LookupTimeout: r.LookupTimeout}
+	if provider := siteReconciler.resolveDBConfig(site, bench).Provider; provider != "mariadb" && provider != "postgres" {
+		return "", nil
+	}
+	rootUser, rootPassword, dbKind, err := siteReconciler.getDBRootCredentials(ctx, site, bench)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s root credentials: %w", dbKind, err)
+	}
+	name := restoreDBRootSecretName(siteRestore)
+	data := map[string][]byte{
+		"db_root_user":     []byte(rootUser),
+		"db_root_password": []byte(rootPassword),
+	}
+
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, client.ObjectKey{Name: name, Namespace: siteRestore.Namespace}, secret)
+	if err == nil {
+		secret.Data = data
+		return name, r.Update(ctx, secret)
+	}
+	if !errors.IsNotFound(err) {
+		return "", err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: siteRestore.Namespace,
+			Labels: map[string]string{
+				"app":     "frappe",
+				"site":    siteRestore.Spec.Site,
+				"restore": "true",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	if err := controllerutil.SetControllerReference(siteRestore, secret, r.Scheme); err != nil {
+		return "", err
+	}
+	return name, r.Create(ctx, secret)
+}
+
+// dbRootEnv passes the root credentials in secretName to the restore script
+func dbRootEnv(secretName string) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, v := range []struct{ name, key string }{
+		{"DB_ROOT_USERNAME", "db_root_user"},
+		{"DB_ROOT_PASSWORD", "db_root_password"},
+	} {
+		env = append(env, corev1.EnvVar{
+			Name: v.name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  v.key,
+				},
+			},
+		})
+	}
+	return env
+}
+
+// redactedConfigValue replaces credentials in config backups taken with redactConfig
+const redactedConfigValue = "__redacted__"
+
+// liveConfigKeys are the site_config.json keys a config restore always takes from the live
+// site: they point at this deployment's database and redis, not the backup's origin
+var liveConfigKeys = []string{"db_name", "db_user", "db_password", "db_host", "db_port", "db_type", "host_name", "redis_cache", "redis_queue", "redis_socketio"}
+
+// liveConfigKeysPython renders liveConfigKeys as a Python list literal
+func liveConfigKeysPython() string {
+	return fmt.Sprintf(`["%s"]`, strings.Join(liveConfigKeys, `", "`))
+}
+
 func (r *SiteRestoreReconciler) buildRestoreScript(siteRestore *vyogotechv1alpha1.SiteRestore) string {
 	script := `#!/bin/bash
 set -e
@@ -119,7 +297,7 @@ mkdir -p /tmp/restore
 			script += fmt.Sprintf(`
 echo "Downloading %s from S3..."
 python3 << 'PYTHON_SCRIPT'
-import os, boto3, sys
+import os, boto3, sys, time
 bucket = os.getenv("%s_S3_BUCKET")
 region = os.getenv("%s_S3_REGION")
 endpoint = os.getenv("%s_S3_ENDPOINT")
@@ -133,10 +311,23 @@ s3 = boto3.client('s3',
     aws_access_key_id=access_key,
     aws_secret_access_key=secret_key)
 
+# Print progress markers (parsed by the operator) at most every PROGRESS_INTERVAL seconds
+size = s3.head_object(Bucket=bucket, Key=key)["ContentLength"]
+stage = "Downloading " + os.path.basename("%s")
+interval = float(os.getenv("PROGRESS_INTERVAL", "15"))
+state = {"done": 0, "last": 0.0}
+def report(chunk):
+    state["done"] += chunk
+    now = time.monotonic()
+    if now - state["last"] >= interval:
+        state["last"] = now
+        print(f"FRAPPE_PROGRESS {state['done']} {size} {stage}", flush=True)
+
 print(f"Downloading s3://{bucket}/{key} to %s...")
-s3.download_file(bucket, key, "%s")
+s3.download_file(bucket, key, "%s", Callback=report)
+print(f"FRAPPE_PROGRESS {size} {size} {stage}", flush=True)
 PYTHON_SCRIPT
-`, target, envPrefix, envPrefix, envPrefix, envPrefix, envPrefix, envPrefix, target, target)
+`, target, envPrefix, envPrefix, envPrefix, envPrefix, envPrefix, envPrefix, target, target, target)
 		} else if source.LocalPath != "" {
 			script += fmt.Sprintf(`
 echo "Using local backup path: %s"
@@ -168,8 +359,58 @@ cp "%s" "%s"
 		restoreCmd += " --force"
 	}
 
+	if siteRestore.Spec.ConfigBackupSource != nil {
+		configPath := "/tmp/restore/site_config.json"
+		s3Download(*siteRestore.Spec.ConfigBackupSource, configPath, "CONFIG")
+		script += fmt.Sprintf(`
+echo "Restoring site_config.json..."
+python3 - "%s" "sites/%s/site_config.json" << 'PYTHON_SCRIPT'
+import json, os, sys
+backup_path, live_path = sys.argv[1], sys.argv[2]
+with open(backup_path) as f:
+    config = json.load(f)
+with open(live_path) as f:
+    live = json.load(f)
+# Connection settings belong to this deployment, never to the backup
+for key in %s:
+    if key in live:
+        config[key] = live[key]
+    else:
+        config.pop(key, None)
+# Re-inject values redacted by SiteBackup's redactConfig from the backup's own Secret; the
+# live site's values belong to another site and would not decrypt the restored database
+redacted = {}
+if os.getenv("REDACTED_CONFIG_VALUES"):
+    from cryptography.fernet import Fernet
+    redacted = json.loads(Fernet(os.environ["REDACTED_CONFIG_KEY"].encode()).decrypt(os.environ["REDACTED_CONFIG_VALUES"].encode()))
+for key, value in list(config.items()):
+    if value != "%s":
+        continue
+    if key in redacted:
+        config[key] = redacted[key]
+    elif key == "encryption_key":
+        # Without the original key, encrypted Password fields in the database cannot be read
+        sys.exit("ERROR: encryption_key is redacted in the backup; set redactedConfigSecretRef to the SiteBackup's redacted config Secret")
+    else:
+        print(f"WARNING: {key} is redacted in the backup and not in redactedConfigSecretRef, dropping it")
+        del config[key]
+with open(live_path, "w") as f:
+    json.dump(config, f, indent=1)
+PYTHON_SCRIPT
+`, configPath, siteRestore.Spec.Site, liveConfigKeysPython(), redactedConfigValue)
+	}
+
+	// bench restore drops and recreates the site database, which needs the root user
+	restoreCmd += ` "${DB_ROOT_ARGS[@]}"`
+
 	script += fmt.Sprintf(`
 echo "Executing restore command..."
+DB_ROOT_ARGS=()
+if [ -n "$DB_ROOT_PASSWORD" ]; then
+  DB_ROOT_ARGS=(--db-root-username "$DB_ROOT_USERNAME" --db-root-password "$DB_ROOT_PASSWORD")
+fi
+# bench restore does not report progress; mark the stage with an unknown total
+echo "FRAPPE_PROGRESS 0 0 Importing"
 # Handle admin password if provided via env
 if [ ! -z "$ADMIN_PASSWORD" ]; then
   %s --admin-password "$ADMIN_PASSWORD"
@@ -220,6 +461,25 @@ func (r *SiteRestoreReconciler) buildRestoreJob(siteRestore *vyogotechv1alpha1.S
 	if siteRestore.Spec.PrivateFilesSource != nil {
 		addS3Env(*siteRestore.Spec.PrivateFilesSource, "PRIVATE")
 	}
+	if siteRestore.Spec.ConfigBackupSource != nil {
+		addS3Env(*siteRestore.Spec.ConfigBackupSource, "CONFIG")
+	}
+
+	if ref := siteRestore.Spec.RedactedConfigSecretRef; ref != nil && siteRestore.Spec.ConfigBackupSource != nil {
+		// Optional, so a backup that never reported its values fails in the script with a
+		// clear message instead of leaving the pod unable to start
+		for _, v := range []struct{ name, key string }{
+			{"REDACTED_CONFIG_KEY", redactedConfigKeyKey},
+			{"REDACTED_CONFIG_VALUES", redactedConfigValuesKey},
+		} {
+			env = append(env, corev1.EnvVar{
+				Name: v.name,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: *ref, Key: v.key, Optional: boolPtr(true)},
+				},
+			})
+		}
+	}
 
 	if siteRestore.Spec.AdminPasswordSecretRef != nil {
 		env = append(env, corev1.EnvVar{
@@ -242,8 +502,13 @@ func (r *SiteRestoreReconciler) buildRestoreJob(siteRestore *vyogotechv1alpha1.S
 		},
 		Spec: batchv1.JobSpec{
 			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: jobPodAnnotations(bench),
+				},
 				Spec: corev1.PodSpec{
-					RestartPolicy: corev1.RestartPolicyNever,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ImagePullSecrets:   imagePullSecrets(bench),
+					ServiceAccountName: jobServiceAccount(bench),
 					// Reusing logic from SiteBackup for now
 					SecurityContext: &corev1.PodSecurityContext{
 						RunAsNonRoot: boolPtr(true),
@@ -260,8 +525,8 @@ func (r *SiteRestoreReconciler) buildRestoreJob(siteRestore *vyogotechv1alpha1.S
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "sites",
-									MountPath: "/home/frappe/frappe-bench/sites",
-									SubPath:   "frappe-sites",
+									MountPath: sitesMountPath,
+									SubPath:   sitesVolumeSubPath,
 								},
 							},
 							Env: env,
@@ -290,11 +555,16 @@ func (r *SiteRestoreReconciler) buildRestoreJob(siteRestore *vyogotechv1alpha1.S
 		},
 	}
 
+	labelJob(job, jobLabels(jobOperationRestore, bench.Name, siteRestore.Spec.Site))
+	syncPodPlacement(&job.Spec.Template.Spec, benchJobPodConfig(bench))
 	controllerutil.SetControllerReference(siteRestore, job, r.Scheme)
 	return job
 }
 
 func (r *SiteRestoreReconciler) getBenchImage(bench *vyogotechv1alpha1.FrappeBench) string {
+	if image := channelImage(bench); image != "" {
+		return image
+	}
 	if bench.Spec.ImageConfig != nil && bench.Spec.ImageConfig.Repository != "" {
 		image := bench.Spec.ImageConfig.Repository
 		if bench.Spec.ImageConfig.Tag != "" {
@@ -318,6 +588,9 @@ func (r *SiteRestoreReconciler) updateStatus(ctx context.Context, siteRestore *v
 		now := metav1.Now()
 		latest.Status.CompletionTime = &now
 	}
+	if phase == "Succeeded" {
+		completeProgress(latest.Status.Progress)
+	}
 
 	return r.Status().Update(ctx, latest)
 }
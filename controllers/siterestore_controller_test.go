@@ -0,0 +1,266 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestSiteRestoreReconciler_configBackupSource(t *testing.T) {
+	r := &SiteRestoreReconciler{}
+	siteRestore := &vyogotechv1alpha1.SiteRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+		Spec: vyogotechv1alpha1.SiteRestoreSpec{
+			Site:                 "site.local",
+			BenchRef:             vyogotechv1alpha1.NamespacedName{Name: "bench"},
+			DatabaseBackupSource: vyogotechv1alpha1.BackupSource{LocalPath: "sites/site.local/private/backups/db.sql.gz"},
+			ConfigBackupSource:   &vyogotechv1alpha1.BackupSource{LocalPath: "sites/site.local/private/backups/site_config_backup.json"},
+		},
+	}
+
+	script := r.buildRestoreScript(siteRestore)
+	for _, want := range []string{`"sites/site.local/site_config.json"`, `"db_password"`, `if value != "__redacted__":`} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected restore script to contain %q", want)
+		}
+	}
+	// The live credentials must be in place before bench restore connects to the database
+	if strings.Index(script, "Restoring site_config.json") > strings.Index(script, "Executing restore command") {
+		t.Error("expected site_config.json to be restored before the database")
+	}
+
+	// Redacted values come from the SiteBackup's Secret, never from the live site
+	if strings.Contains(script, "live[key] != ") {
+		t.Error("expected redacted values not to be taken from the live site")
+	}
+	r.Scheme = newPausedTestScheme()
+	siteRestore.Spec.RedactedConfigSecretRef = &corev1.LocalObjectReference{Name: "backup-redacted-config"}
+	bench := &vyogotechv1alpha1.FrappeBench{ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"}}
+	env := map[string]*corev1.SecretKeySelector{}
+	for _, e := range r.buildRestoreJob(siteRestore, bench).Spec.Template.Spec.Containers[0].Env {
+		if e.ValueFrom != nil {
+			env[e.Name] = e.ValueFrom.SecretKeyRef
+		}
+	}
+	for name, key := range map[string]string{"REDACTED_CONFIG_KEY": "key", "REDACTED_CONFIG_VALUES": "values"} {
+		if ref := env[name]; ref == nil || ref.Name != "backup-redacted-config" || ref.Key != key {
+			t.Errorf("expected %s from key %q of the redacted config Secret, got %+v", name, key, ref)
+		}
+	}
+
+	siteRestore.Spec.ConfigBackupSource = nil
+	if strings.Contains(r.buildRestoreScript(siteRestore), "site_config.json") {
+		t.Error("expected site_config.json to be left alone without configBackupSource")
+	}
+}
+
+func TestSiteRestoreReconciler_volumeSnapshot(t *testing.T) {
+	newReconciler := func(withSnapshotCRD bool) (*SiteRestoreReconciler, *vyogotechv1alpha1.SiteRestore) {
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+		scheme.AddKnownTypeWithName(volumeSnapshotGVK, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(volumeSnapshotGVK.GroupVersion().WithKind("VolumeSnapshotList"), &unstructured.UnstructuredList{})
+
+		_, bench := newInitJobTestObjects()
+		siteRestore := &vyogotechv1alpha1.SiteRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+			Spec: vyogotechv1alpha1.SiteRestoreSpec{
+				Site:                 "site.local",
+				BenchRef:             vyogotechv1alpha1.NamespacedName{Name: "bench", Namespace: "default"},
+				DatabaseBackupSource: vyogotechv1alpha1.BackupSource{LocalPath: "sites/site.local/private/backups/db.sql.gz"},
+				VolumeSnapshot:       &vyogotechv1alpha1.VolumeSnapshotConfig{VolumeSnapshotClassName: "csi-snapclass"},
+			},
+		}
+		builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bench, siteRestore).WithStatusSubresource(siteRestore)
+		if !withSnapshotCRD {
+			builder = builder.WithInterceptorFuncs(interceptor.Funcs{
+				List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					if _, ok := list.(*vyogotechv1alpha1.FrappeSiteList); ok {
+						return c.List(ctx, list, opts...)
+					}
+					return &meta.NoKindMatchError{GroupKind: volumeSnapshotGVK.GroupKind(), SearchedVersions: []string{"v1"}}
+				},
+			})
+		}
+		c := builder.Build()
+		return &SiteRestoreReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(20)}, siteRestore
+	}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKey{Name: "restore", Namespace: "default"}}
+	jobKey := client.ObjectKey{Name: "restore-restore", Namespace: "default"}
+
+	t.Run("waits for the snapshot before restoring", func(t *testing.T) {
+		r, siteRestore := newReconciler(true)
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		if result.RequeueAfter != volumeSnapshotPollInterval {
+			t.Errorf("expected requeue after %s while the snapshot is cut, got %s", volumeSnapshotPollInterval, result.RequeueAfter)
+		}
+		if err := r.Get(ctx, jobKey, &batchv1.Job{}); err == nil {
+			t.Fatal("expected no restore job before the snapshot is cut")
+		}
+
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		if err := r.Get(ctx, client.ObjectKey{Name: "restore-pre-restore", Namespace: "default"}, snapshot); err != nil {
+			t.Fatalf("expected VolumeSnapshot: %v", err)
+		}
+		if pvc, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName"); pvc != "bench-sites" {
+			t.Errorf("expected snapshot of bench-sites, got %q", pvc)
+		}
+		if class, _, _ := unstructured.NestedString(snapshot.Object, "spec", "volumeSnapshotClassName"); class != "csi-snapclass" {
+			t.Errorf("expected VolumeSnapshotClass csi-snapclass, got %q", class)
+		}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(siteRestore), siteRestore); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if siteRestore.Status.VolumeSnapshot != "restore-pre-restore" {
+			t.Errorf("expected status.volumeSnapshot to be recorded, got %q", siteRestore.Status.VolumeSnapshot)
+		}
+
+		utilruntime.Must(unstructured.SetNestedField(snapshot.Object, "2024-01-01T00:00:00Z", "status", "creationTime"))
+		if err := r.Update(ctx, snapshot); err != nil {
+			t.Fatalf("Update snapshot: %v", err)
+		}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		if err := r.Get(ctx, jobKey, &batchv1.Job{}); err != nil {
+			t.Fatalf("expected restore job once the snapshot is cut: %v", err)
+		}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(siteRestore), siteRestore); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if siteRestore.Status.Phase != "Running" || siteRestore.Status.VolumeSnapshot != "restore-pre-restore" {
+			t.Errorf("expected Running with the snapshot kept in status, got %q / %q", siteRestore.Status.Phase, siteRestore.Status.VolumeSnapshot)
+		}
+	})
+
+	t.Run("fails without the VolumeSnapshot CRD", func(t *testing.T) {
+		r, siteRestore := newReconciler(false)
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(siteRestore), siteRestore); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if siteRestore.Status.Phase != "Failed" || !strings.Contains(siteRestore.Status.Message, "not installed") {
+			t.Errorf("expected Failed because the CRD is missing, got %q: %s", siteRestore.Status.Phase, siteRestore.Status.Message)
+		}
+		if err := r.Get(ctx, jobKey, &batchv1.Job{}); err == nil {
+			t.Error("expected no restore job without a snapshot")
+		}
+	})
+}
+
+func TestSiteRestoreReconciler_targetSite(t *testing.T) {
+	newReconciler := func(phase vyogotechv1alpha1.FrappeSitePhase) (*SiteRestoreReconciler, *vyogotechv1alpha1.SiteRestore) {
+		site, bench := newInitJobTestObjects()
+		site.Spec.DBConfig.Mode = "dedicated"
+		site.Status.Phase = phase
+		rootSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "site-mariadb-root", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("rootpw")},
+		}
+		siteRestore := &vyogotechv1alpha1.SiteRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+			Spec: vyogotechv1alpha1.SiteRestoreSpec{
+				Site:                 "site.local",
+				BenchRef:             vyogotechv1alpha1.NamespacedName{Name: "bench", Namespace: "default"},
+				DatabaseBackupSource: vyogotechv1alpha1.BackupSource{LocalPath: "sites/site.local/private/backups/db.sql.gz"},
+			},
+		}
+		siteReconciler, _ := newInitJobTestReconciler()
+		c := fake.NewClientBuilder().WithScheme(siteReconciler.Scheme).
+			WithObjects(site, bench, rootSecret, siteRestore).WithStatusSubresource(site, siteRestore).Build()
+		return &SiteRestoreReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}, siteRestore
+	}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKey{Name: "restore", Namespace: "default"}}
+	jobKey := client.ObjectKey{Name: "restore-restore", Namespace: "default"}
+
+	t.Run("waits for an initializing site", func(t *testing.T) {
+		r, siteRestore := newReconciler(vyogotechv1alpha1.FrappeSitePhaseProvisioning)
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		if result.RequeueAfter != restoreSiteInitPollInterval {
+			t.Errorf("expected requeue after %s, got %s", restoreSiteInitPollInterval, result.RequeueAfter)
+		}
+		if err := r.Get(ctx, jobKey, &batchv1.Job{}); err == nil {
+			t.Fatal("expected no restore job while the site is initializing")
+		}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(siteRestore), siteRestore); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if siteRestore.Status.Phase != "Pending" || !strings.Contains(siteRestore.Status.Message, "initializing") {
+			t.Errorf("expected Pending while the site initializes, got %q: %s", siteRestore.Status.Phase, siteRestore.Status.Message)
+		}
+	})
+
+	t.Run("passes database root credentials to the job", func(t *testing.T) {
+		r, _ := newReconciler(vyogotechv1alpha1.FrappeSitePhaseReady)
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Name: "restore-db-root", Namespace: "default"}, secret); err != nil {
+			t.Fatalf("expected root credentials Secret: %v", err)
+		}
+		if string(secret.Data["db_root_user"]) != "root" || string(secret.Data["db_root_password"]) != "rootpw" {
+			t.Errorf("unexpected root credentials %v", secret.Data)
+		}
+		job := &batchv1.Job{}
+		if err := r.Get(ctx, jobKey, job); err != nil {
+			t.Fatalf("expected restore job: %v", err)
+		}
+		container := job.Spec.Template.Spec.Containers[0]
+		var fromSecret bool
+		for _, env := range container.Env {
+			if env.Name == "DB_ROOT_PASSWORD" && env.ValueFrom != nil && env.ValueFrom.SecretKeyRef.Name == "restore-db-root" {
+				fromSecret = true
+			}
+		}
+		if !fromSecret {
+			t.Errorf("expected DB_ROOT_PASSWORD from restore-db-root, got %+v", container.Env)
+		}
+		if !strings.Contains(container.Args[0], `"${DB_ROOT_ARGS[@]}"`) || strings.Contains(container.Args[0], "rootpw") {
+			t.Error("expected the restore command to take the root credentials from the environment")
+		}
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ensureVolumeSnapshot snapshots the bench's sites PVC before the restore job overwrites
+// it. It reports whether the snapshot has been cut, or a failure message when the restore
+// can't go ahead. The snapshot isn't owned by the SiteRestore so it outlives it.
+func (r *SiteRestoreReconciler) ensureVolumeSnapshot(ctx context.Context, siteRestore *vyogotechv1alpha1.SiteRestore, bench *vyogotechv1alpha1.FrappeBench) (bool, string, error) {
+	available, err := isVolumeSnapshotAvailable(ctx, r.Client, r.LookupTimeout)
+	if err != nil {
+		return false, "", err
+	}
+	if !available {
+		return false, "volumeSnapshot is set but " + volumeSnapshotCRDMissing, nil
+	}
+
+	key := client.ObjectKey{Name: siteRestore.Name + "-pre-restore", Namespace: bench.Namespace}
+	snapshot, created, err := ensureSitesVolumeSnapshot(ctx, r.Client, bench, key, siteRestore.Spec.VolumeSnapshot, map[string]string{
+		"app":         "frappe",
+		"bench":       bench.Name,
+		"site":        siteRestore.Spec.Site,
+		"siterestore": siteRestore.Name,
+	})
+	if err != nil {
+		return false, "", err
+	}
+	if created {
+		log.FromContext(ctx).Info("Created pre-restore VolumeSnapshot", "volumeSnapshot", key.Name)
+		r.Recorder.Event(siteRestore, "Normal", "VolumeSnapshotCreated", fmt.Sprintf("Created VolumeSnapshot %s of PVC %s-sites", key.Name, bench.Name))
+		return false, "", r.recordVolumeSnapshot(ctx, siteRestore, key.Name)
+	}
+
+	cut, failure := volumeSnapshotCut(snapshot)
+	return cut, failure, nil
+}
+
+// recordVolumeSnapshot records the pre-restore snapshot in status while it is being cut
+func (r *SiteRestoreReconciler) recordVolumeSnapshot(ctx context.Context, siteRestore *vyogotechv1alpha1.SiteRestore, name string) error {
+	latest := &vyogotechv1alpha1.SiteRestore{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(siteRestore), latest); err != nil {
+		return err
+	}
+	latest.Status.Phase = "Pending"
+	latest.Status.Message = fmt.Sprintf("Waiting for VolumeSnapshot %s", name)
+	latest.Status.VolumeSnapshot = name
+	return r.Status().Update(ctx, latest)
+}
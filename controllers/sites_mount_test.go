@@ -0,0 +1,103 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/controllers/database"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// assertSitesMount checks that a pod spec mounts the sites PVC at the shared path and subPath
+func assertSitesMount(t *testing.T, what string, spec corev1.PodSpec) {
+	t.Helper()
+	found := false
+	for _, c := range spec.Containers {
+		for _, m := range c.VolumeMounts {
+			if m.Name != "sites" {
+				continue
+			}
+			found = true
+			if m.MountPath != sitesMountPath || m.SubPath != sitesVolumeSubPath {
+				t.Errorf("%s: container %s mounts sites at %q (subPath %q), want %q (subPath %q)",
+					what, c.Name, m.MountPath, m.SubPath, sitesMountPath, sitesVolumeSubPath)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("%s: no sites volume mount found", what)
+	}
+}
+
+func TestSitesMountConsistentAcrossWorkloads(t *testing.T) {
+	site, bench := newInitJobTestObjects()
+	siteReconciler, c := newInitJobTestReconciler(site, bench)
+	benchReconciler := &FrappeBenchReconciler{Client: c, Scheme: siteReconciler.Scheme, Recorder: record.NewFakeRecorder(20)}
+	ctx := context.Background()
+
+	if _, err := benchReconciler.ensureBenchInitialized(ctx, bench, false, nil, 0); err != nil {
+		t.Fatalf("ensureBenchInitialized: %v", err)
+	}
+	benchInit := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-init", Namespace: "default"}, benchInit); err != nil {
+		t.Fatalf("Get bench-init Job: %v", err)
+	}
+	assertSitesMount(t, "bench-init job", benchInit.Spec.Template.Spec)
+
+	if err := benchReconciler.ensureGunicornDeployment(ctx, bench); err != nil {
+		t.Fatalf("ensureGunicornDeployment: %v", err)
+	}
+	gunicorn := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "bench-gunicorn", Namespace: "default"}, gunicorn); err != nil {
+		t.Fatalf("Get gunicorn Deployment: %v", err)
+	}
+	assertSitesMount(t, "gunicorn deployment", gunicorn.Spec.Template.Spec)
+
+	dbInfo := &database.DatabaseInfo{Provider: "mariadb", Name: "db"}
+	dbCreds := &database.DatabaseCredentials{Username: "user", Password: "pass"}
+	if _, err := siteReconciler.ensureSiteInitialized(ctx, site, bench, "site.local", dbInfo, dbCreds); err != nil {
+		t.Fatalf("ensureSiteInitialized: %v", err)
+	}
+	siteInit := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "site-init", Namespace: "default"}, siteInit); err != nil {
+		t.Fatalf("Get site-init Job: %v", err)
+	}
+	assertSitesMount(t, "site-init job", siteInit.Spec.Template.Spec)
+
+	backupReconciler := &SiteBackupReconciler{Scheme: siteReconciler.Scheme}
+	siteBackup := &vyogotechv1alpha1.SiteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteBackupSpec{Site: "site.local", Schedule: "0 2 * * *"},
+	}
+	assertSitesMount(t, "backup job", backupReconciler.buildBackupJob(siteBackup, bench).Spec.Template.Spec)
+	assertSitesMount(t, "backup cronjob", backupReconciler.buildBackupCronJob(siteBackup, bench).Spec.JobTemplate.Spec.Template.Spec)
+
+	restoreReconciler := &SiteRestoreReconciler{Scheme: siteReconciler.Scheme}
+	siteRestore := &vyogotechv1alpha1.SiteRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+		Spec:       vyogotechv1alpha1.SiteRestoreSpec{Site: "site.local"},
+	}
+	assertSitesMount(t, "restore job", restoreReconciler.buildRestoreJob(siteRestore, bench).Spec.Template.Spec)
+}
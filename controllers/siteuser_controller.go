@@ -18,16 +18,34 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+	"github.com/vyogotech/frappe-operator/pkg/scripts"
 )
 
+// userSecretsMountPath is where the user job reads the password, in file "password"
+const userSecretsMountPath = "/tmp/user-secrets"
+
 // SiteUserReconciler reconciles a SiteUser object
 type SiteUserReconciler struct {
 	client.Client
@@ -38,27 +56,293 @@ type SiteUserReconciler struct {
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siteusers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siteusers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siteusers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=vyogo.tech,resources=frappesites,verbs=get;list;watch
+//+kubebuilder:rbac:groups=vyogo.tech,resources=frappebenches,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the SiteUser object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
+// Reconcile brings the site's user in line with the spec in a Job: it creates a missing
+// user, enables or disables it and sets its password from spec.passwordSecretRef. The
+// desired state, including the password Secret's resourceVersion, is hashed into
+// status.hash, so the job runs again only when the spec or the Secret changes, whether
+// the last attempt succeeded or failed.
 func (r *SiteUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+
+	user := &vyogotechv1alpha1.SiteUser{}
+	if err := r.Get(ctx, req.NamespacedName, user); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if err := validateSiteUser(user); err != nil {
+		if user.Status.Phase == "Failed" && user.Status.Message == err.Error() {
+			return ctrl.Result{}, nil
+		}
+		r.Recorder.Event(user, corev1.EventTypeWarning, "InvalidSiteUser", err.Error())
+		return ctrl.Result{}, r.updateUserStatus(ctx, user, func(status *vyogotechv1alpha1.SiteUserStatus) {
+			status.Phase = "Failed"
+			status.Hash = ""
+			status.Message = err.Error()
+		})
+	}
+
+	// The password is set again whenever its Secret changes
+	passwordVersion := ""
+	if ref := user.Spec.PasswordSecretRef; ref != nil {
+		missingSecret, err := findMissingSecret(ctx, r.Client, []secretRef{{
+			field:     "spec.passwordSecretRef",
+			namespace: user.Namespace,
+			name:      ref.Name,
+			keys:      []string{ref.Key},
+		}})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.recordMissingSecret(ctx, user, missingSecret); err != nil {
+			return ctrl.Result{}, err
+		}
+		if missingSecret != "" {
+			logger.Info("Referenced Secret missing, holding user", "reason", missingSecret)
+			return ctrl.Result{RequeueAfter: missingSecretRequeue}, nil
+		}
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: user.Namespace}, secret); err != nil {
+			return ctrl.Result{}, err
+		}
+		passwordVersion = secret.ResourceVersion
+	}
+
+	enabled := siteUserEnabled(user)
+	state, err := json.Marshal([]string{user.Spec.Email, user.Spec.FirstName, user.Spec.LastName, strconv.FormatBool(enabled), passwordVersion})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(state))[:16]
+	if user.Status.Hash == hash && (user.Status.Phase == "Applied" || user.Status.Phase == "Failed") {
+		return ctrl.Result{}, nil
+	}
+
+	jobName := fmt.Sprintf("%s-apply-%s", user.Name, hash[:8])
+	job := &batchv1.Job{}
+	err = r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: user.Namespace}, job)
+	if errors.IsNotFound(err) {
+		return r.startUserJob(ctx, user, hash, jobName)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if job.Status.Succeeded > 0 {
+		logger.Info("Site user applied", "user", user.Spec.Email, "site", user.Spec.Site, "enabled", enabled)
+		r.Recorder.Event(user, corev1.EventTypeNormal, "UserApplied", siteUserAppliedMessage(user, enabled, passwordVersion))
+		return ctrl.Result{}, r.updateUserStatus(ctx, user, func(status *vyogotechv1alpha1.SiteUserStatus) {
+			status.Phase = "Applied"
+			status.Hash = hash
+			status.JobName = job.Name
+			status.Message = "User matches the spec"
+			status.Enabled = &enabled
+			status.PasswordSecretVersion = passwordVersion
+			now := metav1.Now()
+			status.LastAppliedTime = &now
+		})
+	}
+	if jobFailed(job) {
+		message := fmt.Sprintf("Job %s failed to update user %s", job.Name, user.Spec.Email)
+		r.Recorder.Event(user, corev1.EventTypeWarning, "UserApplyFailed", message)
+		return ctrl.Result{}, r.updateUserStatus(ctx, user, func(status *vyogotechv1alpha1.SiteUserStatus) {
+			status.Phase = "Failed"
+			status.Hash = hash
+			status.JobName = job.Name
+			status.Message = message
+		})
+	}
+
+	if user.Status.Phase == "Applying" && user.Status.JobName == job.Name {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, r.updateUserStatus(ctx, user, func(status *vyogotechv1alpha1.SiteUserStatus) {
+		status.Phase = "Applying"
+		status.Hash = hash
+		status.JobName = job.Name
+		status.Message = fmt.Sprintf("Updating user %s", user.Spec.Email)
+	})
+}
+
+// startUserJob starts the Job updating the user once the site is Ready. A site that
+// doesn't exist or isn't Ready yet is waited for.
+func (r *SiteUserReconciler) startUserJob(ctx context.Context, user *vyogotechv1alpha1.SiteUser, hash, jobName string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	site, bench, err := findReadySiteBench(ctx, r.Client, user.Namespace, user.Spec.Site)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if bench == nil {
+		message := fmt.Sprintf("Waiting for FrappeSite %s to be Ready", user.Spec.Site)
+		if user.Status.Phase == "Pending" && user.Status.Message == message {
+			return ctrl.Result{RequeueAfter: missingSecretRequeue}, nil
+		}
+		return ctrl.Result{RequeueAfter: missingSecretRequeue}, r.updateUserStatus(ctx, user, func(status *vyogotechv1alpha1.SiteUserStatus) {
+			status.Phase = "Pending"
+			status.Message = message
+		})
+	}
+
+	userScript, err := scripts.RenderScript(scripts.SiteUser, scripts.SiteUserData{SiteName: user.Spec.Site})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to render site user script: %w", err)
+	}
+
+	enabled := "0"
+	if siteUserEnabled(user) {
+		enabled = "1"
+	}
+	// The site controller's helpers pick the bench image and security contexts
+	siteReconciler := &FrappeSiteReconciler{Client: r.Client, Scheme: r.Scheme}
+	containerBuilder := resources.NewContainerBuilder("site-user", siteReconciler.getBenchImage(ctx, bench)).
+		WithCommand("bash", "-c").
+		WithArgs(userScript).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithSecurityContext(siteReconciler.getContainerSecurityContext(ctx, bench)).
+		WithEnv("USER", "frappe").
+		WithEnv("USER_EMAIL", user.Spec.Email).
+		WithEnv("USER_FIRST_NAME", user.Spec.FirstName).
+		WithEnv("USER_LAST_NAME", user.Spec.LastName).
+		WithEnv("USER_ENABLED", enabled)
+	if user.Spec.PasswordSecretRef != nil {
+		// Mounted as a file, like the site init secrets, so the password stays out of the
+		// pod's environment
+		containerBuilder = containerBuilder.WithVolumeMountReadOnly("user-secrets", userSecretsMountPath)
+	}
 
-	// TODO(user): your logic here
+	jobBuilder := resources.NewJobBuilder(jobName, user.Namespace).
+		WithLabels(map[string]string{"app": "frappe", "site": site.Name}).
+		WithLabels(jobLabels(jobOperationUser, bench.Name, user.Spec.Site)).
+		WithBackoffLimit(0).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(siteReconciler.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(containerBuilder.Build()).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithOwner(user, r.Scheme)
+	if ref := user.Spec.PasswordSecretRef; ref != nil {
+		jobBuilder = jobBuilder.WithVolume(corev1.Volume{
+			Name: "user-secrets",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  ref.Name,
+					Items:       []corev1.KeyToPath{{Key: ref.Key, Path: "password"}},
+					DefaultMode: resources.Int32Ptr(0444),
+				},
+			},
+		})
+	}
+	job, err := jobBuilder.Build()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	applyDefaultJobTTL(&job.Spec)
+	if err := r.Create(ctx, job); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	return ctrl.Result{}, nil
+	logger.Info("Created site user job", "job", jobName, "user", user.Spec.Email)
+	r.Recorder.Event(user, corev1.EventTypeNormal, "UserApplying",
+		fmt.Sprintf("Updating user %s on %s", user.Spec.Email, user.Spec.Site))
+	return ctrl.Result{}, r.updateUserStatus(ctx, user, func(status *vyogotechv1alpha1.SiteUserStatus) {
+		status.Phase = "Applying"
+		status.Hash = hash
+		status.JobName = jobName
+		status.Message = fmt.Sprintf("Updating user %s", user.Spec.Email)
+	})
+}
+
+// updateUserStatus applies update to the latest version of the SiteUser's status
+func (r *SiteUserReconciler) updateUserStatus(ctx context.Context, user *vyogotechv1alpha1.SiteUser, update func(*vyogotechv1alpha1.SiteUserStatus)) error {
+	latest := &vyogotechv1alpha1.SiteUser{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(user), latest); err != nil {
+		return err
+	}
+	update(&latest.Status)
+	return r.Status().Update(ctx, latest)
+}
+
+// recordMissingSecret records the MissingSecret condition and, while the password Secret
+// is missing, holds the user in Pending with the Secret named in the message
+func (r *SiteUserReconciler) recordMissingSecret(ctx context.Context, user *vyogotechv1alpha1.SiteUser, missingSecret string) error {
+	latest := &vyogotechv1alpha1.SiteUser{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(user), latest); err != nil {
+		return err
+	}
+	changed := setMissingSecretCondition(&latest.Status.Conditions, missingSecret, latest.Generation)
+	if missingSecret != "" && (latest.Status.Phase != "Pending" || latest.Status.Message != missingSecret) {
+		latest.Status.Phase = "Pending"
+		latest.Status.Message = missingSecret
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if missingSecret != "" {
+		r.Recorder.Event(user, corev1.EventTypeWarning, "MissingSecret", missingSecret)
+	}
+	return r.Status().Update(ctx, latest)
+}
+
+// usersForPasswordSecret maps a Secret to the SiteUsers whose password it holds
+func (r *SiteUserReconciler) usersForPasswordSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	users := &vyogotechv1alpha1.SiteUserList{}
+	if err := r.List(ctx, users, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, user := range users.Items {
+		if ref := user.Spec.PasswordSecretRef; ref != nil && ref.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: user.Name, Namespace: user.Namespace}})
+		}
+	}
+	return requests
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SiteUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vyogotechv1alpha1.SiteUser{}).
+		Owns(&batchv1.Job{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.usersForPasswordSecret)).
 		Complete(r)
 }
+
+// siteUserEnabled returns spec.enabled, which defaults to true
+func siteUserEnabled(user *vyogotechv1alpha1.SiteUser) bool {
+	return user.Spec.Enabled == nil || *user.Spec.Enabled
+}
+
+// validateSiteUser checks the fields the user job relies on
+func validateSiteUser(user *vyogotechv1alpha1.SiteUser) error {
+	if errs := validation.IsDNS1123Subdomain(user.Spec.Site); len(errs) > 0 {
+		return fmt.Errorf("site %q is not a valid site name: %s", user.Spec.Site, errs[0])
+	}
+	local, domain, ok := strings.Cut(user.Spec.Email, "@")
+	if !ok || local == "" || domain == "" || strings.ContainsAny(user.Spec.Email, " \t\n") {
+		return fmt.Errorf("email %q is not a valid email address", user.Spec.Email)
+	}
+	if ref := user.Spec.PasswordSecretRef; ref != nil && (ref.Name == "" || ref.Key == "") {
+		return fmt.Errorf("passwordSecretRef needs a name and a key")
+	}
+	return nil
+}
+
+// siteUserAppliedMessage describes what an applied user job changed, without the password
+func siteUserAppliedMessage(user *vyogotechv1alpha1.SiteUser, enabled bool, passwordVersion string) string {
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	message := fmt.Sprintf("User %s on %s is %s", user.Spec.Email, user.Spec.Site, state)
+	if passwordVersion != "" && passwordVersion != user.Status.PasswordSecretVersion {
+		message += fmt.Sprintf(", password set from Secret %s", user.Spec.PasswordSecretRef.Name)
+	}
+	return message
+}
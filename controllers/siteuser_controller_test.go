@@ -0,0 +1,217 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newSiteUserTestReconciler(objs ...client.Object) (*SiteUserReconciler, client.Client, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&vyogotechv1alpha1.SiteUser{}).Build()
+	recorder := record.NewFakeRecorder(10)
+	return &SiteUserReconciler{Client: c, Scheme: scheme, Recorder: recorder}, c, recorder
+}
+
+func newSiteUserTestObjects() (*vyogotechv1alpha1.SiteUser, *corev1.Secret, *vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench) {
+	site, bench := newInitJobTestObjects()
+	site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseReady
+	user := &vyogotechv1alpha1.SiteUser{
+		ObjectMeta: metav1.ObjectMeta{Name: "jane", Namespace: "default", UID: "user-uid"},
+		Spec: vyogotechv1alpha1.SiteUserSpec{
+			Site:      "site.local",
+			Email:     "jane@example.com",
+			FirstName: "Jane",
+			PasswordSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "jane-password"},
+				Key:                  "password",
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "jane-password", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("s3cret-pass")},
+	}
+	return user, secret, site, bench
+}
+
+func reconcileSiteUser(t *testing.T, r *SiteUserReconciler, c client.Client) *vyogotechv1alpha1.SiteUser {
+	t.Helper()
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "jane", Namespace: "default"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteUser{}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteUser: %v", err)
+	}
+	return updated
+}
+
+func succeedSiteUserJob(t *testing.T, c client.Client, name string) *batchv1.Job {
+	t.Helper()
+	job := &batchv1.Job{}
+	if err := c.Get(context.Background(), types.NamespacedName{Name: name, Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(context.Background(), job); err != nil {
+		t.Fatalf("Update Job: %v", err)
+	}
+	return job
+}
+
+func TestSiteUserReconcile(t *testing.T) {
+	user, secret, site, bench := newSiteUserTestObjects()
+	r, c, recorder := newSiteUserTestReconciler(user, secret, site, bench)
+
+	updated := reconcileSiteUser(t, r, c)
+	if updated.Status.Phase != "Applying" || updated.Status.Hash == "" {
+		t.Fatalf("expected Applying with a hash, got %+v", updated.Status)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(context.Background(), types.NamespacedName{Name: updated.Status.JobName, Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationUser {
+		t.Errorf("expected operation label %q, got %q", jobOperationUser, job.Labels[jobOperationLabel])
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if jobEnv(job, "USER_EMAIL") != "jane@example.com" || jobEnv(job, "USER_ENABLED") != "1" {
+		t.Errorf("unexpected user env: %+v", container.Env)
+	}
+	// The password only reaches the job as a mounted file
+	for _, env := range container.Env {
+		if env.ValueFrom != nil || strings.Contains(env.Value, "s3cret-pass") {
+			t.Errorf("expected no password in the environment, got %+v", env)
+		}
+	}
+	var volume *corev1.Volume
+	for i := range job.Spec.Template.Spec.Volumes {
+		if job.Spec.Template.Spec.Volumes[i].Name == "user-secrets" {
+			volume = &job.Spec.Template.Spec.Volumes[i]
+		}
+	}
+	if volume == nil || volume.Secret == nil || volume.Secret.SecretName != "jane-password" ||
+		len(volume.Secret.Items) != 1 || volume.Secret.Items[0].Path != "password" {
+		t.Fatalf("expected the password Secret key mounted as file password, got %+v", volume)
+	}
+	<-recorder.Events
+
+	succeedSiteUserJob(t, c, updated.Status.JobName)
+	updated = reconcileSiteUser(t, r, c)
+	if updated.Status.Phase != "Applied" || updated.Status.Enabled == nil || !*updated.Status.Enabled ||
+		updated.Status.PasswordSecretVersion == "" || updated.Status.LastAppliedTime == nil {
+		t.Errorf("expected Applied with the applied state, got %+v", updated.Status)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "password set from Secret jane-password") || strings.Contains(event, "s3cret-pass") {
+		t.Errorf("expected a UserApplied event naming the Secret, got %q", event)
+	}
+
+	// An unchanged user isn't updated again
+	applied := updated.Status.JobName
+	if updated = reconcileSiteUser(t, r, c); updated.Status.JobName != applied {
+		t.Errorf("expected no new job for an applied user, got %s", updated.Status.JobName)
+	}
+
+	// Rotating the password Secret sets the password again
+	secret.Data["password"] = []byte("rotated-pass")
+	if err := c.Update(context.Background(), secret); err != nil {
+		t.Fatalf("Update Secret: %v", err)
+	}
+	if updated = reconcileSiteUser(t, r, c); updated.Status.JobName == applied || updated.Status.Phase != "Applying" {
+		t.Fatalf("expected a new job after the Secret rotated, got %+v", updated.Status)
+	}
+
+	// Disabling the user runs another job with USER_ENABLED=0
+	succeedSiteUserJob(t, c, updated.Status.JobName)
+	updated = reconcileSiteUser(t, r, c)
+	<-recorder.Events
+	<-recorder.Events
+	disabled := false
+	updated.Spec.Enabled = &disabled
+	if err := c.Update(context.Background(), updated); err != nil {
+		t.Fatalf("Update SiteUser: %v", err)
+	}
+	updated = reconcileSiteUser(t, r, c)
+	job = succeedSiteUserJob(t, c, updated.Status.JobName)
+	if jobEnv(job, "USER_ENABLED") != "0" {
+		t.Errorf("expected USER_ENABLED=0 for a disabled user, got %q", jobEnv(job, "USER_ENABLED"))
+	}
+	if updated = reconcileSiteUser(t, r, c); updated.Status.Enabled == nil || *updated.Status.Enabled {
+		t.Errorf("expected status.enabled false, got %+v", updated.Status)
+	}
+}
+
+func TestSiteUserMissingPasswordSecret(t *testing.T) {
+	user, _, site, bench := newSiteUserTestObjects()
+	r, c, recorder := newSiteUserTestReconciler(user, site, bench)
+
+	updated := reconcileSiteUser(t, r, c)
+	if updated.Status.Phase != "Pending" || !meta.IsStatusConditionTrue(updated.Status.Conditions, missingSecretCondition) {
+		t.Errorf("expected Pending with MissingSecret, got %+v", updated.Status)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "Warning MissingSecret") {
+		t.Errorf("expected MissingSecret event, got %q", event)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(context.Background(), jobs); err != nil {
+		t.Fatalf("List Jobs: %v", err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("expected no job without the password Secret, got %d", len(jobs.Items))
+	}
+}
+
+func TestValidateSiteUser(t *testing.T) {
+	user, _, _, _ := newSiteUserTestObjects()
+	if err := validateSiteUser(user); err != nil {
+		t.Errorf("expected a valid user, got %v", err)
+	}
+	for _, email := range []string{"jane", "@example.com", "jane@", "jane doe@example.com"} {
+		invalid := user.DeepCopy()
+		invalid.Spec.Email = email
+		if err := validateSiteUser(invalid); err == nil {
+			t.Errorf("expected email %q to be rejected", email)
+		}
+	}
+	invalid := user.DeepCopy()
+	invalid.Spec.PasswordSecretRef.Key = ""
+	if err := validateSiteUser(invalid); err == nil {
+		t.Error("expected a passwordSecretRef without a key to be rejected")
+	}
+}
@@ -18,14 +18,32 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"github.com/vyogotech/frappe-operator/pkg/resources"
+)
+
+const (
+	// workspaceDocumentKey is the ConfigMap key, and the file name, of the Workspace
+	// document; bench import-doc only loads .json files
+	workspaceDocumentKey = "workspace.json"
+	// workspaceMountPath is where the apply job reads the Workspace document
+	workspaceMountPath = "/tmp/workspace"
 )
 
 // SiteWorkspaceReconciler reconciles a SiteWorkspace object
@@ -38,27 +56,355 @@ type SiteWorkspaceReconciler struct {
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siteworkspaces,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siteworkspaces/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=vyogo.tech,resources=siteworkspaces/finalizers,verbs=update
+//+kubebuilder:rbac:groups=vyogo.tech,resources=frappesites,verbs=get;list;watch
+//+kubebuilder:rbac:groups=vyogo.tech,resources=frappebenches,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the SiteWorkspace object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
+// Reconcile writes the Workspace document described by the spec to a ConfigMap and runs
+// `bench --site <site> import-doc` on it in a Job, which replaces the Workspace of that
+// name. The document's hash is kept in status.hash, so an unchanged spec is not applied
+// again, whether the last attempt succeeded or failed.
 func (r *SiteWorkspaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+
+	workspace := &vyogotechv1alpha1.SiteWorkspace{}
+	if err := r.Get(ctx, req.NamespacedName, workspace); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	document, err := workspaceDocument(workspace)
+	if err != nil {
+		if workspace.Status.Phase == "Failed" && workspace.Status.Message == err.Error() {
+			return ctrl.Result{}, nil
+		}
+		r.Recorder.Event(workspace, corev1.EventTypeWarning, "InvalidWorkspace", err.Error())
+		return ctrl.Result{}, r.updateWorkspaceStatus(ctx, workspace, func(status *vyogotechv1alpha1.SiteWorkspaceStatus) {
+			status.Phase = "Failed"
+			status.Hash = ""
+			status.Message = err.Error()
+		})
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(document))[:16]
+	if workspace.Status.Hash == hash && (workspace.Status.Phase == "Applied" || workspace.Status.Phase == "Failed") {
+		return ctrl.Result{}, nil
+	}
+
+	jobName := fmt.Sprintf("%s-apply-%s", workspace.Name, hash[:8])
+	job := &batchv1.Job{}
+	err = r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: workspace.Namespace}, job)
+	if errors.IsNotFound(err) {
+		return r.startWorkspaceJob(ctx, workspace, document, hash, jobName)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if job.Status.Succeeded > 0 {
+		logger.Info("Workspace applied", "workspace", workspace.Spec.Label, "site", workspace.Spec.Site)
+		r.Recorder.Event(workspace, corev1.EventTypeNormal, "WorkspaceApplied",
+			fmt.Sprintf("Applied workspace %s to %s", workspace.Spec.Label, workspace.Spec.Site))
+		return ctrl.Result{}, r.updateWorkspaceStatus(ctx, workspace, func(status *vyogotechv1alpha1.SiteWorkspaceStatus) {
+			status.Phase = "Applied"
+			status.Hash = hash
+			status.JobName = job.Name
+			status.Message = "Workspace applied"
+			now := metav1.Now()
+			status.LastAppliedTime = &now
+		})
+	}
+	if jobFailed(job) {
+		message := fmt.Sprintf("Job %s failed to apply workspace %s", job.Name, workspace.Spec.Label)
+		r.Recorder.Event(workspace, corev1.EventTypeWarning, "WorkspaceApplyFailed", message)
+		return ctrl.Result{}, r.updateWorkspaceStatus(ctx, workspace, func(status *vyogotechv1alpha1.SiteWorkspaceStatus) {
+			status.Phase = "Failed"
+			status.Hash = hash
+			status.JobName = job.Name
+			status.Message = message
+		})
+	}
+
+	if workspace.Status.Phase == "Applying" && workspace.Status.JobName == job.Name {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, r.updateWorkspaceStatus(ctx, workspace, func(status *vyogotechv1alpha1.SiteWorkspaceStatus) {
+		status.Phase = "Applying"
+		status.Hash = hash
+		status.JobName = job.Name
+		status.Message = fmt.Sprintf("Applying workspace %s", workspace.Spec.Label)
+	})
+}
+
+// startWorkspaceJob stores the document and starts the import-doc Job once the site is
+// Ready. A site that doesn't exist or isn't Ready yet is waited for.
+func (r *SiteWorkspaceReconciler) startWorkspaceJob(ctx context.Context, workspace *vyogotechv1alpha1.SiteWorkspace, document []byte, hash, jobName string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	site, err := findSiteByName(ctx, r.Client, workspace.Namespace, workspace.Spec.Site)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if site == nil || site.Spec.BenchRef == nil || site.Status.Phase != vyogotechv1alpha1.FrappeSitePhaseReady {
+		message := fmt.Sprintf("Waiting for FrappeSite %s to be Ready", workspace.Spec.Site)
+		if workspace.Status.Phase == "Pending" && workspace.Status.Message == message {
+			return ctrl.Result{RequeueAfter: missingSecretRequeue}, nil
+		}
+		return ctrl.Result{RequeueAfter: missingSecretRequeue}, r.updateWorkspaceStatus(ctx, workspace, func(status *vyogotechv1alpha1.SiteWorkspaceStatus) {
+			status.Phase = "Pending"
+			status.Message = message
+		})
+	}
+	benchNamespace := site.Spec.BenchRef.Namespace
+	if benchNamespace == "" {
+		benchNamespace = site.Namespace
+	}
+	bench := &vyogotechv1alpha1.FrappeBench{}
+	if err := r.Get(ctx, client.ObjectKey{Name: site.Spec.BenchRef.Name, Namespace: benchNamespace}, bench); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	configMapName := fmt.Sprintf("%s-workspace", workspace.Name)
+	if err := r.ensureWorkspaceConfigMap(ctx, workspace, configMapName, document); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The site controller's helpers pick the bench image and security contexts
+	siteReconciler := &FrappeSiteReconciler{Client: r.Client, Scheme: r.Scheme}
+	container := resources.NewContainerBuilder("import-doc", siteReconciler.getBenchImage(ctx, bench)).
+		WithCommand("bench").
+		WithArgs("--site", workspace.Spec.Site, "import-doc", workspaceMountPath+"/"+workspaceDocumentKey).
+		WithWorkingDir(benchDir).
+		WithVolumeMountSubPath("sites", sitesMountPath, sitesVolumeSubPath).
+		WithVolumeMountReadOnly("workspace", workspaceMountPath).
+		WithSecurityContext(siteReconciler.getContainerSecurityContext(ctx, bench)).
+		WithEnv("USER", "frappe").
+		Build()
+
+	job, err := resources.NewJobBuilder(jobName, workspace.Namespace).
+		WithLabels(map[string]string{"app": "frappe", "site": site.Name}).
+		WithLabels(jobLabels(jobOperationWorkspace, bench.Name, workspace.Spec.Site)).
+		WithBackoffLimit(0).
+		WithPodAnnotations(jobPodAnnotations(bench)).
+		WithPodSecurityContext(siteReconciler.getPodSecurityContext(ctx, bench)).
+		WithImagePullSecrets(imagePullSecrets(bench)).
+		WithServiceAccountName(jobServiceAccount(bench)).
+		WithContainer(container).
+		WithPVCVolume("sites", fmt.Sprintf("%s-sites", bench.Name)).
+		WithVolume(corev1.Volume{
+			Name: "workspace",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+				},
+			},
+		}).
+		WithOwner(workspace, r.Scheme).
+		Build()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	applyDefaultJobTTL(&job.Spec)
+	if err := r.Create(ctx, job); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	// TODO(user): your logic here
+	logger.Info("Created workspace job", "job", jobName, "workspace", workspace.Spec.Label)
+	r.Recorder.Event(workspace, corev1.EventTypeNormal, "WorkspaceApplying",
+		fmt.Sprintf("Applying workspace %s to %s", workspace.Spec.Label, workspace.Spec.Site))
+	return ctrl.Result{}, r.updateWorkspaceStatus(ctx, workspace, func(status *vyogotechv1alpha1.SiteWorkspaceStatus) {
+		status.Phase = "Applying"
+		status.Hash = hash
+		status.JobName = jobName
+		status.Message = fmt.Sprintf("Applying workspace %s", workspace.Spec.Label)
+	})
+}
+
+// ensureWorkspaceConfigMap keeps the Workspace document in the ConfigMap the apply job mounts
+func (r *SiteWorkspaceReconciler) ensureWorkspaceConfigMap(ctx context.Context, workspace *vyogotechv1alpha1.SiteWorkspace, name string, document []byte) error {
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, client.ObjectKey{Name: name, Namespace: workspace.Namespace}, cm)
+	if err == nil {
+		if cm.Data[workspaceDocumentKey] == string(document) {
+			return nil
+		}
+		cm.Data = map[string]string{workspaceDocumentKey: string(document)}
+		return r.Update(ctx, cm)
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	cm = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: workspace.Namespace,
+			Labels:    map[string]string{"app": "frappe"},
+		},
+		Data: map[string]string{workspaceDocumentKey: string(document)},
+	}
+	if err := controllerutil.SetControllerReference(workspace, cm, r.Scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, cm)
+}
 
-	return ctrl.Result{}, nil
+// updateWorkspaceStatus applies update to the latest version of the SiteWorkspace's status
+func (r *SiteWorkspaceReconciler) updateWorkspaceStatus(ctx context.Context, workspace *vyogotechv1alpha1.SiteWorkspace, update func(*vyogotechv1alpha1.SiteWorkspaceStatus)) error {
+	latest := &vyogotechv1alpha1.SiteWorkspace{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(workspace), latest); err != nil {
+		return err
+	}
+	update(&latest.Status)
+	return r.Status().Update(ctx, latest)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SiteWorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vyogotechv1alpha1.SiteWorkspace{}).
+		Owns(&batchv1.Job{}).
 		Complete(r)
 }
+
+// Workspace document rows, in the field names of Frappe's Workspace, Workspace Shortcut
+// and Workspace Link doctypes
+type (
+	workspaceDoc struct {
+		Doctype    string                 `json:"doctype"`
+		Name       string                 `json:"name"`
+		Label      string                 `json:"label"`
+		Title      string                 `json:"title"`
+		Module     string                 `json:"module,omitempty"`
+		Icon       string                 `json:"icon,omitempty"`
+		ParentPage string                 `json:"parent_page,omitempty"`
+		SequenceID *int32                 `json:"sequence_id,omitempty"`
+		Public     int                    `json:"public"`
+		Content    string                 `json:"content"`
+		Shortcuts  []workspaceShortcutRow `json:"shortcuts"`
+		Links      []workspaceLinkRow     `json:"links"`
+	}
+	workspaceShortcutRow struct {
+		Doctype string `json:"doctype"`
+		Label   string `json:"label"`
+		Type    string `json:"type"`
+		LinkTo  string `json:"link_to,omitempty"`
+		URL     string `json:"url,omitempty"`
+		Color   string `json:"color,omitempty"`
+	}
+	workspaceLinkRow struct {
+		Doctype   string `json:"doctype"`
+		Type      string `json:"type"`
+		Label     string `json:"label"`
+		LinkType  string `json:"link_type,omitempty"`
+		LinkTo    string `json:"link_to,omitempty"`
+		LinkCount int    `json:"link_count,omitempty"`
+		Onboard   int    `json:"onboard"`
+	}
+	// workspaceBlock is an Editor.js block of the workspace page layout
+	workspaceBlock struct {
+		ID   string         `json:"id"`
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+)
+
+// workspaceDocument renders the spec as the Workspace document bench import-doc loads. The
+// page layout in content places the shortcuts, then the cards, in spec order.
+func workspaceDocument(workspace *vyogotechv1alpha1.SiteWorkspace) ([]byte, error) {
+	spec := workspace.Spec
+	if errs := validation.IsDNS1123Subdomain(spec.Site); len(errs) > 0 {
+		return nil, fmt.Errorf("site %q is not a valid site name: %s", spec.Site, errs[0])
+	}
+	if spec.Label == "" {
+		return nil, fmt.Errorf("label is required")
+	}
+
+	doc := workspaceDoc{
+		Doctype:    "Workspace",
+		Name:       spec.Label,
+		Label:      spec.Label,
+		Title:      spec.Title,
+		Module:     spec.Module,
+		Icon:       spec.Icon,
+		ParentPage: spec.ParentPage,
+		SequenceID: spec.SequenceID,
+		Public:     1,
+		Shortcuts:  []workspaceShortcutRow{},
+		Links:      []workspaceLinkRow{},
+	}
+	if doc.Title == "" {
+		doc.Title = spec.Label
+	}
+
+	var blocks []workspaceBlock
+	header := func(id, text string) workspaceBlock {
+		return workspaceBlock{ID: id, Type: "header", Data: map[string]any{
+			"text": fmt.Sprintf(`<span class="h4"><b>%s</b></span>`, text),
+			"col":  12,
+		}}
+	}
+
+	if len(spec.Shortcuts) > 0 {
+		blocks = append(blocks, header("shortcuts-header", "Your Shortcuts"))
+	}
+	for i, shortcut := range spec.Shortcuts {
+		if shortcut.Type == "URL" && shortcut.URL == "" {
+			return nil, fmt.Errorf("shortcut %q of type URL needs a url", shortcut.Label)
+		}
+		if shortcut.Type != "URL" && shortcut.LinkTo == "" {
+			return nil, fmt.Errorf("shortcut %q of type %s needs linkTo", shortcut.Label, shortcut.Type)
+		}
+		doc.Shortcuts = append(doc.Shortcuts, workspaceShortcutRow{
+			Doctype: "Workspace Shortcut",
+			Label:   shortcut.Label,
+			Type:    shortcut.Type,
+			LinkTo:  shortcut.LinkTo,
+			URL:     shortcut.URL,
+			Color:   shortcut.Color,
+		})
+		blocks = append(blocks, workspaceBlock{ID: fmt.Sprintf("shortcut-%d", i), Type: "shortcut", Data: map[string]any{
+			"shortcut_name": shortcut.Label,
+			"col":           3,
+		}})
+	}
+
+	if len(spec.Cards) > 0 {
+		blocks = append(blocks, header("cards-header", "Reports &amp; Masters"))
+	}
+	for i, card := range spec.Cards {
+		doc.Links = append(doc.Links, workspaceLinkRow{
+			Doctype:   "Workspace Link",
+			Type:      "Card Break",
+			Label:     card.Label,
+			LinkCount: len(card.Links),
+		})
+		for _, link := range card.Links {
+			onboard := 0
+			if link.Onboard {
+				onboard = 1
+			}
+			doc.Links = append(doc.Links, workspaceLinkRow{
+				Doctype:  "Workspace Link",
+				Type:     "Link",
+				Label:    link.Label,
+				LinkType: link.Type,
+				LinkTo:   link.LinkTo,
+				Onboard:  onboard,
+			})
+		}
+		blocks = append(blocks, workspaceBlock{ID: fmt.Sprintf("card-%d", i), Type: "card", Data: map[string]any{
+			"card_name": card.Label,
+			"col":       4,
+		}})
+	}
+
+	if blocks == nil {
+		blocks = []workspaceBlock{}
+	}
+	content, err := json.Marshal(blocks)
+	if err != nil {
+		return nil, err
+	}
+	doc.Content = string(content)
+	return json.MarshalIndent(doc, "", "  ")
+}
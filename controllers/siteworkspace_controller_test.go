@@ -0,0 +1,269 @@
+/*
+Copyright 2023 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newSiteWorkspaceTestReconciler(objs ...client.Object) (*SiteWorkspaceReconciler, client.Client, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(vyogotechv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&vyogotechv1alpha1.SiteWorkspace{}).Build()
+	recorder := record.NewFakeRecorder(10)
+	return &SiteWorkspaceReconciler{Client: c, Scheme: scheme, Recorder: recorder}, c, recorder
+}
+
+func newSiteWorkspaceTestObjects() (*vyogotechv1alpha1.SiteWorkspace, *vyogotechv1alpha1.FrappeSite, *vyogotechv1alpha1.FrappeBench) {
+	site, bench := newInitJobTestObjects()
+	site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseReady
+	workspace := &vyogotechv1alpha1.SiteWorkspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "sales", Namespace: "default", UID: "ws-uid"},
+		Spec: vyogotechv1alpha1.SiteWorkspaceSpec{
+			Site:   "site.local",
+			Label:  "Sales Ops",
+			Module: "Selling",
+			Shortcuts: []vyogotechv1alpha1.WorkspaceShortcut{
+				{Label: "Sales Order", Type: "DocType", LinkTo: "Sales Order", Color: "Blue"},
+				{Label: "Handbook", Type: "URL", URL: "https://wiki.example.com/sales"},
+			},
+			Cards: []vyogotechv1alpha1.WorkspaceCard{{
+				Label: "Masters",
+				Links: []vyogotechv1alpha1.WorkspaceLink{
+					{Label: "Customer", Type: "DocType", LinkTo: "Customer", Onboard: true},
+					{Label: "Sales Analytics", Type: "Report", LinkTo: "Sales Analytics"},
+				},
+			}},
+		},
+	}
+	return workspace, site, bench
+}
+
+func TestWorkspaceDocument(t *testing.T) {
+	workspace, _, _ := newSiteWorkspaceTestObjects()
+
+	document, err := workspaceDocument(workspace)
+	if err != nil {
+		t.Fatalf("workspaceDocument: %v", err)
+	}
+	var doc workspaceDoc
+	if err := json.Unmarshal(document, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if doc.Doctype != "Workspace" || doc.Name != "Sales Ops" || doc.Title != "Sales Ops" || doc.Module != "Selling" || doc.Public != 1 {
+		t.Errorf("unexpected workspace fields: %+v", doc)
+	}
+	if len(doc.Shortcuts) != 2 || doc.Shortcuts[1].URL != "https://wiki.example.com/sales" {
+		t.Errorf("unexpected shortcuts: %+v", doc.Shortcuts)
+	}
+	wantLinks := []workspaceLinkRow{
+		{Doctype: "Workspace Link", Type: "Card Break", Label: "Masters", LinkCount: 2},
+		{Doctype: "Workspace Link", Type: "Link", Label: "Customer", LinkType: "DocType", LinkTo: "Customer", Onboard: 1},
+		{Doctype: "Workspace Link", Type: "Link", Label: "Sales Analytics", LinkType: "Report", LinkTo: "Sales Analytics"},
+	}
+	if !slices.Equal(doc.Links, wantLinks) {
+		t.Errorf("links = %+v, want %+v", doc.Links, wantLinks)
+	}
+	var blocks []workspaceBlock
+	if err := json.Unmarshal([]byte(doc.Content), &blocks); err != nil {
+		t.Fatalf("content is not JSON: %v", err)
+	}
+	var types []string
+	for _, b := range blocks {
+		types = append(types, b.Type)
+	}
+	if want := []string{"header", "shortcut", "shortcut", "header", "card"}; !slices.Equal(types, want) {
+		t.Errorf("content blocks = %v, want %v", types, want)
+	}
+
+	// The same spec renders the same document, so its hash is stable
+	again, _ := workspaceDocument(workspace)
+	if string(again) != string(document) {
+		t.Error("expected a deterministic document")
+	}
+
+	invalid := workspace.DeepCopy()
+	invalid.Spec.Shortcuts = []vyogotechv1alpha1.WorkspaceShortcut{{Label: "Orders", Type: "DocType"}}
+	if _, err := workspaceDocument(invalid); err == nil {
+		t.Error("expected a DocType shortcut without linkTo to be rejected")
+	}
+}
+
+func TestSiteWorkspaceReconcile(t *testing.T) {
+	workspace, site, bench := newSiteWorkspaceTestObjects()
+	r, c, recorder := newSiteWorkspaceTestReconciler(workspace, site, bench)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "sales", Namespace: "default"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	updated := &vyogotechv1alpha1.SiteWorkspace{}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteWorkspace: %v", err)
+	}
+	if updated.Status.Phase != "Applying" || updated.Status.Hash == "" {
+		t.Fatalf("expected Applying with a hash, got %+v", updated.Status)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: updated.Status.JobName, Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	wantArgs := []string{"--site", "site.local", "import-doc", "/tmp/workspace/workspace.json"}
+	if !slices.Equal(container.Command, []string{"bench"}) || !slices.Equal(container.Args, wantArgs) {
+		t.Errorf("expected bench %v, got %v %v", wantArgs, container.Command, container.Args)
+	}
+	if job.Labels[jobOperationLabel] != jobOperationWorkspace {
+		t.Errorf("expected operation label %q, got %q", jobOperationWorkspace, job.Labels[jobOperationLabel])
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "sales-workspace", Namespace: "default"}, cm); err != nil {
+		t.Fatalf("Get ConfigMap: %v", err)
+	}
+	if !strings.Contains(cm.Data[workspaceDocumentKey], `"name": "Sales Ops"`) {
+		t.Errorf("expected the Workspace document in the ConfigMap, got %s", cm.Data[workspaceDocumentKey])
+	}
+	<-recorder.Events
+
+	job.Status.Succeeded = 1
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteWorkspace: %v", err)
+	}
+	if updated.Status.Phase != "Applied" || updated.Status.LastAppliedTime == nil {
+		t.Errorf("expected Applied, got %+v", updated.Status)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "WorkspaceApplied") {
+		t.Errorf("expected WorkspaceApplied event, got %q", event)
+	}
+
+	// An unchanged spec isn't applied again, even once the job is gone
+	if err := c.Delete(ctx, job); err != nil {
+		t.Fatalf("Delete Job: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(ctx, jobs); err != nil {
+		t.Fatalf("List Jobs: %v", err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("expected no new job for an applied spec, got %d", len(jobs.Items))
+	}
+
+	// A spec change is applied by a new job
+	updated.Spec.Icon = "sell"
+	if err := c.Update(ctx, updated); err != nil {
+		t.Fatalf("Update SiteWorkspace: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.List(ctx, jobs); err != nil {
+		t.Fatalf("List Jobs: %v", err)
+	}
+	if len(jobs.Items) != 1 || jobs.Items[0].Name == job.Name {
+		t.Errorf("expected a new apply job for the changed spec, got %d", len(jobs.Items))
+	}
+}
+
+func TestSiteWorkspaceReconcileFailure(t *testing.T) {
+	workspace, site, bench := newSiteWorkspaceTestObjects()
+	r, c, recorder := newSiteWorkspaceTestReconciler(workspace, site, bench)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "sales", Namespace: "default"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	<-recorder.Events
+	updated := &vyogotechv1alpha1.SiteWorkspace{}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteWorkspace: %v", err)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: updated.Status.JobName, Namespace: "default"}, job); err != nil {
+		t.Fatalf("Get Job: %v", err)
+	}
+	job.Status.Failed = 1
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Update Job: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteWorkspace: %v", err)
+	}
+	if updated.Status.Phase != "Failed" {
+		t.Errorf("expected Failed, got %+v", updated.Status)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "Warning WorkspaceApplyFailed") {
+		t.Errorf("expected WorkspaceApplyFailed event, got %q", event)
+	}
+}
+
+func TestSiteWorkspaceWaitsForSite(t *testing.T) {
+	workspace, site, bench := newSiteWorkspaceTestObjects()
+	site.Status.Phase = vyogotechv1alpha1.FrappeSitePhaseProvisioning
+	r, c, _ := newSiteWorkspaceTestReconciler(workspace, site, bench)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "sales", Namespace: "default"}}
+
+	result, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("expected a requeue while the site isn't Ready")
+	}
+	updated := &vyogotechv1alpha1.SiteWorkspace{}
+	if err := c.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Get SiteWorkspace: %v", err)
+	}
+	if updated.Status.Phase != "Pending" {
+		t.Errorf("expected Pending, got %+v", updated.Status)
+	}
+}
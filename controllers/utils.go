@@ -40,6 +40,9 @@ import (
 // getBenchImage returns the image to use from the bench
 // Priority: 1. bench.spec.imageConfig, 2. operator ConfigMap defaults, 3. hardcoded constants
 func (r *FrappeSiteReconciler) getBenchImage(ctx context.Context, bench *vyogotechv1alpha1.FrappeBench) string {
+	if image := channelImage(bench); image != "" {
+		return image
+	}
 	// Priority 1: Check bench-level ImageConfig override
 	if bench.Spec.ImageConfig != nil && bench.Spec.ImageConfig.Repository != "" {
 		image := bench.Spec.ImageConfig.Repository
@@ -77,6 +80,19 @@ func (r *FrappeSiteReconciler) getBenchImage(ctx context.Context, bench *vyogote
 
 // getOperatorConfig retrieves the operator configuration ConfigMap
 func (r *FrappeSiteReconciler) getOperatorConfig(ctx context.Context, namespace string) (*corev1.ConfigMap, error) {
+	if r.Client == nil {
+		return nil, fmt.Errorf("client not initialized")
+	}
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      "frappe-operator-config",
+		Namespace: "frappe-operator-system", // Operator namespace
+	}, configMap)
+	return configMap, err
+}
+
+// getOperatorConfig retrieves the operator configuration ConfigMap
+func (r *SiteBackupReconciler) getOperatorConfig(ctx context.Context) (*corev1.ConfigMap, error) {
 	configMap := &corev1.ConfigMap{}
 	err := r.Get(ctx, types.NamespacedName{
 		Name:      "frappe-operator-config",
@@ -225,3 +241,18 @@ func applyDefaultJobTTL(spec *batchv1.JobSpec) {
 	}
 	spec.TTLSecondsAfterFinished = int32Ptr(resources.DefaultJobTTL)
 }
+
+// lifecycleJobTTL returns the TTL of the bench-init, site-init and site-delete Jobs: the
+// bench's spec.jobTTLSecondsAfterFinished, else jobTTLSecondsAfterFinished from the
+// operator ConfigMap, else resources.DefaultJobTTL
+func lifecycleJobTTL(bench *vyogotechv1alpha1.FrappeBench, operatorConfig *corev1.ConfigMap) int32 {
+	if bench.Spec.JobTTLSecondsAfterFinished != nil {
+		return *bench.Spec.JobTTLSecondsAfterFinished
+	}
+	if operatorConfig != nil {
+		if ttl, err := strconv.ParseInt(strings.TrimSpace(operatorConfig.Data["jobTTLSecondsAfterFinished"]), 10, 32); err == nil && ttl >= 0 {
+			return int32(ttl)
+		}
+	}
+	return resources.DefaultJobTTL
+}
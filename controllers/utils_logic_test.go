@@ -112,6 +112,26 @@ func TestApplyDefaultJobTTL(t *testing.T) {
 	}
 }
 
+func TestLifecycleJobTTL(t *testing.T) {
+	bench := &vyogotechv1alpha1.FrappeBench{}
+	if ttl := lifecycleJobTTL(bench, nil); ttl != resources.DefaultJobTTL {
+		t.Errorf("expected the default TTL %d, got %d", resources.DefaultJobTTL, ttl)
+	}
+	cfg := &corev1.ConfigMap{Data: map[string]string{"jobTTLSecondsAfterFinished": "600"}}
+	if ttl := lifecycleJobTTL(bench, cfg); ttl != 600 {
+		t.Errorf("expected the operator config TTL 600, got %d", ttl)
+	}
+	invalid := &corev1.ConfigMap{Data: map[string]string{"jobTTLSecondsAfterFinished": "-5"}}
+	if ttl := lifecycleJobTTL(bench, invalid); ttl != resources.DefaultJobTTL {
+		t.Errorf("expected an invalid value to fall back to %d, got %d", resources.DefaultJobTTL, ttl)
+	}
+	override := int32(0)
+	bench.Spec.JobTTLSecondsAfterFinished = &override
+	if ttl := lifecycleJobTTL(bench, cfg); ttl != 0 {
+		t.Errorf("expected the bench override 0, got %d", ttl)
+	}
+}
+
 func TestIsLocalDomain(t *testing.T) {
 	if !isLocalDomain("site.local") {
 		t.Error("site.local should be local domain")
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// volumeSnapshotPollInterval is how often an operation waits for its VolumeSnapshot to be cut
+const volumeSnapshotPollInterval = 10 * time.Second
+
+// volumeSnapshotCRDMissing is the failure message when a snapshot is requested without the CRDs
+const volumeSnapshotCRDMissing = "the snapshot.storage.k8s.io VolumeSnapshot CRD is not installed"
+
+var volumeSnapshotGVK = schema.GroupVersionKind{
+	Group:   "snapshot.storage.k8s.io",
+	Version: "v1",
+	Kind:    "VolumeSnapshot",
+}
+
+// isVolumeSnapshotAvailable checks if the VolumeSnapshot CRDs are installed, the same way
+// isKEDAAvailable probes for KEDA. The check is bounded by timeout.
+func isVolumeSnapshotAvailable(ctx context.Context, c client.Client, timeout time.Duration) (bool, error) {
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(volumeSnapshotGVK)
+
+	err := withLookupTimeout(ctx, timeout, "VolumeSnapshot API", func(ctx context.Context) error {
+		return c.List(ctx, list, client.Limit(1))
+	})
+	if isLookupTimeout(err) {
+		return false, err
+	}
+	if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// ensureSitesVolumeSnapshot returns the VolumeSnapshot key of the bench's sites PVC,
+// creating it first if it doesn't exist yet; created reports whether it was just created
+func ensureSitesVolumeSnapshot(ctx context.Context, c client.Client, bench *vyogotechv1alpha1.FrappeBench, key client.ObjectKey, config *vyogotechv1alpha1.VolumeSnapshotConfig, labels map[string]string) (*unstructured.Unstructured, bool, error) {
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	err := c.Get(ctx, key, snapshot)
+	if err == nil {
+		return snapshot, false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, false, err
+	}
+
+	snapshot = buildVolumeSnapshot(bench, key, config, labels)
+	if err := c.Create(ctx, snapshot); err != nil {
+		return nil, false, err
+	}
+	return snapshot, true, nil
+}
+
+// volumeSnapshotCut reports whether the point-in-time copy exists, or a failure message
+// when the snapshot controller gave up on it
+func volumeSnapshotCut(snapshot *unstructured.Unstructured) (bool, string) {
+	if message, found, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message"); found && message != "" {
+		return false, fmt.Sprintf("VolumeSnapshot %s failed: %s", snapshot.GetName(), message)
+	}
+	// Once creationTime is set the point-in-time copy exists and the PVC may be written again
+	_, cut, _ := unstructured.NestedString(snapshot.Object, "status", "creationTime")
+	ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	return cut || ready, ""
+}
+
+// buildVolumeSnapshot builds the VolumeSnapshot of the bench's sites PVC
+func buildVolumeSnapshot(bench *vyogotechv1alpha1.FrappeBench, key client.ObjectKey, config *vyogotechv1alpha1.VolumeSnapshotConfig, labels map[string]string) *unstructured.Unstructured {
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"volumeSnapshotClassName": config.VolumeSnapshotClassName,
+				"source": map[string]interface{}{
+					"persistentVolumeClaimName": fmt.Sprintf("%s-sites", bench.Name),
+				},
+			},
+		},
+	}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	snapshot.SetName(key.Name)
+	snapshot.SetNamespace(key.Namespace)
+	snapshot.SetLabels(labels)
+	return snapshot
+}
@@ -21,6 +21,7 @@ import (
 	"flag"
 	"os"
 	"strconv"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -39,6 +40,7 @@ import (
 	routev1 "github.com/openshift/api/route/v1"
 	vyogotechv1alpha1 "github.com/vyogotech/frappe-operator/api/v1alpha1"
 	"github.com/vyogotech/frappe-operator/controllers"
+	"github.com/vyogotech/frappe-operator/pkg/progress"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -58,7 +60,21 @@ func init() {
 	//+kubebuilder:scaffold:scheme
 }
 
-const defaultMaxConcurrentSiteReconciles = 10
+const (
+	defaultMaxConcurrentSiteReconciles  = 10
+	defaultMaxConcurrentBenchReconciles = 3
+)
+
+// maxConcurrentFromEnv returns the positive integer in the environment variable name,
+// or def when it is unset or invalid
+func maxConcurrentFromEnv(name string, def int) int {
+	if s := os.Getenv(name); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
 
 // effectiveMaxFromBenches returns the effective max concurrent site reconciles from env value and bench list.
 // Used by getMaxConcurrentSiteReconciles; exported for testing.
@@ -84,12 +100,7 @@ func effectiveMaxFromBenches(fromEnv int, items []vyogotechv1alpha1.FrappeBench)
 // max(operatorConfig from env FRAPPE_MAX_CONCURRENT_SITE_RECONCILES, max(spec.siteReconcileConcurrency across benches)).
 // Operator config is from frappe-operator-config ConfigMap (e.g. maxConcurrentSiteReconciles), passed via env when using Helm.
 func getMaxConcurrentSiteReconciles(mgr ctrl.Manager) int {
-	fromEnv := defaultMaxConcurrentSiteReconciles
-	if s := os.Getenv("FRAPPE_MAX_CONCURRENT_SITE_RECONCILES"); s != "" {
-		if n, err := strconv.Atoi(s); err == nil && n > 0 {
-			fromEnv = n
-		}
-	}
+	fromEnv := maxConcurrentFromEnv("FRAPPE_MAX_CONCURRENT_SITE_RECONCILES", defaultMaxConcurrentSiteReconciles)
 	var items []vyogotechv1alpha1.FrappeBench
 	cl, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
 	if err == nil {
@@ -102,15 +113,39 @@ func getMaxConcurrentSiteReconciles(mgr ctrl.Manager) int {
 	return effectiveMaxFromBenches(fromEnv, items)
 }
 
+// getMaxConcurrentBenchReconciles returns the max concurrent bench reconciles from env
+// FRAPPE_MAX_CONCURRENT_BENCH_RECONCILES (operator config maxConcurrentBenchReconciles when using Helm).
+// Bench-init jobs stay capped separately by maxConcurrentBenchInits.
+func getMaxConcurrentBenchReconciles() int {
+	return maxConcurrentFromEnv("FRAPPE_MAX_CONCURRENT_BENCH_RECONCILES", defaultMaxConcurrentBenchReconciles)
+}
+
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var sequentialBenchReconcile bool
+	var optionalAPITimeout time.Duration
+	var provisioningBackoffBase, provisioningBackoffMax time.Duration
+	var maxProvisioningAttempts int
+	var enableWebhooks bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&sequentialBenchReconcile, "sequential-bench-reconcile", false,
+		"Reconcile FrappeBench components one at a time instead of concurrently. Useful for debugging.")
+	flag.DurationVar(&optionalAPITimeout, "optional-api-timeout", 10*time.Second,
+		"Timeout for lookups against optional operator APIs (MariaDB, KEDA, VolumeSnapshot); a lookup that times out is requeued.")
+	flag.DurationVar(&provisioningBackoffBase, "provisioning-backoff-base", 10*time.Second,
+		"Initial requeue delay while a FrappeSite waits for its bench or database; doubled on every attempt.")
+	flag.DurationVar(&provisioningBackoffMax, "provisioning-backoff-max", 5*time.Minute,
+		"Maximum requeue delay while a FrappeSite waits for its bench or database.")
+	flag.IntVar(&maxProvisioningAttempts, "max-provisioning-attempts", 30,
+		"Backoff steps a FrappeSite may spend waiting for its bench or database before it is marked Failed; 0 waits forever.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the FrappeSite validating webhook on port 9443. Requires the webhook service and serving certificate to be deployed.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -151,11 +186,23 @@ func main() {
 		setupLog.Info("Standard Kubernetes platform detected")
 	}
 
+	maxBenchReconciles := getMaxConcurrentBenchReconciles()
+	setupLog.Info("FrappeBench controller concurrency", "maxConcurrentReconciles", maxBenchReconciles)
+
+	logReader, err := progress.NewLogReader(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create pod log reader")
+		os.Exit(1)
+	}
 	if err = (&controllers.FrappeBenchReconciler{
-		Client:      mgr.GetClient(),
-		Scheme:      mgr.GetScheme(),
-		Recorder:    mgr.GetEventRecorderFor("frappebench-controller"),
-		IsOpenShift: isOpenShift,
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("frappebench-controller"),
+		IsOpenShift:             isOpenShift,
+		SequentialEnsure:        sequentialBenchReconcile,
+		LookupTimeout:           optionalAPITimeout,
+		MaxConcurrentReconciles: maxBenchReconciles,
+		LogReader:               logReader,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "FrappeBench")
 		os.Exit(1)
@@ -168,6 +215,11 @@ func main() {
 		Recorder:                mgr.GetEventRecorderFor("frappesite-controller"),
 		IsOpenShift:             isOpenShift,
 		MaxConcurrentReconciles: maxSiteReconciles,
+		LookupTimeout:           optionalAPITimeout,
+		ProvisioningBackoffBase: provisioningBackoffBase,
+		ProvisioningBackoffMax:  provisioningBackoffMax,
+		MaxProvisioningAttempts: int32(maxProvisioningAttempts),
+		LogReader:               logReader,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "FrappeSite")
 		os.Exit(1)
@@ -212,30 +264,45 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "SiteDashboard")
 		os.Exit(1)
 	}
-	if err = (&controllers.SiteJobReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("sitejob-controller"),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "SiteJob")
-		os.Exit(1)
-	}
+	// Backup and restore progress and SiteJob failure logs are read from job pod logs
 	if err = (&controllers.SiteBackupReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("sitebackup-controller"),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("sitebackup-controller"),
+		LogReader: logReader,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SiteBackup")
 		os.Exit(1)
 	}
 	if err = (&controllers.SiteRestoreReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("siterestore-controller"),
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Recorder:      mgr.GetEventRecorderFor("siterestore-controller"),
+		LogReader:     logReader,
+		LookupTimeout: optionalAPITimeout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SiteRestore")
 		os.Exit(1)
 	}
+	if err = (&controllers.SiteJobReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("sitejob-controller"),
+		LogReader: logReader,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SiteJob")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err = (&vyogotechv1alpha1.FrappeBench{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "FrappeBench")
+			os.Exit(1)
+		}
+		if err = (&vyogotechv1alpha1.FrappeSite{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "FrappeSite")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -107,3 +107,25 @@ func Test_effectiveMaxFromBenches(t *testing.T) {
 		})
 	}
 }
+
+func Test_maxConcurrentFromEnv(t *testing.T) {
+	const name = "FRAPPE_MAX_CONCURRENT_BENCH_RECONCILES"
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", defaultMaxConcurrentBenchReconciles},
+		{"8", 8},
+		{"0", defaultMaxConcurrentBenchReconciles},
+		{"-2", defaultMaxConcurrentBenchReconciles},
+		{"many", defaultMaxConcurrentBenchReconciles},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(name, tt.value)
+			if got := maxConcurrentFromEnv(name, defaultMaxConcurrentBenchReconciles); got != tt.want {
+				t.Errorf("maxConcurrentFromEnv(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package progress parses the progress markers printed by backup and restore jobs
+package progress
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Marker prefixes progress lines in job logs: "FRAPPE_PROGRESS <bytes processed> <bytes total> <stage>"
+const Marker = "FRAPPE_PROGRESS"
+
+// Report is a single progress marker
+type Report struct {
+	Stage          string
+	BytesProcessed int64
+	BytesTotal     int64
+}
+
+// Percent returns BytesProcessed relative to BytesTotal, or 0 when the total is unknown
+func (r Report) Percent() int32 {
+	if r.BytesTotal <= 0 {
+		return 0
+	}
+	percent := r.BytesProcessed * 100 / r.BytesTotal
+	if percent > 100 {
+		percent = 100
+	}
+	return int32(percent)
+}
+
+// Parse returns the last well-formed progress marker in logs
+func Parse(logs string) (Report, bool) {
+	var last Report
+	found := false
+
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != Marker {
+			continue
+		}
+		processed, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		total, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		last = Report{Stage: strings.Join(fields[3:], " "), BytesProcessed: processed, BytesTotal: total}
+		found = true
+	}
+
+	return last, found
+}
+
+// LogReader returns the last lines of a pod container's log
+type LogReader interface {
+	TailLog(ctx context.Context, namespace, pod, container string, lines int64) (string, error)
+}
+
+// clientsetLogReader reads pod logs through the Kubernetes API
+type clientsetLogReader struct {
+	clientset kubernetes.Interface
+}
+
+// NewLogReader returns a LogReader backed by the pods/log API
+func NewLogReader(config *rest.Config) (LogReader, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &clientsetLogReader{clientset: clientset}, nil
+}
+
+func (r *clientsetLogReader) TailLog(ctx context.Context, namespace, pod, container string, lines int64) (string, error) {
+	stream, err := r.clientset.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &lines,
+	}).Stream(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
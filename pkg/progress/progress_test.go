@@ -0,0 +1,61 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package progress
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		logs  string
+		want  Report
+		found bool
+	}{
+		{"no markers", "Starting backup\nDone\n", Report{}, false},
+		{"last marker wins", "FRAPPE_PROGRESS 10 100 Backing up\nnoise\nFRAPPE_PROGRESS 40 100 Backing up\n",
+			Report{Stage: "Backing up", BytesProcessed: 40, BytesTotal: 100}, true},
+		{"malformed markers skipped", "FRAPPE_PROGRESS 5 50 Downloading\nFRAPPE_PROGRESS x 50\nFRAPPE_PROGRESS 7\n",
+			Report{Stage: "Downloading", BytesProcessed: 5, BytesTotal: 50}, true},
+		{"stage optional", "FRAPPE_PROGRESS 0 0", Report{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := Parse(tt.logs)
+			if found != tt.found || got != tt.want {
+				t.Errorf("Parse() = %+v, %v; want %+v, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestReportPercent(t *testing.T) {
+	tests := []struct {
+		report Report
+		want   int32
+	}{
+		{Report{BytesProcessed: 50, BytesTotal: 200}, 25},
+		{Report{BytesProcessed: 10, BytesTotal: 0}, 0},
+		{Report{BytesProcessed: 300, BytesTotal: 200}, 100},
+	}
+
+	for _, tt := range tests {
+		if got := tt.report.Percent(); got != tt.want {
+			t.Errorf("%+v.Percent() = %d, want %d", tt.report, got, tt.want)
+		}
+	}
+}
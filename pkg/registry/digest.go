@@ -0,0 +1,239 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRegistry     = "docker.io"
+	dockerHubAPIHost    = "registry-1.docker.io"
+	defaultTag          = "latest"
+	digestHeader        = "Docker-Content-Digest"
+	defaultHTTPTimeout  = 10 * time.Second
+	authenticateHeader  = "WWW-Authenticate"
+	bearerAuthChallenge = "bearer "
+)
+
+// challengeParamPattern matches key="value" pairs; values may themselves contain commas (e.g. scope)
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// manifestMediaTypes are accepted when resolving a tag so multi-arch indexes resolve to the index digest
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Credentials are optional basic-auth credentials for a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// DigestResolver resolves an image reference to the digest it currently points at
+type DigestResolver interface {
+	Resolve(ctx context.Context, image string, creds *Credentials) (string, error)
+}
+
+// Reference is a parsed image reference
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference splits an image reference into registry, repository, tag and digest,
+// applying Docker Hub defaults (docker.io, library/ prefix, latest tag)
+func ParseReference(image string) Reference {
+	ref := Reference{}
+	name := image
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+	}
+
+	// A registry host is only present if the first component looks like a host
+	if i := strings.Index(name, "/"); i >= 0 {
+		first := name[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			ref.Registry = first
+			name = name[i+1:]
+		}
+	}
+	if ref.Registry == "" {
+		ref.Registry = defaultRegistry
+	}
+
+	// The tag separator is the last colon after the last slash
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+
+	if ref.Registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+
+	return ref
+}
+
+// HTTPDigestResolver resolves digests through the OCI distribution API,
+// handling anonymous and basic-auth bearer token challenges
+type HTTPDigestResolver struct {
+	Client *http.Client
+}
+
+// NewHTTPDigestResolver returns a resolver with a bounded request timeout
+func NewHTTPDigestResolver() *HTTPDigestResolver {
+	return &HTTPDigestResolver{Client: &http.Client{Timeout: defaultHTTPTimeout}}
+}
+
+// Resolve returns the manifest digest for image. Digest-pinned references are returned as-is.
+func (r *HTTPDigestResolver) Resolve(ctx context.Context, image string, creds *Credentials) (string, error) {
+	ref := ParseReference(image)
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	host := ref.Registry
+	if host == defaultRegistry {
+		host = dockerHubAPIHost
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, ref.Repository, ref.Tag)
+
+	resp, err := r.headManifest(ctx, manifestURL, "", creds)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get(authenticateHeader)
+		if !strings.HasPrefix(strings.ToLower(challenge), bearerAuthChallenge) {
+			return "", fmt.Errorf("registry %s requires unsupported authentication", ref.Registry)
+		}
+		token, err := r.fetchToken(ctx, challenge, creds)
+		if err != nil {
+			return "", err
+		}
+		if resp, err = r.headManifest(ctx, manifestURL, token, nil); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s: registry returned %s", image, resp.Status)
+	}
+
+	digest := resp.Header.Get(digestHeader)
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for %s", image)
+	}
+	return digest, nil
+}
+
+func (r *HTTPDigestResolver) headManifest(ctx context.Context, manifestURL, token string, creds *Credentials) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registry: %w", err)
+	}
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+// fetchToken exchanges a Bearer challenge (realm, service, scope) for a registry token
+func (r *HTTPDigestResolver) fetchToken(ctx context.Context, challenge string, creds *Credentials) (string, error) {
+	params := parseChallenge(challenge[len(bearerAuthChallenge):])
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry auth challenge has no realm")
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid registry auth realm: %w", err)
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch registry token: %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseChallenge parses comma-separated key="value" pairs from a WWW-Authenticate header
+func parseChallenge(s string) map[string]string {
+	params := map[string]string{}
+	for _, match := range challengeParamPattern.FindAllStringSubmatch(s, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	return params
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 Vyogo Technologies.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image string
+		want  Reference
+	}{
+		{"nginx", Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{"frappe/erpnext:version-15", Reference{Registry: "docker.io", Repository: "frappe/erpnext", Tag: "version-15"}},
+		{"ghcr.io/org/app:v1", Reference{Registry: "ghcr.io", Repository: "org/app", Tag: "v1"}},
+		{"localhost:5000/app", Reference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{"quay.io/org/app@sha256:abc", Reference{Registry: "quay.io", Repository: "org/app", Digest: "sha256:abc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := ParseReference(tt.image); got != tt.want {
+				t.Errorf("ParseReference(%q) = %+v, want %+v", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPDigestResolver_PinnedDigest(t *testing.T) {
+	r := NewHTTPDigestResolver()
+	digest, err := r.Resolve(context.Background(), "quay.io/org/app@sha256:abc", nil)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if digest != "sha256:abc" {
+		t.Errorf("expected pinned digest, got %s", digest)
+	}
+}
+
+func TestHTTPDigestResolver_BearerChallenge(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/token":
+			if req.URL.Query().Get("scope") != "repository:org/app:pull,push" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"secret-token"}`))
+		case strings.HasPrefix(req.URL.Path, "/v2/org/app/manifests/v1"):
+			if req.Header.Get("Authorization") != "Bearer secret-token" {
+				w.Header().Set("WWW-Authenticate",
+					fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:org/app:pull,push"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:feed")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r := &HTTPDigestResolver{Client: server.Client()}
+	host := strings.TrimPrefix(server.URL, "https://")
+	digest, err := r.Resolve(context.Background(), host+"/org/app:v1", nil)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if digest != "sha256:feed" {
+		t.Errorf("expected sha256:feed, got %s", digest)
+	}
+}
@@ -76,6 +76,34 @@ func TestDeploymentBuilderWithReplicas(t *testing.T) {
 	}
 }
 
+func TestDeploymentBuilderWithPodAnnotations(t *testing.T) {
+	d := NewDeploymentBuilder("test", "default").
+		WithAnnotations(map[string]string{"owner": "ops"}).
+		WithPodAnnotations(map[string]string{"sidecar.istio.io/inject": "true"}).
+		MustBuild()
+
+	if d.Spec.Template.Annotations["sidecar.istio.io/inject"] != "true" {
+		t.Error("expected pod annotation on pod template")
+	}
+	if _, ok := d.Annotations["sidecar.istio.io/inject"]; ok {
+		t.Error("pod annotation should not be set on the deployment")
+	}
+	if _, ok := d.Spec.Template.Annotations["owner"]; ok {
+		t.Error("deployment annotation should not be set on the pod template")
+	}
+}
+
+func TestDeploymentBuilderWithTerminationGracePeriod(t *testing.T) {
+	grace := int64(600)
+	d := NewDeploymentBuilder("test", "default").
+		WithTerminationGracePeriod(&grace).
+		MustBuild()
+
+	if d.Spec.Template.Spec.TerminationGracePeriodSeconds == nil || *d.Spec.Template.Spec.TerminationGracePeriodSeconds != 600 {
+		t.Errorf("expected termination grace period 600, got %v", d.Spec.Template.Spec.TerminationGracePeriodSeconds)
+	}
+}
+
 func TestDeploymentBuilderWithContainer(t *testing.T) {
 	container := NewContainerBuilder("app", "nginx:latest").
 		WithPort("http", 80).
@@ -205,6 +233,22 @@ func TestJobBuilderWithBackoffLimit(t *testing.T) {
 	}
 }
 
+func TestJobBuilderWithPodAnnotations(t *testing.T) {
+	j := NewJobBuilder("test", "default").
+		WithPodAnnotations(nil).
+		MustBuild()
+	if j.Spec.Template.Annotations != nil {
+		t.Errorf("expected no pod annotations, got %v", j.Spec.Template.Annotations)
+	}
+
+	j = NewJobBuilder("test", "default").
+		WithPodAnnotations(map[string]string{"linkerd.io/inject": "disabled"}).
+		MustBuild()
+	if j.Spec.Template.Annotations["linkerd.io/inject"] != "disabled" {
+		t.Error("expected pod annotation on job pod template")
+	}
+}
+
 func TestNewContainerBuilder(t *testing.T) {
 	c := NewContainerBuilder("app", "nginx:latest").Build()
 
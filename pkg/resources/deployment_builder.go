@@ -212,6 +212,26 @@ func (b *DeploymentBuilder) WithAffinity(affinity *corev1.Affinity) *DeploymentB
 	return b
 }
 
+// WithTerminationGracePeriod sets how long pods get to shut down gracefully
+func (b *DeploymentBuilder) WithTerminationGracePeriod(seconds *int64) *DeploymentBuilder {
+	b.deployment.Spec.Template.Spec.TerminationGracePeriodSeconds = seconds
+	return b
+}
+
+// WithPodAnnotations adds annotations to the pod template
+func (b *DeploymentBuilder) WithPodAnnotations(annotations map[string]string) *DeploymentBuilder {
+	if len(annotations) == 0 {
+		return b
+	}
+	if b.deployment.Spec.Template.Annotations == nil {
+		b.deployment.Spec.Template.Annotations = make(map[string]string)
+	}
+	for k, v := range annotations {
+		b.deployment.Spec.Template.Annotations[k] = v
+	}
+	return b
+}
+
 // WithExtraPodLabels adds extra labels to the pod template
 func (b *DeploymentBuilder) WithExtraPodLabels(labels map[string]string) *DeploymentBuilder {
 	if b.deployment.Spec.Template.Labels == nil {
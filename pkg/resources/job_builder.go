@@ -212,6 +212,20 @@ func (b *JobBuilder) WithAffinity(affinity *corev1.Affinity) *JobBuilder {
 	return b
 }
 
+// WithPodAnnotations adds annotations to the pod template
+func (b *JobBuilder) WithPodAnnotations(annotations map[string]string) *JobBuilder {
+	if len(annotations) == 0 {
+		return b
+	}
+	if b.job.Spec.Template.Annotations == nil {
+		b.job.Spec.Template.Annotations = make(map[string]string)
+	}
+	for k, v := range annotations {
+		b.job.Spec.Template.Annotations[k] = v
+	}
+	return b
+}
+
 // WithExtraPodLabels adds extra labels to the pod template
 func (b *JobBuilder) WithExtraPodLabels(labels map[string]string) *JobBuilder {
 	if b.job.Spec.Template.Labels == nil {
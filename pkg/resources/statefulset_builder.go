@@ -110,6 +110,12 @@ func (b *StatefulSetBuilder) WithPodSecurityContext(ctx *corev1.PodSecurityConte
 	return b
 }
 
+// WithImagePullSecrets sets image pull secrets
+func (b *StatefulSetBuilder) WithImagePullSecrets(secrets []corev1.LocalObjectReference) *StatefulSetBuilder {
+	b.sts.Spec.Template.Spec.ImagePullSecrets = secrets
+	return b
+}
+
 // Build returns the constructed StatefulSet
 func (b *StatefulSetBuilder) Build() (*appsv1.StatefulSet, error) {
 	if b.owner != nil && b.scheme != nil {
@@ -24,7 +24,7 @@ import (
 	"text/template"
 )
 
-//go:embed templates/*.sh templates/*.py
+//go:embed templates/*.sh templates/*.py templates/*.conf
 var templateFS embed.FS
 
 // ScriptName represents available script templates
@@ -37,12 +37,38 @@ const (
 	SiteDelete ScriptName = "site_delete.sh"
 	// SiteBackup creates a backup of a Frappe site
 	SiteBackup ScriptName = "site_backup.sh"
+	// BackupProgress wraps `bench backup` and prints progress markers while it runs
+	BackupProgress ScriptName = "backup_progress.sh"
 	// BenchInit initializes a Frappe bench (sites dir, common_site_config.json, assets)
 	BenchInit ScriptName = "bench_init.sh"
 	// AppInstall installs an app on a Frappe site
 	AppInstall ScriptName = "app_install.sh"
+	// AppUninstall uninstalls apps from a Frappe site
+	AppUninstall ScriptName = "app_uninstall.sh"
 	// UpdateSiteConfig updates site_config.json
 	UpdateSiteConfig ScriptName = "update_site_config.py"
+	// SiteHealthCheck waits for a site to respond through the bench nginx
+	SiteHealthCheck ScriptName = "site_health_check.sh"
+	// SyncCommonSiteConfig points the redis URLs in common_site_config.json at the bench's services
+	SyncCommonSiteConfig ScriptName = "sync_common_site_config.sh"
+	// CommonSiteConfigMerge merges spec.commonSiteConfig keys into common_site_config.json
+	CommonSiteConfigMerge ScriptName = "common_site_config_merge.sh"
+	// SiteCORSConfig writes allow_cors to a site's site_config.json
+	SiteCORSConfig ScriptName = "site_cors_config.sh"
+	// SiteConfigMerge merges spec.siteConfig keys into a site's site_config.json
+	SiteConfigMerge ScriptName = "site_config_merge.sh"
+	// SiteDBCredentials rewrites the database credentials in a site's site_config.json
+	SiteDBCredentials ScriptName = "site_db_credentials.sh"
+	// SiteMaintenanceMode turns a site's maintenance mode on or off
+	SiteMaintenanceMode ScriptName = "site_maintenance_mode.sh"
+	// NginxFrappeConf is the frappe server block template of the bench nginx
+	NginxFrappeConf ScriptName = "nginx_frappe.conf"
+	// DashboardChart replaces or removes Dashboard Chart documents on a site
+	DashboardChart ScriptName = "dashboard_chart.sh"
+	// SiteUser creates, enables or disables a site user and sets its password
+	SiteUser ScriptName = "site_user.sh"
+	// WaitForDatabase waits for a site's database to accept connections
+	WaitForDatabase ScriptName = "wait_for_database.sh"
 )
 
 // GetScript returns the raw script content
@@ -117,15 +143,88 @@ type AppInstallData struct {
 	GitBranch string
 }
 
+// AppUninstallData provides data for the app uninstall script
+type AppUninstallData struct {
+	SiteName string
+	Apps     []string
+}
+
+// SiteHealthCheckData provides data for the site health check script
+type SiteHealthCheckData struct {
+	NginxAddress string // host:port of the bench nginx Service
+}
+
+// SyncCommonSiteConfigData provides data for the common_site_config.json redis sync script
+type SyncCommonSiteConfigData struct {
+	RedisCache string
+	RedisQueue string
+}
+
+// SiteCORSConfigData provides data for the site CORS configuration script
+type SiteCORSConfigData struct {
+	SiteName     string
+	AllowOrigins []string // empty removes allow_cors
+}
+
+// SiteConfigMergeData provides data for the site config merge script. The keys themselves
+// are passed to the job in the SITE_CONFIG_PATCH environment variable.
+type SiteConfigMergeData struct {
+	SiteName string
+}
+
+// SiteDBCredentialsData provides data for the database credentials update script
+type SiteDBCredentialsData struct {
+	SiteName string
+}
+
+// SiteMaintenanceModeData provides data for the site maintenance mode script
+type SiteMaintenanceModeData struct {
+	SiteName string
+	Mode     string // "on" or "off"
+}
+
+// NginxFrappeConfData provides data for the nginx frappe server block
+type NginxFrappeConfData struct {
+	ClientMaxBodySize string
+	ProxyReadTimeout  string
+	ExtraConfig       string // appended to the server block verbatim
+}
+
+// DashboardChartData provides data for the dashboard chart script. The charts to delete
+// and the chart to insert are passed to the job in the CHART_DELETE and CHART_INSERT
+// environment variables.
+type DashboardChartData struct {
+	SiteName string
+}
+
+// SiteUserData provides data for the site user script. The user is passed to the job in
+// the USER_EMAIL, USER_FIRST_NAME, USER_LAST_NAME and USER_ENABLED environment variables.
+type SiteUserData struct {
+	SiteName string
+}
+
 // ListScripts returns all available script names
 func ListScripts() []ScriptName {
 	return []ScriptName{
 		SiteInit,
 		SiteDelete,
 		SiteBackup,
+		BackupProgress,
 		BenchInit,
 		AppInstall,
+		AppUninstall,
 		UpdateSiteConfig,
+		SiteHealthCheck,
+		SyncCommonSiteConfig,
+		CommonSiteConfigMerge,
+		SiteCORSConfig,
+		SiteConfigMerge,
+		SiteDBCredentials,
+		SiteMaintenanceMode,
+		NginxFrappeConf,
+		DashboardChart,
+		SiteUser,
+		WaitForDatabase,
 	}
 }
 
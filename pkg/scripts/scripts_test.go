@@ -30,6 +30,7 @@ func TestGetScript(t *testing.T) {
 		{SiteDelete, "bench drop-site"},
 		{SiteBackup, "bench --site"},
 		{AppInstall, "install-app"},
+		{AppUninstall, "uninstall-app"},
 		{UpdateSiteConfig, "site_config.json"},
 	}
 
@@ -85,7 +86,7 @@ func TestListScripts(t *testing.T) {
 		t.Error("ListScripts() returned empty list")
 	}
 
-	expected := []ScriptName{SiteInit, SiteDelete, SiteBackup, BenchInit, AppInstall, UpdateSiteConfig}
+	expected := []ScriptName{SiteInit, SiteDelete, SiteBackup, BackupProgress, BenchInit, AppInstall, AppUninstall, UpdateSiteConfig, SiteHealthCheck, SyncCommonSiteConfig, CommonSiteConfigMerge, SiteCORSConfig, SiteConfigMerge, SiteDBCredentials, SiteMaintenanceMode, NginxFrappeConf, DashboardChart, SiteUser, WaitForDatabase}
 	if len(scripts) != len(expected) {
 		t.Errorf("expected %d scripts, got %d", len(expected), len(scripts))
 	}
@@ -93,7 +94,7 @@ func TestListScripts(t *testing.T) {
 
 func TestScriptShebang(t *testing.T) {
 	// Shell scripts should have proper shebang
-	shellScripts := []ScriptName{SiteInit, SiteDelete, SiteBackup, BenchInit, AppInstall}
+	shellScripts := []ScriptName{SiteInit, SiteDelete, SiteBackup, BackupProgress, BenchInit, AppInstall, AppUninstall, SiteHealthCheck, SyncCommonSiteConfig, CommonSiteConfigMerge, SiteCORSConfig, SiteConfigMerge, SiteDBCredentials, SiteMaintenanceMode, DashboardChart, SiteUser, WaitForDatabase}
 	for _, name := range shellScripts {
 		content, err := GetScript(name)
 		if err != nil {
@@ -107,7 +108,7 @@ func TestScriptShebang(t *testing.T) {
 
 func TestScriptSetE(t *testing.T) {
 	// Shell scripts should use set -e for error handling
-	shellScripts := []ScriptName{SiteInit, SiteDelete, SiteBackup, BenchInit, AppInstall}
+	shellScripts := []ScriptName{SiteInit, SiteDelete, SiteBackup, BackupProgress, BenchInit, AppInstall, AppUninstall, SiteHealthCheck, SyncCommonSiteConfig, CommonSiteConfigMerge, SiteCORSConfig, SiteConfigMerge, SiteDBCredentials, SiteMaintenanceMode, DashboardChart, SiteUser, WaitForDatabase}
 	for _, name := range shellScripts {
 		content, err := GetScript(name)
 		if err != nil {
@@ -171,4 +172,75 @@ func TestRenderScript(t *testing.T) {
 	if !strings.Contains(benchContent, "redis://e2e-bench-redis-queue:6379") {
 		t.Error("rendered bench init script should contain bench name in redis_queue URL")
 	}
+	if !strings.Contains(benchContent, `if [ "${SHARED_ASSETS:-0}" = "1" ]; then`) {
+		t.Error("rendered bench init script should skip the asset sync with a shared assets volume")
+	}
+	// NginxFrappeConfData
+	nginxContent, err := RenderScript(NginxFrappeConf, NginxFrappeConfData{ClientMaxBodySize: "200m", ProxyReadTimeout: "300s", ExtraConfig: "    add_header X-Test 1;"})
+	if err != nil {
+		t.Fatalf("RenderScript(NginxFrappeConf) error: %v", err)
+	}
+	if !strings.Contains(nginxContent, "client_max_body_size 200m;") || !strings.Contains(nginxContent, "proxy_read_timeout 300s;") {
+		t.Error("rendered nginx config should contain the body size and read timeout")
+	}
+	if !strings.Contains(nginxContent, "    add_header X-Test 1;\n}") {
+		t.Error("rendered nginx config should end the server block with the extra config")
+	}
+	if !strings.Contains(nginxContent, "server ${BACKEND} fail_timeout=0;") {
+		t.Error("rendered nginx config should leave the entrypoint variables to envsubst")
+	}
+	// SiteHealthCheckData
+	healthData := SiteHealthCheckData{NginxAddress: "my-bench-nginx:8080"}
+	healthContent, err := RenderScript(SiteHealthCheck, healthData)
+	if err != nil {
+		t.Fatalf("RenderScript(SiteHealthCheck, healthData) error: %v", err)
+	}
+	if !strings.Contains(healthContent, `-H "Host: $DOMAIN" "$URL"`) || !strings.Contains(healthContent, "http://my-bench-nginx:8080/api/method/ping") {
+		t.Error("rendered health check script should curl the site through nginx")
+	}
+	// SyncCommonSiteConfigData
+	syncData := SyncCommonSiteConfigData{RedisCache: "redis://new-bench-redis-cache:6379", RedisQueue: "redis://new-bench-redis-queue:6379"}
+	syncContent, err := RenderScript(SyncCommonSiteConfig, syncData)
+	if err != nil {
+		t.Fatalf("RenderScript(SyncCommonSiteConfig, syncData) error: %v", err)
+	}
+	if !strings.Contains(syncContent, `"redis_cache": "redis://new-bench-redis-cache:6379"`) || !strings.Contains(syncContent, `"redis_queue": "redis://new-bench-redis-queue:6379"`) {
+		t.Error("rendered config sync script should contain the desired redis URLs")
+	}
+	// SiteCORSConfigData
+	corsData := SiteCORSConfigData{SiteName: "site.local", AllowOrigins: []string{"https://app.example.com", "http://localhost:3000"}}
+	corsContent, err := RenderScript(SiteCORSConfig, corsData)
+	if err != nil {
+		t.Fatalf("RenderScript(SiteCORSConfig, corsData) error: %v", err)
+	}
+	if !strings.Contains(corsContent, `origins = ["https://app.example.com", "http://localhost:3000"]`) {
+		t.Error("rendered CORS script should contain the allowed origins as a list")
+	}
+	if !strings.Contains(corsContent, "sites/site.local/site_config.json") {
+		t.Error("rendered CORS script should target the site's config")
+	}
+	// SiteConfigMergeData
+	mergeContent, err := RenderScript(SiteConfigMerge, SiteConfigMergeData{SiteName: "site.local"})
+	if err != nil {
+		t.Fatalf("RenderScript(SiteConfigMerge) error: %v", err)
+	}
+	if !strings.Contains(mergeContent, "sites/site.local/site_config.json") {
+		t.Error("rendered site config merge script should target the site's config")
+	}
+	// SiteDBCredentialsData
+	credsContent, err := RenderScript(SiteDBCredentials, SiteDBCredentialsData{SiteName: "site.local"})
+	if err != nil {
+		t.Fatalf("RenderScript(SiteDBCredentials) error: %v", err)
+	}
+	if !strings.Contains(credsContent, "sites/site.local/site_config.json") || !strings.Contains(credsContent, `config["db_password"]`) {
+		t.Error("rendered db credentials script should rewrite db_password in the site's config")
+	}
+	// SiteMaintenanceModeData
+	maintenanceContent, err := RenderScript(SiteMaintenanceMode, SiteMaintenanceModeData{SiteName: "site.local", Mode: "on"})
+	if err != nil {
+		t.Fatalf("RenderScript(SiteMaintenanceMode) error: %v", err)
+	}
+	if !strings.Contains(maintenanceContent, "bench --site site.local set-maintenance-mode on") {
+		t.Error("rendered maintenance mode script should turn maintenance mode on for the site")
+	}
 }
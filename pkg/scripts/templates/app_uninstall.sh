@@ -0,0 +1,27 @@
+#!/bin/bash
+# App uninstall script for Frappe (embedded in operator, executed in site app uninstall jobs)
+# Removes apps that were dropped from spec.apps; app names are validated by the operator
+
+set -e
+
+# Setup user for OpenShift compatibility
+if ! whoami &>/dev/null; then
+  export USER=frappe
+  export LOGNAME=frappe
+  if [ -w /etc/passwd ]; then
+    echo "frappe:x:$(id -u):0:frappe user:/home/frappe:/sbin/nologin" >> /etc/passwd
+  fi
+fi
+
+cd /home/frappe/frappe-bench
+
+SITE_NAME="{{.SiteName}}"
+APPS=({{range $i, $app := .Apps}}{{if $i}} {{end}}"{{$app}}"{{end}})
+
+# bench skips apps that aren't installed on the site
+for APP_NAME in "${APPS[@]}"; do
+    echo "Uninstalling $APP_NAME from site $SITE_NAME"
+    bench --site "$SITE_NAME" uninstall-app "$APP_NAME" --yes
+done
+
+echo "Uninstalled ${APPS[*]} from $SITE_NAME"
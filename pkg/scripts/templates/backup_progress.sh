@@ -0,0 +1,195 @@
+#!/bin/bash
+# Backup wrapper script for Frappe (embedded in operator, executed in backup jobs)
+# Runs `bench <args>` and periodically prints progress markers parsed by the operator:
+#   FRAPPE_PROGRESS <bytes processed> <bytes total> <stage>
+# Bytes processed is the size of the backup files written so far. The total is an estimate
+# (database size, plus site files with --with-files), so compressed backups finish below 100%.
+# With BACKUP_MODE=files-only, the database dump written by this run is deleted; bench backup has no
+# option to skip it.
+# With REDACT_CONFIG=true, credentials in the site_config.json backup are replaced by "__redacted__",
+# and the original values are reported encrypted with the Fernet key in REDACT_CONFIG_KEY.
+# With S3_BUCKET set, the files written by this run are uploaded to s3://$S3_BUCKET/$S3_PREFIX/ under
+# their names without the timestamp and site prefix (database.sql.gz, files.tar, ...), and removed
+# from the sites volume afterwards when S3_REMOVE_LOCAL=true.
+# The files written by this run are reported to the operator through the termination message as
+#   {"files": [{"path": ..., "size": ...}], "totalBytes": ..., "redactedConfig": <Fernet token>}
+
+set -e
+
+cd /home/frappe/frappe-bench
+
+PROGRESS_INTERVAL="${PROGRESS_INTERVAL:-15}"
+
+SITE_NAME=""
+WITH_FILES="false"
+BACKUP_DIRS=()
+ARGS=("$@")
+for ((i = 0; i < ${#ARGS[@]}; i++)); do
+    case "${ARGS[$i]}" in
+        --site) SITE_NAME="${ARGS[$((i + 1))]}" ;;
+        --with-files) WITH_FILES="true" ;;
+        --backup-path) BACKUP_DIRS+=("${ARGS[$((i + 1))]}") ;;
+        --backup-path-db|--backup-path-conf|--backup-path-files|--backup-path-private-files)
+            BACKUP_DIRS+=("$(dirname "${ARGS[$((i + 1))]}")") ;;
+    esac
+done
+if [[ ${#BACKUP_DIRS[@]} -eq 0 ]]; then
+    BACKUP_DIRS=("sites/$SITE_NAME/private/backups")
+fi
+
+# Estimate the total from the database size (reported in MB) and the site files
+TOTAL=0
+if [[ "$BACKUP_MODE" != "files-only" ]]; then
+    DB_MB=$(bench --site "$SITE_NAME" execute frappe.db.get_database_size 2>/dev/null | tail -n 1 || true)
+    TOTAL=$(awk -v mb="$DB_MB" 'BEGIN { printf "%d", mb * 1048576 }')
+fi
+if [[ "$WITH_FILES" == "true" ]]; then
+    FILES_BYTES=$(du -sbc "sites/$SITE_NAME/public/files" "sites/$SITE_NAME/private/files" 2>/dev/null | tail -n 1 | cut -f1 || true)
+    TOTAL=$((TOTAL + ${FILES_BYTES:-0}))
+fi
+
+START_MARKER=$(mktemp)
+
+report() {
+    local processed
+    processed=$(find "${BACKUP_DIRS[@]}" -type f -newer "$START_MARKER" -printf '%s\n' 2>/dev/null | awk '{ sum += $1 } END { printf "%d", sum }')
+    echo "FRAPPE_PROGRESS ${processed:-0} $TOTAL Backing up"
+}
+
+bench "$@" &
+BENCH_PID=$!
+
+report
+ELAPSED=0
+while kill -0 "$BENCH_PID" 2>/dev/null; do
+    sleep 1
+    ELAPSED=$((ELAPSED + 1))
+    if (( ELAPSED % PROGRESS_INTERVAL == 0 )); then
+        report
+    fi
+done
+
+# Propagate the backup's exit code
+wait "$BENCH_PID"
+
+if [[ "$BACKUP_MODE" == "files-only" ]]; then
+    find "${BACKUP_DIRS[@]}" -type f -name '*-database.sql*' -newer "$START_MARKER" -print -delete 2>/dev/null || true
+fi
+report
+
+# Strip credentials from the config backup written by this run. The original values are
+# collected in REDACTED_VALUES and handed to the operator encrypted, for SiteRestore.
+REDACTED_VALUES=""
+if [[ "$REDACT_CONFIG" == "true" ]]; then
+    REDACTED_VALUES="$(mktemp)"
+    find "${BACKUP_DIRS[@]}" -type f -name '*site_config_backup.json' -newer "$START_MARKER" -print0 2>/dev/null |
+    while IFS= read -r -d '' CONFIG_BACKUP; do
+        python3 - "$CONFIG_BACKUP" "$REDACTED_VALUES" << 'PYTHON_SCRIPT'
+import json, re, sys
+path, values_path = sys.argv[1], sys.argv[2]
+with open(path) as f:
+    config = json.load(f)
+try:
+    with open(values_path) as f:
+        values = json.load(f)
+except ValueError:
+    values = {}
+redacted = sorted(k for k in config if re.search(r"(password|secret|token|_key)$", k))
+for key in redacted:
+    values[key] = config[key]
+    config[key] = "__redacted__"
+with open(path, "w") as f:
+    json.dump(config, f, indent=1)
+with open(values_path, "w") as f:
+    json.dump(values, f)
+print(f"Redacted {', '.join(redacted) or 'nothing'} in {path}")
+PYTHON_SCRIPT
+    done
+    # Replace the values by their Fernet token; a backup whose values can't be kept fails
+    if [[ -s "$REDACTED_VALUES" ]]; then
+        python3 - "$REDACTED_VALUES" << 'PYTHON_SCRIPT'
+import os, sys
+from cryptography.fernet import Fernet
+path = sys.argv[1]
+with open(path, "rb") as f:
+    token = Fernet(os.environ["REDACT_CONFIG_KEY"].encode()).encrypt(f.read())
+with open(path, "wb") as f:
+    f.write(token)
+PYTHON_SCRIPT
+    fi
+fi
+
+# Report the files written by this run before an S3 upload removes them
+find "${BACKUP_DIRS[@]}" -type f -newer "$START_MARKER" -printf '%p\t%s\n' 2>/dev/null |
+REDACTED_VALUES="$REDACTED_VALUES" python3 -c '
+import json, os, sys
+files = []
+for line in sys.stdin:
+    path, size = line.rstrip("\n").rsplit("\t", 1)
+    files.append({"path": os.path.abspath(path), "size": int(size)})
+files.sort(key=lambda f: f["path"])
+result = {"files": files, "totalBytes": sum(f["size"] for f in files)}
+print(f"Backup files: {json.dumps(result)}")
+token_path = os.getenv("REDACTED_VALUES")
+if token_path and os.path.getsize(token_path) > 0:
+    with open(token_path) as f:
+        result["redactedConfig"] = f.read()
+with open("/dev/termination-log", "w") as f:
+    json.dump(result, f)
+' || true
+if [[ -n "$REDACTED_VALUES" ]]; then
+    rm -f "$REDACTED_VALUES"
+fi
+
+if [[ -n "$S3_BUCKET" ]]; then
+    find "${BACKUP_DIRS[@]}" -type f -newer "$START_MARKER" -print0 2>/dev/null |
+    while IFS= read -r -d '' BACKUP_FILE; do
+        python3 - "$BACKUP_FILE" << 'PYTHON_SCRIPT'
+import os, re, sys, time
+import boto3
+from botocore.config import Config
+
+path = sys.argv[1]
+bucket = os.environ["S3_BUCKET"]
+use_ssl = os.getenv("S3_USE_SSL", "true") == "true"
+endpoint = os.getenv("S3_ENDPOINT") or None
+if endpoint and "://" not in endpoint:
+    endpoint = ("https://" if use_ssl else "http://") + endpoint
+
+# MinIO and most other S3-compatible stores only serve path-style requests
+s3 = boto3.client("s3",
+    region_name=os.getenv("S3_REGION") or None,
+    endpoint_url=endpoint,
+    use_ssl=use_ssl,
+    aws_access_key_id=os.getenv("AWS_ACCESS_KEY_ID"),
+    aws_secret_access_key=os.getenv("AWS_SECRET_ACCESS_KEY"),
+    config=Config(s3={"addressing_style": "path"}) if endpoint else None)
+
+# 20240101_020000-site_local-database.sql.gz -> database.sql.gz
+name = re.sub(r"^\d{8}_\d{6}-[^.]*?-(?=(database|site_config_backup|files|private-files)\.)", "", os.path.basename(path))
+key = os.environ["S3_PREFIX"].strip("/") + "/" + name
+
+# Print progress markers (parsed by the operator) at most every PROGRESS_INTERVAL seconds
+size = os.path.getsize(path)
+stage = "Uploading " + name
+interval = float(os.getenv("PROGRESS_INTERVAL", "15"))
+state = {"done": 0, "last": 0.0}
+def report(chunk):
+    state["done"] += chunk
+    now = time.monotonic()
+    if now - state["last"] >= interval:
+        state["last"] = now
+        print(f"FRAPPE_PROGRESS {state['done']} {size} {stage}", flush=True)
+
+print(f"Uploading {path} to s3://{bucket}/{key}...")
+s3.upload_file(path, bucket, key, Callback=report)
+print(f"FRAPPE_PROGRESS {size} {size} {stage}", flush=True)
+PYTHON_SCRIPT
+        if [[ "$S3_REMOVE_LOCAL" == "true" ]]; then
+            rm -f "$BACKUP_FILE"
+        fi
+    done
+fi
+rm -f "$START_MARKER"
+
+echo "Backup completed successfully!"
@@ -39,18 +39,49 @@ if [ -d "apps" ]; then
     ls -1 apps > sites/apps.txt || { echo "ERROR: Failed to write to sites/apps.txt"; exit 1; }
 fi
 
-# Create or update common_site_config.json
+# Create or update common_site_config.json with the operator-managed keys and any
+# spec.commonSiteConfig keys ($COMMON_SITE_CONFIG_PATCH, Secret keys at /tmp/common-site-config)
 echo "Creating common_site_config.json..."
-cat > sites/common_site_config.json <<EOF
-{
-  "redis_cache": "redis://{{.BenchName}}-redis-cache:6379",
-  "redis_queue": "redis://{{.BenchName}}-redis-queue:6379",
-  "socketio_port": 9000
-}
-EOF
-
-# Sync assets from the image cache to the Persistent Volume
-if [ -d "/home/frappe/assets_cache" ]; then
+python3 - <<'PYEOF'
+import json
+import os
+
+path = "sites/common_site_config.json"
+
+def parse(value):
+    try:
+        return json.loads(value)
+    except ValueError:
+        return value
+
+config = {}
+if os.path.exists(path):
+    with open(path) as f:
+        config = json.load(f)
+
+config["redis_cache"] = os.environ.get("REDIS_CACHE") or "redis://{{.BenchName}}-redis-cache:6379"
+config["redis_queue"] = os.environ.get("REDIS_QUEUE") or "redis://{{.BenchName}}-redis-queue:6379"
+config["socketio_port"] = 9000
+
+patch = json.loads(os.environ.get("COMMON_SITE_CONFIG_PATCH") or "{}")
+for key, value in patch.get("set", {}).items():
+    config[key] = parse(value)
+for key in patch.get("secretKeys", []):
+    with open(os.path.join("/tmp/common-site-config", key)) as f:
+        # kubectl create secret --from-file keeps the file's trailing newline
+        config[key] = parse(f.read().rstrip("\r\n"))
+
+tmp = path + ".tmp"
+with open(tmp, "w") as f:
+    json.dump(config, f, indent=1)
+os.replace(tmp, path)
+PYEOF
+
+# Sync assets from the image cache to the Persistent Volume, unless a shared
+# read-only assets volume is mounted at sites/assets
+if [ "${SHARED_ASSETS:-0}" = "1" ]; then
+    echo "Using shared assets volume, skipping asset sync"
+elif [ -d "/home/frappe/assets_cache" ]; then
     echo "Syncing pre-built assets from image to PVC..."
     mkdir -p sites/assets
     # Use -n to not overwrite existing files, preserving permissions where possible
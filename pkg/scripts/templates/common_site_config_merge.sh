@@ -0,0 +1,45 @@
+#!/bin/bash
+# common_site_config.json merge script for Frappe (embedded in operator, executed in common site config jobs)
+# Merges spec.commonSiteConfig and commonSiteConfigSecretRef keys into the bench's
+# common_site_config.json, leaving the operator-managed redis keys untouched.
+# The operator passes the keys in $COMMON_SITE_CONFIG_PATCH and mounts the Secret at /tmp/common-site-config.
+
+set -e
+
+cd /home/frappe/frappe-bench
+
+python3 - <<'PYEOF'
+import json
+import os
+
+path = "sites/common_site_config.json"
+patch = json.loads(os.environ["COMMON_SITE_CONFIG_PATCH"])
+
+def parse(value):
+    try:
+        return json.loads(value)
+    except ValueError:
+        return value
+
+config = {}
+if os.path.exists(path):
+    with open(path) as f:
+        config = json.load(f)
+
+for key in patch.get("remove", []):
+    config.pop(key, None)
+for key, value in patch.get("set", {}).items():
+    config[key] = parse(value)
+for key in patch.get("secretKeys", []):
+    with open(os.path.join("/tmp/common-site-config", key)) as f:
+        # kubectl create secret --from-file keeps the file's trailing newline
+        config[key] = parse(f.read().rstrip("\r\n"))
+
+tmp = path + ".tmp"
+with open(tmp, "w") as f:
+    json.dump(config, f, indent=1)
+os.replace(tmp, path)
+
+print("common_site_config.json updated: set " + ", ".join(sorted(set(patch.get("set", {})) | set(patch.get("secretKeys", [])))) +
+      "; removed " + ", ".join(patch.get("remove", [])))
+PYEOF
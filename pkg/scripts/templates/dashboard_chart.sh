@@ -0,0 +1,23 @@
+#!/bin/bash
+# Dashboard chart script for Frappe (embedded in operator, executed in dashboard chart jobs)
+# Deletes the Dashboard Charts named in $CHART_DELETE, one JSON list of
+# `frappe.delete_doc_if_exists` arguments per line, then inserts the chart in $CHART_INSERT,
+# the `frappe.client.insert` keyword arguments, if set. Charts are deleted with force so
+# dashboards linking them don't block the replacement.
+
+set -e
+
+cd /home/frappe/frappe-bench
+
+while IFS= read -r args; do
+    if [ -n "$args" ]; then
+        bench --site {{.SiteName}} execute frappe.delete_doc_if_exists --args "$args"
+    fi
+done <<< "${CHART_DELETE:-}"
+
+if [ -n "${CHART_INSERT:-}" ]; then
+    bench --site {{.SiteName}} execute frappe.client.insert --kwargs "$CHART_INSERT"
+    echo "Dashboard chart applied to {{.SiteName}}"
+else
+    echo "Dashboard chart removed from {{.SiteName}}"
+fi
@@ -0,0 +1,61 @@
+#!/bin/bash
+# Site config merge script for Frappe (embedded in operator, executed in site config jobs)
+# Merges spec.siteConfig and siteConfigSecretRef keys into the site's site_config.json
+# and links each of spec.aliases to the site directory.
+# The operator passes the keys in $SITE_CONFIG_PATCH and mounts the Secret at /tmp/site-config.
+
+set -e
+
+cd /home/frappe/frappe-bench
+
+python3 - <<'PYEOF'
+import json
+import os
+
+path = "sites/{{.SiteName}}/site_config.json"
+patch = json.loads(os.environ["SITE_CONFIG_PATCH"])
+
+def parse(value):
+    try:
+        return json.loads(value)
+    except ValueError:
+        return value
+
+with open(path) as f:
+    config = json.load(f)
+
+for key in patch.get("remove", []):
+    config.pop(key, None)
+for key, value in patch.get("set", {}).items():
+    config[key] = parse(value)
+for key in patch.get("secretKeys", []):
+    with open(os.path.join("/tmp/site-config", key)) as f:
+        # kubectl create secret --from-file keeps the file's trailing newline
+        config[key] = parse(f.read().rstrip("\r\n"))
+
+tmp = path + ".tmp"
+with open(tmp, "w") as f:
+    json.dump(config, f, indent=1)
+os.replace(tmp, path)
+
+# nginx passes the Host header as the site name, so aliases resolve through symlinks
+site = "{{.SiteName}}"
+links = set(patch.get("links", []))
+for name in os.listdir("sites"):
+    link = os.path.join("sites", name)
+    if name not in links and os.path.islink(link) and os.readlink(link) == site:
+        os.remove(link)
+for name in sorted(links):
+    link = os.path.join("sites", name)
+    if os.path.islink(link):
+        if os.readlink(link) == site:
+            continue
+        os.remove(link)
+    elif os.path.exists(link):
+        print("warning: sites/" + name + " exists and is not a link, alias left alone")
+        continue
+    os.symlink(site, link)
+
+print("site_config.json updated: set " + ", ".join(sorted(set(patch.get("set", {})) | set(patch.get("secretKeys", [])))) +
+      "; removed " + ", ".join(patch.get("remove", [])))
+PYEOF
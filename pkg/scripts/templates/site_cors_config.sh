@@ -0,0 +1,32 @@
+#!/bin/bash
+# CORS configuration script for Frappe (embedded in operator, executed in site CORS jobs)
+# Writes allow_cors to the site's site_config.json, or removes it when no origins are given
+
+set -e
+
+cd /home/frappe/frappe-bench
+
+python3 - <<'PYEOF'
+import json
+import os
+
+path = "sites/{{.SiteName}}/site_config.json"
+origins = [{{range $i, $origin := .AllowOrigins}}{{if $i}}, {{end}}"{{$origin}}"{{end}}]
+
+with open(path) as f:
+    config = json.load(f)
+
+if not origins:
+    config.pop("allow_cors", None)
+elif origins == ["*"]:
+    config["allow_cors"] = "*"
+else:
+    config["allow_cors"] = origins
+
+tmp = path + ".tmp"
+with open(tmp, "w") as f:
+    json.dump(config, f, indent=1)
+os.replace(tmp, path)
+
+print("allow_cors set to " + json.dumps(config.get("allow_cors")))
+PYEOF
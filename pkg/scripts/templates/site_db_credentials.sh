@@ -0,0 +1,32 @@
+#!/bin/bash
+# Database credentials update script for Frappe (embedded in operator, executed in site db-credentials jobs)
+# Rewrites db_user and db_password in the site's site_config.json after the credentials rotated.
+# The operator mounts the current credentials at /tmp/db-credentials.
+
+set -e
+
+cd /home/frappe/frappe-bench
+
+python3 - <<'PYEOF'
+import json
+import os
+
+path = "sites/{{.SiteName}}/site_config.json"
+
+def read_credential(name):
+    with open(os.path.join("/tmp/db-credentials", name)) as f:
+        return f.read()
+
+with open(path) as f:
+    config = json.load(f)
+
+config["db_user"] = read_credential("db_user")
+config["db_password"] = read_credential("db_password")
+
+tmp = path + ".tmp"
+with open(tmp, "w") as f:
+    json.dump(config, f, indent=1)
+os.replace(tmp, path)
+
+print("Database credentials updated for user " + config["db_user"])
+PYEOF
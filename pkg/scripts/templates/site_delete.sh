@@ -29,8 +29,19 @@ DB_ROOT_USER=$(cat /tmp/secrets/db_root_user)
 DB_ROOT_PASSWORD=$(cat /tmp/secrets/db_root_password)
 SITE_NAME=$(cat /tmp/secrets/site_name)
 
+# dbConfig.skipDropOnDelete: keep the database, archive the site directory like drop-site does
+if [[ "${SKIP_DB_DROP:-0}" == "1" ]]; then
+    echo "Archiving Frappe site $SITE_NAME without dropping its database"
+    if [ -d "sites/$SITE_NAME" ]; then
+        mkdir -p archived_sites
+        mv "sites/$SITE_NAME" "archived_sites/$SITE_NAME-$(date +%Y-%m-%d_%H:%M:%S)"
+    fi
+    echo "Site $SITE_NAME archived successfully!"
+    exit 0
+fi
+
 echo "Dropping Frappe site: $SITE_NAME"
-echo "Using MariaDB root credentials from secret volume for secure deletion"
+echo "Using database root credentials from secret volume for secure deletion"
 
 # Use root credentials to drop the site (site user cannot drop database)
 bench drop-site "$SITE_NAME" --force --db-root-username "$DB_ROOT_USER" --db-root-password "$DB_ROOT_PASSWORD" --no-backup
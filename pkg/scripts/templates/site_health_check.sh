@@ -0,0 +1,18 @@
+#!/bin/bash
+# Site health check script for Frappe
+# Polls the site through the in-cluster nginx until it responds.
+# The job's activeDeadlineSeconds bounds how long the operator waits.
+# DOMAIN is passed as an env var so it never becomes part of the script.
+
+set -e
+
+URL="http://{{ .NginxAddress }}/api/method/ping"
+
+echo "Waiting for $DOMAIN to respond at $URL"
+until curl -fsS --max-time 10 -H "Host: $DOMAIN" "$URL"; do
+    echo "Site $DOMAIN is not healthy yet, retrying in 5s"
+    sleep 5
+done
+
+echo ""
+echo "Site $DOMAIN is healthy"
@@ -14,6 +14,14 @@ if ! whoami &>/dev/null; then
   fi
 fi
 
+# Run the user-supplied preamble (spec.initScriptPreamble) if mounted.
+# It runs in a child shell so it cannot alter this script's variables or flow.
+if [ -f /tmp/site-preamble/preamble.sh ]; then
+  echo "Running init script preamble..."
+  bash /tmp/site-preamble/preamble.sh
+  echo "Init script preamble completed"
+fi
+
 cd /home/frappe/frappe-bench
 
 # Read from secret files mounted at /tmp/site-secrets
@@ -22,6 +30,8 @@ DOMAIN=$(cat /tmp/site-secrets/domain)
 ADMIN_PASSWORD=$(cat /tmp/site-secrets/admin_password)
 BENCH_NAME=$(cat /tmp/site-secrets/bench_name)
 DB_PROVIDER=$(cat /tmp/site-secrets/db_provider)
+REDIS_CACHE=$(cat /tmp/site-secrets/redis_cache 2>/dev/null || echo "redis://${BENCH_NAME}-redis-cache:6379")
+REDIS_QUEUE=$(cat /tmp/site-secrets/redis_queue 2>/dev/null || echo "redis://${BENCH_NAME}-redis-queue:6379")
 APPS_TO_INSTALL=$(cat /tmp/site-secrets/apps_to_install 2>/dev/null || echo "")
 
 echo "Creating Frappe site: $SITE_NAME"
@@ -81,6 +91,7 @@ if [[ -n "$APPS_TO_INSTALL" ]]; then
 	# Build install arguments and validate each app
 	# New approach: Skip apps that aren't available instead of failing
 	SKIPPED_APPS=""
+	SKIPPED_APP_NAMES=""
 	for app in $APPS_TO_INSTALL; do
 		# Check if app directory exists
 		if [[ -d "apps/$app" ]]; then
@@ -91,6 +102,7 @@ if [[ -n "$APPS_TO_INSTALL" ]]; then
 			# Gracefully skip missing apps
 			echo "⚠ WARNING: App '$app' not found in bench directory - skipping"
 			echo "  The app may not be installed in this bench yet"
+			SKIPPED_APP_NAMES+=" $app"
 			if [[ -n "$SKIPPED_APPS" ]]; then
 				SKIPPED_APPS="$SKIPPED_APPS, $app"
 			else
@@ -233,18 +245,33 @@ else
     exit 1
 fi
 
-# Create or update common_site_config.json
-echo "Creating common_site_config.json..."
-cat > sites/common_site_config.json <<EOF
-{
-  "redis_cache": "redis://${BENCH_NAME}-redis-cache:6379",
-  "redis_queue": "redis://${BENCH_NAME}-redis-queue:6379",
-  "socketio_port": 9000
-}
-EOF
-
-# Sync assets from the image cache to the Persistent Volume
-if [ -d "/home/frappe/assets_cache" ]; then
+# Create or update common_site_config.json, keeping the bench's spec.commonSiteConfig keys
+echo "Updating common_site_config.json..."
+python3 - <<'PYEOF'
+import json
+import os
+
+path = "sites/common_site_config.json"
+config = {}
+if os.path.exists(path):
+    with open(path) as f:
+        config = json.load(f)
+
+config["redis_cache"] = os.environ.get("REDIS_CACHE", "")
+config["redis_queue"] = os.environ.get("REDIS_QUEUE", "")
+config["socketio_port"] = 9000
+
+tmp = path + ".tmp"
+with open(tmp, "w") as f:
+    json.dump(config, f, indent=1)
+os.replace(tmp, path)
+PYEOF
+
+# Sync assets from the image cache to the Persistent Volume, unless a shared
+# read-only assets volume is mounted at sites/assets
+if [ "${SHARED_ASSETS:-0}" = "1" ]; then
+    echo "Using shared assets volume, skipping asset sync"
+elif [ -d "/home/frappe/assets_cache" ]; then
     echo "Syncing pre-built assets from image to PVC..."
     mkdir -p sites/assets
     cp -rn /home/frappe/assets_cache/* sites/assets/ || true
@@ -276,6 +303,14 @@ with open('/tmp/site-secrets/db_password', 'r') as f:
     db_password = f.read().strip()
 with open('/tmp/site-secrets/db_provider', 'r') as f:
     db_provider = f.read().strip()
+try:
+    with open('/tmp/site-secrets/redis_cache', 'r') as f:
+        redis_cache = f.read().strip()
+    with open('/tmp/site-secrets/redis_queue', 'r') as f:
+        redis_queue = f.read().strip()
+except FileNotFoundError:
+    redis_cache = f"redis://{bench_name}-redis-cache:6379"
+    redis_queue = f"redis://{bench_name}-redis-queue:6379"
 
 site_path = f"/home/frappe/frappe-bench/sites/{site_name}"
 config_file = os.path.join(site_path, "site_config.json")
@@ -291,8 +326,8 @@ except FileNotFoundError:
 config['host_name'] = domain
 
 # Add Redis configuration for this site
-config['redis_cache'] = f"redis://{bench_name}-redis-cache:6379"
-config['redis_queue'] = f"redis://{bench_name}-redis-queue:6379"
+config['redis_cache'] = redis_cache
+config['redis_queue'] = redis_queue
 
 # Explicitly add database credentials for self-healing
 config['db_name'] = db_name
@@ -312,10 +347,30 @@ with open(config_file, 'w') as f:
     json.dump(config, f, indent=2)
 
 print(f"Updated site_config.json for domain: {domain}")
-print(f"Redis cache: {bench_name}-redis-cache:6379")
-print(f"Redis queue: {bench_name}-redis-queue:6379")
+print(f"Redis cache: {redis_cache.rpartition('@')[2]}")
+print(f"Redis queue: {redis_queue.rpartition('@')[2]}")
 PYTHON_SCRIPT
 
+# Report installed and skipped apps to the operator through the termination message.
+# The apps bench lists for the site win over what this run asked new-site to install,
+# so a site that already existed reports what it really has.
+SITE_APPS=$(bench --site "$SITE_NAME" list-apps 2>/dev/null | awk '{print $1}' || true)
+REQUESTED_APPS="$APPS_TO_INSTALL" SKIPPED_APP_NAMES="$SKIPPED_APP_NAMES" SITE_APPS="$SITE_APPS" python3 - <<'PYEOF'
+import json
+import os
+
+requested = os.environ.get("REQUESTED_APPS", "").split()
+skipped = os.environ.get("SKIPPED_APP_NAMES", "").split()
+site_apps = set(os.environ.get("SITE_APPS", "").split())
+
+installed = [app for app in requested if app not in skipped and (not site_apps or app in site_apps)]
+skipped += [app for app in requested if app not in skipped and app not in installed]
+
+with open("/dev/termination-log", "w") as f:
+    json.dump({"installed": installed, "skipped": skipped}, f)
+print(f"Installed apps: {installed or 'none'}, skipped apps: {skipped or 'none'}")
+PYEOF
+
 echo "Site initialization complete!"
 
 # Exit success regardless of whether new-site ran
@@ -0,0 +1,11 @@
+#!/bin/bash
+# Maintenance mode script for Frappe (embedded in operator, executed in site maintenance mode jobs)
+# Turns maintenance mode on or off; Frappe then serves the maintenance page to users
+
+set -e
+
+cd /home/frappe/frappe-bench
+
+bench --site {{.SiteName}} set-maintenance-mode {{.Mode}}
+
+echo "Maintenance mode {{.Mode}} for {{.SiteName}}"
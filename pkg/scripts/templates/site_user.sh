@@ -0,0 +1,56 @@
+#!/bin/bash
+# Site user script for Frappe (embedded in operator, executed in site user jobs)
+# Creates the user in $USER_EMAIL if missing, sets its names and enabled flag and, when the
+# operator mounts a password at /tmp/user-secrets/password, sets the password. The password
+# is read inside Python so it never reaches the command line, the environment or the logs.
+
+set -e
+
+cd /home/frappe/frappe-bench/sites
+
+../env/bin/python - <<'PYEOF'
+import os
+
+import frappe
+from frappe.utils.password import update_password
+
+email = os.environ["USER_EMAIL"]
+first_name = os.environ.get("USER_FIRST_NAME", "")
+last_name = os.environ.get("USER_LAST_NAME", "")
+enabled = int(os.environ.get("USER_ENABLED", "1"))
+password_file = "/tmp/user-secrets/password"
+
+frappe.init(site="{{.SiteName}}", sites_path=".")
+frappe.connect()
+try:
+    if frappe.db.exists("User", email):
+        user = frappe.get_doc("User", email)
+        if first_name:
+            user.first_name = first_name
+        if last_name:
+            user.last_name = last_name
+        user.enabled = enabled
+        user.save(ignore_permissions=True)
+        print("Updated user " + email)
+    else:
+        user = frappe.get_doc({
+            "doctype": "User",
+            "email": email,
+            "first_name": first_name or email.split("@")[0],
+            "last_name": last_name,
+            "enabled": enabled,
+            "send_welcome_email": 0,
+        })
+        user.insert(ignore_permissions=True)
+        print("Created user " + email)
+
+    if os.path.exists(password_file):
+        with open(password_file) as f:
+            update_password(email, f.read().rstrip("\n"))
+        print("Password set for " + email)
+
+    frappe.db.commit()
+    print("User " + email + (" enabled" if enabled else " disabled"))
+finally:
+    frappe.destroy()
+PYEOF
@@ -0,0 +1,42 @@
+#!/bin/bash
+# common_site_config.json redis sync script for Frappe (embedded in operator, executed in config sync jobs)
+# Points redis_cache/redis_queue at the bench's current redis services (or $REDIS_CACHE/$REDIS_QUEUE
+# for an external redis), leaving other keys untouched,
+# and reports "changed" or "unchanged" through the container termination message
+
+set -e
+
+cd /home/frappe/frappe-bench
+
+python3 - <<'PYEOF'
+import json
+import os
+
+path = "sites/common_site_config.json"
+desired = {
+    "redis_cache": "{{.RedisCache}}",
+    "redis_queue": "{{.RedisQueue}}",
+}
+# External redis URLs carry passwords and come from the environment instead
+for key in desired:
+    if os.environ.get(key.upper()):
+        desired[key] = os.environ[key.upper()]
+
+config = {}
+if os.path.exists(path):
+    with open(path) as f:
+        config = json.load(f)
+
+changed = any(config.get(key) != value for key, value in desired.items())
+if changed:
+    config.update(desired)
+    tmp = path + ".tmp"
+    with open(tmp, "w") as f:
+        json.dump(config, f, indent=1)
+    os.replace(tmp, path)
+
+result = "changed" if changed else "unchanged"
+print("common_site_config.json redis URLs " + result)
+with open("/dev/termination-log", "w") as f:
+    f.write(result)
+PYEOF
@@ -0,0 +1,28 @@
+#!/bin/bash
+# Waits for the site's database to accept TCP connections (embedded in operator, run as the
+# init container of site init jobs). Reads the host and port from the mounted init secret and
+# gives up after DB_WAIT_TIMEOUT seconds, failing the pod so the job retries it.
+
+set -e
+
+DB_HOST=$(cat /tmp/site-secrets/db_host 2>/dev/null || echo "")
+DB_PORT=$(cat /tmp/site-secrets/db_port 2>/dev/null || echo "3306")
+DB_WAIT_TIMEOUT="${DB_WAIT_TIMEOUT:-300}"
+
+if [[ -z "$DB_HOST" ]]; then
+    echo "No database host configured, not waiting"
+    exit 0
+fi
+
+echo "Waiting up to ${DB_WAIT_TIMEOUT}s for the database at $DB_HOST:$DB_PORT"
+DEADLINE=$((SECONDS + DB_WAIT_TIMEOUT))
+until timeout 5 bash -c "exec 3<>/dev/tcp/$DB_HOST/$DB_PORT" 2>/dev/null; do
+    if (( SECONDS >= DEADLINE )); then
+        echo "Database at $DB_HOST:$DB_PORT is not reachable after ${DB_WAIT_TIMEOUT}s" >&2
+        exit 1
+    fi
+    echo "Database at $DB_HOST:$DB_PORT is not reachable yet, retrying in 5s"
+    sleep 5
+done
+
+echo "Database at $DB_HOST:$DB_PORT is reachable"